@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+)
+
+func newAnalyzeCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "analyze",
+		ArgsUsage:    "[ROUTES_FILE]",
+		Usage:        "Reports the complexity of the routes of a routes file (predicates, regexps, filters and path depth, routes per host, the most complex routes)",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "routes, r",
+				Usage: "Load routes from `FILE` (alternative to the ROUTES_FILE argument)",
+			},
+			cli.StringFlag{
+				Name:  "format, f",
+				Value: outputText,
+				Usage: "Output `FORMAT`: text or json",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			routesFile := c.String("routes")
+			if routesFile == "" {
+				routesFile = c.Args().First()
+			}
+			if routesFile, err = conf.routesFile(routesFile); err != nil {
+				return loadError(err)
+			}
+			if routesFile == "" {
+				return usageError("A routes file must be provided")
+			}
+			format := c.String("format")
+			if format != outputText && format != outputJSON {
+				return usageError("unsupported format %q, use one of text or json", format)
+			}
+
+			report, err := matcher.Analyze(&matcher.Options{
+				RoutesFile:     routesFile,
+				OnLoadProgress: loadProgress(c.App.ErrWriter),
+			})
+			if err != nil {
+				return loadError(err)
+			}
+			if format == outputJSON {
+				return writeData(c.App.Writer, outputJSON, report)
+			}
+			return writeAnalysis(c.App.Writer, report)
+		},
+	}
+}
+
+// writeAnalysis writes the summary of a complexity report
+func writeAnalysis(w io.Writer, report *matcher.Report) error {
+	fmt.Fprintf(w, "routes: %d\n", report.Routes)
+	fmt.Fprintf(w, "predicates per route: %.2f\n", report.AveragePredicates)
+	fmt.Fprintf(w, "filters per route: %.2f\n", report.AverageFilters)
+
+	fmt.Fprintln(w, "\nroutes per host:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	hosts := make([]string, 0, len(report.RoutesPerHost))
+	for host := range report.RoutesPerHost {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		fmt.Fprintf(tw, "  %s\t%d\n", host, report.RoutesPerHost[host])
+	}
+	if report.RoutesWithoutHost > 0 {
+		fmt.Fprintf(tw, "  (any host)\t%d\n", report.RoutesWithoutHost)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nmost complex routes:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSCORE\tPREDICATES\tREGEXPS\tFILTERS\tDEPTH\tLOCATION")
+	for _, rc := range report.MostComplex {
+		location := "-"
+		if rc.Location != nil {
+			location = rc.Location.String()
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", rc.RouteID, rc.Score, rc.Predicates, rc.RegexpPredicates, rc.Filters, rc.PathDepth, location)
+	}
+	return tw.Flush()
+}
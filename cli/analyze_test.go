@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+func TestAnalyze(t *testing.T) {
+	runGolden(t, "analyze.txt", "analyze", "testdata/inventory.eskip")
+}
+
+func TestAnalyzeJSON(t *testing.T) {
+	code, out := runCommand(t, "analyze", "-r", "testdata/inventory.eskip", "-f", "json")
+	if code != exitOK {
+		t.Fatalf("expected exit code %d but got %d", exitOK, code)
+	}
+	var report matcher.Report
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Routes != 5 || len(report.Complexity) != 5 {
+		t.Errorf("expected 5 routes but got %d", report.Routes)
+	}
+	if report.MostComplex[0].RouteID != "shop_cart" {
+		t.Errorf("expected shop_cart to be the most complex route but got %s", report.MostComplex[0].RouteID)
+	}
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "missing routes file", code: exitUsage},
+		{name: "unsupported format", args: []string{"-f", "yaml", "testdata/inventory.eskip"}, code: exitUsage},
+		{name: "not found", args: []string{"testdata/blue.eskip"}, code: exitLoad},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := runCommand(t, "analyze", tt.args...); code != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+)
+
+func newBackendsCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "backends",
+		ArgsUsage:    "[ROUTES_FILE]",
+		Usage:        "Lists the backends of a routes file with the routes using them, load balanced routes are listed under each endpoint",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "routes, r",
+				Usage: "Load routes from `FILE` (alternative to the ROUTES_FILE argument)",
+			},
+			cli.StringFlag{
+				Name:  "format, f",
+				Value: outputText,
+				Usage: "Output `FORMAT`: text or json",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			routesFile := c.String("routes")
+			if routesFile == "" {
+				routesFile = c.Args().First()
+			}
+			if routesFile, err = conf.routesFile(routesFile); err != nil {
+				return loadError(err)
+			}
+			if routesFile == "" {
+				return usageError("A routes file must be provided")
+			}
+			format := c.String("format")
+			if format != outputText && format != outputJSON {
+				return usageError("unsupported format %q, use one of text or json", format)
+			}
+
+			inventory, err := matcher.BackendInventory(&matcher.Options{
+				RoutesFile:     routesFile,
+				OnLoadProgress: loadProgress(c.App.ErrWriter),
+			})
+			if err != nil {
+				return loadError(err)
+			}
+			if format == outputJSON {
+				return writeData(c.App.Writer, outputJSON, inventory)
+			}
+			return writeBackends(c.App.Writer, inventory)
+		},
+	}
+}
+
+// writeBackends writes the backend inventory as a table
+func writeBackends(w io.Writer, inventory []matcher.BackendRoutes) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tROUTES")
+	for _, b := range inventory {
+		fmt.Fprintf(tw, "%s\t%s\n", b.Backend, strings.Join(b.Routes, ", "))
+	}
+	return tw.Flush()
+}
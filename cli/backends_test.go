@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+func TestBackends(t *testing.T) {
+	runGolden(t, "backends.txt", "backends", "testdata/backends.eskip")
+}
+
+func TestBackendsJSON(t *testing.T) {
+	code, out := runCommand(t, "backends", "-r", "testdata/backends.eskip", "-f", "json")
+	if code != exitOK {
+		t.Fatalf("expected exit code %d but got %d", exitOK, code)
+	}
+	var inventory []matcher.BackendRoutes
+	if err := json.Unmarshal([]byte(out), &inventory); err != nil {
+		t.Fatal(err)
+	}
+	want := matcher.BackendRoutes{Backend: "https://legacy-orders.internal", Routes: []string{"orders", "orders_v2"}}
+	if len(inventory) != 5 || !reflect.DeepEqual(inventory[4], want) {
+		t.Errorf("expected 5 backends ending with %v but got %v", want, inventory)
+	}
+}
+
+func TestBackendsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "missing routes file", code: exitUsage},
+		{name: "unsupported format", args: []string{"-f", "yaml", "testdata/backends.eskip"}, code: exitUsage},
+		{name: "not found", args: []string{"testdata/blue.eskip"}, code: exitLoad},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := runCommand(t, "backends", tt.args...); code != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, code)
+			}
+		})
+	}
+}
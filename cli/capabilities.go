@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+)
+
+func newCapabilitiesCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "capabilities",
+		Usage:        "Lists the filters and predicates the routes can use and the skipper version compiled in",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
+			},
+			cli.StringFlag{
+				Name:  "format, f",
+				Value: outputText,
+				Usage: "Output `FORMAT`: text or json",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			format := c.String("format")
+			if format != outputText && format != outputJSON {
+				return usageError("unsupported format %q, use one of text or json", format)
+			}
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+
+			caps := matcher.ListCapabilities(&matcher.Options{
+				MockFilters: append(conf.CustomFilters, list(c.String("mock-filters"))...),
+			})
+			if format == outputJSON {
+				return writeData(c.App.Writer, format, caps)
+			}
+			return writeCapabilities(c.App.Writer, caps)
+		},
+	}
+}
+
+// writeCapabilities writes a section for each kind of filter and predicate,
+// one name per line so the output can be searched with grep
+func writeCapabilities(w io.Writer, caps *matcher.Capabilities) error {
+	fmt.Fprintf(w, "skipper version: %s\n", caps.SkipperVersion)
+	sections := []struct {
+		title string
+		names []string
+	}{
+		{"filters", caps.Filters},
+		{"custom filters", caps.CustomFilters},
+		{"mocked filters", caps.MockFilters},
+		{"predicates", caps.Predicates},
+		{"custom predicates", caps.CustomPredicates},
+	}
+	for _, s := range sections {
+		if len(s.names) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", s.title)
+		for _, name := range s.names {
+			if _, err := fmt.Fprintf(w, "  %s\n", name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
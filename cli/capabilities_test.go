@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+func TestCapabilitiesCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		contains []string
+	}{
+		{
+			name:     "text",
+			args:     []string{"-c", "testdata/config.yml", "capabilities", "--mock-filters", "oauthTokeninfoAnyScope"},
+			contains: []string{"skipper version: ", "\nfilters:\n", "  setPath\n", "\nmocked filters:\n  bar\n  foo\n  oauthTokeninfoAnyScope\n", "\npredicates:\n", "  PathSubtree\n", "  Traffic\n"},
+		},
+		{
+			name: "unsupported format",
+			args: []string{"capabilities", "-f", "yaml"},
+			code: exitUsage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode = 0
+			app := NewApp()
+			out := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = &bytes.Buffer{}
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+
+			if exitCode != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, exitCode)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("expected output to contain %q but got:\n%s", s, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCapabilitiesJSON(t *testing.T) {
+	exitCode = 0
+	app := NewApp()
+	out := &bytes.Buffer{}
+	app.Writer = out
+	app.Run([]string{"eskip-match", "-c", "testdata/config.yml", "capabilities", "--format", "json", "--mock-filters", "baz"})
+
+	var caps matcher.Capabilities
+	if err := json.Unmarshal(out.Bytes(), &caps); err != nil {
+		t.Fatalf("invalid json %q: %v", out.String(), err)
+	}
+	if len(caps.Filters) == 0 || len(caps.Predicates) == 0 {
+		t.Errorf("expected filters and predicates but got %+v", caps)
+	}
+	if !reflect.DeepEqual(caps.MockFilters, []string{"bar", "baz", "foo"}) {
+		t.Errorf("expected the configured and the given mocked filters but got %v", caps.MockFilters)
+	}
+}
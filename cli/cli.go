@@ -4,11 +4,13 @@ package cli
 
 import (
 	"fmt"
-	"log"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/rbarilani/eskip-match/matcher"
 	"github.com/urfave/cli"
+	"github.com/zalando/skipper/eskip"
 )
 
 // Options configurable options
@@ -21,38 +23,59 @@ type options struct {
 
 // NewApp creates the cli application
 func NewApp() *cli.App {
-	var configFile string
-	loader := newConfigLoader(configDefaultFile)
 	o := &options{
-		ConfigFile:   configFile,
-		ConfigLoader: loader,
+		ConfigLoader: newConfigLoader(configDefaultFile, configLegacyFile),
 	}
 
 	app := cli.NewApp()
 	app.Name = "eskip-match"
 	app.Usage = "A command line tool that helps you test .eskip files routing matching logic"
 
+	app.OnUsageError = onUsageError
+	// the warnings and diagnostics, urfave/cli leaves it nil
+	app.ErrWriter = os.Stderr
+
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:        "config, c",
-			Usage:       "Load configuration from `FILE`",
-			Destination: &configFile,
+			Usage:       "Load configuration from `FILE` (by default " + configDefaultFile + " is searched from the current directory upward)",
+			Destination: &o.ConfigFile,
 		},
 	}
 
 	app.Commands = []cli.Command{
 		newTestCommand(o),
+		newReplCommand(o),
+		newCoverageCommand(o),
+		newLintCommand(o),
+		newSuiteCommand(o),
+		newServeCommand(o),
+		newExplainCommand(o),
+		newDiffCommand(o),
+		newMirrorCommand(o),
+		newGenerateCommand(o),
+		newRoutesCommand(o),
+		newAnalyzeCommand(o),
+		newBackendsCommand(o),
+		newGraphCommand(o),
+		newCapabilitiesCommand(o),
+		newConfigCommand(o),
 	}
 	return app
 }
 
 func newTestCommand(o *options) cli.Command {
 	return cli.Command{
-		Name:      "test",
-		Aliases:   []string{"t"},
-		ArgsUsage: "<ROUTES_FILE>",
-		Usage:     "Given a routes file and request attributes, checks a route matches",
+		Name:         "test",
+		Aliases:      []string{"t"},
+		ArgsUsage:    "[ROUTES_FILE] [-- CURL_ARGS...]",
+		Usage:        "Given a routes file and request attributes, checks a route matches",
+		OnUsageError: onUsageError,
 		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "routes, r",
+				Usage: "Load routes from `FILE` (alternative to the ROUTES_FILE argument)",
+			},
 			cli.StringFlag{
 				Name:  "method, m",
 				Usage: "Specify request `METHOD`",
@@ -61,63 +84,299 @@ func newTestCommand(o *options) cli.Command {
 				Name:  "path, p",
 				Usage: "Specify request `PATH`",
 			},
+			cli.StringFlag{
+				Name:  "host",
+				Usage: "Specify request `HOST`",
+			},
+			cli.StringSliceFlag{
+				Name:  "query, Q",
+				Usage: "Specify request query parameter `QUERY` as key=value pair",
+			},
 			cli.StringSliceFlag{
 				Name:  "header, H",
-				Usage: "Specify request `HEADER` as key=value pair",
+				Usage: "Specify request `HEADER` as key=value or \"Key: value\" pair",
+			},
+			cli.BoolFlag{
+				Name:  "ignore-trailing-slash",
+				Usage: "Ignore trailing slashes in paths when matching",
+			},
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
 			},
+			verboseFlag,
+			vvFlag,
+			vvvFlag,
 			cli.BoolFlag{
-				Name:  "verbose, v",
-				Usage: "Print verbose output",
+				Name:  "quiet, q",
+				Usage: "Print only the matching route id, nothing on no match",
 			},
+			cli.BoolFlag{
+				Name:  "stdin-request",
+				Usage: "Read a raw HTTP request from the standard input (request line, headers and optional body)",
+			},
+			cli.BoolFlag{
+				Name:  "watch, w",
+				Usage: "Watch the routes file and run the test again on every change",
+			},
+			cli.StringFlag{
+				Name:  "expect-route",
+				Usage: "Exit with success only if the route with `ID` matches",
+			},
+			cli.BoolFlag{
+				Name:  "expect-no-match",
+				Usage: "Exit with success only if no route matches",
+			},
+			outputFlag,
+			colorFlag,
+			noColorFlag,
 		},
 		Action: func(c *cli.Context) error {
-			conf := o.ConfigLoader.Load(o.ConfigFile)
-			routesFile := c.Args().First()
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			routesFile, args := routesFileAndCurlArgs(c)
+			if routesFile, err = conf.routesFile(routesFile); err != nil {
+				return loadError(err)
+			}
 			if routesFile == "" {
-				return fmt.Errorf("A routes file must be provided")
+				return usageError("A routes file must be provided")
+			}
+			if c.String("expect-route") != "" && c.Bool("expect-no-match") {
+				return usageError("--expect-route and --expect-no-match can't be used together")
 			}
 
-			m, err := matcher.New(&matcher.Options{
-				RoutesFile:  routesFile,
-				MockFilters: conf.CustomFilters,
-				Verbose:     c.Bool("v"),
-			})
+			format, err := outputFormat(c)
 			if err != nil {
-				return err
+				return usageError("%v", err)
+			}
+			if c.Bool("quiet") && format != outputText {
+				return usageError("--quiet can't be used together with --output %s", format)
+			}
+			style, err := outputStyle(c, c.App.Writer)
+			if err != nil {
+				return usageError("%v", err)
 			}
 
-			res := m.Test(&matcher.RequestAttributes{
-				Method:  strings.ToUpper(c.String("m")),
-				Path:    c.String("p"),
-				Headers: headers(c.StringSlice("H")),
-			})
+			attrs, err := requestAttributes(c, conf, args, diagnostics(c, format))
+			if err != nil {
+				return err
+			}
+			if verbosity(c) > matcher.VerbositySilent && (c.Bool("stdin-request") || len(args) > 0) {
+				redacted := append(append([]string{}, matcher.DefaultRedactedHeaders...), conf.RedactedHeaders...)
+				fmt.Fprintf(c.App.ErrWriter, "parsed request:\n%s\n", matcher.RawRequest(attrs, redacted))
+			}
 
-			out := res.PrettyPrintLines()
-			route := res.Route()
-			for _, line := range out {
-				log.Println(line)
+			check := func() error {
+				return runTest(c, conf, routesFile, format, style, attrs)
 			}
-			if route == nil {
-				return fmt.Errorf("no match")
+			if c.Bool("watch") {
+				return newWatcher(diagnostics(c, format), routesFile).run(interrupted(), check)
 			}
-			return nil
+			return check()
 		},
 	}
 }
 
-// headers parses a list of strings representing http headers
-// written in a key=value format (eg. ["Content=text", "Accept=json"])
-// and transform them to a map (eg. {"Content":"text", "Accept": "json"}).
-// It discards items with an invalid format (eg. ["Content="])
-func headers(pairs []string) map[string]string {
+// routesFileAndCurlArgs returns the routes file given by the --routes flag
+// or by the first argument, and the curl arguments following it.
+// The routes file is empty if only curl arguments are given after --.
+func routesFileAndCurlArgs(c *cli.Context) (string, []string) {
+	args := []string(c.Args())
+	routesFile := c.String("routes")
+	if routesFile == "" && len(args) > 0 && args[0] != "--" {
+		routesFile, args = args[0], args[1:]
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	return routesFile, args
+}
+
+// requestAttributes returns the request attributes to test given by the
+// command flags, parsed from the standard input with --stdin-request or
+// translated from curl arguments. Host, query and header flags add to the
+// parsed request, the configured host is used when no host is given.
+// Curl warnings are written to diag.
+func requestAttributes(c *cli.Context, conf config, curlArgs []string, diag io.Writer) (*matcher.RequestAttributes, error) {
+	if !c.Bool("stdin-request") && len(curlArgs) == 0 {
+		host := c.String("host")
+		if host == "" {
+			host = conf.Host
+		}
+		hs, err := headers(c.StringSlice("H"))
+		if err != nil {
+			return nil, usageError("%v", err)
+		}
+		return &matcher.RequestAttributes{
+			Method:  strings.ToUpper(c.String("m")),
+			Path:    c.String("p"),
+			Host:    host,
+			Query:   pairs(c.StringSlice("Q")),
+			Headers: hs,
+		}, nil
+	}
+
+	if c.Bool("stdin-request") && len(curlArgs) > 0 {
+		return nil, usageError("--stdin-request can't be used together with curl arguments")
+	}
+	if c.IsSet("path") || c.IsSet("method") {
+		return nil, usageError("--path and --method can't be used together with --stdin-request or curl arguments")
+	}
+
+	var attrs *matcher.RequestAttributes
+	var err error
+	if len(curlArgs) > 0 {
+		var warnings []string
+		attrs, warnings, err = matcher.ParseCurlArgs(curlArgs)
+		for _, w := range warnings {
+			fmt.Fprintf(diag, "warning: %s\n", w)
+		}
+	} else {
+		attrs, err = matcher.ParseRawRequest(stdin)
+	}
+	if err != nil {
+		return nil, usageError("%v", err)
+	}
+	if host := c.String("host"); host != "" {
+		attrs.Host = host
+	} else if attrs.Host == "" {
+		attrs.Host = conf.Host
+	}
+	for key, value := range pairs(c.StringSlice("Q")) {
+		attrs.Query[key] = value
+	}
+	hs, err := headers(c.StringSlice("H"))
+	if err != nil {
+		return nil, usageError("%v", err)
+	}
+	for key, value := range hs {
+		attrs.Headers[key] = value
+	}
+	return attrs, nil
+}
+
+// runTest runs a single test of the request attributes against routesFile
+// and prints the result in the given format, the text one decorated by style.
+// It returns an error with exitFailure code when the expectation given by the
+// flags fails (by default that a route matches).
+func runTest(c *cli.Context, conf config, routesFile string, format string, style matcher.Style, attrs *matcher.RequestAttributes) error {
+	m, err := loadMatcher(c, &matcher.Options{
+		RoutesFile:          routesFile,
+		MockFilters:         append(conf.CustomFilters, list(c.String("mock-filters"))...),
+		IgnoreTrailingSlash: c.Bool("ignore-trailing-slash"),
+		OnLoadProgress:      loadProgress(c.App.ErrWriter),
+		Verbosity:           verbosity(c),
+		Log:                 c.App.ErrWriter,
+		RedactedHeaders:     conf.RedactedHeaders,
+	})
+	if err != nil {
+		return loadError(err)
+	}
+	defer m.Close()
+
+	res := m.Test(attrs)
+
+	route := res.Route()
+	switch {
+	case c.Bool("quiet"):
+		if route != nil {
+			fmt.Fprintln(c.App.Writer, route.Id)
+		}
+	case isDataOutput(format):
+		if err := writeData(c.App.Writer, format, res); err != nil {
+			return err
+		}
+	default:
+		for _, line := range res.PrettyPrintLinesWith(style) {
+			fmt.Fprintln(c.App.Writer, line)
+		}
+		if route == nil && res.Err() == nil {
+			fmt.Fprintln(c.App.Writer, style.DecorateNoMatch("NO MATCH"))
+		}
+	}
+	// an error isn't a no match, whatever the expectation
+	if err := res.Err(); err != nil {
+		return failure("the request couldn't be tested: %v", err)
+	}
+	return expect(c, route)
+}
+
+// expect checks the matching route against the expectation given by the command flags
+func expect(c *cli.Context, route *eskip.Route) error {
+	expectedID := c.String("expect-route")
+	switch {
+	case c.Bool("expect-no-match"):
+		if route != nil {
+			return failure("expected no match but route %s matches", route.Id)
+		}
+	case expectedID != "":
+		if route == nil {
+			return failure("expected route %s to match but there is no match", expectedID)
+		}
+		if route.Id != expectedID {
+			return failure("expected route %s to match but route %s matches", expectedID, route.Id)
+		}
+	case route == nil:
+		return failure("no match")
+	}
+	return nil
+}
+
+// list parses a comma separated list of names (eg. "foo, bar")
+// discarding empty items
+func list(value string) []string {
+	names := []string{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// pairs parses a list of strings written in a key=value format
+// (eg. ["q=foo", "page=2"]) and transform them to a map
+// (eg. {"q":"foo", "page":"2"}).
+// It discards items with an invalid format (eg. ["q"])
+func pairs(items []string) map[string]string {
 	m := make(map[string]string)
-	for _, pair := range pairs {
+	for _, item := range items {
 		// Use SplitN to handle "=" symbol in the value
 		// bar=bar=foo -> "bar": "bar=foo"
-		parts := strings.SplitN(pair, "=", 2)
-		if len(parts) >= 2 {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) == 2 && parts[0] != "" {
 			m[parts[0]] = parts[1]
 		}
 	}
 	return m
 }
+
+// headers parses a list of strings representing http headers
+// written in a key=value (eg. ["Content=text", "Accept=json"])
+// or curl like "Key: value" format (eg. ["Accept: json"])
+// and transform them to a map (eg. {"Content":"text", "Accept": "json"}).
+// The separator appearing first in the item wins, so values can contain
+// the other one (eg. "Authorization=Basic a:b"), and empty (eg. "Content=").
+// It fails on an item without separator or key (eg. "Content" or ": json").
+func headers(items []string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, item := range items {
+		sep := strings.IndexAny(item, "=:")
+		if sep < 0 {
+			return nil, fmt.Errorf("invalid header %q, use key=value or \"Key: value\"", item)
+		}
+		key := strings.TrimSpace(item[:sep])
+		if key == "" {
+			return nil, fmt.Errorf("invalid header %q, the name is empty", item)
+		}
+		value := item[sep+1:]
+		if item[sep] == ':' {
+			value = strings.TrimSpace(value)
+		}
+		m[key] = value
+	}
+	return m, nil
+}
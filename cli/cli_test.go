@@ -1,7 +1,12 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
+	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/urfave/cli"
@@ -40,10 +45,56 @@ func TestApp(t *testing.T) {
 				"bar=foo",
 			},
 		},
+		{
+			name: "routes flag",
+			args: []string{"eskip-match", "test", "--routes", "testdata/routes.eskip", "-p", "/bar"},
+		},
+		{
+			name: "host",
+			args: []string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/orders", "--host", "api.example.org"},
+		},
+		{
+			name: "wrong host",
+			err:  true,
+			args: []string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/orders", "--host", "www.example.org"},
+		},
+		{
+			name: "query",
+			args: []string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/search", "-Q", "q=shoes"},
+		},
+		{
+			name: "missing query",
+			err:  true,
+			args: []string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/search"},
+		},
+		{
+			name: "curl style header",
+			args: []string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/tenant", "-H", "X-Tenant: acme"},
+		},
+		{
+			name: "trailing slash",
+			err:  true,
+			args: []string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/bar/"},
+		},
+		{
+			name: "ignore trailing slash",
+			args: []string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/bar/", "--ignore-trailing-slash"},
+		},
+		{
+			name: "unmocked filter",
+			err:  true,
+			args: []string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/mocked"},
+		},
+		{
+			name: "mock filters",
+			args: []string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/mocked", "--mock-filters", "foofilter, mockedfilter"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			app.Writer = &out
 			err := app.Run(tt.args)
 			if tt.err && err == nil {
 				t.Error("expecting error but got nil")
@@ -51,8 +102,143 @@ func TestApp(t *testing.T) {
 			if tt.err == false && err != nil {
 				t.Error("expecting match but got error", err)
 			}
+			if tt.err == false && !strings.Contains(out.String(), "matching route id") {
+				t.Errorf("expecting matching route in the output but got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestAppOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		err    bool
+		want   string
+	}{
+		{
+			name:   "json",
+			format: "json",
+			want:   `"routeId": "bar"`,
+		},
+		{
+			name:   "yaml",
+			format: "yaml",
+			want:   "routeId: bar\n",
+		},
+		{
+			name:   "text",
+			format: "text",
+			want:   "matching route id: bar\n",
+		},
+		{
+			name:   "unsupported",
+			format: "xml",
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, stderr bytes.Buffer
+			app := NewApp()
+			app.Writer = &out
+			app.ErrWriter = &stderr
+			err := app.Run([]string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/bar", "-o", tt.format})
+			if tt.err {
+				if err == nil {
+					t.Error("expecting error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("expecting match but got error", err)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("expecting output to contain %q but got %q", tt.want, out.String())
+			}
 		})
 	}
+
+	// data output must be valid and contain only the document
+	var out bytes.Buffer
+	app := NewApp()
+	app.Writer = &out
+	err := app.Run([]string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/foofoo", "-o", "json"})
+	if err == nil {
+		t.Error("expecting no match error")
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &data); err != nil {
+		t.Fatalf("expecting json output but got %q: %v", out.String(), err)
+	}
+	if data["matched"] != false {
+		t.Errorf("expecting matched to be false but got %v", data["matched"])
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []string
+		want  map[string]string
+		err   string
+	}{
+		{
+			name:  "key=value",
+			items: []string{"Accept=json", "Authorization=Basic a:b"},
+			want:  map[string]string{"Accept": "json", "Authorization": "Basic a:b"},
+		},
+		{
+			name:  "curl style",
+			items: []string{"X-Tenant: acme", "X-Forwarded-Host:a=b"},
+			want:  map[string]string{"X-Tenant": "acme", "X-Forwarded-Host": "a=b"},
+		},
+		{
+			name:  "empty value",
+			items: []string{"Accept=", "X-Tenant:"},
+			want:  map[string]string{"Accept": "", "X-Tenant": ""},
+		},
+		{
+			name:  "no separator",
+			items: []string{"Accept=json", "X-Tenant"},
+			err:   `invalid header "X-Tenant", use key=value or "Key: value"`,
+		},
+		{
+			name:  "no key",
+			items: []string{": json"},
+			err:   `invalid header ": json", the name is empty`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := headers(tt.items)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("expected error %q but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	got := list(" foo,bar ,, baz")
+	want := []string{"foo", "bar", "baz"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v but got %v", want, got)
+	}
+	if len(list("")) != 0 {
+		t.Error("expected empty list")
+	}
 }
 
 func TestNewTestCommand(t *testing.T) {
@@ -98,7 +284,9 @@ func TestNewTestCommand(t *testing.T) {
 			set.Parse(tt.args)
 			tt.setFlags(set)
 
-			ctx := cli.NewContext(nil, set, nil)
+			app := cli.NewApp()
+			app.Writer = ioutil.Discard
+			ctx := cli.NewContext(app, set, nil)
 			ctx.Command = testcommand
 
 			err := fn(ctx)
@@ -109,3 +297,275 @@ func TestNewTestCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestStdinRequest(t *testing.T) {
+	raw := "GET /orders HTTP/1.1\r\nHost: api.example.org\r\nAuthorization: Bearer secret\r\n\r\n"
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		code  int
+		out   string
+		diag  string
+	}{
+		{
+			name:  "match",
+			args:  []string{"test", "-r", "testdata/routes.eskip", "--stdin-request"},
+			stdin: raw,
+			out:   "matching route id: orders",
+		},
+		{
+			name:  "flags add to the request",
+			args:  []string{"test", "-r", "testdata/routes.eskip", "--stdin-request", "-H", "X-Tenant: acme"},
+			stdin: "GET /tenant HTTP/1.1\n",
+			out:   "matching route id: tenant",
+		},
+		{
+			name:  "verbose echoes the redacted request",
+			args:  []string{"test", "-r", "testdata/routes.eskip", "-v", "--stdin-request", "-o", "json"},
+			stdin: raw,
+			out:   `"routeId": "orders"`,
+			diag:  "parsed request:\nGET /orders HTTP/1.1\nHost: api.example.org\nAuthorization: <redacted>\n",
+		},
+		{
+			name:  "conflicting path",
+			args:  []string{"test", "-r", "testdata/routes.eskip", "--stdin-request", "-p", "/bar"},
+			stdin: raw,
+			code:  exitUsage,
+		},
+		{
+			name:  "conflicting method",
+			args:  []string{"test", "-r", "testdata/routes.eskip", "--stdin-request", "--method", "POST"},
+			stdin: raw,
+			code:  exitUsage,
+		},
+		{
+			name:  "invalid request",
+			args:  []string{"test", "-r", "testdata/routes.eskip", "--stdin-request"},
+			stdin: "/orders",
+			code:  exitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdin = strings.NewReader(tt.stdin)
+			exitCode = 0
+			app := NewApp()
+			out := &bytes.Buffer{}
+			diag := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = diag
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+
+			if exitCode != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, exitCode)
+			}
+			if !strings.Contains(out.String(), tt.out) {
+				t.Errorf("expected output to contain %q but got:\n%s", tt.out, out.String())
+			}
+			if !strings.Contains(diag.String(), tt.diag) {
+				t.Errorf("expected diagnostics to contain %q but got:\n%s", tt.diag, diag.String())
+			}
+		})
+	}
+}
+
+func TestCurlArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+		out  string
+		diag string
+	}{
+		{
+			name: "routes argument",
+			args: []string{"test", "testdata/routes.eskip", "--", "-H", "Accept: json", "https://api.example.org/orders"},
+			out:  "matching route id: orders",
+		},
+		{
+			name: "routes flag",
+			args: []string{"test", "-r", "testdata/routes.eskip", "--", "-X", "POST", "-d", `{"a":1}`, "http://localhost/tenant", "-H", "X-Tenant: acme"},
+			out:  "request: POST /tenant",
+		},
+		{
+			name: "unsupported flags warn",
+			args: []string{"test", "-r", "testdata/routes.eskip", "--", "-sS", "--http2", "http://localhost/bar"},
+			out:  "matching route id: bar",
+			diag: "warning: curl flag -s ignored\nwarning: curl flag -S ignored\nwarning: unsupported curl flag --http2 ignored\n",
+		},
+		{
+			name: "conflicting path",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-p", "/bar", "--", "http://localhost/bar"},
+			code: exitUsage,
+		},
+		{
+			name: "conflicting stdin request",
+			args: []string{"test", "-r", "testdata/routes.eskip", "--stdin-request", "--", "http://localhost/bar"},
+			code: exitUsage,
+		},
+		{
+			name: "missing url",
+			args: []string{"test", "-r", "testdata/routes.eskip", "--", "-X", "POST"},
+			code: exitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode = 0
+			app := NewApp()
+			out := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = &bytes.Buffer{}
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+
+			if exitCode != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, exitCode)
+			}
+			if !strings.Contains(out.String(), tt.out) {
+				t.Errorf("expected output to contain %q but got:\n%s", tt.out, out.String())
+			}
+			// in text format diagnostics are written with the output
+			if !strings.HasPrefix(out.String(), tt.diag) {
+				t.Errorf("expected diagnostics %q but got:\n%s", tt.diag, out.String())
+			}
+		})
+	}
+}
+
+// mockedWarning the route ignored in testdata/routes.eskip when its filter isn't mocked
+const mockedWarning = "testdata/routes.eskip:6: error: mocked: unknown filter mockedfilter, the route is ignored [unknown-filter]\n"
+
+func TestQuiet(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "match",
+			args:   []string{"test", "-r", "testdata/routes.eskip", "-p", "/bar", "--quiet"},
+			stdout: "bar\n",
+			stderr: mockedWarning,
+		},
+		{
+			name:   "no match",
+			args:   []string{"test", "-r", "testdata/routes.eskip", "-p", "/foofoo", "-q"},
+			code:   exitFailure,
+			stdout: "",
+			stderr: mockedWarning,
+		},
+		{
+			name:   "expected route",
+			args:   []string{"test", "-r", "testdata/routes.eskip", "-p", "/bar", "-q", "--expect-route", "bar"},
+			stdout: "bar\n",
+			stderr: mockedWarning,
+		},
+		{
+			name:   "unexpected route",
+			args:   []string{"test", "-r", "testdata/routes.eskip", "-p", "/bar", "-q", "--expect-route", "orders"},
+			code:   exitFailure,
+			stdout: "bar\n",
+			stderr: mockedWarning,
+		},
+		{
+			name:   "warnings go to stderr",
+			args:   []string{"test", "-r", "testdata/routes.eskip", "-q", "--", "-s", "http://localhost/bar"},
+			stdout: "bar\n",
+			stderr: "warning: curl flag -s ignored\n" + mockedWarning,
+		},
+		{
+			name: "data output",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-p", "/bar", "-q", "-o", "json"},
+			code: exitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode = 0
+			app := NewApp()
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			app.Writer = stdout
+			app.ErrWriter = stderr
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+
+			if exitCode != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, exitCode)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("expected stdout %q but got %q", tt.stdout, stdout.String())
+			}
+			if stderr.String() != tt.stderr {
+				t.Errorf("expected stderr %q but got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestVerbosity(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    []string
+		contains []string
+		missing  []string
+	}{
+		{
+			name:    "silent",
+			missing: []string{"tested request", "routing info", "near miss", "route table"},
+		},
+		{
+			name:     "info",
+			flags:    []string{"-v"},
+			contains: []string{"tested request:\n  GET /bar HTTP/1.1\n", "matching route: bar (testdata/routes.eskip:1)\n", "routing info: route settings applied\n"},
+			missing:  []string{"near miss", "route table"},
+		},
+		{
+			name:     "debug",
+			flags:    []string{"-vv"},
+			contains: []string{"matching route: bar", "routing info: route settings applied\n", "near miss: bar_header"},
+			missing:  []string{"route table"},
+		},
+		{
+			name:     "trace",
+			flags:    []string{"-vvv"},
+			contains: []string{"matching route: bar", "near miss: bar_header", "route table:", "  testdata/routes.eskip:3: orders: "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode = 0
+			app := NewApp()
+			out := &bytes.Buffer{}
+			diag := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = diag
+			args := append([]string{"eskip-match", "test", "-r", "testdata/routes.eskip", "-p", "/bar", "-o", "json"}, tt.flags...)
+			app.Run(args)
+
+			if exitCode != exitOK {
+				t.Errorf("expected exit code %d but got %d", exitOK, exitCode)
+			}
+			var data map[string]interface{}
+			if err := json.Unmarshal(out.Bytes(), &data); err != nil {
+				t.Errorf("expected only json on stdout but got %q: %v", out.String(), err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(diag.String(), s) {
+					t.Errorf("expected diagnostics to contain %q but got:\n%s", s, diag.String())
+				}
+			}
+			for _, s := range tt.missing {
+				if strings.Contains(diag.String(), s) {
+					t.Errorf("expected diagnostics not to contain %q but got:\n%s", s, diag.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// color modes
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// colorFlag chooses when the human output is colorized
+var colorFlag = cli.StringFlag{
+	Name:  "color",
+	Value: colorAuto,
+	Usage: "Colorize the output `WHEN`: auto (stdout is a terminal and NO_COLOR isn't set), always or never",
+}
+
+// noColorFlag disables colors, like --color=never
+var noColorFlag = cli.BoolFlag{
+	Name:  "no-color",
+	Usage: "Don't colorize the output",
+}
+
+// isTerminal true if w is attached to a terminal, replaced by tests
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && terminal.IsTerminal(int(f.Fd()))
+}
+
+// outputStyle returns the style of the human output written to w according
+// to the command flags: --no-color and --color=never disable colors,
+// --color=always forces them and by default colors are used only when w is
+// a terminal and the NO_COLOR environment variable isn't set
+func outputStyle(c *cli.Context, w io.Writer) (matcher.Style, error) {
+	if c.Bool("no-color") {
+		return matcher.PlainStyle, nil
+	}
+	switch mode := c.String("color"); mode {
+	case colorAlways:
+		return matcher.ColorStyle, nil
+	case colorNever:
+		return matcher.PlainStyle, nil
+	case colorAuto, "":
+		if os.Getenv("NO_COLOR") != "" || !isTerminal(w) {
+			return matcher.PlainStyle, nil
+		}
+		return matcher.ColorStyle, nil
+	default:
+		return matcher.PlainStyle, fmt.Errorf("unsupported color mode %q, use one of %s, %s, %s",
+			mode, colorAuto, colorAlways, colorNever)
+	}
+}
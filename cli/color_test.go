@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+)
+
+func TestOutputStyle(t *testing.T) {
+	defer func(f func(io.Writer) bool) { isTerminal = f }(isTerminal)
+	defer os.Setenv("NO_COLOR", os.Getenv("NO_COLOR"))
+
+	tests := []struct {
+		name     string
+		args     []string
+		terminal bool
+		noColor  string
+		color    bool
+		err      bool
+	}{
+		{name: "not a terminal"},
+		{name: "terminal", terminal: true, color: true},
+		{name: "NO_COLOR", terminal: true, noColor: "1"},
+		{name: "no-color flag", args: []string{"--no-color"}, terminal: true},
+		{name: "never", args: []string{"--color", "never"}, terminal: true},
+		{name: "always", args: []string{"--color", "always"}, color: true},
+		{name: "always wins over NO_COLOR", args: []string{"--color", "always"}, noColor: "1", color: true},
+		{name: "no-color wins over always", args: []string{"--color", "always", "--no-color"}},
+		{name: "unsupported", args: []string{"--color", "sometimes"}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isTerminal = func(io.Writer) bool { return tt.terminal }
+			os.Setenv("NO_COLOR", tt.noColor)
+
+			set := flag.NewFlagSet("test", 0)
+			colorFlag.Apply(set)
+			noColorFlag.Apply(set)
+			if err := set.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			style, err := outputStyle(cli.NewContext(nil, set, nil), &bytes.Buffer{})
+			if tt.err {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := style.Match != nil; got != tt.color {
+				t.Errorf("expected color %v but got %v", tt.color, got)
+			}
+		})
+	}
+}
+
+func TestColorOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		golden string
+	}{
+		{
+			name:   "match colored",
+			args:   []string{"test", "-r", "testdata/routes.eskip", "-p", "/tenant", "-H", "X-Tenant: acme", "--color=always"},
+			golden: "test-match-color.txt",
+		},
+		{
+			name:   "match plain",
+			args:   []string{"test", "-r", "testdata/routes.eskip", "-p", "/tenant", "-H", "X-Tenant: acme", "--color=never"},
+			golden: "test-match-plain.txt",
+		},
+		{
+			name:   "no match colored",
+			args:   []string{"test", "-r", "testdata/routes.eskip", "-p", "/foofoo", "--color=always"},
+			golden: "test-nomatch-color.txt",
+		},
+		{
+			name:   "no match plain",
+			args:   []string{"test", "-r", "testdata/routes.eskip", "-p", "/foofoo", "--no-color"},
+			golden: "test-nomatch-plain.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewApp()
+			out := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = &bytes.Buffer{}
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+			golden(t, tt.golden, out.String())
+		})
+	}
+}
+
+func TestReplColors(t *testing.T) {
+	var out bytes.Buffer
+	r := &repl{
+		out:     &out,
+		style:   matcher.ColorStyle,
+		explain: true,
+		newMatcher: func() (matcher.Matcher, error) {
+			return matcher.New(&matcher.Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"mockedfilter"}})
+		},
+	}
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	r.eval("/tenant X-Tenant:other")
+	golden(t, "repl-nomatch-color.txt", out.String())
+}
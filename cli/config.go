@@ -1,52 +1,232 @@
 package cli
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/jinzhu/configor"
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
 )
 
-// DefaultFile default configuration file
-const configDefaultFile = ".eskip-match.yml"
+// configDefaultFile default configuration file
+const configDefaultFile = ".eskip-match.yaml"
 
-// Config root element for configuration
+// configLegacyFile configuration file name still searched for backward compatibility
+const configLegacyFile = ".eskip-match.yml"
+
+// configEnvPrefix prefix of the environment variables overriding the configuration
+const configEnvPrefix = "EM_"
+
+// Config root element for configuration.
+// The values are used when the corresponding flags aren't given,
+// the environment variables (eg. EM_HOST) override the file ones.
 type config struct {
-	CustomFilters []string
+	// Routes routes file or glob pattern, relative to the configuration file
+	Routes string `yaml:"routes,omitempty"`
+	// Host request host used when the request doesn't specify one
+	Host string `yaml:"host,omitempty"`
+	// CustomFilters names of the custom filters to mock
+	CustomFilters []string `yaml:"customfilters,omitempty"`
+	// RedactedHeaders headers redacted when requests are printed, in addition to the default ones
+	RedactedHeaders []string `yaml:"redactedheaders,omitempty"`
+	// Reports reports written by the suite command as FORMAT=FILE, files relative to the configuration file
+	Reports []string `yaml:"reports,omitempty"`
+	// Deprecations filters and predicates the lint command reports as
+	// deprecated, in addition to or overriding the default ones
+	Deprecations []matcher.DeprecationRule `yaml:"deprecations,omitempty"`
+
+	// file the configuration was loaded from, empty if none
+	file string
+}
+
+// routesFiles returns the files matching the configured routes pattern
+func (c config) routesFiles() ([]string, error) {
+	if c.Routes == "" {
+		return nil, nil
+	}
+	files, err := filepath.Glob(c.Routes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid routes pattern %q: %v", c.Routes, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no routes file matches %q", c.Routes)
+	}
+	return files, nil
+}
+
+// routesFile returns the routes file given by the command, or the
+// configured one when empty. It fails if the configured pattern doesn't
+// match exactly one file.
+func (c config) routesFile(given string) (string, error) {
+	if given != "" {
+		return given, nil
+	}
+	files, err := c.routesFiles()
+	if err != nil || len(files) == 0 {
+		return "", err
+	}
+	if len(files) > 1 {
+		return "", fmt.Errorf("routes pattern %q matches %d files, a single routes file is supported", c.Routes, len(files))
+	}
+	return files[0], nil
+}
+
+// deprecations returns the default deprecations merged with the configured
+// ones, it fails on a configured rule without a name or with an unsupported
+// kind or severity
+func (c config) deprecations() ([]matcher.DeprecationRule, error) {
+	for _, d := range c.Deprecations {
+		if d.Name == "" {
+			return nil, fmt.Errorf("deprecation without a name")
+		}
+		if d.Kind != matcher.DeprecatedFilter && d.Kind != matcher.DeprecatedPredicate {
+			return nil, fmt.Errorf("unsupported deprecation kind %q of %s, use one of filter or predicate", d.Kind, d.Name)
+		}
+		if d.Severity != "" && d.Severity != matcher.SeverityWarning && d.Severity != matcher.SeverityError {
+			return nil, fmt.Errorf("unsupported deprecation severity %q of %s, use one of warning or error", d.Severity, d.Name)
+		}
+	}
+	return matcher.MergeDeprecations(matcher.DefaultDeprecations(), c.Deprecations...), nil
 }
 
 // Loader ...
 type configLoader interface {
-	Load(file string) config
+	Load(file string) (config, error)
 }
 
-type configorLoader struct {
-	defaultFile string
+type fileConfigLoader struct {
+	defaultFiles []string
 }
 
-// NewLoader ...
-func newConfigLoader(defaultFile string) configLoader {
-	return &configorLoader{
-		defaultFile,
+// NewLoader creates a loader searching the default files from the
+// current directory upward when no configuration file is given
+func newConfigLoader(defaultFiles ...string) configLoader {
+	return &fileConfigLoader{
+		defaultFiles,
 	}
 }
 
-// Load configuration from a file
-func (c *configorLoader) Load(file string) config {
+// Load configuration from a file, the default one when file is empty,
+// and the environment. Unknown keys are errors.
+func (l *fileConfigLoader) Load(file string) (config, error) {
 	var conf config
-	configorConf := &configor.Config{ENVPrefix: "EM"}
-
 	if file == "" {
-		// search for default configuration file
-		file = c.defaultFile
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			file = ""
+		file = l.discover()
+	}
+
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return conf, err
+		}
+		if err := yaml.UnmarshalStrict(b, &conf); err != nil {
+			return conf, fmt.Errorf("%s: %v", file, err)
+		}
+		conf.file = file
+		conf.Routes = relativeTo(file, conf.Routes)
+		for i, report := range conf.Reports {
+			if parts := strings.SplitN(report, "=", 2); len(parts) == 2 {
+				conf.Reports[i] = parts[0] + "=" + relativeTo(file, parts[1])
+			}
 		}
 	}
 
-	if file == "" {
-		configor.New(configorConf).Load(&conf)
-	} else {
-		configor.New(configorConf).Load(&conf, file)
+	if v, ok := os.LookupEnv(configEnvPrefix + "ROUTES"); ok {
+		conf.Routes = v
+	}
+	if v, ok := os.LookupEnv(configEnvPrefix + "HOST"); ok {
+		conf.Host = v
+	}
+	if v, ok := os.LookupEnv(configEnvPrefix + "CUSTOMFILTERS"); ok {
+		conf.CustomFilters = list(v)
+	}
+	if v, ok := os.LookupEnv(configEnvPrefix + "REDACTEDHEADERS"); ok {
+		conf.RedactedHeaders = list(v)
+	}
+	if v, ok := os.LookupEnv(configEnvPrefix + "REPORTS"); ok {
+		conf.Reports = list(v)
+	}
+	return conf, nil
+}
+
+// discover returns the first default file found in the current
+// directory or its parents, empty if none
+func (l *fileConfigLoader) discover() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		for _, name := range l.defaultFiles {
+			if name == "" {
+				continue
+			}
+			file := filepath.Join(dir, name)
+			if _, err := os.Stat(file); err == nil {
+				return relativePath(file)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// relativeTo resolves path relative to the directory of file
+func relativeTo(file string, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(file), path)
+}
+
+// relativePath returns path relative to the current directory when possible,
+// to keep the file names short in the messages
+func relativePath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+func newConfigCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:  "config",
+		Usage: "Inspects the configuration",
+		Subcommands: []cli.Command{
+			{
+				Name:         "show",
+				Usage:        "Prints the effective configuration, the configuration file merged with the environment",
+				OnUsageError: onUsageError,
+				Action: func(c *cli.Context) error {
+					conf, err := o.ConfigLoader.Load(o.ConfigFile)
+					if err != nil {
+						return loadError(err)
+					}
+					if conf.file != "" {
+						fmt.Fprintf(c.App.Writer, "# configuration file: %s\n", conf.file)
+					} else {
+						fmt.Fprintln(c.App.Writer, "# no configuration file found")
+					}
+					b, err := yaml.Marshal(conf)
+					if err != nil {
+						return err
+					}
+					_, err = c.App.Writer.Write(b)
+					return err
+				},
+			},
+		},
 	}
-	return conf
 }
@@ -1,6 +1,12 @@
 package cli
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -31,10 +37,220 @@ func TestLoader(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			loader := newConfigLoader(tt.defaultFile)
-			config := loader.Load(tt.file)
+			config, err := loader.Load(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
 			if len(config.CustomFilters) != tt.customFiltersLen {
 				t.Errorf("expect CustomFilters to have been populated by %d items but got %d", tt.customFiltersLen, len(config.CustomFilters))
 			}
 		})
 	}
 }
+
+// chdir changes the working directory, the returned function restores it
+func chdir(t *testing.T, dir string) func() {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	return func() { os.Chdir(wd) }
+}
+
+// setenv sets an environment variable, the returned function restores it
+func setenv(key, value string) func() {
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// configProject creates a directory with a configuration file and
+// a routes directory, it returns the project and the routes directory.
+// The project must be removed by the caller.
+func configProject(t *testing.T, conf string) (string, string) {
+	t.Helper()
+	project, err := ioutil.TempDir("", "eskip-match-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := filepath.Join(project, "routes")
+	if err := os.Mkdir(routes, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(project, configDefaultFile), []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile("testdata/routes.eskip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(routes, "api.eskip"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return project, routes
+}
+
+func TestLoaderDiscovery(t *testing.T) {
+	project, routes := configProject(t, "routes: routes/*.eskip\nhost: api.example.org\n")
+	defer os.RemoveAll(project)
+	defer chdir(t, routes)()
+
+	conf, err := newConfigLoader(configDefaultFile, configLegacyFile).Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.file != filepath.Join("..", configDefaultFile) {
+		t.Errorf("expected the configuration file in the parent directory but got %q", conf.file)
+	}
+	file, err := conf.routesFile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file != filepath.Join("..", "routes", "api.eskip") {
+		t.Errorf("expected the routes relative to the configuration file but got %q", file)
+	}
+	if conf.Host != "api.example.org" {
+		t.Errorf("expected host api.example.org but got %q", conf.Host)
+	}
+}
+
+func TestLoaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		conf string
+		err  string
+	}{
+		{"unknown key", "routes: a.eskip\nhosts: api.example.org\n", "field hosts not found"},
+		{"invalid yaml", "routes: [", "yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, _ := configProject(t, tt.conf)
+			defer os.RemoveAll(project)
+			_, err := newConfigLoader().Load(filepath.Join(project, configDefaultFile))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error containing %q but got %v", tt.err, err)
+			}
+		})
+	}
+
+	if _, err := newConfigLoader().Load("testdata/missing.yaml"); err == nil {
+		t.Error("expected an error loading a missing file")
+	}
+}
+
+func TestLoaderEnvironment(t *testing.T) {
+	defer setenv("EM_HOST", "env.example.org")()
+	defer setenv("EM_CUSTOMFILTERS", "a, b")()
+	conf, err := newConfigLoader().Load("testdata/config.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Host != "env.example.org" {
+		t.Errorf("expected the environment host but got %q", conf.Host)
+	}
+	if !reflect.DeepEqual(conf.CustomFilters, []string{"a", "b"}) {
+		t.Errorf("expected the environment custom filters but got %v", conf.CustomFilters)
+	}
+}
+
+func TestConfigRoutesFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes string
+		given  string
+		want   string
+		err    bool
+	}{
+		{name: "given wins", routes: "testdata/*.eskip", given: "a.eskip", want: "a.eskip"},
+		{name: "not configured"},
+		{name: "single match", routes: "testdata/routes.eskip", want: "testdata/routes.eskip"},
+		{name: "several matches", routes: "testdata/*.eskip", err: true},
+		{name: "no match", routes: "testdata/missing-*.eskip", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := config{Routes: tt.routes}.routesFile(tt.given)
+			if (err != nil) != tt.err {
+				t.Fatalf("expected error %v but got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfiguredCommands(t *testing.T) {
+	project, _ := configProject(t, "routes: routes/api.eskip\nhost: api.example.org\nredactedheaders: [X-Tenant]\n")
+	defer os.RemoveAll(project)
+	defer chdir(t, project)()
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		out  string
+		diag string
+	}{
+		{
+			name: "routes and host from the configuration",
+			args: []string{"test", "-p", "/orders"},
+			out:  "matching route id: orders",
+		},
+		{
+			name: "flags win",
+			args: []string{"test", "-p", "/orders", "--host", "other.example.org"},
+			code: exitFailure,
+			out:  "NO MATCH",
+		},
+		{
+			name: "configured redacted headers",
+			args: []string{"test", "-p", "/tenant", "-H", "X-Tenant: acme", "-v", "-o", "json"},
+			diag: "X-Tenant: <redacted>",
+		},
+		{
+			name: "show",
+			args: []string{"config", "show"},
+			out:  "# configuration file: " + configDefaultFile + "\nroutes: routes/api.eskip\nhost: api.example.org\nredactedheaders:\n- X-Tenant\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode = 0
+			app := NewApp()
+			out := &bytes.Buffer{}
+			diag := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = diag
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+
+			if exitCode != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, exitCode)
+			}
+			if !strings.Contains(out.String(), tt.out) {
+				t.Errorf("expected output to contain %q but got:\n%s", tt.out, out.String())
+			}
+			if !strings.Contains(diag.String(), tt.diag) {
+				t.Errorf("expected diagnostics to contain %q but got:\n%s", tt.diag, diag.String())
+			}
+		})
+	}
+}
+
+func TestConfigFlag(t *testing.T) {
+	code, err := run("-c", "testdata/missing.yaml", "test", "testdata/routes.eskip", "-p", "/bar")
+	if code != exitLoad {
+		t.Errorf("expected exit code %d for a missing configuration file but got %d (%v)", exitLoad, code, err)
+	}
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/suite"
+	"github.com/urfave/cli"
+	"github.com/zalando/skipper/eskip"
+)
+
+func newCoverageCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "coverage",
+		ArgsUsage:    "[FIXTURE_FILES...]",
+		Usage:        "Runs fixture suites against a routes file and reports which routes they cover",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "routes, r",
+				Usage: "Load routes from `FILE`",
+			},
+			cli.StringSliceFlag{
+				Name:  "suite, s",
+				Usage: "Load fixture suites from files matching `PATTERN` (alternative to the FIXTURE_FILES arguments)",
+			},
+			cli.Float64Flag{
+				Name:  "min",
+				Usage: "Exit with failure when less than `PERCENT` of the routes are covered",
+			},
+			cli.BoolFlag{
+				Name:  "ignore-trailing-slash",
+				Usage: "Ignore trailing slashes in paths when matching",
+			},
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
+			},
+			cli.StringFlag{
+				Name:  "output, o",
+				Value: outputText,
+				Usage: "Output `FORMAT`: text, json, yaml or markdown",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			routesFile, err := conf.routesFile(c.String("routes"))
+			if err != nil {
+				return loadError(err)
+			}
+			if routesFile == "" {
+				return usageError("A routes file must be provided")
+			}
+			patterns := append(c.StringSlice("suite"), c.Args()...)
+			if len(patterns) == 0 {
+				return usageError("At least a fixture file must be provided")
+			}
+			format, err := outputFormat(c, outputMarkdown)
+			if err != nil {
+				return usageError("%v", err)
+			}
+
+			_, locations, err := parseRoutesFile(routesFile)
+			if err != nil {
+				return loadError(err)
+			}
+			suites, err := suite.LoadFiles(patterns...)
+			if err != nil {
+				return loadError(err)
+			}
+
+			m, err := loadMatcher(c, &matcher.Options{
+				RoutesFile:          routesFile,
+				MockFilters:         append(conf.CustomFilters, list(c.String("mock-filters"))...),
+				IgnoreTrailingSlash: c.Bool("ignore-trailing-slash"),
+				OnLoadProgress:      loadProgress(c.App.ErrWriter),
+			})
+			if err != nil {
+				return loadError(err)
+			}
+			defer m.Close()
+
+			res := suite.Run(m, suites, &suite.RunOptions{Coverage: true})
+			if !res.OK() {
+				fmt.Fprintf(diagnostics(c, format), "warning: %d of %d case(s) failed\n", res.Failed, len(res.Cases))
+			}
+
+			cov := suite.NewCoverage(m.Routes(), locations, res.Hits)
+			switch format {
+			case outputText:
+				err = cov.WriteText(c.App.Writer)
+			case outputMarkdown:
+				err = cov.WriteMarkdown(c.App.Writer)
+			default:
+				err = writeData(c.App.Writer, format, cov)
+			}
+			if err != nil {
+				return err
+			}
+
+			if min := c.Float64("min"); cov.Percent < min {
+				return failure("coverage %.1f%% is below the minimum %.1f%%", cov.Percent, min)
+			}
+			return nil
+		},
+	}
+}
+
+// parseRoutesFile parses a routes file returning its routes
+// and where each one of them is defined
+func parseRoutesFile(file string) ([]*eskip.Route, map[string]matcher.Location, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	routes, err := eskip.Parse(string(b))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return routes, matcher.RouteLocations(file, b), nil
+}
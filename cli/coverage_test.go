@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCoverageCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		contains []string
+	}{
+		{
+			name: "text",
+			args: []string{"coverage", "-r", "testdata/routes.eskip", "--mock-filters", "mockedfilter", "testdata/fixtures/*.yaml"},
+			contains: []string{
+				"ROUTE       HITS  LOCATION\n",
+				"bar         1     testdata/routes.eskip:1\n",
+				"tenant      0     testdata/routes.eskip:5\n",
+				"mocked      0     testdata/routes.eskip:6\n",
+				"coverage: 66.7% (4 of 6 routes)\n",
+			},
+		},
+		{
+			name:     "suite flag",
+			args:     []string{"coverage", "-r", "testdata/routes.eskip", "--mock-filters", "mockedfilter", "-s", "testdata/fixtures/search.yaml"},
+			contains: []string{"coverage: 16.7% (1 of 6 routes)\n"},
+		},
+		{
+			name: "markdown",
+			args: []string{"coverage", "-r", "testdata/routes.eskip", "--mock-filters", "mockedfilter", "-o", "markdown", "testdata/fixtures/*.yaml"},
+			contains: []string{
+				"**66.7%** of the routes are covered (4 of 6).\n",
+				"| `tenant` | testdata/routes.eskip:5 |\n",
+			},
+		},
+		{
+			name:     "min satisfied",
+			args:     []string{"coverage", "-r", "testdata/routes.eskip", "--mock-filters", "mockedfilter", "--min", "60", "testdata/fixtures/*.yaml"},
+			contains: []string{"coverage: 66.7%"},
+		},
+		{
+			name: "min not satisfied",
+			args: []string{"coverage", "-r", "testdata/routes.eskip", "--mock-filters", "mockedfilter", "--min", "80", "testdata/fixtures/*.yaml"},
+			code: exitFailure,
+		},
+		{
+			name: "missing routes",
+			args: []string{"coverage", "testdata/fixtures/*.yaml"},
+			code: exitUsage,
+		},
+		{
+			name: "missing fixtures",
+			args: []string{"coverage", "-r", "testdata/routes.eskip"},
+			code: exitUsage,
+		},
+		{
+			name: "fixtures not found",
+			args: []string{"coverage", "-r", "testdata/routes.eskip", "--mock-filters", "mockedfilter", "testdata/fixtures/*.json"},
+			code: exitLoad,
+		},
+		{
+			name: "routes not found",
+			args: []string{"coverage", "-r", "testdata/blue.eskip", "testdata/fixtures/*.yaml"},
+			code: exitLoad,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode = 0
+			app := NewApp()
+			out := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = &bytes.Buffer{}
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+
+			if exitCode != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, exitCode)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("expected output to contain %q but got:\n%s", s, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCoverageCommandJSON(t *testing.T) {
+	app := NewApp()
+	out := &bytes.Buffer{}
+	app.Writer = out
+	app.ErrWriter = &bytes.Buffer{}
+	err := app.Run([]string{"eskip-match", "coverage", "-r", "testdata/routes.eskip", "--mock-filters", "mockedfilter", "-o", "json", "testdata/fixtures/*.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cov struct {
+		Total     int
+		Uncovered []struct {
+			ID       string
+			Location struct {
+				File string
+				Line int
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &cov); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+	if cov.Total != 6 || len(cov.Uncovered) != 2 {
+		t.Errorf("unexpected coverage %+v", cov)
+	}
+	if u := cov.Uncovered[1]; u.ID != "tenant" || u.Location.File != "testdata/routes.eskip" || u.Location.Line != 5 {
+		t.Errorf("unexpected uncovered route %+v", u)
+	}
+}
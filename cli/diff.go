@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+)
+
+// diffData result of the diff command, used by the data output formats
+type diffData struct {
+	// Routes routes added, removed or changed
+	Routes []matcher.RouteChange `json:"routes" yaml:"routes"`
+	// Requests number of requests tested against both routes files
+	Requests int `json:"requests" yaml:"requests"`
+	// Generated true if the requests were generated from the routes
+	Generated bool `json:"generated" yaml:"generated"`
+	// Changes requests resolving to a different route or backend
+	Changes []matcher.BehaviorChange `json:"changes" yaml:"changes"`
+	// Omitted number of changes left out by --max-entries
+	Omitted int `json:"omitted,omitempty" yaml:"omitted,omitempty"`
+}
+
+func newDiffCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "diff",
+		ArgsUsage:    "OLD_ROUTES_FILE NEW_ROUTES_FILE",
+		Usage:        "Compares two routes files, reporting the requests resolving to a different route or backend",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "requests",
+				Usage: "Test the requests in `FILE`, one json request attributes object per line (by default a request is generated for each route)",
+			},
+			cli.BoolFlag{
+				Name:  "ignore-trailing-slash",
+				Usage: "Ignore trailing slashes in paths when matching",
+			},
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
+			},
+			cli.IntFlag{
+				Name:  "max-entries",
+				Usage: "Show at most `N` requests resolving differently, followed by a line counting the others (all of them when 0)",
+			},
+			cli.StringFlag{
+				Name:  "output, o",
+				Value: outputText,
+				Usage: "Output `FORMAT`: text, json, yaml or markdown",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 2 {
+				return usageError("The old and the new routes files must be provided")
+			}
+			oldFile, newFile := c.Args().Get(0), c.Args().Get(1)
+			format, err := outputFormat(c, outputMarkdown)
+			if err != nil {
+				return usageError("%v", err)
+			}
+
+			oldRoutes, _, err := parseRoutesFile(oldFile)
+			if err != nil {
+				return loadError(err)
+			}
+			newRoutes, _, err := parseRoutesFile(newFile)
+			if err != nil {
+				return loadError(err)
+			}
+
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			load := func(file string) (matcher.Matcher, error) {
+				return loadMatcher(c, &matcher.Options{
+					RoutesFile:          file,
+					MockFilters:         append(conf.CustomFilters, list(c.String("mock-filters"))...),
+					IgnoreTrailingSlash: c.Bool("ignore-trailing-slash"),
+					OnLoadProgress:      loadProgress(c.App.ErrWriter),
+				})
+			}
+			oldMatcher, err := load(oldFile)
+			if err != nil {
+				return loadError(err)
+			}
+			defer oldMatcher.Close()
+			newMatcher, err := load(newFile)
+			if err != nil {
+				return loadError(err)
+			}
+			defer newMatcher.Close()
+
+			data := &diffData{Routes: matcher.DiffRoutes(oldRoutes, newRoutes)}
+			var requests []*matcher.RequestAttributes
+			if file := c.String("requests"); file != "" {
+				requests, err = readRequests(file)
+				if err != nil {
+					return loadError(err)
+				}
+			} else {
+				data.Generated = true
+				requests = matcher.SeedRequests(append(oldRoutes, newRoutes...))
+			}
+			data.Requests = len(requests)
+			data.Changes = matcher.DiffBehavior(oldMatcher, newMatcher, requests)
+			changed := len(data.Changes)
+			if max := c.Int("max-entries"); max > 0 && changed > max {
+				data.Changes, data.Omitted = data.Changes[:max], changed-max
+			}
+
+			switch format {
+			case outputText:
+				err = writeDiffText(c.App.Writer, data)
+			case outputMarkdown:
+				err = writeDiffMarkdown(c.App.Writer, data)
+			default:
+				err = writeData(c.App.Writer, format, data)
+			}
+			if err != nil {
+				return err
+			}
+
+			if changed > 0 {
+				return failure("%d of %d request(s) resolve differently", changed, data.Requests)
+			}
+			return nil
+		},
+	}
+}
+
+// readRequests reads request attributes from a json lines file,
+// empty lines are skipped
+func readRequests(file string) ([]*matcher.RequestAttributes, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var requests []*matcher.RequestAttributes
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		attrs := &matcher.RequestAttributes{}
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(attrs); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid request attributes: %v", file, line, err)
+		}
+		requests = append(requests, attrs)
+	}
+	return requests, scanner.Err()
+}
+
+// destination short representation of where a request is routed, eg. orders -> https://orders.example.org
+func destination(r *matcher.ResultData) string {
+	if !r.Matched {
+		return "no match"
+	}
+	return fmt.Sprintf("%s -> %s", r.RouteID, r.Backend)
+}
+
+// requestLine short representation of a request, eg. GET api.example.org/orders
+func requestLine(a *matcher.RequestAttributes) string {
+	return fmt.Sprintf("%s %s%s", a.Method, a.Host, a.Path)
+}
+
+func writeDiffText(w io.Writer, data *diffData) error {
+	fmt.Fprintf(w, "%d route(s) added, removed or changed\n", len(data.Routes))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range data.Routes {
+		fmt.Fprintf(tw, "  %s\t%s\n", r.Change, r.ID)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n%d of %d request(s) resolve differently\n", len(data.Changes)+data.Omitted, data.Requests)
+	if len(data.Changes) == 0 {
+		return nil
+	}
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  REQUEST\tOLD\tNEW")
+	for _, ch := range data.Changes {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", requestLine(ch.Request), destination(ch.Old), destination(ch.New))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if data.Omitted > 0 {
+		fmt.Fprintf(w, "  ... %d more request(s) not shown\n", data.Omitted)
+	}
+	return nil
+}
+
+func writeDiffMarkdown(w io.Writer, data *diffData) error {
+	fmt.Fprintf(w, "### Routes\n\n")
+	if len(data.Routes) == 0 {
+		fmt.Fprintf(w, "No route added, removed or changed.\n")
+	} else {
+		fmt.Fprintf(w, "| Change | Route |\n|--------|-------|\n")
+		for _, r := range data.Routes {
+			fmt.Fprintf(w, "| %s | `%s` |\n", r.Change, r.ID)
+		}
+	}
+
+	fmt.Fprintf(w, "\n### Requests\n\n%d of %d request(s) resolve differently.\n", len(data.Changes)+data.Omitted, data.Requests)
+	if len(data.Changes) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "\n| Request | Old | New |\n|---------|-----|-----|\n")
+	for _, ch := range data.Changes {
+		_, err := fmt.Fprintf(w, "| `%s` | `%s` | `%s` |\n",
+			markdownCell(requestLine(ch.Request)), markdownCell(destination(ch.Old)), markdownCell(destination(ch.New)))
+		if err != nil {
+			return err
+		}
+	}
+	if data.Omitted > 0 {
+		fmt.Fprintf(w, "\n_%d more request(s) not shown._\n", data.Omitted)
+	}
+	return nil
+}
+
+// markdownCell escapes the characters breaking a markdown table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestDiffCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		golden string
+	}{
+		{
+			name:   "generated requests",
+			args:   []string{"diff", "testdata/diff/old.eskip", "testdata/diff/new.eskip"},
+			code:   exitFailure,
+			golden: "diff.txt",
+		},
+		{
+			name:   "requests file",
+			args:   []string{"diff", "--requests", "testdata/diff/requests.jsonl", "testdata/diff/old.eskip", "testdata/diff/new.eskip"},
+			code:   exitFailure,
+			golden: "diff-requests.txt",
+		},
+		{
+			name:   "markdown",
+			args:   []string{"diff", "-o", "markdown", "testdata/diff/old.eskip", "testdata/diff/new.eskip"},
+			code:   exitFailure,
+			golden: "diff.md",
+		},
+		{
+			name:   "max entries",
+			args:   []string{"diff", "--max-entries", "1", "testdata/diff/old.eskip", "testdata/diff/new.eskip"},
+			code:   exitFailure,
+			golden: "diff-max-entries.txt",
+		},
+		{
+			name:   "markdown max entries",
+			args:   []string{"diff", "-o", "markdown", "--max-entries", "1", "testdata/diff/old.eskip", "testdata/diff/new.eskip"},
+			code:   exitFailure,
+			golden: "diff-max-entries.md",
+		},
+		{
+			name:   "no difference",
+			args:   []string{"diff", "testdata/diff/old.eskip", "testdata/diff/same.eskip"},
+			golden: "diff-same.txt",
+		},
+		{
+			name: "missing file argument",
+			args: []string{"diff", "testdata/diff/old.eskip"},
+			code: exitUsage,
+		},
+		{
+			name: "invalid requests file",
+			args: []string{"diff", "--requests", "testdata/diff/old.eskip", "testdata/diff/old.eskip", "testdata/diff/new.eskip"},
+			code: exitLoad,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode = 0
+			app := NewApp()
+			out := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = &bytes.Buffer{}
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+
+			if exitCode != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, exitCode)
+			}
+			if tt.golden != "" {
+				golden(t, tt.golden, out.String())
+			}
+		})
+	}
+}
+
+func TestDiffCommandJSON(t *testing.T) {
+	app := NewApp()
+	out := &bytes.Buffer{}
+	app.Writer = out
+	app.ErrWriter = &bytes.Buffer{}
+	app.Run([]string{"eskip-match", "diff", "-o", "json", "--requests", "testdata/diff/requests.jsonl", "testdata/diff/old.eskip", "testdata/diff/new.eskip"})
+
+	var data struct {
+		Routes []struct {
+			ID     string `json:"id"`
+			Change string `json:"change"`
+		} `json:"routes"`
+		Requests int `json:"requests"`
+		Changes  []struct {
+			Old struct {
+				Backend string `json:"backend"`
+			} `json:"old"`
+			New struct {
+				Backend string `json:"backend"`
+			} `json:"new"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &data); err != nil {
+		t.Fatalf("expected json output but got %q: %v", out.String(), err)
+	}
+	if len(data.Routes) != 3 || data.Routes[0].ID != "health" || data.Routes[0].Change != "added" {
+		t.Errorf("unexpected routes changes %+v", data.Routes)
+	}
+	if data.Requests != 3 || len(data.Changes) != 1 {
+		t.Fatalf("expected 1 of 3 requests to change but got %d of %d", len(data.Changes), data.Requests)
+	}
+	if data.Changes[0].Old.Backend != "https://orders.example.org" || data.Changes[0].New.Backend != "https://orders-v2.example.org" {
+		t.Errorf("unexpected change %+v", data.Changes[0])
+	}
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+)
+
+// Process exit codes, they allow to use the commands for CI gating
+const (
+	// exitOK the expectation is satisfied (or a route matches when there isn't any expectation)
+	exitOK = 0
+	// exitFailure no match or the expectation failed
+	exitFailure = 1
+	// exitUsage commands are used in the wrong way (missing arguments, bad flags)
+	exitUsage = 2
+	// exitLoad routes can't be loaded or parsed
+	exitLoad = 3
+)
+
+// failure returns an error exiting the process with exitFailure code
+func failure(format string, a ...interface{}) error {
+	return cli.NewExitError(fmt.Sprintf(format, a...), exitFailure)
+}
+
+// usageError returns an error exiting the process with exitUsage code
+func usageError(format string, a ...interface{}) error {
+	return cli.NewExitError(fmt.Sprintf(format, a...), exitUsage)
+}
+
+// loadError wraps err in an error exiting the process with exitLoad code,
+// a parse error is followed by the snippet of the failing lines and a
+// routes file of the wrong type by a hint
+func loadError(err error) error {
+	if e, ok := err.(*matcher.ParseError); ok && e.Snippet != "" {
+		return cli.NewExitError(err.Error()+"\n"+strings.TrimSuffix(e.Snippet, "\n"), exitLoad)
+	}
+	var fileErr *matcher.RoutesFileError
+	if errors.As(err, &fileErr) {
+		if hint := routesFileHint(fileErr); hint != "" {
+			return cli.NewExitError(err.Error()+"\nhint: "+hint, exitLoad)
+		}
+	}
+	return cli.NewExitError(err.Error(), exitLoad)
+}
+
+// routesFileHint how to fix a routes file of the wrong type, empty if none
+func routesFileHint(err *matcher.RoutesFileError) string {
+	switch {
+	case errors.Is(err, matcher.ErrRoutesFileIsDirectory):
+		files, _ := filepath.Glob(filepath.Join(err.Path, "*.eskip"))
+		if len(files) == 0 {
+			return "pass a routes file, the directory has no .eskip files"
+		}
+		if len(files) > maxHintFiles {
+			files = append(files[:maxHintFiles], "...")
+		}
+		return "pass one of the routes files of the directory: " + strings.Join(files, ", ")
+	case errors.Is(err, matcher.ErrRoutesFileBrokenSymlink):
+		return "fix the symlink or pass the path of an existing routes file"
+	case errors.Is(err, matcher.ErrRoutesFileEmpty):
+		return "the file has no routes, check it was written completely"
+	}
+	return ""
+}
+
+// maxHintFiles routes files a hint lists
+const maxHintFiles = 3
+
+// onUsageError makes flags parsing errors exit with exitUsage code
+func onUsageError(c *cli.Context, err error, isSubcommand bool) error {
+	return usageError("Incorrect Usage: %s", err.Error())
+}
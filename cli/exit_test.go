@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+// exitCode last exit code the app tried to exit the process with
+var exitCode int
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestMain(m *testing.M) {
+	// never exit the test process, record the code instead
+	cli.OsExiter = func(code int) {
+		exitCode = code
+	}
+	cli.ErrWriter = ioutil.Discard
+	os.Exit(m.Run())
+}
+
+// golden compares got with the content of the golden file testdata/golden/name,
+// with -update the golden file is written instead
+func golden(t *testing.T, name string, got string) {
+	t.Helper()
+	file := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%v, run the tests with -update to create it", err)
+	}
+	if string(want) != got {
+		t.Errorf("output doesn't match %s, got:\n%s", file, got)
+	}
+}
+
+// run runs the app with args and returns the exit code the process would exit with
+func run(args ...string) (int, error) {
+	exitCode = 0
+	app := NewApp()
+	app.Writer = &bytes.Buffer{}
+	app.ErrWriter = &bytes.Buffer{}
+	err := app.Run(append([]string{"eskip-match"}, args...))
+	if err != nil && exitCode == 0 {
+		// errors not carrying an exit code make main exit with 1
+		return exitFailure, err
+	}
+	return exitCode, err
+}
+
+// runCommand runs the command name of the app with args and returns the exit
+// code the process would exit with and the output
+func runCommand(t *testing.T, name string, args ...string) (int, string) {
+	t.Helper()
+	exitCode = 0
+	app := NewApp()
+	out := &bytes.Buffer{}
+	app.Writer = out
+	app.ErrWriter = &bytes.Buffer{}
+	app.Run(append([]string{"eskip-match", name}, args...))
+	return exitCode, out.String()
+}
+
+// runGolden runs the command name of the app with args, which must succeed,
+// and compares its output with the golden file
+func runGolden(t *testing.T, file string, name string, args ...string) {
+	t.Helper()
+	code, out := runCommand(t, name, args...)
+	if code != exitOK {
+		t.Fatalf("expected exit code %d but got %d", exitOK, code)
+	}
+	golden(t, file, out)
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{
+			name: "match",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-p", "/bar"},
+			code: exitOK,
+		},
+		{
+			name: "no match",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-p", "/foofoo"},
+			code: exitFailure,
+		},
+		{
+			name: "expected route matches",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-p", "/bar", "--expect-route", "bar"},
+			code: exitOK,
+		},
+		{
+			name: "other route matches",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-p", "/bar", "-H", "foo=bar", "--expect-route", "bar"},
+			code: exitFailure,
+		},
+		{
+			name: "expected route doesn't match",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-p", "/foofoo", "--expect-route", "bar"},
+			code: exitFailure,
+		},
+		{
+			name: "expected no match",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-p", "/foofoo", "--expect-no-match"},
+			code: exitOK,
+		},
+		{
+			name: "unexpected match",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-p", "/bar", "--expect-no-match"},
+			code: exitFailure,
+		},
+		{
+			name: "missing routes file",
+			args: []string{"test", "-p", "/bar"},
+			code: exitUsage,
+		},
+		{
+			name: "conflicting expectations",
+			args: []string{"test", "-r", "testdata/routes.eskip", "--expect-route", "bar", "--expect-no-match"},
+			code: exitUsage,
+		},
+		{
+			name: "unknown flag",
+			args: []string{"test", "-r", "testdata/routes.eskip", "--blue"},
+			code: exitUsage,
+		},
+		{
+			name: "bad output format",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-o", "xml"},
+			code: exitUsage,
+		},
+		{
+			name: "invalid header expected no match",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-p", "/bar", "-H", "Bad Name=x", "--expect-no-match"},
+			code: exitFailure,
+		},
+		{
+			name: "header without separator",
+			args: []string{"test", "-r", "testdata/routes.eskip", "-p", "/bar", "-H", "X-Tenant"},
+			code: exitUsage,
+		},
+		{
+			name: "routes file doesn't exist",
+			args: []string{"test", "-r", "testdata/blue.eskip", "-p", "/bar"},
+			code: exitLoad,
+		},
+		{
+			name: "routes file can't be parsed",
+			args: []string{"test", "-r", "testdata/config.yml", "-p", "/bar"},
+			code: exitLoad,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := run(tt.args...)
+			if code != tt.code {
+				t.Errorf("expected exit code %d but got %d (%v)", tt.code, code, err)
+			}
+		})
+	}
+}
+
+func TestUsageErrorVerbatim(t *testing.T) {
+	code, err := run("test", "-r", "testdata/routes.eskip", "--output", "x%d", "-p", "/")
+	if code != exitUsage {
+		t.Fatalf("expected exit code %d but got %d (%v)", exitUsage, code, err)
+	}
+	if want := `unsupported output format "x%d"`; !strings.Contains(err.Error(), want) {
+		t.Errorf("got %q, want it to contain %q", err, want)
+	}
+}
+
+func TestTestError(t *testing.T) {
+	code, err := run("test", "-r", "testdata/routes.eskip", "-p", "/bar", "-H", "Bad Name=x", "--expect-no-match")
+	if code != exitFailure {
+		t.Fatalf("expected exit code %d but got %d (%v)", exitFailure, code, err)
+	}
+	if !strings.HasPrefix(err.Error(), "the request couldn't be tested: ") {
+		t.Errorf("got %q", err)
+	}
+}
+
+func TestLoadErrorSnippet(t *testing.T) {
+	f, err := ioutil.TempFile("", "routes-*.eskip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("a: Path(\"/a\") -> <shunt>;\nb: Path(\"/b\") -> -> <shunt>;\n")
+	f.Close()
+
+	code, err := run("test", "-r", f.Name(), "-p", "/a")
+	if code != exitLoad {
+		t.Fatalf("expected exit code %d but got %d (%v)", exitLoad, code, err)
+	}
+	want := f.Name() + `:2:18: syntax error near "->"
+1 | a: Path("/a") -> <shunt>;
+2 | b: Path("/b") -> -> <shunt>;
+  |                  ^^`
+	if err.Error() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", err, want)
+	}
+}
+
+func TestLoadErrorRoutesFileHints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "routes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	routes := filepath.Join(dir, "routes.eskip")
+	empty := filepath.Join(dir, "empty.eskip")
+	broken := filepath.Join(dir, "broken.eskip")
+	if err := ioutil.WriteFile(routes, []byte(`a: Path("/a") -> <shunt>;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing.eskip"), broken); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, file, hint string
+	}{
+		{"directory", dir, "hint: pass one of the routes files of the directory: " + broken + ", " + empty + ", " + routes},
+		{"broken symlink", broken, "hint: fix the symlink or pass the path of an existing routes file"},
+		{"empty", empty, "hint: the file has no routes, check it was written completely"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := run("test", "-r", tt.file, "-p", "/a")
+			if code != exitLoad {
+				t.Fatalf("expected exit code %d but got %d (%v)", exitLoad, code, err)
+			}
+			if !strings.HasSuffix(err.Error(), "\n"+tt.hint) {
+				t.Errorf("got:\n%s\nwant the hint:\n%s", err, tt.hint)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+)
+
+// explainCandidates default number of near misses shown by the explain command
+const explainCandidates = 3
+
+// explainData result of the explain command, used by the data output formats
+type explainData struct {
+	// Request normalized request attributes
+	Request *matcher.RequestAttributes `json:"request" yaml:"request"`
+	// RouteID id of the matching route, if any
+	RouteID string `json:"routeId,omitempty" yaml:"routeId,omitempty"`
+	// NearMisses the closest routes not matching, the closest one first
+	NearMisses []matcher.NearMiss `json:"nearMisses" yaml:"nearMisses"`
+}
+
+func newExplainCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "explain",
+		ArgsUsage:    "[ROUTES_FILE] [-- CURL_ARGS...]",
+		Usage:        "Given a routes file and request attributes, shows why the closest routes don't match",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "routes, r",
+				Usage: "Load routes from `FILE` (alternative to the ROUTES_FILE argument)",
+			},
+			cli.StringFlag{
+				Name:  "method, m",
+				Usage: "Specify request `METHOD`",
+			},
+			cli.StringFlag{
+				Name:  "path, p",
+				Usage: "Specify request `PATH`",
+			},
+			cli.StringFlag{
+				Name:  "host",
+				Usage: "Specify request `HOST`",
+			},
+			cli.StringSliceFlag{
+				Name:  "query, Q",
+				Usage: "Specify request query parameter `QUERY` as key=value pair",
+			},
+			cli.StringSliceFlag{
+				Name:  "header, H",
+				Usage: "Specify request `HEADER` as key=value or \"Key: value\" pair",
+			},
+			cli.IntFlag{
+				Name:  "candidates, n",
+				Value: explainCandidates,
+				Usage: "Show the `N` closest routes not matching, all of them when 0",
+			},
+			cli.BoolFlag{
+				Name:  "ignore-trailing-slash",
+				Usage: "Ignore trailing slashes in paths when matching",
+			},
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
+			},
+			outputFlag,
+			colorFlag,
+			noColorFlag,
+			verboseFlag,
+			vvFlag,
+			vvvFlag,
+		},
+		Action: func(c *cli.Context) error {
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			routesFile, args := routesFileAndCurlArgs(c)
+			if routesFile, err = conf.routesFile(routesFile); err != nil {
+				return loadError(err)
+			}
+			if routesFile == "" {
+				return usageError("A routes file must be provided")
+			}
+			if c.Int("candidates") < 0 {
+				return usageError("--candidates must be 0 or more")
+			}
+			format, err := outputFormat(c)
+			if err != nil {
+				return usageError("%v", err)
+			}
+			style, err := outputStyle(c, c.App.Writer)
+			if err != nil {
+				return usageError("%v", err)
+			}
+			attrs, err := requestAttributes(c, conf, args, diagnostics(c, format))
+			if err != nil {
+				return err
+			}
+
+			m, err := loadMatcher(c, &matcher.Options{
+				RoutesFile:          routesFile,
+				MockFilters:         append(conf.CustomFilters, list(c.String("mock-filters"))...),
+				IgnoreTrailingSlash: c.Bool("ignore-trailing-slash"),
+				OnLoadProgress:      loadProgress(c.App.ErrWriter),
+				Verbosity:           verbosity(c),
+				Log:                 c.App.ErrWriter,
+				RedactedHeaders:     conf.RedactedHeaders,
+			})
+			if err != nil {
+				return loadError(err)
+			}
+			defer m.Close()
+
+			data := &explainData{}
+			result := m.Test(attrs)
+			if route := result.Route(); route != nil {
+				data.RouteID = route.Id
+			}
+			data.Request = result.Attributes()
+			data.NearMisses = m.Explain(attrs, c.Int("candidates"))
+
+			if isDataOutput(format) {
+				return writeData(c.App.Writer, format, data)
+			}
+			return writeExplain(c.App.Writer, data, style)
+		},
+	}
+}
+
+// writeExplain writes the near misses one predicate verdict per line,
+// the columns of each near miss aligned
+func writeExplain(w io.Writer, data *explainData, style matcher.Style) error {
+	fmt.Fprintf(w, "request: %s %s\n", data.Request.Method, data.Request.Path)
+	if data.Request.Host != "" {
+		fmt.Fprintf(w, "request host: %s\n", data.Request.Host)
+	}
+	if data.RouteID != "" {
+		fmt.Fprintf(w, "matching route id: %s\n", style.DecorateMatch(data.RouteID))
+	} else {
+		fmt.Fprintln(w, style.DecorateNoMatch("NO MATCH"))
+	}
+	if len(data.NearMisses) == 0 {
+		fmt.Fprintln(w, "no near misses")
+		return nil
+	}
+
+	for _, miss := range data.NearMisses {
+		fmt.Fprintf(w, "\n%s: %d of %d predicate(s) failed\n", miss.RouteID, miss.Failed, len(miss.Verdicts))
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, v := range miss.Verdicts {
+			if detail := verdictDetail(v); detail != "" {
+				fmt.Fprintf(tw, "  %s\t%s\t%s\n", verdictMark(v, style), v.Predicate, detail)
+			} else {
+				fmt.Fprintf(tw, "  %s\t%s\n", verdictMark(v, style), v.Predicate)
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verdictDetail the observed value of a predicate verdict or why it couldn't be evaluated
+func verdictDetail(v matcher.PredicateVerdict) string {
+	switch {
+	case v.Error != "":
+		return "error: " + v.Error
+	case v.Observed != "" || v.Name == "Header" || v.Name == "HeaderRegexp":
+		return fmt.Sprintf("observed %q", v.Observed)
+	default:
+		return ""
+	}
+}
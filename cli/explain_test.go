@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExplainCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		golden string
+	}{
+		{
+			name:   "near misses",
+			args:   []string{"explain", "-r", "testdata/routes.eskip", "-p", "/orders", "--host", "internal.example.org"},
+			golden: "explain.txt",
+		},
+		{
+			name:   "candidates",
+			args:   []string{"explain", "testdata/routes.eskip", "-p", "/tenant", "-H", "X-Tenant: other", "--candidates", "1", "--color=always"},
+			golden: "explain-color.txt",
+		},
+		{
+			name:   "match",
+			args:   []string{"explain", "-r", "testdata/routes.eskip", "-p", "/bar", "-n", "2"},
+			golden: "explain-match.txt",
+		},
+		{
+			name:   "json",
+			args:   []string{"explain", "-r", "testdata/routes.eskip", "-p", "/search", "-n", "1", "--output", "json"},
+			golden: "explain.json",
+		},
+		{
+			name:   "curl arguments",
+			args:   []string{"explain", "testdata/routes.eskip", "-n", "1", "--", "-H", "X-Tenant: acme", "http://localhost/tenants"},
+			golden: "explain-curl.txt",
+		},
+		{
+			name: "missing routes",
+			args: []string{"explain", "-p", "/bar"},
+			code: exitUsage,
+		},
+		{
+			name: "negative candidates",
+			args: []string{"explain", "-r", "testdata/routes.eskip", "-p", "/bar", "-n", "-1"},
+			code: exitUsage,
+		},
+		{
+			name: "missing routes file",
+			args: []string{"explain", "-r", "testdata/missing.eskip", "-p", "/bar"},
+			code: exitLoad,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode = 0
+			app := NewApp()
+			out := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = &bytes.Buffer{}
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+
+			if exitCode != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, exitCode)
+			}
+			if tt.golden != "" {
+				golden(t, tt.golden, out.String())
+			}
+		})
+	}
+}
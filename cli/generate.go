@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/suite"
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// generated request formats
+const (
+	generateHTTP    = "http"
+	generateCurl    = "curl"
+	generateFixture = "fixture"
+)
+
+func newGenerateCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "generate",
+		ArgsUsage:    "[ROUTES_FILE]",
+		Usage:        "Generates a sample request matching a route, or every route with --all",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "routes, r",
+				Usage: "Load routes from `FILE` (alternative to the ROUTES_FILE argument)",
+			},
+			cli.StringFlag{
+				Name:  "route",
+				Usage: "Generate a request for the route with `ID`",
+			},
+			cli.BoolFlag{
+				Name:  "all",
+				Usage: "Generate a request for every route and print how many of them match their route",
+			},
+			cli.BoolFlag{
+				Name:  "fixture",
+				Usage: "Generate a suite fixture with a case for every route, the cases needing manual values commented out",
+			},
+			cli.StringFlag{
+				Name:  "as",
+				Value: generateHTTP,
+				Usage: "Print the request as `FORMAT`: http (raw request), curl (command line) or fixture (suite case)",
+			},
+			cli.Int64Flag{
+				Name:  "seed",
+				Usage: "Sample the regexps with random values from `SEED`, the same for the same seed (default: the shortest values)",
+			},
+			cli.StringFlag{
+				Name:  "placeholders",
+				Value: string(matcher.PlaceholderExample),
+				Usage: "Fill the path parameters in `STYLE`: example (example-id), braces ({id}) or numeric (1)",
+			},
+			cli.StringFlag{
+				Name:  "host",
+				Usage: "Use `HOST` for the routes without a Host predicate",
+			},
+			cli.BoolFlag{
+				Name:  "optional",
+				Usage: "Include the optional parts of the regexps in the sampled values",
+			},
+			cli.BoolFlag{
+				Name:  "ignore-trailing-slash",
+				Usage: "Ignore trailing slashes in paths when matching",
+			},
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			routesFile := c.String("routes")
+			if routesFile == "" {
+				routesFile = c.Args().First()
+			}
+			if routesFile, err = conf.routesFile(routesFile); err != nil {
+				return loadError(err)
+			}
+			if routesFile == "" {
+				return usageError("A routes file must be provided")
+			}
+			id := c.String("route")
+			all := c.Bool("all")
+			fixture := c.Bool("fixture")
+			switch {
+			case fixture && (id != "" || all || c.IsSet("as")):
+				return usageError("--fixture can't be used together with --route, --all or --as")
+			case id == "" && !all && !fixture:
+				return usageError("A route id must be provided with --route, or --all or --fixture")
+			case id != "" && all:
+				return usageError("--route and --all can't be used together")
+			}
+			format := c.String("as")
+			if format != generateHTTP && format != generateCurl && format != generateFixture {
+				return usageError("unsupported format %q, use one of %s, %s, %s", format, generateHTTP, generateCurl, generateFixture)
+			}
+			placeholders, err := matcher.ParsePlaceholderStyle(c.String("placeholders"))
+			if err != nil {
+				return usageError("%s", err)
+			}
+
+			mo := &matcher.Options{
+				RoutesFile:          routesFile,
+				MockFilters:         append(conf.CustomFilters, list(c.String("mock-filters"))...),
+				IgnoreTrailingSlash: c.Bool("ignore-trailing-slash"),
+				Generate: matcher.GenerateOptions{
+					Seed:         c.Int64("seed"),
+					Placeholders: placeholders,
+					DefaultHost:  c.String("host"),
+					Optional:     c.Bool("optional"),
+				},
+			}
+			if fixture {
+				if err := matcher.GenerateFixture(mo, c.App.Writer); err != nil {
+					return loadError(err)
+				}
+				return nil
+			}
+			if all {
+				return generateAll(c, mo, format)
+			}
+			m, err := loadMatcher(c, mo)
+			if err != nil {
+				return loadError(err)
+			}
+			defer m.Close()
+			// the request is checked against the whole table, another route can win
+			attrs, unsatisfied, err := m.GenerateRequestFor(id)
+			if attrs == nil {
+				return failure("%s", err)
+			}
+
+			if err := writeGenerated(c.App.Writer, format, id, attrs); err != nil {
+				return err
+			}
+			for _, u := range unsatisfied {
+				fmt.Fprintf(c.App.ErrWriter, "warning: %s can't be satisfied automatically: %s\n", u.Predicate, u.Reason)
+			}
+			switch {
+			case err != nil:
+				return failure("%s", err)
+			case len(unsatisfied) > 0:
+				return failure("the generated request isn't guaranteed to match route %s", id)
+			}
+			return nil
+		},
+	}
+}
+
+// generateAll prints the requests generated for every route, warns about
+// the ones not matching their route and prints how many do on stderr
+func generateAll(c *cli.Context, o *matcher.Options, format string) error {
+	// a route behind a Traffic predicate is reachable, by some requests
+	o.TrafficMode = matcher.TrafficAlways
+	cases, err := matcher.GenerateAll(o)
+	if err != nil {
+		return loadError(err)
+	}
+	if err := writeGeneratedAll(c.App.Writer, format, cases); err != nil {
+		return err
+	}
+	reachable := 0
+	for _, gc := range cases {
+		for _, u := range gc.Unsatisfied {
+			fmt.Fprintf(c.App.ErrWriter, "warning: %s: %s can't be satisfied automatically: %s\n", gc.RouteID, u.Predicate, u.Reason)
+		}
+		switch {
+		case gc.Reachable:
+			reachable++
+		case gc.Matched != "":
+			fmt.Fprintf(c.App.ErrWriter, "warning: %s: the generated request matches route %s instead\n", gc.RouteID, gc.Matched)
+		default:
+			fmt.Fprintf(c.App.ErrWriter, "warning: %s: the generated request doesn't match it\n", gc.RouteID)
+		}
+	}
+	fmt.Fprintf(c.App.ErrWriter, "%d of %d routes reachable by generation\n", reachable, len(cases))
+	if reachable < len(cases) {
+		return failure("%d route(s) not reachable by generation", len(cases)-reachable)
+	}
+	return nil
+}
+
+// writeGeneratedAll writes the requests generated for the routes in the
+// given format, each one after a comment with its route, or a fixture suite
+// with a case for each reachable route
+func writeGeneratedAll(w io.Writer, format string, cases []matcher.GeneratedCase) error {
+	if format == generateFixture {
+		s := &suite.Suite{Name: "generated", Cases: []suite.Case{}}
+		for _, gc := range cases {
+			if gc.Reachable {
+				s.Cases = append(s.Cases, suite.Case{
+					Name:    fmt.Sprintf("%s matches", gc.RouteID),
+					Request: *gc.Request,
+					Expect:  suite.Expect{Route: gc.RouteID},
+				})
+			}
+		}
+		b, err := yaml.Marshal(s)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	for i, gc := range cases {
+		if i > 0 && format == generateHTTP {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "# %s\n", gc.RouteID)
+		if err := writeGenerated(w, format, gc.RouteID, gc.Request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeGenerated writes the request generated for the route in the given format
+func writeGenerated(w io.Writer, format string, id string, attrs *matcher.RequestAttributes) error {
+	switch format {
+	case generateCurl:
+		_, err := fmt.Fprintln(w, matcher.CurlCommand(attrs))
+		return err
+	case generateFixture:
+		b, err := yaml.Marshal(&suite.Suite{
+			Name: id,
+			Cases: []suite.Case{{
+				Name:    fmt.Sprintf("%s matches", id),
+				Request: *attrs,
+				Expect:  suite.Expect{Route: id},
+			}},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	default:
+		_, err := fmt.Fprint(w, matcher.RawRequest(attrs, nil))
+		return err
+	}
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+)
+
+func newGraphCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "graph",
+		ArgsUsage:    "[ROUTES_FILE]",
+		Usage:        "Prints the routing tree of a routes file as a Graphviz DOT graph, eg. eskip-match graph routes.eskip | dot -Tsvg > routes.svg",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "routes, r",
+				Usage: "Load routes from `FILE` (alternative to the ROUTES_FILE argument)",
+			},
+			cli.BoolFlag{
+				Name:  "cluster-hosts",
+				Usage: "Render a tree per Host predicate, each in its own cluster",
+			},
+			cli.IntFlag{
+				Name:  "max-nodes",
+				Usage: "Collapse the rest of a tree once it has `N` nodes, 0 for no bound",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			routesFile := c.String("routes")
+			if routesFile == "" {
+				routesFile = c.Args().First()
+			}
+			if routesFile, err = conf.routesFile(routesFile); err != nil {
+				return loadError(err)
+			}
+			if routesFile == "" {
+				return usageError("A routes file must be provided")
+			}
+			if c.Int("max-nodes") < 0 {
+				return usageError("invalid max nodes %d, use a positive number or 0", c.Int("max-nodes"))
+			}
+
+			err = matcher.ExportDOT(c.App.Writer, &matcher.Options{
+				RoutesFile:     routesFile,
+				OnLoadProgress: loadProgress(c.App.ErrWriter),
+				Graph: matcher.GraphOptions{
+					ClusterHosts: c.Bool("cluster-hosts"),
+					MaxNodes:     c.Int("max-nodes"),
+				},
+			})
+			if err != nil {
+				return loadError(err)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,26 @@
+package cli
+
+import "testing"
+
+func TestGraph(t *testing.T) {
+	runGolden(t, "graph.dot", "graph", "--cluster-hosts", "testdata/inventory.eskip")
+}
+
+func TestGraphErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "missing routes file", code: exitUsage},
+		{name: "negative max nodes", args: []string{"--max-nodes", "-1", "testdata/inventory.eskip"}, code: exitUsage},
+		{name: "not found", args: []string{"testdata/blue.eskip"}, code: exitLoad},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := runCommand(t, "graph", tt.args...); code != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, code)
+			}
+		})
+	}
+}
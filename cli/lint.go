@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+)
+
+// stdinFile name used for routes read from the standard input
+const stdinFile = "-"
+
+// stdin where commands read from when a file name is "-"
+var stdin io.Reader = os.Stdin
+
+func newLintCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "lint",
+		ArgsUsage:    "[ROUTES_FILES...]",
+		Usage:        "Checks routes files for problems (duplicate ids, unknown filters and predicates, shadowed routes, invalid backends, deprecated filters), by default the configured ones, reads the standard input with - or when none is configured",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "fail-on",
+				Value: string(matcher.SeverityError),
+				Usage: "Exit with failure on findings of `SEVERITY` or more serious: warning or error",
+			},
+			cli.StringFlag{
+				Name:  "format, f",
+				Value: outputText,
+				Usage: "Output `FORMAT`: text or json",
+			},
+			cli.StringSliceFlag{
+				Name:  "ignore",
+				Usage: "Don't check `RULE` (eg. shadowed-route)",
+			},
+			cli.StringSliceFlag{
+				Name:  "severity",
+				Usage: "Report the findings of a rule with another severity, `RULE=SEVERITY` (eg. duplicate-id=warning)",
+			},
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
+			},
+			cli.BoolFlag{
+				Name:  "allow-backend-path",
+				Usage: "Accept network backends with a path or a query",
+			},
+			cli.BoolFlag{
+				Name:  "check-filter-args",
+				Usage: "Create the filters of the routes to check their arguments (custom filters too, mocked ones and lua and basicAuth are skipped)",
+			},
+			cli.BoolFlag{
+				Name:  "check-ambiguity",
+				Usage: "Report the routes which can match the same requests, with a sample request",
+			},
+			cli.BoolFlag{
+				Name:  "find-ambiguities",
+				Usage: "Load the routes and report the routes matching the request generated for another route, with the route the routing picks (slower than --check-ambiguity)",
+			},
+			cli.BoolFlag{
+				Name:  "check-path-subtree",
+				Usage: "Report the Path routes inside the PathSubtree of another route, with sample requests for the exact path and a sub-path",
+			},
+			cli.StringSliceFlag{
+				Name:  "allowed-host-suffix",
+				Usage: "Report the Host predicates which can match hosts outside the `DOMAIN` and its subdomains, and the routes matching any host",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			failOn := matcher.Severity(c.String("fail-on"))
+			if failOn != matcher.SeverityWarning && failOn != matcher.SeverityError {
+				return usageError("unsupported severity %q, use one of warning or error", failOn)
+			}
+			format := c.String("format")
+			if format != outputText && format != outputJSON {
+				return usageError("unsupported format %q, use one of text or json", format)
+			}
+
+			severities, err := parseSeverities(c.StringSlice("severity"))
+			if err != nil {
+				return usageError("%v", err)
+			}
+
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			deprecations, err := conf.deprecations()
+			if err != nil {
+				return loadError(err)
+			}
+			files := []string(c.Args())
+			if len(files) == 0 {
+				if files, err = conf.routesFiles(); err != nil {
+					return loadError(err)
+				}
+			}
+			if len(files) == 0 {
+				files = []string{stdinFile}
+			}
+			docs := make([]matcher.Document, 0, len(files))
+			for _, file := range files {
+				doc, err := readDocument(file)
+				if err != nil {
+					return loadError(err)
+				}
+				docs = append(docs, doc)
+			}
+
+			mockFilters := append(conf.CustomFilters, list(c.String("mock-filters"))...)
+			findings := matcher.Lint(docs, &matcher.LintOptions{
+				MockFilters:         mockFilters,
+				Ignore:              c.StringSlice("ignore"),
+				Severity:            severities,
+				AllowBackendPath:    c.Bool("allow-backend-path"),
+				CheckFilterArgs:     c.Bool("check-filter-args"),
+				CheckAmbiguity:      c.Bool("check-ambiguity"),
+				CheckPathSubtree:    c.Bool("check-path-subtree"),
+				Deprecations:        deprecations,
+				AllowedHostSuffixes: c.StringSlice("allowed-host-suffix"),
+			})
+			if c.Bool("find-ambiguities") {
+				rules := matcher.RuleOptions{Ignore: c.StringSlice("ignore"), Severity: severities}
+				for _, doc := range docs {
+					if parseFailed(findings, doc.File) {
+						continue
+					}
+					ambiguities, err := findAmbiguities(doc, mockFilters)
+					if err != nil {
+						return loadError(err)
+					}
+					findings = append(findings, matcher.AmbiguityFindings(ambiguities, rules)...)
+				}
+			}
+
+			if format == outputJSON {
+				if err := writeData(c.App.Writer, format, findings); err != nil {
+					return err
+				}
+			} else {
+				writeFindings(c.App.Writer, findings)
+			}
+
+			failed := 0
+			for _, f := range findings {
+				if f.Severity.AtLeast(failOn) {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return failure("%d finding(s) with severity %s or more serious", failed, failOn)
+			}
+			return nil
+		},
+	}
+}
+
+// parseFailed true if a parse finding is about the file
+func parseFailed(findings []matcher.Finding, file string) bool {
+	for _, f := range findings {
+		if f.RuleID == matcher.RuleParse && f.File == file {
+			return true
+		}
+	}
+	return false
+}
+
+// findAmbiguities finds the ambiguities of the routes of the document, the
+// routes of the standard input are loaded from a temporary file
+func findAmbiguities(doc matcher.Document, mockFilters []string) ([]matcher.Ambiguity, error) {
+	file := doc.File
+	if file == "<stdin>" {
+		f, err := ioutil.TempFile("", "stdin-*.eskip")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(doc.Content)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+		file = f.Name()
+	}
+	ambiguities, err := matcher.FindAmbiguities(&matcher.Options{
+		RoutesFile:             file,
+		MockFilters:            mockFilters,
+		AllowMissingFilters:    true,
+		AllowUnknownPredicates: true,
+	})
+	for i := range ambiguities {
+		ambiguities[i].Location.File = doc.File
+	}
+	return ambiguities, err
+}
+
+// parseSeverities parses the RULE=SEVERITY severity overrides
+func parseSeverities(values []string) (map[string]matcher.Severity, error) {
+	severities := make(map[string]matcher.Severity, len(values))
+	for _, v := range values {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid severity %q, use RULE=SEVERITY", v)
+		}
+		severity := matcher.Severity(parts[1])
+		if severity != matcher.SeverityWarning && severity != matcher.SeverityError {
+			return nil, fmt.Errorf("unsupported severity %q, use one of warning or error", severity)
+		}
+		severities[parts[0]] = severity
+	}
+	return severities, nil
+}
+
+// readDocument reads a routes document from a file or the standard input
+func readDocument(file string) (matcher.Document, error) {
+	var b []byte
+	var err error
+	if file == stdinFile {
+		file = "<stdin>"
+		b, err = ioutil.ReadAll(stdin)
+	} else {
+		b, err = ioutil.ReadFile(file)
+	}
+	return matcher.Document{File: file, Content: b}, err
+}
+
+// writeFindings writes a finding per line followed by a summary
+func writeFindings(w io.Writer, findings []matcher.Finding) {
+	counts := make(map[matcher.Severity]int)
+	for _, f := range findings {
+		fmt.Fprintln(w, f)
+		counts[f.Severity]++
+	}
+	fmt.Fprintf(w, "%d error(s), %d warning(s)\n", counts[matcher.SeverityError], counts[matcher.SeverityWarning])
+}
@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLintCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		code  int
+		out   string
+	}{
+		{
+			name: "valid",
+			args: []string{"lint", "--mock-filters", "mockedfilter", "testdata/routes.eskip"},
+			out:  "0 error(s), 0 warning(s)\n",
+		},
+		{
+			name: "findings",
+			args: []string{"lint", "--mock-filters", "mockedfilter", "testdata/routes.eskip", "testdata/lint.eskip"},
+			code: exitFailure,
+			out: `testdata/lint.eskip:2: warning: bar_copy: route has the same predicates of route bar, only one of them can match [shadowed-route]
+testdata/lint.eskip:3: error: unknown: unknown filter unknownfilter, the route is ignored [unknown-filter]
+testdata/lint.eskip:3: error: unknown: invalid backend "http//unknown.svc": scheme must be http or https [invalid-backend]
+testdata/routes.eskip:1: error: bar: route id is defined 2 times (testdata/routes.eskip:1, testdata/lint.eskip:1), the definition at testdata/lint.eskip:1 wins [duplicate-id]
+3 error(s), 1 warning(s)
+`,
+		},
+		{
+			name:  "severity",
+			args:  []string{"lint", "--severity", "duplicate-id=warning"},
+			stdin: `a: Path("/a") -> <shunt>; a: Path("/b") -> <shunt>;`,
+			out:   "<stdin>:1: warning: a: route id is defined 2 times (<stdin>:1, <stdin>:1), the definition at <stdin>:1 wins [duplicate-id]\n0 error(s), 1 warning(s)\n",
+		},
+		{
+			name: "all the broken routes",
+			args: []string{"lint", "--check-filter-args", "-"},
+			stdin: `badargs: Path("/args") -> setPath(42) -> <shunt>;
+badregexp: PathRegexp("[") -> <shunt>;
+tenant: Path("/tenant") && TenantIs("acme") -> <shunt>;
+`,
+			code: exitFailure,
+			out: "<stdin>:1: error: badargs: invalid filter setPath(42): invalid filter parameters, the route is ignored [invalid-filter-args]\n" +
+				"<stdin>:2: error: badregexp: invalid regexp PathRegexp(\"[\"): missing closing ] `[` at position 1, the route is ignored [invalid-regexp]\n" +
+				"<stdin>:3: error: tenant: unknown predicate TenantIs, the route is ignored [unknown-predicate]\n" +
+				"3 error(s), 0 warning(s)\n",
+		},
+		{
+			name: "bad severity override",
+			args: []string{"lint", "--severity", "duplicate-id", "testdata/routes.eskip"},
+			code: exitUsage,
+		},
+		{
+			name: "valid json",
+			args: []string{"lint", "--format", "json", "--mock-filters", "mockedfilter", "testdata/routes.eskip"},
+			out:  "[]\n",
+		},
+		{
+			name:  "stdin",
+			args:  []string{"lint", "--fail-on", "warning"},
+			stdin: `a: Path("/a") -> <shunt>; b: Path("/a") -> <shunt>;`,
+			code:  exitFailure,
+			out:   "<stdin>:1: warning: b: route has the same predicates of route a, only one of them can match [shadowed-route]\n0 error(s), 1 warning(s)\n",
+		},
+		{
+			name:  "warnings don't fail by default",
+			args:  []string{"lint", "-"},
+			stdin: `a: Path("/a") -> <shunt>; b: Path("/a") -> <shunt>;`,
+			out:   "<stdin>:1: warning: b: route has the same predicates of route a, only one of them can match [shadowed-route]\n0 error(s), 1 warning(s)\n",
+		},
+		{
+			name:  "ignore",
+			args:  []string{"lint", "--fail-on", "warning", "--ignore", "shadowed-route"},
+			stdin: `a: Path("/a") -> <shunt>; b: Path("/a") -> <shunt>;`,
+			out:   "0 error(s), 0 warning(s)\n",
+		},
+		{
+			name:  "mock filters",
+			args:  []string{"lint", "--mock-filters", "unknownfilter", "--ignore", "invalid-backend"},
+			stdin: `unknown: Path("/unknown") -> unknownfilter() -> "http//unknown.svc";`,
+			out:   "0 error(s), 0 warning(s)\n",
+		},
+		{
+			name:  "backend path",
+			args:  []string{"lint"},
+			stdin: `a: Path("/a") -> "http://a.svc/v1";`,
+			code:  exitFailure,
+			out:   "<stdin>:1: error: a: invalid backend \"http://a.svc/v1\": unexpected path /v1 [invalid-backend]\n1 error(s), 0 warning(s)\n",
+		},
+		{
+			name:  "allow backend path",
+			args:  []string{"lint", "--allow-backend-path"},
+			stdin: `a: Path("/a") -> "http://a.svc/v1";`,
+			out:   "0 error(s), 0 warning(s)\n",
+		},
+		{
+			name:  "filter args",
+			args:  []string{"lint", "--check-filter-args"},
+			stdin: `a: Path("/a") -> setPath() -> <shunt>;`,
+			code:  exitFailure,
+			out:   "<stdin>:1: error: a: invalid filter setPath(): invalid filter parameters, the route is ignored [invalid-filter-args]\n1 error(s), 0 warning(s)\n",
+		},
+		{
+			name:  "ambiguity",
+			args:  []string{"lint", "--check-ambiguity"},
+			stdin: "a: PathSubtree(\"/a\") && Method(\"GET\") -> <shunt>;\nb: Path(\"/a/b\") && Header(\"X-A\", \"1\") -> <shunt>;",
+			out:   "<stdin>:2: warning: b: route can match the same requests of route a (<stdin>:1), eg. GET /a/b X-A: 1 [ambiguous-routes]\n0 error(s), 1 warning(s)\n",
+		},
+		{
+			name:  "find ambiguities",
+			args:  []string{"lint", "--find-ambiguities"},
+			stdin: "a: PathSubtree(\"/a\") && Method(\"GET\") -> <shunt>;\nb: Path(\"/a/b\") && Header(\"X-A\", \"1\") -> <shunt>;\nc: Path(\"/c\") -> <shunt>;",
+			out:   "<stdin>:2: warning: b: routes a, b match the request generated for the route, b wins, eg. GET /a/b X-A: 1 [ambiguous-witness]\n0 error(s), 1 warning(s)\n",
+		},
+		{
+			name:  "find ambiguities ignored",
+			args:  []string{"lint", "--find-ambiguities", "--ignore", "ambiguous-witness"},
+			stdin: "a: PathSubtree(\"/a\") && Method(\"GET\") -> <shunt>;\nb: Path(\"/a/b\") && Header(\"X-A\", \"1\") -> <shunt>;",
+			out:   "0 error(s), 0 warning(s)\n",
+		},
+		{
+			name:  "deprecated",
+			args:  []string{"lint"},
+			stdin: `a: Path("/a") -> requestHeader("X-A", "1") -> <shunt>;`,
+			out:   "<stdin>:1: warning: a: filter requestHeader is deprecated, use setRequestHeader or appendRequestHeader [deprecated]\n0 error(s), 1 warning(s)\n",
+		},
+		{
+			name:  "deprecated fail on warning",
+			args:  []string{"lint", "--fail-on", "warning"},
+			stdin: `a: Path("/a") -> requestHeader("X-A", "1") -> <shunt>;`,
+			code:  exitFailure,
+		},
+		{
+			name:  "configured deprecations",
+			args:  []string{"-c", "testdata/deprecations.yml", "lint", "--mock-filters", "oauthTokeninfoAnyScope"},
+			stdin: "a: Path(\"/a\") -> oauthTokeninfoAnyScope(\"uid\") -> <shunt>;\nb: Path(\"/b\") -> redirect(302, \"/a\") -> <shunt>;",
+			code:  exitFailure,
+			out: `<stdin>:1: error: a: filter oauthTokeninfoAnyScope is deprecated, use oauthTokeninfoAllScope [deprecated]
+<stdin>:2: warning: b: filter redirect is deprecated, use redirectTo with a status code [deprecated]
+1 error(s), 1 warning(s)
+`,
+		},
+		{
+			name:  "ignore deprecations",
+			args:  []string{"-c", "testdata/deprecations.yml", "lint", "--mock-filters", "oauthTokeninfoAnyScope", "--ignore", "deprecated"},
+			stdin: `a: Path("/a") -> oauthTokeninfoAnyScope("uid") -> <shunt>;`,
+			out:   "0 error(s), 0 warning(s)\n",
+		},
+		{
+			name: "bad deprecations",
+			args: []string{"-c", "testdata/bad-deprecations.yml", "lint", "testdata/routes.eskip"},
+			code: exitLoad,
+		},
+		{
+			name:  "allowed hosts",
+			args:  []string{"lint", "--allowed-host-suffix", "example.org", "--allowed-host-suffix", "example.com"},
+			stdin: "a: Host(/^a[.]example[.]net$/) && Path(\"/a\") -> <shunt>;\nb: Path(\"/b\") -> <shunt>;\nc: Host(/^c[.]example[.]com$/) && Path(\"/c\") -> <shunt>;",
+			code:  exitFailure,
+			out: `<stdin>:1: error: a: Host("^a[.]example[.]net$") can match host a.example.net outside the allowed domains (example.org, example.com) [host-not-allowed]
+<stdin>:2: warning: b: route has no Host predicate, it matches any host [any-host]
+1 error(s), 1 warning(s)
+`,
+		},
+		{
+			name:  "path subtree",
+			args:  []string{"lint", "--check-path-subtree", "--check-ambiguity"},
+			stdin: "a: PathSubtree(\"/a\") && Method(\"GET\") -> <shunt>;\nb: Path(\"/a/b\") && Header(\"X-A\", \"1\") -> <shunt>;",
+			out:   "<stdin>:2: warning: b: Path(\"/a/b\") is inside PathSubtree(\"/a\") of route a (<stdin>:1): this route wins for the exact path, eg. GET /a/b X-A: 1, route a serves the sub-paths, eg. GET /a/b/x X-A: 1 [path-in-subtree]\n0 error(s), 1 warning(s)\n",
+		},
+		{
+			name:  "parse error",
+			args:  []string{"lint"},
+			stdin: `a: Path("/a") ->`,
+			code:  exitFailure,
+		},
+		{
+			name: "missing file",
+			args: []string{"lint", "testdata/blue.eskip"},
+			code: exitLoad,
+		},
+		{
+			name: "bad severity",
+			args: []string{"lint", "--fail-on", "info", "testdata/routes.eskip"},
+			code: exitUsage,
+		},
+		{
+			name: "bad format",
+			args: []string{"lint", "--format", "yaml", "testdata/routes.eskip"},
+			code: exitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdin = strings.NewReader(tt.stdin)
+			exitCode = 0
+			app := NewApp()
+			out := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = &bytes.Buffer{}
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+
+			if exitCode != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, exitCode)
+			}
+			if tt.out != "" && out.String() != tt.out {
+				t.Errorf("expected output:\n%s\nbut got:\n%s", tt.out, out.String())
+			}
+		})
+	}
+}
+
+func TestLintCommandJSON(t *testing.T) {
+	app := NewApp()
+	out := &bytes.Buffer{}
+	app.Writer = out
+	app.ErrWriter = &bytes.Buffer{}
+	app.Run([]string{"eskip-match", "lint", "--format", "json", "testdata/lint.eskip"})
+
+	var findings []struct {
+		Rule     string
+		Severity string
+		RouteID  string
+		File     string
+		Line     int
+	}
+	if err := json.Unmarshal(out.Bytes(), &findings); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+	if len(findings) != 3 {
+		t.Fatalf("expected 3 findings but got %+v", findings)
+	}
+	if f := findings[0]; f.Rule != "shadowed-route" || f.Severity != "warning" || f.RouteID != "bar_copy" || f.Line != 2 {
+		t.Errorf("unexpected finding %+v", f)
+	}
+}
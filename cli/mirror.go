@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+)
+
+func newMirrorCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "mirror",
+		ArgsUsage:    "CURRENT_ROUTES_FILE CANDIDATE_ROUTES_FILE",
+		Usage:        "Audits the requests of an access log against two routes files, writing a json line for each request they route differently",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "access-log",
+				Value: stdinFile,
+				Usage: "Read the requests from the access log `FILE`, skipper, Apache common or combined lines or json request attributes (- for stdin, eg. a tail of the gateway log)",
+			},
+			cli.Float64Flag{
+				Name:  "sample-rate",
+				Value: 1,
+				Usage: "Audit the `FRACTION` of the requests, between 0 and 1",
+			},
+			cli.Int64Flag{
+				Name:  "seed",
+				Usage: "Sample the requests with `SEED`",
+			},
+			cli.BoolFlag{
+				Name:  "ignore-trailing-slash",
+				Usage: "Ignore trailing slashes in paths when matching",
+			},
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 2 {
+				return usageError("The current and the candidate routes files must be provided")
+			}
+			if rate := c.Float64("sample-rate"); rate <= 0 || rate > 1 {
+				return usageError("invalid sample rate %v, expected a fraction between 0 and 1", rate)
+			}
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			load := func(file string) (matcher.Matcher, error) {
+				return loadMatcher(c, &matcher.Options{
+					RoutesFile:          file,
+					MockFilters:         append(conf.CustomFilters, list(c.String("mock-filters"))...),
+					IgnoreTrailingSlash: c.Bool("ignore-trailing-slash"),
+					OnLoadProgress:      loadProgress(c.App.ErrWriter),
+				})
+			}
+			current, err := load(c.Args().Get(0))
+			if err != nil {
+				return loadError(err)
+			}
+			defer current.Close()
+			candidate, err := load(c.Args().Get(1))
+			if err != nil {
+				return loadError(err)
+			}
+			defer candidate.Close()
+
+			var log io.Reader = stdin
+			if file := c.String("access-log"); file != stdinFile {
+				f, err := os.Open(file)
+				if err != nil {
+					return loadError(err)
+				}
+				defer f.Close()
+				log = f
+			}
+
+			a := matcher.MirrorAuditorWith(current, candidate, c.App.Writer, matcher.AuditOptions{
+				SampleRate:      c.Float64("sample-rate"),
+				Seed:            c.Int64("seed"),
+				RedactedHeaders: conf.RedactedHeaders,
+			})
+			if err := a.AuditAccessLog(log); err != nil {
+				return err
+			}
+			stats := a.Stats()
+			if c.App.ErrWriter != nil {
+				fmt.Fprintf(c.App.ErrWriter, "%d of %d request(s) audited, %d invalid\n", stats.Audited, stats.Requests, stats.Errors)
+			}
+			if stats.Disagreements > 0 {
+				return failure("%d of %d audited request(s) resolve differently", stats.Disagreements, stats.Audited)
+			}
+			return nil
+		},
+	}
+}
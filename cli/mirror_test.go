@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+func TestMirrorCommand(t *testing.T) {
+	accessLog, err := ioutil.ReadFile("testdata/mirror/access.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := []string{"testdata/diff/old.eskip", "testdata/diff/new.eskip"}
+
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		code    int
+		changes []string
+		diag    string
+	}{
+		{
+			name:    "access log file",
+			args:    append([]string{"mirror", "--access-log", "testdata/mirror/access.log"}, routes...),
+			code:    exitFailure,
+			changes: []string{"orders -> orders", "legacy -> -", "- -> health"},
+			diag:    "4 of 4 request(s) audited, 0 invalid",
+		},
+		{
+			name:    "stdin",
+			args:    append([]string{"mirror"}, routes...),
+			stdin:   string(accessLog) + "not a request\n",
+			code:    exitFailure,
+			changes: []string{"orders -> orders", "legacy -> -", "- -> health"},
+			diag:    "4 of 5 request(s) audited, 1 invalid",
+		},
+		{
+			name:  "agreement",
+			args:  []string{"mirror", "testdata/diff/old.eskip", "testdata/diff/same.eskip"},
+			stdin: string(accessLog),
+			diag:  "4 of 4 request(s) audited, 0 invalid",
+		},
+		{
+			name: "missing routes file",
+			args: []string{"mirror", "testdata/diff/old.eskip"},
+			code: exitUsage,
+		},
+		{
+			name: "invalid sample rate",
+			args: append([]string{"mirror", "--sample-rate", "1.5"}, routes...),
+			code: exitUsage,
+		},
+		{
+			name: "missing access log",
+			args: append([]string{"mirror", "--access-log", "testdata/mirror/missing.log"}, routes...),
+			code: exitLoad,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdin = strings.NewReader(tt.stdin)
+			exitCode = 0
+			app := NewApp()
+			out := &bytes.Buffer{}
+			diag := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = diag
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+
+			if exitCode != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, exitCode)
+			}
+			var changes []string
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				if line == "" {
+					continue
+				}
+				var r matcher.AuditRecord
+				if err := json.Unmarshal([]byte(line), &r); err != nil {
+					t.Fatalf("invalid record %s: %v", line, err)
+				}
+				changes = append(changes, auditRouteID(r.Current)+" -> "+auditRouteID(r.Candidate))
+			}
+			if strings.Join(changes, ", ") != strings.Join(tt.changes, ", ") {
+				t.Errorf("expected changes %v but got %v", tt.changes, changes)
+			}
+			if !strings.Contains(diag.String(), tt.diag) {
+				t.Errorf("expected diagnostics to contain %q but got:\n%s", tt.diag, diag.String())
+			}
+		})
+	}
+}
+
+func auditRouteID(r *matcher.AuditRoute) string {
+	if r == nil {
+		return "-"
+	}
+	return r.ID
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// output formats
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+	// outputMarkdown report format, supported by some commands only
+	outputMarkdown = "markdown"
+)
+
+// outputFlag allows to choose between the human output and data formats
+var outputFlag = cli.StringFlag{
+	Name:  "output, o",
+	Value: outputText,
+	Usage: "Output `FORMAT`: text, json or yaml",
+}
+
+// outputFormat returns the output format selected by the command flags,
+// extra formats can be supported by the command in addition to the common ones
+func outputFormat(c *cli.Context, extra ...string) (string, error) {
+	format := c.String("output")
+	supported := append([]string{outputText, outputJSON, outputYAML}, extra...)
+	if format == "" {
+		return outputText, nil
+	}
+	for _, f := range supported {
+		if format == f {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported output format %q, use one of %s",
+		format, strings.Join(supported, ", "))
+}
+
+// isDataOutput true if the output must contain data only
+func isDataOutput(format string) bool {
+	return format == outputJSON || format == outputYAML
+}
+
+// diagnostics returns where decorative and diagnostic output must be written,
+// when data is written to stdout or in quiet mode it's the stderr
+func diagnostics(c *cli.Context, format string) io.Writer {
+	if isDataOutput(format) || c.Bool("quiet") {
+		return c.App.ErrWriter
+	}
+	return c.App.Writer
+}
+
+// writeData writes v as a single json or yaml document
+func writeData(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	default:
+		return fmt.Errorf("unsupported data format %q", format)
+	}
+}
+
+// loadMatcher creates a matcher even if routes use missing filters or
+// unknown predicates, the routes ignored because of them are reported on stderr
+func loadMatcher(c *cli.Context, o *matcher.Options) (matcher.Matcher, error) {
+	o.AllowMissingFilters = true
+	o.AllowUnknownPredicates = true
+	m, err := matcher.New(o)
+	if err != nil {
+		return nil, err
+	}
+	if c.App.ErrWriter == nil {
+		return m, nil
+	}
+	for _, f := range m.Findings() {
+		if f.RuleID == matcher.RuleUnknownFilter || f.RuleID == matcher.RuleUnknownPredicate {
+			fmt.Fprintln(c.App.ErrWriter, f)
+		}
+	}
+	return m, nil
+}
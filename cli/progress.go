@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+)
+
+// progressMinSize routes files smaller than this load without a progress indicator
+const progressMinSize = 4 << 20
+
+// loadProgress returns a matcher load progress callback rendering the
+// percentage of the routes file parsed on w, nil when w isn't a terminal
+func loadProgress(w io.Writer) func(parsed int, total int64) {
+	if !isTerminal(w) {
+		return nil
+	}
+	last := -1
+	return func(parsed int, total int64) {
+		if total < progressMinSize {
+			return
+		}
+		percent := int(int64(parsed) * 100 / total)
+		if percent == last {
+			return
+		}
+		last = percent
+		if int64(parsed) >= total {
+			// done, clear the line for the next output
+			fmt.Fprint(w, "\r\033[K")
+			return
+		}
+		fmt.Fprintf(w, "\rloading routes: %3d%%", percent)
+	}
+}
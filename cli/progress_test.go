@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestLoadProgress(t *testing.T) {
+	defer func(f func(io.Writer) bool) { isTerminal = f }(isTerminal)
+
+	isTerminal = func(io.Writer) bool { return false }
+	if loadProgress(&bytes.Buffer{}) != nil {
+		t.Fatal("no progress expected when not writing to a terminal")
+	}
+
+	isTerminal = func(io.Writer) bool { return true }
+	var out bytes.Buffer
+	progress := loadProgress(&out)
+	progress(10, progressMinSize-1)
+	if out.Len() != 0 {
+		t.Fatalf("no progress expected for a small file, got %q", out.String())
+	}
+	for _, parsed := range []int{progressMinSize / 4, progressMinSize / 4, progressMinSize / 2, progressMinSize} {
+		progress(parsed, progressMinSize)
+	}
+	if got, want := out.String(), "\rloading routes:  25%\rloading routes:  50%\r\033[K"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,326 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	replPrompt = "eskip-match> "
+	// replNearMisses how many near misses are shown in explain mode
+	replNearMisses = 3
+	replHelp       = `Request syntax: [METHOD] PATH [host=HOST] [Header-Name:value] [param=value]
+
+  GET /v1/orders?id=2 host=api.example.org X-Tenant:acme
+
+  METHOD         http method, GET when omitted
+  PATH           request path, eventually with a query string
+  host=HOST      request host
+  Name:value     request header
+  name=value     query parameter
+
+Commands:
+  :reload        reload the routes file
+  :verbose on    print the http request used for matching (off to disable)
+  :explain on    show the near misses when nothing matches (off to disable)
+  :help          show this help
+  :quit          exit`
+)
+
+// httpMethods methods recognized as the first token of a request line
+var httpMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"POST":    true,
+	"PUT":     true,
+	"PATCH":   true,
+	"DELETE":  true,
+	"CONNECT": true,
+	"OPTIONS": true,
+	"TRACE":   true,
+}
+
+func newReplCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "repl",
+		ArgsUsage:    "[ROUTES_FILE]",
+		Usage:        "Loads a routes file once and interactively tests the requests typed in",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "routes, r",
+				Usage: "Load routes from `FILE` (alternative to the ROUTES_FILE argument)",
+			},
+			cli.BoolFlag{
+				Name:  "ignore-trailing-slash",
+				Usage: "Ignore trailing slashes in paths when matching",
+			},
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
+			},
+			colorFlag,
+			noColorFlag,
+		},
+		Action: func(c *cli.Context) error {
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			routesFile := c.String("routes")
+			if routesFile == "" {
+				routesFile = c.Args().First()
+			}
+			if routesFile, err = conf.routesFile(routesFile); err != nil {
+				return loadError(err)
+			}
+			if routesFile == "" {
+				return usageError("A routes file must be provided")
+			}
+			style, err := outputStyle(c, os.Stdout)
+			if err != nil {
+				return usageError("%v", err)
+			}
+
+			r := &repl{
+				style: style,
+				newMatcher: func() (matcher.Matcher, error) {
+					return loadMatcher(c, &matcher.Options{
+						RoutesFile:          routesFile,
+						MockFilters:         append(conf.CustomFilters, list(c.String("mock-filters"))...),
+						IgnoreTrailingSlash: c.Bool("ignore-trailing-slash"),
+					})
+				},
+			}
+
+			if err := r.reload(); err != nil {
+				return loadError(err)
+			}
+			defer r.matcher.Close()
+
+			// use a line editor with history when attached to a terminal
+			fd := int(os.Stdin.Fd())
+			if terminal.IsTerminal(fd) {
+				state, err := terminal.MakeRaw(fd)
+				if err != nil {
+					return err
+				}
+				defer terminal.Restore(fd, state)
+
+				t := terminal.NewTerminal(struct {
+					io.Reader
+					io.Writer
+				}{os.Stdin, c.App.Writer}, replPrompt)
+				r.out = t
+				return r.run(t.ReadLine)
+			}
+
+			r.out = c.App.Writer
+			scanner := bufio.NewScanner(os.Stdin)
+			return r.run(scannerLines(scanner))
+		},
+	}
+}
+
+// scannerLines adapts a scanner to the function used by repl to read lines
+func scannerLines(scanner *bufio.Scanner) func() (string, error) {
+	return func() (string, error) {
+		if scanner.Scan() {
+			return scanner.Text(), nil
+		}
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+}
+
+// repl read-eval-print loop testing the request lines against a matcher
+type repl struct {
+	out        io.Writer
+	style      matcher.Style
+	newMatcher func() (matcher.Matcher, error)
+	matcher    matcher.Matcher
+	verbose    bool
+	explain    bool
+}
+
+// reload creates the matcher again, the current one is kept on failure
+func (r *repl) reload() error {
+	m, err := r.newMatcher()
+	if err != nil {
+		return err
+	}
+	if r.matcher != nil {
+		r.matcher.Close()
+	}
+	r.matcher = m
+	return nil
+}
+
+// run evaluates the lines returned by readLine until it returns an error,
+// io.EOF and the :quit command stop the loop successfully
+func (r *repl) run(readLine func() (string, error)) error {
+	for {
+		line, err := readLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if quit := r.eval(strings.TrimSpace(line)); quit {
+			return nil
+		}
+	}
+}
+
+// eval evaluates a single line, it returns true when the loop must stop
+func (r *repl) eval(line string) bool {
+	switch {
+	case line == "":
+	case line == ":quit" || line == ":q" || line == ":exit":
+		return true
+	case line == ":help":
+		r.print(replHelp)
+	case line == ":reload":
+		if err := r.reload(); err != nil {
+			r.println("reload failed: %v", err)
+		} else {
+			r.println("routes reloaded")
+		}
+	case strings.HasPrefix(line, ":verbose"):
+		r.verbose = r.toggle(line, r.verbose)
+		r.println("verbose %s", onOff(r.verbose))
+	case strings.HasPrefix(line, ":explain"):
+		r.explain = r.toggle(line, r.explain)
+		r.println("explain %s", onOff(r.explain))
+	case strings.HasPrefix(line, ":"):
+		r.println("unknown command %s, type :help for help", line)
+	default:
+		r.test(line)
+	}
+	return false
+}
+
+// toggle parses the on/off argument of a command, without argument switches the current value
+func (r *repl) toggle(line string, current bool) bool {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return !current
+	}
+	return fields[1] == "on" || fields[1] == "true"
+}
+
+func (r *repl) test(line string) {
+	attrs, err := parseRequestLine(line)
+	if err != nil {
+		r.println("invalid request: %v", err)
+		return
+	}
+
+	res := r.matcher.Test(attrs)
+	if r.verbose && res.Request() != nil {
+		req := res.Request()
+		r.println("http request: %s %s (host %q)", req.Method, req.URL.RequestURI(), req.Host)
+	}
+	for _, line := range res.PrettyPrintLinesWith(r.style) {
+		r.print(line)
+	}
+	if res.Route() != nil {
+		return
+	}
+
+	r.print(r.style.DecorateNoMatch("no match"))
+	if !r.explain {
+		return
+	}
+	for _, miss := range r.matcher.Explain(attrs, replNearMisses) {
+		r.println("near miss %s, %d failed predicate(s):", miss.RouteID, miss.Failed)
+		for _, v := range miss.Verdicts {
+			r.println("  %s %s (observed %q)", verdictMark(v, r.style), v.Predicate, v.Observed)
+		}
+	}
+}
+
+func (r *repl) println(format string, a ...interface{}) {
+	fmt.Fprintf(r.out, format+"\n", a...)
+}
+
+// print writes a line of text that isn't a format, eg. a printed route
+func (r *repl) print(s string) {
+	fmt.Fprintln(r.out, s)
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// verdictMark short representation of a predicate verdict
+func verdictMark(v matcher.PredicateVerdict, style matcher.Style) string {
+	if v.Matched {
+		return style.DecorateMatch("ok  ")
+	}
+	return style.DecorateNoMatch("FAIL")
+}
+
+// parseRequestLine parses the mini syntax used by the repl to describe a request:
+//
+//	[METHOD] PATH [host=HOST] [Header-Name:value] [param=value]
+//
+// PATH can be a full URL, in that case the host is taken from it too.
+func parseRequestLine(line string) (*matcher.RequestAttributes, error) {
+	attrs := &matcher.RequestAttributes{
+		Query:   map[string]string{},
+		Headers: map[string]string{},
+	}
+
+	tokens := strings.Fields(line)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty request")
+	}
+	if method := strings.ToUpper(tokens[0]); httpMethods[method] {
+		attrs.Method = method
+		tokens = tokens[1:]
+	}
+
+	for _, token := range tokens {
+		sep := strings.IndexAny(token, ":=")
+		switch {
+		case strings.HasPrefix(token, "/") && attrs.Path == "":
+			attrs.Path = token
+		case strings.HasPrefix(token, "http://") || strings.HasPrefix(token, "https://"):
+			u, err := url.Parse(token)
+			if err != nil {
+				return nil, err
+			}
+			attrs.Host = u.Host
+			attrs.Path = u.RequestURI()
+		case sep <= 0:
+			return nil, fmt.Errorf("unexpected %q, expecting a path, host=HOST, Name:value or name=value", token)
+		case token[sep] == ':':
+			attrs.Headers[token[:sep]] = token[sep+1:]
+		case strings.ToLower(token[:sep]) == "host":
+			attrs.Host = token[sep+1:]
+		default:
+			attrs.Query[token[:sep]] = token[sep+1:]
+		}
+	}
+
+	if attrs.Path == "" {
+		return nil, fmt.Errorf("missing path")
+	}
+	return attrs, nil
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+func TestParseRequestLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want *matcher.RequestAttributes
+		err  bool
+	}{
+		{
+			line: "/foo",
+			want: &matcher.RequestAttributes{Path: "/foo"},
+		},
+		{
+			line: "post /v1/orders",
+			want: &matcher.RequestAttributes{Method: "POST", Path: "/v1/orders"},
+		},
+		{
+			line: "GET /v1/orders?id=2 host=api.example.org X-Tenant:acme",
+			want: &matcher.RequestAttributes{
+				Method:  "GET",
+				Path:    "/v1/orders?id=2",
+				Host:    "api.example.org",
+				Headers: map[string]string{"X-Tenant": "acme"},
+			},
+		},
+		{
+			line: "DELETE /orders page=2 Authorization:Basic=a:b",
+			want: &matcher.RequestAttributes{
+				Method:  "DELETE",
+				Path:    "/orders",
+				Query:   map[string]string{"page": "2"},
+				Headers: map[string]string{"Authorization": "Basic=a:b"},
+			},
+		},
+		{
+			line: "PUT https://api.example.org/orders/1?x=y",
+			want: &matcher.RequestAttributes{Method: "PUT", Path: "/orders/1?x=y", Host: "api.example.org"},
+		},
+		{
+			line: "GET",
+			err:  true,
+		},
+		{
+			line: "GET /foo bar",
+			err:  true,
+		},
+		{
+			line: "GET /foo :value",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := parseRequestLine(tt.line)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected error but got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want.Query == nil {
+				tt.want.Query = map[string]string{}
+			}
+			if tt.want.Headers == nil {
+				tt.want.Headers = map[string]string{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v but got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRepl(t *testing.T) {
+	var out bytes.Buffer
+	reloads := 0
+	r := &repl{
+		out: &out,
+		newMatcher: func() (matcher.Matcher, error) {
+			reloads++
+			return matcher.New(&matcher.Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"mockedfilter"}})
+		},
+	}
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	script := strings.Join([]string{
+		"GET /bar",
+		"/foofoo",
+		":explain on",
+		"/orders host=www.example.org",
+		":verbose",
+		"/bar foo:bar",
+		":reload",
+		":blue",
+		"GET",
+		":quit",
+		"/bar",
+	}, "\n")
+	err := r.run(scannerLines(bufio.NewScanner(strings.NewReader(script))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"matching route id: bar\n",
+		"no match\n",
+		"explain on\n",
+		"near miss orders, 1 failed predicate(s):\n",
+		`FAIL Host("^api[.]example[.]org$") (observed "www.example.org")`,
+		"verbose on\n",
+		`http request: GET /bar (host "")`,
+		"matching route id: bar_header\n",
+		"routes reloaded\n",
+		"unknown command :blue",
+		"invalid request: missing path",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q but got:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "matching route id: bar\n") != 1 {
+		t.Errorf("expected lines after :quit to be ignored, got:\n%s", output)
+	}
+	if reloads != 2 {
+		t.Errorf("expected matcher to be created twice but got %d", reloads)
+	}
+}
+
+func TestReplPrintsPercent(t *testing.T) {
+	f, err := ioutil.TempFile("", "repl-*.eskip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`percent: Path("/p") -> setRequestHeader("X-Format", "%d%%") -> setPath("/%s") -> <shunt>;`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	var out bytes.Buffer
+	r := &repl{
+		out: &out,
+		newMatcher: func() (matcher.Matcher, error) {
+			return matcher.New(&matcher.Options{RoutesFile: f.Name()})
+		},
+	}
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.matcher.Close()
+	r.eval("GET /p")
+
+	if output := out.String(); !strings.Contains(output, `setPath("/%s")`) || strings.Contains(output, "%!") {
+		t.Errorf("expected the route to be printed as is but got:\n%s", output)
+	}
+}
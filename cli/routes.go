@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+	"github.com/zalando/skipper/eskip"
+)
+
+// routes listing formats
+const (
+	routesTable = "table"
+	routesJSON  = "json"
+	routesIDs   = "ids"
+	// routesInventory the matcher.Inventory of the whole routes file
+	routesInventory = "inventory"
+)
+
+// routeQuery selects the routes to list, all the conditions must be satisfied
+type routeQuery struct {
+	// id regexp the route id must match
+	id *regexp.Regexp
+	// predicates conditions on the predicates, see parsePredicateCondition
+	predicates []predicateCondition
+	// filters names of filters the route must use
+	filters []string
+	// backend text the backend address must contain
+	backend string
+}
+
+// predicateCondition a predicate the route must have, with arguments containing text when not empty
+type predicateCondition struct {
+	name string
+	text string
+}
+
+// parsePredicateCondition parses conditions like Host (the route has a Host
+// predicate) or Host~shop (the route has a Host predicate containing shop)
+func parsePredicateCondition(s string) (predicateCondition, error) {
+	parts := strings.SplitN(s, "~", 2)
+	cond := predicateCondition{name: strings.TrimSpace(parts[0])}
+	if cond.name == "" {
+		return cond, fmt.Errorf("invalid filter %q, use NAME or NAME~TEXT", s)
+	}
+	if len(parts) == 2 {
+		cond.text = parts[1]
+	}
+	return cond, nil
+}
+
+func (q *routeQuery) match(r *eskip.Route) bool {
+	if q.id != nil && !q.id.MatchString(r.Id) {
+		return false
+	}
+	for _, cond := range q.predicates {
+		if !hasPredicate(r, cond) {
+			return false
+		}
+	}
+	for _, name := range q.filters {
+		if !hasFilter(r, name) {
+			return false
+		}
+	}
+	if q.backend != "" {
+		data := matcher.NewRouteData(r)
+		backends := append([]string{data.Backend}, data.LBEndpoints...)
+		if !containsAny(backends, q.backend) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasPredicate(r *eskip.Route, cond predicateCondition) bool {
+	for _, p := range matcher.Predicates(r) {
+		if p.Name != cond.name {
+			continue
+		}
+		if cond.text == "" {
+			return true
+		}
+		for _, arg := range p.Args {
+			if strings.Contains(fmt.Sprint(arg), cond.text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasFilter(r *eskip.Route, name string) bool {
+	for _, f := range r.Filters {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(items []string, text string) bool {
+	for _, item := range items {
+		if strings.Contains(item, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func newRoutesCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "routes",
+		ArgsUsage:    "[ROUTES_FILE]",
+		Usage:        "Lists the routes loaded from a routes file, sorted by id",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "routes, r",
+				Usage: "Load routes from `FILE` (alternative to the ROUTES_FILE argument)",
+			},
+			cli.StringFlag{
+				Name:  "id",
+				Usage: "List only the routes with id matching `REGEXP`",
+			},
+			cli.StringSliceFlag{
+				Name:  "filter",
+				Usage: "List only the routes having the predicate `NAME`, or NAME~TEXT for a predicate with arguments containing TEXT (eg. Host~shop)",
+			},
+			cli.StringSliceFlag{
+				Name:  "uses-filter",
+				Usage: "List only the routes using the filter `NAME`",
+			},
+			cli.StringFlag{
+				Name:  "backend",
+				Usage: "List only the routes with a backend containing `TEXT`",
+			},
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
+			},
+			cli.StringFlag{
+				Name:  "format, f",
+				Value: routesTable,
+				Usage: "Output `FORMAT`: table, json, ids (one id per line) or inventory (versioned json of all the routes with their comments, for other tools)",
+			},
+			cli.BoolFlag{
+				Name:  "effective",
+				Usage: "List the whole route table the routing applied in eskip (or json with --format json), annotated with what eskip-match changed, followed by the dropped routes",
+			},
+			cli.StringFlag{
+				Name:  "default-filters-prepend",
+				Usage: "`FILTERS` added before the filters of every route, like the skipper flag",
+			},
+			cli.StringFlag{
+				Name:  "default-filters-append",
+				Usage: "`FILTERS` added after the filters of every route, like the skipper flag",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			routesFile := c.String("routes")
+			if routesFile == "" {
+				routesFile = c.Args().First()
+			}
+			if routesFile, err = conf.routesFile(routesFile); err != nil {
+				return loadError(err)
+			}
+			if routesFile == "" {
+				return usageError("A routes file must be provided")
+			}
+			format := c.String("format")
+			if format != routesTable && format != routesJSON && format != routesIDs && format != routesInventory {
+				return usageError("unsupported format %q, use one of %s, %s, %s, %s", format, routesTable, routesJSON, routesIDs, routesInventory)
+			}
+			if c.Bool("effective") && (format == routesIDs || format == routesInventory) {
+				return usageError("--effective lists the routes in eskip or json")
+			}
+			if format == routesInventory {
+				for _, name := range []string{"id", "filter", "uses-filter", "backend"} {
+					if c.IsSet(name) {
+						return usageError("--format inventory exports all the routes, --%s can't be used", name)
+					}
+				}
+				err := matcher.ExportInventory(&matcher.Options{
+					RoutesFile:     routesFile,
+					OnLoadProgress: loadProgress(c.App.ErrWriter),
+				}, c.App.Writer)
+				if err != nil {
+					return loadError(err)
+				}
+				return nil
+			}
+
+			q := &routeQuery{filters: c.StringSlice("uses-filter"), backend: c.String("backend")}
+			if expr := c.String("id"); expr != "" {
+				rx, err := regexp.Compile(expr)
+				if err != nil {
+					return usageError("invalid id regexp: %v", err)
+				}
+				q.id = rx
+			}
+			for _, s := range c.StringSlice("filter") {
+				cond, err := parsePredicateCondition(s)
+				if err != nil {
+					return usageError("%v", err)
+				}
+				q.predicates = append(q.predicates, cond)
+			}
+
+			_, locations, err := parseRoutesFile(routesFile)
+			if err != nil {
+				return loadError(err)
+			}
+			m, err := loadMatcher(c, &matcher.Options{
+				RoutesFile:            routesFile,
+				MockFilters:           append(conf.CustomFilters, list(c.String("mock-filters"))...),
+				DefaultFiltersPrepend: c.String("default-filters-prepend"),
+				DefaultFiltersAppend:  c.String("default-filters-append"),
+				OnLoadProgress:        loadProgress(c.App.ErrWriter),
+			})
+			if err != nil {
+				return loadError(err)
+			}
+			defer m.Close()
+
+			if c.Bool("effective") {
+				if format == routesJSON {
+					return m.DumpEffectiveRoutes(c.App.Writer, matcher.FormatJSON)
+				}
+				return m.DumpEffectiveRoutes(c.App.Writer, matcher.FormatEskip)
+			}
+
+			selected := []*matcher.RouteData{}
+			for _, r := range m.Routes() {
+				if !q.match(r) {
+					continue
+				}
+				data := matcher.NewRouteData(r)
+				if loc, ok := locations[r.Id]; ok {
+					data.Location = &loc
+				}
+				selected = append(selected, data)
+			}
+
+			switch format {
+			case routesJSON:
+				return writeData(c.App.Writer, outputJSON, selected)
+			case routesIDs:
+				for _, r := range selected {
+					fmt.Fprintln(c.App.Writer, r.ID)
+				}
+				return nil
+			default:
+				return writeRoutesTable(c.App.Writer, selected)
+			}
+		},
+	}
+}
+
+// writeRoutesTable writes the routes as a table with columns as wide as their content
+func writeRoutesTable(w io.Writer, routes []*matcher.RouteData) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tHOST\tPATH\tBACKEND\tFILTERS")
+	for _, r := range routes {
+		var hosts, paths []string
+		for _, p := range r.Predicates {
+			switch {
+			case strings.HasPrefix(p, "Host("):
+				hosts = append(hosts, p)
+			case strings.HasPrefix(p, "Path"):
+				paths = append(paths, p)
+			}
+		}
+		var filters []string
+		for _, f := range r.Filters {
+			filters = append(filters, f.Name)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.ID, column(hosts), column(paths), r.Backend, column(filters))
+	}
+	return tw.Flush()
+}
+
+// column joins the values of a table cell, - when empty
+func column(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ", ")
+}
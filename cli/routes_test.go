@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+func TestRoutesTable(t *testing.T) {
+	runGolden(t, "routes.txt", "routes", "-routes", "testdata/inventory.eskip")
+}
+
+func TestRoutesFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		out  string
+	}{
+		{
+			name: "all",
+			out:  "api_lb\napi_orders\nhealth\nshop_cart\nshop_home\n",
+		},
+		{
+			name: "id regexp",
+			args: []string{"--id", "^api_"},
+			out:  "api_lb\napi_orders\n",
+		},
+		{
+			name: "predicate presence",
+			args: []string{"--filter", "Method"},
+			out:  "api_orders\n",
+		},
+		{
+			name: "predicate text",
+			args: []string{"--filter", "Host~shop"},
+			out:  "shop_cart\nshop_home\n",
+		},
+		{
+			name: "predicate conditions combined",
+			args: []string{"--filter", "Host~shop", "--filter", "PathSubtree"},
+			out:  "shop_cart\n",
+		},
+		{
+			name: "filter name",
+			args: []string{"--uses-filter", "stripQuery"},
+			out:  "shop_cart\n",
+		},
+		{
+			name: "backend substring",
+			args: []string{"--backend", "legacy"},
+			out:  "api_lb\napi_orders\n",
+		},
+		{
+			name: "nothing selected",
+			args: []string{"--filter", "Cookie"},
+			out:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-r", "testdata/inventory.eskip", "-f", "ids"}, tt.args...)
+			code, out := runCommand(t, "routes", args...)
+			if code != exitOK {
+				t.Errorf("expected exit code %d but got %d", exitOK, code)
+			}
+			if out != tt.out {
+				t.Errorf("expected output %q but got %q", tt.out, out)
+			}
+		})
+	}
+}
+
+func TestRoutesJSON(t *testing.T) {
+	code, out := runCommand(t, "routes", "-r", "testdata/inventory.eskip", "-f", "json", "--id", "shop_cart")
+	if code != exitOK {
+		t.Fatalf("expected exit code %d but got %d", exitOK, code)
+	}
+	var routes []*matcher.RouteData
+	if err := json.Unmarshal([]byte(out), &routes); err != nil {
+		t.Fatalf("invalid json %q: %v", out, err)
+	}
+	if len(routes) != 1 {
+		t.Fatalf("expected 1 route but got %d", len(routes))
+	}
+	r := routes[0]
+	if r.ID != "shop_cart" || r.Backend != "https://cart.internal" || len(r.Filters) != 2 {
+		t.Errorf("unexpected route %+v", r)
+	}
+	if r.Location == nil || r.Location.Line != 2 {
+		t.Errorf("expected the route location at line 2 but got %+v", r.Location)
+	}
+}
+
+func TestRoutesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"missing routes file", nil, exitUsage},
+		{"unsupported format", []string{"testdata/inventory.eskip", "-f", "csv"}, exitUsage},
+		{"invalid id regexp", []string{"-r", "testdata/inventory.eskip", "--id", "("}, exitUsage},
+		{"invalid filter", []string{"-r", "testdata/inventory.eskip", "--filter", "~shop"}, exitUsage},
+		{"missing routes file path", []string{"testdata/missing.eskip"}, exitLoad},
+		{"inventory with a selection", []string{"-r", "testdata/catalog.eskip", "-f", "inventory", "--id", "shop"}, exitUsage},
+		{"inventory of the effective routes", []string{"-r", "testdata/catalog.eskip", "-f", "inventory", "--effective"}, exitUsage},
+		{"inventory of a missing routes file", []string{"-r", "testdata/missing.eskip", "-f", "inventory"}, exitLoad},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := runCommand(t, "routes", tt.args...); code != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, code)
+			}
+		})
+	}
+}
+
+func TestRoutesEffective(t *testing.T) {
+	args := []string{"-routes", "testdata/effective.eskip", "--effective", "--mock-filters", "customfilter", "--default-filters-prepend", `setRequestHeader("X-Env", "test")`}
+	runGolden(t, "routes-effective.eskip", "routes", args...)
+
+	runGolden(t, "routes-effective.json", "routes", append(args, "--format", "json")...)
+
+	if code, _ := runCommand(t, "routes", append(args, "--format", "ids")...); code != exitUsage {
+		t.Errorf("expected exit code %d but got %d", exitUsage, code)
+	}
+}
+
+func TestRoutesInventory(t *testing.T) {
+	runGolden(t, "routes-inventory.json", "routes", "-r", "testdata/catalog.eskip", "--format", "inventory")
+}
@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/server"
+	"github.com/rbarilani/eskip-match/server/prommetrics"
+	"github.com/rbarilani/eskip-match/suite"
+	"github.com/urfave/cli"
+)
+
+const (
+	// serveListen default address of the serve command
+	serveListen = ":9090"
+	// serveShutdownTimeout how long the running requests are waited for on exit
+	serveShutdownTimeout = 5 * time.Second
+)
+
+func newServeCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "serve",
+		ArgsUsage:    "[ROUTES_FILE]",
+		Usage:        "Starts an HTTP server answering match requests against a routes file",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "routes, r",
+				Usage: "Load routes from `FILE` (alternative to the ROUTES_FILE argument)",
+			},
+			cli.StringFlag{
+				Name:  "listen, l",
+				Value: serveListen,
+				Usage: "Listen on `ADDRESS`",
+			},
+			cli.BoolFlag{
+				Name:  "watch, w",
+				Usage: "Watch the routes file and reload it on every change",
+			},
+			cli.BoolFlag{
+				Name:  "metrics",
+				Usage: "Serve Prometheus metrics of the matches and the reloads at /metrics",
+			},
+			cli.StringSliceFlag{
+				Name:  "pin",
+				Usage: "Run the cases of the fixture files matching `PATTERN` after every reload and stream their changes at /events",
+			},
+			cli.StringFlag{
+				Name:  "notify-url",
+				Usage: "Post the pinned cases a reload makes fail to the webhook `URL`, eg. a Slack incoming webhook",
+			},
+			cli.StringFlag{
+				Name:  "notify-template",
+				Usage: "Post the json payload of the Go `TEMPLATE` instead of the regression, eg. '{\"text\": {{json .Summary}}}'",
+			},
+			cli.BoolFlag{
+				Name:  "allow-push",
+				Usage: "Accept routes pushed as text/eskip to POST /-/reload, replacing the ones of the routes file until the next reload",
+			},
+			cli.StringFlag{
+				Name:   "reload-token",
+				EnvVar: "ESKIP_MATCH_RELOAD_TOKEN",
+				Usage:  "Require the bearer `TOKEN` to POST /-/reload",
+			},
+			cli.BoolFlag{
+				Name:  "ignore-trailing-slash",
+				Usage: "Ignore trailing slashes in paths when matching",
+			},
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
+			},
+			verboseFlag,
+			vvFlag,
+			vvvFlag,
+		},
+		Action: func(c *cli.Context) error {
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			routesFile := c.String("routes")
+			if routesFile == "" {
+				routesFile = c.Args().First()
+			}
+			if routesFile, err = conf.routesFile(routesFile); err != nil {
+				return loadError(err)
+			}
+			if routesFile == "" {
+				return usageError("A routes file must be provided")
+			}
+
+			notify := server.NotifyOptions{URL: c.String("notify-url"), Log: c.App.ErrWriter}
+			if text := c.String("notify-template"); text != "" {
+				if notify.URL == "" {
+					return usageError("--notify-template requires --notify-url")
+				}
+				if notify.Template, err = server.NotifyTemplate(text); err != nil {
+					return usageError("invalid notify template: %v", err)
+				}
+			}
+			var pinned []*suite.Suite
+			if patterns := c.StringSlice("pin"); len(patterns) > 0 {
+				if pinned, err = suite.LoadFiles(patterns...); err != nil {
+					return loadError(err)
+				}
+			}
+			var metrics server.Metrics
+			var hooks matcher.Hooks
+			if c.Bool("metrics") {
+				metrics = prommetrics.New()
+				hooks = server.MetricsHooks(metrics)
+			}
+			options := func(file string) *matcher.Options {
+				return &matcher.Options{
+					RoutesFile:          file,
+					MockFilters:         append(conf.CustomFilters, list(c.String("mock-filters"))...),
+					IgnoreTrailingSlash: c.Bool("ignore-trailing-slash"),
+					OnLoadProgress:      loadProgress(c.App.ErrWriter),
+					Verbosity:           verbosity(c),
+					Log:                 c.App.ErrWriter,
+					RedactedHeaders:     conf.RedactedHeaders,
+					Hooks:               hooks,
+				}
+			}
+			serverOptions := server.ServerOptions{Metrics: metrics, Pinned: pinned, ReloadToken: c.String("reload-token"), Notify: notify}
+			if c.Bool("allow-push") {
+				serverOptions.LoadDocument = func(doc []byte) (*server.Table, error) {
+					return loadPushedRoutes(doc, options)
+				}
+			}
+			srv := server.NewWithOptions(func() (*server.Table, error) {
+				_, locations, err := parseRoutesFile(routesFile)
+				if err != nil {
+					return nil, err
+				}
+				m, err := loadMatcher(c, options(routesFile))
+				if err != nil {
+					return nil, err
+				}
+				return &server.Table{Matcher: m, Routes: m.Routes(), Locations: locations}, nil
+			}, serverOptions)
+			if err := srv.Reload(); err != nil {
+				return loadError(err)
+			}
+
+			ln, err := net.Listen("tcp", c.String("listen"))
+			if err != nil {
+				return err
+			}
+			httpServer := &http.Server{Handler: srv}
+			// the event streams would keep the shutdown waiting, the
+			// notifications retries outlive it
+			httpServer.RegisterOnShutdown(srv.Close)
+			served := make(chan error, 1)
+			go func() {
+				served <- httpServer.Serve(ln)
+			}()
+			fmt.Fprintf(c.App.Writer, "listening on http://%s, press Ctrl+C to exit\n", ln.Addr())
+
+			stop := interrupted()
+			if c.Bool("watch") {
+				w := newWatcher(c.App.ErrWriter, routesFile)
+				w.clear = false
+				// the routes are already loaded by the first reload above
+				w.skipFirst = true
+				go w.run(stop, srv.Reload)
+			}
+
+			select {
+			case err := <-served:
+				return err
+			case <-stop:
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+			defer cancel()
+			return httpServer.Shutdown(ctx)
+		},
+	}
+}
+
+// loadPushedRoutes creates the routing table of the routes pushed to
+// /-/reload: unlike the routes file, the routes with unknown filters or
+// predicates or with invalid filter arguments are refused
+func loadPushedRoutes(doc []byte, options func(file string) *matcher.Options) (*server.Table, error) {
+	f, err := ioutil.TempFile("", "eskip-match-*.eskip")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(doc)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	o := options(f.Name())
+	o.FailOnLoadErrors = true
+	m, err := matcher.New(o)
+	if err != nil {
+		return nil, err
+	}
+	return &server.Table{Matcher: m, Routes: m.Routes(), Locations: matcher.RouteLocations(server.PushedDocument, doc)}, nil
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestServeCommand(t *testing.T) {
+	stop := make(chan struct{})
+	defer func(f func() <-chan struct{}) { interrupted = f }(interrupted)
+	interrupted = func() <-chan struct{} { return stop }
+
+	out, w := io.Pipe()
+	app := NewApp()
+	app.Writer = w
+	app.ErrWriter = &bytes.Buffer{}
+	done := make(chan error)
+	go func() {
+		done <- app.Run([]string{"eskip-match", "serve", "-r", "testdata/routes.eskip", "--listen", "127.0.0.1:0", "--mock-filters", "mockedfilter", "--pin", "testdata/fixtures/search.yaml", "--allow-push", "--reload-token", "t0ken"})
+	}()
+
+	line, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := strings.Fields(strings.TrimPrefix(line, "listening on "))[0]
+	addr = strings.TrimSuffix(addr, ",")
+
+	rsp, err := http.Post(addr+"/match", "application/json", strings.NewReader(`{"path": "/mocked"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"routeId":"mocked"`) {
+		t.Errorf("expected mocked route to match but got %d %s", rsp.StatusCode, body)
+	}
+
+	rsp, err = http.Get(addr + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Errorf("expected server to be ready but got %d", rsp.StatusCode)
+	}
+
+	pushes := []struct {
+		doc    string
+		status int
+	}{
+		{`pushed: Path("/pushed") -> mockedfilter() -> <shunt>;`, http.StatusOK},
+		{`unknown: Path("/unknown") -> unknownFilter() -> <shunt>;`, http.StatusUnprocessableEntity},
+	}
+	for _, p := range pushes {
+		req, _ := http.NewRequest("POST", addr+"/-/reload", strings.NewReader(p.doc))
+		req.Header.Set("Content-Type", "text/eskip")
+		req.Header.Set("Authorization", "Bearer t0ken")
+		// a connection dialed but not used delays the shutdown
+		req.Close = true
+		rsp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = ioutil.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		if rsp.StatusCode != p.status {
+			t.Errorf("expected %d pushing %s but got %d %s", p.status, p.doc, rsp.StatusCode, body)
+		}
+	}
+	rsp, err = http.Post(addr+"/match", "application/json", strings.NewReader(`{"path": "/pushed"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if !strings.Contains(string(body), `"routeId":"pushed"`) {
+		t.Errorf("expected the pushed route to match but got %s", body)
+	}
+
+	// the shutdown ends the event streams
+	rsp, err = http.Get(addr + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	snapshot, err := bufio.NewReader(rsp.Body).ReadString('\n')
+	if err != nil || snapshot != "event: snapshot\n" {
+		t.Errorf("expected the snapshot event but got %q, %v", snapshot, err)
+	}
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("expected clean shutdown but got %v", err)
+	}
+}
+
+func TestServeCommandErrors(t *testing.T) {
+	invalid, err := ioutil.TempFile("", "routes-*.eskip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(invalid.Name())
+	invalid.WriteString(`foo: Path("/foo") -> `)
+	invalid.Close()
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "missing routes", args: []string{"serve"}, code: exitUsage},
+		{name: "invalid routes", args: []string{"serve", "-r", invalid.Name()}, code: exitLoad},
+		{name: "missing file", args: []string{"serve", "-r", "testdata/missing.eskip"}, code: exitLoad},
+		{name: "template without url", args: []string{"serve", "-r", "testdata/routes.eskip", "--notify-template", "{}"}, code: exitUsage},
+		{name: "invalid template", args: []string{"serve", "-r", "testdata/routes.eskip", "--notify-url", "http://localhost", "--notify-template", "{{json"}, code: exitUsage},
+		{name: "missing pinned fixture", args: []string{"serve", "-r", "testdata/routes.eskip", "--pin", "testdata/fixtures/missing.yaml"}, code: exitLoad},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := run(tt.args...)
+			if code != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, code)
+			}
+		})
+	}
+}
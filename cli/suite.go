@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/suite"
+	"github.com/urfave/cli"
+)
+
+func newSuiteCommand(o *options) cli.Command {
+	return cli.Command{
+		Name:         "suite",
+		ArgsUsage:    "FIXTURE_FILES...",
+		Usage:        "Runs fixture suites against a routes file (patterns like tests/**/*.yaml are supported)",
+		OnUsageError: onUsageError,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "routes, r",
+				Usage: "Load routes from `FILE`",
+			},
+			cli.IntFlag{
+				Name:  "parallel",
+				Value: 1,
+				Usage: "Run `N` cases at the same time",
+			},
+			cli.StringFlag{
+				Name:  "tags",
+				Usage: "Comma separated list of `TAGS`, run only the cases having at least one of them",
+			},
+			cli.BoolFlag{
+				Name:  "fail-fast",
+				Usage: "Stop after the first failure",
+			},
+			cli.StringSliceFlag{
+				Name:  "report",
+				Usage: "Write a report as `FORMAT=FILE`, formats are junit and md (eg. junit=report.xml)",
+			},
+			cli.BoolFlag{
+				Name:  "update-snapshots",
+				Usage: "Write the snapshot files of the cases expecting one instead of checking them",
+			},
+			cli.BoolFlag{
+				Name:  "ignore-trailing-slash",
+				Usage: "Ignore trailing slashes in paths when matching",
+			},
+			cli.StringFlag{
+				Name:  "mock-filters",
+				Usage: "Comma separated list of custom filter `NAMES` to mock (in addition to the configured ones)",
+			},
+			cli.BoolFlag{
+				Name:  "watch, w",
+				Usage: "Watch the routes and fixture files and run the suites again on every change",
+			},
+			cli.BoolFlag{
+				Name:  "minimize",
+				Usage: "Print a minimal subset of the cases hitting all the routes they hit instead of running them",
+			},
+			cli.BoolFlag{
+				Name:  "mutate",
+				Usage: "Run the suites against mutants of the routes and print the mutants no case kills",
+			},
+			cli.IntFlag{
+				Name:  "max-mutants",
+				Value: suite.DefaultMaxMutants,
+				Usage: "Test at most `N` mutants with --mutate",
+			},
+			outputFlag,
+			verboseFlag,
+			vvFlag,
+			vvvFlag,
+		},
+		Action: func(c *cli.Context) error {
+			conf, err := o.ConfigLoader.Load(o.ConfigFile)
+			if err != nil {
+				return loadError(err)
+			}
+			routesFile, err := conf.routesFile(c.String("routes"))
+			if err != nil {
+				return loadError(err)
+			}
+			if routesFile == "" {
+				return usageError("A routes file must be provided")
+			}
+			if len(c.Args()) == 0 {
+				return usageError("At least a fixture file must be provided")
+			}
+			format, err := outputFormat(c)
+			if err != nil {
+				return usageError("%v", err)
+			}
+			reportFlags := c.StringSlice("report")
+			if len(reportFlags) == 0 {
+				reportFlags = conf.Reports
+			}
+			reports, err := reportFiles(reportFlags)
+			if err != nil {
+				return usageError("%v", err)
+			}
+
+			if c.Bool("minimize") || c.Bool("mutate") {
+				if c.Bool("watch") || len(reports) > 0 || c.Bool("update-snapshots") || (c.Bool("minimize") && c.Bool("mutate")) {
+					return usageError("--minimize and --mutate can't be used together or with --watch, --report or --update-snapshots")
+				}
+				if c.Bool("mutate") {
+					return mutateSuites(c, conf, routesFile, format)
+				}
+				return minimizeSuites(c, conf, routesFile, format)
+			}
+
+			check := func() error {
+				return runSuites(c, conf, routesFile, format, reports)
+			}
+			if c.Bool("watch") {
+				suites, err := suite.LoadFiles(c.Args()...)
+				if err != nil {
+					return loadError(err)
+				}
+				files := []string{routesFile}
+				for _, s := range suites {
+					files = append(files, s.File)
+				}
+				return newWatcher(diagnostics(c, format), files...).run(interrupted(), check)
+			}
+			return check()
+		},
+	}
+}
+
+// report formats
+const (
+	reportJUnit    = "junit"
+	reportMarkdown = "md"
+)
+
+// reportFile a report to write after running the suites
+type reportFile struct {
+	format string
+	file   string
+}
+
+// reportFiles parses the FORMAT=FILE report flags
+func reportFiles(items []string) ([]reportFile, error) {
+	var reports []reportFile
+	for _, item := range items {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid report %q, use FORMAT=FILE", item)
+		}
+		if parts[0] != reportJUnit && parts[0] != reportMarkdown {
+			return nil, fmt.Errorf("unsupported report format %q, use one of junit or md", parts[0])
+		}
+		reports = append(reports, reportFile{parts[0], parts[1]})
+	}
+	return reports, nil
+}
+
+// runSuites runs the fixture suites given by the command arguments against
+// routesFile, prints the result and writes the reports.
+// It returns an error with exitFailure code when a case fails.
+func runSuites(c *cli.Context, conf config, routesFile string, format string, reports []reportFile) error {
+	suites, err := suite.LoadFiles(c.Args()...)
+	if err != nil {
+		return loadError(err)
+	}
+
+	m, err := loadMatcher(c, &matcher.Options{
+		RoutesFile:          routesFile,
+		MockFilters:         append(conf.CustomFilters, list(c.String("mock-filters"))...),
+		IgnoreTrailingSlash: c.Bool("ignore-trailing-slash"),
+		OnLoadProgress:      loadProgress(c.App.ErrWriter),
+		Verbosity:           verbosity(c),
+		Log:                 c.App.ErrWriter,
+	})
+	if err != nil {
+		return loadError(err)
+	}
+	defer m.Close()
+
+	res := suite.Run(m, suites, &suite.RunOptions{
+		Parallel:        c.Int("parallel"),
+		Tags:            list(c.String("tags")),
+		FailFast:        c.Bool("fail-fast"),
+		UpdateSnapshots: c.Bool("update-snapshots"),
+	})
+
+	if isDataOutput(format) {
+		err = writeData(c.App.Writer, format, res)
+	} else {
+		err = res.WriteText(c.App.Writer)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, r := range reports {
+		if err := writeReport(r, res); err != nil {
+			return err
+		}
+	}
+
+	if !res.OK() {
+		return failure("%d of %d case(s) failed", res.Failed, len(res.Cases))
+	}
+	return nil
+}
+
+// minimizeSuites prints the cases of the fixture suites given by the command
+// arguments to keep to hit all the routes they hit, following the loopback
+// routes, and the ones to drop
+func minimizeSuites(c *cli.Context, conf config, routesFile string, format string) error {
+	suites, err := suite.LoadFiles(c.Args()...)
+	if err != nil {
+		return loadError(err)
+	}
+
+	m, err := loadMatcher(c, &matcher.Options{
+		RoutesFile:          routesFile,
+		MockFilters:         append(conf.CustomFilters, list(c.String("mock-filters"))...),
+		IgnoreTrailingSlash: c.Bool("ignore-trailing-slash"),
+		FollowLoopback:      true,
+		OnLoadProgress:      loadProgress(c.App.ErrWriter),
+		Verbosity:           verbosity(c),
+		Log:                 c.App.ErrWriter,
+	})
+	if err != nil {
+		return loadError(err)
+	}
+	defer m.Close()
+
+	var cases []suite.Case
+	for _, s := range suites {
+		cases = append(cases, s.Cases...)
+	}
+	r := suite.Minimize(m, cases)
+	if isDataOutput(format) {
+		return writeData(c.App.Writer, format, r)
+	}
+	return r.WriteText(c.App.Writer)
+}
+
+// mutateSuites runs the fixture suites given by the command arguments
+// against mutants of routesFile and prints the kill rate with the surviving
+// mutants
+func mutateSuites(c *cli.Context, conf config, routesFile string, format string) error {
+	suites, err := suite.LoadFiles(c.Args()...)
+	if err != nil {
+		return loadError(err)
+	}
+
+	r, err := suite.Mutate(&matcher.Options{
+		RoutesFile:          routesFile,
+		MockFilters:         append(conf.CustomFilters, list(c.String("mock-filters"))...),
+		IgnoreTrailingSlash: c.Bool("ignore-trailing-slash"),
+		// like loadMatcher, the routes with unknown filters are loaded without them
+		AllowMissingFilters:    true,
+		AllowUnknownPredicates: true,
+		Verbosity:              verbosity(c),
+		Log:                    c.App.ErrWriter,
+	}, suites, &suite.MutateOptions{
+		MaxMutants: c.Int("max-mutants"),
+		Parallel:   c.Int("parallel"),
+	})
+	if err != nil {
+		return loadError(err)
+	}
+	if isDataOutput(format) {
+		return writeData(c.App.Writer, format, r)
+	}
+	return r.WriteText(c.App.Writer)
+}
+
+// writeReport writes the result to the report file
+func writeReport(r reportFile, res *suite.Result) error {
+	f, err := os.Create(r.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	write := map[string]func(io.Writer) error{
+		reportJUnit:    res.WriteJUnit,
+		reportMarkdown: res.WriteMarkdown,
+	}[r.format]
+	if err := write(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSuiteCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	junit := filepath.Join(dir, "report.xml")
+	md := filepath.Join(dir, "report.md")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		out  string
+	}{
+		{
+			name: "pass",
+			args: []string{"suite", "-r", "testdata/routes.eskip", "testdata/fixtures/*.yaml"},
+			out:  "4 passed, 0 failed, 0 skipped\n",
+		},
+		{
+			name: "fail",
+			args: []string{"suite", "-r", "testdata/routes.eskip", "--parallel", "2", "testdata/fixtures/*.yaml", "testdata/failing.yaml"},
+			code: exitFailure,
+			out:  "FAIL failing / wrong route: expected route bar_header to match but route bar matches\n\n4 passed, 1 failed, 0 skipped\n",
+		},
+		{
+			name: "tags",
+			args: []string{"suite", "-r", "testdata/routes.eskip", "--tags", "smoke,other", "testdata/fixtures/*.yaml", "testdata/failing.yaml"},
+			code: exitFailure,
+			out:  "FAIL failing / wrong route: expected route bar_header to match but route bar matches\n\n0 passed, 1 failed, 4 skipped\n",
+		},
+		{
+			name: "fail fast",
+			args: []string{"suite", "-r", "testdata/routes.eskip", "--fail-fast", "testdata/failing.yaml", "testdata/fixtures/*.yaml"},
+			code: exitFailure,
+			out:  "FAIL failing / wrong route: expected route bar_header to match but route bar matches\n\n0 passed, 1 failed, 4 skipped\n",
+		},
+		{
+			name: "reports",
+			args: []string{"suite", "-r", "testdata/routes.eskip", "--report", "junit=" + junit, "--report", "md=" + md, "testdata/fixtures/*.yaml"},
+			out:  "4 passed, 0 failed, 0 skipped\n",
+		},
+		{
+			name: "minimize",
+			args: []string{"suite", "-r", "testdata/routes.eskip", "--minimize", "testdata/fixtures/*.yaml", "testdata/fixtures/*.yaml"},
+			out: "keep bar: bar\nkeep bar with header: bar_header\nkeep orders: orders\nkeep search: search\n" +
+				"drop bar: bar\ndrop bar with header: bar_header\ndrop orders: orders\ndrop search: search\n" +
+				"routes needing a dedicated case: bar, bar_header, orders, search\n" +
+				"4 of 8 cases hit the 4 routes hit by all of them\n",
+		},
+		{
+			name: "mutate",
+			args: []string{"suite", "-r", "testdata/routes.eskip", "--mutate", "--max-mutants", "2", "testdata/fixtures/*.yaml"},
+			out:  "2 of 2 mutants killed (100.0%), capped at 2 mutants\n",
+		},
+		{
+			name: "mutate and minimize",
+			args: []string{"suite", "-r", "testdata/routes.eskip", "--mutate", "--minimize", "testdata/fixtures/*.yaml"},
+			code: exitUsage,
+		},
+		{
+			name: "minimize and watch",
+			args: []string{"suite", "-r", "testdata/routes.eskip", "--minimize", "--watch", "testdata/fixtures/*.yaml"},
+			code: exitUsage,
+		},
+		{
+			name: "missing routes",
+			args: []string{"suite", "testdata/fixtures/*.yaml"},
+			code: exitUsage,
+		},
+		{
+			name: "missing fixtures",
+			args: []string{"suite", "-r", "testdata/routes.eskip"},
+			code: exitUsage,
+		},
+		{
+			name: "bad report",
+			args: []string{"suite", "-r", "testdata/routes.eskip", "--report", "html=report.html", "testdata/fixtures/*.yaml"},
+			code: exitUsage,
+		},
+		{
+			name: "fixtures not found",
+			args: []string{"suite", "-r", "testdata/routes.eskip", "testdata/fixtures/*.json"},
+			code: exitLoad,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode = 0
+			app := NewApp()
+			out := &bytes.Buffer{}
+			app.Writer = out
+			app.ErrWriter = &bytes.Buffer{}
+			app.Run(append([]string{"eskip-match"}, tt.args...))
+
+			if exitCode != tt.code {
+				t.Errorf("expected exit code %d but got %d", tt.code, exitCode)
+			}
+			if tt.out != "" && out.String() != tt.out {
+				t.Errorf("expected output:\n%s\nbut got:\n%s", tt.out, out.String())
+			}
+		})
+	}
+
+	b, err := ioutil.ReadFile(junit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `<testsuites tests="4" failures="0">`) {
+		t.Errorf("unexpected junit report:\n%s", b)
+	}
+	b, err = ioutil.ReadFile(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "## Routing tests passed\n") {
+		t.Errorf("unexpected markdown report:\n%s", b)
+	}
+}
+
+func TestSuiteCommandJSON(t *testing.T) {
+	app := NewApp()
+	out := &bytes.Buffer{}
+	app.Writer = out
+	app.ErrWriter = &bytes.Buffer{}
+	app.Run([]string{"eskip-match", "suite", "-r", "testdata/routes.eskip", "-o", "json", "testdata/failing.yaml"})
+
+	var res struct {
+		Passed int
+		Failed int
+		Cases  []struct {
+			RouteID string
+			Message string
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+	if res.Failed != 1 || len(res.Cases) != 1 || res.Cases[0].RouteID != "bar" {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+func TestSuiteCommandUpdateSnapshots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fixture := filepath.Join(dir, "fixture.yaml")
+	err = ioutil.WriteFile(fixture, []byte("cases:\n  - request: {path: /bar}\n    expect: {snapshot: bar.json}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"eskip-match", "suite", "-r", "testdata/routes.eskip"}
+	for _, tt := range []struct {
+		args []string
+		code int
+	}{
+		{append(args, fixture), exitFailure},
+		{append(args, "--update-snapshots", fixture), exitOK},
+		{append(args, fixture), exitOK},
+	} {
+		exitCode = 0
+		app := NewApp()
+		app.Writer = &bytes.Buffer{}
+		app.ErrWriter = &bytes.Buffer{}
+		app.Run(tt.args)
+		if exitCode != tt.code {
+			t.Errorf("%v: expected exit code %d but got %d", tt.args, tt.code, exitCode)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "bar.json")); err != nil {
+		t.Error(err)
+	}
+}
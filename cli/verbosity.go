@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/urfave/cli"
+)
+
+// verboseFlag prints the tested requests and the matching routes location
+var verboseFlag = cli.BoolFlag{
+	Name:  "verbose, v",
+	Usage: "Print the tested requests, the location of the matching routes, the load report and the routing log but the debug lines to stderr",
+}
+
+// vvFlag like verboseFlag, with the skipper routing debug lines and the near
+// misses
+var vvFlag = cli.BoolFlag{
+	Name:  "vv",
+	Usage: "Like -v, printing the skipper routing debug lines and the near misses of the requests too",
+}
+
+// vvvFlag like vvFlag, with the route table
+var vvvFlag = cli.BoolFlag{
+	Name:  "vvv",
+	Usage: "Like -vv, printing the whole and the effective route tables once loaded too",
+}
+
+// verbosity returns the matcher verbosity level given by the -v, -vv and -vvv flags
+func verbosity(c *cli.Context) int {
+	switch {
+	case c.Bool("vvv"):
+		return matcher.VerbosityTrace
+	case c.Bool("vv"):
+		return matcher.VerbosityDebug
+	case c.Bool("verbose"):
+		return matcher.VerbosityInfo
+	default:
+		return matcher.VerbositySilent
+	}
+}
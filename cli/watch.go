@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const (
+	// watchInterval how often watched files are checked for changes
+	watchInterval = 200 * time.Millisecond
+	// watchDebounce how long files must stay untouched before re-running,
+	// so that editors doing several writes per save trigger a single run
+	watchDebounce = 300 * time.Millisecond
+	// clearScreen ANSI sequence moving the cursor home and clearing the terminal
+	clearScreen = "\033[H\033[2J"
+)
+
+// fileState holds what is needed to detect a file change
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func statFile(file string) fileState {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// watcher re-runs a check every time one of the files changes
+type watcher struct {
+	files    []string
+	interval time.Duration
+	debounce time.Duration
+	out      io.Writer
+	now      func() time.Time
+	// clear clears the terminal before every run
+	clear bool
+	// skipFirst runs check only on changes, the caller already ran it
+	skipFirst bool
+}
+
+func newWatcher(out io.Writer, files ...string) *watcher {
+	return &watcher{
+		files:    files,
+		interval: watchInterval,
+		debounce: watchDebounce,
+		out:      out,
+		now:      time.Now,
+		clear:    true,
+	}
+}
+
+// run executes check once, unless skipFirst is set, and then again on every
+// (debounced) change of the watched files until stop is closed. Errors returned by check are printed and
+// don't stop the watcher, so a broken routes file can be fixed while running.
+// It returns the error of the last check, that is the last status.
+func (w *watcher) run(stop <-chan struct{}, check func() error) error {
+	states := w.states()
+	var last error
+	if !w.skipFirst {
+		last = w.check(check)
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var changedAt time.Time
+	for {
+		select {
+		case <-stop:
+			return last
+		case <-ticker.C:
+			current := w.states()
+			if w.changed(states, current) {
+				states = current
+				changedAt = w.now()
+			}
+			if !changedAt.IsZero() && w.now().Sub(changedAt) >= w.debounce {
+				changedAt = time.Time{}
+				last = w.check(check)
+			}
+		}
+	}
+}
+
+func (w *watcher) check(check func() error) error {
+	if w.clear {
+		fmt.Fprint(w.out, clearScreen)
+	}
+	fmt.Fprintf(w.out, "[%s] watching %d file(s), press Ctrl+C to exit\n", w.now().Format("15:04:05"), len(w.files))
+	err := check()
+	if err != nil {
+		fmt.Fprintf(w.out, "[%s] error: %v\n", w.now().Format("15:04:05"), err)
+	}
+	return err
+}
+
+func (w *watcher) states() []fileState {
+	states := make([]fileState, len(w.files))
+	for i, file := range w.files {
+		states[i] = statFile(file)
+	}
+	return states
+}
+
+func (w *watcher) changed(previous, current []fileState) bool {
+	for i := range current {
+		p, c := previous[i], current[i]
+		if p.exists != c.exists || p.size != c.size || !p.modTime.Equal(c.modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// interrupted returns a channel closed when the process receives SIGINT,
+// replaced by tests
+var interrupted = func() <-chan struct{} {
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		close(stop)
+	}()
+	return stop
+}
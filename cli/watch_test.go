@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eskip-match-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "routes.eskip")
+	if err := ioutil.WriteFile(file, []byte(`a: * -> <shunt>;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu   sync.Mutex
+		runs int
+		out  bytes.Buffer
+	)
+	counter := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return runs
+	}
+	check := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		runs++
+		if runs == 2 {
+			return fmt.Errorf("broken routes")
+		}
+		return nil
+	}
+	waitFor := func(n int) {
+		deadline := time.Now().Add(2 * time.Second)
+		for counter() < n && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if counter() < n {
+			t.Fatalf("expected %d runs but got %d", n, counter())
+		}
+	}
+
+	w := newWatcher(&syncWriter{w: &out, mu: &mu}, file)
+	w.interval = 10 * time.Millisecond
+	w.debounce = 50 * time.Millisecond
+
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- w.run(stop, check) }()
+
+	waitFor(1)
+
+	// several writes in a row are debounced into a single run
+	for i := 0; i < 3; i++ {
+		content := fmt.Sprintf("a: * -> <shunt>; // %d", i)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(15 * time.Millisecond)
+	}
+	waitFor(2)
+	time.Sleep(100 * time.Millisecond)
+	if counter() != 2 {
+		t.Errorf("expected rapid writes to be debounced in a single run but got %d runs", counter())
+	}
+
+	// second run failed but watcher keeps running
+	if err := ioutil.WriteFile(file, []byte(`a: * -> <shunt>; b: * -> <shunt>;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(3)
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("expected last status to be a success but got %v", err)
+	}
+
+	mu.Lock()
+	output := out.String()
+	mu.Unlock()
+	if !strings.Contains(output, "error: broken routes") {
+		t.Errorf("expected the error to be printed but got %q", output)
+	}
+	if strings.Count(output, clearScreen) != 3 {
+		t.Errorf("expected screen to be cleared on every run but got %q", output)
+	}
+}
+
+func TestWatcherLastStatus(t *testing.T) {
+	w := newWatcher(ioutil.Discard)
+	stop := make(chan struct{})
+	close(stop)
+	err := w.run(stop, func() error { return fmt.Errorf("no match") })
+	if err == nil || err.Error() != "no match" {
+		t.Errorf("expected last check error to be returned but got %v", err)
+	}
+}
+
+func TestWatcherSkipFirst(t *testing.T) {
+	w := newWatcher(ioutil.Discard)
+	w.skipFirst = true
+	stop := make(chan struct{})
+	close(stop)
+	runs := 0
+	if err := w.run(stop, func() error { runs++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 0 {
+		t.Errorf("expected no run before a change but got %d", runs)
+	}
+}
+
+// syncWriter serializes writes to a shared buffer
+type syncWriter struct {
+	w  *bytes.Buffer
+	mu *sync.Mutex
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
@@ -1,12 +1,49 @@
 module github.com/rbarilani/eskip-match
 
-go 1.12
+go 1.20
 
 require (
-	github.com/BurntSushi/toml v0.3.1 // indirect
-	github.com/jinzhu/configor v1.0.0
-	github.com/mitchellh/gox v1.0.0 // indirect
+	github.com/golang/protobuf v1.2.0
+	github.com/google/go-cmp v0.4.0
+	github.com/prometheus/client_golang v0.9.0-pre1.0.20180907102542-7858729281ec
 	github.com/stretchr/testify v1.2.2
 	github.com/urfave/cli v1.20.0
 	github.com/zalando/skipper v0.10.190
+	golang.org/x/crypto v0.0.0-20170912191825-faadfbdc0353
+	golang.org/x/net v0.0.0-20181213202711-891ebc4b82d6
+	google.golang.org/grpc v1.14.0
+	gopkg.in/yaml.v2 v2.2.1
+)
+
+require (
+	github.com/abbot/go-http-auth v0.0.0-20150922224136-efc9484eee77 // indirect
+	github.com/beorn7/perks v0.0.0-20160804104726-4c0e84591b9a // indirect
+	github.com/cenkalti/backoff v2.1.0+incompatible // indirect
+	github.com/cjoudrey/gluahttp v0.0.0-20161028104506-b4bfe0c50fea // indirect
+	github.com/cjoudrey/gluaurl v0.0.0-20161028222611-31cbb9bef199 // indirect
+	github.com/coreos/go-oidc v2.0.0+incompatible // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dimfeld/httppath v0.0.0-20170720192232-ee938bf73598 // indirect
+	github.com/go-redis/redis v6.15.2+incompatible // indirect
+	github.com/gogo/protobuf v1.1.1 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/oklog/ulid v0.3.0 // indirect
+	github.com/opentracing/opentracing-go v1.0.3-0.20180908211932-6aa6febac7b9 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/pquerna/cachecontrol v0.0.0-20180517163645-1555304b9b35 // indirect
+	github.com/prometheus/client_model v0.0.0-20171117100541-99fa1f4be8e5 // indirect
+	github.com/prometheus/common v0.0.0-20171117163051-2e54d0b93cba // indirect
+	github.com/prometheus/procfs v0.0.0-20181204211112-1dc9a6cbc91a // indirect
+	github.com/rcrowley/go-metrics v0.0.0-20161128210544-1f30fe9094a5 // indirect
+	github.com/sirupsen/logrus v1.0.4 // indirect
+	github.com/sony/gobreaker v0.0.0-20170530031423-e9556a45379e // indirect
+	github.com/szuecs/rate-limit-buffer v0.7.0 // indirect
+	github.com/yuin/gopher-lua v0.0.0-20171229012508-478861c8ce6e // indirect
+	golang.org/x/oauth2 v0.0.0-20180821212333-d2e6202438be // indirect
+	golang.org/x/sys v0.0.0-20180831094639-fa5fdf94c789 // indirect
+	golang.org/x/text v0.3.0 // indirect
+	google.golang.org/appengine v1.2.0 // indirect
+	google.golang.org/genproto v0.0.0-20180831171423-11092d34479b // indirect
+	gopkg.in/square/go-jose.v2 v2.1.9 // indirect
+	layeh.com/gopher-json v0.0.0-20180103211521-1aab82196e3b // indirect
 )
@@ -0,0 +1,667 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: eskipmatch/v1/match.proto
+
+package grpcapi // import "github.com/rbarilani/eskip-match/grpcapi"
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// RequestAttributes the attributes of an http request, like the ones of the
+// test command.
+type RequestAttributes struct {
+	// method GET when empty
+	Method               string            `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Path                 string            `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Host                 string            `protobuf:"bytes,3,opt,name=host,proto3" json:"host,omitempty"`
+	Query                map[string]string `protobuf:"bytes,4,rep,name=query,proto3" json:"query,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Headers              map[string]string `protobuf:"bytes,5,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *RequestAttributes) Reset()         { *m = RequestAttributes{} }
+func (m *RequestAttributes) String() string { return proto.CompactTextString(m) }
+func (*RequestAttributes) ProtoMessage()    {}
+func (*RequestAttributes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_match_2f6271c84c231479, []int{0}
+}
+func (m *RequestAttributes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RequestAttributes.Unmarshal(m, b)
+}
+func (m *RequestAttributes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RequestAttributes.Marshal(b, m, deterministic)
+}
+func (dst *RequestAttributes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RequestAttributes.Merge(dst, src)
+}
+func (m *RequestAttributes) XXX_Size() int {
+	return xxx_messageInfo_RequestAttributes.Size(m)
+}
+func (m *RequestAttributes) XXX_DiscardUnknown() {
+	xxx_messageInfo_RequestAttributes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RequestAttributes proto.InternalMessageInfo
+
+func (m *RequestAttributes) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *RequestAttributes) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *RequestAttributes) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+func (m *RequestAttributes) GetQuery() map[string]string {
+	if m != nil {
+		return m.Query
+	}
+	return nil
+}
+
+func (m *RequestAttributes) GetHeaders() map[string]string {
+	if m != nil {
+		return m.Headers
+	}
+	return nil
+}
+
+type MatchRequest struct {
+	Request *RequestAttributes `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	// explain adds the closest routes not matching the request to the result.
+	Explain bool `protobuf:"varint,2,opt,name=explain,proto3" json:"explain,omitempty"`
+	// near_misses number of near misses with explain, 3 when 0, all of them
+	// when < 0.
+	NearMisses           int32    `protobuf:"varint,3,opt,name=near_misses,json=nearMisses,proto3" json:"near_misses,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MatchRequest) Reset()         { *m = MatchRequest{} }
+func (m *MatchRequest) String() string { return proto.CompactTextString(m) }
+func (*MatchRequest) ProtoMessage()    {}
+func (*MatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_match_2f6271c84c231479, []int{1}
+}
+func (m *MatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MatchRequest.Unmarshal(m, b)
+}
+func (m *MatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MatchRequest.Marshal(b, m, deterministic)
+}
+func (dst *MatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MatchRequest.Merge(dst, src)
+}
+func (m *MatchRequest) XXX_Size() int {
+	return xxx_messageInfo_MatchRequest.Size(m)
+}
+func (m *MatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MatchRequest proto.InternalMessageInfo
+
+func (m *MatchRequest) GetRequest() *RequestAttributes {
+	if m != nil {
+		return m.Request
+	}
+	return nil
+}
+
+func (m *MatchRequest) GetExplain() bool {
+	if m != nil {
+		return m.Explain
+	}
+	return false
+}
+
+func (m *MatchRequest) GetNearMisses() int32 {
+	if m != nil {
+		return m.NearMisses
+	}
+	return 0
+}
+
+type Filter struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// args the arguments formatted as strings, eg. 302 and /login
+	Args []string `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
+	// default the filter is one of the default filters
+	Default              bool     `protobuf:"varint,3,opt,name=default,proto3" json:"default,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Filter) Reset()         { *m = Filter{} }
+func (m *Filter) String() string { return proto.CompactTextString(m) }
+func (*Filter) ProtoMessage()    {}
+func (*Filter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_match_2f6271c84c231479, []int{2}
+}
+func (m *Filter) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Filter.Unmarshal(m, b)
+}
+func (m *Filter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Filter.Marshal(b, m, deterministic)
+}
+func (dst *Filter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Filter.Merge(dst, src)
+}
+func (m *Filter) XXX_Size() int {
+	return xxx_messageInfo_Filter.Size(m)
+}
+func (m *Filter) XXX_DiscardUnknown() {
+	xxx_messageInfo_Filter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Filter proto.InternalMessageInfo
+
+func (m *Filter) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Filter) GetArgs() []string {
+	if m != nil {
+		return m.Args
+	}
+	return nil
+}
+
+func (m *Filter) GetDefault() bool {
+	if m != nil {
+		return m.Default
+	}
+	return false
+}
+
+type PredicateVerdict struct {
+	// predicate eskip representation of the predicate, eg. Path("/foo")
+	Predicate string `protobuf:"bytes,1,opt,name=predicate,proto3" json:"predicate,omitempty"`
+	Name      string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Matched   bool   `protobuf:"varint,3,opt,name=matched,proto3" json:"matched,omitempty"`
+	// observed the request value the predicate was evaluated against, if known
+	Observed string `protobuf:"bytes,4,opt,name=observed,proto3" json:"observed,omitempty"`
+	// error why the predicate couldn't be evaluated, eg. unknown predicate
+	Error                string   `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Captures             []string `protobuf:"bytes,6,rep,name=captures,proto3" json:"captures,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PredicateVerdict) Reset()         { *m = PredicateVerdict{} }
+func (m *PredicateVerdict) String() string { return proto.CompactTextString(m) }
+func (*PredicateVerdict) ProtoMessage()    {}
+func (*PredicateVerdict) Descriptor() ([]byte, []int) {
+	return fileDescriptor_match_2f6271c84c231479, []int{3}
+}
+func (m *PredicateVerdict) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PredicateVerdict.Unmarshal(m, b)
+}
+func (m *PredicateVerdict) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PredicateVerdict.Marshal(b, m, deterministic)
+}
+func (dst *PredicateVerdict) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PredicateVerdict.Merge(dst, src)
+}
+func (m *PredicateVerdict) XXX_Size() int {
+	return xxx_messageInfo_PredicateVerdict.Size(m)
+}
+func (m *PredicateVerdict) XXX_DiscardUnknown() {
+	xxx_messageInfo_PredicateVerdict.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PredicateVerdict proto.InternalMessageInfo
+
+func (m *PredicateVerdict) GetPredicate() string {
+	if m != nil {
+		return m.Predicate
+	}
+	return ""
+}
+
+func (m *PredicateVerdict) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *PredicateVerdict) GetMatched() bool {
+	if m != nil {
+		return m.Matched
+	}
+	return false
+}
+
+func (m *PredicateVerdict) GetObserved() string {
+	if m != nil {
+		return m.Observed
+	}
+	return ""
+}
+
+func (m *PredicateVerdict) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *PredicateVerdict) GetCaptures() []string {
+	if m != nil {
+		return m.Captures
+	}
+	return nil
+}
+
+// NearMiss a route not matching the request.
+type NearMiss struct {
+	RouteId string `protobuf:"bytes,1,opt,name=route_id,json=routeId,proto3" json:"route_id,omitempty"`
+	// failed number of predicates the request doesn't satisfy
+	Failed               int32               `protobuf:"varint,2,opt,name=failed,proto3" json:"failed,omitempty"`
+	Verdicts             []*PredicateVerdict `protobuf:"bytes,3,rep,name=verdicts,proto3" json:"verdicts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *NearMiss) Reset()         { *m = NearMiss{} }
+func (m *NearMiss) String() string { return proto.CompactTextString(m) }
+func (*NearMiss) ProtoMessage()    {}
+func (*NearMiss) Descriptor() ([]byte, []int) {
+	return fileDescriptor_match_2f6271c84c231479, []int{4}
+}
+func (m *NearMiss) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NearMiss.Unmarshal(m, b)
+}
+func (m *NearMiss) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NearMiss.Marshal(b, m, deterministic)
+}
+func (dst *NearMiss) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NearMiss.Merge(dst, src)
+}
+func (m *NearMiss) XXX_Size() int {
+	return xxx_messageInfo_NearMiss.Size(m)
+}
+func (m *NearMiss) XXX_DiscardUnknown() {
+	xxx_messageInfo_NearMiss.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NearMiss proto.InternalMessageInfo
+
+func (m *NearMiss) GetRouteId() string {
+	if m != nil {
+		return m.RouteId
+	}
+	return ""
+}
+
+func (m *NearMiss) GetFailed() int32 {
+	if m != nil {
+		return m.Failed
+	}
+	return 0
+}
+
+func (m *NearMiss) GetVerdicts() []*PredicateVerdict {
+	if m != nil {
+		return m.Verdicts
+	}
+	return nil
+}
+
+type MatchResult struct {
+	Matched bool   `protobuf:"varint,1,opt,name=matched,proto3" json:"matched,omitempty"`
+	RouteId string `protobuf:"bytes,2,opt,name=route_id,json=routeId,proto3" json:"route_id,omitempty"`
+	// backend_type one of network, shunt, loopback, dynamic, loadbalanced
+	BackendType string `protobuf:"bytes,3,opt,name=backend_type,json=backendType,proto3" json:"backend_type,omitempty"`
+	// backend address of a network backend or a placeholder like <shunt>
+	Backend     string    `protobuf:"bytes,4,opt,name=backend,proto3" json:"backend,omitempty"`
+	LbEndpoints []string  `protobuf:"bytes,5,rep,name=lb_endpoints,json=lbEndpoints,proto3" json:"lb_endpoints,omitempty"`
+	Filters     []*Filter `protobuf:"bytes,6,rep,name=filters,proto3" json:"filters,omitempty"`
+	// predicates the normalized predicates of the matching route
+	Predicates []string `protobuf:"bytes,7,rep,name=predicates,proto3" json:"predicates,omitempty"`
+	// request the normalized request attributes
+	Request *RequestAttributes `protobuf:"bytes,8,opt,name=request,proto3" json:"request,omitempty"`
+	// near_misses the closest routes not matching, with explain
+	NearMisses []*NearMiss `protobuf:"bytes,9,rep,name=near_misses,json=nearMisses,proto3" json:"near_misses,omitempty"`
+	// error why the request couldn't be tested, eg. invalid attributes
+	Error                string   `protobuf:"bytes,10,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MatchResult) Reset()         { *m = MatchResult{} }
+func (m *MatchResult) String() string { return proto.CompactTextString(m) }
+func (*MatchResult) ProtoMessage()    {}
+func (*MatchResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_match_2f6271c84c231479, []int{5}
+}
+func (m *MatchResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MatchResult.Unmarshal(m, b)
+}
+func (m *MatchResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MatchResult.Marshal(b, m, deterministic)
+}
+func (dst *MatchResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MatchResult.Merge(dst, src)
+}
+func (m *MatchResult) XXX_Size() int {
+	return xxx_messageInfo_MatchResult.Size(m)
+}
+func (m *MatchResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_MatchResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MatchResult proto.InternalMessageInfo
+
+func (m *MatchResult) GetMatched() bool {
+	if m != nil {
+		return m.Matched
+	}
+	return false
+}
+
+func (m *MatchResult) GetRouteId() string {
+	if m != nil {
+		return m.RouteId
+	}
+	return ""
+}
+
+func (m *MatchResult) GetBackendType() string {
+	if m != nil {
+		return m.BackendType
+	}
+	return ""
+}
+
+func (m *MatchResult) GetBackend() string {
+	if m != nil {
+		return m.Backend
+	}
+	return ""
+}
+
+func (m *MatchResult) GetLbEndpoints() []string {
+	if m != nil {
+		return m.LbEndpoints
+	}
+	return nil
+}
+
+func (m *MatchResult) GetFilters() []*Filter {
+	if m != nil {
+		return m.Filters
+	}
+	return nil
+}
+
+func (m *MatchResult) GetPredicates() []string {
+	if m != nil {
+		return m.Predicates
+	}
+	return nil
+}
+
+func (m *MatchResult) GetRequest() *RequestAttributes {
+	if m != nil {
+		return m.Request
+	}
+	return nil
+}
+
+func (m *MatchResult) GetNearMisses() []*NearMiss {
+	if m != nil {
+		return m.NearMisses
+	}
+	return nil
+}
+
+func (m *MatchResult) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*RequestAttributes)(nil), "eskipmatch.v1.RequestAttributes")
+	proto.RegisterMapType((map[string]string)(nil), "eskipmatch.v1.RequestAttributes.HeadersEntry")
+	proto.RegisterMapType((map[string]string)(nil), "eskipmatch.v1.RequestAttributes.QueryEntry")
+	proto.RegisterType((*MatchRequest)(nil), "eskipmatch.v1.MatchRequest")
+	proto.RegisterType((*Filter)(nil), "eskipmatch.v1.Filter")
+	proto.RegisterType((*PredicateVerdict)(nil), "eskipmatch.v1.PredicateVerdict")
+	proto.RegisterType((*NearMiss)(nil), "eskipmatch.v1.NearMiss")
+	proto.RegisterType((*MatchResult)(nil), "eskipmatch.v1.MatchResult")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// MatchServiceClient is the client API for MatchService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MatchServiceClient interface {
+	// Match tests a request.
+	Match(ctx context.Context, in *MatchRequest, opts ...grpc.CallOption) (*MatchResult, error)
+	// MatchStream tests the requests of the stream, the results are sent in
+	// the same order. A request failing to be tested gets a result with the
+	// error, the stream goes on.
+	MatchStream(ctx context.Context, opts ...grpc.CallOption) (MatchService_MatchStreamClient, error)
+}
+
+type matchServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewMatchServiceClient(cc *grpc.ClientConn) MatchServiceClient {
+	return &matchServiceClient{cc}
+}
+
+func (c *matchServiceClient) Match(ctx context.Context, in *MatchRequest, opts ...grpc.CallOption) (*MatchResult, error) {
+	out := new(MatchResult)
+	err := c.cc.Invoke(ctx, "/eskipmatch.v1.MatchService/Match", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchServiceClient) MatchStream(ctx context.Context, opts ...grpc.CallOption) (MatchService_MatchStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_MatchService_serviceDesc.Streams[0], "/eskipmatch.v1.MatchService/MatchStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &matchServiceMatchStreamClient{stream}
+	return x, nil
+}
+
+type MatchService_MatchStreamClient interface {
+	Send(*MatchRequest) error
+	Recv() (*MatchResult, error)
+	grpc.ClientStream
+}
+
+type matchServiceMatchStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *matchServiceMatchStreamClient) Send(m *MatchRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *matchServiceMatchStreamClient) Recv() (*MatchResult, error) {
+	m := new(MatchResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MatchServiceServer is the server API for MatchService service.
+type MatchServiceServer interface {
+	// Match tests a request.
+	Match(context.Context, *MatchRequest) (*MatchResult, error)
+	// MatchStream tests the requests of the stream, the results are sent in
+	// the same order. A request failing to be tested gets a result with the
+	// error, the stream goes on.
+	MatchStream(MatchService_MatchStreamServer) error
+}
+
+func RegisterMatchServiceServer(s *grpc.Server, srv MatchServiceServer) {
+	s.RegisterService(&_MatchService_serviceDesc, srv)
+}
+
+func _MatchService_Match_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchServiceServer).Match(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/eskipmatch.v1.MatchService/Match",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchServiceServer).Match(ctx, req.(*MatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatchService_MatchStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MatchServiceServer).MatchStream(&matchServiceMatchStreamServer{stream})
+}
+
+type MatchService_MatchStreamServer interface {
+	Send(*MatchResult) error
+	Recv() (*MatchRequest, error)
+	grpc.ServerStream
+}
+
+type matchServiceMatchStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *matchServiceMatchStreamServer) Send(m *MatchResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *matchServiceMatchStreamServer) Recv() (*MatchRequest, error) {
+	m := new(MatchRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _MatchService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "eskipmatch.v1.MatchService",
+	HandlerType: (*MatchServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Match",
+			Handler:    _MatchService_Match_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "MatchStream",
+			Handler:       _MatchService_MatchStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "eskipmatch/v1/match.proto",
+}
+
+func init() { proto.RegisterFile("eskipmatch/v1/match.proto", fileDescriptor_match_2f6271c84c231479) }
+
+var fileDescriptor_match_2f6271c84c231479 = []byte{
+	// 656 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x54, 0xcb, 0x6e, 0xd3, 0x4c,
+	0x14, 0x96, 0x9d, 0x3a, 0x76, 0x8e, 0xfb, 0x4b, 0xfd, 0x47, 0x5c, 0xdc, 0x80, 0x68, 0xc8, 0xaa,
+	0x12, 0x6a, 0xd2, 0x86, 0x4d, 0xd5, 0x6e, 0x28, 0x52, 0xb9, 0x54, 0x2a, 0x02, 0x17, 0xb1, 0x60,
+	0x13, 0x8d, 0xed, 0xd3, 0x66, 0x54, 0xc7, 0x76, 0xc7, 0xe3, 0x88, 0xb0, 0xe7, 0x2d, 0x78, 0x04,
+	0x5e, 0x87, 0xe7, 0x01, 0xcd, 0xc5, 0x89, 0x13, 0x90, 0xaa, 0xb2, 0xf2, 0xf9, 0x3e, 0xcf, 0xb9,
+	0x7f, 0x33, 0xb0, 0x8d, 0xe5, 0x35, 0x2b, 0xa6, 0x54, 0xc4, 0x93, 0xe1, 0xec, 0x60, 0xa8, 0x8c,
+	0x41, 0xc1, 0x73, 0x91, 0x93, 0xff, 0x96, 0xbf, 0x06, 0xb3, 0x83, 0xfe, 0x4f, 0x1b, 0xfe, 0x0f,
+	0xf1, 0xa6, 0xc2, 0x52, 0x9c, 0x08, 0xc1, 0x59, 0x54, 0x09, 0x2c, 0xc9, 0x03, 0x68, 0x4f, 0x51,
+	0x4c, 0xf2, 0x24, 0xb0, 0x7a, 0xd6, 0x6e, 0x27, 0x34, 0x88, 0x10, 0xd8, 0x28, 0xa8, 0x98, 0x04,
+	0xb6, 0x62, 0x95, 0x2d, 0xb9, 0x49, 0x5e, 0x8a, 0xa0, 0xa5, 0x39, 0x69, 0x93, 0x13, 0x70, 0x6e,
+	0x2a, 0xe4, 0xf3, 0x60, 0xa3, 0xd7, 0xda, 0xf5, 0x47, 0xcf, 0x06, 0x2b, 0x49, 0x07, 0x7f, 0x24,
+	0x1c, 0x7c, 0x90, 0xa7, 0x4f, 0x33, 0xc1, 0xe7, 0xa1, 0xf6, 0x24, 0xaf, 0xc1, 0x9d, 0x20, 0x4d,
+	0x90, 0x97, 0x81, 0xa3, 0x82, 0xec, 0xdd, 0x1a, 0xe4, 0x8d, 0x3e, 0xaf, 0xc3, 0xd4, 0xde, 0xdd,
+	0x43, 0x80, 0x65, 0x74, 0xb2, 0x05, 0xad, 0x6b, 0x9c, 0x9b, 0xb6, 0xa4, 0x49, 0xee, 0x81, 0x33,
+	0xa3, 0x69, 0x85, 0xa6, 0x29, 0x0d, 0x8e, 0xec, 0x43, 0xab, 0x7b, 0x04, 0x9b, 0xcd, 0x90, 0x77,
+	0xf1, 0xed, 0x7f, 0xb3, 0x60, 0xf3, 0x5c, 0x96, 0x6a, 0xca, 0x24, 0x47, 0xe0, 0x72, 0x6d, 0xaa,
+	0x00, 0xfe, 0xa8, 0x77, 0x5b, 0x3f, 0x61, 0xed, 0x40, 0x02, 0x70, 0xf1, 0x4b, 0x91, 0x52, 0x96,
+	0xa9, 0x44, 0x5e, 0x58, 0x43, 0xb2, 0x03, 0x7e, 0x86, 0x94, 0x8f, 0xa7, 0xac, 0x2c, 0xb1, 0x54,
+	0x3b, 0x70, 0x42, 0x90, 0xd4, 0xb9, 0x62, 0xfa, 0x67, 0xd0, 0x7e, 0xc5, 0x52, 0x81, 0x5c, 0xee,
+	0x29, 0xa3, 0x53, 0x34, 0xe5, 0x2b, 0x5b, 0x72, 0x94, 0x5f, 0x95, 0x81, 0xdd, 0x6b, 0x49, 0x4e,
+	0xda, 0x32, 0x59, 0x82, 0x97, 0xb4, 0x4a, 0xf5, 0x4a, 0xbd, 0xb0, 0x86, 0xfd, 0x1f, 0x16, 0x6c,
+	0xbd, 0xe7, 0x98, 0xb0, 0x98, 0x0a, 0xfc, 0x84, 0x3c, 0x61, 0xb1, 0x20, 0x8f, 0xa1, 0x53, 0xd4,
+	0x9c, 0x89, 0xbd, 0x24, 0x16, 0x49, 0xed, 0x46, 0xd2, 0x00, 0x5c, 0xd5, 0x34, 0x26, 0x75, 0x02,
+	0x03, 0x49, 0x17, 0xbc, 0x3c, 0x2a, 0x91, 0xcf, 0x30, 0x09, 0x36, 0x94, 0xc7, 0x02, 0xcb, 0x51,
+	0x23, 0xe7, 0x39, 0x0f, 0x1c, 0x3d, 0x6a, 0x05, 0xa4, 0x47, 0x4c, 0x0b, 0x51, 0x71, 0x2c, 0x83,
+	0xb6, 0x6a, 0x62, 0x81, 0xfb, 0x5f, 0xc1, 0x7b, 0x67, 0x06, 0x41, 0xb6, 0xc1, 0xe3, 0x79, 0x25,
+	0x70, 0xcc, 0x6a, 0x49, 0xbb, 0x0a, 0xbf, 0x4d, 0xa4, 0xd6, 0x2f, 0x29, 0x4b, 0x31, 0x51, 0x45,
+	0x3a, 0xa1, 0x41, 0xe4, 0x18, 0xbc, 0x99, 0xee, 0x51, 0xce, 0x55, 0x2a, 0x70, 0x67, 0x6d, 0x63,
+	0xeb, 0xb3, 0x08, 0x17, 0x0e, 0xfd, 0x5f, 0x36, 0xf8, 0x66, 0xfd, 0x65, 0x95, 0x8a, 0x66, 0xcf,
+	0xd6, 0x6a, 0xcf, 0xcd, 0xca, 0xec, 0xd5, 0xca, 0x9e, 0xc2, 0x66, 0x44, 0xe3, 0x6b, 0xcc, 0x92,
+	0xb1, 0x98, 0x17, 0x68, 0x6e, 0x98, 0x6f, 0xb8, 0x8f, 0xf3, 0x42, 0xcd, 0xd2, 0x40, 0x33, 0xb0,
+	0x1a, 0x4a, 0xe7, 0x34, 0x1a, 0x63, 0x96, 0x14, 0x39, 0xcb, 0x84, 0xbe, 0x44, 0x9d, 0xd0, 0x4f,
+	0xa3, 0xd3, 0x9a, 0x22, 0x43, 0x70, 0x2f, 0x95, 0x36, 0xf4, 0xec, 0xfc, 0xd1, 0xfd, 0xb5, 0x06,
+	0xb5, 0x72, 0xc2, 0xfa, 0x14, 0x79, 0x02, 0xb0, 0x58, 0x6d, 0x19, 0xb8, 0x2a, 0x62, 0x83, 0x69,
+	0x6a, 0xdc, 0xbb, 0xab, 0xc6, 0x0f, 0x57, 0x95, 0xdc, 0x51, 0x05, 0x3d, 0x5c, 0xf3, 0xaf, 0xf7,
+	0xd9, 0x94, 0xf8, 0x52, 0x19, 0xd0, 0x50, 0xc6, 0xe8, 0x7b, 0x7d, 0x01, 0x2f, 0x90, 0xcf, 0x58,
+	0x8c, 0xe4, 0x05, 0x38, 0x0a, 0x93, 0x47, 0x6b, 0x41, 0x9b, 0xd7, 0xb4, 0xdb, 0xfd, 0xfb, 0x4f,
+	0xb5, 0xc4, 0x33, 0xb3, 0xd3, 0x0b, 0xc1, 0x91, 0x4e, 0xff, 0x39, 0xce, 0xae, 0xb5, 0x6f, 0xbd,
+	0x1c, 0x7d, 0xde, 0xbf, 0x62, 0x62, 0x52, 0x45, 0x83, 0x38, 0x9f, 0x0e, 0x79, 0x44, 0x39, 0x4b,
+	0x69, 0xc6, 0x86, 0xca, 0x6b, 0x4f, 0xbf, 0xdc, 0x57, 0xbc, 0x88, 0x69, 0xc1, 0x8e, 0xcd, 0x37,
+	0x6a, 0xab, 0x17, 0xfc, 0xf9, 0xef, 0x01, 0x00, 0x6b, 0x21, 0x17, 0xfa, 0xde, 0x05, 0x00, 0x00,
+}
@@ -0,0 +1,193 @@
+// Package grpcapi exposes a matcher over gRPC, the MatchService of
+// proto/eskipmatch/v1/match.proto, for the tools generating their clients
+// from the schema. The messages and the service stubs of match.pb.go are
+// generated with protoc-gen-go v1.2.0 from the grpcapi directory:
+//
+//	protoc -I proto --go_out=plugins=grpc,paths=source_relative:. eskipmatch/v1/match.proto
+//
+// and moved next to this file.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/server"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements MatchServiceServer testing the requests against the
+// routing table of a server.Loader
+type Server struct {
+	load server.Loader
+
+	mu        sync.RWMutex
+	table     *server.Table
+	reloadErr error
+}
+
+// NewServer creates a server loading its routing table with load, nothing
+// is loaded until Reload is called. Register it with RegisterMatchServiceServer.
+func NewServer(load server.Loader) *Server {
+	return &Server{load: load}
+}
+
+// Reload loads the routing table again and swaps it with the current one,
+// closing its matcher. On failure the current one keeps being used.
+func (s *Server) Reload() error {
+	t, err := s.load()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadErr = err
+	if err == nil {
+		if s.table != nil {
+			s.table.Matcher.Close()
+		}
+		s.table = t
+	}
+	return err
+}
+
+// current returns the routing table in use, nil if never loaded
+func (s *Server) current() *server.Table {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.table
+}
+
+// Match see MatchServiceServer, fails with codes.Unavailable before the
+// routes are loaded and codes.InvalidArgument for invalid attributes
+func (s *Server) Match(ctx context.Context, req *MatchRequest) (*MatchResult, error) {
+	t := s.current()
+	if t == nil {
+		return nil, status.Error(codes.Unavailable, "routes not loaded")
+	}
+	res, err := s.match(t, req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return res, nil
+}
+
+// MatchStream see MatchServiceServer, fails with codes.Unavailable before
+// the routes are loaded
+func (s *Server) MatchStream(stream MatchService_MatchStreamServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		t := s.current()
+		if t == nil {
+			return status.Error(codes.Unavailable, "routes not loaded")
+		}
+		res, err := s.match(t, req)
+		if err != nil {
+			res = &MatchResult{Error: err.Error()}
+		}
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+// match tests the request against the table, the error is why it can't be
+// tested
+func (s *Server) match(t *server.Table, req *MatchRequest) (*MatchResult, error) {
+	attrs := requestAttributes(req.GetRequest())
+	res := t.Matcher.Test(attrs)
+	if errors.Is(res.Err(), matcher.ErrClosed) {
+		// the table was swapped and closed by a reload meanwhile
+		t = s.current()
+		res = t.Matcher.Test(attrs)
+	}
+	if err := res.Err(); err != nil {
+		// the client knows the request, the cause is enough
+		var me *matcher.MatchError
+		if errors.As(err, &me) {
+			err = me.Err
+		}
+		return nil, fmt.Errorf("invalid request attributes: %v", err)
+	}
+
+	result := newMatchResult(res)
+	if req.GetExplain() {
+		n := int(req.GetNearMisses())
+		switch {
+		case n == 0:
+			n = server.DefaultNearMisses
+		case n < 0:
+			n = 0
+		}
+		for _, miss := range t.Matcher.Explain(attrs, n) {
+			result.NearMisses = append(result.NearMisses, newNearMiss(miss))
+		}
+	}
+	return result, nil
+}
+
+// requestAttributes the matcher request attributes of the message
+func requestAttributes(a *RequestAttributes) *matcher.RequestAttributes {
+	return &matcher.RequestAttributes{
+		Method:  a.GetMethod(),
+		Path:    a.GetPath(),
+		Host:    a.GetHost(),
+		Query:   a.GetQuery(),
+		Headers: a.GetHeaders(),
+	}
+}
+
+// newMatchResult the message of a test result
+func newMatchResult(res matcher.TestResult) *MatchResult {
+	data := matcher.NewResultData(res)
+	result := &MatchResult{
+		Matched:     data.Matched,
+		RouteId:     data.RouteID,
+		BackendType: data.BackendType,
+		Backend:     data.Backend,
+		LbEndpoints: data.LBEndpoints,
+		Request: &RequestAttributes{
+			Method:  data.Request.Method,
+			Path:    data.Request.Path,
+			Host:    data.Request.Host,
+			Query:   data.Request.Query,
+			Headers: data.Request.Headers,
+		},
+	}
+	for _, f := range data.Filters {
+		filter := &Filter{Name: f.Name, Default: f.Default}
+		for _, arg := range f.Args {
+			filter.Args = append(filter.Args, fmt.Sprint(arg))
+		}
+		result.Filters = append(result.Filters, filter)
+	}
+	if route := res.Route(); route != nil {
+		result.Predicates = matcher.NewRouteData(route).Predicates
+	}
+	return result
+}
+
+// newNearMiss the message of a near miss
+func newNearMiss(miss matcher.NearMiss) *NearMiss {
+	m := &NearMiss{RouteId: miss.RouteID, Failed: int32(miss.Failed)}
+	for _, v := range miss.Verdicts {
+		m.Verdicts = append(m.Verdicts, &PredicateVerdict{
+			Predicate: v.Predicate,
+			Name:      v.Name,
+			Matched:   v.Matched,
+			Observed:  v.Observed,
+			Error:     v.Error,
+			Captures:  v.Captures,
+		})
+	}
+	return m
+}
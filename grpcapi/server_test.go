@@ -0,0 +1,147 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const routesFile = "testdata/routes.eskip"
+
+func loadTable() (*server.Table, error) {
+	m, err := matcher.New(&matcher.Options{RoutesFile: routesFile})
+	if err != nil {
+		return nil, err
+	}
+	return &server.Table{Matcher: m}, nil
+}
+
+// dial serves s on an in-memory listener and returns a client of it and
+// a function stopping both
+func dial(t *testing.T, s *Server) (MatchServiceClient, func()) {
+	ln := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	RegisterMatchServiceServer(gs, s)
+	go gs.Serve(ln)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+		return ln.Dial()
+	}))
+	require.NoError(t, err)
+	return NewMatchServiceClient(conn), func() {
+		conn.Close()
+		gs.Stop()
+	}
+}
+
+func TestMatch(t *testing.T) {
+	s := NewServer(loadTable)
+	require.NoError(t, s.Reload())
+	client, stop := dial(t, s)
+	defer stop()
+	ctx := context.Background()
+
+	res, err := client.Match(ctx, &MatchRequest{Request: &RequestAttributes{Path: "/orders", Host: "api.example.org"}})
+	require.NoError(t, err)
+	assert.True(t, res.Matched)
+	assert.Equal(t, "orders", res.RouteId)
+	assert.Equal(t, "network", res.BackendType)
+	assert.Equal(t, "https://orders.example.org", res.Backend)
+	assert.Equal(t, []string{`Path("/orders")`, `Host("^api[.]example[.]org$")`}, res.Predicates)
+	assert.Equal(t, "GET", res.Request.Method)
+	assert.Empty(t, res.NearMisses)
+
+	res, err = client.Match(ctx, &MatchRequest{Request: &RequestAttributes{Path: "/orders"}, Explain: true, NearMisses: 1})
+	require.NoError(t, err)
+	assert.False(t, res.Matched)
+	if assert.Len(t, res.NearMisses, 1) {
+		assert.Equal(t, "orders", res.NearMisses[0].RouteId)
+		assert.Equal(t, int32(1), res.NearMisses[0].Failed)
+		assert.Len(t, res.NearMisses[0].Verdicts, 2)
+	}
+
+	_, err = client.Match(ctx, &MatchRequest{Request: &RequestAttributes{Path: "/%zz"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMatchStream(t *testing.T) {
+	s := NewServer(loadTable)
+	require.NoError(t, s.Reload())
+	client, stop := dial(t, s)
+	defer stop()
+
+	stream, err := client.MatchStream(context.Background())
+	require.NoError(t, err)
+	requests := []*RequestAttributes{
+		{Path: "/search", Query: map[string]string{"q": "shoes"}},
+		{Path: "/%zz"},
+		{Path: "/orders", Host: "api.example.org"},
+		{Path: "/missing"},
+	}
+	for _, r := range requests {
+		require.NoError(t, stream.Send(&MatchRequest{Request: r}))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	var routes []string
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if res.Error != "" {
+			routes = append(routes, "error")
+			continue
+		}
+		routes = append(routes, res.RouteId)
+	}
+	assert.Equal(t, []string{"search", "error", "orders", ""}, routes)
+}
+
+func TestServerReload(t *testing.T) {
+	var fail error
+	s := NewServer(func() (*server.Table, error) {
+		if fail != nil {
+			return nil, fail
+		}
+		return loadTable()
+	})
+	client, stop := dial(t, s)
+	defer stop()
+	ctx := context.Background()
+	req := &MatchRequest{Request: &RequestAttributes{Path: "/search", Query: map[string]string{"q": "shoes"}}}
+
+	_, err := client.Match(ctx, req)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	require.NoError(t, s.Reload())
+	previous := s.current().Matcher
+	fail = errors.New("parse error")
+	assert.Equal(t, fail, s.Reload())
+
+	// the previous routes are still used
+	res, err := client.Match(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "search", res.RouteId)
+
+	// a successful reload closes the previous matcher
+	fail = nil
+	require.NoError(t, s.Reload())
+	assert.True(t, errors.Is(previous.Test(&matcher.RequestAttributes{Path: "/search"}).Err(), matcher.ErrClosed))
+	res, err = client.Match(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "search", res.RouteId)
+}
@@ -0,0 +1,250 @@
+package matcher
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+// anyPathBucket bucket of the routes which can match paths of any bucket:
+// without a path, matching all the subtrees or starting with a wildcard
+const anyPathBucket = "*"
+
+// ambiguousRoutes reports the pairs of routes which can both match a request
+// generated for both of them, only with CheckAmbiguity. The routes are
+// compared to the ones sharing the first path segment and to the ones
+// matching any path, the pairs reported by shadowedRoutes and pathsInSubtrees
+// are skipped.
+func (l *linter) ambiguousRoutes() {
+	if !l.rules.CheckAmbiguity {
+		return
+	}
+	buckets := make(map[string][]*routeConditions)
+	for i, r := range l.routes {
+		rc := newRouteConditions(r)
+		rc.index = i
+		key := pathBucket(rc)
+		buckets[key] = append(buckets[key], rc)
+	}
+
+	keys := make([]string, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		routes := buckets[key]
+		for i, a := range routes {
+			for _, b := range routes[i+1:] {
+				l.ambiguous(a, b)
+			}
+			if key == anyPathBucket {
+				continue
+			}
+			for _, b := range buckets[anyPathBucket] {
+				l.ambiguous(a, b)
+			}
+		}
+	}
+}
+
+// ambiguous reports the later of the two routes when a witness request matches both
+func (l *linter) ambiguous(a, b *routeConditions) {
+	if a.index > b.index {
+		a, b = b, a
+	}
+	if a.route.route.Id == b.route.route.Id || a.shadows(b) != notShadowed || b.shadows(a) != notShadowed {
+		return
+	}
+	if _, _, ok := l.pathInSubtree(a.route.route, b.route.route); ok {
+		return
+	}
+	if _, _, ok := l.pathInSubtree(b.route.route, a.route.route); ok {
+		return
+	}
+	witness, ok := ambiguityWitness(a.route.route, b.route.route, l.predicates)
+	if !ok {
+		return
+	}
+	f := l.report(RuleAmbiguousRoutes, SeverityWarning, b.route, "route can match the same requests of route %s (%s), eg. %s", a.route.route.Id, a.route.location, witnessString(witness))
+	if f != nil {
+		f.Witness = witness
+	}
+}
+
+// pathBucket the first segment of the path of the route, anyPathBucket when
+// the route can match paths starting with any segment
+func pathBucket(rc *routeConditions) string {
+	if rc.leaf == "" || rc.catchAll() {
+		return anyPathBucket
+	}
+	segment := strings.SplitN(strings.TrimPrefix(rc.leaf, "/"), "/", 2)[0]
+	if segment == "" || strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+		return anyPathBucket
+	}
+	return segment
+}
+
+// ambiguityWitness generates a request for the predicates of both routes,
+// the path taken from one route or the other, and returns it if both the
+// routes match it. The routes with predicates the generator can't satisfy,
+// eg. Traffic or custom ones, have no witness.
+func ambiguityWitness(a, b *eskip.Route, specs map[string]routing.PredicateSpec) (*RequestAttributes, bool) {
+	for _, pair := range [][2]*eskip.Route{{a, b}, {b, a}} {
+		ps := Predicates(pair[0])
+		for _, p := range Predicates(pair[1]) {
+			if p.Name != pathPredicate && p.Name != pathSubtreePredicate {
+				ps = append(ps, p)
+			}
+		}
+		attrs, unsatisfied := GenerateRequest(&eskip.Route{Predicates: ps})
+		if len(unsatisfied) == 0 && routeMatches(a, attrs, specs) && routeMatches(b, attrs, specs) {
+			return attrs, true
+		}
+	}
+	return nil, false
+}
+
+// routeMatches true if all the predicates of the route match the request
+func routeMatches(r *eskip.Route, attrs *RequestAttributes, specs map[string]routing.PredicateSpec) bool {
+	req, err := createHTTPRequest(attrs)
+	if err != nil {
+		return false
+	}
+	for _, v := range evaluateRoute(r, req, cleanPath(req.URL.Path, false), specs, false) {
+		if !v.Matched {
+			return false
+		}
+	}
+	return true
+}
+
+// witnessString formats a request on one line, eg. GET /orders?q=example Host: a.org X-A: 1
+func witnessString(attrs *RequestAttributes) string {
+	var b strings.Builder
+	b.WriteString(attrs.Method + " " + attrs.Path)
+	if len(attrs.Query) > 0 {
+		query := url.Values{}
+		for name, value := range attrs.Query {
+			query.Set(name, value)
+		}
+		b.WriteString("?" + query.Encode())
+	}
+	if attrs.Host != "" {
+		b.WriteString(" Host: " + attrs.Host)
+	}
+	for _, name := range sortedKeys(attrs.Headers) {
+		b.WriteString(" " + name + ": " + attrs.Headers[name])
+	}
+	return b.String()
+}
+
+// Ambiguity routes matching the request generated for one of them, see
+// FindAmbiguities
+type Ambiguity struct {
+	// RouteID the route the witness was generated for
+	RouteID string `json:"routeId" yaml:"routeId"`
+	// Location where the route is defined
+	Location Location `json:"location" yaml:"location"`
+	// Routes ids of all the routes matching the witness, sorted
+	Routes []string `json:"routes" yaml:"routes"`
+	// Winner the route the routing picks for the witness
+	Winner string `json:"winner" yaml:"winner"`
+	// Witness the request generated for the route, see GenerateRequest
+	Witness *RequestAttributes `json:"witness" yaml:"witness"`
+}
+
+// FindAmbiguities loads the routes file, generates a request for each route
+// of the routing table and evaluates the predicates of all the routes
+// against it: when more routes match it, they are returned with the one the
+// routing picks. A set of routes is returned once, with the witness of the
+// first route by id it's found for. The routes with predicates the generator can't satisfy,
+// eg. Traffic or custom ones, have no witness. The Traffic predicates
+// always match with TrafficRandom, they can match any request. Options.Lazy
+// is ignored.
+func FindAmbiguities(o *Options) ([]Ambiguity, error) {
+	if o.TrafficMode == TrafficRandom {
+		c := *o
+		c.TrafficMode = TrafficAlways
+		o = &c
+	}
+	f, err := newMatcher(o)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	routes := f.events.routes()
+	ambiguities := []Ambiguity{}
+	seen := make(map[string]bool)
+	for _, r := range routes {
+		witness, unsatisfied := GenerateRequest(r)
+		if len(unsatisfied) > 0 {
+			continue
+		}
+		req, err := createHTTPRequest(normalizeAttributes(witness))
+		if err != nil {
+			continue
+		}
+		path := cleanPath(req.URL.Path, f.options.IgnoreTrailingSlash)
+		var matching []string
+		for _, other := range routes {
+			if f.routeMatches(other, req, path) {
+				matching = append(matching, other.Id)
+			}
+		}
+		key := strings.Join(matching, "\x00")
+		if len(matching) < 2 || !contains(matching, r.Id) || seen[key] {
+			continue
+		}
+		seen[key] = true
+		a := Ambiguity{RouteID: r.Id, Location: f.locations[r.Id], Routes: matching, Witness: witness}
+		if winner := f.test(normalizeAttributes(witness), time.Time{}).route; winner != nil {
+			a.Winner = winner.Id
+		}
+		ambiguities = append(ambiguities, a)
+	}
+	return ambiguities, nil
+}
+
+// routeMatches true if all the predicates of the route match the request
+func (f *matcher) routeMatches(r *eskip.Route, req *http.Request, path string) bool {
+	for _, v := range evaluateRoute(r, req, path, f.predicates, f.options.IgnoreTrailingSlash) {
+		if !v.Matched {
+			return false
+		}
+	}
+	return true
+}
+
+// AmbiguityFindings the ambiguities as findings of the ambiguous-witness
+// rule, on the route the witness was generated for, with the severity of
+// the rules, none when the rule is ignored
+func AmbiguityFindings(ambiguities []Ambiguity, rules RuleOptions) []Finding {
+	if rules.ignored(RuleAmbiguousWitness) {
+		return nil
+	}
+	findings := make([]Finding, 0, len(ambiguities))
+	for _, a := range ambiguities {
+		winner := a.Winner
+		if winner == "" {
+			winner = "none"
+		}
+		findings = append(findings, rules.apply(Finding{
+			RuleID:   RuleAmbiguousWitness,
+			Severity: SeverityWarning,
+			RouteID:  a.RouteID,
+			File:     a.Location.File,
+			Line:     a.Location.Line,
+			Message:  fmt.Sprintf("routes %s match the request generated for the route, %s wins, eg. %s", strings.Join(a.Routes, ", "), winner, witnessString(a.Witness)),
+			Witness:  a.Witness,
+		}))
+	}
+	return findings
+}
@@ -0,0 +1,102 @@
+package matcher
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintAmbiguousRoutes(t *testing.T) {
+	docs := []Document{{
+		File: "routes.eskip",
+		Content: []byte(`api: PathSubtree("/api") && Header("X-Tenant", "acme") -> <shunt>;
+users: Path("/api/users") && Method("GET") -> <shunt>;
+posts: Path("/api/posts") && Method("POST") -> <shunt>;
+tenant: Host(/^acme[.]org$/) && Path("/api/posts") -> <shunt>;
+search: Path("/search") && QueryParam("q") -> <shunt>;
+canary: Path("/search") && Traffic(0.1) -> <shunt>;
+other: Path("/other") && Method("GET") -> <shunt>;
+`),
+	}}
+
+	assert.Empty(t, Lint(docs, nil), "disabled by default")
+
+	findings := Lint(docs, &LintOptions{CheckAmbiguity: true})
+	var strs []string
+	for _, f := range findings {
+		strs = append(strs, f.String())
+	}
+	assert.Equal(t, []string{
+		`routes.eskip:2: warning: users: route can match the same requests of route api (routes.eskip:1), eg. GET /api/users X-Tenant: acme [ambiguous-routes]`,
+		`routes.eskip:3: warning: posts: route can match the same requests of route api (routes.eskip:1), eg. POST /api/posts X-Tenant: acme [ambiguous-routes]`,
+		`routes.eskip:4: warning: tenant: route can match the same requests of route api (routes.eskip:1), eg. GET /api/posts Host: acme.org X-Tenant: acme [ambiguous-routes]`,
+		`routes.eskip:4: warning: tenant: route can match the same requests of route posts (routes.eskip:3), eg. POST /api/posts Host: acme.org [ambiguous-routes]`,
+	}, strs)
+	if assert.NotEmpty(t, findings) {
+		assert.Equal(t, &RequestAttributes{
+			Method:  "GET",
+			Path:    "/api/users",
+			Query:   map[string]string{},
+			Headers: map[string]string{"X-Tenant": "acme"},
+		}, findings[0].Witness)
+	}
+}
+
+func TestPathBucket(t *testing.T) {
+	for predicates, want := range map[string]string{
+		`Path("/api/users")`:   "api",
+		`PathSubtree("/api")`:  "api",
+		`Path("/:tenant/api")`: anyPathBucket,
+		`PathSubtree("/")`:     anyPathBucket,
+		`Method("GET")`:        anyPathBucket,
+	} {
+		assert.Equal(t, want, pathBucket(conditionsOf(t, predicates)), predicates)
+	}
+}
+
+func TestFindAmbiguities(t *testing.T) {
+	file := writeRoutes(t, `api: PathSubtree("/api") && Header("X-Tenant", "acme") -> <shunt>;
+users: Path("/api/users") && Header("X-Tenant", "acme") -> <shunt>;
+v1: Path("/v1") -> <shunt>;
+v1_regexp: PathRegexp(/^\/v1/) -> <shunt>;
+canary: Path("/v1") && Traffic(0.1) -> <shunt>;
+other: Path("/other") && Method("GET") -> <shunt>;
+`)
+	defer os.Remove(file)
+
+	ambiguities, err := FindAmbiguities(&Options{RoutesFile: file})
+	require.NoError(t, err)
+	require.Len(t, ambiguities, 2)
+
+	// the witness of users matches api as well, the witness of api doesn't match users
+	assert.Equal(t, "users", ambiguities[0].RouteID)
+	assert.Equal(t, []string{"api", "users"}, ambiguities[0].Routes)
+	assert.Equal(t, "users", ambiguities[0].Winner)
+	assert.Equal(t, Location{File: file, Line: 2}, ambiguities[0].Location)
+	assert.Equal(t, "/api/users", ambiguities[0].Witness.Path)
+
+	// the set once, canary has no witness but matches the one of v1
+	assert.Equal(t, "v1", ambiguities[1].RouteID)
+	assert.Equal(t, []string{"canary", "v1", "v1_regexp"}, ambiguities[1].Routes)
+	assert.Equal(t, "canary", ambiguities[1].Winner)
+
+	var strs []string
+	for _, f := range AmbiguityFindings(ambiguities, RuleOptions{Severity: map[string]Severity{RuleAmbiguousWitness: SeverityError}}) {
+		strs = append(strs, f.String())
+	}
+	assert.Equal(t, []string{
+		file + `:2: error: users: routes api, users match the request generated for the route, users wins, eg. GET /api/users X-Tenant: acme [ambiguous-witness]`,
+		file + `:3: error: v1: routes canary, v1, v1_regexp match the request generated for the route, canary wins, eg. GET /v1 [ambiguous-witness]`,
+	}, strs)
+	assert.Empty(t, AmbiguityFindings(ambiguities, RuleOptions{Ignore: []string{RuleAmbiguousWitness}}))
+
+	ambiguities, err = FindAmbiguities(&Options{RoutesFile: file, TrafficMode: TrafficNever})
+	require.NoError(t, err)
+	require.Len(t, ambiguities, 2)
+	assert.Equal(t, []string{"v1", "v1_regexp"}, ambiguities[1].Routes)
+
+	_, err = FindAmbiguities(&Options{RoutesFile: "testdata/missing.eskip"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package matcher
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// mostComplexRoutes number of routes listed in Report.MostComplex
+const mostComplexRoutes = 10
+
+// Report metrics about the routes of a routes file, to find the routes
+// worth refactoring
+type Report struct {
+	// Routes number of routes
+	Routes int `json:"routes" yaml:"routes"`
+	// AveragePredicates average number of predicates per route
+	AveragePredicates float64 `json:"averagePredicates" yaml:"averagePredicates"`
+	// AverageFilters average number of filters per route
+	AverageFilters float64 `json:"averageFilters" yaml:"averageFilters"`
+	// RoutesPerHost number of routes by Host predicate regexp
+	RoutesPerHost map[string]int `json:"routesPerHost" yaml:"routesPerHost"`
+	// RoutesWithoutHost number of routes without a Host predicate
+	RoutesWithoutHost int `json:"routesWithoutHost" yaml:"routesWithoutHost"`
+	// MostComplex the most complex routes, by score
+	MostComplex []RouteComplexity `json:"mostComplex" yaml:"mostComplex"`
+	// Complexity of every route in file order
+	Complexity []RouteComplexity `json:"complexity" yaml:"complexity"`
+}
+
+// RouteComplexity metrics of a route
+type RouteComplexity struct {
+	// RouteID id of the route
+	RouteID string `json:"routeId" yaml:"routeId"`
+	// Location where the route is defined, when known
+	Location *Location `json:"location,omitempty" yaml:"location,omitempty"`
+	// Predicates number of predicates, Path and the legacy fields included
+	Predicates int `json:"predicates" yaml:"predicates"`
+	// RegexpPredicates number of predicates with a regexp argument: Host,
+	// PathRegexp, HeaderRegexp and Cookie or QueryParam with a value regexp
+	RegexpPredicates int `json:"regexpPredicates" yaml:"regexpPredicates"`
+	// Filters number of filters
+	Filters int `json:"filters" yaml:"filters"`
+	// PathDepth number of segments of the Path or PathSubtree, 0 without a path
+	PathDepth int `json:"pathDepth" yaml:"pathDepth"`
+	// Score sum of the other metrics
+	Score int `json:"score" yaml:"score"`
+}
+
+// Analyze loads the routes file and returns the complexity report of its
+// routes, a syntax error is returned as a *ParseError
+func Analyze(o *Options) (*Report, error) {
+	c, err := openFile(o.RoutesFile, o.OnLoadProgress)
+	if err != nil {
+		return nil, err
+	}
+	return analyzeRoutes(pairRoutes(c.definitions, c.routes)), nil
+}
+
+// analyzeRoutes computes the report of the routes
+func analyzeRoutes(routes []lintRoute) *Report {
+	report := &Report{
+		Routes:        len(routes),
+		RoutesPerHost: map[string]int{},
+		MostComplex:   []RouteComplexity{},
+		Complexity:    []RouteComplexity{},
+	}
+	predicates, filters := 0, 0
+	for _, r := range routes {
+		rc := routeComplexity(r)
+		report.Complexity = append(report.Complexity, rc)
+		predicates += rc.Predicates
+		filters += rc.Filters
+
+		hosts := 0
+		for _, p := range Predicates(r.route) {
+			if host, ok := stringArg(p, 0); ok && p.Name == hostPredicate {
+				report.RoutesPerHost[host]++
+				hosts++
+			}
+		}
+		if hosts == 0 {
+			report.RoutesWithoutHost++
+		}
+	}
+	if len(routes) > 0 {
+		report.AveragePredicates = float64(predicates) / float64(len(routes))
+		report.AverageFilters = float64(filters) / float64(len(routes))
+	}
+
+	report.MostComplex = append(report.MostComplex, report.Complexity...)
+	sort.SliceStable(report.MostComplex, func(i, j int) bool {
+		return report.MostComplex[i].Score > report.MostComplex[j].Score
+	})
+	if len(report.MostComplex) > mostComplexRoutes {
+		report.MostComplex = report.MostComplex[:mostComplexRoutes]
+	}
+	return report
+}
+
+// routeComplexity computes the metrics of a route
+func routeComplexity(r lintRoute) RouteComplexity {
+	rc := RouteComplexity{RouteID: r.route.Id, Filters: len(r.route.Filters)}
+	if r.location.File != "" {
+		loc := r.location
+		rc.Location = &loc
+	}
+	for _, p := range Predicates(r.route) {
+		rc.Predicates++
+		if isRegexpPredicate(p) {
+			rc.RegexpPredicates++
+		}
+		if p.Name == pathPredicate || p.Name == pathSubtreePredicate {
+			path, _ := stringArg(p, 0)
+			rc.PathDepth = pathDepth(path)
+		}
+	}
+	rc.Score = rc.Predicates + rc.RegexpPredicates + rc.Filters + rc.PathDepth
+	return rc
+}
+
+// isRegexpPredicate true for the predicates with a regexp argument
+func isRegexpPredicate(p *eskip.Predicate) bool {
+	switch p.Name {
+	case hostPredicate, pathRegexpPredicate, headerRegexpPredicate:
+		return true
+	case cookiePredicate, queryParamPredicate:
+		return len(p.Args) > 1
+	default:
+		return false
+	}
+}
+
+// pathDepth number of segments of a path, eg. 2 for /orders/:id
+func pathDepth(path string) int {
+	depth := 0
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			depth++
+		}
+	}
+	return depth
+}
@@ -0,0 +1,61 @@
+package matcher
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/eskip"
+)
+
+func TestAnalyze(t *testing.T) {
+	file := writeRoutes(t, `home: Host(/^shop[.]org$/) && Path("/") -> <shunt>;
+orders: Host(/^shop[.]org$/) && Path("/api/orders/:id") && Method("GET") && QueryParam("v", "^[0-9]+$") -> setPath("/") -> stripQuery() -> "https://orders.svc";
+health: * -> status(200) -> <shunt>;
+`)
+	defer os.Remove(file)
+
+	report, err := Analyze(&Options{RoutesFile: file})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Routes)
+	assert.InDelta(t, 2, report.AveragePredicates, 0.001)
+	assert.InDelta(t, 1, report.AverageFilters, 0.001)
+	assert.Equal(t, map[string]int{"^shop[.]org$": 2}, report.RoutesPerHost)
+	assert.Equal(t, 1, report.RoutesWithoutHost)
+	assert.Equal(t, RouteComplexity{
+		RouteID:          "orders",
+		Location:         &Location{File: file, Line: 2},
+		Predicates:       4,
+		RegexpPredicates: 2,
+		Filters:          2,
+		PathDepth:        3,
+		Score:            11,
+	}, report.MostComplex[0])
+	var order []string
+	for _, rc := range report.MostComplex {
+		order = append(order, rc.RouteID)
+	}
+	assert.Equal(t, []string{"orders", "home", "health"}, order)
+	assert.Equal(t, "home", report.Complexity[0].RouteID)
+}
+
+func TestAnalyzeParseError(t *testing.T) {
+	file := writeRoutes(t, `broken: Path("/") -> `)
+	defer os.Remove(file)
+
+	_, err := Analyze(&Options{RoutesFile: file})
+	assert.IsType(t, &ParseError{}, err)
+}
+
+func TestRouteComplexityPredicateSyntax(t *testing.T) {
+	legacy, err := eskip.Parse(`r: Host(/^a[.]org$/) && Path("/a/b") && Method("GET") -> <shunt>;`)
+	require.NoError(t, err)
+	predicates := &eskip.Route{Id: "r", Predicates: []*eskip.Predicate{
+		{Name: hostPredicate, Args: []interface{}{"^a[.]org$"}},
+		{Name: pathPredicate, Args: []interface{}{"/a/b"}},
+		{Name: methodPredicate, Args: []interface{}{"GET"}},
+	}, BackendType: eskip.ShuntBackend}
+
+	assert.Equal(t, routeComplexity(lintRoute{route: legacy[0]}), routeComplexity(lintRoute{route: predicates}))
+}
@@ -0,0 +1,256 @@
+// Package assertions provides the matchers of the test results for the
+// testify and gomega users, eg.
+//
+//	assertions.That(t, result, assertions.MatchRoute("api_orders"))
+//	Expect(result).To(assertions.MatchRoute("api_orders").WithBackend("https://orders.svc"))
+//
+// Neither library is a dependency: That takes a TestingT like the one of
+// testify, the *testing.T, and a *ResultMatcher is a gomega
+// types.GomegaMatcher by its methods. The failure messages show the tested
+// request and the matching route pretty printed.
+package assertions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+// TestingT the failure reporting of the tests, eg. *testing.T or the
+// assert.TestingT of testify
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// helper the TestingT reporting the failures at the line of the caller
+type helper interface {
+	Helper()
+}
+
+// ResultMatcher the expectations on a matcher.TestResult, all of them
+// satisfied for a match
+type ResultMatcher struct {
+	checks  []check
+	negated bool
+}
+
+// check an expectation, ok tells what the result has instead when it isn't
+// satisfied
+type check struct {
+	// want the expectation, eg. route orders
+	want string
+	ok   func(result matcher.TestResult) (bool, string)
+}
+
+// MatchRoute expects the route with the id to match
+func MatchRoute(id string) *ResultMatcher {
+	return (&ResultMatcher{}).with(check{"route " + id, func(result matcher.TestResult) (bool, string) {
+		route := result.Route()
+		if route == nil {
+			return false, "no route matches"
+		}
+		return route.Id == id, "route " + route.Id + " matches"
+	}})
+}
+
+// Matched expects a route to match
+func Matched() *ResultMatcher {
+	return (&ResultMatcher{}).with(check{"a matching route", func(result matcher.TestResult) (bool, string) {
+		return result.Route() != nil, "no route matches"
+	}})
+}
+
+// Unmatched expects no route to match
+func Unmatched() *ResultMatcher {
+	return (&ResultMatcher{}).with(check{"no matching route", func(result matcher.TestResult) (bool, string) {
+		if route := result.Route(); route != nil {
+			return false, "route " + route.Id + " matches"
+		}
+		return true, ""
+	}})
+}
+
+// HaveBackend expects the matching route to have the backend, like in
+// eskip, eg. https://orders.svc or <shunt>
+func HaveBackend(backend string) *ResultMatcher {
+	return (&ResultMatcher{}).WithBackend(backend)
+}
+
+// HaveFilter expects the filter in the filter chain of the matching route
+func HaveFilter(name string) *ResultMatcher {
+	return (&ResultMatcher{}).WithFilter(name)
+}
+
+// HavePathParam expects the path parameter of the matching route with the
+// value, eg. id of Path("/orders/:id")
+func HavePathParam(name, value string) *ResultMatcher {
+	return (&ResultMatcher{}).WithPathParam(name, value)
+}
+
+// Not expects the result not to satisfy the matcher
+func Not(m *ResultMatcher) *ResultMatcher {
+	return &ResultMatcher{checks: m.checks, negated: !m.negated}
+}
+
+// WithBackend expects in addition the backend, see HaveBackend
+func (m *ResultMatcher) WithBackend(backend string) *ResultMatcher {
+	return m.with(check{"backend " + backend, func(result matcher.TestResult) (bool, string) {
+		if result.Route() == nil {
+			return false, "no route matches"
+		}
+		got := matcher.NewResultData(result).Backend
+		return got == backend, "backend " + got
+	}})
+}
+
+// WithFilter expects in addition the filter, see HaveFilter
+func (m *ResultMatcher) WithFilter(name string) *ResultMatcher {
+	return m.with(check{"filter " + name, func(result matcher.TestResult) (bool, string) {
+		if result.Route() == nil {
+			return false, "no route matches"
+		}
+		var names []string
+		for _, f := range result.Filters() {
+			if f.Name == name {
+				return true, ""
+			}
+			names = append(names, f.Name)
+		}
+		if len(names) == 0 {
+			return false, "no filters"
+		}
+		return false, "filters " + strings.Join(names, ", ")
+	}})
+}
+
+// WithPathParam expects in addition the path parameter, see HavePathParam
+func (m *ResultMatcher) WithPathParam(name, value string) *ResultMatcher {
+	return m.with(check{fmt.Sprintf("path parameter %s=%s", name, value), func(result matcher.TestResult) (bool, string) {
+		if result.Route() == nil {
+			return false, "no route matches"
+		}
+		got, ok := result.PathParams()[name]
+		if !ok {
+			return false, "no path parameter " + name
+		}
+		return got == value, fmt.Sprintf("path parameter %s=%s", name, got)
+	}})
+}
+
+// with a copy of the matcher with the check added
+func (m *ResultMatcher) with(c check) *ResultMatcher {
+	checks := append(append([]check(nil), m.checks...), c)
+	return &ResultMatcher{checks: checks, negated: m.negated}
+}
+
+// String describes the expectations, eg. route orders, backend <shunt>
+func (m *ResultMatcher) String() string {
+	wants := make([]string, len(m.checks))
+	for i, c := range m.checks {
+		wants[i] = c.want
+	}
+	if m.negated {
+		return "not " + strings.Join(wants, ", ")
+	}
+	return strings.Join(wants, ", ")
+}
+
+// Match true if the result, a matcher.TestResult, satisfies the
+// expectations, the error is the one of a result which couldn't be tested
+// (gomega types.GomegaMatcher)
+func (m *ResultMatcher) Match(actual interface{}) (bool, error) {
+	result, err := testResult(actual)
+	if err != nil {
+		return false, err
+	}
+	_, failed := m.failure(result)
+	return !failed != m.negated, nil
+}
+
+// FailureMessage why the result doesn't satisfy the expectations (gomega
+// types.GomegaMatcher)
+func (m *ResultMatcher) FailureMessage(actual interface{}) string {
+	return m.message(actual, false)
+}
+
+// NegatedFailureMessage why the result satisfies the expectations when it
+// shouldn't (gomega types.GomegaMatcher)
+func (m *ResultMatcher) NegatedFailureMessage(actual interface{}) string {
+	return m.message(actual, true)
+}
+
+// That reports a failure with t when the result doesn't satisfy the
+// matcher, true if it does, eg.
+// assertions.That(t, result, assertions.Not(assertions.Matched()))
+func That(t TestingT, result matcher.TestResult, m *ResultMatcher, msgAndArgs ...interface{}) bool {
+	if h, ok := t.(helper); ok {
+		h.Helper()
+	}
+	ok, err := m.Match(result)
+	if err != nil {
+		t.Errorf("%s%v", prefix(msgAndArgs), err)
+		return false
+	}
+	if !ok {
+		t.Errorf("%s%s", prefix(msgAndArgs), m.FailureMessage(result))
+	}
+	return ok
+}
+
+// failure what the result has instead of the first expectation it doesn't
+// satisfy, false when it satisfies all
+func (m *ResultMatcher) failure(result matcher.TestResult) (string, bool) {
+	for _, c := range m.checks {
+		if ok, got := c.ok(result); !ok {
+			return got, true
+		}
+	}
+	return "", false
+}
+
+// message the failure message of To, of NotTo when negated
+func (m *ResultMatcher) message(actual interface{}, negated bool) string {
+	result, err := testResult(actual)
+	if err != nil {
+		return err.Error()
+	}
+	expected := &ResultMatcher{checks: m.checks}
+	var b strings.Builder
+	if negated != m.negated {
+		fmt.Fprintf(&b, "expected not %s\n", expected)
+	} else {
+		got, _ := expected.failure(result)
+		fmt.Fprintf(&b, "expected %s, got %s\n", expected, got)
+	}
+	b.WriteString(result.PrettyPrint())
+	return b.String()
+}
+
+// testResult the matcher.TestResult of actual, an error when it isn't one
+// or couldn't be tested
+func testResult(actual interface{}) (matcher.TestResult, error) {
+	result, ok := actual.(matcher.TestResult)
+	if !ok || result == nil {
+		return nil, fmt.Errorf("expected a matcher.TestResult, got %T", actual)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("the request couldn't be tested: %v", err)
+	}
+	return result, nil
+}
+
+// prefix the message of testify msgAndArgs followed by a line break, empty
+// without
+func prefix(msgAndArgs []interface{}) string {
+	switch {
+	case len(msgAndArgs) == 0:
+		return ""
+	case len(msgAndArgs) == 1:
+		return fmt.Sprintf("%v\n", msgAndArgs[0])
+	}
+	if format, ok := msgAndArgs[0].(string); ok {
+		return fmt.Sprintf(format, msgAndArgs[1:]...) + "\n"
+	}
+	return fmt.Sprint(msgAndArgs...) + "\n"
+}
@@ -0,0 +1,124 @@
+package assertions
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingT TestingT keeping the failures
+type recordingT struct {
+	errors  []string
+	helpers int
+}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingT) Helper() { r.helpers++ }
+
+func newResults(t *testing.T) (orders, unknown matcher.TestResult) {
+	m, err := matcher.NewWith(matcher.WithRoutes(`orders: Path("/orders/:id") -> setRequestHeader("X-Team", "orders") -> "https://orders.svc";`), matcher.WithTB(t))
+	require.NoError(t, err)
+	return m.Test(&matcher.RequestAttributes{Path: "/orders/42"}), m.Test(&matcher.RequestAttributes{Path: "/unknown"})
+}
+
+func TestThat(t *testing.T) {
+	orders, unknown := newResults(t)
+
+	tests := []struct {
+		name    string
+		result  matcher.TestResult
+		matcher *ResultMatcher
+		want    string
+	}{
+		{"route", orders, MatchRoute("orders"), ""},
+		{"other route", orders, MatchRoute("users"), "expected route users, got route orders matches"},
+		{"no route", unknown, MatchRoute("orders"), "expected route orders, got no route matches"},
+		{"not route", orders, Not(MatchRoute("users")), ""},
+		{"not the route", orders, Not(MatchRoute("orders")), "expected not route orders"},
+		{"matched", orders, Matched(), ""},
+		{"not matched", unknown, Matched(), "expected a matching route, got no route matches"},
+		{"unmatched", unknown, Unmatched(), ""},
+		{"not unmatched", unknown, Not(Unmatched()), "expected not no matching route"},
+		{"backend", orders, MatchRoute("orders").WithBackend("https://orders.svc"), ""},
+		{"other backend", orders, MatchRoute("orders").WithBackend("<shunt>"), "expected route orders, backend <shunt>, got backend https://orders.svc"},
+		{"have backend", orders, HaveBackend("https://orders.svc"), ""},
+		{"not backend", orders, Not(HaveBackend("https://orders.svc")), "expected not backend https://orders.svc"},
+		{"filter", orders, HaveFilter("setRequestHeader"), ""},
+		{"missing filter", orders, HaveFilter("setPath"), "expected filter setPath, got filters setRequestHeader"},
+		{"not filter", orders, Not(HaveFilter("setPath")), ""},
+		{"path param", orders, HavePathParam("id", "42"), ""},
+		{"other path param", orders, MatchRoute("orders").WithPathParam("id", "43"), "expected route orders, path parameter id=43, got path parameter id=42"},
+		{"missing path param", orders, HavePathParam("item", "1"), "expected path parameter item=1, got no path parameter item"},
+		{"not path param", orders, Not(HavePathParam("id", "43")), ""},
+		{"path param without match", unknown, HavePathParam("id", "42"), "expected path parameter id=42, got no route matches"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordingT{}
+			ok := That(rt, tt.result, tt.matcher)
+			assert.Equal(t, 1, rt.helpers)
+			if tt.want == "" {
+				assert.True(t, ok)
+				assert.Empty(t, rt.errors)
+				return
+			}
+			assert.False(t, ok)
+			require.Len(t, rt.errors, 1)
+			assert.Equal(t, tt.want+"\n"+tt.result.PrettyPrint(), rt.errors[0], "with the request and the route")
+		})
+	}
+
+	rt := &recordingT{}
+	assert.False(t, That(rt, orders, MatchRoute("users"), "order %d", 42))
+	require.Len(t, rt.errors, 1)
+	assert.Contains(t, rt.errors[0], "order 42\nexpected route users, got route orders matches\n")
+}
+
+// TestGomega the methods of gomega types.GomegaMatcher like Expect calls
+// them: To fails with FailureMessage when Match is false, NotTo with
+// NegatedFailureMessage when it's true
+func TestGomega(t *testing.T) {
+	orders, unknown := newResults(t)
+
+	m := MatchRoute("orders").WithBackend("https://orders.svc")
+	ok, err := m.Match(orders)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "expected not route orders, backend https://orders.svc\n"+orders.PrettyPrint(), m.NegatedFailureMessage(orders))
+
+	ok, err = m.Match(unknown)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "expected route orders, backend https://orders.svc, got no route matches\n"+unknown.PrettyPrint(), m.FailureMessage(unknown))
+
+	negated := Not(MatchRoute("orders"))
+	ok, err = negated.Match(orders)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "expected not route orders\n"+orders.PrettyPrint(), negated.FailureMessage(orders))
+	ok, err = negated.Match(unknown)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "expected route orders, got no route matches\n"+unknown.PrettyPrint(), negated.NegatedFailureMessage(unknown), "NotTo(Not(...))")
+
+	_, err = m.Match("orders")
+	assert.EqualError(t, err, "expected a matcher.TestResult, got string")
+}
+
+func TestNotTested(t *testing.T) {
+	m, err := matcher.NewWith(matcher.WithRoutes(`orders: Path("/orders") -> <shunt>;`))
+	require.NoError(t, err)
+	m.Close()
+	result := m.Test(&matcher.RequestAttributes{Path: "/orders"})
+
+	rt := &recordingT{}
+	assert.False(t, That(rt, result, Not(Matched())), "neither matched nor unmatched")
+	require.Len(t, rt.errors, 1)
+	assert.Contains(t, rt.errors[0], "the request couldn't be tested: ")
+}
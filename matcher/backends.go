@@ -0,0 +1,83 @@
+package matcher
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// BackendRoutes the routes sending the requests to a backend
+type BackendRoutes struct {
+	// Backend normalized address of a network backend or load balancer
+	// endpoint, or the <shunt>, <loopback> and <dynamic> placeholders
+	Backend string `json:"backend" yaml:"backend"`
+	// Routes ids of the routes using the backend, in file order
+	Routes []string `json:"routes" yaml:"routes"`
+}
+
+// BackendInventory loads the routes file and groups its routes by backend,
+// sorted by backend. A load balanced route is listed under every one of its
+// endpoints. A syntax error is returned as a *ParseError.
+func BackendInventory(o *Options) ([]BackendRoutes, error) {
+	c, err := openFile(o.RoutesFile, o.OnLoadProgress)
+	if err != nil {
+		return nil, err
+	}
+	return backendInventory(c.routes), nil
+}
+
+// backendInventory groups the routes by normalized backend address
+func backendInventory(routes []*eskip.Route) []BackendRoutes {
+	byBackend := make(map[string][]string)
+	add := func(backend, id string) {
+		ids := byBackend[backend]
+		if len(ids) == 0 || ids[len(ids)-1] != id {
+			byBackend[backend] = append(ids, id)
+		}
+	}
+	for _, r := range routes {
+		switch routeBackendType(r) {
+		case eskip.NetworkBackend:
+			add(normalizeBackend(r.Backend), r.Id)
+		case eskip.LBBackend:
+			for _, ep := range r.LBEndpoints {
+				add(normalizeBackend(ep), r.Id)
+			}
+		default:
+			add(backendString(r), r.Id)
+		}
+	}
+
+	inventory := make([]BackendRoutes, 0, len(byBackend))
+	for backend, ids := range byBackend {
+		inventory = append(inventory, BackendRoutes{Backend: backend, Routes: ids})
+	}
+	sort.Slice(inventory, func(i, j int) bool { return inventory[i].Backend < inventory[j].Backend })
+	return inventory
+}
+
+// normalizeBackend lower cases the scheme and the host of a backend address
+// and strips the default port, eg. HTTPS://Svc:443 becomes https://svc. An
+// address which isn't a valid url is returned as it is.
+func normalizeBackend(backend string) string {
+	u, err := url.Parse(backend)
+	if err != nil || u.Host == "" {
+		return backend
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == "80" && u.Scheme == "http" || port == "443" && u.Scheme == "https" {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		// an ipv6 address
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+	return u.String()
+}
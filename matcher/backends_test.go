@@ -0,0 +1,46 @@
+package matcher
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendInventory(t *testing.T) {
+	file := writeRoutes(t, `orders: Path("/orders") -> "https://legacy-orders.internal:443";
+orders_v2: Path("/v2/orders") -> "HTTPS://Legacy-Orders.internal";
+lb: Path("/lb") -> <roundRobin, "http://a.internal:80", "http://b.internal:8080", "http://legacy-orders.internal">;
+lb_same: Path("/lb2") -> <roundRobin, "http://a.internal", "http://a.internal:80">;
+health: Path("/health") -> status(200) -> <shunt>;
+loop: Path("/loop") -> setPath("/health") -> <loopback>;
+dyn: Path("/dyn") -> <dynamic>;
+`)
+	defer os.Remove(file)
+
+	inventory, err := BackendInventory(&Options{RoutesFile: file})
+	require.NoError(t, err)
+	assert.Equal(t, []BackendRoutes{
+		{Backend: "<dynamic>", Routes: []string{"dyn"}},
+		{Backend: "<loopback>", Routes: []string{"loop"}},
+		{Backend: "<shunt>", Routes: []string{"health"}},
+		{Backend: "http://a.internal", Routes: []string{"lb", "lb_same"}},
+		{Backend: "http://b.internal:8080", Routes: []string{"lb"}},
+		{Backend: "http://legacy-orders.internal", Routes: []string{"lb"}},
+		{Backend: "https://legacy-orders.internal", Routes: []string{"orders", "orders_v2"}},
+	}, inventory)
+}
+
+func TestNormalizeBackend(t *testing.T) {
+	for backend, want := range map[string]string{
+		"https://svc:443":       "https://svc",
+		"http://svc:443":        "http://svc:443",
+		"HTTP://SVC:80/Path":    "http://svc/Path",
+		"http://[::1]:80":       "http://[::1]",
+		"http://[::1]:8080":     "http://[::1]:8080",
+		"http//orders.internal": "http//orders.internal",
+	} {
+		assert.Equal(t, want, normalizeBackend(backend), backend)
+	}
+}
@@ -0,0 +1,300 @@
+package matcher
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// cache matchers shared by Cached
+var cache = &matcherCache{entries: map[string]*cacheEntry{}}
+
+// matcherCache shared matchers by routes file and options, see cacheKey
+type matcherCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry a shared matcher, ready is closed once its creation is over
+type cacheEntry struct {
+	// sum hash of the routes file content the matcher was created from
+	sum   [sha256.Size]byte
+	ready chan struct{}
+	m     Matcher
+	err   error
+	// handles open handles, evicted set once the entry left the cache,
+	// both guarded by the cache mutex
+	handles int
+	evicted bool
+}
+
+// Cached returns a matcher shared with the other callers using the same
+// routes file, with the same content, and the same filters and matching
+// options (MockFilters, DisableBuiltinFilters, BuiltinFilterAllowlist,
+// IgnoreTrailingSlash, LightResults, Lazy, Strict, AutoMockFilters,
+// TrafficMode, DisableBundledPredicates, FailOnLoadErrors, Trace,
+// FollowLoopback, MaxLoopbackHops, DefaultFiltersPrepend,
+// DefaultFiltersAppend, TraceLookup, CaptureLogs, Generate,
+// AllowInvalidHeaders, AllowFilterOverride, DisablePanicRecovery,
+// AllowMissingFilters, AllowUnknownPredicates and FailOnNameCollisions),
+// eg. test packages testing one large routes file. Concurrent callers wait
+// for a single creation. The shared matcher is created with the options of
+// the first caller, the diagnostics and the InitTimeout options of the
+// others are ignored.
+//
+// Closing the returned matcher closes the caller's handle only: its results
+// have ErrClosed afterwards while the shared matcher keeps serving the other
+// and the next callers. A shared matcher is closed by CloseCached or, once the
+// routes file content changed and a new one replaced it, with its last handle.
+//
+// Options with CustomPredicates, CustomFilters, a FilterRegistry, plugins,
+// Hooks, an LBDecision, a Logger, a LogWriter, a DecisionLog, an
+// OnRouteDropped or a TB can't be compared, the matcher is then created by
+// New and isn't shared. Neither are the matchers of Routes documents and
+// Kubernetes routes, which have no routes file to key them by.
+func Cached(o *Options) (Matcher, error) {
+	if len(o.CustomPredicates) > 0 || len(o.CustomFilters) > 0 || len(o.PluginDirs) > 0 || len(o.Plugins) > 0 ||
+		o.Hooks.OnTest != nil || o.Hooks.OnReload != nil || o.LBDecision != nil || o.FilterRegistry != nil ||
+		o.Logger != nil || o.LogWriter != nil || o.DecisionLog != nil ||
+		o.OnRouteDropped != nil || o.TB != nil || o.Routes != "" || o.Kubernetes != nil {
+		return New(o)
+	}
+	doc, err := ioutil.ReadFile(o.RoutesFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := cacheKey(o)
+	if err != nil {
+		return nil, err
+	}
+
+	e, created := cache.acquire(key, sha256.Sum256(doc))
+	if created {
+		opts := *o
+		e.m, e.err = New(&opts)
+		if e.err != nil {
+			cache.remove(key, e)
+		}
+		close(e.ready)
+	}
+	<-e.ready
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &cachedMatcher{Matcher: e.m, entry: e, source: routesSource(o)}, nil
+}
+
+// CloseCached closes the matchers shared by Cached and empties the cache,
+// eg. at the end of a TestMain. Their handles have ErrClosed results afterwards.
+func CloseCached() {
+	cache.mu.Lock()
+	entries := cache.entries
+	cache.entries = map[string]*cacheEntry{}
+	for _, e := range entries {
+		e.evicted = true
+	}
+	cache.mu.Unlock()
+
+	for _, e := range entries {
+		<-e.ready
+		if e.m != nil {
+			e.m.Close()
+		}
+	}
+}
+
+// cacheKey identifies the options a shared matcher depends on,
+// the routes file content is compared on its own
+func cacheKey(o *Options) (string, error) {
+	path, err := filepath.Abs(o.RoutesFile)
+	if err != nil {
+		return "", err
+	}
+	mocked := append([]string{}, o.MockFilters...)
+	sort.Strings(mocked)
+	builtins := append([]string{}, o.BuiltinFilterAllowlist...)
+	sort.Strings(builtins)
+	return fmt.Sprintf("%s\x00%s\x00%t\x00%t\x00%s\x00%t\x00%t\x00%t\x00%t\x00%s\x00%t\x00%t\x00%t\x00%t\x00%d\x00%s\x00%s\x00%t\x00%t\x00%+v\x00%t\x00%t\x00%t\x00%t\x00%t\x00%t", path, strings.Join(mocked, ","), o.IgnoreTrailingSlash,
+		o.DisableBuiltinFilters, strings.Join(builtins, ","), o.LightResults, o.Lazy, o.Strict, o.AutoMockFilters, o.TrafficMode,
+		o.DisableBundledPredicates, o.FailOnLoadErrors, o.Trace, o.FollowLoopback, o.MaxLoopbackHops,
+		o.DefaultFiltersPrepend, o.DefaultFiltersAppend, o.TraceLookup, o.CaptureLogs, o.Generate, o.AllowInvalidHeaders,
+		o.AllowFilterOverride, o.DisablePanicRecovery, o.AllowMissingFilters,
+		o.AllowUnknownPredicates, o.FailOnNameCollisions), nil
+}
+
+// acquire returns the entry of key with a new handle, created is true when
+// the caller must create its matcher. An entry created from a different
+// content is evicted.
+func (c *matcherCache) acquire(key string, sum [sha256.Size]byte) (e *cacheEntry, created bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		if e.sum == sum {
+			e.handles++
+			return e, false
+		}
+		c.evict(key, e)
+	}
+	e = &cacheEntry{sum: sum, ready: make(chan struct{}), handles: 1}
+	c.entries[key] = e
+	return e, true
+}
+
+// evict removes the entry from the cache, its matcher is closed with the last handle
+func (c *matcherCache) evict(key string, e *cacheEntry) {
+	delete(c.entries, key)
+	e.evicted = true
+	if e.handles == 0 {
+		go func() {
+			<-e.ready
+			if e.m != nil {
+				e.m.Close()
+			}
+		}()
+	}
+}
+
+// remove removes an entry whose creation failed
+func (c *matcherCache) remove(key string, e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[key] == e {
+		delete(c.entries, key)
+	}
+	e.evicted = true
+}
+
+// release closes a handle of the entry
+func (c *matcherCache) release(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.handles--
+	if e.evicted && e.handles == 0 {
+		e.m.Close()
+	}
+}
+
+// cachedMatcher handle of a shared matcher
+type cachedMatcher struct {
+	Matcher
+	entry *cacheEntry
+	// source where the routes are loaded from, see MatchError
+	source string
+	// closed set to 1 by Close
+	closed    int32
+	closeOnce sync.Once
+}
+
+func (c *cachedMatcher) Test(attributes *RequestAttributes, opts ...TestOption) TestResult {
+	if c.isClosed() {
+		return errResult(attributes, c.source, ErrClosed)
+	}
+	return c.Matcher.Test(attributes, opts...)
+}
+
+func (c *cachedMatcher) TestAt(attributes *RequestAttributes, at time.Time) TestResult {
+	if c.isClosed() {
+		return errResult(attributes, c.source, ErrClosed)
+	}
+	return c.Matcher.TestAt(attributes, at)
+}
+
+func (c *cachedMatcher) Explain(attributes *RequestAttributes, n int) []NearMiss {
+	if c.isClosed() {
+		return nil
+	}
+	return c.Matcher.Explain(attributes, n)
+}
+
+func (c *cachedMatcher) TestManyStream(in <-chan *RequestAttributes, out chan<- TestResult) {
+	if c.isClosed() {
+		failStream(in, out, c.source, ErrClosed)
+		return
+	}
+	c.Matcher.TestManyStream(in, out)
+}
+
+func (c *cachedMatcher) Ready() (bool, error) {
+	if c.isClosed() {
+		return false, ErrClosed
+	}
+	return c.Matcher.Ready()
+}
+
+func (c *cachedMatcher) RouteCount() int {
+	if c.isClosed() {
+		return 0
+	}
+	return c.Matcher.RouteCount()
+}
+
+func (c *cachedMatcher) InitStats() InitStats {
+	if c.isClosed() {
+		return InitStats{}
+	}
+	return c.Matcher.InitStats()
+}
+
+func (c *cachedMatcher) Routes() []*eskip.Route {
+	if c.isClosed() {
+		return nil
+	}
+	return c.Matcher.Routes()
+}
+
+func (c *cachedMatcher) GetRoute(id string) (*eskip.Route, bool) {
+	if c.isClosed() {
+		return nil, false
+	}
+	return c.Matcher.GetRoute(id)
+}
+
+func (c *cachedMatcher) GenerateRequestFor(id string) (*RequestAttributes, []Unsatisfied, error) {
+	if c.isClosed() {
+		return nil, nil, ErrClosed
+	}
+	return c.Matcher.GenerateRequestFor(id)
+}
+
+func (c *cachedMatcher) Findings() []Finding {
+	if c.isClosed() {
+		return nil
+	}
+	return c.Matcher.Findings()
+}
+
+func (c *cachedMatcher) LoadErrors() []RouteLoadError {
+	if c.isClosed() {
+		return nil
+	}
+	return c.Matcher.LoadErrors()
+}
+
+func (c *cachedMatcher) DumpEffectiveRoutes(w io.Writer, format Format) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	return c.Matcher.DumpEffectiveRoutes(w, format)
+}
+
+// Close closes the handle, the shared matcher keeps running, see Cached
+func (c *cachedMatcher) Close() error {
+	c.closeOnce.Do(func() {
+		atomic.StoreInt32(&c.closed, 1)
+		cache.release(c.entry)
+	})
+	return nil
+}
+
+func (c *cachedMatcher) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
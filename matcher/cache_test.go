@@ -0,0 +1,209 @@
+package matcher
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/builtin"
+	"github.com/zalando/skipper/routing"
+)
+
+// countDataClients counts the matcher creations, it returns the restore function
+func countDataClients(created *int32) func() {
+	create := newDataClients
+	newDataClients = func(o *Options) ([]routing.DataClient, error) {
+		atomic.AddInt32(created, 1)
+		return create(o)
+	}
+	return func() { newDataClients = create }
+}
+
+// shared returns the matcher shared by a Cached handle
+func shared(m Matcher) Matcher {
+	return m.(*cachedMatcher).Matcher
+}
+
+func TestCachedSharesMatcher(t *testing.T) {
+	defer CloseCached()
+	var created int32
+	defer countDataClients(&created)()
+
+	const callers = 20
+	handles := make([]Matcher, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m, err := Cached(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+			assert.NoError(t, err)
+			handles[i] = m
+		}(i)
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, created)
+	for _, m := range handles {
+		require.NotNil(t, m)
+		assert.True(t, shared(handles[0]) == shared(m), "the callers must share one matcher")
+	}
+
+	other, err := Cached(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, IgnoreTrailingSlash: true})
+	require.NoError(t, err)
+	assert.True(t, shared(handles[0]) != shared(other), "different options need a different matcher")
+	assert.EqualValues(t, 2, created)
+
+	lazy, err := Cached(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, Lazy: true})
+	require.NoError(t, err)
+	assert.True(t, shared(handles[0]) != shared(lazy), "a lazy matcher must not be shared with an eager one")
+	_, isLazy := shared(lazy).(*lazyMatcher)
+	assert.True(t, isLazy)
+}
+
+func TestCachedClose(t *testing.T) {
+	defer CloseCached()
+	a, err := Cached(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	require.NoError(t, err)
+	b, err := Cached(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	require.NoError(t, err)
+
+	assert.NoError(t, a.Close())
+	assert.NoError(t, a.Close())
+	res := a.Test(&RequestAttributes{Path: "/bar"})
+	assert.True(t, errors.Is(res.Err(), ErrClosed))
+	assert.Nil(t, a.Explain(&RequestAttributes{Path: "/bar"}, 0))
+	assert.Equal(t, 0, a.RouteCount())
+	assert.Nil(t, a.Findings())
+	assert.Nil(t, a.Routes())
+	_, ok := a.GetRoute("bar")
+	assert.False(t, ok)
+	assert.NotEmpty(t, b.Routes())
+	assert.True(t, b.RouteCount() > 0)
+
+	res = b.Test(&RequestAttributes{Path: "/bar"})
+	assert.NoError(t, res.Err(), "closing a handle must not close the shared matcher")
+	require.NotNil(t, res.Route())
+
+	CloseCached()
+	assert.True(t, errors.Is(b.Test(&RequestAttributes{Path: "/bar"}).Err(), ErrClosed))
+	assert.NoError(t, b.Close())
+}
+
+func TestCachedContentChange(t *testing.T) {
+	defer CloseCached()
+	f, err := ioutil.TempFile("", "cached-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte(`a: Path("/a") -> <shunt>;`), 0644))
+
+	old, err := Cached(&Options{RoutesFile: f.Name()})
+	require.NoError(t, err)
+	require.NotNil(t, old.Test(&RequestAttributes{Path: "/a"}).Route())
+
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte(`b: Path("/b") -> <shunt>;`), 0644))
+	m, err := Cached(&Options{RoutesFile: f.Name()})
+	require.NoError(t, err)
+	assert.True(t, shared(old) != shared(m), "a changed content needs a new matcher")
+	assert.Nil(t, m.Test(&RequestAttributes{Path: "/a"}).Route())
+	assert.NotNil(t, m.Test(&RequestAttributes{Path: "/b"}).Route())
+
+	// the replaced matcher keeps serving its handles until the last one is closed
+	assert.NotNil(t, old.Test(&RequestAttributes{Path: "/a"}).Route())
+	replaced := shared(old)
+	old.Close()
+	assert.True(t, errors.Is(replaced.Test(&RequestAttributes{Path: "/a"}).Err(), ErrClosed))
+}
+
+func TestCachedErrors(t *testing.T) {
+	defer CloseCached()
+	_, err := Cached(&Options{RoutesFile: "testdata/missing.eskip"})
+	assert.Error(t, err)
+
+	f, err := ioutil.TempFile("", "cached-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte(`a: Path(`), 0644))
+	_, err = Cached(&Options{RoutesFile: f.Name(), InitTimeout: 100 * time.Millisecond})
+	assert.Error(t, err)
+	assert.Empty(t, cache.entries, "failed creations must not be cached")
+}
+
+func TestCachedCustomFiltersNotShared(t *testing.T) {
+	o := &Options{RoutesFile: "testdata/routes.eskip", CustomFilters: []filters.Spec{builtin.NewSetPath()}, MockFilters: []string{"customfilter"}, AllowFilterOverride: true}
+	a, err := Cached(o)
+	require.NoError(t, err)
+	defer a.Close()
+	b, err := Cached(o)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.True(t, a != b)
+	_, ok := a.(*cachedMatcher)
+	assert.False(t, ok)
+}
+
+func TestCachedRoutesNotShared(t *testing.T) {
+	defer CloseCached()
+	mocked := []string{"customfilter"}
+	file, err := Cached(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: mocked})
+	require.NoError(t, err)
+	defer file.Close()
+
+	m, err := Cached(&Options{Routes: `only: Path("/only") -> <shunt>;`, MockFilters: mocked})
+	require.NoError(t, err)
+	defer m.Close()
+	_, ok := m.(*cachedMatcher)
+	assert.False(t, ok)
+	routes := m.Routes()
+	require.Len(t, routes, 1)
+	assert.Equal(t, "only", routes[0].Id)
+	assert.True(t, len(file.Routes()) > 1, "the file entry keeps its routes")
+
+	_, err = Cached(&Options{RoutesFile: "testdata/routes.eskip", Routes: `only: Path("/only") -> <shunt>;`, MockFilters: mocked})
+	_, want := New(&Options{RoutesFile: "testdata/routes.eskip", Routes: `only: Path("/only") -> <shunt>;`, MockFilters: mocked})
+	require.Error(t, want)
+	assert.EqualError(t, err, want.Error(), "the file entry isn't returned for a Routes document")
+}
+
+func TestCachedLoadingOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		lenient Options
+		strict  Options
+	}{
+		{"missing filters", `a: Path("/a") -> <shunt>; b: Path("/b") -> unknownFilterX() -> <shunt>;`, Options{AllowMissingFilters: true}, Options{}},
+		{"unknown predicates", `a: Path("/a") -> <shunt>; b: UnknownPredicateX() -> <shunt>;`, Options{AllowUnknownPredicates: true}, Options{}},
+		{
+			"name collisions", `a: Path("/a") -> setPath("/b") -> <shunt>;`,
+			Options{MockFilters: []string{"setPath"}, AllowFilterOverride: true},
+			Options{MockFilters: []string{"setPath"}, AllowFilterOverride: true, FailOnNameCollisions: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer CloseCached()
+			file := writeRoutes(t, tt.doc)
+			defer os.Remove(file)
+
+			lenient := tt.lenient
+			lenient.RoutesFile = file
+			m, err := Cached(&lenient)
+			require.NoError(t, err)
+			defer m.Close()
+
+			strict := tt.strict
+			strict.RoutesFile = file
+			_, want := New(&strict)
+			require.Error(t, want)
+			_, err = Cached(&strict)
+			assert.EqualError(t, err, want.Error(), "the strict caller doesn't get the lenient matcher")
+		})
+	}
+}
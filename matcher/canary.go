@@ -0,0 +1,228 @@
+package matcher
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Defaults of CanaryOptions
+const (
+	DefaultCanaryPrefixSegments = 2
+	DefaultCanaryTopPrefixes    = 10
+)
+
+// CanaryOptions options of CanaryCompare
+type CanaryOptions struct {
+	// SampleRate fraction of the requests of the access log compared,
+	// between 0 and 1, all of them when 0
+	SampleRate float64
+	// Seed seed of the sampling, the same log is sampled the same way with
+	// the same seed. A seed is picked when 0, see CanaryReport.Seed.
+	Seed int64
+	// Threshold the largest fraction of the sampled requests resolving
+	// differently passing the comparison, eg. 0.01 for 1%, none when 0
+	Threshold float64
+	// AllowedRoutes ids of the routes changed on purpose, the requests
+	// matching them with the old or the new routes don't count
+	AllowedRoutes []string
+	// PrefixSegments path segments of the offending prefixes,
+	// DefaultCanaryPrefixSegments when 0
+	PrefixSegments int
+	// TopPrefixes number of offending prefixes reported,
+	// DefaultCanaryTopPrefixes when 0
+	TopPrefixes int
+}
+
+// CanaryReport result of CanaryCompare
+type CanaryReport struct {
+	// Seed seed of the sampling, to sample the same requests again
+	Seed int64 `json:"seed"`
+	// SampleRate fraction of the requests sampled
+	SampleRate float64 `json:"sampleRate"`
+	// Threshold the largest passing Ratio
+	Threshold float64 `json:"threshold"`
+	// Lines the requests of the access log, sampled or not
+	Lines int `json:"lines"`
+	// Invalid lines of the access log which aren't valid requests, skipped
+	Invalid int `json:"invalid"`
+	// Sampled requests tested against both routes
+	Sampled int `json:"sampled"`
+	// Allowed sampled requests resolving differently because of the allowed
+	// routes
+	Allowed int `json:"allowed"`
+	// Offending sampled requests resolving differently, the allowed ones
+	// excluded
+	Offending int `json:"offending"`
+	// Ratio offending fraction of the sampled requests
+	Ratio float64 `json:"ratio"`
+	// Passed true when the ratio isn't over the threshold
+	Passed bool `json:"passed"`
+	// Prefixes the path prefixes of the most offending requests, the most
+	// frequent first
+	Prefixes []PrefixCount `json:"prefixes"`
+	// Changes the offending requests, in the log order
+	Changes []RequestChange `json:"changes"`
+}
+
+// PrefixCount offending requests of a path prefix
+type PrefixCount struct {
+	// Prefix the first path segments, eg. /api/orders
+	Prefix string `json:"prefix"`
+	// Requests number of offending requests
+	Requests int `json:"requests"`
+}
+
+// CanaryCompare replays a sample of the requests of an access log against
+// the old and the new routes and fails when more than the threshold of them
+// resolve differently, like Diff, not counting the ones of the allowed
+// routes, eg. before each deploy of the routes. The lines of the log are the
+// ones of Auditor.AuditAccessLog, the invalid ones are skipped. The Traffic
+// predicates always match.
+func CanaryCompare(oldOpts, newOpts *Options, logReader io.Reader, opts CanaryOptions) (*CanaryReport, error) {
+	rate := opts.SampleRate
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	segments := opts.PrefixSegments
+	if segments <= 0 {
+		segments = DefaultCanaryPrefixSegments
+	}
+	top := opts.TopPrefixes
+	if top <= 0 {
+		top = DefaultCanaryTopPrefixes
+	}
+	allowed := make(map[string]bool, len(opts.AllowedRoutes))
+	for _, id := range opts.AllowedRoutes {
+		allowed[id] = true
+	}
+
+	old, err := newComparedMatcher(oldOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer old.Close()
+	new, err := newComparedMatcher(newOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer new.Close()
+
+	report := &CanaryReport{Seed: seed, SampleRate: rate, Threshold: opts.Threshold, Changes: []RequestChange{}}
+	random := rand.New(rand.NewSource(seed))
+	prefixes := make(map[string]int)
+	scanner := bufio.NewScanner(logReader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		report.Lines++
+		// every line draws, the sample doesn't depend on the invalid ones
+		if random.Float64() >= rate {
+			continue
+		}
+		req, err := ParseAccessLogLine(line)
+		if err != nil {
+			report.Invalid++
+			continue
+		}
+		oldTest, newTest := old.Test(req), new.Test(req)
+		if oldTest.Err() != nil || newTest.Err() != nil {
+			report.Invalid++
+			continue
+		}
+		report.Sampled++
+		c := RequestChange{Request: oldTest.Attributes(), Old: NewResultData(oldTest), New: NewResultData(newTest)}
+		if c.Class = changeClass(c.Old, c.New); c.Class == "" {
+			continue
+		}
+		if c.Old.Matched && allowed[c.Old.RouteID] || c.New.Matched && allowed[c.New.RouteID] {
+			report.Allowed++
+			continue
+		}
+		report.Offending++
+		report.Changes = append(report.Changes, c)
+		prefixes[pathPrefix(c.Request.Path, segments)]++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if report.Sampled > 0 {
+		report.Ratio = float64(report.Offending) / float64(report.Sampled)
+	}
+	report.Passed = report.Ratio <= report.Threshold
+	report.Prefixes = topPrefixes(prefixes, top)
+	return report, nil
+}
+
+// pathPrefix the first segments of the path, eg. /api/orders of
+// /api/orders/42 with 2 segments
+func pathPrefix(path string, segments int) string {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", segments+1)
+	if len(parts) > segments {
+		parts = parts[:segments]
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// topPrefixes the n prefixes with the most requests, the most frequent
+// first and then by prefix
+func topPrefixes(counts map[string]int, n int) []PrefixCount {
+	prefixes := make([]PrefixCount, 0, len(counts))
+	for prefix, requests := range counts {
+		prefixes = append(prefixes, PrefixCount{Prefix: prefix, Requests: requests})
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if prefixes[i].Requests != prefixes[j].Requests {
+			return prefixes[i].Requests > prefixes[j].Requests
+		}
+		return prefixes[i].Prefix < prefixes[j].Prefix
+	})
+	if len(prefixes) > n {
+		prefixes = prefixes[:n]
+	}
+	return prefixes
+}
+
+// WriteText writes the verdict, the counts and the offending prefixes, eg.
+// FAIL: 2.50% of 400 sampled request(s) resolve differently, threshold 1.00%
+func (r *CanaryReport) WriteText(w io.Writer) error {
+	verdict := "PASS"
+	if !r.Passed {
+		verdict = "FAIL"
+	}
+	fmt.Fprintf(w, "%s: %.2f%% of %d sampled request(s) resolve differently, threshold %.2f%%\n",
+		verdict, 100*r.Ratio, r.Sampled, 100*r.Threshold)
+	fmt.Fprintf(w, "%d line(s), %d invalid, %d offending, %d allowed, sample rate %g, seed %d\n",
+		r.Lines, r.Invalid, r.Offending, r.Allowed, r.SampleRate, r.Seed)
+	if len(r.Prefixes) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  PREFIX\tREQUESTS")
+	for _, p := range r.Prefixes {
+		fmt.Fprintf(tw, "  %s\t%d\n", p.Prefix, p.Requests)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report in indented json
+func (r *CanaryReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
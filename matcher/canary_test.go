@@ -0,0 +1,102 @@
+package matcher
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	canaryOld = `orders: PathSubtree("/api/orders") -> "https://orders.example.org";
+search: Path("/api/search") -> "https://search.example.org";
+health: Path("/health") -> <shunt>;`
+	canaryNew = `orders: PathSubtree("/api/orders") -> "https://orders-v2.example.org";
+search: Path("/api/search") -> setQuery("v", "2") -> "https://search.example.org";
+health: Path("/health") -> <shunt>;`
+)
+
+// canaryLog an access log of n requests to each path
+func canaryLog(n int, paths ...string) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		for _, p := range paths {
+			fmt.Fprintf(&b, "10.0.0.1 - - [13/Oct/2026:10:00:00 +0000] \"GET %s HTTP/1.1\" 200 12 \"-\" \"curl/7.68.0\" 3 api.example.org\n", p)
+		}
+	}
+	return b.String()
+}
+
+func TestCanaryCompare(t *testing.T) {
+	oldFile, newFile := writeRoutes(t, canaryOld), writeRoutes(t, canaryNew)
+	defer os.Remove(oldFile)
+	defer os.Remove(newFile)
+	oldOpts, newOpts := &Options{RoutesFile: oldFile}, &Options{RoutesFile: newFile}
+	log := canaryLog(2, "/api/orders/1", "/api/search", "/health", "/health") + "not a request\n"
+
+	report, err := CanaryCompare(oldOpts, newOpts, strings.NewReader(log), CanaryOptions{Seed: 42, Threshold: 0.3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), report.Seed)
+	assert.Equal(t, 9, report.Lines)
+	assert.Equal(t, 1, report.Invalid)
+	assert.Equal(t, 8, report.Sampled)
+	assert.Equal(t, 4, report.Offending)
+	assert.Equal(t, 0.5, report.Ratio)
+	assert.False(t, report.Passed)
+	assert.Equal(t, []PrefixCount{{Prefix: "/api/orders", Requests: 2}, {Prefix: "/api/search", Requests: 2}}, report.Prefixes)
+	require.Len(t, report.Changes, 4)
+	assert.Equal(t, ChangeDestination, report.Changes[0].Class)
+
+	// the orders changes are intended
+	report, err = CanaryCompare(oldOpts, newOpts, strings.NewReader(log), CanaryOptions{Seed: 42, Threshold: 0.3, AllowedRoutes: []string{"orders"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Allowed)
+	assert.Equal(t, 2, report.Offending)
+	assert.Equal(t, 0.25, report.Ratio)
+	assert.True(t, report.Passed)
+	assert.Equal(t, []PrefixCount{{Prefix: "/api/search", Requests: 2}}, report.Prefixes)
+
+	var b bytes.Buffer
+	require.NoError(t, report.WriteText(&b))
+	assert.Equal(t, `PASS: 25.00% of 8 sampled request(s) resolve differently, threshold 30.00%
+9 line(s), 1 invalid, 2 offending, 2 allowed, sample rate 1, seed 42
+  PREFIX       REQUESTS
+  /api/search  2
+`, b.String())
+}
+
+func TestCanaryCompareSampling(t *testing.T) {
+	oldFile, newFile := writeRoutes(t, canaryOld), writeRoutes(t, canaryNew)
+	defer os.Remove(oldFile)
+	defer os.Remove(newFile)
+	oldOpts, newOpts := &Options{RoutesFile: oldFile}, &Options{RoutesFile: newFile}
+	log := canaryLog(100, "/api/orders/1", "/health")
+
+	compare := func(seed int64) *CanaryReport {
+		report, err := CanaryCompare(oldOpts, newOpts, strings.NewReader(log), CanaryOptions{SampleRate: 0.25, Seed: seed})
+		require.NoError(t, err)
+		return report
+	}
+	report := compare(7)
+	assert.Equal(t, 200, report.Lines)
+	assert.True(t, report.Sampled > 0 && report.Sampled < 200, "%d sampled", report.Sampled)
+	assert.Equal(t, report, compare(7), "the same seed samples the same requests")
+	assert.False(t, report.Passed, "no difference passes without threshold")
+
+	// a seed is picked and reported
+	report, err := CanaryCompare(oldOpts, newOpts, strings.NewReader(log), CanaryOptions{SampleRate: 0.25})
+	require.NoError(t, err)
+	assert.NotZero(t, report.Seed)
+	again := compare(report.Seed)
+	assert.Equal(t, report.Sampled, again.Sampled)
+	assert.Equal(t, report.Offending, again.Offending)
+}
+
+func TestCanaryCompareLoadError(t *testing.T) {
+	_, err := CanaryCompare(&Options{RoutesFile: "testdata/missing.eskip"}, &Options{}, strings.NewReader(""), CanaryOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,110 @@
+package matcher
+
+import (
+	"runtime/debug"
+	"sort"
+)
+
+// skipperModule module path of the skipper library
+const skipperModule = "github.com/zalando/skipper"
+
+// routingPredicates predicates skipper handles without a predicate spec
+var routingPredicates = []string{
+	pathPredicate,
+	pathSubtreePredicate,
+	pathRegexpPredicate,
+	hostPredicate,
+	methodPredicate,
+	headerPredicate,
+	headerRegexpPredicate,
+}
+
+// Capabilities filters and predicates a matcher created with
+// the same options makes available to the routes, names sorted
+type Capabilities struct {
+	// SkipperVersion version of the skipper library compiled in, unknown if not available
+	SkipperVersion string `json:"skipperVersion" yaml:"skipperVersion"`
+	// Filters builtin skipper filters
+	Filters []string `json:"filters" yaml:"filters"`
+	// CustomFilters filters given by Options.CustomFilters
+	CustomFilters []string `json:"customFilters" yaml:"customFilters"`
+	// MockFilters filters mocked by Options.MockFilters
+	MockFilters []string `json:"mockFilters" yaml:"mockFilters"`
+	// Predicates predicates handled by skipper routing and the bundled ones
+	Predicates []string `json:"predicates" yaml:"predicates"`
+	// CustomPredicates predicates given by Options.CustomPredicates
+	CustomPredicates []string `json:"customPredicates" yaml:"customPredicates"`
+}
+
+// ListCapabilities returns the filters and predicates available to the
+// routes with the given options, taken from the same filter registry and
+// predicate specs a matcher uses
+func ListCapabilities(o *Options) *Capabilities {
+	c := &Capabilities{
+		SkipperVersion:   skipperVersion(),
+		Filters:          []string{},
+		CustomFilters:    []string{},
+		MockFilters:      []string{},
+		Predicates:       append([]string{}, routingPredicates...),
+		CustomPredicates: []string{},
+	}
+
+	custom := make(map[string]bool)
+	for _, f := range o.CustomFilters {
+		custom[f.Name()] = true
+	}
+	mocked := make(map[string]bool)
+	for _, name := range o.MockFilters {
+		mocked[name] = true
+	}
+	// custom filters are registered after the mocks, they win
+	registry, _ := filterRegistry(o)
+	for name := range registry {
+		switch {
+		case custom[name]:
+			c.CustomFilters = append(c.CustomFilters, name)
+		case mocked[name]:
+			c.MockFilters = append(c.MockFilters, name)
+		default:
+			c.Filters = append(c.Filters, name)
+		}
+	}
+
+	bundled := make(map[string]bool)
+	if !o.DisableBundledPredicates {
+		for _, spec := range bundledPredicates() {
+			bundled[spec.Name()] = true
+			c.Predicates = append(c.Predicates, spec.Name())
+		}
+	}
+	for name := range mapPredicateSpecs(o.CustomPredicates) {
+		if !bundled[name] {
+			c.CustomPredicates = append(c.CustomPredicates, name)
+		}
+	}
+
+	for _, names := range [][]string{c.Filters, c.CustomFilters, c.MockFilters, c.Predicates, c.CustomPredicates} {
+		sort.Strings(names)
+	}
+	return c
+}
+
+// skipperVersion returns the version of the skipper module in the build info
+func skipperVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, m := range info.Deps {
+		if m.Path != skipperModule {
+			continue
+		}
+		if m.Replace != nil {
+			m = m.Replace
+		}
+		if m.Version != "" {
+			return m.Version
+		}
+	}
+	return "unknown"
+}
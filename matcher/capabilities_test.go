@@ -0,0 +1,51 @@
+package matcher
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/predicates/source"
+	"github.com/zalando/skipper/routing"
+)
+
+func TestListCapabilities(t *testing.T) {
+	caps := ListCapabilities(&Options{
+		CustomFilters:    []filters.Spec{&filtertest.Filter{FilterName: "custom"}},
+		MockFilters:      []string{"oauthTokeninfoAnyScope"},
+		CustomPredicates: []routing.PredicateSpec{source.New()},
+	})
+
+	assert.NotEmpty(t, caps.SkipperVersion)
+	assert.Contains(t, caps.Filters, "setPath")
+	assert.NotContains(t, caps.Filters, "oauthTokeninfoAnyScope")
+	assert.Equal(t, []string{"custom"}, caps.CustomFilters)
+	assert.Equal(t, []string{"oauthTokeninfoAnyScope"}, caps.MockFilters)
+	assert.Contains(t, caps.Predicates, "Path")
+	assert.Contains(t, caps.Predicates, "Traffic")
+	assert.Empty(t, caps.CustomPredicates, "bundled predicates aren't custom")
+	assert.True(t, sort.StringsAreSorted(caps.Filters), "filters must be sorted")
+}
+
+func TestListCapabilitiesMatchesRouting(t *testing.T) {
+	// every listed filter can be used by the routes of a matcher
+	caps := ListCapabilities(&Options{})
+	registry, err := filterRegistry(&Options{})
+	require.NoError(t, err)
+	assert.Len(t, caps.Filters, len(registry))
+	for _, name := range caps.Filters {
+		assert.Contains(t, registry, name)
+	}
+}
+
+func TestListCapabilitiesBuiltinFilters(t *testing.T) {
+	caps := ListCapabilities(&Options{DisableBuiltinFilters: true, MockFilters: []string{"customfilter"}})
+	assert.Empty(t, caps.Filters)
+	assert.Equal(t, []string{"customfilter"}, caps.MockFilters)
+
+	caps = ListCapabilities(&Options{BuiltinFilterAllowlist: []string{"status", "setPath"}})
+	assert.Equal(t, []string{"setPath", "status"}, caps.Filters)
+}
@@ -0,0 +1,174 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NameCollisionsError error of New with Options.FailOnNameCollisions when
+// custom or mocked filters or predicates have the name of builtin, bundled
+// or other custom ones
+type NameCollisionsError struct {
+	// Findings the name-collision findings
+	Findings []Finding
+}
+
+func (e *NameCollisionsError) Error() string {
+	msgs := make([]string, len(e.Findings))
+	for i, f := range e.Findings {
+		msgs[i] = f.Message
+	}
+	return fmt.Sprintf("name collisions: %s", strings.Join(msgs, "; "))
+}
+
+// ErrFilterOverride filters replace builtin or mocked ones, see
+// FilterOverrideError
+var ErrFilterOverride = errors.New("filter override")
+
+// FilterOverrideError error of New when custom or mocked filters have the
+// name of builtin ones, or custom filters the name of mocked ones, without
+// Options.AllowFilterOverride
+type FilterOverrideError struct {
+	// Overrides the overrides sorted by filter name, eg. custom filter
+	// setPath replaces the builtin one
+	Overrides []string
+}
+
+func (e *FilterOverrideError) Error() string {
+	return fmt.Sprintf("filter overrides: %s, allow them with AllowFilterOverride", strings.Join(e.Overrides, "; "))
+}
+
+// Is matches ErrFilterOverride
+func (e *FilterOverrideError) Is(target error) bool { return target == ErrFilterOverride }
+
+// filterOverrideError the overrides of the builtin and mocked filters, nil
+// without. The auto-mocked filters are the ones missing in the registry, the
+// custom filters included, they replace none.
+func filterOverrideError(o *Options) error {
+	overrides := filterOverrides(o)
+	if len(overrides) == 0 {
+		return nil
+	}
+	e := &FilterOverrideError{}
+	for _, name := range sortedKeys(overrides) {
+		e.Overrides = append(e.Overrides, overrides[name])
+	}
+	return e
+}
+
+// filterOverrides what the custom and mocked filters replace by filter
+// name, following the precedence of the registry: the custom filters win
+// over the mocked ones, which win over the builtin ones
+func filterOverrides(o *Options) map[string]string {
+	overrides := make(map[string]string)
+	if len(o.MockFilters) == 0 && len(o.CustomFilters) == 0 {
+		return overrides
+	}
+	// an invalid allowlist is reported by New
+	builtins, _ := enabledBuiltinFilters(o)
+	mocked := make(map[string]bool, len(o.MockFilters))
+	for _, name := range o.MockFilters {
+		mocked[name] = true
+	}
+	custom := make(map[string]bool, len(o.CustomFilters))
+	for _, spec := range o.CustomFilters {
+		custom[spec.Name()] = true
+	}
+	for name := range custom {
+		_, isBuiltin := builtins[name]
+		switch {
+		case mocked[name]:
+			overrides[name] = fmt.Sprintf("custom filter %s replaces the mocked one", name)
+		case isBuiltin:
+			overrides[name] = fmt.Sprintf("custom filter %s replaces the builtin one", name)
+		}
+	}
+	for name := range mocked {
+		if _, isBuiltin := builtins[name]; isBuiltin && !custom[name] {
+			overrides[name] = fmt.Sprintf("mocked filter %s replaces the builtin one", name)
+		}
+	}
+	return overrides
+}
+
+// nameCollisions reports the custom and mocked predicates and filters
+// colliding with the routing, bundled, builtin or other custom ones, with the
+// precedence deciding which one is used (rule name-collision): the custom
+// filters win over the mocked ones, which win over the builtin ones, the
+// custom predicates over the bundled ones, and among custom ones the last.
+// The options must have the plugins loaded.
+func nameCollisions(o *Options) []Finding {
+	if o.Rules.ignored(RuleNameCollision) {
+		return nil
+	}
+	var msgs []string
+
+	routingNames := make(map[string]bool, len(routingPredicates))
+	for _, name := range routingPredicates {
+		routingNames[name] = true
+	}
+	bundled := make(map[string]bool)
+	if !o.DisableBundledPredicates {
+		for _, spec := range bundledPredicates() {
+			bundled[spec.Name()] = true
+		}
+	}
+	predicates := make(map[string]int)
+	for _, spec := range o.CustomPredicates {
+		predicates[spec.Name()]++
+	}
+	for _, name := range sortedCounts(predicates) {
+		switch {
+		case routingNames[name]:
+			msgs = append(msgs, fmt.Sprintf("custom predicate %s is never used, the routing handles %s itself", name, name))
+		case bundled[name]:
+			msgs = append(msgs, fmt.Sprintf("custom predicate %s replaces the bundled one", name))
+		}
+		if n := predicates[name]; n > 1 {
+			msgs = append(msgs, fmt.Sprintf("custom predicate %s is registered %d times, the last one wins", name, n))
+		}
+	}
+
+	overrides := filterOverrides(o)
+	mocked := make(map[string]bool, len(o.MockFilters))
+	for _, name := range o.MockFilters {
+		mocked[name] = true
+	}
+	custom := make(map[string]int, len(o.CustomFilters))
+	for _, spec := range o.CustomFilters {
+		custom[spec.Name()]++
+	}
+	names := make(map[string]int, len(mocked)+len(custom))
+	for name := range mocked {
+		names[name]++
+	}
+	for name := range custom {
+		names[name]++
+	}
+	for _, name := range sortedCounts(names) {
+		if msg, ok := overrides[name]; ok {
+			msgs = append(msgs, msg)
+		}
+		if n := custom[name]; n > 1 {
+			msgs = append(msgs, fmt.Sprintf("custom filter %s is registered %d times, the last one wins", name, n))
+		}
+	}
+
+	findings := make([]Finding, 0, len(msgs))
+	for _, msg := range msgs {
+		findings = append(findings, o.Rules.apply(Finding{RuleID: RuleNameCollision, Severity: SeverityWarning, Message: msg}))
+	}
+	return findings
+}
+
+// sortedCounts the sorted keys of the counts
+func sortedCounts(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
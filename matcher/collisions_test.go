@@ -0,0 +1,150 @@
+package matcher
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/routing"
+)
+
+func TestNameCollisions(t *testing.T) {
+	file := writeRoutes(t, `orders: Path("/orders") && Cookie("session", "^.+$") -> "http://orders.svc";
+`)
+	defer os.Remove(file)
+
+	tenant := PredicateFunc("TenantIs", 1, func([]interface{}, *http.Request) bool { return true })
+	tests := []struct {
+		name    string
+		options Options
+		want    []string
+	}{
+		{"none", Options{CustomPredicates: []routing.PredicateSpec{tenant}, MockFilters: []string{"tenantAuth"}}, nil},
+		{"custom predicate and bundled one", Options{CustomPredicates: []routing.PredicateSpec{MockPredicate("Cookie", true)}}, []string{
+			"custom predicate Cookie replaces the bundled one",
+		}},
+		{"bundled predicates disabled", Options{CustomPredicates: []routing.PredicateSpec{MockPredicate("Cookie", true)}, DisableBundledPredicates: true}, nil},
+		{"custom predicate and routing one", Options{CustomPredicates: []routing.PredicateSpec{MockPredicate("Method", true)}}, []string{
+			"custom predicate Method is never used, the routing handles Method itself",
+		}},
+		{"custom predicate and mocked one", Options{CustomPredicates: append(MockPredicates([]string{"TenantIs"}), tenant)}, []string{
+			"custom predicate TenantIs is registered 2 times, the last one wins",
+		}},
+		{"mocked filter and builtin one", Options{MockFilters: []string{"setPath"}}, []string{
+			"mocked filter setPath replaces the builtin one",
+		}},
+		{"builtin filters disabled", Options{MockFilters: []string{"setPath"}, DisableBuiltinFilters: true}, nil},
+		{"custom filter and builtin one", Options{CustomFilters: []filters.Spec{MockFilter("setPath", nil)}}, []string{
+			"custom filter setPath replaces the builtin one",
+		}},
+		{"custom filter and mocked one", Options{CustomFilters: []filters.Spec{MockFilter("tenantAuth", nil)}, MockFilters: []string{"tenantAuth"}}, []string{
+			"custom filter tenantAuth replaces the mocked one",
+		}},
+		{"custom filters", Options{CustomFilters: []filters.Spec{MockFilter("tenantAuth", nil), MockFilter("tenantAuth", arity(1))}}, []string{
+			"custom filter tenantAuth is registered 2 times, the last one wins",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := tt.options
+			o.RoutesFile = file
+			o.AllowFilterOverride = true
+			m, err := New(&o)
+			require.NoError(t, err)
+			defer m.Close()
+			var got []string
+			for _, finding := range m.Findings() {
+				if finding.RuleID == RuleNameCollision {
+					assert.Equal(t, SeverityWarning, finding.Severity)
+					got = append(got, finding.Message)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+
+			findings, err := Validate(&o)
+			require.NoError(t, err)
+			got = nil
+			for _, finding := range findings {
+				if finding.RuleID == RuleNameCollision {
+					got = append(got, finding.Message)
+				}
+			}
+			assert.Equal(t, tt.want, got, "Validate reports them too")
+
+			o.FailOnNameCollisions = true
+			_, err = New(&o)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.IsType(t, &NameCollisionsError{}, err) {
+				assert.Len(t, err.(*NameCollisionsError).Findings, len(tt.want))
+				assert.Contains(t, err.Error(), tt.want[0])
+			}
+		})
+	}
+
+	m, err := New(&Options{RoutesFile: file, MockFilters: []string{"setPath"}, AllowFilterOverride: true, Rules: RuleOptions{Ignore: []string{RuleNameCollision}}})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Empty(t, m.Findings(), "the rule can be ignored")
+}
+
+func TestFilterOverride(t *testing.T) {
+	file := writeRoutes(t, `orders: Path("/orders") -> setPath("/v2/orders") -> tenantAuth() -> <shunt>;
+`)
+	defer os.Remove(file)
+
+	tests := []struct {
+		name    string
+		options Options
+		want    []string
+	}{
+		{"mocked filter and builtin one", Options{MockFilters: []string{"setPath", "tenantAuth"}}, []string{
+			"mocked filter setPath replaces the builtin one",
+		}},
+		{"custom filter and builtin one", Options{CustomFilters: []filters.Spec{MockFilter("setPath", nil)}, MockFilters: []string{"tenantAuth"}}, []string{
+			"custom filter setPath replaces the builtin one",
+		}},
+		{"custom filter and mocked one", Options{CustomFilters: []filters.Spec{MockFilter("tenantAuth", nil)}, MockFilters: []string{"tenantAuth"}}, []string{
+			"custom filter tenantAuth replaces the mocked one",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := tt.options
+			o.RoutesFile = file
+			_, err := New(&o)
+			assert.True(t, errors.Is(err, ErrFilterOverride))
+			if assert.IsType(t, &FilterOverrideError{}, err) {
+				assert.Equal(t, tt.want, err.(*FilterOverrideError).Overrides)
+			}
+		})
+	}
+
+	t.Run("auto-mocked filters", func(t *testing.T) {
+		m, err := New(&Options{RoutesFile: file, CustomFilters: []filters.Spec{MockFilter("tenantAuth", nil)}, AutoMockFilters: true})
+		require.NoError(t, err, "the custom filters aren't auto-mocked")
+		m.Close()
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		var args []interface{}
+		custom := MockFilter("setPath", func(a []interface{}) error {
+			args = a
+			return nil
+		})
+		m, err := New(&Options{RoutesFile: file, CustomFilters: []filters.Spec{custom}, MockFilters: []string{"setPath", "tenantAuth"}, AllowFilterOverride: true})
+		require.NoError(t, err)
+		defer m.Close()
+		assert.Equal(t, []interface{}{"/v2/orders"}, args, "the custom filter wins")
+		res := m.Test(&RequestAttributes{Path: "/orders"})
+		if assert.NotNil(t, res.Route()) {
+			assert.Equal(t, "orders", res.Route().Id)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package matcher
+
+import (
+	"runtime"
+	"sync"
+)
+
+// ChangeError class of the results of which one failed the test, see
+// ResultsEquivalent
+const ChangeError = "error"
+
+// Disagreement a request the matchers of Matcher.Compare route differently
+type Disagreement struct {
+	// Request the normalized request
+	Request *RequestAttributes
+	// Class what differs, see ResultsEquivalent
+	Class string
+	// Result the result of the matcher Compare was called on
+	Result TestResult
+	// Other the result of the other matcher
+	Other TestResult
+}
+
+// ResultsEquivalent compares two results of the same request: they are
+// equivalent when they route to the same backend with the same filter
+// chain. The class tells what differs, empty when nothing does:
+// ChangeRenamed for equivalent results of routes with another id,
+// ChangeFilters, ChangeDestination, or ChangeError when a result has an
+// error, eg. ErrClosed.
+func ResultsEquivalent(a, b TestResult) (bool, string) {
+	if a.Err() != nil || b.Err() != nil {
+		return false, ChangeError
+	}
+	class := changeClass(NewResultData(a), NewResultData(b))
+	return class == "" || class == ChangeRenamed, class
+}
+
+// compareMatchers tests every request against both matchers, the pairs in
+// parallel, and returns the disagreements in the requests order
+func compareMatchers(m, other Matcher, reqs []*RequestAttributes) []Disagreement {
+	found := make([]*Disagreement, len(reqs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(reqs) {
+		workers = len(reqs)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				a, b := m.Test(reqs[i]), other.Test(reqs[i])
+				if _, class := ResultsEquivalent(a, b); class != "" {
+					found[i] = &Disagreement{Request: a.Attributes(), Class: class, Result: a, Other: b}
+				}
+			}
+		}()
+	}
+	for i := range reqs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	disagreements := []Disagreement{}
+	for _, d := range found {
+		if d != nil {
+			disagreements = append(disagreements, *d)
+		}
+	}
+	return disagreements
+}
+
+func (f *matcher) Compare(other Matcher, reqs []*RequestAttributes) []Disagreement {
+	return compareMatchers(f, other, reqs)
+}
+
+func (l *lazyMatcher) Compare(other Matcher, reqs []*RequestAttributes) []Disagreement {
+	return compareMatchers(l, other, reqs)
+}
+
+func (c *cachedMatcher) Compare(other Matcher, reqs []*RequestAttributes) []Disagreement {
+	return compareMatchers(c, other, reqs)
+}
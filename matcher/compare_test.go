@@ -0,0 +1,114 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	old := newTestMatcher(t, diffReportOld)
+	defer old.Close()
+	new := newTestMatcher(t, diffReportNew)
+	defer new.Close()
+
+	var reqs []*RequestAttributes
+	for i := 0; i < 50; i++ {
+		for _, path := range []string{"/orders", "/search", "/legacy", "/health", "/catalog"} {
+			reqs = append(reqs, &RequestAttributes{Path: path, Query: map[string]string{"i": fmt.Sprint(i)}})
+		}
+	}
+	disagreements := old.Compare(new, reqs)
+	require.Len(t, disagreements, 200)
+	// in the requests order
+	for i, d := range disagreements {
+		assert.Equal(t, fmt.Sprint(i/4), d.Request.Query["i"])
+		assert.Equal(t, []string{ChangeRenamed, ChangeFilters, ChangeDestination, ChangeDestination}[i%4], d.Class, d.Request.Path)
+	}
+	d := disagreements[0]
+	assert.Equal(t, "orders", d.Result.Route().Id)
+	assert.Equal(t, "orders_v1", d.Other.Route().Id)
+	assert.Nil(t, disagreements[3].Result.Route())
+	assert.Equal(t, "catalog", disagreements[3].Other.Route().Id)
+
+	assert.Empty(t, old.Compare(old, reqs))
+	assert.Empty(t, old.Compare(new, nil))
+}
+
+func TestCompareErrors(t *testing.T) {
+	m := newTestMatcher(t, diffReportOld)
+	defer m.Close()
+	closed := newTestMatcher(t, diffReportOld)
+	closed.Close()
+
+	disagreements := m.Compare(closed, []*RequestAttributes{{Path: "/orders"}, {Path: "/missing"}})
+	require.Len(t, disagreements, 2)
+	for _, d := range disagreements {
+		assert.Equal(t, ChangeError, d.Class)
+		assert.NoError(t, d.Result.Err())
+		assert.True(t, errors.Is(d.Other.Err(), ErrClosed))
+	}
+	assert.Equal(t, "orders", disagreements[0].Result.Route().Id)
+}
+
+func TestCompareSourceKinds(t *testing.T) {
+	eskipFile, err := New(&Options{RoutesFile: "testdata/kubernetes/shop-drift.eskip"})
+	require.NoError(t, err)
+	defer eskipFile.Close()
+	lazy, err := New(&Options{RoutesFile: "testdata/kubernetes/shop.eskip", Lazy: true})
+	require.NoError(t, err)
+	defer lazy.Close()
+	ingresses, err := New(&Options{Kubernetes: &KubernetesOptions{Manifests: shopManifests}})
+	require.NoError(t, err)
+	defer ingresses.Close()
+
+	reqs := []*RequestAttributes{
+		{Host: "shop.example.org", Path: "/orders/1"},
+		{Host: "shop.example.org", Path: "/search"},
+	}
+	// the generated ids of the ingress routes, the endpoints in another order
+	disagreements := lazy.Compare(ingresses, reqs)
+	require.Len(t, disagreements, 2)
+	for _, d := range disagreements {
+		assert.Equal(t, ChangeRenamed, d.Class)
+		equivalent, _ := ResultsEquivalent(d.Result, d.Other)
+		assert.True(t, equivalent)
+	}
+
+	disagreements = ingresses.Compare(eskipFile, reqs)
+	require.Len(t, disagreements, 2)
+	assert.Equal(t, ChangeDestination, disagreements[0].Class)
+	assert.Equal(t, "orders", disagreements[0].Other.Route().Id)
+	assert.Equal(t, ChangeRenamed, disagreements[1].Class)
+}
+
+func TestResultsEquivalent(t *testing.T) {
+	old := newTestMatcher(t, diffReportOld)
+	defer old.Close()
+	new := newTestMatcher(t, diffReportNew)
+	defer new.Close()
+
+	tests := []struct {
+		path       string
+		equivalent bool
+		class      string
+	}{
+		{path: "/health", equivalent: true},
+		{path: "/missing", equivalent: true},
+		{path: "/orders", equivalent: true, class: ChangeRenamed},
+		{path: "/search", class: ChangeFilters},
+		{path: "/legacy", class: ChangeDestination},
+		{path: "/catalog", class: ChangeDestination},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := &RequestAttributes{Path: tt.path}
+			equivalent, class := ResultsEquivalent(old.Test(req), new.Test(req))
+			assert.Equal(t, tt.equivalent, equivalent)
+			assert.Equal(t, tt.class, class)
+		})
+	}
+}
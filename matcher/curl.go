@@ -0,0 +1,474 @@
+package matcher
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// curlFlag how a curl flag is translated to request attributes
+type curlFlag struct {
+	// value true if the flag takes a value
+	value bool
+	// apply translates the flag, nil for the flags ignored with a warning
+	apply func(c *curlRequest, value string) error
+}
+
+// curlRequest request being built while parsing curl arguments
+type curlRequest struct {
+	attrs    *RequestAttributes
+	url      string
+	data     []string
+	get      bool
+	cookies  []string
+	warnings []string
+}
+
+var curlFlags = map[string]curlFlag{
+	"-X":               {true, setCurlMethod},
+	"--request":        {true, setCurlMethod},
+	"-H":               {true, addCurlHeader},
+	"--header":         {true, addCurlHeader},
+	"-A":               {true, setCurlHeader("User-Agent")},
+	"--user-agent":     {true, setCurlHeader("User-Agent")},
+	"-e":               {true, setCurlHeader("Referer")},
+	"--referer":        {true, setCurlHeader("Referer")},
+	"-u":               {true, setCurlUser},
+	"--user":           {true, setCurlUser},
+	"-b":               {true, addCurlCookie},
+	"--cookie":         {true, addCurlCookie},
+	"-d":               {true, setCurlData},
+	"--data":           {true, setCurlData},
+	"--data-raw":       {true, setCurlData},
+	"--data-binary":    {true, setCurlData},
+	"--data-ascii":     {true, setCurlData},
+	"--data-urlencode": {true, setCurlData},
+	"--json":           {true, setCurlJSON},
+	"-G":               {false, setCurlGet},
+	"--get":            {false, setCurlGet},
+	"-I":               {false, setCurlHead},
+	"--head":           {false, setCurlHead},
+	"--url":            {true, setCurlURL},
+
+	// flags not affecting routing, ignored with a warning
+	"-s": {}, "--silent": {}, "-S": {}, "--show-error": {}, "-v": {}, "--verbose": {},
+	"-k": {}, "--insecure": {}, "-L": {}, "--location": {}, "-i": {}, "--include": {},
+	"--compressed": {}, "-f": {}, "--fail": {}, "-#": {}, "--progress-bar": {},
+	"-o": {value: true}, "--output": {value: true}, "-w": {value: true}, "--write-out": {value: true},
+	"-m": {value: true}, "--max-time": {value: true}, "--connect-timeout": {value: true},
+	"--retry": {value: true}, "-x": {value: true}, "--proxy": {value: true},
+	"--cacert": {value: true}, "-E": {value: true}, "--cert": {value: true}, "--key": {value: true},
+	"-F": {value: true}, "--form": {value: true}, "--resolve": {value: true},
+	"-c": {value: true}, "--cookie-jar": {value: true},
+}
+
+func setCurlMethod(c *curlRequest, v string) error {
+	c.attrs.Method = strings.ToUpper(v)
+	return nil
+}
+
+func addCurlHeader(c *curlRequest, v string) error {
+	sep := strings.Index(v, ":")
+	if sep <= 0 {
+		return fmt.Errorf("invalid header %q", v)
+	}
+	name, value := strings.TrimSpace(v[:sep]), strings.TrimSpace(v[sep+1:])
+	if strings.EqualFold(name, "Host") {
+		c.attrs.Host = value
+		return nil
+	}
+	c.attrs.Headers[name] = value
+	return nil
+}
+
+// setCurlHeader returns a flag setting the header name
+func setCurlHeader(name string) func(c *curlRequest, v string) error {
+	return func(c *curlRequest, v string) error {
+		return addCurlHeader(c, name+": "+v)
+	}
+}
+
+func setCurlUser(c *curlRequest, v string) error {
+	c.attrs.Headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(v))
+	return nil
+}
+
+func addCurlCookie(c *curlRequest, v string) error {
+	if !strings.Contains(v, "=") {
+		c.warnings = append(c.warnings, fmt.Sprintf("cookie file %s ignored", v))
+		return nil
+	}
+	c.cookies = append(c.cookies, v)
+	return nil
+}
+
+func setCurlData(c *curlRequest, v string) error {
+	c.data = append(c.data, v)
+	return nil
+}
+
+// setCurlJSON like curl --json sends json data, with json content type and accept headers
+func setCurlJSON(c *curlRequest, v string) error {
+	c.data = append(c.data, v)
+	for _, name := range []string{"Content-Type", "Accept"} {
+		if _, ok := c.attrs.Headers[name]; !ok {
+			c.attrs.Headers[name] = "application/json"
+		}
+	}
+	return nil
+}
+
+func setCurlGet(c *curlRequest, v string) error {
+	c.get = true
+	return nil
+}
+
+func setCurlHead(c *curlRequest, v string) error {
+	return setCurlMethod(c, "HEAD")
+}
+
+func setCurlURL(c *curlRequest, v string) error {
+	if c.url != "" {
+		c.warnings = append(c.warnings, fmt.Sprintf("only the first url is used, %s ignored", v))
+		return nil
+	}
+	c.url = v
+	return nil
+}
+
+// ParseCurl parses a curl command line (eg. copied from a runbook or from
+// the browser developer tools "Copy as cURL") and translates it to request
+// attributes, see ParseCurlArgs. Arguments are split like a POSIX shell does
+// with single and double quotes, backslash escapes and line continuations.
+func ParseCurl(command string) (*RequestAttributes, []string, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+	return ParseCurlArgs(args)
+}
+
+// ParseCurlArgs translates curl arguments to request attributes. Method, url,
+// headers, user, cookies and data flags are supported: sending data makes the
+// default method POST, unless -G is used to send it as query parameters.
+// Flags not affecting routing are ignored and returned as warnings together
+// with the unknown ones.
+func ParseCurlArgs(args []string) (*RequestAttributes, []string, error) {
+	c := &curlRequest{
+		attrs: &RequestAttributes{
+			Query:   map[string]string{},
+			Headers: map[string]string{},
+		},
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			setCurlURL(c, arg)
+			continue
+		}
+
+		name, value, hasValue := splitCurlFlag(arg)
+		flag, ok := curlFlags[name]
+		if !ok && !hasValue && !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			// combined short flags, eg. -sSL
+			if err := c.applyCombined(arg); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if !ok {
+			c.warnings = append(c.warnings, fmt.Sprintf("unsupported curl flag %s ignored", name))
+			continue
+		}
+
+		if flag.value && !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("curl flag %s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if flag.apply == nil {
+			c.warnings = append(c.warnings, fmt.Sprintf("curl flag %s ignored", name))
+			continue
+		}
+		if err := flag.apply(c, value); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := c.finish(); err != nil {
+		return nil, nil, err
+	}
+	return c.attrs, c.warnings, nil
+}
+
+// splitCurlFlag splits --name=value and -Xvalue flags
+func splitCurlFlag(arg string) (name string, value string, hasValue bool) {
+	if strings.HasPrefix(arg, "--") {
+		if i := strings.Index(arg, "="); i > 0 {
+			return arg[:i], arg[i+1:], true
+		}
+		return arg, "", false
+	}
+	if len(arg) > 2 {
+		if flag, ok := curlFlags[arg[:2]]; ok && flag.value {
+			return arg[:2], arg[2:], true
+		}
+	}
+	return arg, "", false
+}
+
+// applyCombined applies combined short flags without values, eg. -sSL
+func (c *curlRequest) applyCombined(arg string) error {
+	for _, r := range arg[1:] {
+		name := "-" + string(r)
+		flag, ok := curlFlags[name]
+		switch {
+		case !ok:
+			c.warnings = append(c.warnings, fmt.Sprintf("unsupported curl flag %s ignored", name))
+		case flag.value:
+			return fmt.Errorf("curl flag %s in %s needs a value", name, arg)
+		case flag.apply == nil:
+			c.warnings = append(c.warnings, fmt.Sprintf("curl flag %s ignored", name))
+		default:
+			if err := flag.apply(c, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// finish translates the url and applies the defaults curl uses
+func (c *curlRequest) finish() error {
+	if c.url == "" {
+		return fmt.Errorf("missing url")
+	}
+	raw := c.url
+	if !strings.Contains(raw, "://") {
+		// like curl, urls without scheme are http
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %v", c.url, err)
+	}
+
+	if c.attrs.Host == "" {
+		c.attrs.Host = u.Host
+	}
+	c.attrs.Path = u.EscapedPath()
+	if c.attrs.Path == "" {
+		c.attrs.Path = "/"
+	}
+	for key, values := range u.Query() {
+		c.attrs.Query[key] = values[0]
+	}
+
+	if len(c.cookies) > 0 {
+		c.attrs.Headers["Cookie"] = strings.Join(c.cookies, "; ")
+	}
+	if c.get {
+		// like curl, -G sends the data as query parameters
+		for _, d := range c.data {
+			q, err := url.ParseQuery(d)
+			if err != nil {
+				return fmt.Errorf("invalid query data %q: %v", d, err)
+			}
+			for key, values := range q {
+				c.attrs.Query[key] = values[0]
+			}
+		}
+	}
+	if c.attrs.Method == "" {
+		c.attrs.Method = "GET"
+		if len(c.data) > 0 && !c.get {
+			c.attrs.Method = "POST"
+		}
+	}
+	return nil
+}
+
+// CurlCommand returns a curl command line sending a request with the
+// attributes, it can be parsed back with ParseCurl
+func CurlCommand(attributes *RequestAttributes) string {
+	req, err := createHTTPRequest(copyAttributes(attributes))
+	if err != nil {
+		return fmt.Sprintf("invalid request: %v", err)
+	}
+
+	args := []string{"curl"}
+	if req.Method != "GET" {
+		args = append(args, "-X", req.Method)
+	}
+	for _, name := range sortedKeys(attributes.Headers) {
+		args = append(args, "-H", shellQuote(name+": "+attributes.Headers[name]))
+	}
+	host := req.Host
+	if host == "" {
+		host = "localhost"
+	}
+	args = append(args, shellQuote("http://"+host+req.URL.RequestURI()))
+	return strings.Join(args, " ")
+}
+
+// AsCurl returns a curl command line sending the generated request to
+// baseURL, eg. https://staging.example.org: the method, the host of the
+// request as the Host header, the other headers, the cookies and the query
+func (c GeneratedCase) AsCurl(baseURL string) string {
+	req, err := createHTTPRequest(copyAttributes(c.Request))
+	if err != nil {
+		return fmt.Sprintf("invalid request: %v", err)
+	}
+
+	args := []string{"curl"}
+	if req.Method != "GET" {
+		args = append(args, "-X", req.Method)
+	}
+	headers := make(map[string]string, len(c.Request.Headers)+1)
+	for name, value := range c.Request.Headers {
+		headers[name] = value
+	}
+	if c.Request.Host != "" {
+		headers["Host"] = c.Request.Host
+	}
+	cookies := headers["Cookie"]
+	delete(headers, "Cookie")
+	for _, name := range sortedKeys(headers) {
+		args = append(args, "-H", shellQuote(name+": "+headers[name]))
+	}
+	if cookies != "" {
+		args = append(args, "-b", shellQuote(cookies))
+	}
+	args = append(args, shellQuote(strings.TrimSuffix(baseURL, "/")+req.URL.RequestURI()))
+	return strings.Join(args, " ")
+}
+
+// ExportCurl loads the routes file and writes a curl command sending the
+// request generated for each route to baseURL, see GeneratedCase.AsCurl,
+// after a comment with the route id. The routes whose request needs manual
+// attention, with predicates it can't satisfy or not matching the route,
+// are skipped and listed at the end. The Traffic predicates always match
+// with TrafficRandom. Options.Lazy is ignored.
+func ExportCurl(o *Options, baseURL string, w io.Writer) error {
+	if o.TrafficMode == TrafficRandom {
+		c := *o
+		c.TrafficMode = TrafficAlways
+		o = &c
+	}
+	cases, err := GenerateAll(o)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	var skipped []string
+	for _, c := range cases {
+		switch {
+		case len(c.Unsatisfied) > 0:
+			for _, u := range c.Unsatisfied {
+				skipped = append(skipped, fmt.Sprintf("%s: %s can't be satisfied automatically: %s", c.RouteID, u.Predicate, u.Reason))
+			}
+		case c.Matched != "":
+			skipped = append(skipped, fmt.Sprintf("%s: the generated request matches route %s instead", c.RouteID, c.Matched))
+		case !c.Reachable:
+			skipped = append(skipped, fmt.Sprintf("%s: the generated request doesn't match it", c.RouteID))
+		default:
+			fmt.Fprintf(&b, "# %s\n%s\n", c.RouteID, c.AsCurl(baseURL))
+		}
+	}
+	if len(skipped) > 0 {
+		b.WriteString("\n# skipped, the generated requests need manual attention:\n")
+		for _, s := range skipped {
+			fmt.Fprintf(&b, "# %s\n", s)
+		}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// shellQuote quotes s for a POSIX shell when needed
+func shellQuote(s string) string {
+	safe := s != ""
+	for _, r := range s {
+		if !strings.ContainsRune("-_./:=@%+,", r) && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// splitShellWords splits a command line in arguments like a POSIX shell
+func splitShellWords(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+			if r == '\n' {
+				// line continuation
+				continue
+			}
+			if quote == '"' && !strings.ContainsRune("$`\"\\", r) {
+				// in double quotes only a few characters can be escaped
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			inWord = true
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,238 @@
+package matcher
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCurl(t *testing.T) {
+	tests := []struct {
+		command  string
+		want     *RequestAttributes
+		warnings []string
+		err      bool
+	}{
+		{
+			command: `curl https://api.example.org/v1/orders`,
+			want:    &RequestAttributes{Method: "GET", Path: "/v1/orders", Host: "api.example.org"},
+		},
+		{
+			command: `curl -X POST -H 'Content-Type: application/json' -d '{"a":1}' 'https://api.example.org/v1/orders'`,
+			want: &RequestAttributes{
+				Method:  "POST",
+				Path:    "/v1/orders",
+				Host:    "api.example.org",
+				Headers: map[string]string{"Content-Type": "application/json"},
+			},
+		},
+		{
+			command: `curl --data-raw 'q=1' api.example.org:8080/search?q=shoes&page=2`,
+			want: &RequestAttributes{
+				Method: "POST",
+				Path:   "/search",
+				Host:   "api.example.org:8080",
+				Query:  map[string]string{"q": "shoes", "page": "2"},
+			},
+		},
+		{
+			command: `curl -G -d q=shoes --data page=2 "http://localhost:9090/search"`,
+			want: &RequestAttributes{
+				Method: "GET",
+				Path:   "/search",
+				Host:   "localhost:9090",
+				Query:  map[string]string{"q": "shoes", "page": "2"},
+			},
+		},
+		{
+			command: "curl -sSL -XDELETE \\\n  --header=\"X-Tenant: acme\" \\\n  --url https://api.example.org/v1/orders/1",
+			want: &RequestAttributes{
+				Method:  "DELETE",
+				Path:    "/v1/orders/1",
+				Host:    "api.example.org",
+				Headers: map[string]string{"X-Tenant": "acme"},
+			},
+			warnings: []string{"curl flag -s ignored", "curl flag -S ignored", "curl flag -L ignored"},
+		},
+		{
+			command: `curl -b 'session=abc' --cookie "lang=en" -u admin:secret -A test-agent https://api.example.org/`,
+			want: &RequestAttributes{
+				Method: "GET",
+				Path:   "/",
+				Host:   "api.example.org",
+				Headers: map[string]string{
+					"Cookie":        "session=abc; lang=en",
+					"Authorization": "Basic YWRtaW46c2VjcmV0",
+					"User-Agent":    "test-agent",
+				},
+			},
+		},
+		{
+			command: `curl -I -H 'Host: internal.example.org' http://10.0.0.1/health`,
+			want:    &RequestAttributes{Method: "HEAD", Path: "/health", Host: "internal.example.org"},
+		},
+		{
+			command: `curl 'https://api.example.org/v1/orders' -H 'accept: application/json' --compressed -o out.json --retry 3 --http2`,
+			want: &RequestAttributes{
+				Method:  "GET",
+				Path:    "/v1/orders",
+				Host:    "api.example.org",
+				Headers: map[string]string{"accept": "application/json"},
+			},
+			warnings: []string{
+				"curl flag --compressed ignored",
+				"curl flag -o ignored",
+				"curl flag --retry ignored",
+				"unsupported curl flag --http2 ignored",
+			},
+		},
+		{
+			command: `curl --json '{"a":1}' https://api.example.org/v1/orders https://api.example.org/other -b cookies.txt`,
+			want: &RequestAttributes{
+				Method:  "POST",
+				Path:    "/v1/orders",
+				Host:    "api.example.org",
+				Headers: map[string]string{"Content-Type": "application/json", "Accept": "application/json"},
+			},
+			warnings: []string{"only the first url is used, https://api.example.org/other ignored", "cookie file cookies.txt ignored"},
+		},
+		{
+			command: `curl -X POST`,
+			err:     true,
+		},
+		{
+			command: `curl https://api.example.org -H`,
+			err:     true,
+		},
+		{
+			command: `curl 'https://api.example.org`,
+			err:     true,
+		},
+		{
+			command: `curl https://api.example.org -H 'invalid'`,
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			attrs, warnings, err := ParseCurl(tt.command)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			if tt.want.Query == nil {
+				tt.want.Query = map[string]string{}
+			}
+			if tt.want.Headers == nil {
+				tt.want.Headers = map[string]string{}
+			}
+			assert.Equal(t, tt.want, attrs)
+			assert.Equal(t, tt.warnings, warnings)
+		})
+	}
+}
+
+func TestSplitShellWords(t *testing.T) {
+	words, err := splitShellWords(`a 'b c' "d \"e\" \n" f\ g 'h'"i"` + "\t\\\nj")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b c", `d "e" \n`, "f g", "hi", "j"}, words)
+
+	_, err = splitShellWords(`a "b`)
+	assert.Error(t, err)
+}
+
+func TestCurlCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs *RequestAttributes
+		want  string
+	}{
+		{
+			name:  "get",
+			attrs: &RequestAttributes{Path: "/orders", Host: "localhost"},
+			want:  "curl http://localhost/orders",
+		},
+		{
+			name: "method headers and query",
+			attrs: &RequestAttributes{
+				Method:  "POST",
+				Path:    "/search",
+				Host:    "api.example.org",
+				Query:   map[string]string{"q": "it's"},
+				Headers: map[string]string{"X-Tenant": "acme", "Accept": "application/json"},
+			},
+			want: `curl -X POST -H 'Accept: application/json' -H 'X-Tenant: acme' 'http://api.example.org/search?q=it%27s'`,
+		},
+		{
+			name:  "quotes",
+			attrs: &RequestAttributes{Path: "/a", Host: "localhost", Headers: map[string]string{"X-Quote": "it's"}},
+			want:  `curl -H 'X-Quote: it'\''s' http://localhost/a`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CurlCommand(tt.attrs)
+			assert.Equal(t, tt.want, got)
+
+			// the command can be parsed back
+			parsed, _, err := ParseCurl(got)
+			assert.NoError(t, err)
+			assert.Equal(t, RawRequest(tt.attrs, nil), RawRequest(parsed, nil))
+		})
+	}
+}
+
+func TestGeneratedCaseAsCurl(t *testing.T) {
+	c := GeneratedCase{RouteID: "search", Request: &RequestAttributes{
+		Method:  "POST",
+		Path:    "/search",
+		Host:    "api.example.org",
+		Query:   map[string]string{"q": "it's"},
+		Headers: map[string]string{"X-Quote": "it's", "Cookie": "session=example"},
+	}}
+	got := c.AsCurl("https://staging.example.org/")
+	assert.Equal(t, `curl -X POST -H 'Host: api.example.org' -H 'X-Quote: it'\''s' -b session=example 'https://staging.example.org/search?q=it%27s'`, got)
+
+	parsed, _, err := ParseCurl(got)
+	require.NoError(t, err)
+	assert.Equal(t, "POST", parsed.Method)
+	assert.Equal(t, "/search", parsed.Path)
+	assert.Equal(t, "it's", parsed.Query["q"])
+	assert.Equal(t, "it's", parsed.Headers["X-Quote"])
+}
+
+func TestExportCurl(t *testing.T) {
+	f, err := ioutil.TempFile("", "curl-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`orders: Host(/^api[.]example[.]org$/) && Path("/orders/:id") && Method("GET") -> <shunt>;
+search: Path("/search") && Method("POST") && QueryParam("q", "^[a-z]+$") && Header("X-Tenant", "acme") && Cookie("session", "^s[0-9]$") -> <shunt>;
+canary: Path("/canary") && Traffic(0.1) -> <shunt>;
+v1: Path("/v1") -> <shunt>;
+shadowed: PathRegexp(/^\/v1/) -> <shunt>;
+`)
+	f.Close()
+
+	var b bytes.Buffer
+	require.NoError(t, ExportCurl(&Options{RoutesFile: f.Name()}, "http://localhost:9090", &b))
+	assert.Equal(t, `# orders
+curl -H 'Host: api.example.org' http://localhost:9090/orders/example-id
+# search
+curl -X POST -H 'X-Tenant: acme' -b session=s0 'http://localhost:9090/search?q=a'
+# v1
+curl http://localhost:9090/v1
+
+# skipped, the generated requests need manual attention:
+# canary: Traffic(0.1) can't be satisfied automatically: matches a random share of the requests
+# shadowed: the generated request matches route v1 instead
+`, b.String())
+}
@@ -0,0 +1,185 @@
+package matcher
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Formats of the decision log, see Options.DecisionLogFormat
+const (
+	DecisionLogJSON = "json"
+	DecisionLogText = "text"
+)
+
+// decisionLogBuffer number of decisions waiting to be written, the next ones
+// are dropped
+const decisionLogBuffer = 1024
+
+// DecisionEntry a decision log line, see Options.DecisionLog
+type DecisionEntry struct {
+	// Time when the request was tested
+	Time time.Time `json:"time"`
+	// Request the normalized request, the redacted headers hidden
+	Request *RequestAttributes `json:"request"`
+	// RouteID id of the matching route, nil without a match
+	RouteID *string `json:"routeId"`
+	// DurationMs duration of the test in milliseconds
+	DurationMs float64 `json:"durationMs"`
+	// Normalizations the changes normalizing the request, eg. the default method
+	Normalizations []string `json:"normalizations"`
+	// Error of the test, if any
+	Error string `json:"error,omitempty"`
+	// Dropped number of decisions dropped since the previous line because
+	// the writer didn't keep up
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// String the text format of the entry
+func (e *DecisionEntry) String() string {
+	route := "<no match>"
+	if e.RouteID != nil {
+		route = *e.RouteID
+	}
+	s := fmt.Sprintf("%s %s %s -> %s (%.3fms)", e.Time.Format(time.RFC3339Nano), e.Request.Method, e.Request.Path, route, e.DurationMs)
+	if e.Request.Host != "" {
+		s += fmt.Sprintf(" host=%s", e.Request.Host)
+	}
+	if len(e.Normalizations) > 0 {
+		s += fmt.Sprintf(" normalized: %s", strings.Join(e.Normalizations, ", "))
+	}
+	if e.Error != "" {
+		s += fmt.Sprintf(" error: %s", e.Error)
+	}
+	if e.Dropped > 0 {
+		s += fmt.Sprintf(" (%d decision(s) dropped)", e.Dropped)
+	}
+	return s
+}
+
+// decisionLog writes the decisions to Options.DecisionLog from its own
+// goroutine, the tests never wait for the writer
+type decisionLog struct {
+	mu       sync.RWMutex
+	closed   bool
+	entries  chan *DecisionEntry
+	dropped  uint64
+	done     chan struct{}
+	w        *bufio.Writer
+	text     bool
+	redacted map[string]bool
+}
+
+// checkDecisionLogFormat fails on an unknown Options.DecisionLogFormat
+func checkDecisionLogFormat(format string) error {
+	switch format {
+	case "", DecisionLogJSON, DecisionLogText:
+		return nil
+	default:
+		return fmt.Errorf("invalid DecisionLogFormat %q, expected %s or %s", format, DecisionLogJSON, DecisionLogText)
+	}
+}
+
+// newDecisionLog starts the decision log of the options, nil without Options.DecisionLog
+func newDecisionLog(o *Options) *decisionLog {
+	if o.DecisionLog == nil {
+		return nil
+	}
+	l := &decisionLog{
+		entries:  make(chan *DecisionEntry, decisionLogBuffer),
+		done:     make(chan struct{}),
+		w:        bufio.NewWriter(o.DecisionLog),
+		text:     o.DecisionLogFormat == DecisionLogText,
+		redacted: redactedHeaders(o.RedactedHeaders),
+	}
+	go l.run()
+	return l
+}
+
+// record queues the decision of a test, dropped when the queue is full
+func (l *decisionLog) record(original *RequestAttributes, result *testResult, start time.Time) {
+	e := &DecisionEntry{
+		Time:           start,
+		Request:        l.redact(result.attributes),
+		DurationMs:     float64(time.Since(start)) / float64(time.Millisecond),
+		Normalizations: normalizations(original),
+	}
+	if result.route != nil {
+		id := result.route.Id
+		e.RouteID = &id
+	}
+	if result.err != nil {
+		// the entry has the request already
+		e.Error = matchCause(result.err).Error()
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.entries <- e:
+	default:
+		atomic.AddUint64(&l.dropped, 1)
+	}
+}
+
+// redact copies the attributes with the values of the redacted headers hidden
+func (l *decisionLog) redact(a *RequestAttributes) *RequestAttributes {
+	return redactHeaders(a, l.redacted)
+}
+
+func (l *decisionLog) run() {
+	defer close(l.done)
+	for e := range l.entries {
+		e.Dropped = atomic.SwapUint64(&l.dropped, 0)
+		l.write(e)
+		if len(l.entries) == 0 {
+			l.w.Flush()
+		}
+	}
+	l.w.Flush()
+}
+
+func (l *decisionLog) write(e *DecisionEntry) {
+	if l.text {
+		fmt.Fprintln(l.w, e)
+		return
+	}
+	// the entries are always representable in json
+	b, _ := json.Marshal(e)
+	l.w.Write(append(b, '\n'))
+}
+
+// close writes the queued decisions and stops the decision log
+func (l *decisionLog) close() {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.entries)
+	l.mu.Unlock()
+	<-l.done
+}
+
+// normalizations the changes normalize makes to the attributes
+func normalizations(a *RequestAttributes) []string {
+	n := []string{}
+	if !strings.HasPrefix(a.Path, "/") {
+		n = append(n, "path: leading slash added")
+	}
+	if a.Method == "" {
+		n = append(n, "method: GET by default")
+	}
+	if a.Host == "" {
+		n = append(n, "host: localhost by default")
+	}
+	return n
+}
@@ -0,0 +1,83 @@
+package matcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecisionLog(t *testing.T) {
+	var out bytes.Buffer
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, DecisionLog: &out})
+	require.NoError(t, err)
+	m.Test(&RequestAttributes{Path: "bar", Headers: map[string]string{"Authorization": "secret", "X-Trace": "1"}})
+	m.Test(&RequestAttributes{Method: "POST", Path: "/missing", Host: "example.org"})
+	m.Close()
+
+	assert.NotContains(t, out.String(), "secret")
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		entries = append(entries, entry)
+	}
+	require.Len(t, entries, 2)
+	for _, e := range entries {
+		for _, key := range []string{"time", "request", "routeId", "durationMs", "normalizations"} {
+			assert.Contains(t, e, key)
+		}
+		_, err := time.Parse(time.RFC3339Nano, e["time"].(string))
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, "bar", entries[0]["routeId"])
+	request := entries[0]["request"].(map[string]interface{})
+	assert.Equal(t, "/bar", request["path"])
+	assert.Equal(t, map[string]interface{}{"Authorization": redactedValue, "X-Trace": "1"}, request["headers"])
+	assert.Equal(t, []interface{}{"path: leading slash added", "method: GET by default", "host: localhost by default"}, entries[0]["normalizations"])
+
+	assert.Nil(t, entries[1]["routeId"])
+	assert.Equal(t, []interface{}{}, entries[1]["normalizations"])
+}
+
+func TestDecisionLogText(t *testing.T) {
+	var out bytes.Buffer
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, DecisionLog: &out, DecisionLogFormat: DecisionLogText})
+	require.NoError(t, err)
+	m.Test(&RequestAttributes{Path: "/bar"})
+	m.Test(&RequestAttributes{Path: "/missing"})
+	m.Close()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Regexp(t, `^\S+ GET /bar -> bar \([0-9.]+ms\) normalized: method: GET by default, host: localhost by default$`, lines[0])
+		assert.Regexp(t, `^\S+ GET /missing -> <no match> \(`, lines[1])
+	}
+
+	_, err = New(&Options{RoutesFile: "testdata/routes.eskip", DecisionLog: &out, DecisionLogFormat: "xml"})
+	assert.Error(t, err)
+}
+
+func TestDecisionLogDropped(t *testing.T) {
+	// not started, nothing is written until there is room
+	l := &decisionLog{entries: make(chan *DecisionEntry, 1)}
+	result := &testResult{attributes: &RequestAttributes{Method: "GET", Path: "/"}}
+	for i := 0; i < 3; i++ {
+		l.record(result.attributes, result, time.Now())
+	}
+	assert.Len(t, l.entries, 1)
+	assert.Equal(t, uint64(2), l.dropped)
+
+	var out bytes.Buffer
+	l.w = bufio.NewWriter(&out)
+	l.done = make(chan struct{})
+	go l.run()
+	l.close()
+	assert.Contains(t, out.String(), `"dropped":2`)
+}
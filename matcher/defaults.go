@@ -0,0 +1,107 @@
+package matcher
+
+import (
+	"fmt"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+// ChainFilter a filter of the effective filter chain of the matching route,
+// see TestResult.Filters
+type ChainFilter struct {
+	*eskip.Filter
+	// Default the filter is one of Options.DefaultFiltersPrepend or
+	// Options.DefaultFiltersAppend, not in the route definition
+	Default bool
+}
+
+// defaultFilters the filters added to every route like the skipper
+// -default-filters-prepend and -default-filters-append flags
+type defaultFilters struct {
+	prepend []*eskip.Filter
+	append  []*eskip.Filter
+}
+
+// parseDefaultFilters parses the default filters of the options, nil if there are none
+func parseDefaultFilters(o *Options) (*defaultFilters, error) {
+	if o.DefaultFiltersPrepend == "" && o.DefaultFiltersAppend == "" {
+		return nil, nil
+	}
+	prepend, err := eskip.ParseFilters(o.DefaultFiltersPrepend)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DefaultFiltersPrepend: %v", err)
+	}
+	append, err := eskip.ParseFilters(o.DefaultFiltersAppend)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DefaultFiltersAppend: %v", err)
+	}
+	return &defaultFilters{prepend: prepend, append: append}, nil
+}
+
+// apply returns copies of the routes with the default filters, the routes
+// aren't modified
+func (d *defaultFilters) apply(routes []*eskip.Route) []*eskip.Route {
+	if d == nil {
+		return routes
+	}
+	applied := make([]*eskip.Route, len(routes))
+	for i, r := range routes {
+		c := *r
+		c.Filters = make([]*eskip.Filter, 0, len(d.prepend)+len(r.Filters)+len(d.append))
+		// like skipper each route gets its own copies
+		for _, f := range d.prepend {
+			c.Filters = append(c.Filters, copyFilter(f))
+		}
+		c.Filters = append(c.Filters, r.Filters...)
+		for _, f := range d.append {
+			c.Filters = append(c.Filters, copyFilter(f))
+		}
+		applied[i] = &c
+	}
+	return applied
+}
+
+func copyFilter(f *eskip.Filter) *eskip.Filter {
+	return &eskip.Filter{Name: f.Name, Args: append([]interface{}(nil), f.Args...)}
+}
+
+// wrap returns the data clients loading the routes with the default filters
+func (d *defaultFilters) wrap(clients []routing.DataClient) []routing.DataClient {
+	if d == nil {
+		return clients
+	}
+	wrapped := make([]routing.DataClient, len(clients))
+	for i, c := range clients {
+		wrapped[i] = &defaultFiltersClient{DataClient: c, defaults: d}
+	}
+	return wrapped
+}
+
+// chain the filters of a loaded route with the default ones marked
+func (d *defaultFilters) chain(r *eskip.Route) []ChainFilter {
+	chain := make([]ChainFilter, len(r.Filters))
+	for i, f := range r.Filters {
+		chain[i] = ChainFilter{Filter: f}
+		if d != nil && (i < len(d.prepend) || i >= len(r.Filters)-len(d.append)) {
+			chain[i].Default = true
+		}
+	}
+	return chain
+}
+
+// defaultFiltersClient data client adding the default filters to the routes
+type defaultFiltersClient struct {
+	routing.DataClient
+	defaults *defaultFilters
+}
+
+func (c *defaultFiltersClient) LoadAll() ([]*eskip.Route, error) {
+	routes, err := c.DataClient.LoadAll()
+	return c.defaults.apply(routes), err
+}
+
+func (c *defaultFiltersClient) LoadUpdate() ([]*eskip.Route, []string, error) {
+	routes, deleted, err := c.DataClient.LoadUpdate()
+	return c.defaults.apply(routes), deleted, err
+}
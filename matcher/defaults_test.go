@@ -0,0 +1,48 @@
+package matcher
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultFilters(t *testing.T) {
+	file := writeRoutes(t, `items: Path("/items") -> setPath("/v1/items") -> "http://items.svc";
+plain: Path("/plain") -> "http://plain.svc";
+`)
+	defer os.Remove(file)
+
+	m, err := New(&Options{
+		RoutesFile:            file,
+		DefaultFiltersPrepend: `setRequestHeader("X-Env", "test")`,
+		DefaultFiltersAppend:  `setResponseHeader("X-Served-By", "skipper")`,
+	})
+	require.NoError(t, err)
+	defer m.Close()
+
+	result := m.Test(&RequestAttributes{Path: "/items"})
+	require.NoError(t, result.Err())
+	chain := result.Filters()
+	if assert.Len(t, chain, 3) {
+		assert.Equal(t, "setRequestHeader", chain[0].Name)
+		assert.True(t, chain[0].Default)
+		assert.Equal(t, "setPath", chain[1].Name)
+		assert.False(t, chain[1].Default)
+		assert.Equal(t, "setResponseHeader", chain[2].Name)
+		assert.True(t, chain[2].Default)
+	}
+	assert.Contains(t, result.PrettyPrintLines(), `effective filters: setRequestHeader("X-Env", "test") [default] -> setPath("/v1/items") -> setResponseHeader("X-Served-By", "skipper") [default]`)
+
+	req, _, err := result.SimulateRequestFilters()
+	require.NoError(t, err)
+	assert.Equal(t, "test", req.Header.Get("X-Env"))
+
+	result = m.Test(&RequestAttributes{Path: "/plain"})
+	require.NoError(t, result.Err())
+	assert.Len(t, result.Filters(), 2, "each route gets the default filters")
+
+	_, err = New(&Options{RoutesFile: file, DefaultFiltersPrepend: `setPath(`})
+	assert.Error(t, err)
+}
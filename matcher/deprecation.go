@@ -0,0 +1,97 @@
+package matcher
+
+// DeprecationKind what a deprecation rule is about
+type DeprecationKind string
+
+// Deprecation kinds
+const (
+	// DeprecatedFilter a filter name
+	DeprecatedFilter DeprecationKind = "filter"
+	// DeprecatedPredicate a predicate name
+	DeprecatedPredicate DeprecationKind = "predicate"
+)
+
+// DeprecationRule a filter or a predicate the routes shouldn't use anymore,
+// reported by the deprecated rule
+type DeprecationRule struct {
+	// Name of the filter or the predicate
+	Name string `json:"name" yaml:"name"`
+	// Kind filter or predicate
+	Kind DeprecationKind `json:"kind" yaml:"kind"`
+	// Replacement suggested in the finding message, eg. setRequestHeader
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// Severity of the findings, warning when empty
+	Severity Severity `json:"severity,omitempty" yaml:"severity,omitempty"`
+}
+
+// DefaultDeprecations returns the filters skipper itself deprecated, used
+// by the deprecated rule unless RuleOptions.Deprecations is set
+func DefaultDeprecations() []DeprecationRule {
+	return []DeprecationRule{
+		{Name: "requestHeader", Kind: DeprecatedFilter, Replacement: "setRequestHeader or appendRequestHeader"},
+		{Name: "responseHeader", Kind: DeprecatedFilter, Replacement: "setResponseHeader or appendResponseHeader"},
+		{Name: "redirect", Kind: DeprecatedFilter, Replacement: "redirectTo"},
+		{Name: "accessLogDisabled", Kind: DeprecatedFilter, Replacement: "disableAccessLog or enableAccessLog"},
+		{Name: "Tee", Kind: DeprecatedFilter, Replacement: "tee"},
+		{Name: "localRatelimit", Kind: DeprecatedFilter, Replacement: "clientRatelimit"},
+	}
+}
+
+// MergeDeprecations returns the rules with the overrides, an override
+// replaces the rule of the same kind and name
+func MergeDeprecations(rules []DeprecationRule, overrides ...DeprecationRule) []DeprecationRule {
+	merged := append([]DeprecationRule{}, rules...)
+	for _, o := range overrides {
+		replaced := false
+		for i, r := range merged {
+			if r.Kind == o.Kind && r.Name == o.Name {
+				merged[i], replaced = o, true
+			}
+		}
+		if !replaced {
+			merged = append(merged, o)
+		}
+	}
+	return merged
+}
+
+// deprecatedUsages reports the routes using deprecated filters or predicates
+func (l *linter) deprecatedUsages() {
+	deprecations := l.rules.Deprecations
+	if deprecations == nil {
+		deprecations = DefaultDeprecations()
+	}
+	byName := map[DeprecationKind]map[string]DeprecationRule{DeprecatedFilter: {}, DeprecatedPredicate: {}}
+	for _, d := range deprecations {
+		if names, ok := byName[d.Kind]; ok {
+			names[d.Name] = d
+		}
+	}
+
+	for _, r := range l.routes {
+		for _, p := range Predicates(r.route) {
+			if d, ok := byName[DeprecatedPredicate][p.Name]; ok {
+				l.reportDeprecation(r, d)
+			}
+		}
+		for _, f := range r.route.Filters {
+			if d, ok := byName[DeprecatedFilter][f.Name]; ok {
+				l.reportDeprecation(r, d)
+			}
+		}
+	}
+}
+
+func (l *linter) reportDeprecation(r lintRoute, d DeprecationRule) {
+	severity := d.Severity
+	if severity == "" {
+		severity = SeverityWarning
+	}
+	if d.Replacement == "" {
+		l.report(RuleDeprecated, severity, r, "%s %s is deprecated", d.Kind, d.Name)
+		return
+	}
+	if f := l.report(RuleDeprecated, severity, r, "%s %s is deprecated, use %s", d.Kind, d.Name, d.Replacement); f != nil {
+		f.Suggestion = "use " + d.Replacement
+	}
+}
@@ -0,0 +1,111 @@
+package matcher
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// Kinds of route changes
+const (
+	RouteAdded   = "added"
+	RouteRemoved = "removed"
+	RouteChanged = "changed"
+)
+
+// RouteChange a route added, removed or changed between two routes tables
+type RouteChange struct {
+	// ID id of the route
+	ID string `json:"id" yaml:"id"`
+	// Change one of added, removed and changed
+	Change string `json:"change" yaml:"change"`
+	// Old definition of the route, empty when added
+	Old string `json:"old,omitempty" yaml:"old,omitempty"`
+	// New definition of the route, empty when removed
+	New string `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+// BehaviorChange a request resolving to a different route or backend
+type BehaviorChange struct {
+	// Request the request attributes tested
+	Request *RequestAttributes `json:"request" yaml:"request"`
+	// Old result with the old routes
+	Old *ResultData `json:"old" yaml:"old"`
+	// New result with the new routes
+	New *ResultData `json:"new" yaml:"new"`
+}
+
+// DiffRoutes compares two routes tables by route id, the definitions are
+// compared in their normalized eskip form. Changes are sorted by id.
+func DiffRoutes(old, new []*eskip.Route) []RouteChange {
+	oldDefs, newDefs := routeDefinitions(old), routeDefinitions(new)
+
+	changes := []RouteChange{}
+	for id, def := range oldDefs {
+		newDef, ok := newDefs[id]
+		switch {
+		case !ok:
+			changes = append(changes, RouteChange{ID: id, Change: RouteRemoved, Old: def})
+		case newDef != def:
+			changes = append(changes, RouteChange{ID: id, Change: RouteChanged, Old: def, New: newDef})
+		}
+	}
+	for id, def := range newDefs {
+		if _, ok := oldDefs[id]; !ok {
+			changes = append(changes, RouteChange{ID: id, Change: RouteAdded, New: def})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+	return changes
+}
+
+// routeDefinitions eskip definitions by route id, the last one wins like in skipper
+func routeDefinitions(routes []*eskip.Route) map[string]string {
+	defs := make(map[string]string, len(routes))
+	for _, r := range routes {
+		defs[r.Id] = r.String()
+	}
+	return defs
+}
+
+// DiffBehavior tests every request against both matchers and returns the
+// ones resolving to a different route or backend, in the requests order
+func DiffBehavior(old, new Matcher, requests []*RequestAttributes) []BehaviorChange {
+	changes := []BehaviorChange{}
+	for _, req := range requests {
+		oldTest := old.Test(req)
+		oldRes := NewResultData(oldTest)
+		newRes := NewResultData(new.Test(req))
+		if sameDestination(oldRes, newRes) {
+			continue
+		}
+		changes = append(changes, BehaviorChange{Request: oldTest.Attributes(), Old: oldRes, New: newRes})
+	}
+	return changes
+}
+
+// sameDestination true if both results have the same route and backend
+func sameDestination(a, b *ResultData) bool {
+	return a.RouteID == b.RouteID &&
+		a.BackendType == b.BackendType &&
+		a.Backend == b.Backend &&
+		reflect.DeepEqual(a.LBEndpoints, b.LBEndpoints)
+}
+
+// SeedRequests generates a request for each route, see GenerateRequest,
+// the requests generated more than once are returned only once
+func SeedRequests(routes []*eskip.Route) []*RequestAttributes {
+	seen := make(map[string]bool)
+	var requests []*RequestAttributes
+	for _, r := range routes {
+		attrs, _ := GenerateRequest(r)
+		key := RawRequest(attrs, nil)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		requests = append(requests, attrs)
+	}
+	return requests
+}
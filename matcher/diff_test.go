@@ -0,0 +1,85 @@
+package matcher
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/eskip"
+)
+
+const (
+	diffOld = `
+		orders: Path("/orders") -> "https://orders.example.org";
+		search: Path("/search") -> <shunt>;
+		legacy: Path("/legacy") -> <shunt>;
+	`
+	diffNew = `
+		orders: Path("/orders") -> "https://orders-v2.example.org";
+		search: Path("/search") -> <shunt>;
+		health: Path("/health") -> <shunt>;
+	`
+)
+
+// newTestMatcher creates a matcher loading the routes from a temporary file
+func newTestMatcher(t *testing.T, routes string) Matcher {
+	file := writeRoutes(t, routes)
+	defer os.Remove(file)
+	m, err := New(&Options{RoutesFile: file})
+	require.NoError(t, err)
+	return m
+}
+
+func TestDiffRoutes(t *testing.T) {
+	old, err := eskip.Parse(diffOld)
+	require.NoError(t, err)
+	new, err := eskip.Parse(diffNew)
+	require.NoError(t, err)
+
+	assert.Equal(t, []RouteChange{
+		{ID: "health", Change: RouteAdded, New: `Path("/health") -> <shunt>`},
+		{ID: "legacy", Change: RouteRemoved, Old: `Path("/legacy") -> <shunt>`},
+		{
+			ID:     "orders",
+			Change: RouteChanged,
+			Old:    `Path("/orders") -> "https://orders.example.org"`,
+			New:    `Path("/orders") -> "https://orders-v2.example.org"`,
+		},
+	}, DiffRoutes(old, new))
+	assert.Empty(t, DiffRoutes(old, old))
+}
+
+func TestDiffBehavior(t *testing.T) {
+	old, new := newTestMatcher(t, diffOld), newTestMatcher(t, diffNew)
+	requests := []*RequestAttributes{
+		{Path: "/orders"},
+		{Path: "/search"},
+		{Path: "/legacy"},
+		{Path: "/health"},
+		{Path: "/missing"},
+	}
+
+	changes := DiffBehavior(old, new, requests)
+	require.Len(t, changes, 3)
+	assert.Equal(t, "/orders", changes[0].Request.Path)
+	assert.Equal(t, "https://orders.example.org", changes[0].Old.Backend)
+	assert.Equal(t, "https://orders-v2.example.org", changes[0].New.Backend)
+	assert.Equal(t, "legacy", changes[1].Old.RouteID)
+	assert.False(t, changes[1].New.Matched)
+	assert.Equal(t, "health", changes[2].New.RouteID)
+
+	// requests are left untouched
+	assert.Equal(t, "", requests[0].Method)
+}
+
+func TestSeedRequests(t *testing.T) {
+	routes, err := eskip.Parse(diffOld + diffNew)
+	require.NoError(t, err)
+
+	var paths []string
+	for _, r := range SeedRequests(routes) {
+		paths = append(paths, r.Path)
+	}
+	assert.Equal(t, []string{"/orders", "/search", "/legacy", "/health"}, paths)
+}
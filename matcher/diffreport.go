@@ -0,0 +1,266 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// Classes of a RequestChange, from the least to the most relevant one
+const (
+	// ChangeRenamed another route matches with the same backend and filters
+	ChangeRenamed = "renamed"
+	// ChangeFilters the backend is the same, not the filter chain
+	ChangeFilters = "filters"
+	// ChangeDestination another backend, or a route matches on one side only
+	ChangeDestination = "destination"
+)
+
+// DiffReport result of Diff
+type DiffReport struct {
+	// Requests number of requests tested against both routes
+	Requests int `json:"requests"`
+	// Unchanged number of requests routed the same way
+	Unchanged int `json:"unchanged"`
+	// Renamed number of ChangeRenamed changes
+	Renamed int `json:"renamed"`
+	// Filters number of ChangeFilters changes
+	Filters int `json:"filters"`
+	// Destination number of ChangeDestination changes
+	Destination int `json:"destination"`
+	// Changes the requests routed differently, in the requests order
+	Changes []RequestChange `json:"changes"`
+	// Omitted number of changes left out of a written report, see
+	// ReportOptions.MaxEntries
+	Omitted int `json:"omitted,omitempty"`
+	// RouteWitnesses the requests demonstrating the changes of the routes,
+	// see Witnesses
+	RouteWitnesses []RouteWitnesses `json:"witnesses,omitempty"`
+
+	oldOpts, newOpts *Options
+}
+
+// RequestChange a request routed differently by the new routes
+type RequestChange struct {
+	// Request the normalized request
+	Request *RequestAttributes `json:"request"`
+	// Class ChangeRenamed, ChangeFilters or ChangeDestination
+	Class string `json:"class"`
+	// Old the result with the old routes
+	Old *ResultData `json:"old"`
+	// New the result with the new routes
+	New *ResultData `json:"new"`
+}
+
+// Diff tests every request against the routes of both options and reports
+// the ones matching another route id, backend or filter chain, eg. to prove
+// a refactoring of the routes is a no-op. The requests can be the ones of
+// fixtures, of access logs, see ParseAccessLogLine, or generated: without
+// requests one is generated for each route of both sides, see SeedRequests.
+// The load balanced endpoints are compared in any order, the Traffic
+// predicates always match.
+func Diff(oldOpts, newOpts *Options, requests []*RequestAttributes) (*DiffReport, error) {
+	old, err := newComparedMatcher(oldOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer old.Close()
+	new, err := newComparedMatcher(newOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer new.Close()
+
+	if requests == nil {
+		requests = SeedRequests(append(old.Routes(), new.Routes()...))
+	}
+	report := &DiffReport{Requests: len(requests), Changes: []RequestChange{}, oldOpts: oldOpts, newOpts: newOpts}
+	for _, req := range requests {
+		oldTest := old.Test(req)
+		if err := oldTest.Err(); err != nil {
+			return nil, fmt.Errorf("invalid request %s %s: %v", req.Method, req.Path, matchCause(err))
+		}
+		c := RequestChange{Request: oldTest.Attributes(), Old: NewResultData(oldTest), New: NewResultData(new.Test(req))}
+		switch c.Class = changeClass(c.Old, c.New); c.Class {
+		case ChangeRenamed:
+			report.Renamed++
+		case ChangeFilters:
+			report.Filters++
+		case ChangeDestination:
+			report.Destination++
+		default:
+			report.Unchanged++
+			continue
+		}
+		report.Changes = append(report.Changes, c)
+	}
+	return report, nil
+}
+
+// changeClass the class of the change between the results, empty when
+// there's none
+func changeClass(old, new *ResultData) string {
+	switch {
+	case old.Matched != new.Matched ||
+		old.BackendType != new.BackendType ||
+		old.Backend != new.Backend ||
+		!sameEndpoints(old.LBEndpoints, new.LBEndpoints):
+		return ChangeDestination
+	case !reflect.DeepEqual(old.Filters, new.Filters):
+		return ChangeFilters
+	case old.RouteID != new.RouteID:
+		return ChangeRenamed
+	}
+	return ""
+}
+
+// sameEndpoints true if the load balanced endpoints are the same, in any
+// order
+func sameEndpoints(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sa, sb := append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(sa)
+	sort.Strings(sb)
+	return reflect.DeepEqual(sa, sb)
+}
+
+// ReportOptions options of DiffReport.WriteWith
+type ReportOptions struct {
+	// MaxEntries the most changes written, in the requests order, followed
+	// by a line counting the others, all of them when 0
+	MaxEntries int
+	// Style decorates the text format, the old results with Removed and the
+	// new ones with Added
+	Style Style
+}
+
+// Write writes the report in the format, FormatText when empty, its text
+// colored when w is a terminal, see TerminalStyle
+func (r *DiffReport) Write(w io.Writer, format Format) error {
+	return r.WriteWith(w, format, ReportOptions{Style: TerminalStyle(w)})
+}
+
+// WriteWith writes the report in the format with the options: the text is
+// a unified diff of the old and the new result of each change, the json the
+// report itself and the markdown a table of the changes, eg. for pull
+// request comments. The witnesses follow the changes.
+func (r *DiffReport) WriteWith(w io.Writer, format Format, o ReportOptions) error {
+	shown := *r
+	if o.MaxEntries > 0 && len(r.Changes) > o.MaxEntries {
+		shown.Changes = r.Changes[:o.MaxEntries]
+		shown.Omitted = len(r.Changes) - o.MaxEntries
+	}
+	switch format {
+	case FormatText, "":
+		return shown.writeText(w, o.Style)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(&shown)
+	case FormatMarkdown:
+		return shown.writeMarkdown(w)
+	default:
+		return fmt.Errorf("unsupported format %q, use %s, %s or %s", format, FormatText, FormatJSON, FormatMarkdown)
+	}
+}
+
+// WriteText writes the report in FormatText, without colors
+func (r *DiffReport) WriteText(w io.Writer) error {
+	return r.WriteWith(w, FormatText, ReportOptions{})
+}
+
+// WriteJSON writes the report in indented json
+func (r *DiffReport) WriteJSON(w io.Writer) error {
+	return r.WriteWith(w, FormatJSON, ReportOptions{})
+}
+
+// WriteMarkdown writes the report in FormatMarkdown
+func (r *DiffReport) WriteMarkdown(w io.Writer) error {
+	return r.WriteWith(w, FormatMarkdown, ReportOptions{})
+}
+
+// summary the counts, eg. 3 of 5 request(s) changed: 1 renamed, 1 filters, 1 destination
+func (r *DiffReport) summary() string {
+	return fmt.Sprintf("%d of %d request(s) changed: %d renamed, %d filters, %d destination",
+		r.Renamed+r.Filters+r.Destination, r.Requests, r.Renamed, r.Filters, r.Destination)
+}
+
+// writeText writes a hunk for each change, eg.
+// @@ GET /orders @@ destination
+// -orders -> https://orders.example.org
+// +orders -> https://orders-v2.example.org
+func (r *DiffReport) writeText(w io.Writer, style Style) error {
+	fmt.Fprintln(w, r.summary())
+	for _, c := range r.Changes {
+		fmt.Fprintf(w, "@@ %s @@ %s\n", requestLine(c.Request), c.Class)
+		writeResults(w, style, c.Old, c.New)
+	}
+	if r.Omitted > 0 {
+		fmt.Fprintf(w, "... %d more change(s) not shown\n", r.Omitted)
+	}
+	for _, rw := range r.RouteWitnesses {
+		fmt.Fprintf(w, "@@ witnesses of %s @@ %s\n", rw.RouteID, rw.Change)
+		switch {
+		case rw.FilterOnly:
+			fmt.Fprintln(w, " only the filters changed, the route matches the same requests")
+		case len(rw.Witnesses) == 0:
+			fmt.Fprintln(w, " no request could be generated")
+		}
+		for _, wt := range rw.Witnesses {
+			fmt.Fprintf(w, " %s\n", requestLine(wt.Request))
+			writeResults(w, style, wt.Old, wt.New)
+		}
+	}
+	return nil
+}
+
+// writeResults writes the old and the new result as the removed and the
+// added lines of a diff
+func writeResults(w io.Writer, style Style, old, new *ResultData) {
+	fmt.Fprintln(w, style.DecorateRemoved("-"+diffDestination(old)))
+	fmt.Fprintln(w, style.DecorateAdded("+"+diffDestination(new)))
+}
+
+// writeMarkdown writes the counts, a table of the changes and the
+// witnesses
+func (r *DiffReport) writeMarkdown(w io.Writer) error {
+	fmt.Fprintf(w, "**%s**\n", r.summary())
+	if len(r.Changes) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Request | Change | Old | New |")
+		fmt.Fprintln(w, "| --- | --- | --- | --- |")
+		for _, c := range r.Changes {
+			fmt.Fprintf(w, "| %s | %s | %s | %s |\n", markdownCode(requestLine(c.Request)),
+				c.Class, markdownCode(diffDestination(c.Old)), markdownCode(diffDestination(c.New)))
+		}
+	}
+	if r.Omitted > 0 {
+		fmt.Fprintf(w, "\n_%d more change(s) not shown._\n", r.Omitted)
+	}
+	return writeWitnessesMarkdown(w, r.RouteWitnesses)
+}
+
+// requestLine short representation of a request, eg. GET api.example.org/orders
+func requestLine(a *RequestAttributes) string {
+	return fmt.Sprintf("%s %s%s", a.Method, a.Host, a.Path)
+}
+
+// diffDestination where the request is routed, eg.
+// orders -> setPath("/v2") -> https://orders.example.org
+func diffDestination(r *ResultData) string {
+	if !r.Matched {
+		return "no match"
+	}
+	s := r.RouteID
+	for _, f := range r.Filters {
+		s += " -> " + filterString(&eskip.Filter{Name: f.Name, Args: f.Args})
+	}
+	return s + " -> " + r.Backend
+}
@@ -0,0 +1,211 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	diffReportOld = `orders: Path("/orders") -> "https://orders.example.org";
+search: Path("/search") -> setQuery("v", "1") -> "https://search.example.org";
+legacy: Path("/legacy") -> "https://legacy.example.org";
+health: Path("/health") -> <shunt>;`
+	diffReportNew = `orders_v1: Path("/orders") -> "https://orders.example.org";
+search: Path("/search") -> setQuery("v", "2") -> "https://search.example.org";
+legacy: Path("/legacy") -> "https://legacy-v2.example.org";
+health: Path("/health") -> <shunt>;
+catalog: Path("/catalog") -> "https://catalog.example.org";`
+)
+
+func TestDiff(t *testing.T) {
+	oldFile, newFile := writeRoutes(t, diffReportOld), writeRoutes(t, diffReportNew)
+	defer os.Remove(oldFile)
+	defer os.Remove(newFile)
+	oldOpts, newOpts := &Options{RoutesFile: oldFile}, &Options{RoutesFile: newFile}
+
+	// the requests of a fixture
+	report, err := Diff(oldOpts, newOpts, []*RequestAttributes{
+		{Path: "/orders"},
+		{Path: "/search", Query: map[string]string{"q": "shoes"}},
+		{Path: "/legacy"},
+		{Path: "/health"},
+		{Path: "/missing"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Requests)
+	assert.Equal(t, 2, report.Unchanged)
+	assert.Equal(t, 1, report.Renamed)
+	assert.Equal(t, 1, report.Filters)
+	assert.Equal(t, 1, report.Destination)
+	require.Len(t, report.Changes, 3)
+	classes := []string{}
+	for _, c := range report.Changes {
+		classes = append(classes, c.Class)
+	}
+	assert.Equal(t, []string{ChangeRenamed, ChangeFilters, ChangeDestination}, classes)
+	assert.Equal(t, "orders", report.Changes[0].Old.RouteID)
+	assert.Equal(t, "orders_v1", report.Changes[0].New.RouteID)
+	assert.Equal(t, "GET", report.Changes[1].Request.Method)
+
+	var text bytes.Buffer
+	require.NoError(t, report.WriteText(&text))
+	assert.Equal(t, `3 of 5 request(s) changed: 1 renamed, 1 filters, 1 destination
+@@ GET /orders @@ renamed
+-orders -> https://orders.example.org
++orders_v1 -> https://orders.example.org
+@@ GET /search @@ filters
+-search -> setQuery("v", "1") -> https://search.example.org
++search -> setQuery("v", "2") -> https://search.example.org
+@@ GET /legacy @@ destination
+-legacy -> https://legacy.example.org
++legacy -> https://legacy-v2.example.org
+`, text.String())
+
+	var doc bytes.Buffer
+	require.NoError(t, report.WriteJSON(&doc))
+	var decoded DiffReport
+	require.NoError(t, json.Unmarshal(doc.Bytes(), &decoded))
+	assert.Equal(t, report.Changes[2].New.Backend, decoded.Changes[2].New.Backend)
+	assert.Contains(t, doc.String(), `"class": "destination"`)
+}
+
+func TestDiffGeneratedRequests(t *testing.T) {
+	oldFile, newFile := writeRoutes(t, diffReportOld), writeRoutes(t, diffReportNew)
+	defer os.Remove(oldFile)
+	defer os.Remove(newFile)
+
+	report, err := Diff(&Options{RoutesFile: oldFile}, &Options{RoutesFile: newFile}, nil)
+	require.NoError(t, err)
+	// the catalog route matches on the new side only
+	assert.Equal(t, 5, report.Requests)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Equal(t, 1, report.Renamed)
+	assert.Equal(t, 1, report.Filters)
+	assert.Equal(t, 2, report.Destination)
+
+	requests, err := Diff(&Options{RoutesFile: oldFile}, &Options{RoutesFile: newFile}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, report, requests, "stable order")
+
+	same, err := Diff(&Options{RoutesFile: oldFile}, &Options{RoutesFile: oldFile}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, same.Changes)
+	var text bytes.Buffer
+	require.NoError(t, same.WriteText(&text))
+	assert.Equal(t, "0 of 4 request(s) changed: 0 renamed, 0 filters, 0 destination\n", text.String())
+}
+
+func TestDiffAccessLogRequests(t *testing.T) {
+	oldFile, newFile := writeRoutes(t, diffReportOld), writeRoutes(t, diffReportNew)
+	defer os.Remove(oldFile)
+	defer os.Remove(newFile)
+
+	var requests []*RequestAttributes
+	for _, line := range []string{
+		`10.0.0.1 - - [02/Jan/2024:10:00:00 +0000] "GET /orders HTTP/1.1" 200 512`,
+		`10.0.0.2 - - [02/Jan/2024:10:00:01 +0000] "POST /catalog HTTP/1.1" 201 0`,
+	} {
+		attrs, err := ParseAccessLogLine(line)
+		require.NoError(t, err)
+		requests = append(requests, attrs)
+	}
+	report, err := Diff(&Options{RoutesFile: oldFile}, &Options{RoutesFile: newFile}, requests)
+	require.NoError(t, err)
+	require.Len(t, report.Changes, 2)
+	assert.Equal(t, ChangeRenamed, report.Changes[0].Class)
+	assert.Equal(t, ChangeDestination, report.Changes[1].Class)
+	assert.False(t, report.Changes[1].Old.Matched)
+	assert.Equal(t, "catalog", report.Changes[1].New.RouteID)
+
+	_, err = Diff(&Options{RoutesFile: oldFile}, &Options{RoutesFile: newFile}, []*RequestAttributes{{Host: "exa mple.org", Path: "/%zz"}})
+	assert.Error(t, err)
+	_, err = Diff(&Options{RoutesFile: "testdata/missing.eskip"}, &Options{RoutesFile: newFile}, nil)
+	assert.Error(t, err)
+}
+
+func TestDiffReportWitnesses(t *testing.T) {
+	oldFile, newFile := writeRoutes(t, diffReportOld), writeRoutes(t, diffReportNew)
+	defer os.Remove(oldFile)
+	defer os.Remove(newFile)
+	report, err := Diff(&Options{RoutesFile: oldFile}, &Options{RoutesFile: newFile}, nil)
+	require.NoError(t, err)
+	require.NoError(t, report.Witnesses(2))
+
+	byID := make(map[string]RouteWitnesses)
+	for _, rw := range report.RouteWitnesses {
+		byID[rw.RouteID] = rw
+	}
+	assert.Len(t, byID, 5)
+	assert.True(t, byID["search"].FilterOnly)
+	assert.Empty(t, byID["search"].Witnesses)
+	assert.Equal(t, RouteRemoved, byID["orders"].Change)
+	require.Len(t, byID["orders"].Witnesses, 1, "a path without regexps has a single request")
+	assert.Equal(t, "orders_v1", byID["orders"].Witnesses[0].New.RouteID)
+	legacy := byID["legacy"]
+	assert.False(t, legacy.FilterOnly)
+	require.Len(t, legacy.Witnesses, 1)
+	assert.Equal(t, "https://legacy.example.org", legacy.Witnesses[0].Old.Backend)
+	assert.Equal(t, "https://legacy-v2.example.org", legacy.Witnesses[0].New.Backend)
+	assert.False(t, byID["catalog"].Witnesses[0].Old.Matched)
+
+	var b bytes.Buffer
+	require.NoError(t, report.WriteMarkdown(&b))
+	golden(t, "witnesses.md", b.Bytes())
+
+	b.Reset()
+	require.NoError(t, report.WriteJSON(&b))
+	assert.Contains(t, b.String(), `"filterOnly": true`)
+
+	assert.Error(t, (&DiffReport{}).Witnesses(2))
+}
+
+func TestDiffReportWrite(t *testing.T) {
+	oldFile, newFile := writeRoutes(t, diffReportOld), writeRoutes(t, diffReportNew)
+	defer os.Remove(oldFile)
+	defer os.Remove(newFile)
+	report, err := Diff(&Options{RoutesFile: oldFile}, &Options{RoutesFile: newFile}, nil)
+	require.NoError(t, err)
+	require.NoError(t, report.Witnesses(1))
+
+	for _, tc := range []struct {
+		file   string
+		format Format
+		style  Style
+	}{
+		{"diffreport/report.txt", FormatText, PlainStyle},
+		{"diffreport/report-color.txt", FormatText, ColorStyle},
+		{"diffreport/report.json", FormatJSON, PlainStyle},
+		{"diffreport/report.md", FormatMarkdown, PlainStyle},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			var b bytes.Buffer
+			require.NoError(t, report.WriteWith(&b, tc.format, ReportOptions{MaxEntries: 2, Style: tc.style}))
+			golden(t, tc.file, b.Bytes())
+		})
+	}
+	assert.Len(t, report.Changes, 4, "writing doesn't truncate the report")
+	assert.Zero(t, report.Omitted)
+
+	var b bytes.Buffer
+	assert.Error(t, report.Write(&b, "yaml"))
+	require.NoError(t, report.Write(&b, ""))
+	assert.NotContains(t, b.String(), "\x1b[", "no colors when not writing to a terminal")
+}
+
+func TestTerminalStyle(t *testing.T) {
+	defer func(f func(io.Writer) bool) { isTerminal = f }(isTerminal)
+	isTerminal = func(io.Writer) bool { return true }
+	defer os.Setenv("NO_COLOR", os.Getenv("NO_COLOR"))
+
+	os.Unsetenv("NO_COLOR")
+	assert.Equal(t, "\x1b[32m+\x1b[0m", TerminalStyle(ioutil.Discard).DecorateAdded("+"))
+	os.Setenv("NO_COLOR", "1")
+	assert.Equal(t, "+", TerminalStyle(ioutil.Discard).DecorateAdded("+"))
+}
@@ -0,0 +1,150 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+// Format output format of Matcher.DumpEffectiveRoutes and DiffReport.Write
+type Format string
+
+// Formats of Matcher.DumpEffectiveRoutes, eskip or json, and of
+// DiffReport.Write, text, json or markdown
+const (
+	FormatEskip    Format = "eskip"
+	FormatJSON     Format = "json"
+	FormatText     Format = "text"
+	FormatMarkdown Format = "markdown"
+)
+
+// EffectiveRoutes serializable representation of the active route table,
+// see Matcher.DumpEffectiveRoutes
+type EffectiveRoutes struct {
+	// Routes the routes the routing applied, sorted by id
+	Routes []*EffectiveRouteData `json:"routes"`
+	// Dropped the routes of the routes file the routing dropped, sorted by id
+	Dropped []DroppedRoute `json:"dropped,omitempty"`
+}
+
+// EffectiveRouteData an applied route with what eskip-match changed in it
+type EffectiveRouteData struct {
+	*RouteData
+	// Annotations what eskip-match changed in the route or injected, eg.
+	// the default filters
+	Annotations []string `json:"annotations,omitempty"`
+
+	route *eskip.Route
+}
+
+// DroppedRoute a route the routing dropped
+type DroppedRoute struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// effectiveRoutes the active route table with its annotations and the dropped routes
+func (f *matcher) effectiveRoutes() *EffectiveRoutes {
+	e := &EffectiveRoutes{Routes: []*EffectiveRouteData{}}
+	for _, r := range f.events.routes() {
+		data := &EffectiveRouteData{RouteData: NewRouteData(r), Annotations: f.annotations(r), route: r}
+		if loc, ok := f.locations[r.Id]; ok {
+			data.Location = &loc
+		}
+		e.Routes = append(e.Routes, data)
+	}
+	for id, reason := range f.events.rejections() {
+		e.Dropped = append(e.Dropped, DroppedRoute{ID: id, Reason: reason})
+	}
+	sort.Slice(e.Dropped, func(i, j int) bool { return e.Dropped[i].ID < e.Dropped[j].ID })
+	return e
+}
+
+// annotations what eskip-match changed in the route: the default filters
+// added and the filters mocked
+func (f *matcher) annotations(r *eskip.Route) []string {
+	var annotations, defaults, mocked []string
+	for _, c := range f.defaults.chain(r) {
+		if c.Default {
+			defaults = append(defaults, filterString(c.Filter))
+		}
+	}
+	for _, fr := range r.Filters {
+		if isMockFilterSpec(f.registry[fr.Name]) && !contains(mocked, fr.Name) {
+			mocked = append(mocked, fr.Name)
+		}
+	}
+	if len(defaults) > 0 {
+		annotations = append(annotations, fmt.Sprintf("default filters: %s", strings.Join(defaults, ", ")))
+	}
+	if len(mocked) > 0 {
+		annotations = append(annotations, fmt.Sprintf("mocked filters: %s", strings.Join(mocked, ", ")))
+	}
+	return annotations
+}
+
+// isMockFilterSpec true for the specs of the mocked filters
+func isMockFilterSpec(spec filters.Spec) bool {
+	switch spec.(type) {
+	case *mockFilterSpec, *filtertest.Filter:
+		return true
+	default:
+		return false
+	}
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// DumpEffectiveRoutes see Matcher.DumpEffectiveRoutes
+func (f *matcher) DumpEffectiveRoutes(w io.Writer, format Format) error {
+	if f.isClosed() {
+		return ErrClosed
+	}
+	return writeEffectiveRoutes(w, format, f.effectiveRoutes())
+}
+
+// writeEffectiveRoutes writes the routes in eskip, the annotations and the
+// dropped routes as comments, or in json
+func writeEffectiveRoutes(w io.Writer, format Format, e *EffectiveRoutes) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(e)
+	case FormatEskip, "":
+	default:
+		return fmt.Errorf("unsupported format %q, use %s or %s", format, FormatEskip, FormatJSON)
+	}
+
+	var b strings.Builder
+	for i, r := range e.Routes {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, a := range r.Annotations {
+			fmt.Fprintf(&b, "// %s\n", a)
+		}
+		fmt.Fprintf(&b, "%s: %s;\n", r.ID, r.route.String())
+	}
+	if len(e.Dropped) > 0 {
+		b.WriteString("\n")
+	}
+	for _, d := range e.Dropped {
+		fmt.Fprintf(&b, "// dropped %s: %s\n", d.ID, d.Reason)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,26 @@
+package matcher
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpEffectiveRoutes(t *testing.T) {
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, DefaultFiltersAppend: `setResponseHeader("X-Test", "1")`})
+	require.NoError(t, err)
+	defer m.Close()
+
+	var out bytes.Buffer
+	require.NoError(t, m.DumpEffectiveRoutes(&out, FormatEskip))
+	assert.Contains(t, out.String(), `// default filters: setResponseHeader("X-Test", "1")
+bar: Path("/bar") -> setResponseHeader("X-Test", "1") -> <shunt>;
+`)
+	assert.Contains(t, out.String(), "// mocked filters: customfilter\ncustomfilter: ")
+
+	assert.Error(t, m.DumpEffectiveRoutes(&out, Format("yaml")))
+	m.Close()
+	assert.Equal(t, ErrClosed, m.DumpEffectiveRoutes(&out, FormatJSON))
+}
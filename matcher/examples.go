@@ -0,0 +1,250 @@
+package matcher
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// NoExample example path of the routes whose PathRegexp has no simple
+// sample, see ExamplePaths
+const NoExample = "no example"
+
+// exampleParam value of the path parameters in the concrete example paths
+const exampleParam = "42"
+
+// maxRegexpExamples maximum number of samples of a PathRegexp
+const maxRegexpExamples = 4
+
+// ExamplePaths loads the routes file and returns example paths for each route
+// by route id: for a Path or PathSubtree pattern with wildcards a template,
+// the :params and *splats replaced by placeholders, eg. /users/{example-id},
+// and a concrete path, eg. /users/42, and a sub-path for a PathSubtree. A
+// route with only a PathRegexp gets samples of its simple regexps, literal
+// alternations included, eg. ^/(users|groups)$ gives /users and /groups, the
+// other regexps NoExample. A route with both gets the paths the regexp
+// matches. The parameters of the concrete paths are 42 unless
+// Options.Generate sets the placeholders, a copy of the optional parts of the
+// regexps is sampled with Options.Generate.Optional. A syntax error is
+// returned as a *ParseError.
+func ExamplePaths(o *Options) (map[string][]string, error) {
+	c, err := openFile(o.RoutesFile, o.OnLoadProgress)
+	if err != nil {
+		return nil, err
+	}
+	examples := make(map[string][]string, len(c.routes))
+	for _, r := range c.routes {
+		examples[r.Id] = examplePaths(r, o.Generate)
+	}
+	return examples, nil
+}
+
+// examplePaths the example paths of a route
+func examplePaths(r *eskip.Route, o GenerateOptions) []string {
+	var paths, regexps []string
+	for _, p := range Predicates(r) {
+		arg, ok := stringArg(p, 0)
+		if !ok {
+			continue
+		}
+		switch p.Name {
+		case pathPredicate:
+			paths = patternExamples(arg, o.Placeholders)
+		case pathSubtreePredicate:
+			paths = patternExamples(arg, o.Placeholders)
+			sub := strings.TrimSuffix(paths[len(paths)-1], "/") + "/" + sampleValue
+			paths = append(paths, sub)
+		case pathRegexpPredicate:
+			regexps = append(regexps, arg)
+		}
+	}
+	if len(regexps) == 0 {
+		if len(paths) == 0 {
+			return []string{"/"}
+		}
+		return paths
+	}
+
+	if len(paths) == 0 {
+		for _, s := range regexpSamples(regexps[0], o.Optional) {
+			if !strings.HasPrefix(s, "/") {
+				s = "/" + s
+			}
+			paths = append(paths, s)
+		}
+	}
+	var matching []string
+	for _, p := range paths {
+		if matchesAll(regexps, p) {
+			matching = append(matching, p)
+		}
+	}
+	if len(matching) == 0 {
+		return []string{NoExample}
+	}
+	return matching
+}
+
+// patternExamples the template and the concrete path of a path pattern with
+// wildcards, the path itself without. The concrete values are in the style,
+// 42 by default.
+func patternExamples(pattern string, style PlaceholderStyle) []string {
+	segments := strings.Split(pattern, "/")
+	template := make([]string, len(segments))
+	concrete := make([]string, len(segments))
+	wildcards := 0
+	for i, s := range segments {
+		template[i], concrete[i] = s, s
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			wildcards++
+			name := s[1:]
+			if name == "" {
+				name = "value"
+			}
+			template[i] = "{" + sampleValue + "-" + name + "}"
+			switch style {
+			case PlaceholderExample:
+				concrete[i] = sampleValue + "-" + name
+			case PlaceholderBraces:
+				concrete[i] = "{" + name + "}"
+			case PlaceholderNumeric:
+				concrete[i] = strconv.Itoa(wildcards)
+			default:
+				concrete[i] = exampleParam
+				if strings.HasPrefix(s, "*") {
+					concrete[i] = sampleValue + "/" + exampleParam
+				}
+			}
+		}
+	}
+	path := func(segments []string) string {
+		p := strings.Join(segments, "/")
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		return p
+	}
+	if wildcards == 0 {
+		return []string{path(template)}
+	}
+	return []string{path(template), path(concrete)}
+}
+
+// matchesAll true if all the regexps match the path, an invalid one matches nothing
+func matchesAll(exprs []string, path string) bool {
+	for _, expr := range exprs {
+		rx, err := regexp.Compile(expr)
+		if err != nil || !rx.MatchString(path) {
+			return false
+		}
+	}
+	return true
+}
+
+// regexpSamples samples of a simple regexp, one for each alternative up to
+// maxRegexpExamples, none when the regexp isn't simple, eg. with a . or an
+// unbounded class. The optional parts are sampled with a copy with optional.
+func regexpSamples(expr string, optional bool) []string {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	samples, ok := simpleSamples(re.Simplify(), optional)
+	if !ok {
+		return nil
+	}
+	rx := regexp.MustCompile(expr)
+	var valid []string
+	for _, s := range samples {
+		if rx.MatchString(s) && !contains(valid, s) {
+			valid = append(valid, s)
+		}
+	}
+	return valid
+}
+
+// simpleSamples the strings matched by re, false when it isn't simple
+func simpleSamples(re *syntax.Regexp, optional bool) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}, true
+	case syntax.OpCharClass:
+		// a negated class has the ranges of the whole unicode
+		if len(re.Rune) == 0 || re.Rune[len(re.Rune)-1] == '\U0010FFFF' {
+			return nil, false
+		}
+		r, _ := classSample(re.Rune)
+		return []string{string(r)}, true
+	case syntax.OpCapture, syntax.OpPlus:
+		return simpleSamples(re.Sub[0], optional)
+	case syntax.OpRepeat:
+		sub, ok := simpleSamples(re.Sub[0], optional)
+		if !ok {
+			return nil, false
+		}
+		samples := []string{""}
+		n := re.Min
+		if optional && n == 0 && re.Max != 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			samples = product(samples, sub[:1])
+		}
+		return samples, true
+	case syntax.OpConcat:
+		samples := []string{""}
+		for _, sub := range re.Sub {
+			s, ok := simpleSamples(sub, optional)
+			if !ok {
+				return nil, false
+			}
+			samples = product(samples, s)
+		}
+		return samples, true
+	case syntax.OpAlternate:
+		var samples []string
+		for _, sub := range re.Sub {
+			s, ok := simpleSamples(sub, optional)
+			if !ok {
+				return nil, false
+			}
+			samples = append(samples, s...)
+		}
+		if len(samples) > maxRegexpExamples {
+			samples = samples[:maxRegexpExamples]
+		}
+		return samples, true
+	case syntax.OpStar, syntax.OpQuest:
+		sub, ok := simpleSamples(re.Sub[0], optional)
+		if !ok {
+			return nil, false
+		}
+		if optional {
+			return sub[:1], true
+		}
+		return []string{""}, true
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return []string{""}, true
+	default:
+		// any char, no match
+		return nil, false
+	}
+}
+
+// product the concatenations of the prefixes and the suffixes, up to
+// maxRegexpExamples
+func product(prefixes, suffixes []string) []string {
+	var p []string
+	for _, prefix := range prefixes {
+		for _, suffix := range suffixes {
+			if len(p) < maxRegexpExamples {
+				p = append(p, prefix+suffix)
+			}
+		}
+	}
+	return p
+}
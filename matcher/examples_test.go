@@ -0,0 +1,87 @@
+package matcher
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExamplePaths(t *testing.T) {
+	path := writeRoutes(t, `users: Path("/users/:id") -> <shunt>;
+orgUsers: Path("/orgs/:org/users/:id") -> <shunt>;
+static: Path("/static/*file") -> <shunt>;
+home: Path("/") -> <shunt>;
+api: PathSubtree("/api") -> <shunt>;
+resources: PathRegexp("^/(users|groups)/[0-9]{2}$") -> <shunt>;
+anything: PathRegexp("^/.*[.]json$") -> <shunt>;
+filtered: Path("/v/:version") && PathRegexp("^/v/42$") -> <shunt>;
+catchAll: * -> <shunt>;
+`)
+	defer os.Remove(path)
+
+	examples, err := ExamplePaths(&Options{RoutesFile: path})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"users":     {"/users/{example-id}", "/users/42"},
+		"orgUsers":  {"/orgs/{example-org}/users/{example-id}", "/orgs/42/users/42"},
+		"static":    {"/static/{example-file}", "/static/example/42"},
+		"home":      {"/"},
+		"api":       {"/api", "/api/example"},
+		"resources": {"/users/00", "/groups/00"},
+		"anything":  {NoExample},
+		"filtered":  {"/v/42"},
+		"catchAll":  {"/"},
+	}, examples)
+}
+
+func TestExamplePathsGenerateOptions(t *testing.T) {
+	file := writeRoutes(t, `orgUsers: Path("/orgs/:org/users/:id") -> <shunt>;
+versions: PathRegexp("^/v[0-9]?/users$") -> <shunt>;
+`)
+	defer os.Remove(file)
+
+	tests := []struct {
+		options GenerateOptions
+		want    map[string][]string
+	}{
+		{
+			options: GenerateOptions{Placeholders: PlaceholderExample},
+			want: map[string][]string{
+				"orgUsers": {"/orgs/{example-org}/users/{example-id}", "/orgs/example-org/users/example-id"},
+				"versions": {"/v/users"},
+			},
+		},
+		{
+			options: GenerateOptions{Placeholders: PlaceholderBraces, Optional: true},
+			want: map[string][]string{
+				"orgUsers": {"/orgs/{example-org}/users/{example-id}", "/orgs/{org}/users/{id}"},
+				"versions": {"/v0/users"},
+			},
+		},
+		{
+			options: GenerateOptions{Placeholders: PlaceholderNumeric},
+			want: map[string][]string{
+				"orgUsers": {"/orgs/{example-org}/users/{example-id}", "/orgs/1/users/2"},
+				"versions": {"/v/users"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.options.Placeholders), func(t *testing.T) {
+			examples, err := ExamplePaths(&Options{RoutesFile: file, Generate: tt.options})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, examples)
+		})
+	}
+}
+
+func TestExamplePathsParseError(t *testing.T) {
+	file := writeRoutes(t, `broken: Path("/") -> `)
+	defer os.Remove(file)
+
+	_, err := ExamplePaths(&Options{RoutesFile: file})
+	assert.IsType(t, &ParseError{}, err)
+}
@@ -0,0 +1,412 @@
+package matcher
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+// Names of the predicates skipper handles without a predicate spec
+const (
+	pathPredicate         = "Path"
+	pathSubtreePredicate  = "PathSubtree"
+	pathRegexpPredicate   = "PathRegexp"
+	hostPredicate         = "Host"
+	methodPredicate       = "Method"
+	headerPredicate       = "Header"
+	headerRegexpPredicate = "HeaderRegexp"
+)
+
+// PredicateVerdict outcome of evaluating a single route predicate against a request
+type PredicateVerdict struct {
+	// Predicate eskip representation of the predicate, eg. Path("/foo")
+	Predicate string `json:"predicate"`
+	// Name of the predicate
+	Name string `json:"name"`
+	// Matched true if the request satisfies the predicate
+	Matched bool `json:"matched"`
+	// Observed the request value the predicate was evaluated against, if known
+	Observed string `json:"observed,omitempty"`
+	// Error why the predicate couldn't be evaluated, eg. unknown predicate
+	Error string `json:"error,omitempty"`
+	// Captures the submatches of the regexp of a matching PathRegexp, Host
+	// or HeaderRegexp predicate, if any
+	Captures []string `json:"captures,omitempty"`
+}
+
+// NearMiss a route not matching a request together with
+// the verdict of each one of its predicates
+type NearMiss struct {
+	// Route the candidate route
+	Route *eskip.Route `json:"-"`
+	// RouteID id of the candidate route
+	RouteID string `json:"routeId"`
+	// Failed number of predicates the request doesn't satisfy
+	Failed int `json:"failed"`
+	// Verdicts one for each route predicate
+	Verdicts []PredicateVerdict `json:"verdicts"`
+}
+
+// Predicates returns all the predicates of a route in a normalized form:
+// the ones parsed into dedicated eskip.Route fields (Path, Host, PathRegexp,
+// Method, Header, HeaderRegexp) are returned as predicates too, so routes
+// can be inspected the same way no matter how they were defined.
+// Header predicates are sorted by name to keep the order stable.
+func Predicates(r *eskip.Route) []*eskip.Predicate {
+	var ps []*eskip.Predicate
+	if r.Path != "" {
+		ps = append(ps, &eskip.Predicate{Name: pathPredicate, Args: []interface{}{r.Path}})
+	}
+	for _, rx := range r.HostRegexps {
+		ps = append(ps, &eskip.Predicate{Name: hostPredicate, Args: []interface{}{rx}})
+	}
+	for _, rx := range r.PathRegexps {
+		ps = append(ps, &eskip.Predicate{Name: pathRegexpPredicate, Args: []interface{}{rx}})
+	}
+	if r.Method != "" {
+		ps = append(ps, &eskip.Predicate{Name: methodPredicate, Args: []interface{}{r.Method}})
+	}
+	for _, name := range sortedKeys(r.Headers) {
+		ps = append(ps, &eskip.Predicate{Name: headerPredicate, Args: []interface{}{name, r.Headers[name]}})
+	}
+	headerRegexpNames := make([]string, 0, len(r.HeaderRegexps))
+	for name := range r.HeaderRegexps {
+		headerRegexpNames = append(headerRegexpNames, name)
+	}
+	sort.Strings(headerRegexpNames)
+	for _, name := range headerRegexpNames {
+		for _, rx := range r.HeaderRegexps[name] {
+			ps = append(ps, &eskip.Predicate{Name: headerRegexpPredicate, Args: []interface{}{name, rx}})
+		}
+	}
+	return append(ps, r.Predicates...)
+}
+
+// Explain evaluates every loaded route against the request attributes and
+// returns up to n routes not matching the request, the ones failing the
+// fewest predicates first and, among them, the ones satisfying the most
+// predicates. A n <= 0 returns all of them.
+func (f *matcher) Explain(attributes *RequestAttributes, n int) []NearMiss {
+	if f.isClosed() {
+		return nil
+	}
+	if !f.options.AllowInvalidHeaders && validateHeaders(attributes.Headers) != nil {
+		return nil
+	}
+	req, err := createHTTPRequest(attributes)
+	if err != nil {
+		return nil
+	}
+
+	path := cleanPath(req.URL.Path, f.options.IgnoreTrailingSlash)
+	misses := []NearMiss{}
+	for _, r := range f.routes {
+		verdicts := evaluateRoute(r, req, path, f.predicates, f.options.IgnoreTrailingSlash)
+		failed := 0
+		for _, v := range verdicts {
+			if !v.Matched {
+				failed++
+			}
+		}
+		if failed == 0 {
+			continue
+		}
+		misses = append(misses, NearMiss{
+			Route:    r,
+			RouteID:  r.Id,
+			Failed:   failed,
+			Verdicts: verdicts,
+		})
+	}
+
+	sort.SliceStable(misses, func(i, j int) bool {
+		if misses[i].Failed != misses[j].Failed {
+			return misses[i].Failed < misses[j].Failed
+		}
+		if len(misses[i].Verdicts) != len(misses[j].Verdicts) {
+			return len(misses[i].Verdicts) > len(misses[j].Verdicts)
+		}
+		return misses[i].RouteID < misses[j].RouteID
+	})
+	if n > 0 && len(misses) > n {
+		misses = misses[:n]
+	}
+	return misses
+}
+
+// MatchPredicates evaluates a predicates expression, eg.
+// `Host(/^api[.]/) && QueryParam("debug")`, against the request attributes
+// without a routes file, with the same predicate specs a matcher created
+// with the options uses: the bundled, the custom and the mocked ones, and
+// the traffic mode. Matched when the request satisfies all of them. Fails
+// on an invalid expression, on unknown predicates and on predicates which
+// can't be created, eg. with invalid arguments.
+func MatchPredicates(expr string, attrs *RequestAttributes, o *Options) (bool, []PredicateVerdict, error) {
+	if o == nil {
+		o = &Options{}
+	}
+	o, err := withPlugins(o)
+	if err != nil {
+		return false, nil, err
+	}
+	ps, err := eskip.ParsePredicates(expr)
+	if err != nil {
+		return false, nil, fmt.Errorf("invalid predicates %q: %v", expr, err)
+	}
+	specs := mapPredicateSpecs(trafficModePredicates(o))
+	var unknown []string
+	for _, p := range ps {
+		if _, ok := specs[p.Name]; !ok && !isBuiltinPredicate(p.Name) {
+			unknown = append(unknown, p.Name)
+		}
+	}
+	if len(unknown) > 0 {
+		return false, nil, fmt.Errorf("unknown predicates %s, register them with CustomPredicates or mock them with MockPredicates", strings.Join(unknown, ", "))
+	}
+
+	req, err := createHTTPRequest(normalizeAttributes(attrs))
+	if err != nil {
+		return false, nil, err
+	}
+	if o.TrafficMode.kind != trafficRandom {
+		var discarded []string
+		req = withForcedTraffic(req, &discarded)
+	}
+	path := cleanPath(req.URL.Path, o.IgnoreTrailingSlash)
+	matched := true
+	verdicts := make([]PredicateVerdict, 0, len(ps))
+	for _, p := range ps {
+		v := evaluatePredicate(p, req, path, specs, o.IgnoreTrailingSlash)
+		if v.Error != "" {
+			return false, verdicts, fmt.Errorf("predicate %s: %s", v.Predicate, v.Error)
+		}
+		matched = matched && v.Matched
+		verdicts = append(verdicts, v)
+	}
+	return matched, verdicts, nil
+}
+
+// evaluateRoute evaluates each predicate of a route against the request,
+// path is the request path cleaned the same way skipper does before matching
+func evaluateRoute(r *eskip.Route, req *http.Request, path string, specs map[string]routing.PredicateSpec, ignoreTrailingSlash bool) []PredicateVerdict {
+	ps := Predicates(r)
+	verdicts := make([]PredicateVerdict, 0, len(ps))
+	for _, p := range ps {
+		verdicts = append(verdicts, evaluatePredicate(p, req, path, specs, ignoreTrailingSlash))
+	}
+	return verdicts
+}
+
+// evaluatePredicate evaluates a single predicate against the request
+func evaluatePredicate(p *eskip.Predicate, req *http.Request, path string, specs map[string]routing.PredicateSpec, ignoreTrailingSlash bool) PredicateVerdict {
+	v := PredicateVerdict{
+		Predicate: predicateString(p),
+		Name:      p.Name,
+	}
+
+	fail := func(format string, a ...interface{}) PredicateVerdict {
+		v.Error = fmt.Sprintf(format, a...)
+		return v
+	}
+
+	switch p.Name {
+	case pathPredicate, pathSubtreePredicate:
+		arg, ok := stringArg(p, 0)
+		if !ok || len(p.Args) != 1 {
+			return fail("invalid arguments")
+		}
+		v.Observed = path
+		if p.Name == pathPredicate {
+			_, v.Matched = matchPath(cleanPath(arg, ignoreTrailingSlash), path)
+		} else {
+			_, v.Matched = matchPathSubtree(cleanPath(arg, true), path)
+		}
+	case hostPredicate, pathRegexpPredicate:
+		arg, ok := stringArg(p, 0)
+		if !ok || len(p.Args) != 1 {
+			return fail("invalid arguments")
+		}
+		rx, err := regexp.Compile(arg)
+		if err != nil {
+			return fail("invalid regexp: %v", err)
+		}
+		v.Observed = path
+		if p.Name == hostPredicate {
+			v.Observed = req.Host
+		}
+		v.Matched, v.Captures = matchRegexp(rx, v.Observed)
+	case methodPredicate:
+		arg, ok := stringArg(p, 0)
+		if !ok || len(p.Args) != 1 {
+			return fail("invalid arguments")
+		}
+		v.Observed = req.Method
+		v.Matched = req.Method == arg
+	case headerPredicate, headerRegexpPredicate:
+		name, ok := stringArg(p, 0)
+		value, ok2 := stringArg(p, 1)
+		if !ok || !ok2 || len(p.Args) != 2 {
+			return fail("invalid arguments")
+		}
+		check := func(s string) (bool, []string) { return s == value, nil }
+		if p.Name == headerRegexpPredicate {
+			rx, err := regexp.Compile(value)
+			if err != nil {
+				return fail("invalid regexp: %v", err)
+			}
+			check = func(s string) (bool, []string) { return matchRegexp(rx, s) }
+		}
+		values := req.Header[http.CanonicalHeaderKey(name)]
+		v.Observed = strings.Join(values, ", ")
+		for _, hv := range values {
+			if v.Matched, v.Captures = check(hv); v.Matched {
+				break
+			}
+		}
+	default:
+		spec, ok := specs[p.Name]
+		if !ok {
+			return fail("predicate not found")
+		}
+		pr, err := spec.Create(p.Args)
+		if err != nil {
+			return fail("%v", err)
+		}
+		v.Matched = pr.Match(req)
+	}
+	return v
+}
+
+// matchRegexp matches s with rx, returns the submatches of the match
+func matchRegexp(rx *regexp.Regexp, s string) (bool, []string) {
+	m := rx.FindStringSubmatch(s)
+	if m == nil {
+		return false, nil
+	}
+	if len(m) == 1 {
+		return true, nil
+	}
+	return true, m[1:]
+}
+
+// String the predicate with its verdict and the observed value
+func (v PredicateVerdict) String() string {
+	var s string
+	switch {
+	case v.Error != "":
+		return fmt.Sprintf("%s: error, %s", v.Predicate, v.Error)
+	case v.Matched:
+		s = v.Predicate + ": match"
+	default:
+		s = v.Predicate + ": no match"
+	}
+	if v.Observed != "" {
+		s += fmt.Sprintf(", observed %q", v.Observed)
+	}
+	if len(v.Captures) > 0 {
+		s += fmt.Sprintf(", captures %q", v.Captures)
+	}
+	return s
+}
+
+// predicateString eskip representation of a predicate
+func predicateString(p *eskip.Predicate) string {
+	r := &eskip.Route{Predicates: []*eskip.Predicate{p}, BackendType: eskip.ShuntBackend}
+	s := r.String()
+	// strip the backend, the route is only used to format the predicate
+	return strings.TrimSuffix(s, " -> <shunt>")
+}
+
+func stringArg(p *eskip.Predicate, i int) (string, bool) {
+	if len(p.Args) <= i {
+		return "", false
+	}
+	s, ok := p.Args[i].(string)
+	return s, ok
+}
+
+// cleanPath normalizes a path like skipper does before matching it
+func cleanPath(path string, ignoreTrailingSlash bool) string {
+	if path == "" {
+		return "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	// keep the trailing slash, httppath.Clean does the same
+	trailing := len(path) > 1 && strings.HasSuffix(path, "/")
+	segments := []string{}
+	for _, s := range strings.Split(path, "/") {
+		switch s {
+		case "", ".":
+		case "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			segments = append(segments, s)
+		}
+	}
+	path = "/" + strings.Join(segments, "/")
+	if trailing && path != "/" && !ignoreTrailingSlash {
+		path += "/"
+	}
+	return path
+}
+
+// matchPath matches a Path predicate pattern (supporting :param and
+// a final *wildcard segment) against a cleaned request path
+func matchPath(pattern, path string) (map[string]string, bool) {
+	params := make(map[string]string)
+	ps := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	xs := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, p := range ps {
+		switch {
+		case strings.HasPrefix(p, "*") && i == len(ps)-1:
+			if len(xs) < i+1 {
+				return nil, false
+			}
+			params[p[1:]] = "/" + strings.Join(xs[i:], "/")
+			return params, true
+		case i >= len(xs):
+			return nil, false
+		case strings.HasPrefix(p, ":"):
+			if xs[i] == "" {
+				return nil, false
+			}
+			params[p[1:]] = xs[i]
+		case p != xs[i]:
+			return nil, false
+		}
+	}
+	if len(ps) != len(xs) {
+		return nil, false
+	}
+	return params, true
+}
+
+// matchPathSubtree matches a PathSubtree predicate pattern against a cleaned request path
+func matchPathSubtree(pattern, path string) (map[string]string, bool) {
+	if pattern == "/" {
+		return map[string]string{"*": path}, true
+	}
+
+	// named free wildcards are part of the subtree pattern
+	wildcard := "*"
+	if i := strings.LastIndex(pattern, "/*"); i >= 0 {
+		wildcard = pattern[i+2:]
+		pattern = pattern[:i]
+	}
+
+	if params, ok := matchPath(pattern, strings.TrimSuffix(path, "/")); ok {
+		params[wildcard] = "/"
+		return params, true
+	}
+	return matchPath(pattern+"/*"+wildcard, path)
+}
@@ -0,0 +1,153 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zalando/skipper/eskip"
+)
+
+func TestExplain(t *testing.T) {
+	m, err := New(&Options{
+		RoutesFile:  "./testdata/routes.eskip",
+		MockFilters: []string{"customfilter"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	misses := m.Explain(&RequestAttributes{
+		Method: "POST",
+		Path:   "/host",
+		Host:   "www.example.org",
+	}, 2)
+
+	if !assert.Len(t, misses, 2) {
+		return
+	}
+	assert.Equal(t, "host", misses[0].RouteID)
+	assert.Equal(t, 1, misses[0].Failed)
+	assert.Equal(t, []PredicateVerdict{
+		{Predicate: `Path("/host")`, Name: "Path", Matched: true, Observed: "/host"},
+		{Predicate: `Host("^api[.]example[.]org$")`, Name: "Host", Matched: false, Observed: "www.example.org"},
+	}, misses[0].Verdicts)
+
+	all := m.Explain(&RequestAttributes{Path: "/bar"}, 0)
+	for _, miss := range all {
+		assert.NotEqual(t, "bar", miss.RouteID, "matching routes aren't near misses")
+		assert.True(t, miss.Failed > 0)
+	}
+}
+
+func TestEvaluatePredicate(t *testing.T) {
+	m, err := New(&Options{
+		RoutesFile:  "./testdata/routes.eskip",
+		MockFilters: []string{"customfilter"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := m.(*matcher)
+
+	tests := []struct {
+		predicates string
+		attrs      *RequestAttributes
+		matched    bool
+		observed   string
+		err        bool
+	}{
+		{`Path("/foo/:id")`, &RequestAttributes{Path: "/foo/1"}, true, "/foo/1", false},
+		{`Path("/foo/:id")`, &RequestAttributes{Path: "/foo/1/2"}, false, "/foo/1/2", false},
+		{`Path("/foo/*rest")`, &RequestAttributes{Path: "/foo/1/2"}, true, "/foo/1/2", false},
+		{`PathSubtree("/foo")`, &RequestAttributes{Path: "/foo"}, true, "/foo", false},
+		{`PathSubtree("/foo")`, &RequestAttributes{Path: "/foo/bar/baz"}, true, "/foo/bar/baz", false},
+		{`PathSubtree("/foo")`, &RequestAttributes{Path: "/foobar"}, false, "/foobar", false},
+		{`PathRegexp("^/a+$")`, &RequestAttributes{Path: "/aaa"}, true, "/aaa", false},
+		{`Method("PUT")`, &RequestAttributes{Method: "put"}, true, "PUT", false},
+		{`Header("X-Foo", "bar")`, &RequestAttributes{Headers: map[string]string{"x-foo": "bar"}}, true, "bar", false},
+		{`HeaderRegexp("X-Foo", "^b")`, &RequestAttributes{Headers: map[string]string{"X-Foo": "car"}}, false, "car", false},
+		{`QueryParam("q")`, &RequestAttributes{Query: map[string]string{"q": "1"}}, true, "", false},
+		{`Unknown("q")`, &RequestAttributes{}, false, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.predicates, func(t *testing.T) {
+			ps, err := eskip.ParsePredicates(tt.predicates)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := &eskip.Route{Predicates: ps}
+			req, err := createHTTPRequest(tt.attrs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := cleanPath(req.URL.Path, false)
+			verdicts := evaluateRoute(r, req, path, f.predicates, false)
+			if !assert.Len(t, verdicts, 1) {
+				return
+			}
+			assert.Equal(t, tt.matched, verdicts[0].Matched)
+			assert.Equal(t, tt.observed, verdicts[0].Observed)
+			assert.Equal(t, tt.err, verdicts[0].Error != "")
+		})
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	routes, err := eskip.Parse(`r: Path("/a") && Host(/^h$/) && Method("GET") && Header("B", "2") && Header("A", "1") && Cookie("c", "v") -> <shunt>;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, p := range Predicates(routes[0]) {
+		names = append(names, predicateString(p))
+	}
+	assert.Equal(t, []string{
+		`Path("/a")`,
+		`Host("^h$")`,
+		`Method("GET")`,
+		`Header("A", "1")`,
+		`Header("B", "2")`,
+		`Cookie("c", "v")`,
+	}, names)
+}
+
+func TestMatchPredicates(t *testing.T) {
+	attrs := &RequestAttributes{Path: "/items", Host: "api.example.org", Query: map[string]string{"debug": "1"}}
+	matched, verdicts, err := MatchPredicates(`Host(/^api[.]/) && QueryParam("debug")`, attrs, nil)
+	assert.NoError(t, err)
+	assert.True(t, matched)
+	if assert.Len(t, verdicts, 2) {
+		assert.Equal(t, "api.example.org", verdicts[0].Observed)
+		assert.True(t, verdicts[1].Matched)
+	}
+
+	matched, verdicts, err = MatchPredicates(`Path("/items") && Method("POST")`, attrs, nil)
+	assert.NoError(t, err)
+	assert.False(t, matched)
+	if assert.Len(t, verdicts, 2) {
+		assert.True(t, verdicts[0].Matched)
+		assert.False(t, verdicts[1].Matched)
+	}
+
+	matched, _, err = MatchPredicates(`TenantIs("acme")`, attrs, &Options{CustomPredicates: MockPredicates([]string{"TenantIs"})})
+	assert.NoError(t, err)
+	assert.True(t, matched, "the mocked predicates match")
+
+	_, _, err = MatchPredicates(`TenantIs("acme")`, attrs, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unknown predicates TenantIs")
+	}
+	_, _, err = MatchPredicates(`Path(`, attrs, nil)
+	assert.Error(t, err)
+	_, _, err = MatchPredicates(`Traffic("half")`, attrs, nil)
+	assert.Error(t, err, "invalid arguments")
+}
+
+func TestCleanPath(t *testing.T) {
+	assert.Equal(t, "/", cleanPath("", false))
+	assert.Equal(t, "/a/c", cleanPath("a//b/../c", false))
+	assert.Equal(t, "/a/", cleanPath("/a/", false))
+	assert.Equal(t, "/a", cleanPath("/a/", true))
+	assert.Equal(t, "/", cleanPath("/", true))
+}
@@ -0,0 +1,260 @@
+package matcher
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+// progressInterval bytes parsed between two OnLoadProgress calls
+const progressInterval = 256 << 10
+
+// fileDataClient data client with the routes of an eskip file, parsed
+// route by route while the file is read. Like skipper eskipfile it
+// doesn't watch the file.
+type fileDataClient struct {
+	routes []*eskip.Route
+	// definitions of the routes in order
+	definitions []routeDefinition
+	// locations of the route definitions by route id
+	locations map[string]Location
+	// read, parse time spent reading and parsing the file
+	read, parse time.Duration
+}
+
+// RoutesFileError the routes file can't be opened or read, it matches
+// ErrRoutesFileNotFound or ErrRoutesFileUnreadable with errors.Is
+type RoutesFileError struct {
+	// Path of the routes file
+	Path string
+	// Err the error of the file system, eg. a *os.PathError
+	Err error
+}
+
+func (e *RoutesFileError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the error of the file system
+func (e *RoutesFileError) Unwrap() error { return e.Err }
+
+// Is matches ErrRoutesFileNotFound when the file or the target of its
+// symlink doesn't exist, ErrRoutesFileUnreadable otherwise
+func (e *RoutesFileError) Is(target error) bool {
+	notFound := errors.Is(e.Err, os.ErrNotExist) || errors.Is(e.Err, ErrRoutesFileBrokenSymlink)
+	switch target {
+	case ErrRoutesFileNotFound:
+		return notFound
+	case ErrRoutesFileUnreadable:
+		return !notFound && !errors.Is(e.Err, ErrRoutesFileEmpty)
+	}
+	return false
+}
+
+// checkRoutesFile fails with a *RoutesFileError when the routes file is a
+// directory, a symlink to a missing target or an empty file
+func checkRoutesFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return &RoutesFileError{Path: path, Err: err}
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		if info, err = os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			target, _ := os.Readlink(path)
+			return &RoutesFileError{Path: path, Err: fmt.Errorf("%s %w: %s", path, ErrRoutesFileBrokenSymlink, target)}
+		} else if err != nil {
+			return &RoutesFileError{Path: path, Err: err}
+		}
+	}
+	switch {
+	case info.IsDir():
+		return &RoutesFileError{Path: path, Err: fmt.Errorf("%s %w", path, ErrRoutesFileIsDirectory)}
+	case info.Mode().IsRegular() && info.Size() == 0:
+		return &RoutesFileError{Path: path, Err: fmt.Errorf("%s %w", path, ErrRoutesFileEmpty)}
+	}
+	return nil
+}
+
+// routesFileError classifies an error of openFile, the parse errors are
+// returned as they are
+func routesFileError(path string, err error) error {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return err
+	}
+	return &RoutesFileError{Path: path, Err: err}
+}
+
+// openFile reads and parses an eskip file, progress is called periodically
+// with the bytes parsed so far and the file size when not nil
+func openFile(path string, progress func(parsed int, total int64)) (*fileDataClient, error) {
+	start := time.Now()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &fileDataClient{locations: make(map[string]Location)}
+	parsed, reported, line := 0, 0, 1
+	err = splitRouteDefinitions(bufio.NewReaderSize(f, 64<<10), func(def string) error {
+		parseStart := time.Now()
+		routes, err := eskip.Parse(def)
+		c.parse += time.Since(parseStart)
+		if err != nil {
+			return err
+		}
+		c.routes = append(c.routes, routes...)
+		// the chunk starts after the ';' of the previous definition, the
+		// directives on that line belong to it
+		if n := len(c.definitions); n > 0 {
+			for _, s := range leadingSuppressions([]byte(def)) {
+				c.definitions[n-1].suppressed = append(c.definitions[n-1].suppressed, suppression{s.rule, line})
+			}
+		}
+		for _, d := range scanRouteDefinitions(path, []byte(def)) {
+			if len(c.definitions) > 0 && d.location.Line > 1 {
+				for len(d.suppressed) > 0 && d.suppressed[0].line == 1 {
+					d.suppressed = d.suppressed[1:]
+				}
+				for len(d.comments) > 0 && d.comments[0].line == 1 {
+					d.comments = d.comments[1:]
+				}
+			}
+			for i := range d.suppressed {
+				d.suppressed[i].line += line - 1
+			}
+			for i := range d.comments {
+				d.comments[i].line += line - 1
+			}
+			d.location.Line += line - 1
+			c.definitions = append(c.definitions, d)
+			if _, ok := c.locations[d.id]; !ok {
+				c.locations[d.id] = d.location
+			}
+		}
+		line += strings.Count(def, "\n")
+		parsed += len(def)
+		if progress != nil && parsed-reported >= progressInterval {
+			reported = parsed
+			progress(parsed, info.Size())
+		}
+		return nil
+	})
+	if err != nil {
+		return parseWholeFile(path)
+	}
+	if progress != nil {
+		progress(parsed, info.Size())
+	}
+	c.read = time.Since(start) - c.parse
+	return c, nil
+}
+
+// routesDocument the file of the locations of the routes of Options.Routes
+const routesDocument = "<routes>"
+
+// parseWholeFile parses the file at once like skipper eskipfile, used
+// when a route definition doesn't parse on its own: the errors are the
+// ones of the whole document
+func parseWholeFile(path string) (*fileDataClient, error) {
+	start := time.Now()
+	doc, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDocument(path, doc, time.Since(start))
+}
+
+// parseDocument parses an eskip document at once, the routes of path, read
+// in the given time
+func parseDocument(path string, doc []byte, read time.Duration) (*fileDataClient, error) {
+	start := time.Now()
+	routes, err := eskip.Parse(string(doc))
+	if err != nil {
+		return nil, newParseError(Document{File: path, Content: doc}, err)
+	}
+	return &fileDataClient{
+		routes:      routes,
+		definitions: scanRouteDefinitions(path, doc),
+		locations:   RouteLocations(path, doc),
+		read:        read,
+		parse:       time.Since(start),
+	}, nil
+}
+
+// LoadAll returns the parsed routes
+func (c *fileDataClient) LoadAll() ([]*eskip.Route, error) { return c.routes, nil }
+
+// LoadUpdate noop, the file isn't watched
+func (c *fileDataClient) LoadUpdate() ([]*eskip.Route, []string, error) { return nil, nil, nil }
+
+// splitRouteDefinitions reads an eskip document and calls fn with each route
+// definition up to its ';', then with the text after the last one if any.
+// Like the scanner of RouteLocations it skips comments, strings and regexps.
+func splitRouteDefinitions(r *bufio.Reader, fn func(def string) error) error {
+	var def strings.Builder
+	// quote of the string or regexp being read, 0 outside
+	var quote rune
+	comment, escaped := false, false
+	for {
+		c, _, err := r.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		def.WriteRune(c)
+
+		switch {
+		case comment:
+			comment = c != '\n'
+		case quote != 0:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
+		case c == '/':
+			if next, err := r.Peek(1); err == nil && next[0] == '/' {
+				comment = true
+			} else {
+				quote = c
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case c == ';':
+			if err := fn(def.String()); err != nil {
+				return err
+			}
+			def.Reset()
+		}
+	}
+	if def.Len() > 0 {
+		return fn(def.String())
+	}
+	return nil
+}
+
+// singleFile returns the data client of a routes file read by openFile
+func singleFile(clients []routing.DataClient) (*fileDataClient, bool) {
+	if len(clients) != 1 {
+		return nil, false
+	}
+	c, ok := clients[0].(*fileDataClient)
+	return c, ok
+}
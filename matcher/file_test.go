@@ -0,0 +1,139 @@
+package matcher
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/eskipfile"
+	"github.com/zalando/skipper/routing"
+)
+
+// trickyRoutes route definitions with ';' and comments in strings, regexps and backticks
+const trickyRoutes = `// leading comment; with a semicolon
+semicolon: Path("/a;b") -> setPath("/c;d") -> <shunt>;
+regexp: PathRegexp(/^\/re;[a-z]+\/?$/) -> <shunt>; // trailing; comment
+escaped: Header("X-Quote", "a\";b") -> "https://example.org/;";
+backtick: Path("/tick") -> setRequestHeader("X-Raw", ` + "`a;b`" + `) -> <shunt>;
+// commented: Path("/commented") -> <shunt>;
+twice: Method("POST") && Path("/twice") -> <shunt>; last: Path("/last") -> <shunt>
+`
+
+// writeRoutes writes doc to a temporary routes file, it returns its path
+func writeRoutes(t testing.TB, doc string) string {
+	f, err := ioutil.TempFile("", "routes-*.eskip")
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(doc)
+	require.NoError(t, err)
+	return f.Name()
+}
+
+// largeRoutes returns a document of n route definitions
+func largeRoutes(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "// route %d; generated\n", i)
+		fmt.Fprintf(&b, "r%d: Path(\"/r/%d;x\") && Header(\"X-Tenant\", \"t%d\") -> setPath(`/s;%d`) -> <shunt>;\n", i, i, i%7, i)
+	}
+	return b.String() + trickyRoutes
+}
+
+func TestOpenFileSameAsEskipParse(t *testing.T) {
+	for name, doc := range map[string]string{
+		"tricky": trickyRoutes,
+		"large":  largeRoutes(5000),
+		"empty":  "// no routes\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := writeRoutes(t, doc)
+			defer os.Remove(path)
+
+			var calls []int
+			var totals []int64
+			c, err := openFile(path, func(parsed int, total int64) {
+				calls = append(calls, parsed)
+				totals = append(totals, total)
+			})
+			require.NoError(t, err)
+
+			want, err := eskip.Parse(doc)
+			require.NoError(t, err)
+			assert.Equal(t, len(want), len(c.routes))
+			assert.Equal(t, eskip.Print(eskip.PrettyPrintInfo{}, want...), eskip.Print(eskip.PrettyPrintInfo{}, c.routes...))
+			assert.Equal(t, RouteLocations(path, []byte(doc)), c.locations)
+
+			require.NotEmpty(t, calls)
+			for i := 1; i < len(calls); i++ {
+				assert.True(t, calls[i] > calls[i-1], "the progress must increase")
+			}
+			assert.Equal(t, len(doc), calls[len(calls)-1])
+			assert.EqualValues(t, len(doc), totals[len(totals)-1])
+		})
+	}
+}
+
+func TestOpenFileProgressInterval(t *testing.T) {
+	doc := largeRoutes(10000)
+	path := writeRoutes(t, doc)
+	defer os.Remove(path)
+
+	calls := 0
+	_, err := openFile(path, func(int, int64) { calls++ })
+	require.NoError(t, err)
+	assert.True(t, calls > 1, "a large file must report its progress while it's parsed")
+	assert.True(t, calls <= len(doc)/progressInterval+1)
+}
+
+func TestOpenFileErrors(t *testing.T) {
+	_, err := openFile("testdata/missing.eskip", nil)
+	assert.True(t, os.IsNotExist(err))
+
+	doc := "a: Path(\"/a\") -> <shunt>;\nb: Path(\"/b\" -> <shunt>;\n"
+	path := writeRoutes(t, doc)
+	defer os.Remove(path)
+	_, err = openFile(path, nil)
+	require.IsType(t, &ParseError{}, err)
+	e := err.(*ParseError)
+	assert.Equal(t, path, e.File)
+	assert.Equal(t, 2, e.Line, "the error must be positioned in the whole document")
+	assert.Equal(t, 14, e.Column)
+	assert.Equal(t, "->", e.Token)
+}
+
+func TestNewStreamedSameMatches(t *testing.T) {
+	doc := largeRoutes(2000)
+	path := writeRoutes(t, doc)
+	defer os.Remove(path)
+
+	streamed, err := New(&Options{RoutesFile: path})
+	require.NoError(t, err)
+	defer streamed.Close()
+
+	create := newDataClients
+	defer func() { newDataClients = create }()
+	newDataClients = func(o *Options) ([]routing.DataClient, error) {
+		client, err := eskipfile.Open(o.RoutesFile)
+		if err != nil {
+			return nil, err
+		}
+		return []routing.DataClient{client}, nil
+	}
+	whole, err := New(&Options{RoutesFile: path})
+	require.NoError(t, err)
+	defer whole.Close()
+
+	assert.Equal(t, whole.RouteCount(), streamed.RouteCount())
+	routes, err := eskip.Parse(doc)
+	require.NoError(t, err)
+	for _, req := range append(SeedRequests(routes), &RequestAttributes{Path: "/unknown"}) {
+		want, got := whole.Test(req), streamed.Test(req)
+		require.NoError(t, got.Err())
+		assert.Equal(t, routeID(want), routeID(got), "%s %s", req.Method, req.Path)
+	}
+}
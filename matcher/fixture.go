@@ -0,0 +1,64 @@
+package matcher
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// fixtureCase a case of a suite fixture file, see the suite package
+type fixtureCase struct {
+	Name    string             `yaml:"name"`
+	Request *RequestAttributes `yaml:"request"`
+	Expect  fixtureExpect      `yaml:"expect"`
+}
+
+// fixtureExpect the expectation of a fixtureCase
+type fixtureExpect struct {
+	Route   string `yaml:"route,omitempty"`
+	NoMatch bool   `yaml:"noMatch,omitempty"`
+}
+
+// GenerateFixture loads the routes file and writes a suite fixture file with
+// a case for each route, named after it, with the request generated for the
+// route expecting it to match, see GenerateAll. The cases of the routes whose
+// request needs manual values, with predicates it can't satisfy or not
+// matching the route, are commented out after a TODO telling why. The Traffic
+// predicates always match with TrafficRandom. Options.Lazy is ignored.
+func GenerateFixture(o *Options, w io.Writer) error {
+	if o.TrafficMode == TrafficRandom {
+		c := *o
+		c.TrafficMode = TrafficAlways
+		o = &c
+	}
+	cases, err := GenerateAll(o)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# generated by eskip-match from %s\nname: generated\ncases:\n", o.RoutesFile)
+	for _, c := range cases {
+		// a list of a single case, the same as an item of the cases
+		y, err := yaml.Marshal([]fixtureCase{{Name: c.RouteID, Request: c.Request, Expect: fixtureExpect{Route: c.RouteID}}})
+		if err != nil {
+			return err
+		}
+		todos := generatedTODOs(c)
+		if len(todos) == 0 {
+			b.Write(y)
+			continue
+		}
+		for _, todo := range todos {
+			fmt.Fprintf(&b, "# TODO %s\n", todo)
+		}
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(string(y), "\n"), "\n") {
+			b.WriteString("# " + line)
+		}
+		b.WriteString("\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,53 @@
+package matcher
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateFixture(t *testing.T) {
+	file := writeRoutes(t, `orders: Host(/^api[.]example[.]org$/) && Path("/orders/:id") && Method("GET") -> <shunt>;
+canary: Path("/canary") && Traffic(0.1) -> <shunt>;
+v1: Path("/v1") -> <shunt>;
+shadowed: PathRegexp(/^\/v1/) -> <shunt>;
+`)
+	defer os.Remove(file)
+
+	var b bytes.Buffer
+	require.NoError(t, GenerateFixture(&Options{RoutesFile: file}, &b))
+	assert.Equal(t, `# generated by eskip-match from `+file+`
+name: generated
+cases:
+- name: orders
+  request:
+    method: GET
+    path: /orders/example-id
+    host: api.example.org
+  expect:
+    route: orders
+# TODO canary: set a value satisfying Traffic(0.1): matches a random share of the requests
+# - name: canary
+#   request:
+#     method: GET
+#     path: /canary
+#   expect:
+#     route: canary
+- name: v1
+  request:
+    method: GET
+    path: /v1
+  expect:
+    route: v1
+# TODO shadowed: the generated request matches route v1 instead
+# - name: shadowed
+#   request:
+#     method: GET
+#     path: /v1
+#   expect:
+#     route: shadowed
+`, b.String())
+}
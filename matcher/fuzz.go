@@ -0,0 +1,343 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// DefaultFuzzIterations default number of requests tested by Fuzz
+const DefaultFuzzIterations = 10000
+
+// fuzzMethods methods a mutated request can get
+var fuzzMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// FuzzOptions options of Fuzz
+type FuzzOptions struct {
+	// Seed of the random mutations, the same seed and iterations test the
+	// same requests. The Traffic predicates decide with the TrafficMode of
+	// the matcher, TrafficSeeded keeps the whole run deterministic.
+	Seed int64
+	// Iterations number of requests tested, DefaultFuzzIterations when 0
+	Iterations int
+	// Seeds requests mutated in addition to the ones generated for the
+	// routes, see GenerateRequest
+	Seeds []*RequestAttributes
+}
+
+// FuzzReport coverage of the routes by the requests tested by Fuzz
+type FuzzReport struct {
+	// Iterations number of requests tested
+	Iterations int `json:"iterations" yaml:"iterations"`
+	// Hits number of requests matching each route by route id, 0 for the
+	// routes never matched
+	Hits map[string]int `json:"hits" yaml:"hits"`
+	// NoMatch number of requests matching no route
+	NoMatch int `json:"noMatch" yaml:"noMatch"`
+	// Unreached ids of the routes no request matched, sorted
+	Unreached []string `json:"unreached" yaml:"unreached"`
+	// Corpus the first request matching each route, in the order the
+	// routes were reached
+	Corpus []FuzzCase `json:"corpus" yaml:"corpus"`
+	// Panics the requests whose test panicked, eg. in a custom predicate
+	Panics []FuzzPanic `json:"panics,omitempty" yaml:"panics,omitempty"`
+}
+
+// FuzzCase the first request Fuzz tested matching a route
+type FuzzCase struct {
+	RouteID string             `json:"routeId" yaml:"routeId"`
+	Request *RequestAttributes `json:"request" yaml:"request"`
+}
+
+// FuzzPanic a request whose test panicked
+type FuzzPanic struct {
+	Request *RequestAttributes `json:"request" yaml:"request"`
+	// Value the value of the panic
+	Value string `json:"value" yaml:"value"`
+}
+
+// histogramWidth width of the longest bar of FuzzReport.Histogram
+const histogramWidth = 40
+
+// Histogram the hits of the routes by route id, with a bar scaled to the
+// most matched route, and the requests matching no route last
+func (r *FuzzReport) Histogram() []string {
+	ids := make([]string, 0, len(r.Hits))
+	idWidth, max := len("<no match>"), r.NoMatch
+	for id, hits := range r.Hits {
+		ids = append(ids, id)
+		if len(id) > idWidth {
+			idWidth = len(id)
+		}
+		if hits > max {
+			max = hits
+		}
+	}
+	sort.Strings(ids)
+	line := func(id string, hits int) string {
+		bar := 0
+		if max > 0 {
+			bar = (hits*histogramWidth + max - 1) / max
+		}
+		return strings.TrimRight(fmt.Sprintf("%-*s %6d %s", idWidth, id, hits, strings.Repeat("#", bar)), " ")
+	}
+	lines := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		lines = append(lines, line(id, r.Hits[id]))
+	}
+	return append(lines, line("<no match>", r.NoMatch))
+}
+
+// Fuzz tests semi-random requests against the matcher and reports how many
+// match each route: the requests generated for the routes and the seeds are
+// mutated, their path segments replaced by the ones of the routes or random
+// ones, added or removed, their method, host, headers and query parameters
+// changed. The panics of the tests, eg. of a custom predicate, are
+// recovered and reported with the request.
+func Fuzz(m Matcher, o *FuzzOptions) (*FuzzReport, error) {
+	if ready, err := m.Ready(); !ready {
+		if err == nil {
+			err = ErrClosed
+		}
+		return nil, err
+	}
+	iterations := o.Iterations
+	if iterations <= 0 {
+		iterations = DefaultFuzzIterations
+	}
+	routes := m.Routes()
+	z := newFuzzer(o.Seed, append(SeedRequests(routes), o.Seeds...), collectFuzzDictionary(routes))
+
+	report := &FuzzReport{Iterations: iterations, Hits: make(map[string]int, len(routes))}
+	for _, r := range routes {
+		report.Hits[r.Id] = 0
+	}
+	for i := 0; i < iterations; i++ {
+		attrs := z.next()
+		id, matched, panicked := fuzzTest(m, attrs)
+		switch {
+		case panicked != "":
+			report.Panics = append(report.Panics, FuzzPanic{Request: attrs, Value: panicked})
+		case !matched:
+			report.NoMatch++
+		default:
+			if report.Hits[id] == 0 {
+				report.Corpus = append(report.Corpus, FuzzCase{RouteID: id, Request: attrs})
+			}
+			report.Hits[id]++
+		}
+	}
+	report.Unreached = []string{}
+	for _, r := range routes {
+		if report.Hits[r.Id] == 0 {
+			report.Unreached = append(report.Unreached, r.Id)
+		}
+	}
+	return report, nil
+}
+
+// fuzzTest tests the request, recovering its panic, a *PanicError of the
+// result is a panic too
+func fuzzTest(m Matcher, attrs *RequestAttributes) (id string, matched bool, panicked string) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = fmt.Sprint(r)
+		}
+	}()
+	res := m.Test(attrs)
+	var p *PanicError
+	if errors.As(res.Err(), &p) {
+		return "", false, fmt.Sprint(p.Value)
+	}
+	if route := res.Route(); route != nil {
+		return route.Id, true, ""
+	}
+	return "", false, ""
+}
+
+// FuzzMatch runs the native go fuzzing, go test -fuzz, on the matcher: the
+// requests generated for its routes are the seed corpus, a test panicking,
+// eg. in a custom predicate, fails. Call it from a FuzzXxx function:
+//
+//	func FuzzRoutes(f *testing.F) {
+//		m, err := matcher.New(&matcher.Options{RoutesFile: "routes.eskip"})
+//		if err != nil {
+//			f.Fatal(err)
+//		}
+//		matcher.FuzzMatch(f, m)
+//	}
+func FuzzMatch(f *testing.F, m Matcher) {
+	for _, attrs := range SeedRequests(m.Routes()) {
+		query := url.Values{}
+		for name, value := range attrs.Query {
+			query.Set(name, value)
+		}
+		name, value := "", ""
+		for n, v := range attrs.Headers {
+			if n > name {
+				name, value = n, v
+			}
+		}
+		f.Add(attrs.Method, attrs.Path, attrs.Host, query.Encode(), name, value)
+	}
+	f.Fuzz(func(t *testing.T, method, path, host, rawQuery, headerName, headerValue string) {
+		attrs := &RequestAttributes{Method: method, Path: path, Host: host}
+		if query, err := url.ParseQuery(rawQuery); err == nil && len(query) > 0 {
+			attrs.Query = make(map[string]string, len(query))
+			for name := range query {
+				attrs.Query[name] = query.Get(name)
+			}
+		}
+		if headerName != "" {
+			attrs.Headers = map[string]string{headerName: headerValue}
+		}
+		if _, _, panicked := fuzzTest(m, attrs); panicked != "" {
+			t.Fatalf("test of %+v panicked: %s", attrs, panicked)
+		}
+	})
+}
+
+// fuzzDictionary values of the routes the mutations pick from: the literal
+// path segments, the hosts and the header names of the predicates
+type fuzzDictionary struct {
+	segments []string
+	hosts    []string
+	headers  []string
+}
+
+// collectFuzzDictionary the dictionary of the routes, sorted and without
+// duplicates so the mutations only depend on the seed
+func collectFuzzDictionary(routes []*eskip.Route) *fuzzDictionary {
+	segments, hosts, headers := map[string]string{}, map[string]string{}, map[string]string{}
+	for _, r := range routes {
+		for _, p := range Predicates(r) {
+			switch p.Name {
+			case pathPredicate, pathSubtreePredicate:
+				path, _ := stringArg(p, 0)
+				for _, s := range pathSegments(path) {
+					if !strings.HasPrefix(s, ":") && !strings.HasPrefix(s, "*") {
+						segments[s] = ""
+					}
+				}
+			case hostPredicate:
+				if expr, ok := stringArg(p, 0); ok {
+					if host, ok := sampleRegexp(expr); ok {
+						hosts[host] = ""
+					}
+				}
+			case headerPredicate, headerRegexpPredicate:
+				if name, ok := stringArg(p, 0); ok {
+					headers[http.CanonicalHeaderKey(name)] = ""
+				}
+			}
+		}
+	}
+	return &fuzzDictionary{segments: sortedKeys(segments), hosts: sortedKeys(hosts), headers: sortedKeys(headers)}
+}
+
+// fuzzer mutates the seed requests with the values of the dictionary
+type fuzzer struct {
+	rand  *rand.Rand
+	seeds []*RequestAttributes
+	dict  *fuzzDictionary
+}
+
+func newFuzzer(seed int64, seeds []*RequestAttributes, dict *fuzzDictionary) *fuzzer {
+	if len(seeds) == 0 {
+		seeds = []*RequestAttributes{{Method: "GET", Path: "/"}}
+	}
+	return &fuzzer{rand: rand.New(rand.NewSource(seed)), seeds: seeds, dict: dict}
+}
+
+// next a copy of a seed with up to 3 mutations
+func (z *fuzzer) next() *RequestAttributes {
+	attrs := cloneAttributes(z.seeds[z.rand.Intn(len(z.seeds))])
+	for n := z.rand.Intn(4); n > 0; n-- {
+		z.mutate(attrs)
+	}
+	return attrs
+}
+
+func (z *fuzzer) mutate(attrs *RequestAttributes) {
+	segments := pathSegments(attrs.Path)
+	switch z.rand.Intn(8) {
+	case 0:
+		// replace a segment
+		if len(segments) > 0 {
+			segments[z.rand.Intn(len(segments))] = z.segment()
+		}
+	case 1:
+		segments = append(segments, z.segment())
+	case 2:
+		if len(segments) > 0 {
+			segments = segments[:len(segments)-1]
+		}
+	case 3:
+		// toggle the trailing slash
+		if strings.HasSuffix(attrs.Path, "/") {
+			attrs.Path = strings.TrimSuffix(attrs.Path, "/")
+		} else {
+			attrs.Path += "/"
+		}
+		return
+	case 4:
+		attrs.Method = fuzzMethods[z.rand.Intn(len(fuzzMethods))]
+		return
+	case 5:
+		attrs.Host = z.pick(append([]string{""}, z.dict.hosts...))
+		return
+	case 6:
+		z.toggleHeader(attrs)
+		return
+	default:
+		z.toggleQuery(attrs)
+		return
+	}
+	attrs.Path = "/" + strings.Join(segments, "/")
+}
+
+// segment a literal segment of the routes or a random one
+func (z *fuzzer) segment() string {
+	if len(z.dict.segments) > 0 && z.rand.Intn(3) > 0 {
+		return z.pick(z.dict.segments)
+	}
+	return fmt.Sprintf("x%d", z.rand.Intn(1000))
+}
+
+// toggleHeader removes a header of the request or sets one of the routes
+func (z *fuzzer) toggleHeader(attrs *RequestAttributes) {
+	if len(attrs.Headers) > 0 && z.rand.Intn(2) == 0 {
+		delete(attrs.Headers, z.pick(sortedKeys(attrs.Headers)))
+		return
+	}
+	if len(z.dict.headers) == 0 {
+		return
+	}
+	if attrs.Headers == nil {
+		attrs.Headers = map[string]string{}
+	}
+	attrs.Headers[z.pick(z.dict.headers)] = sampleValue
+}
+
+// toggleQuery removes a query parameter of the request or adds a random one
+func (z *fuzzer) toggleQuery(attrs *RequestAttributes) {
+	if len(attrs.Query) > 0 && z.rand.Intn(2) == 0 {
+		delete(attrs.Query, z.pick(sortedKeys(attrs.Query)))
+		return
+	}
+	if attrs.Query == nil {
+		attrs.Query = map[string]string{}
+	}
+	attrs.Query[fmt.Sprintf("q%d", z.rand.Intn(10))] = sampleValue
+}
+
+func (z *fuzzer) pick(values []string) string {
+	return values[z.rand.Intn(len(values))]
+}
@@ -0,0 +1,85 @@
+package matcher
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/routing"
+)
+
+const fuzzRoutes = `
+	orders: Path("/orders/:id") && Method("GET") -> <shunt>;
+	items: PathSubtree("/orders/:id/items") -> <shunt>;
+	admin: Path("/admin") && Header("X-Admin", "example") -> <shunt>;
+	exploding: Path("/boom") && Explode() -> <shunt>;
+	never: Path("/never") && False() -> <shunt>;
+	fallback: PathSubtree("/") -> <shunt>;
+`
+
+func newFuzzMatcher(t testing.TB) Matcher {
+	file := writeRoutes(t, fuzzRoutes)
+	defer os.Remove(file)
+	m, err := New(&Options{RoutesFile: file, CustomPredicates: []routing.PredicateSpec{
+		PredicateFunc("Explode", 0, func(args []interface{}, req *http.Request) bool {
+			if req.URL.Query().Get("q1") != "" {
+				panic("exploded")
+			}
+			return true
+		}),
+	}})
+	require.NoError(t, err)
+	return m
+}
+
+func TestFuzz(t *testing.T) {
+	m := newFuzzMatcher(t)
+	defer m.Close()
+
+	report, err := Fuzz(m, &FuzzOptions{Seed: 1, Iterations: 2000})
+	require.NoError(t, err)
+	assert.Equal(t, 2000, report.Iterations)
+	assert.Equal(t, []string{"never"}, report.Unreached)
+	total := report.NoMatch + len(report.Panics)
+	for _, hits := range report.Hits {
+		total += hits
+	}
+	assert.Equal(t, 2000, total)
+	assert.Len(t, report.Hits, 6)
+
+	reached := map[string]bool{}
+	for _, c := range report.Corpus {
+		assert.False(t, reached[c.RouteID], "a single request for %s", c.RouteID)
+		reached[c.RouteID] = true
+		assert.Equal(t, c.RouteID, m.Test(c.Request).Route().Id)
+	}
+	assert.Len(t, reached, 5)
+
+	if assert.NotEmpty(t, report.Panics) {
+		assert.Equal(t, "exploded", report.Panics[0].Value)
+		assert.Equal(t, "/boom", strings.TrimSuffix(report.Panics[0].Request.Path, "/"))
+	}
+
+	histogram := report.Histogram()
+	require.Len(t, histogram, 7)
+	assert.Regexp(t, `^admin +\d+ #+$`, histogram[0])
+	assert.Regexp(t, `^never +0$`, histogram[4])
+	assert.Regexp(t, `^<no match> +\d+`, histogram[6])
+
+	again, err := Fuzz(m, &FuzzOptions{Seed: 1, Iterations: 2000})
+	require.NoError(t, err)
+	assert.Equal(t, report, again, "the same seed tests the same requests")
+
+	require.NoError(t, m.Close())
+	_, err = Fuzz(m, &FuzzOptions{})
+	assert.Equal(t, ErrClosed, err)
+}
+
+func FuzzRoutes(f *testing.F) {
+	m := newFuzzMatcher(f)
+	defer m.Close()
+	FuzzMatch(f, m)
+}
@@ -0,0 +1,396 @@
+package matcher
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// Names of the bundled predicates the request generator knows about
+const (
+	cookiePredicate         = "Cookie"
+	queryParamPredicate     = "QueryParam"
+	sourcePredicate         = "Source"
+	sourceFromLastPredicate = "SourceFromLast"
+	betweenPredicate        = "Between"
+	beforePredicate         = "Before"
+	afterPredicate          = "After"
+	trafficPredicate        = "Traffic"
+)
+
+// sampleValue value used when a predicate only requires something to be present
+const sampleValue = "example"
+
+// Unsatisfied a route predicate a generated request isn't guaranteed to satisfy
+type Unsatisfied struct {
+	// Predicate eskip representation of the predicate, eg. Traffic(0.1)
+	Predicate string `json:"predicate" yaml:"predicate"`
+	// Reason why the predicate can't be satisfied automatically
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// PlaceholderStyle values of the path parameters in the generated requests
+type PlaceholderStyle string
+
+// Placeholder styles, eg. for Path("/users/:id")
+const (
+	// PlaceholderDefault the default style of the feature, PlaceholderExample
+	// for the generated requests
+	PlaceholderDefault PlaceholderStyle = ""
+	// PlaceholderExample /users/example-id
+	PlaceholderExample PlaceholderStyle = "example"
+	// PlaceholderBraces /users/{id}
+	PlaceholderBraces PlaceholderStyle = "braces"
+	// PlaceholderNumeric /users/1, the parameters numbered from 1
+	PlaceholderNumeric PlaceholderStyle = "numeric"
+)
+
+// GenerateOptions how the requests are generated for the routes, shared by
+// GenerateRequestWith, Matcher.GenerateRequestFor, GenerateAll and the
+// features built on it, see Options.Generate. The zero value generates the
+// same requests as GenerateRequest, the same options always generate the
+// same requests.
+type GenerateOptions struct {
+	// Seed of the random values of the regexps, see SampleRegexp, 0 for
+	// the shortest values
+	Seed int64
+
+	// Placeholders style of the values of the path parameters,
+	// PlaceholderExample by default
+	Placeholders PlaceholderStyle
+
+	// DefaultHost host of the requests for the routes without a Host
+	// predicate, none when empty
+	DefaultHost string
+
+	// Optional includes a copy of the optional parts of the regexps in
+	// their shortest values, eg. v1 for /^v[0-9]?$/ instead of v, the
+	// random values include them by chance
+	Optional bool
+}
+
+// validPlaceholders the placeholder styles, in the order they are documented
+var validPlaceholders = []PlaceholderStyle{PlaceholderExample, PlaceholderBraces, PlaceholderNumeric}
+
+// ParsePlaceholderStyle returns the placeholder style with the name, eg. braces
+func ParsePlaceholderStyle(name string) (PlaceholderStyle, error) {
+	for _, p := range validPlaceholders {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+	return PlaceholderDefault, fmt.Errorf("unsupported placeholder style %q, use one of example, braces, numeric", name)
+}
+
+// requestGenerator request being built to satisfy the predicates of a route
+type requestGenerator struct {
+	options     GenerateOptions
+	sampler     *regexpSampler
+	attrs       *RequestAttributes
+	cookies     []string
+	unsatisfied []Unsatisfied
+}
+
+// GenerateRequest generates a concrete request matching the route: path
+// parameters get example values, a literal is picked for the Host, PathRegexp,
+// HeaderRegexp, Cookie and QueryParam regexps and the required method and
+// headers are set. The predicates that can't be satisfied automatically,
+// eg. depending on time, client address or randomness like Traffic, are
+// returned together with the reason. The regexps are sampled with the
+// shortest strings, see GenerateRequestWithSeed for random ones.
+func GenerateRequest(r *eskip.Route) (*RequestAttributes, []Unsatisfied) {
+	return GenerateRequestWith(r, GenerateOptions{})
+}
+
+// GenerateRequestWithSeed like GenerateRequest with the regexps sampled
+// with random strings, the same for the same seed, see SampleRegexp
+func GenerateRequestWithSeed(r *eskip.Route, seed int64) (*RequestAttributes, []Unsatisfied) {
+	return generateRequest(r, GenerateOptions{}, newRegexpSampler(rand.New(rand.NewSource(seed)), false))
+}
+
+// GenerateRequestWith like GenerateRequest generated with the options
+func GenerateRequestWith(r *eskip.Route, o GenerateOptions) (*RequestAttributes, []Unsatisfied) {
+	var random *rand.Rand
+	if o.Seed != 0 {
+		random = rand.New(rand.NewSource(o.Seed))
+	}
+	return generateRequest(r, o, newRegexpSampler(random, o.Optional))
+}
+
+func generateRequest(r *eskip.Route, o GenerateOptions, sampler *regexpSampler) (*RequestAttributes, []Unsatisfied) {
+	g := &requestGenerator{
+		options: o,
+		sampler: sampler,
+		attrs: &RequestAttributes{
+			Method:  "GET",
+			Query:   map[string]string{},
+			Headers: map[string]string{},
+		},
+	}
+
+	ps := Predicates(r)
+	// the path first, the path regexps are checked against it
+	for _, p := range ps {
+		if p.Name == pathPredicate || p.Name == pathSubtreePredicate {
+			g.path(p)
+		}
+	}
+	for _, p := range ps {
+		if p.Name != pathPredicate && p.Name != pathSubtreePredicate {
+			g.predicate(p)
+		}
+	}
+
+	if g.attrs.Path == "" {
+		g.attrs.Path = "/"
+	}
+	if g.attrs.Host == "" {
+		g.attrs.Host = o.DefaultHost
+	}
+	if len(g.cookies) > 0 {
+		g.attrs.Headers["Cookie"] = strings.Join(g.cookies, "; ")
+	}
+	return g.attrs, g.unsatisfied
+}
+
+// GeneratedRequestError the request generated for a route doesn't match
+// it, see Matcher.GenerateRequestFor
+type GeneratedRequestError struct {
+	// RouteID the route the request was generated for
+	RouteID string
+	// Matched the route matching the request instead, empty without a match
+	Matched string
+}
+
+func (e *GeneratedRequestError) Error() string {
+	if e.Matched == "" {
+		return fmt.Sprintf("the generated request doesn't match route %s", e.RouteID)
+	}
+	return fmt.Sprintf("the generated request matches route %s instead of %s", e.Matched, e.RouteID)
+}
+
+// GenerateRequestFor see Matcher.GenerateRequestFor
+func (f *matcher) GenerateRequestFor(id string) (*RequestAttributes, []Unsatisfied, error) {
+	if f.isClosed() {
+		return nil, nil, ErrClosed
+	}
+	var route *eskip.Route
+	for _, r := range f.routes {
+		if r.Id == id {
+			route = r
+		}
+	}
+	if route == nil {
+		return nil, nil, fmt.Errorf("route %s not found", id)
+	}
+	attrs, unsatisfied := GenerateRequestWith(route, f.options.Generate)
+
+	// the hooks, the diagnostics and the decision log aren't involved
+	result := f.test(normalizeAttributes(attrs), time.Time{})
+	if result.err != nil {
+		return attrs, unsatisfied, result.err
+	}
+	switch {
+	case result.route == nil:
+		return attrs, unsatisfied, &GeneratedRequestError{RouteID: id}
+	case result.route.Id != id:
+		return attrs, unsatisfied, &GeneratedRequestError{RouteID: id, Matched: result.route.Id}
+	}
+	return attrs, unsatisfied, nil
+}
+
+func (g *requestGenerator) fail(p *eskip.Predicate, format string, a ...interface{}) {
+	g.unsatisfied = append(g.unsatisfied, Unsatisfied{
+		Predicate: predicateString(p),
+		Reason:    fmt.Sprintf(format, a...),
+	})
+}
+
+// path sets the request path from a Path or PathSubtree predicate
+func (g *requestGenerator) path(p *eskip.Predicate) {
+	pattern, ok := stringArg(p, 0)
+	if !ok {
+		g.fail(p, "invalid arguments")
+		return
+	}
+	path := samplePath(pattern, g.options.Placeholders)
+	if g.attrs.Path != "" && g.attrs.Path != path {
+		g.fail(p, "conflicts with the path %s", g.attrs.Path)
+		return
+	}
+	g.attrs.Path = path
+}
+
+func (g *requestGenerator) predicate(p *eskip.Predicate) {
+	switch p.Name {
+	case hostPredicate:
+		g.regexpValue(p, 0, g.attrs.Host, func(v string) { g.attrs.Host = v })
+	case pathRegexpPredicate:
+		g.regexpValue(p, 0, g.attrs.Path, func(v string) {
+			if !strings.HasPrefix(v, "/") {
+				v = "/" + v
+			}
+			g.attrs.Path = v
+		})
+	case methodPredicate:
+		if method, ok := stringArg(p, 0); ok {
+			g.attrs.Method = strings.ToUpper(method)
+		} else {
+			g.fail(p, "invalid arguments")
+		}
+	case headerPredicate:
+		name, ok := stringArg(p, 0)
+		value, ok2 := stringArg(p, 1)
+		if !ok || !ok2 {
+			g.fail(p, "invalid arguments")
+			return
+		}
+		g.attrs.Headers[name] = value
+	case headerRegexpPredicate:
+		name, ok := stringArg(p, 0)
+		if !ok {
+			g.fail(p, "invalid arguments")
+			return
+		}
+		g.regexpValue(p, 1, g.attrs.Headers[name], func(v string) { g.attrs.Headers[name] = v })
+	case cookiePredicate:
+		name, ok := stringArg(p, 0)
+		if !ok {
+			g.fail(p, "invalid arguments")
+			return
+		}
+		g.regexpValue(p, 1, "", func(v string) { g.cookies = append(g.cookies, name+"="+v) })
+	case queryParamPredicate:
+		name, ok := stringArg(p, 0)
+		if !ok {
+			g.fail(p, "invalid arguments")
+			return
+		}
+		if len(p.Args) == 1 {
+			g.attrs.Query[name] = sampleValue
+			return
+		}
+		g.regexpValue(p, 1, "", func(v string) { g.attrs.Query[name] = v })
+	case sourcePredicate, sourceFromLastPredicate:
+		g.fail(p, "depends on the client address")
+	case betweenPredicate, beforePredicate, afterPredicate:
+		g.fail(p, "depends on the current time")
+	case trafficPredicate:
+		g.fail(p, "matches a random share of the requests")
+	case truePredicate, weightPredicate, lbGroupPredicate:
+	case lbMemberPredicate:
+		g.fail(p, "matches the requests forwarded by its group route only")
+	case falsePredicate:
+		g.fail(p, "never matches")
+	default:
+		g.fail(p, "unknown predicate")
+	}
+}
+
+// regexpValue satisfies the regexp argument i of p: the current value is kept
+// when it already matches, otherwise set is called with a sample of the regexp
+func (g *requestGenerator) regexpValue(p *eskip.Predicate, i int, current string, set func(v string)) {
+	expr, ok := stringArg(p, i)
+	if !ok {
+		g.fail(p, "invalid arguments")
+		return
+	}
+	rx, err := regexp.Compile(expr)
+	if err != nil {
+		g.fail(p, "invalid regexp: %v", err)
+		return
+	}
+	if current != "" {
+		if !rx.MatchString(current) {
+			g.fail(p, "conflicts with %q", current)
+		}
+		return
+	}
+	v, err := g.sampler.sample(expr)
+	if e, ok := err.(*SampleError); ok && e.Unbounded {
+		g.fail(p, "unbounded, a matching value is longer than %d characters", maxSampleLength)
+		return
+	}
+	if err != nil {
+		g.fail(p, "no literal found matching the regexp")
+		return
+	}
+	set(v)
+}
+
+// samplePath fills the wildcards of a path pattern with values in the
+// style, eg. /orders/:id/*rest becomes /orders/example-id/example-rest
+func samplePath(pattern string, style PlaceholderStyle) string {
+	segments := strings.Split(pattern, "/")
+	n := 0
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			name := s[1:]
+			if name == "" {
+				name = "value"
+			}
+			n++
+			switch style {
+			case PlaceholderBraces:
+				segments[i] = "{" + name + "}"
+			case PlaceholderNumeric:
+				segments[i] = strconv.Itoa(n)
+			default:
+				segments[i] = sampleValue + "-" + name
+			}
+		}
+	}
+	path := strings.Join(segments, "/")
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// GeneratedCase a request generated for a route of the routes file, see
+// GenerateAll
+type GeneratedCase struct {
+	// RouteID the route the request was generated for
+	RouteID string `json:"routeId" yaml:"routeId"`
+	// Request the generated request
+	Request *RequestAttributes `json:"request" yaml:"request"`
+	// Reachable the request matches the route
+	Reachable bool `json:"reachable" yaml:"reachable"`
+	// Matched the route matching the request instead, empty when it's
+	// reachable or without a match
+	Matched string `json:"matched,omitempty" yaml:"matched,omitempty"`
+	// Unsatisfied the predicates the request isn't guaranteed to satisfy
+	Unsatisfied []Unsatisfied `json:"unsatisfied,omitempty" yaml:"unsatisfied,omitempty"`
+}
+
+// GenerateAll loads the routes file and generates a request for each of its
+// routes, in file order, telling if it matches the route, see
+// Matcher.GenerateRequestFor. Options.Lazy is ignored.
+func GenerateAll(o *Options) ([]GeneratedCase, error) {
+	f, err := newMatcher(o)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cases := []GeneratedCase{}
+	seen := make(map[string]bool)
+	for _, r := range f.routes {
+		if seen[r.Id] {
+			continue
+		}
+		seen[r.Id] = true
+		attrs, unsatisfied, err := f.GenerateRequestFor(r.Id)
+		c := GeneratedCase{RouteID: r.Id, Request: attrs, Reachable: err == nil, Unsatisfied: unsatisfied}
+		if e, ok := err.(*GeneratedRequestError); ok {
+			c.Matched = e.Matched
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
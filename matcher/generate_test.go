@@ -0,0 +1,286 @@
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/eskip"
+)
+
+const generateRoutes = `
+	literal: Path("/orders") -> <shunt>;
+	params: Path("/orders/:id/items/*rest") && Method("DELETE") -> <shunt>;
+	subtree: PathSubtree("/api") && Header("Accept", "application/json") -> <shunt>;
+	host: Host(/^(api|admin)[.]example[.]org$/) && Path("/host") -> <shunt>;
+	path_regexp: PathRegexp(/^\/v[0-9]+\/users$/) -> <shunt>;
+	header_regexp: Path("/tenant") && HeaderRegexp("X-Tenant", /^[a-z]+-[0-9]{2}$/) -> <shunt>;
+	cookie: Path("/cookie") && Cookie("session", /^s[0-9]+$/) && Cookie("lang", "en") -> <shunt>;
+	query: Path("/search") && QueryParam("q") && QueryParam("page", "^[1-9][0-9]*$") -> <shunt>;
+`
+
+func TestGenerateRequest(t *testing.T) {
+	routes, err := eskip.Parse(generateRoutes)
+	require.NoError(t, err)
+
+	want := map[string]*RequestAttributes{
+		"literal":       {Method: "GET", Path: "/orders"},
+		"params":        {Method: "DELETE", Path: "/orders/example-id/items/example-rest"},
+		"subtree":       {Method: "GET", Path: "/api", Headers: map[string]string{"Accept": "application/json"}},
+		"host":          {Method: "GET", Path: "/host", Host: "api.example.org"},
+		"path_regexp":   {Method: "GET", Path: "/v0/users"},
+		"header_regexp": {Method: "GET", Path: "/tenant", Headers: map[string]string{"X-Tenant": "a-00"}},
+		"cookie":        {Method: "GET", Path: "/cookie", Headers: map[string]string{"Cookie": "session=s0; lang=en"}},
+		"query":         {Method: "GET", Path: "/search", Query: map[string]string{"q": "example", "page": "1"}},
+	}
+
+	f, err := ioutil.TempFile("", "generate-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(generateRoutes)
+	f.Close()
+	m, err := New(&Options{RoutesFile: f.Name()})
+	require.NoError(t, err)
+
+	for _, r := range routes {
+		t.Run(r.Id, func(t *testing.T) {
+			attrs, unsatisfied := GenerateRequest(r)
+			assert.Empty(t, unsatisfied)
+
+			expected := want[r.Id]
+			if expected.Query == nil {
+				expected.Query = map[string]string{}
+			}
+			if expected.Headers == nil {
+				expected.Headers = map[string]string{}
+			}
+			assert.Equal(t, expected, attrs)
+
+			res := m.Test(attrs)
+			if assert.NotNil(t, res.Route(), "generated request doesn't match") {
+				assert.Equal(t, r.Id, res.Route().Id)
+			}
+		})
+	}
+}
+
+func TestGenerateRequestUnsatisfied(t *testing.T) {
+	routes, err := eskip.Parse(`
+		traffic: Path("/a") && Traffic(0.1) -> <shunt>;
+		source: Source("10.0.0.0/8") && After("2020-01-01T00:00:00Z") -> <shunt>;
+		custom: Path("/b") && Custom("x") -> <shunt>;
+		conflict: Path("/c") && PathRegexp(/^\/d/) -> <shunt>;
+		impossible: Host(/a^b/) -> <shunt>;
+	`)
+	require.NoError(t, err)
+
+	want := map[string][]Unsatisfied{
+		"traffic": {{`Traffic(0.1)`, "matches a random share of the requests"}},
+		"source": {
+			{`Source("10.0.0.0/8")`, "depends on the client address"},
+			{`After("2020-01-01T00:00:00Z")`, "depends on the current time"},
+		},
+		"custom":     {{`Custom("x")`, "unknown predicate"}},
+		"conflict":   {{`PathRegexp("^/d")`, `conflicts with "/c"`}},
+		"impossible": {{`Host("a^b")`, "no literal found matching the regexp"}},
+	}
+	for _, r := range routes {
+		_, unsatisfied := GenerateRequest(r)
+		assert.Equal(t, want[r.Id], unsatisfied, r.Id)
+	}
+}
+
+func TestGenerateRequestFor(t *testing.T) {
+	f, err := ioutil.TempFile("", "generate-for-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`
+		orders: Path("/orders/:id") && Method("DELETE") -> <shunt>;
+		canary: Path("/canary") && Traffic(0.1) -> <shunt>;
+		v1: Path("/v1") -> <shunt>;
+		shadowed: PathRegexp(/^\/v1/) -> <shunt>;
+		never: Path("/never") && False() -> <shunt>;
+	`)
+	f.Close()
+	m, err := New(&Options{RoutesFile: f.Name(), TrafficMode: TrafficAlways})
+	require.NoError(t, err)
+
+	attrs, unsatisfied, err := m.GenerateRequestFor("orders")
+	require.NoError(t, err)
+	assert.Empty(t, unsatisfied)
+	assert.Equal(t, "DELETE", attrs.Method)
+	assert.Equal(t, "/orders/example-id", attrs.Path)
+
+	attrs, unsatisfied, err = m.GenerateRequestFor("canary")
+	assert.NoError(t, err, "the Traffic predicate is forced")
+	assert.Equal(t, "/canary", attrs.Path)
+	assert.Equal(t, []Unsatisfied{{`Traffic(0.1)`, "matches a random share of the requests"}}, unsatisfied)
+
+	attrs, _, err = m.GenerateRequestFor("shadowed")
+	assert.Equal(t, &GeneratedRequestError{RouteID: "shadowed", Matched: "v1"}, err)
+	assert.EqualError(t, err, "the generated request matches route v1 instead of shadowed")
+	assert.Equal(t, "/v1", attrs.Path)
+
+	_, unsatisfied, err = m.GenerateRequestFor("never")
+	assert.EqualError(t, err, "the generated request doesn't match route never")
+	assert.Len(t, unsatisfied, 1)
+
+	attrs, _, err = m.GenerateRequestFor("missing")
+	assert.EqualError(t, err, "route missing not found")
+	assert.Nil(t, attrs)
+
+	require.NoError(t, m.Close())
+	_, _, err = m.GenerateRequestFor("orders")
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestGenerateAll(t *testing.T) {
+	f, err := ioutil.TempFile("", "generate-all-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`
+		v1: Path("/v1") -> <shunt>;
+		shadowed: PathRegexp(/^\/v1/) -> <shunt>;
+		never: Path("/never") && False() -> <shunt>;
+	`)
+	f.Close()
+
+	cases, err := GenerateAll(&Options{RoutesFile: f.Name()})
+	require.NoError(t, err)
+	require.Len(t, cases, 3)
+	assert.Equal(t, GeneratedCase{RouteID: "v1", Request: cases[0].Request, Reachable: true}, cases[0])
+	assert.Equal(t, "/v1", cases[0].Request.Path)
+	assert.Equal(t, "shadowed", cases[1].RouteID)
+	assert.False(t, cases[1].Reachable)
+	assert.Equal(t, "v1", cases[1].Matched)
+	assert.Equal(t, "never", cases[2].RouteID)
+	assert.False(t, cases[2].Reachable)
+	assert.Empty(t, cases[2].Matched)
+	assert.Len(t, cases[2].Unsatisfied, 1)
+
+	_, err = GenerateAll(&Options{RoutesFile: "testdata/missing.eskip"})
+	assert.Error(t, err)
+}
+
+func TestGenerateRequestWith(t *testing.T) {
+	routes, err := eskip.Parse(generateRoutes)
+	require.NoError(t, err)
+	byID := map[string]*eskip.Route{}
+	for _, r := range routes {
+		byID[r.Id] = r
+	}
+
+	tests := []struct {
+		name    string
+		route   string
+		options GenerateOptions
+		want    *RequestAttributes
+	}{
+		{
+			name:  "zero value",
+			route: "params",
+			want:  &RequestAttributes{Method: "DELETE", Path: "/orders/example-id/items/example-rest"},
+		},
+		{
+			name:    "braces",
+			route:   "params",
+			options: GenerateOptions{Placeholders: PlaceholderBraces},
+			want:    &RequestAttributes{Method: "DELETE", Path: "/orders/{id}/items/{rest}"},
+		},
+		{
+			name:    "numeric",
+			route:   "params",
+			options: GenerateOptions{Placeholders: PlaceholderNumeric},
+			want:    &RequestAttributes{Method: "DELETE", Path: "/orders/1/items/2"},
+		},
+		{
+			name:    "default host",
+			route:   "literal",
+			options: GenerateOptions{DefaultHost: "www.example.org"},
+			want:    &RequestAttributes{Method: "GET", Path: "/orders", Host: "www.example.org"},
+		},
+		{
+			name:    "host predicate over the default host",
+			route:   "host",
+			options: GenerateOptions{DefaultHost: "www.example.org"},
+			want:    &RequestAttributes{Method: "GET", Path: "/host", Host: "api.example.org"},
+		},
+		{
+			name:    "optional",
+			route:   "query",
+			options: GenerateOptions{Optional: true},
+			want:    &RequestAttributes{Method: "GET", Path: "/search", Query: map[string]string{"q": "example", "page": "10"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs, unsatisfied := GenerateRequestWith(byID[tt.route], tt.options)
+			assert.Empty(t, unsatisfied)
+			if tt.want.Query == nil {
+				tt.want.Query = map[string]string{}
+			}
+			if tt.want.Headers == nil {
+				tt.want.Headers = map[string]string{}
+			}
+			assert.Equal(t, tt.want, attrs)
+		})
+	}
+}
+
+func TestGenerateRequestWithSeedOption(t *testing.T) {
+	routes, err := eskip.Parse(generateRoutes)
+	require.NoError(t, err)
+
+	o := GenerateOptions{Seed: 7, Placeholders: PlaceholderNumeric, DefaultHost: "www.example.org", Optional: true}
+	for _, r := range routes {
+		attrs, unsatisfied := GenerateRequestWith(r, o)
+		assert.Empty(t, unsatisfied, r.Id)
+		for i := 0; i < 10; i++ {
+			again, _ := GenerateRequestWith(r, o)
+			assert.Equal(t, attrs, again, r.Id)
+		}
+	}
+}
+
+func TestGenerateRequestForOptions(t *testing.T) {
+	f, err := ioutil.TempFile("", "generate-options-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`orders: Path("/orders/:id") -> <shunt>;`)
+	f.Close()
+
+	cases, err := GenerateAll(&Options{RoutesFile: f.Name(), Generate: GenerateOptions{Placeholders: PlaceholderBraces, DefaultHost: "api.example.org"}})
+	require.NoError(t, err)
+	require.Len(t, cases, 1)
+	assert.True(t, cases[0].Reachable)
+	assert.Equal(t, "/orders/{id}", cases[0].Request.Path)
+	assert.Equal(t, "api.example.org", cases[0].Request.Host)
+}
+
+func TestParsePlaceholderStyle(t *testing.T) {
+	for _, name := range []string{"example", "braces", "numeric"} {
+		p, err := ParsePlaceholderStyle(name)
+		assert.NoError(t, err)
+		assert.Equal(t, PlaceholderStyle(name), p)
+	}
+	_, err := ParsePlaceholderStyle("colons")
+	assert.EqualError(t, err, `unsupported placeholder style "colons", use one of example, braces, numeric`)
+}
+
+func TestSampleRegexp(t *testing.T) {
+	for _, expr := range []string{
+		`^api[.]example[.]org$`,
+		`(?i)^Shop\.example\.(com|org)$`,
+		`^[^/]+\.internal$`,
+		`\d{3}-\w+`,
+		`.*`,
+		`^x?y*z+$`,
+	} {
+		s, ok := sampleRegexp(expr)
+		assert.True(t, ok, expr)
+		assert.Regexp(t, expr, s)
+	}
+}
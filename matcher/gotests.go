@@ -0,0 +1,144 @@
+package matcher
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"strings"
+)
+
+// goTestEntryIndent indentation of the entries of the generated test table
+const goTestEntryIndent = "\t\t"
+
+// GenerateGoTests loads the routes file and writes a go test file of the
+// package pkg, with a table driven test checking the request generated for
+// each route matches it, see GenerateAll. The entries of the routes whose
+// request needs manual values, with predicates it can't satisfy or not
+// matching the route, are commented out after a TODO telling why. The test
+// loads the routes file with the path of Options.RoutesFile, relative to the
+// directory of the package when it's relative. The Traffic predicates always
+// match with TrafficRandom. Options.Lazy is ignored.
+func GenerateGoTests(o *Options, pkg string, w io.Writer) error {
+	if !token.IsIdentifier(pkg) {
+		return fmt.Errorf("invalid package name %q", pkg)
+	}
+	if o.TrafficMode == TrafficRandom {
+		c := *o
+		c.TrafficMode = TrafficAlways
+		o = &c
+	}
+	cases, err := GenerateAll(o)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by eskip-match from %s.\n\n", o.RoutesFile)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("import (\n\"testing\"\n\n\"github.com/rbarilani/eskip-match/matcher\"\n)\n\n")
+	b.WriteString("func TestRoutes(t *testing.T) {\n")
+	fmt.Fprintf(&b, "m, err := matcher.New(%s)\n", goTestOptions(o))
+	b.WriteString("if err != nil {\nt.Fatal(err)\n}\ndefer m.Close()\n\n")
+	b.WriteString("tests := []struct {\nroute string\nrequest *matcher.RequestAttributes\n}{\n")
+	for _, c := range cases {
+		for _, todo := range generatedTODOs(c) {
+			fmt.Fprintf(&b, "// TODO %s\n", todo)
+		}
+		fmt.Fprintf(&b, "{\nroute: %q,\nrequest: %s,\n},\n", c.RouteID, goRequestAttributes(c.Request))
+	}
+	b.WriteString("}\n\n")
+	b.WriteString("for _, tt := range tests {\ntt := tt\nt.Run(tt.route, func(t *testing.T) {\n")
+	b.WriteString("route := m.Test(tt.request).Route()\n")
+	b.WriteString("if route == nil {\nt.Fatalf(\"no route matches, expected %s\", tt.route)\n}\n")
+	b.WriteString("if route.Id != tt.route {\nt.Errorf(\"route %s matches, expected %s\", route.Id, tt.route)\n}\n")
+	b.WriteString("})\n}\n}\n")
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return err
+	}
+	// the entries formatted like the others, then commented out
+	if src, err = format.Source([]byte(commentOutTODOEntries(string(src)))); err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// goTestOptions the go source of the options loading the routes in the
+// generated test
+func goTestOptions(o *Options) string {
+	fields := []string{fmt.Sprintf("RoutesFile: %q", o.RoutesFile)}
+	if len(o.MockFilters) > 0 {
+		fields = append(fields, fmt.Sprintf("MockFilters: %#v", o.MockFilters))
+	}
+	if o.IgnoreTrailingSlash {
+		fields = append(fields, "IgnoreTrailingSlash: true")
+	}
+	return "&matcher.Options{" + strings.Join(fields, ", ") + "}"
+}
+
+// generatedTODOs why the generated case needs manual values, none when
+// the request matches the route
+func generatedTODOs(c GeneratedCase) []string {
+	var todos []string
+	for _, u := range c.Unsatisfied {
+		todos = append(todos, fmt.Sprintf("%s: set a value satisfying %s: %s", c.RouteID, u.Predicate, u.Reason))
+	}
+	switch {
+	case len(todos) > 0 || c.Reachable:
+	case c.Matched != "":
+		todos = append(todos, fmt.Sprintf("%s: the generated request matches route %s instead", c.RouteID, c.Matched))
+	default:
+		todos = append(todos, fmt.Sprintf("%s: the generated request doesn't match it", c.RouteID))
+	}
+	return todos
+}
+
+// goRequestAttributes the go source of the request attributes
+func goRequestAttributes(attrs *RequestAttributes) string {
+	var fields []string
+	if attrs.Method != "" {
+		fields = append(fields, fmt.Sprintf("Method: %q", attrs.Method))
+	}
+	fields = append(fields, fmt.Sprintf("Path: %q", attrs.Path))
+	if attrs.Host != "" {
+		fields = append(fields, fmt.Sprintf("Host: %q", attrs.Host))
+	}
+	if len(attrs.Query) > 0 {
+		fields = append(fields, "Query: "+goStringMap(attrs.Query))
+	}
+	if len(attrs.Headers) > 0 {
+		fields = append(fields, "Headers: "+goStringMap(attrs.Headers))
+	}
+	return "&matcher.RequestAttributes{\n" + strings.Join(fields, ",\n") + ",\n}"
+}
+
+// goStringMap the go source of the map, its keys sorted
+func goStringMap(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for _, key := range sortedKeys(m) {
+		pairs = append(pairs, fmt.Sprintf("%q: %q", key, m[key]))
+	}
+	return "map[string]string{" + strings.Join(pairs, ", ") + "}"
+}
+
+// commentOutTODOEntries comments out the table entries following a TODO
+// comment, up to the line closing them
+func commentOutTODOEntries(src string) string {
+	lines := strings.Split(src, "\n")
+	commenting := false
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, goTestEntryIndent+"// TODO "):
+			commenting = true
+		case commenting:
+			lines[i] = goTestEntryIndent + "// " + strings.TrimPrefix(line, goTestEntryIndent)
+			if line == goTestEntryIndent+"}," {
+				commenting = false
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
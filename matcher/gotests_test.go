@@ -0,0 +1,76 @@
+package matcher
+
+import (
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const goTestsRoutes = `orders: Host(/^api[.]example[.]org$/) && Path("/orders/:id") && Method("GET") -> <shunt>;
+search: Path("/search") && QueryParam("q", "^[a-z]+$") && Header("X-Tenant", "acme") -> <shunt>;
+canary: Path("/canary") && Traffic(0.1) -> <shunt>;
+v1: Path("/v1") -> <shunt>;
+shadowed: PathRegexp(/^\/v1/) -> <shunt>;
+`
+
+func TestGenerateGoTests(t *testing.T) {
+	dir, err := ioutil.TempDir("testdata", "gotests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dir, err = filepath.Abs(dir)
+	require.NoError(t, err)
+	routesFile := filepath.Join(dir, "routes.eskip")
+	require.NoError(t, ioutil.WriteFile(routesFile, []byte(goTestsRoutes), 0644))
+
+	var b bytes.Buffer
+	require.NoError(t, GenerateGoTests(&Options{RoutesFile: routesFile}, "routes_test", &b))
+	src := b.String()
+
+	formatted, err := format.Source(b.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, src, string(formatted), "gofmt leaves it unchanged")
+	assert.Contains(t, src, "package routes_test\n")
+	assert.Contains(t, src, `		{
+			route: "orders",
+			request: &matcher.RequestAttributes{
+				Method: "GET",
+				Path:   "/orders/example-id",
+				Host:   "api.example.org",
+			},
+		},
+`)
+	assert.Contains(t, src, `Query:   map[string]string{"q": "a"},`)
+	assert.Contains(t, src, `		// TODO canary: set a value satisfying Traffic(0.1): matches a random share of the requests
+		// {
+		// 	route: "canary",
+`)
+	assert.Contains(t, src, `		// TODO shadowed: the generated request matches route v1 instead
+		// {
+		// 	route: "shadowed",
+`)
+	assert.Equal(t, 3, strings.Count(src, "\t\t{\n"), "the live entries")
+
+	if testing.Short() {
+		t.Skip("building the generated tests in short mode")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "routes_test.go"), b.Bytes(), 0644))
+	out, err := exec.Command(goBin, "test", "-count=1", "./testdata/"+filepath.Base(dir)).CombinedOutput()
+	assert.NoError(t, err, "%s", out)
+}
+
+func TestGenerateGoTestsInvalidPackage(t *testing.T) {
+	err := GenerateGoTests(&Options{RoutesFile: "testdata/routes.eskip"}, "my-routes", &bytes.Buffer{})
+	assert.EqualError(t, err, `invalid package name "my-routes"`)
+}
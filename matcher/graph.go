@@ -0,0 +1,270 @@
+package matcher
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// GraphOptions how ExportDOT renders the routing tree
+type GraphOptions struct {
+	// ClusterHosts renders a tree per Host predicate, each in its own
+	// cluster, instead of showing the Host predicates on the edges
+	ClusterHosts bool
+
+	// MaxNodes bounds the nodes rendered for a tree, 0 for no bound. The
+	// tree is rendered breadth first, once the bound is reached the children
+	// of a node are collapsed into one node with their number of routes.
+	MaxNodes int
+}
+
+// anyHostCluster label of the cluster of the routes without a Host predicate
+const anyHostCluster = "any host"
+
+// graphNode a path segment of the routing tree
+type graphNode struct {
+	segment  string
+	children map[string]*graphNode
+	// routes ending at the node
+	routes []*eskip.Route
+}
+
+func newGraphNode(segment string) *graphNode {
+	return &graphNode{segment: segment, children: map[string]*graphNode{}}
+}
+
+// add adds the route to the tree under its path segments
+func (n *graphNode) add(r *eskip.Route) {
+	node := n
+	for _, segment := range pathSegments(routePath(r)) {
+		child, ok := node.children[segment]
+		if !ok {
+			child = newGraphNode(segment)
+			node.children[segment] = child
+		}
+		node = child
+	}
+	node.routes = append(node.routes, r)
+}
+
+// routeCount number of routes ending at the node or below
+func (n *graphNode) routeCount() int {
+	count := len(n.routes)
+	for _, child := range n.children {
+		count += child.routeCount()
+	}
+	return count
+}
+
+// sortedChildren the children sorted by segment
+func (n *graphNode) sortedChildren() []*graphNode {
+	children := make([]*graphNode, 0, len(n.children))
+	for _, child := range n.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].segment < children[j].segment })
+	return children
+}
+
+// ExportDOT loads the routes file and writes its routing tree as a Graphviz
+// DOT graph: a node per path segment, a leaf per route with its id and
+// backend, the edges to the leaves labeled with the other predicates. The
+// routes without a path are leaves of the root. See Options.Graph.
+func ExportDOT(w io.Writer, o *Options) error {
+	c, err := openFile(o.RoutesFile, o.OnLoadProgress)
+	if err != nil {
+		return err
+	}
+	return writeDOT(w, c.routes, o.Graph)
+}
+
+// writeDOT writes the graph of the routes, sorted by id to keep it stable
+func writeDOT(w io.Writer, routes []*eskip.Route, o GraphOptions) error {
+	sorted := append([]*eskip.Route{}, routes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+
+	g := &dotWriter{w: bufio.NewWriter(w), options: o}
+	g.printf("digraph routes {\n")
+	g.printf("  rankdir=LR;\n")
+	g.printf("  node [shape=ellipse];\n")
+	if !o.ClusterHosts {
+		root := newGraphNode("/")
+		for _, r := range sorted {
+			root.add(r)
+		}
+		g.tree(root, "  ")
+	} else {
+		trees := map[string]*graphNode{}
+		var hosts []string
+		for _, r := range sorted {
+			host := hostLabel(r)
+			if _, ok := trees[host]; !ok {
+				trees[host] = newGraphNode("/")
+				hosts = append(hosts, host)
+			}
+			trees[host].add(r)
+		}
+		sort.Slice(hosts, func(i, j int) bool {
+			// the routes without a host last
+			if (hosts[i] == anyHostCluster) != (hosts[j] == anyHostCluster) {
+				return hosts[j] == anyHostCluster
+			}
+			return hosts[i] < hosts[j]
+		})
+		for i, host := range hosts {
+			g.printf("  subgraph cluster_%d {\n", i)
+			g.printf("    label=%s;\n", dotQuote(host))
+			g.tree(trees[host], "    ")
+			g.printf("  }\n")
+		}
+	}
+	g.printf("}\n")
+	if g.err != nil {
+		return g.err
+	}
+	return g.w.Flush()
+}
+
+// dotWriter writes the DOT statements, keeping the first error
+type dotWriter struct {
+	w       *bufio.Writer
+	options GraphOptions
+	// nodes number of nodes written, used for their ids
+	nodes int
+	err   error
+}
+
+func (g *dotWriter) printf(format string, a ...interface{}) {
+	if g.err == nil {
+		_, g.err = fmt.Fprintf(g.w, format, a...)
+	}
+}
+
+// node writes a node and returns its id
+func (g *dotWriter) node(indent string, label string, attributes string) string {
+	id := fmt.Sprintf("n%d", g.nodes)
+	g.nodes++
+	g.printf("%s%s [label=%s%s];\n", indent, id, dotQuote(label), attributes)
+	return id
+}
+
+func (g *dotWriter) edge(indent string, from, to string, label string) {
+	if label == "" {
+		g.printf("%s%s -> %s;\n", indent, from, to)
+		return
+	}
+	g.printf("%s%s -> %s [label=%s];\n", indent, from, to, dotQuote(label))
+}
+
+// tree writes the nodes of the tree breadth first, up to MaxNodes
+func (g *dotWriter) tree(root *graphNode, indent string) {
+	type queued struct {
+		node *graphNode
+		id   string
+	}
+	written := 1
+	queue := []queued{{root, g.node(indent, root.segment, "")}}
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+
+		children := q.node.sortedChildren()
+		remaining := len(q.node.routes)
+		for _, child := range children {
+			remaining += child.routeCount()
+		}
+		for _, r := range q.node.routes {
+			if g.full(written) {
+				break
+			}
+			leaf := g.node(indent, r.Id+"\n"+backendString(r), ", shape=box")
+			g.edge(indent, q.id, leaf, g.edgeLabel(r))
+			written++
+			remaining--
+		}
+		for _, child := range children {
+			if g.full(written) {
+				break
+			}
+			queue = append(queue, queued{child, g.node(indent, child.segment, "")})
+			g.edge(indent, q.id, queue[len(queue)-1].id, "")
+			written++
+			remaining -= child.routeCount()
+		}
+		if remaining > 0 {
+			collapsed := g.node(indent, fmt.Sprintf("... %d routes", remaining), ", shape=note")
+			g.edge(indent, q.id, collapsed, "")
+		}
+	}
+}
+
+// full true once the tree has MaxNodes nodes
+func (g *dotWriter) full(written int) bool {
+	return g.options.MaxNodes > 0 && written >= g.options.MaxNodes
+}
+
+// edgeLabel the predicates of the route but its path, PathSubtree is kept as
+// a marker, Host too unless the hosts are clusters
+func (g *dotWriter) edgeLabel(r *eskip.Route) string {
+	var labels []string
+	for _, p := range Predicates(r) {
+		switch {
+		case p.Name == pathPredicate:
+		case p.Name == pathSubtreePredicate:
+			labels = append(labels, pathSubtreePredicate)
+		case p.Name == hostPredicate && g.options.ClusterHosts:
+		default:
+			labels = append(labels, predicateString(p))
+		}
+	}
+	return strings.Join(labels, " && ")
+}
+
+// routePath the Path or PathSubtree argument of the route, empty if none
+func routePath(r *eskip.Route) string {
+	for _, p := range Predicates(r) {
+		if p.Name == pathPredicate || p.Name == pathSubtreePredicate {
+			path, _ := stringArg(p, 0)
+			return path
+		}
+	}
+	return ""
+}
+
+// pathSegments the non empty segments of a path
+func pathSegments(path string) []string {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// hostLabel the Host regexps of the route, anyHostCluster if none
+func hostLabel(r *eskip.Route) string {
+	var hosts []string
+	for _, p := range Predicates(r) {
+		if p.Name == hostPredicate {
+			host, _ := stringArg(p, 0)
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return anyHostCluster
+	}
+	return strings.Join(hosts, " && ")
+}
+
+// dotQuote quotes a DOT string, newlines are kept as line breaks
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
@@ -0,0 +1,126 @@
+package matcher
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	dotNodeRx = regexp.MustCompile(`^\s*n\d+ \[label=".*"(, shape=\w+)?\];$`)
+	dotEdgeRx = regexp.MustCompile(`^\s*n\d+ -> n\d+( \[label=".*"\])?;$`)
+)
+
+// countDOT checks the statements of a DOT graph and counts its nodes and edges
+func countDOT(t *testing.T, dot string) (nodes int, edges int, clusters int) {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(dot), "\n")
+	require.Equal(t, "digraph routes {", lines[0])
+	require.Equal(t, "}", lines[len(lines)-1])
+	depth := 0
+	for _, line := range lines {
+		switch {
+		case dotNodeRx.MatchString(line):
+			nodes++
+		case dotEdgeRx.MatchString(line):
+			edges++
+		case strings.HasSuffix(line, "{"):
+			depth++
+			if strings.Contains(line, "subgraph cluster_") {
+				clusters++
+			}
+		case strings.TrimSpace(line) == "}":
+			depth--
+		case strings.HasSuffix(line, ";"):
+		default:
+			t.Errorf("invalid statement %q", line)
+		}
+	}
+	assert.Equal(t, 0, depth, "unbalanced braces")
+	return nodes, edges, clusters
+}
+
+func exportDOT(t *testing.T, routes string, o GraphOptions) string {
+	t.Helper()
+	file := writeRoutes(t, routes)
+	defer os.Remove(file)
+
+	var b bytes.Buffer
+	require.NoError(t, ExportDOT(&b, &Options{RoutesFile: file, Graph: o}))
+	return b.String()
+}
+
+const graphRoutes = `orders: Host(/^api[.]example[.]org$/) && Path("/orders") -> "https://orders.internal";
+order: Host(/^api[.]example[.]org$/) && Path("/orders/:id") && Method("GET") -> "https://orders.internal";
+cart: Host(/^shop[.]example[.]org$/) && PathSubtree("/cart") -> "https://cart.internal";
+health: Path("/health") -> status(200) -> <shunt>;
+legacy: PathRegexp("^/v1/") -> <shunt>;
+`
+
+func TestExportDOT(t *testing.T) {
+	dot := exportDOT(t, graphRoutes, GraphOptions{})
+	assert.Equal(t, `digraph routes {
+  rankdir=LR;
+  node [shape=ellipse];
+  n0 [label="/"];
+  n1 [label="legacy\n<shunt>", shape=box];
+  n0 -> n1 [label="PathRegexp(\"^/v1/\")"];
+  n2 [label="cart"];
+  n0 -> n2;
+  n3 [label="health"];
+  n0 -> n3;
+  n4 [label="orders"];
+  n0 -> n4;
+  n5 [label="cart\nhttps://cart.internal", shape=box];
+  n2 -> n5 [label="Host(\"^shop[.]example[.]org$\") && PathSubtree"];
+  n6 [label="health\n<shunt>", shape=box];
+  n3 -> n6;
+  n7 [label="orders\nhttps://orders.internal", shape=box];
+  n4 -> n7 [label="Host(\"^api[.]example[.]org$\")"];
+  n8 [label=":id"];
+  n4 -> n8;
+  n9 [label="order\nhttps://orders.internal", shape=box];
+  n8 -> n9 [label="Host(\"^api[.]example[.]org$\") && Method(\"GET\")"];
+}
+`, dot)
+	nodes, edges, clusters := countDOT(t, dot)
+	assert.Equal(t, 10, nodes)
+	assert.Equal(t, 9, edges)
+	assert.Equal(t, 0, clusters)
+	assert.Equal(t, dot, exportDOT(t, graphRoutes, GraphOptions{}), "the graph must be deterministic")
+}
+
+func TestExportDOTClusterHosts(t *testing.T) {
+	dot := exportDOT(t, graphRoutes, GraphOptions{ClusterHosts: true})
+	nodes, edges, clusters := countDOT(t, dot)
+	// a root per cluster
+	assert.Equal(t, 12, nodes)
+	assert.Equal(t, 9, edges)
+	assert.Equal(t, 3, clusters)
+	assert.Contains(t, dot, `  subgraph cluster_0 {
+    label="^api[.]example[.]org$";`)
+	assert.Contains(t, dot, `  subgraph cluster_2 {
+    label="any host";`)
+	assert.NotContains(t, dot, "Host(")
+}
+
+func TestExportDOTMaxNodes(t *testing.T) {
+	var routes strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&routes, "r%d: Path(\"/api/%d/%d\") -> <shunt>;\n", i, i%50, i)
+	}
+	dot := exportDOT(t, routes.String(), GraphOptions{MaxNodes: 20})
+	nodes, edges, _ := countDOT(t, dot)
+	assert.True(t, nodes <= 40, "expected about 20 nodes but got %d", nodes)
+	assert.Equal(t, nodes-1, edges)
+	assert.Contains(t, dot, `routes", shape=note];`)
+
+	all, _, _ := countDOT(t, exportDOT(t, routes.String(), GraphOptions{}))
+	assert.Equal(t, 1+1+50+500+500, all)
+}
@@ -0,0 +1,68 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// ErrInvalidHeader the request attributes have headers which can't be sent,
+// see InvalidHeaderError
+var ErrInvalidHeader = errors.New("invalid header")
+
+// InvalidHeaderError the headers of the request attributes with a name which
+// isn't a token, eg. with spaces or colons, or a value with control
+// characters, eg. a bare CR or LF, see Options.AllowInvalidHeaders
+type InvalidHeaderError struct {
+	// Names the invalid header names, sorted
+	Names []string
+	// Values the names of the headers with an invalid value, sorted
+	Values []string
+}
+
+func (e *InvalidHeaderError) Error() string {
+	var problems []string
+	if len(e.Names) > 0 {
+		quoted := make([]string, len(e.Names))
+		for i, name := range e.Names {
+			quoted[i] = fmt.Sprintf("%q", name)
+		}
+		problems = append(problems, "names "+strings.Join(quoted, ", "))
+	}
+	if len(e.Values) > 0 {
+		problems = append(problems, "values of "+strings.Join(e.Values, ", "))
+	}
+	return "invalid header " + strings.Join(problems, " and ")
+}
+
+// Is matches ErrInvalidHeader
+func (e *InvalidHeaderError) Is(target error) bool { return target == ErrInvalidHeader }
+
+// validateHeaders checks the names of the headers against the token grammar
+// and their values like net/http does before sending them
+func validateHeaders(headers map[string]string) error {
+	var e *InvalidHeaderError
+	for name, value := range headers {
+		validName, validValue := httpguts.ValidHeaderFieldName(name), httpguts.ValidHeaderFieldValue(value)
+		if validName && validValue {
+			continue
+		}
+		if e == nil {
+			e = &InvalidHeaderError{}
+		}
+		if !validName {
+			e.Names = append(e.Names, name)
+		} else {
+			e.Values = append(e.Values, name)
+		}
+	}
+	if e == nil {
+		return nil
+	}
+	sort.Strings(e.Names)
+	sort.Strings(e.Values)
+	return e
+}
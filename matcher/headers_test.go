@@ -0,0 +1,73 @@
+package matcher
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidHeaders(t *testing.T) {
+	file := writeRoutes(t, `any: * -> <shunt>;
+other: Path("/other") -> <shunt>;`)
+	defer os.Remove(file)
+
+	m, err := New(&Options{RoutesFile: file})
+	require.NoError(t, err)
+	defer m.Close()
+
+	res := m.Test(&RequestAttributes{Path: "/", Headers: map[string]string{
+		"X-Ok":      "1",
+		"Bad Name":  "1",
+		"X:Colon":   "1",
+		"X-Evil":    "a\nb",
+		"X-Return":  "a\rb",
+		"X-Tab-Ok":  "a\tb",
+		"X-Unicode": "café",
+	}})
+	require.Error(t, res.Err())
+	assert.Nil(t, res.Route())
+	assert.True(t, errors.Is(res.Err(), ErrInvalidHeader))
+	var e *InvalidHeaderError
+	require.True(t, errors.As(res.Err(), &e))
+	assert.Equal(t, []string{"Bad Name", "X:Colon"}, e.Names)
+	assert.Equal(t, []string{"X-Evil", "X-Return"}, e.Values)
+	assert.Equal(t, `invalid header names "Bad Name", "X:Colon" and values of X-Evil, X-Return`, e.Error())
+
+	res = m.Test(&RequestAttributes{Path: "/", Headers: map[string]string{"X-Tab-Ok": "a\tb", "X-Unicode": "café"}})
+	require.NoError(t, res.Err())
+	assert.Equal(t, "any", res.Route().Id)
+	assert.NotEmpty(t, m.Explain(&RequestAttributes{Path: "/"}, 0))
+	assert.Empty(t, m.Explain(&RequestAttributes{Path: "/", Headers: map[string]string{"X-Evil": "a\nb"}}, 0))
+}
+
+func TestInvalidHeadersAllowed(t *testing.T) {
+	file := writeRoutes(t, `evil: Header("Bad Name", "1") -> <shunt>;
+any: * -> <shunt>;`)
+	defer os.Remove(file)
+
+	for _, light := range []bool{false, true} {
+		m, err := New(&Options{RoutesFile: file, AllowInvalidHeaders: true, LightResults: light})
+		require.NoError(t, err)
+		in, out := make(chan *RequestAttributes, 1), make(chan TestResult, 1)
+		in <- &RequestAttributes{Path: "/", Headers: map[string]string{"Bad Name": "1"}}
+		close(in)
+		go m.TestManyStream(in, out)
+		res := <-out
+		require.NoError(t, res.Err())
+		assert.Equal(t, "evil", res.Route().Id, "the malformed request is tested")
+		m.Close()
+	}
+
+	// the light results are validated too
+	m, err := New(&Options{RoutesFile: file, LightResults: true})
+	require.NoError(t, err)
+	defer m.Close()
+	in, out := make(chan *RequestAttributes, 1), make(chan TestResult, 1)
+	in <- &RequestAttributes{Path: "/", Headers: map[string]string{"X-Evil": "a\nb"}}
+	close(in)
+	go m.TestManyStream(in, out)
+	assert.True(t, errors.Is((<-out).Err(), ErrInvalidHeader))
+}
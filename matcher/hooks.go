@@ -0,0 +1,293 @@
+package matcher
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+// Hooks functions a matcher calls to report what it does, eg. to feed metrics.
+// They are called synchronously: OnTest in the goroutine calling Test, before
+// Test returns, OnReload in the routing goroutine, so they should be fast.
+// A nil hook isn't called and costs nothing, a panicking hook is recovered
+// and reported in the diagnostics.
+type Hooks struct {
+	// OnTest called after every test with a copy of the normalized
+	// attributes and of the result, the result error and the test duration
+	OnTest func(attributes *RequestAttributes, result TestResult, err error, d time.Duration)
+	// OnReload called when a routes table is applied, the first one included,
+	// with the number of its valid routes, or when loading the routes failed
+	OnReload func(routes int, err error)
+}
+
+// routesApplied message logged by the routing when it applies a routes table
+const routesApplied = "route settings applied"
+
+// routesReceived message logged by the routing when it starts processing a
+// routes table, before it logs the routes it drops
+const routesReceived = "route settings received"
+
+// routesLoadFailed prefix of the messages logged by the routing when loading the routes fails
+const routesLoadFailed = "error while receiv"
+
+// routeRejectedRxs messages logged by the routing when it drops an invalid
+// route, capturing the route id and the reason: one with an unknown filter or
+// predicate, or one failing to be added to the tree, eg. an invalid regexp
+var routeRejectedRxs = []*regexp.Regexp{
+	regexp.MustCompile(`^failed to process route \((.+?)\): (.*)$`),
+	regexp.MustCompile(`^(\w+) \[\d+\]: (.*)$`),
+}
+
+// safely calls a hook, recovering and reporting its panic
+func safely(diag *diagnostics, name string, hook func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			diag.printf(VerbositySilent, "hook %s panicked: %v", name, r)
+		}
+	}()
+	hook()
+}
+
+// onTest calls the OnTest hook with a copy of the result
+func (f *matcher) onTest(result *testResult, d time.Duration) {
+	frozen := result.frozen()
+	safely(f.diag, "OnTest", func() {
+		f.options.Hooks.OnTest(frozen.attributes, frozen, frozen.err, d)
+	})
+}
+
+// frozen returns a copy of the result sharing nothing with it
+func (t *testResult) frozen() *testResult {
+	c := &testResult{
+		err:           t.err,
+		forcedTraffic: append([]string(nil), t.forcedTraffic...),
+		lbMember:      t.lbMember,
+		params:        cloneMap(t.params),
+		registry:      t.registry,
+		defaults:      t.defaults,
+		trace:         cloneVerdicts(t.trace),
+		lookup:        cloneLookup(t.lookup),
+		logs:          append([]string(nil), t.logs...),
+	}
+	if t.attributes != nil {
+		c.attributes = cloneAttributes(t.attributes)
+	}
+	if t.route != nil {
+		c.route = t.route.Copy()
+	}
+	if t.req != nil {
+		c.req = cloneRequest(t.req)
+	}
+	if t.hops != nil {
+		c.hops = make([]TestResult, len(t.hops))
+		for i, hop := range t.hops {
+			if hop == TestResult(t) {
+				c.hops[i] = c
+			} else {
+				c.hops[i] = hop.(*testResult).frozen()
+			}
+		}
+	}
+	return c
+}
+
+// cloneVerdicts copies the verdicts and their captures
+func cloneVerdicts(verdicts []PredicateVerdict) []PredicateVerdict {
+	if verdicts == nil {
+		return nil
+	}
+	c := make([]PredicateVerdict, len(verdicts))
+	for i, v := range verdicts {
+		c[i] = v
+		c[i].Captures = append([]string(nil), v.Captures...)
+	}
+	return c
+}
+
+// cloneAttributes copies the attributes and their maps
+func cloneAttributes(a *RequestAttributes) *RequestAttributes {
+	c := *a
+	c.Query = cloneMap(a.Query)
+	c.Headers = cloneMap(a.Headers)
+	return &c
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// routingEvents routing post processor counting the routes of the tables,
+// it tracks the reloads the routing logs and calls the OnReload hook
+type routingEvents struct {
+	// onReload the OnReload hook, nil if not set
+	onReload func(routes int, err error)
+	// onDropped the Options.OnRouteDropped callback, nil if not set
+	onDropped func(routeID string, reason error)
+	diag      *diagnostics
+	// processed number of routes of the last processed table
+	processed int64
+	// updated unix nanoseconds of the first update received since the last
+	// applied table, 0 if none
+	updated int64
+	// reloads number of tables applied after an update, lastReload the
+	// duration of the last one
+	reloads, lastReload int64
+	// table holds the routeTable of the last processed table
+	table atomic.Value
+	// loadErr holds a loadError, the one of the last load
+	loadErr atomic.Value
+	// rejected why the routing dropped a route of the last processed table
+	// by route id, reset when the routing receives a table, guarded by mu
+	mu       sync.Mutex
+	rejected map[string]string
+}
+
+// routeTable routes of a processed table, replaced as a whole on reload
+type routeTable struct {
+	// routes sorted by id
+	routes []*eskip.Route
+	// byID routes by id
+	byID map[string]*eskip.Route
+}
+
+// loadError error of a routes load, nil if it succeeded
+type loadError struct {
+	err error
+}
+
+func newRoutingEvents(onReload func(routes int, err error), diag *diagnostics) *routingEvents {
+	e := &routingEvents{onReload: onReload, diag: diag, rejected: map[string]string{}}
+	e.loadErr.Store(loadError{})
+	e.table.Store(&routeTable{})
+	return e
+}
+
+func (e *routingEvents) Do(routes []*routing.Route) []*routing.Route {
+	table := &routeTable{routes: make([]*eskip.Route, len(routes)), byID: make(map[string]*eskip.Route, len(routes))}
+	for i, r := range routes {
+		table.routes[i] = &r.Route
+		table.byID[r.Id] = &r.Route
+	}
+	sort.SliceStable(table.routes, func(i, j int) bool { return table.routes[i].Id < table.routes[j].Id })
+	e.table.Store(table)
+	atomic.StoreInt64(&e.processed, int64(len(routes)))
+	return routes
+}
+
+// routes returns a copy of the routes of the last processed table, sorted by id
+func (e *routingEvents) routes() []*eskip.Route {
+	table := e.table.Load().(*routeTable)
+	routes := make([]*eskip.Route, len(table.routes))
+	for i, r := range table.routes {
+		routes[i] = copyRoute(r)
+	}
+	return routes
+}
+
+// route returns a copy of the route with the id of the last processed table
+func (e *routingEvents) route(id string) (*eskip.Route, bool) {
+	r, ok := e.table.Load().(*routeTable).byID[id]
+	if !ok {
+		return nil, false
+	}
+	return copyRoute(r), true
+}
+
+// copyRoute copies a route, its predicates, filters and endpoints too
+func copyRoute(r *eskip.Route) *eskip.Route {
+	c := *r
+	c.Predicates, c.Filters = nil, nil
+	for _, p := range r.Predicates {
+		cp := *p
+		cp.Args = append([]interface{}(nil), p.Args...)
+		c.Predicates = append(c.Predicates, &cp)
+	}
+	for _, f := range r.Filters {
+		cf := *f
+		cf.Args = append([]interface{}(nil), f.Args...)
+		c.Filters = append(c.Filters, &cf)
+	}
+	c.HostRegexps = append([]string(nil), r.HostRegexps...)
+	c.PathRegexps = append([]string(nil), r.PathRegexps...)
+	c.LBEndpoints = append([]string(nil), r.LBEndpoints...)
+	if r.Headers != nil {
+		c.Headers = make(map[string]string, len(r.Headers))
+		for name, value := range r.Headers {
+			c.Headers[name] = value
+		}
+	}
+	if r.HeaderRegexps != nil {
+		c.HeaderRegexps = make(map[string][]string, len(r.HeaderRegexps))
+		for name, rxs := range r.HeaderRegexps {
+			c.HeaderRegexps[name] = append([]string(nil), rxs...)
+		}
+	}
+	return &c
+}
+
+// logged tracks the reload reported by the routing log line, if any
+func (e *routingEvents) logged(level string, msg string) {
+	switch {
+	case level == "info" && msg == routesReceived:
+		e.mu.Lock()
+		e.rejected = map[string]string{}
+		e.mu.Unlock()
+	case level == "info" && msg == routesApplied:
+		e.loadErr.Store(loadError{})
+		e.reloadApplied()
+		e.reloaded(int(atomic.LoadInt64(&e.processed)), nil)
+	case level == "error" && strings.HasPrefix(msg, routesLoadFailed):
+		err := errors.New(msg)
+		e.loadErr.Store(loadError{err})
+		e.reloaded(0, err)
+	case level == "error":
+		for _, rx := range routeRejectedRxs {
+			if m := rx.FindStringSubmatch(msg); m != nil {
+				e.mu.Lock()
+				e.rejected[m[1]] = m[2]
+				e.mu.Unlock()
+				if e.onDropped != nil {
+					safely(e.diag, "OnRouteDropped", func() { e.onDropped(m[1], errors.New(m[2])) })
+				}
+				break
+			}
+		}
+	}
+}
+
+// rejections returns why the routing dropped the routes of the last
+// processed table by route id
+func (e *routingEvents) rejections() map[string]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	rejected := make(map[string]string, len(e.rejected))
+	for id, reason := range e.rejected {
+		rejected[id] = reason
+	}
+	return rejected
+}
+
+func (e *routingEvents) reloaded(routes int, err error) {
+	if e.onReload != nil {
+		safely(e.diag, "OnReload", func() { e.onReload(routes, err) })
+	}
+}
+
+// err returns the error of the last load
+func (e *routingEvents) err() error {
+	return e.loadErr.Load().(loadError).err
+}
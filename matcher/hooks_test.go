@@ -0,0 +1,193 @@
+package matcher
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/filters/builtin"
+	"github.com/zalando/skipper/routing"
+	"github.com/zalando/skipper/routing/testdataclient"
+)
+
+// reloadCall arguments of an OnReload call
+type reloadCall struct {
+	routes int
+	err    error
+}
+
+func TestHooksOnTest(t *testing.T) {
+	type call struct {
+		attributes *RequestAttributes
+		result     TestResult
+		err        error
+		d          time.Duration
+	}
+	var calls []call
+	m, err := New(&Options{
+		RoutesFile:  "testdata/routes.eskip",
+		MockFilters: []string{"customfilter"},
+		Hooks: Hooks{OnTest: func(attributes *RequestAttributes, result TestResult, err error, d time.Duration) {
+			calls = append(calls, call{attributes, result, err, d})
+			// hooks work on copies
+			attributes.Path = "/changed"
+			attributes.Headers["Accept"] = "changed"
+			if route := result.Route(); route != nil {
+				route.Id = "changed"
+				route.Path = "/changed"
+				route.Headers["Accept"] = "changed"
+			}
+			if req := result.Request(); req != nil {
+				req.URL.Path = "/changed"
+				req.Header.Set("Accept", "changed")
+			}
+		}},
+	})
+	require.NoError(t, err)
+
+	res := m.Test(&RequestAttributes{Method: "get", Path: "foo", Headers: map[string]string{"Accept": "application/json"}})
+	require.Len(t, calls, 1)
+	require.NotNil(t, res.Route())
+	assert.Equal(t, "foo_header", res.Route().Id)
+	assert.Equal(t, "/foo", res.Route().Path)
+	assert.Equal(t, "application/json", res.Route().Headers["Accept"])
+	assert.Equal(t, "/foo", res.Attributes().Path)
+	assert.Equal(t, "application/json", res.Attributes().Headers["Accept"])
+	assert.Equal(t, "/foo", res.Request().URL.Path)
+	assert.Equal(t, "application/json", res.Request().Header.Get("Accept"))
+	assert.NoError(t, calls[0].err)
+	assert.True(t, calls[0].d > 0)
+	assert.Equal(t, "changed", calls[0].result.Route().Id)
+
+	m.Test(&RequestAttributes{Path: "/a", Host: "bad host"})
+	require.Len(t, calls, 2)
+	assert.Error(t, calls[1].err)
+	assert.Nil(t, calls[1].result.Route())
+
+	m.Close()
+	m.Test(&RequestAttributes{Path: "/bar"})
+	require.Len(t, calls, 3)
+	assert.True(t, errors.Is(calls[2].err, ErrClosed))
+}
+
+func TestHooksPanic(t *testing.T) {
+	var log bytes.Buffer
+	m, err := New(&Options{
+		RoutesFile:  "testdata/routes.eskip",
+		MockFilters: []string{"customfilter"},
+		Log:         &log,
+		Hooks: Hooks{
+			OnTest:   func(*RequestAttributes, TestResult, error, time.Duration) { panic("test hook bug") },
+			OnReload: func(int, error) { panic("reload hook bug") },
+		},
+	})
+	require.NoError(t, err)
+	defer m.Close()
+
+	res := m.Test(&RequestAttributes{Path: "/bar"})
+	require.NotNil(t, res.Route())
+	assert.Equal(t, "bar", res.Route().Id)
+	assert.Contains(t, log.String(), "hook OnTest panicked: test hook bug")
+	assert.Contains(t, log.String(), "hook OnReload panicked: reload hook bug")
+}
+
+func TestHooksOnReload(t *testing.T) {
+	defer func(d time.Duration) { pollTimeout = d }(pollTimeout)
+	pollTimeout = 10 * time.Millisecond
+
+	var mu sync.Mutex
+	var calls []reloadCall
+	reloads := func() []reloadCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]reloadCall{}, calls...)
+	}
+	o := &Options{Hooks: Hooks{OnReload: func(routes int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, reloadCall{routes, err})
+	}}}
+
+	client, err := testdataclient.NewDoc(`a: Path("/a") -> <shunt>; invalid: Path("/invalid") -> nosuchfilter() -> <shunt>;`)
+	require.NoError(t, err)
+	diag := newDiagnostics(o)
+	router, _, err := createRouting([]routing.DataClient{client}, builtin.MakeRegistry(), o, diag, time.Now().Add(time.Second), time.Second)
+	require.NoError(t, err)
+	defer router.Close()
+
+	waitReloads := func(n int) []reloadCall {
+		deadline := time.Now().Add(time.Second)
+		for len(reloads()) < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d reload(s), got %v", n, reloads())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return reloads()
+	}
+	assert.Equal(t, []reloadCall{{1, nil}}, waitReloads(1), "the first table has one valid route")
+
+	// a failing update is reported, then the routing loads all the routes again
+	client.FailNext()
+	go client.UpdateDoc(`b: Path("/b") -> <shunt>;`, nil)
+	got := waitReloads(3)
+	require.Error(t, got[1].err)
+	assert.Contains(t, got[1].err.Error(), "failed to get routes")
+	assert.Equal(t, reloadCall{2, nil}, got[2])
+}
+
+func TestRejectionsOfTheLastTable(t *testing.T) {
+	defer func(d time.Duration) { pollTimeout = d }(pollTimeout)
+	pollTimeout = 10 * time.Millisecond
+
+	client, err := testdataclient.NewDoc(`a: Path("/a") -> <shunt>; bad: Path("/bad") -> setPath(42) -> <shunt>;`)
+	require.NoError(t, err)
+	o := &Options{}
+	router, events, err := createRouting([]routing.DataClient{client}, builtin.MakeRegistry(), o, newDiagnostics(o), time.Now().Add(time.Second), time.Second)
+	require.NoError(t, err)
+	defer router.Close()
+	assert.Equal(t, []string{"bad"}, sortedKeys(events.rejections()))
+
+	waitRejections := func(want ...string) {
+		deadline := time.Now().Add(time.Second)
+		for !assert.ObjectsAreEqual(append([]string{}, want...), sortedKeys(events.rejections())) {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected the rejections %v, got %v", want, events.rejections())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	// the route fixed isn't rejected anymore, the routing polls the update
+	// so it can't be sent inline
+	go client.UpdateDoc(`bad: Path("/bad") -> setPath("/fixed") -> <shunt>; other: Path("/other") -> setPath(1) -> <shunt>;`, nil)
+	waitRejections("other")
+
+	go client.UpdateDoc(``, []string{"other"})
+	waitRejections()
+}
+
+func BenchmarkTestHooks(b *testing.B) {
+	for _, hooked := range []bool{false, true} {
+		name := "nil"
+		o := &Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}}
+		if hooked {
+			name = "set"
+			o.Hooks.OnTest = func(*RequestAttributes, TestResult, error, time.Duration) {}
+		}
+		b.Run(name, func(b *testing.B) {
+			m, err := New(o)
+			require.NoError(b, err)
+			defer m.Close()
+			attrs := &RequestAttributes{Path: "/foo", Headers: map[string]string{"Accept": "application/json"}}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.Test(attrs)
+			}
+		})
+	}
+}
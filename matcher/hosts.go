@@ -0,0 +1,241 @@
+package matcher
+
+import (
+	"regexp/syntax"
+	"strings"
+	"unicode"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// maxHostCandidates number of hosts a Host regexp is expanded to, a larger
+// one can't be verified
+const maxHostCandidates = 64
+
+// hostsNotAllowed reports the Host predicates which can match a host outside
+// the allowed domains, and the ones which can't be verified, only with
+// AllowedHostSuffixes
+func (l *linter) hostsNotAllowed() {
+	suffixes := allowedSuffixes(l.rules.AllowedHostSuffixes)
+	if len(suffixes) == 0 {
+		return
+	}
+	domains := strings.Join(suffixes, ", ")
+	for _, r := range l.routes {
+		for _, p := range Predicates(r.route) {
+			if p.Name != hostPredicate {
+				continue
+			}
+			pattern, ok := stringArg(p, 0)
+			if !ok {
+				continue
+			}
+			re, err := syntax.Parse(pattern, syntax.Perl)
+			if err != nil {
+				// reported by invalid-regexp
+				continue
+			}
+			outside, verified := hostOutside(re.Simplify(), suffixes)
+			switch {
+			case outside != "":
+				l.report(RuleHostNotAllowed, SeverityError, r, "%s can match host %s outside the allowed domains (%s)", predicateString(p), outside, domains)
+			case !verified:
+				l.report(RuleHostNotAllowed, SeverityWarning, r, "cannot verify %s matches only hosts of the allowed domains (%s)", predicateString(p), domains)
+			}
+		}
+	}
+}
+
+// anyHosts reports the routes without a Host predicate, only with AllowedHostSuffixes
+func (l *linter) anyHosts() {
+	if len(allowedSuffixes(l.rules.AllowedHostSuffixes)) == 0 {
+		return
+	}
+	for _, r := range l.routes {
+		if !hasHost(r.route) {
+			l.report(RuleAnyHost, SeverityWarning, r, "route has no Host predicate, it matches any host")
+		}
+	}
+}
+
+// hasHost true if the route has a Host predicate
+func hasHost(r *eskip.Route) bool {
+	for _, p := range Predicates(r) {
+		if p.Name == hostPredicate {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedSuffixes lower cases the suffixes and removes their leading dot
+func allowedSuffixes(suffixes []string) []string {
+	var allowed []string
+	for _, s := range suffixes {
+		if s = strings.ToLower(strings.TrimPrefix(s, ".")); s != "" {
+			allowed = append(allowed, s)
+		}
+	}
+	return allowed
+}
+
+// hostAllowed true if the host, without its port, is one of the suffixes or a subdomain of one
+func hostAllowed(host string, suffixes []string) bool {
+	if i := strings.LastIndex(host, ":"); i >= 0 && isDigits(host[i+1:]) {
+		host = host[:i]
+	}
+	for _, s := range suffixes {
+		if host == s || strings.HasSuffix(host, "."+s) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// hostOutside returns a host the Host regexp matches outside the allowed
+// domains, if it finds one. verified is true when the regexp is known to
+// match only allowed hosts or a host outside was found: the regexps which
+// can be expanded to a few hosts are, as well as the ones ending with a
+// literal subdomain of an allowed suffix, eg. ^[a-z]+[.]example[.]org$
+func hostOutside(re *syntax.Regexp, suffixes []string) (outside string, verified bool) {
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+	anchoredStart := len(subs) > 0 && (subs[0].Op == syntax.OpBeginText || subs[0].Op == syntax.OpBeginLine)
+	if anchoredStart {
+		subs = subs[1:]
+	}
+	n := len(subs)
+	anchoredEnd := n > 0 && (subs[n-1].Op == syntax.OpEndText || subs[n-1].Op == syntax.OpEndLine)
+	if anchoredEnd {
+		subs = subs[:n-1]
+		if n := len(subs); n > 0 && isPortPattern(subs[n-1]) {
+			subs = subs[:n-1]
+		}
+	}
+
+	if hosts, ok := expandConcat(subs); ok {
+		for _, h := range hosts {
+			// an unanchored regexp matches the hosts containing the expanded ones
+			if !anchoredStart {
+				h = "x" + h
+			}
+			if !anchoredEnd {
+				h += ".invalid"
+			}
+			if !hostAllowed(h, suffixes) {
+				return h, true
+			}
+		}
+		if anchoredStart && anchoredEnd {
+			return "", true
+		}
+	}
+
+	if anchoredEnd {
+		tail := literalTail(subs)
+		for _, s := range suffixes {
+			if strings.HasSuffix(tail, "."+s) {
+				return "", true
+			}
+		}
+	}
+	return "", false
+}
+
+// isPortPattern true for an optional port, eg. (:\d+)?
+func isPortPattern(re *syntax.Regexp) bool {
+	if re.Op != syntax.OpQuest {
+		return false
+	}
+	sub := re.Sub[0]
+	for sub.Op == syntax.OpCapture {
+		sub = sub.Sub[0]
+	}
+	return sub.Op == syntax.OpConcat && sub.Sub[0].Op == syntax.OpLiteral && string(sub.Sub[0].Rune) == ":"
+}
+
+// literalTail the lower cased literal the regexps end with
+func literalTail(subs []*syntax.Regexp) string {
+	tail := ""
+	for i := len(subs) - 1; i >= 0 && subs[i].Op == syntax.OpLiteral; i-- {
+		tail = string(subs[i].Rune) + tail
+	}
+	return strings.ToLower(tail)
+}
+
+// expandConcat expands the concatenation of the regexps to the lower cased
+// strings it matches, ok is false if they're more than maxHostCandidates
+// or unbounded
+func expandConcat(subs []*syntax.Regexp) ([]string, bool) {
+	result := []string{""}
+	for _, sub := range subs {
+		expanded, ok := expandRegexp(sub)
+		if !ok || len(result)*len(expanded) > maxHostCandidates {
+			return nil, false
+		}
+		var next []string
+		for _, prefix := range result {
+			for _, s := range expanded {
+				next = append(next, prefix+s)
+			}
+		}
+		result = next
+	}
+	return result, true
+}
+
+// expandRegexp see expandConcat
+func expandRegexp(re *syntax.Regexp) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return []string{""}, true
+	case syntax.OpLiteral:
+		return []string{strings.ToLower(string(re.Rune))}, true
+	case syntax.OpCharClass:
+		var chars []string
+		seen := map[rune]bool{}
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			if int(re.Rune[i+1]-re.Rune[i]) >= maxHostCandidates {
+				return nil, false
+			}
+			for c := re.Rune[i]; c <= re.Rune[i+1]; c++ {
+				if lc := unicode.ToLower(c); !seen[lc] {
+					seen[lc] = true
+					chars = append(chars, string(lc))
+				}
+			}
+		}
+		return chars, len(chars) <= maxHostCandidates
+	case syntax.OpCapture:
+		return expandRegexp(re.Sub[0])
+	case syntax.OpConcat:
+		return expandConcat(re.Sub)
+	case syntax.OpQuest:
+		expanded, ok := expandRegexp(re.Sub[0])
+		return append([]string{""}, expanded...), ok && len(expanded) < maxHostCandidates
+	case syntax.OpAlternate:
+		var all []string
+		for _, sub := range re.Sub {
+			expanded, ok := expandRegexp(sub)
+			if !ok || len(all)+len(expanded) > maxHostCandidates {
+				return nil, false
+			}
+			all = append(all, expanded...)
+		}
+		return all, true
+	default:
+		// unbounded repetitions, any char, ...
+		return nil, false
+	}
+}
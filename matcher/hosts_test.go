@@ -0,0 +1,59 @@
+package matcher
+
+import (
+	"regexp/syntax"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostOutside(t *testing.T) {
+	suffixes := []string{"example.org", "example.com"}
+	tests := []struct {
+		pattern  string
+		outside  string
+		verified bool
+	}{
+		{pattern: `^example[.]org$`, verified: true},
+		{pattern: `^(www|api)[.]example[.]org(:\d+)?$`, verified: true},
+		{pattern: `^(?i)API[.]Example[.]COM$`, verified: true},
+		{pattern: `^[a-z]+[.]example[.]org$`, verified: true},
+		{pattern: `[.]example[.]org$`, verified: true},
+		{pattern: `^shop[.]example[.]org:8080$`, verified: true},
+		{pattern: `^(www[.]example[.]org|example[.]net)$`, outside: "example.net", verified: true},
+		{pattern: `^example[.]org[.]evil[.]io$`, outside: "example.org.evil.io", verified: true},
+		{pattern: `example[.]org$`, outside: "xexample.org", verified: true},
+		{pattern: `^example[.]org`, outside: "example.org.invalid", verified: true},
+		{pattern: `^[a-z]+example[.]org$`},
+		{pattern: `^.*$`},
+		{pattern: `^example.org$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			re, err := syntax.Parse(tt.pattern, syntax.Perl)
+			require.NoError(t, err)
+			outside, verified := hostOutside(re.Simplify(), suffixes)
+			assert.Equal(t, tt.outside, outside)
+			assert.Equal(t, tt.verified, verified)
+		})
+	}
+}
+
+func TestLintHosts(t *testing.T) {
+	docs := []Document{{File: "routes.eskip", Content: []byte(`shop: Host(/^shop[.]example[.]org$/) && Path("/shop") -> <shunt>;
+partner: Host(/^partner[.]example[.]net$/) && Path("/partner") -> <shunt>;
+any: Path("/any") -> <shunt>;
+open: Host(/^[a-z]+example[.]org$/) && Path("/open") -> <shunt>;
+`)}}
+	assert.Empty(t, Lint(docs, nil))
+
+	finding := func(rule string, severity Severity, route string, line int, message string) Finding {
+		return Finding{RuleID: rule, Severity: severity, RouteID: route, File: "routes.eskip", Line: line, Message: message}
+	}
+	assert.Equal(t, []Finding{
+		finding(RuleHostNotAllowed, SeverityError, "partner", 2, `Host("^partner[.]example[.]net$") can match host partner.example.net outside the allowed domains (example.org)`),
+		finding(RuleAnyHost, SeverityWarning, "any", 3, "route has no Host predicate, it matches any host"),
+		finding(RuleHostNotAllowed, SeverityWarning, "open", 4, `cannot verify Host("^[a-z]+example[.]org$") matches only hosts of the allowed domains (example.org)`),
+	}, Lint(docs, &LintOptions{AllowedHostSuffixes: []string{".Example.org"}}))
+}
@@ -0,0 +1,108 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// ImpactReport result of TrailingSlashImpact and PredicateImpact
+type ImpactReport struct {
+	// Change the compared change of the options, eg. IgnoreTrailingSlash
+	Change string `json:"change"`
+	// Requests number of requests tested with and without the change
+	Requests int `json:"requests"`
+	// Unchanged number of requests routed the same way
+	Unchanged int `json:"unchanged"`
+	// Changes the requests routed differently, Old without the change and
+	// New with it, in the requests order
+	Changes []RequestChange `json:"changes"`
+	// Routes ids of the routes matching the changed requests on either side,
+	// sorted
+	Routes []string `json:"routes"`
+	// Collisions the routes matching the same requests once the trailing
+	// slashes are ignored, sorted by route id, TrailingSlashImpact only
+	Collisions []SlashCollision `json:"collisions,omitempty"`
+	// Predicates the changes by mocked predicate, sorted by name,
+	// PredicateImpact only
+	Predicates []PredicateChanges `json:"predicates,omitempty"`
+	// Vestigial the mocked predicates none of the requests depends on,
+	// sorted, PredicateImpact only
+	Vestigial []string `json:"vestigial,omitempty"`
+	// Caution what the report doesn't prove
+	Caution string `json:"caution,omitempty"`
+}
+
+// PredicateChanges the requests routed differently once a predicate always
+// matches
+type PredicateChanges struct {
+	// Name name of the predicate
+	Name string `json:"name"`
+	// Changes the requests whose route, with or without the mock, uses the
+	// predicate, in the requests order
+	Changes []RequestChange `json:"changes"`
+}
+
+// changedRoutes the sorted ids of the routes matching the changes on either
+// side
+func changedRoutes(changes []RequestChange) []string {
+	routes := []string{}
+	seen := make(map[string]bool)
+	for _, c := range changes {
+		for _, res := range []*ResultData{c.Old, c.New} {
+			if res.Matched && !seen[res.RouteID] {
+				seen[res.RouteID] = true
+				routes = append(routes, res.RouteID)
+			}
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// WriteText writes the counts, a line for each change, the changes by
+// predicate, the collisions and the caution
+func (r *ImpactReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "%d of %d request(s) change with %s", len(r.Changes), r.Requests, r.Change)
+	if len(r.Routes) > 0 {
+		fmt.Fprintf(w, ", route(s) %s", strings.Join(r.Routes, ", "))
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(r.Changes) > 0 {
+		fmt.Fprintln(tw, "  REQUEST\tCHANGE\tWITHOUT\tWITH")
+		for _, c := range r.Changes {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", requestLine(c.Request), c.Class, diffDestination(c.Old), diffDestination(c.New))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, p := range r.Predicates {
+		fmt.Fprintf(w, "predicate %s: %d request(s) change\n", p.Name, len(p.Changes))
+	}
+	if len(r.Vestigial) > 0 {
+		fmt.Fprintf(w, "no impact: %s\n", strings.Join(r.Vestigial, ", "))
+	}
+	if r.Collisions != nil {
+		fmt.Fprintf(w, "%d route collision(s) ignoring the trailing slashes\n", len(r.Collisions))
+		for _, c := range r.Collisions {
+			fmt.Fprintf(w, "  %s and %s: %s\n", c.Route, c.Other, c.Predicates)
+		}
+	}
+	if r.Caution != "" {
+		fmt.Fprintf(w, "caution: %s\n", r.Caution)
+	}
+	return nil
+}
+
+// WriteJSON writes the report in indented json
+func (r *ImpactReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,63 @@
+package matcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/routing"
+	"github.com/zalando/skipper/routing/testdataclient"
+)
+
+func TestInitStats(t *testing.T) {
+	start := time.Now()
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	require.NoError(t, err)
+	elapsed := time.Since(start)
+
+	s := m.InitStats()
+	assert.Equal(t, 8, s.Routes)
+	for name, d := range map[string]time.Duration{"read": s.Read, "parse": s.Parse, "registry": s.Registry, "build": s.Build} {
+		assert.True(t, d > 0, "%s not timed", name)
+	}
+	sum := s.Read + s.Parse + s.Registry + s.Build
+	assert.True(t, sum <= s.Total, "phases %s longer than the total %s", sum, s.Total)
+	assert.True(t, s.Total <= elapsed)
+	assert.Zero(t, s.Reloads)
+	assert.Regexp(t, `^loaded 8 routes in \S+ \(read \S+, parse \S+, registry \S+, build \S+\)$`, s.String())
+
+	require.NoError(t, m.Close())
+	assert.Equal(t, InitStats{}, m.InitStats())
+}
+
+func TestInitStatsReload(t *testing.T) {
+	defer func(d time.Duration) { pollTimeout = d }(pollTimeout)
+	pollTimeout = 10 * time.Millisecond
+
+	client, err := testdataclient.NewDoc(`a: Path("/a") -> <shunt>;`)
+	require.NoError(t, err)
+	create := newDataClients
+	defer func() { newDataClients = create }()
+	newDataClients = func(o *Options) ([]routing.DataClient, error) {
+		return []routing.DataClient{client}, nil
+	}
+
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip"})
+	require.NoError(t, err)
+	defer m.Close()
+	s := m.InitStats()
+	assert.Equal(t, 1, s.Routes)
+	assert.True(t, s.Read > 0, "the load of another data client is the read phase")
+	assert.Zero(t, s.Parse)
+
+	require.NoError(t, client.UpdateDoc(`b: Path("/b") -> <shunt>;`, nil))
+	deadline := time.Now().Add(time.Second)
+	for m.InitStats().Reloads == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	reloaded := m.InitStats()
+	assert.Equal(t, 1, reloaded.Reloads)
+	assert.True(t, reloaded.LastReload > 0)
+	assert.Equal(t, s.Total, reloaded.Total, "the initialization timings don't change")
+}
@@ -0,0 +1,92 @@
+package matcher
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/zalando/skipper/predicates/interval"
+	"github.com/zalando/skipper/routing"
+)
+
+// intervalSpec an interval predicate spec, Between, Before or After, whose
+// predicates evaluate against the instant of Matcher.TestAt, the current
+// time otherwise. The arguments are checked by the skipper spec.
+type intervalSpec struct {
+	spec routing.PredicateSpec
+}
+
+// intervalSpecs the interval predicate specs of the matchers
+func intervalSpecs() []routing.PredicateSpec {
+	return []routing.PredicateSpec{
+		&intervalSpec{interval.NewBetween()},
+		&intervalSpec{interval.NewBefore()},
+		&intervalSpec{interval.NewAfter()},
+	}
+}
+
+func (s *intervalSpec) Name() string { return s.spec.Name() }
+
+func (s *intervalSpec) Create(args []interface{}) (routing.Predicate, error) {
+	if _, err := s.spec.Create(args); err != nil {
+		return nil, err
+	}
+	p := &intervalPredicate{name: s.spec.Name()}
+	switch p.name {
+	case betweenPredicate:
+		p.begin, _ = intervalArg(args[0])
+		p.end, _ = intervalArg(args[1])
+	case beforePredicate:
+		p.end, _ = intervalArg(args[0])
+	case afterPredicate:
+		p.begin, _ = intervalArg(args[0])
+	}
+	return p, nil
+}
+
+// intervalArg parses a date argument like skipper, an RFC3339 string or
+// unix seconds
+func intervalArg(arg interface{}) (time.Time, bool) {
+	switch a := arg.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339, a)
+		return t, err == nil
+	case float64:
+		return time.Unix(int64(a), 0), true
+	case int64:
+		return time.Unix(a, 0), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// intervalPredicate see intervalSpec, like skipper the lower boundary is
+// included and the upper one excluded
+type intervalPredicate struct {
+	name  string
+	begin time.Time
+	end   time.Time
+}
+
+func (p *intervalPredicate) Match(req *http.Request) bool {
+	now, ok := req.Context().Value(testTimeKey{}).(time.Time)
+	if !ok {
+		now = time.Now()
+	}
+	switch p.name {
+	case betweenPredicate:
+		return !now.Before(p.begin) && now.Before(p.end)
+	case beforePredicate:
+		return now.Before(p.end)
+	default:
+		return !now.Before(p.begin)
+	}
+}
+
+// testTimeKey context key of the instant of Matcher.TestAt
+type testTimeKey struct{}
+
+// withTestTime returns the request whose interval predicates evaluate against at
+func withTestTime(req *http.Request, at time.Time) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), testTimeKey{}, at))
+}
@@ -0,0 +1,64 @@
+package matcher
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestAt(t *testing.T) {
+	file := writeRoutes(t, `sale: Path("/shop") && Between("2026-11-27T00:00:00Z", "2026-11-30T00:00:00Z") -> "http://sale.svc";
+sale_api: Path("/shop") && Header("Accept", "application/json") && Between("2026-11-27T00:00:00Z", "2026-11-30T00:00:00Z") -> "http://sale-api.svc";
+prelaunch: Path("/launch") && Before(1798761600) -> <shunt>;
+launch: Path("/launch") && After(1798761600) -> "http://launch.svc";
+shop: Path("/shop") -> "http://shop.svc";
+`)
+	defer os.Remove(file)
+
+	m, err := New(&Options{RoutesFile: file})
+	require.NoError(t, err)
+	defer m.Close()
+
+	saleStart := time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC)
+	saleEnd := time.Date(2026, 11, 30, 0, 0, 0, 0, time.UTC)
+	launch := time.Unix(1798761600, 0)
+	json := map[string]string{"Accept": "application/json"}
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		at      time.Time
+		want    string
+	}{
+		{"before the interval", "/shop", nil, saleStart.Add(-time.Second), "shop"},
+		{"lower boundary included", "/shop", nil, saleStart, "sale"},
+		{"inside the interval", "/shop", nil, saleStart.Add(36 * time.Hour), "sale"},
+		{"upper boundary excluded", "/shop", nil, saleEnd, "shop"},
+		{"interval and header", "/shop", json, saleStart, "sale_api"},
+		{"interval and header, outside", "/shop", json, saleEnd, "shop"},
+		{"before", "/launch", nil, launch.Add(-time.Second), "prelaunch"},
+		{"before boundary excluded, after boundary included", "/launch", nil, launch, "launch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := m.TestAt(&RequestAttributes{Method: "GET", Path: tt.path, Headers: tt.headers}, tt.at)
+			require.NoError(t, res.Err())
+			if assert.NotNil(t, res.Route()) {
+				assert.Equal(t, tt.want, res.Route().Id)
+			}
+		})
+	}
+
+	// the same matcher evaluates the current time without an instant
+	res := m.Test(&RequestAttributes{Method: "GET", Path: "/launch"})
+	if assert.NotNil(t, res.Route()) {
+		want := "launch"
+		if time.Now().Before(launch) {
+			want = "prelaunch"
+		}
+		assert.Equal(t, want, res.Route().Id)
+	}
+}
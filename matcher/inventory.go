@@ -0,0 +1,121 @@
+package matcher
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// InventoryVersion version of the Inventory schema, incremented when a field
+// is removed or changes meaning. Fields may be added without a new version.
+const InventoryVersion = 1
+
+// Inventory machine readable snapshot of the routes of a routes file, see
+// ExportInventory
+type Inventory struct {
+	// Version of the schema, InventoryVersion
+	Version int `json:"version"`
+	// RoutesFile path of the routes file
+	RoutesFile string `json:"routesFile"`
+	// Routes the routes in file order
+	Routes []InventoryRoute `json:"routes"`
+}
+
+// InventoryRoute a route of the inventory
+type InventoryRoute struct {
+	// ID route id
+	ID string `json:"id"`
+	// Location where the route is defined, when known
+	Location *Location `json:"location,omitempty"`
+	// Predicates normalized predicates of the route, eg. Path("/foo")
+	Predicates []string `json:"predicates"`
+	// Filters filter chain of the route
+	Filters []InventoryFilter `json:"filters"`
+	// Backend where the route sends the requests
+	Backend InventoryBackend `json:"backend"`
+	// Comments the comments before the route definition, without the
+	// leading // and trimmed, eg. owner or ticket annotations
+	Comments []string `json:"comments"`
+}
+
+// InventoryFilter a filter of a route
+type InventoryFilter struct {
+	// Name filter name
+	Name string `json:"name"`
+	// Args filter arguments, strings and numbers
+	Args []interface{} `json:"args"`
+}
+
+// InventoryBackend the backend of a route
+type InventoryBackend struct {
+	// Type one of network, shunt, loopback, dynamic, loadbalanced
+	Type string `json:"type"`
+	// Address of a network backend
+	Address string `json:"address,omitempty"`
+	// Algorithm of a load balanced backend, eg. roundRobin, empty for the
+	// default one
+	Algorithm string `json:"algorithm,omitempty"`
+	// Endpoints of a load balanced backend
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+// ExportInventory loads the routes file and writes its inventory in indented
+// json, see Inventory. Only Options.RoutesFile and Options.OnLoadProgress
+// are used: the routes are the ones of the file, including the ones the
+// routing would drop. A syntax error is returned as a *ParseError.
+func ExportInventory(o *Options, w io.Writer) error {
+	c, err := openFile(o.RoutesFile, o.OnLoadProgress)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newInventory(o.RoutesFile, c.routes, c.definitions))
+}
+
+// newInventory the inventory of the routes paired with their definitions
+func newInventory(file string, routes []*eskip.Route, defs []routeDefinition) *Inventory {
+	paired, _ := pairDefinitions(defs, routes)
+	inv := &Inventory{Version: InventoryVersion, RoutesFile: file, Routes: []InventoryRoute{}}
+	for i, r := range routes {
+		def := paired[i]
+		route := InventoryRoute{
+			ID:         r.Id,
+			Predicates: []string{},
+			Filters:    []InventoryFilter{},
+			Backend:    inventoryBackend(r),
+			Comments:   []string{},
+		}
+		if def.id != "" {
+			loc := def.location
+			route.Location = &loc
+			for _, c := range def.comments {
+				route.Comments = append(route.Comments, strings.TrimSpace(c.text))
+			}
+		}
+		for _, p := range Predicates(r) {
+			route.Predicates = append(route.Predicates, predicateString(p))
+		}
+		for _, f := range filterData(r) {
+			route.Filters = append(route.Filters, InventoryFilter{Name: f.Name, Args: f.Args})
+		}
+		inv.Routes = append(inv.Routes, route)
+	}
+	return inv
+}
+
+// inventoryBackend the backend of the route
+func inventoryBackend(r *eskip.Route) InventoryBackend {
+	b := InventoryBackend{Type: routeBackendType(r).String()}
+	switch routeBackendType(r) {
+	case eskip.NetworkBackend:
+		b.Address = r.Backend
+	case eskip.LBBackend:
+		b.Algorithm = r.LBAlgorithm
+		b.Endpoints = r.LBEndpoints
+	}
+	return b
+}
@@ -0,0 +1,60 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportInventory(t *testing.T) {
+	file := writeRoutes(t, `// owner: team-orders
+orders: Path("/orders") && Method("GET") -> setPath("/v2") -> "https://orders.internal"; // eskip-match:ignore shadowed-route
+//   on-call: orders-oncall
+lb: Path("/lb") -> <"http://a.internal", "http://b.internal">;
+lb: Path("/lb2") -> <shunt>;
+`)
+	defer os.Remove(file)
+
+	var b bytes.Buffer
+	require.NoError(t, ExportInventory(&Options{RoutesFile: file}, &b))
+	var inv Inventory
+	require.NoError(t, json.Unmarshal(b.Bytes(), &inv))
+
+	assert.Equal(t, InventoryVersion, inv.Version)
+	assert.Equal(t, file, inv.RoutesFile)
+	require.Len(t, inv.Routes, 3)
+
+	orders := inv.Routes[0]
+	assert.Equal(t, "orders", orders.ID)
+	assert.Equal(t, &Location{File: file, Line: 2}, orders.Location)
+	assert.Equal(t, []string{`Path("/orders")`, `Method("GET")`}, orders.Predicates)
+	assert.Equal(t, []InventoryFilter{{Name: "setPath", Args: []interface{}{"/v2"}}}, orders.Filters)
+	assert.Equal(t, InventoryBackend{Type: "network", Address: "https://orders.internal"}, orders.Backend)
+	assert.Equal(t, []string{"owner: team-orders"}, orders.Comments)
+
+	// the comment after the previous route on its line isn't one of the next
+	lb := inv.Routes[1]
+	assert.Equal(t, []string{"on-call: orders-oncall"}, lb.Comments)
+	assert.Equal(t, InventoryBackend{Type: "loadbalanced", Endpoints: []string{"http://a.internal", "http://b.internal"}}, lb.Backend)
+
+	// every definition of a duplicated id is listed with its own location
+	assert.Equal(t, 5, inv.Routes[2].Location.Line)
+	assert.Equal(t, []string{}, inv.Routes[2].Comments)
+	assert.Equal(t, []InventoryFilter{}, inv.Routes[2].Filters)
+}
+
+func TestExportInventoryErrors(t *testing.T) {
+	file := writeRoutes(t, `broken: Path("/a") -> ;`)
+	defer os.Remove(file)
+
+	err := ExportInventory(&Options{RoutesFile: file}, ioutil.Discard)
+	_, ok := err.(*ParseError)
+	assert.True(t, ok, "expected a *ParseError but got %v", err)
+
+	assert.Error(t, ExportInventory(&Options{RoutesFile: "testdata/missing.eskip"}, ioutil.Discard))
+}
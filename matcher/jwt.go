@@ -0,0 +1,141 @@
+package matcher
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// DefaultTestClaimsHeader header of the JSON claims presented to the
+// predicates of MockJWTPredicates
+const DefaultTestClaimsHeader = "X-Test-Claims"
+
+// names of the JWT payload predicates, the regexp ones come with newer
+// skipper versions
+const (
+	jwtPayloadAllKVPredicate       = "JWTPayloadAllKV"
+	jwtPayloadAnyKVPredicate       = "JWTPayloadAnyKV"
+	jwtPayloadAllKVRegexpPredicate = "JWTPayloadAllKVRegexp"
+	jwtPayloadAnyKVRegexpPredicate = "JWTPayloadAnyKVRegexp"
+)
+
+// MockJWTPredicates creates TEST-ONLY doubles of the JWTPayloadAllKV,
+// JWTPayloadAnyKV, JWTPayloadAllKVRegexp and JWTPayloadAnyKVRegexp
+// predicates, taking the claims from the claimsHeader as a JSON object,
+// DefaultTestClaimsHeader when empty, or else from the payload of the
+// Authorization bearer token WITHOUT VERIFYING ITS SIGNATURE. Like skipper
+// only the string claims match. Pass them in Options.CustomPredicates to
+// test which route wins for a set of claims, never register them in a proxy:
+// anyone can present any claims.
+func MockJWTPredicates(claimsHeader string) []routing.PredicateSpec {
+	if claimsHeader == "" {
+		claimsHeader = DefaultTestClaimsHeader
+	}
+	return []routing.PredicateSpec{
+		&jwtSpec{name: jwtPayloadAllKVPredicate, header: claimsHeader, all: true},
+		&jwtSpec{name: jwtPayloadAnyKVPredicate, header: claimsHeader},
+		&jwtSpec{name: jwtPayloadAllKVRegexpPredicate, header: claimsHeader, all: true, regexp: true},
+		&jwtSpec{name: jwtPayloadAnyKVRegexpPredicate, header: claimsHeader, regexp: true},
+	}
+}
+
+// jwtSpec see MockJWTPredicates, the arguments are claim and value
+// pairs like skipper, the values are regular expressions when regexp
+type jwtSpec struct {
+	name   string
+	header string
+	all    bool
+	regexp bool
+}
+
+func (s *jwtSpec) Name() string { return s.name }
+
+func (s *jwtSpec) Create(args []interface{}) (routing.Predicate, error) {
+	if len(args) == 0 || len(args)%2 != 0 {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+	p := &jwtPredicate{header: s.header, all: s.all}
+	for i := 0; i < len(args); i += 2 {
+		k, ok := args[i].(string)
+		if !ok {
+			return nil, predicates.ErrInvalidPredicateParameters
+		}
+		v, ok := args[i+1].(string)
+		if !ok {
+			return nil, predicates.ErrInvalidPredicateParameters
+		}
+		expr := "^" + regexp.QuoteMeta(v) + "$"
+		if s.regexp {
+			expr = v
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, predicates.ErrInvalidPredicateParameters
+		}
+		p.claims = append(p.claims, jwtClaim{name: k, value: re})
+	}
+	return p, nil
+}
+
+// jwtClaim a claim a jwtPredicate expects
+type jwtClaim struct {
+	name  string
+	value *regexp.Regexp
+}
+
+// jwtPredicate see MockJWTPredicates
+type jwtPredicate struct {
+	header string
+	all    bool
+	claims []jwtClaim
+}
+
+func (p *jwtPredicate) Match(req *http.Request) bool {
+	claims, ok := requestClaims(req, p.header)
+	if !ok {
+		return false
+	}
+	for _, c := range p.claims {
+		v, ok := claims[c.name].(string)
+		matches := ok && c.value.MatchString(v)
+		if matches != p.all {
+			return matches
+		}
+	}
+	return p.all
+}
+
+// requestClaims the claims of the header, or else of the bearer token
+// payload, decoded without verifying the token
+func requestClaims(req *http.Request, header string) (map[string]interface{}, bool) {
+	if h := req.Header.Get(header); h != "" {
+		return decodeClaims([]byte(h))
+	}
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, false
+	}
+	parts := strings.Split(strings.TrimPrefix(auth, "Bearer "), ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false
+	}
+	return decodeClaims(payload)
+}
+
+// decodeClaims decodes a JSON object of claims
+func decodeClaims(data []byte) (map[string]interface{}, bool) {
+	var claims map[string]interface{}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, false
+	}
+	return claims, true
+}
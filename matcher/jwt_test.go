@@ -0,0 +1,66 @@
+package matcher
+
+import (
+	"encoding/base64"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockJWTPredicates(t *testing.T) {
+	file := writeRoutes(t, `admin: Path("/orders") && JWTPayloadAllKV("iss", "https://idp", "role", "admin") -> "http://admin.svc";
+partner: Path("/orders") && JWTPayloadAnyKV("iss", "https://partner-idp", "aud", "partners") -> "http://partner.svc";
+staff: Path("/orders") && JWTPayloadAllKVRegexp("email", "@example\\.org$") -> "http://staff.svc";
+orders: Path("/orders") -> "http://orders.svc";
+`)
+	defer os.Remove(file)
+
+	token := func(payload string) string {
+		return "Bearer eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+	}
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"all claims", map[string]string{"X-Test-Claims": `{"iss": "https://idp", "role": "admin"}`}, "admin"},
+		{"one claim missing", map[string]string{"X-Test-Claims": `{"iss": "https://idp"}`}, "orders"},
+		{"one claim differs", map[string]string{"X-Test-Claims": `{"iss": "https://idp", "role": "viewer"}`}, "orders"},
+		{"claim not a string", map[string]string{"X-Test-Claims": `{"iss": "https://idp", "role": ["admin"]}`}, "orders"},
+		{"any claim", map[string]string{"X-Test-Claims": `{"aud": "partners"}`}, "partner"},
+		{"regexp claim", map[string]string{"X-Test-Claims": `{"email": "jane@example.org"}`}, "staff"},
+		{"regexp claim differs", map[string]string{"X-Test-Claims": `{"email": "jane@example.org.evil"}`}, "orders"},
+		{"invalid claims", map[string]string{"X-Test-Claims": `iss=https://idp`}, "orders"},
+		{"no claims", nil, "orders"},
+		{"unverified token", map[string]string{"Authorization": token(`{"iss": "https://idp", "role": "admin"}`)}, "admin"},
+		{"claims header before token", map[string]string{
+			"Authorization": token(`{"iss": "https://idp", "role": "admin"}`),
+			"X-Test-Claims": `{"aud": "partners"}`,
+		}, "partner"},
+		{"malformed token", map[string]string{"Authorization": "Bearer not-a-token"}, "orders"},
+	}
+
+	m, err := New(&Options{RoutesFile: file, CustomPredicates: MockJWTPredicates("")})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Empty(t, m.Findings())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := m.Test(&RequestAttributes{Method: "GET", Path: "/orders", Headers: tt.headers})
+			require.NoError(t, res.Err())
+			if assert.NotNil(t, res.Route()) {
+				assert.Equal(t, tt.want, res.Route().Id)
+			}
+		})
+	}
+
+	m, err = New(&Options{RoutesFile: file, CustomPredicates: MockJWTPredicates("X-Claims")})
+	require.NoError(t, err)
+	defer m.Close()
+	res := m.Test(&RequestAttributes{Method: "GET", Path: "/orders", Headers: map[string]string{"X-Claims": `{"iss": "https://idp", "role": "admin"}`}})
+	if assert.NotNil(t, res.Route()) {
+		assert.Equal(t, "admin", res.Route().Id)
+	}
+}
@@ -0,0 +1,273 @@
+package matcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/zalando/skipper/dataclients/kubernetes"
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// KubernetesOptions the Kubernetes ingresses the routes are loaded from
+// instead of the routes file, converted like the skipper kubernetes data
+// client does, see Options.Kubernetes
+type KubernetesOptions struct {
+	// URL base url of the API server, eg. http://localhost:8001 of kubectl
+	// proxy, the routes follow the changes of the ingresses
+	URL string
+	// Manifests path of a dump of the ingresses with their services and
+	// endpoints, eg. of kubectl get ingresses,services,endpoints -o yaml,
+	// used instead of URL: YAML or JSON, a List or documents of one
+	// resource each. The networking.k8s.io/v1 backends are read too.
+	Manifests string
+	// Namespace the namespace of the ingresses, all the namespaces when
+	// empty
+	Namespace string
+	// IngressClass regexp of the ingress.class annotations loaded, like the
+	// skipper flag, skipper by default. The ingresses without the
+	// annotation are always loaded.
+	IngressClass string
+	// PathMode how the ingress paths are matched, like the skipper flag:
+	// kubernetes-ingress (the default), path-regexp or path-prefix
+	PathMode string
+}
+
+// API paths of the resources the kubernetes data client reads
+const (
+	kubernetesIngresses = "/apis/extensions/v1beta1/"
+	kubernetesCore      = "/api/v1/"
+)
+
+// kubernetesResource the parts of a resource of a manifests dump
+type kubernetesResource struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+	} `json:"metadata"`
+	Items []json.RawMessage `json:"items"`
+}
+
+// staticDataClient data client with fixed routes
+type staticDataClient []*eskip.Route
+
+func (c staticDataClient) LoadAll() ([]*eskip.Route, error) { return c, nil }
+
+func (c staticDataClient) LoadUpdate() ([]*eskip.Route, []string, error) { return nil, nil, nil }
+
+// kubernetesDataClients the data client of the ingresses of the options
+func kubernetesDataClients(o *KubernetesOptions) ([]routing.DataClient, error) {
+	if (o.URL == "") == (o.Manifests == "") {
+		return nil, errors.New("either the URL or the Manifests of the Kubernetes options must be set")
+	}
+	pathMode, err := kubernetes.ParsePathMode(o.PathMode)
+	if o.PathMode == "" {
+		pathMode, err = kubernetes.KubernetesIngressMode, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ko := kubernetes.Options{
+		KubernetesURL:       o.URL,
+		KubernetesNamespace: o.Namespace,
+		IngressClass:        o.IngressClass,
+		PathMode:            pathMode,
+	}
+	if o.URL != "" {
+		client, err := kubernetes.New(ko)
+		if err != nil {
+			return nil, err
+		}
+		return []routing.DataClient{client}, nil
+	}
+
+	resources, err := readManifests(o.Manifests)
+	if err != nil {
+		return nil, err
+	}
+	api := httptest.NewServer(resources)
+	defer api.Close()
+	ko.KubernetesURL = api.URL
+	client, err := kubernetes.New(ko)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	routes, err := client.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", o.Manifests, err)
+	}
+	return []routing.DataClient{staticDataClient(routes)}, nil
+}
+
+// kubernetesManifests the resources of a manifests dump by kind, an API
+// server answering the requests of the kubernetes data client
+type kubernetesManifests map[string][]json.RawMessage
+
+// readManifests reads the ingresses, the services and the endpoints of a
+// manifests dump
+func readManifests(path string) (kubernetesManifests, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := kubernetesManifests{}
+	dec := yaml.NewDecoder(f)
+	for {
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid manifests: %v", path, err)
+		}
+		if doc == nil {
+			continue
+		}
+		// the data client reads json
+		b, err := json.Marshal(jsonValue(doc))
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid manifests: %v", path, err)
+		}
+		if err := m.add(b); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return m, nil
+}
+
+// add adds a resource, the items of a List
+func (m kubernetesManifests) add(b json.RawMessage) error {
+	var r kubernetesResource
+	if err := json.Unmarshal(b, &r); err != nil {
+		return fmt.Errorf("invalid resource: %v", err)
+	}
+	switch r.Kind {
+	case "List", "IngressList", "ServiceList", "EndpointsList":
+		for _, item := range r.Items {
+			if err := m.add(item); err != nil {
+				return err
+			}
+		}
+	case "Ingress":
+		ingress, err := v1beta1Ingress(b)
+		if err != nil {
+			return fmt.Errorf("ingress %s/%s: %v", r.Metadata.Namespace, r.Metadata.Name, err)
+		}
+		m["ingresses"] = append(m["ingresses"], ingress)
+	case "Service":
+		m["services"] = append(m["services"], b)
+	case "Endpoints":
+		m["endpoints"] = append(m["endpoints"], b)
+	case "RouteGroup":
+		return fmt.Errorf("RouteGroup %s/%s: the skipper version of eskip-match converts the Ingresses only", r.Metadata.Namespace, r.Metadata.Name)
+	}
+	return nil
+}
+
+// ServeHTTP answers the list requests of the kubernetes data client, eg.
+// /api/v1/namespaces/shop/services
+func (m kubernetesManifests) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var path string
+	switch {
+	case strings.HasPrefix(r.URL.Path, kubernetesIngresses):
+		path = strings.TrimPrefix(r.URL.Path, kubernetesIngresses)
+	case strings.HasPrefix(r.URL.Path, kubernetesCore):
+		path = strings.TrimPrefix(r.URL.Path, kubernetesCore)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	namespace, kind := "", path
+	if parts := strings.Split(path, "/"); len(parts) == 3 && parts[0] == "namespaces" {
+		namespace, kind = parts[1], parts[2]
+	}
+
+	items := []json.RawMessage{}
+	for _, item := range m[kind] {
+		var r kubernetesResource
+		json.Unmarshal(item, &r)
+		if namespace == "" || r.Metadata.Namespace == namespace {
+			items = append(items, item)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// v1beta1Ingress the ingress with the networking.k8s.io/v1 backends, eg.
+// {"service": {"name": "orders", "port": {"number": 80}}}, in the
+// extensions/v1beta1 form the data client reads
+func v1beta1Ingress(b json.RawMessage) (json.RawMessage, error) {
+	var ingress map[string]interface{}
+	if err := json.Unmarshal(b, &ingress); err != nil {
+		return nil, err
+	}
+	spec, _ := ingress["spec"].(map[string]interface{})
+	if spec == nil {
+		return b, nil
+	}
+	if backend, ok := spec["defaultBackend"]; ok {
+		spec["backend"] = backend
+	}
+	v1beta1Backend(spec["backend"])
+	rules, _ := spec["rules"].([]interface{})
+	for _, rule := range rules {
+		rule, _ := rule.(map[string]interface{})
+		http, _ := rule["http"].(map[string]interface{})
+		paths, _ := http["paths"].([]interface{})
+		for _, path := range paths {
+			if path, ok := path.(map[string]interface{}); ok {
+				v1beta1Backend(path["backend"])
+			}
+		}
+	}
+	return json.Marshal(ingress)
+}
+
+// v1beta1Backend sets the serviceName and the servicePort of a v1 backend
+func v1beta1Backend(v interface{}) {
+	backend, _ := v.(map[string]interface{})
+	service, _ := backend["service"].(map[string]interface{})
+	if service == nil {
+		return
+	}
+	backend["serviceName"] = service["name"]
+	port, _ := service["port"].(map[string]interface{})
+	if number, ok := port["number"]; ok {
+		backend["servicePort"] = number
+	} else if name, ok := port["name"]; ok {
+		backend["servicePort"] = name
+	}
+}
+
+// jsonValue the value decoded from YAML with the maps keyed by strings, as
+// encoding/json requires
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonValue(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = jsonValue(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,91 @@
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const shopManifests = "testdata/kubernetes/shop.yaml"
+
+func TestKubernetesManifests(t *testing.T) {
+	m, err := New(&Options{Kubernetes: &KubernetesOptions{Manifests: shopManifests}})
+	require.NoError(t, err)
+	defer m.Close()
+
+	res := m.Test(&RequestAttributes{Host: "shop.example.org", Path: "/orders/1"})
+	require.NoError(t, res.Err())
+	require.NotNil(t, res.Route())
+	assert.Equal(t, []string{"http://10.2.0.1:8080", "http://10.2.0.2:8080"}, res.Route().LBEndpoints)
+
+	res = m.Test(&RequestAttributes{Host: "shop.example.org", Path: "/search"})
+	require.NotNil(t, res.Route())
+	assert.Equal(t, "http://10.3.0.2:80", res.Route().Backend)
+
+	// the catch-all route of the host
+	res = m.Test(&RequestAttributes{Host: "shop.example.org", Path: "/missing"})
+	require.NotNil(t, res.Route())
+	assert.Equal(t, "<shunt>", backendString(res.Route()))
+}
+
+func TestKubernetesManifestsNetworkingV1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eskip-match")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	manifests := filepath.Join(dir, "shop.json")
+	require.NoError(t, ioutil.WriteFile(manifests, []byte(`{
+  "kind": "Ingress",
+  "metadata": {"namespace": "shop", "name": "shop"},
+  "spec": {"rules": [{"host": "shop.example.org", "http": {"paths": [
+    {"path": "/search", "backend": {"service": {"name": "search", "port": {"number": 80}}}}
+  ]}}]}
+}
+---
+{"kind": "Service", "metadata": {"namespace": "shop", "name": "search"},
+ "spec": {"clusterIP": "10.3.0.2", "ports": [{"port": 80}]}}
+`), 0644))
+
+	m, err := New(&Options{Kubernetes: &KubernetesOptions{Manifests: manifests}})
+	require.NoError(t, err)
+	defer m.Close()
+	res := m.Test(&RequestAttributes{Host: "shop.example.org", Path: "/search"})
+	require.NotNil(t, res.Route())
+	assert.Equal(t, "http://10.3.0.2:80", res.Route().Backend)
+}
+
+func TestKubernetesOptionsErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eskip-match")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	routeGroup := filepath.Join(dir, "routegroup.yaml")
+	require.NoError(t, ioutil.WriteFile(routeGroup, []byte(`apiVersion: zalando.org/v1
+kind: RouteGroup
+metadata:
+  namespace: shop
+  name: shop
+`), 0644))
+
+	tests := []struct {
+		name    string
+		options *Options
+		err     string
+	}{
+		{name: "no source", options: &Options{Kubernetes: &KubernetesOptions{}}, err: "either the URL or the Manifests"},
+		{name: "both sources", options: &Options{Kubernetes: &KubernetesOptions{URL: "http://localhost:8001", Manifests: shopManifests}}, err: "either the URL or the Manifests"},
+		{name: "path mode", options: &Options{Kubernetes: &KubernetesOptions{Manifests: shopManifests, PathMode: "glob"}}, err: "glob"},
+		{name: "missing manifests", options: &Options{Kubernetes: &KubernetesOptions{Manifests: "testdata/kubernetes/missing.yaml"}}, err: "missing.yaml"},
+		{name: "route group", options: &Options{Kubernetes: &KubernetesOptions{Manifests: routeGroup}}, err: "RouteGroup shop/shop"},
+		{name: "lazy", options: &Options{Lazy: true, Kubernetes: &KubernetesOptions{Manifests: shopManifests}}, err: "Kubernetes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.options)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package matcher
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// lazyMatcher matcher initialized by the first Test or Explain call, see Options.Lazy
+type lazyMatcher struct {
+	options  *Options
+	initOnce sync.Once
+	// mu guards m against a Close racing with the initialization
+	mu  sync.Mutex
+	m   *matcher
+	err error
+	// closed set to 1 by Close
+	closed int32
+}
+
+func newLazyMatcher(o *Options) (Matcher, error) {
+	if o.Kubernetes != nil {
+		return nil, errors.New("Lazy needs a RoutesFile, the Kubernetes routes are always loaded")
+	}
+	if o.Routes != "" {
+		return &lazyMatcher{options: o}, nil
+	}
+	if err := checkRoutesFile(o.RoutesFile); err != nil {
+		return nil, err
+	}
+	return &lazyMatcher{options: o}, nil
+}
+
+// NotReadyError error of the results of a lazy matcher whose initialization
+// failed, eg. with an *InitTimeoutError, instead of not matching
+type NotReadyError struct {
+	// Err the initialization error
+	Err error
+}
+
+func (e *NotReadyError) Error() string { return "matcher not ready: " + e.Err.Error() }
+
+// Unwrap returns the initialization error
+func (e *NotReadyError) Unwrap() error { return e.Err }
+
+// Is matches ErrNotReady
+func (e *NotReadyError) Is(target error) bool { return target == ErrNotReady }
+
+// get returns the matcher, the first call initializes it, the error is a
+// *NotReadyError when the initialization failed
+func (l *lazyMatcher) get() (*matcher, error) {
+	if atomic.LoadInt32(&l.closed) == 1 {
+		return nil, ErrClosed
+	}
+	l.initOnce.Do(func() {
+		m, err := newMatcher(l.options)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.m = m
+		if err != nil {
+			l.err = &NotReadyError{Err: err}
+		}
+		if m != nil && atomic.LoadInt32(&l.closed) == 1 {
+			m.Close()
+		}
+	})
+	return l.m, l.err
+}
+
+func (l *lazyMatcher) Test(attributes *RequestAttributes, opts ...TestOption) TestResult {
+	m, err := l.get()
+	if err != nil {
+		return errResult(attributes, routesSource(l.options), err)
+	}
+	return m.Test(attributes, opts...)
+}
+
+func (l *lazyMatcher) TestAt(attributes *RequestAttributes, at time.Time) TestResult {
+	m, err := l.get()
+	if err != nil {
+		return errResult(attributes, routesSource(l.options), err)
+	}
+	return m.TestAt(attributes, at)
+}
+
+func (l *lazyMatcher) Explain(attributes *RequestAttributes, n int) []NearMiss {
+	m, err := l.get()
+	if err != nil {
+		return nil
+	}
+	return m.Explain(attributes, n)
+}
+
+func (l *lazyMatcher) TestManyStream(in <-chan *RequestAttributes, out chan<- TestResult) {
+	m, err := l.get()
+	if err != nil {
+		failStream(in, out, routesSource(l.options), err)
+		return
+	}
+	m.TestManyStream(in, out)
+}
+
+// Ready false until the first call initialized the matcher, with the
+// initialization error if it failed
+func (l *lazyMatcher) Ready() (bool, error) {
+	if atomic.LoadInt32(&l.closed) == 1 {
+		return false, ErrClosed
+	}
+	l.mu.Lock()
+	m, err := l.m, l.err
+	l.mu.Unlock()
+	if m == nil {
+		return false, err
+	}
+	return m.Ready()
+}
+
+func (l *lazyMatcher) RouteCount() int {
+	l.mu.Lock()
+	m := l.m
+	l.mu.Unlock()
+	if m == nil {
+		return 0
+	}
+	return m.RouteCount()
+}
+
+// InitStats zero until the first call initialized the matcher
+func (l *lazyMatcher) InitStats() InitStats {
+	l.mu.Lock()
+	m := l.m
+	l.mu.Unlock()
+	if m == nil {
+		return InitStats{}
+	}
+	return m.InitStats()
+}
+
+func (l *lazyMatcher) Routes() []*eskip.Route {
+	m, err := l.get()
+	if err != nil {
+		return nil
+	}
+	return m.Routes()
+}
+
+func (l *lazyMatcher) GetRoute(id string) (*eskip.Route, bool) {
+	m, err := l.get()
+	if err != nil {
+		return nil, false
+	}
+	return m.GetRoute(id)
+}
+
+func (l *lazyMatcher) GenerateRequestFor(id string) (*RequestAttributes, []Unsatisfied, error) {
+	m, err := l.get()
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.GenerateRequestFor(id)
+}
+
+func (l *lazyMatcher) Findings() []Finding {
+	m, err := l.get()
+	if err != nil {
+		return nil
+	}
+	return m.Findings()
+}
+
+func (l *lazyMatcher) LoadErrors() []RouteLoadError {
+	m, err := l.get()
+	if err != nil {
+		return nil
+	}
+	return m.LoadErrors()
+}
+
+func (l *lazyMatcher) DumpEffectiveRoutes(w io.Writer, format Format) error {
+	m, err := l.get()
+	if err != nil {
+		return err
+	}
+	return m.DumpEffectiveRoutes(w, format)
+}
+
+// Close closes the matcher if initialized, a matcher closed before its
+// first call is never initialized
+func (l *lazyMatcher) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	atomic.StoreInt32(&l.closed, 1)
+	if l.m != nil {
+		return l.m.Close()
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package matcher
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/routing"
+)
+
+func TestLazyInitializesOnFirstCall(t *testing.T) {
+	var created int32
+	defer countDataClients(&created)()
+
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, Lazy: true})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.EqualValues(t, 0, created, "New must not load the routes")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := m.Test(&RequestAttributes{Path: "/bar"})
+			assert.NoError(t, res.Err())
+			if assert.NotNil(t, res.Route()) {
+				assert.Equal(t, "bar", res.Route().Id)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, created, "concurrent first calls must initialize once")
+
+	assert.NotEmpty(t, m.Explain(&RequestAttributes{Path: "/bar"}, 1))
+	assert.EqualValues(t, 1, created)
+}
+
+func TestLazyEagerDefault(t *testing.T) {
+	var created int32
+	defer countDataClients(&created)()
+
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.EqualValues(t, 1, created)
+}
+
+func TestLazyErrors(t *testing.T) {
+	_, err := New(&Options{RoutesFile: "testdata/missing.eskip", Lazy: true})
+	assert.Error(t, err, "New must check the routes file exists")
+
+	f, err := ioutil.TempFile("", "lazy-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte(`a: Path(`), 0644))
+
+	var created int32
+	defer countDataClients(&created)()
+	m, err := New(&Options{RoutesFile: f.Name(), Lazy: true})
+	require.NoError(t, err)
+	defer m.Close()
+
+	res := m.Test(&RequestAttributes{Path: "a"})
+	require.Error(t, res.Err())
+	assert.Nil(t, res.Route())
+	assert.Equal(t, "/a", res.Attributes().Path)
+	assert.Equal(t, res.Err(), m.Test(&RequestAttributes{Path: "/a"}).Err(), "the initialization error must be kept")
+	assert.Nil(t, m.Explain(&RequestAttributes{Path: "/a"}, 0))
+	assert.EqualValues(t, 1, created)
+}
+
+func TestLazyClose(t *testing.T) {
+	var created int32
+	defer countDataClients(&created)()
+
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, Lazy: true})
+	require.NoError(t, err)
+	assert.NoError(t, m.Close())
+	assert.True(t, errors.Is(m.Test(&RequestAttributes{Path: "/bar"}).Err(), ErrClosed))
+	assert.EqualValues(t, 0, created, "a closed matcher must not be initialized")
+
+	m, err = New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, Lazy: true})
+	require.NoError(t, err)
+	require.NotNil(t, m.Test(&RequestAttributes{Path: "/bar"}).Route())
+	assert.NoError(t, m.Close())
+	assert.NoError(t, m.Close())
+	assert.True(t, errors.Is(m.Test(&RequestAttributes{Path: "/bar"}).Err(), ErrClosed))
+}
+
+func TestLazyReady(t *testing.T) {
+	release := make(chan struct{})
+	defer func(f func(*Options) ([]routing.DataClient, error)) { newDataClients = f }(newDataClients)
+	create := newDataClients
+	newDataClients = func(o *Options) ([]routing.DataClient, error) {
+		<-release
+		return create(o)
+	}
+
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, Lazy: true})
+	require.NoError(t, err)
+	defer m.Close()
+
+	ready, err := m.Ready()
+	assert.False(t, ready, "a lazy matcher isn't ready before the first call")
+	assert.NoError(t, err)
+
+	tested := make(chan TestResult)
+	go func() { tested <- m.Test(&RequestAttributes{Path: "/bar"}) }()
+	// still loading
+	ready, err = m.Ready()
+	assert.False(t, ready)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, m.RouteCount())
+
+	close(release)
+	res := <-tested
+	require.NoError(t, res.Err())
+	ready, err = m.Ready()
+	assert.True(t, ready)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, m.RouteCount())
+	assert.Len(t, m.Routes(), 8)
+	_, ok := m.GetRoute("bar")
+	assert.True(t, ok)
+}
+
+func TestLazyReadyFailure(t *testing.T) {
+	f, err := ioutil.TempFile("", "lazy-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte(`a: Path(`), 0644))
+
+	m, err := New(&Options{RoutesFile: f.Name(), Lazy: true})
+	require.NoError(t, err)
+	defer m.Close()
+	m.Test(&RequestAttributes{Path: "/a"})
+
+	ready, err := m.Ready()
+	assert.False(t, ready)
+	assert.Error(t, err)
+	assert.Equal(t, 0, m.RouteCount())
+}
+
+func TestLazyNotReady(t *testing.T) {
+	client := &blockingDataClient{release: make(chan struct{})}
+	defer close(client.release)
+	defer func(f func(*Options) ([]routing.DataClient, error)) { newDataClients = f }(newDataClients)
+	newDataClients = func(*Options) ([]routing.DataClient, error) {
+		return []routing.DataClient{client}, nil
+	}
+
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", Lazy: true, InitTimeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	defer m.Close()
+
+	res := m.Test(&RequestAttributes{Path: "/bar"})
+	assert.Nil(t, res.Route())
+	assert.True(t, errors.Is(res.Err(), ErrNotReady), "a stalled initialization isn't a non-match")
+	assert.True(t, errors.Is(res.Err(), ErrInitTimeout))
+	assert.Equal(t, "failed to test GET /bar against 0 route(s) of testdata/routes.eskip: matcher not ready: routing not initialized after 50ms: 0 routes applied", res.Err().(*MatchError).Line())
+	var timeout *InitTimeoutError
+	if assert.True(t, errors.As(res.Err(), &timeout)) {
+		assert.Equal(t, 50*time.Millisecond, timeout.Timeout)
+		assert.Equal(t, 0, timeout.Applied)
+	}
+
+	ready, err := m.Ready()
+	assert.False(t, ready)
+	assert.True(t, errors.Is(err, ErrNotReady))
+}
@@ -0,0 +1,859 @@
+package matcher
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/routing"
+)
+
+// Severity how serious a lint finding is
+type Severity string
+
+// Severities of the lint findings, from the least serious one
+const (
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// AtLeast true if s is as serious as other or more
+func (s Severity) AtLeast(other Severity) bool {
+	return s.rank() >= other.rank()
+}
+
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 2
+	case SeverityWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Lint rule ids
+const (
+	// RuleParse the routes document can't be parsed
+	RuleParse = "parse"
+	// RuleDuplicateID more routes have the same id, only the last one is used
+	RuleDuplicateID = "duplicate-id"
+	// RuleUnknownFilter a route uses a filter not available, it's dropped from the table
+	RuleUnknownFilter = "unknown-filter"
+	// RuleUnknownPredicate a route uses a predicate not available, it's dropped from the table
+	RuleUnknownPredicate = "unknown-predicate"
+	// RuleShadowedRoute a route can never match because another one always wins
+	RuleShadowedRoute = "shadowed-route"
+	// RuleInvalidBackend a network backend isn't a valid http(s) address
+	RuleInvalidBackend = "invalid-backend"
+	// RuleInvalidFilterArgs a filter can't be created with its arguments, the
+	// route is dropped from the table, checked with CheckFilterArgs only but
+	// for the mocks of MockFilter
+	RuleInvalidFilterArgs = "invalid-filter-args"
+	// RuleInvalidPredicateArgs a custom or bundled predicate can't be created
+	// with its arguments, eg. Cookie("session") without the value, the route
+	// is dropped from the table
+	RuleInvalidPredicateArgs = "invalid-predicate-args"
+	// RuleUnreachableRoute a route without a path can never match because of
+	// a catch all route without other predicates
+	RuleUnreachableRoute = "unreachable-route"
+	// RuleAmbiguousRoutes two routes can match the same request, the
+	// routing priority decides, checked with CheckAmbiguity only
+	RuleAmbiguousRoutes = "ambiguous-routes"
+	// RuleInvalidRegexp a regexp predicate doesn't compile, the route is
+	// dropped from the table, pathological regexps are reported as warnings
+	RuleInvalidRegexp = "invalid-regexp"
+	// RuleDeprecated a route uses a deprecated filter or predicate, see
+	// RuleOptions.Deprecations
+	RuleDeprecated = "deprecated"
+	// RuleHostNotAllowed a Host predicate can match hosts outside the
+	// allowed domains, checked with AllowedHostSuffixes only
+	RuleHostNotAllowed = "host-not-allowed"
+	// RuleAnyHost a route without a Host predicate matches any host,
+	// checked with AllowedHostSuffixes only
+	RuleAnyHost = "any-host"
+	// RulePathInSubtree a route with a literal Path is inside the PathSubtree
+	// of another route, the first wins for the exact path only, checked with
+	// CheckPathSubtree only
+	RulePathInSubtree = "path-in-subtree"
+	// RuleUnknownRule an ignored rule, a severity override or a suppress
+	// directive names a rule which doesn't exist
+	RuleUnknownRule = "unknown-rule"
+	// RuleNameCollision a custom or mocked filter or predicate has the name
+	// of a builtin, bundled or other custom one, reported by New and
+	// Validate only
+	RuleNameCollision = "name-collision"
+	// RuleAmbiguousWitness more routes match the request generated for a
+	// route, reported by AmbiguityFindings only
+	RuleAmbiguousWitness = "ambiguous-witness"
+)
+
+// Finding a problem found linting routes
+type Finding struct {
+	// RuleID id of the rule reporting the finding
+	RuleID string `json:"rule"`
+	// Severity how serious the finding is
+	Severity Severity `json:"severity"`
+	// RouteID id of the route the finding is about, if any
+	RouteID string `json:"routeId,omitempty"`
+	// File where the route is defined
+	File string `json:"file,omitempty"`
+	// Line where the route is defined
+	Line int `json:"line,omitempty"`
+	// Column of the failing token of a parse finding
+	Column int `json:"column,omitempty"`
+	// Message describes the problem
+	Message string `json:"message"`
+	// Suggestion how to fix the problem, if the rule knows it
+	Suggestion string `json:"suggestion,omitempty"`
+	// Witness request matched by the routes of an ambiguous-routes or
+	// ambiguous-witness finding
+	Witness *RequestAttributes `json:"witness,omitempty"`
+}
+
+// String returns a compiler like representation of the finding,
+// eg. routes.eskip:3: error: foo: unknown filter bar [unknown-filter]
+func (f Finding) String() string {
+	var b strings.Builder
+	if loc := (Location{File: f.File, Line: f.Line}).String(); loc != "" {
+		b.WriteString(loc)
+		if f.Line > 0 && f.Column > 0 {
+			fmt.Fprintf(&b, ":%d", f.Column)
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString(string(f.Severity) + ": ")
+	if f.RouteID != "" {
+		b.WriteString(f.RouteID + ": ")
+	}
+	b.WriteString(fmt.Sprintf("%s [%s]", f.Message, f.RuleID))
+	return b.String()
+}
+
+// Document a routes document to lint
+type Document struct {
+	// File name of the document, used to locate the findings
+	File string
+	// Content eskip routes
+	Content []byte
+}
+
+// LintOptions options when linting routes documents
+type LintOptions struct {
+	// CustomPredicates available in addition to the bundled ones
+	CustomPredicates []routing.PredicateSpec
+
+	// CustomFilters available in addition to the builtin ones
+	CustomFilters []filters.Spec
+
+	// MockFilters names of the filters available in addition to the builtin ones
+	MockFilters []string
+
+	// Ignore ids of the rules not to check
+	Ignore []string
+
+	// Severity overrides the default severity of the findings by rule id
+	Severity map[string]Severity
+
+	// AllowBackendPath accepts network backends with a path or a query
+	AllowBackendPath bool
+
+	// CheckFilterArgs creates the filters of the routes to check their
+	// arguments, see RuleOptions.CheckFilterArgs
+	CheckFilterArgs bool
+
+	// CheckAmbiguity reports the routes which can match the same requests
+	CheckAmbiguity bool
+
+	// CheckPathSubtree reports the Path routes inside the PathSubtree of
+	// another route, see RuleOptions.CheckPathSubtree
+	CheckPathSubtree bool
+
+	// Deprecations filters and predicates reported by the deprecated rule,
+	// see RuleOptions.Deprecations
+	Deprecations []DeprecationRule
+
+	// AllowedHostSuffixes domains the Host predicates must stay within,
+	// see RuleOptions.AllowedHostSuffixes
+	AllowedHostSuffixes []string
+}
+
+// RuleOptions which rules are checked and how serious their findings are
+type RuleOptions struct {
+	// Ignore ids of the rules not to check
+	Ignore []string
+
+	// Severity overrides the default severity of the findings by rule id,
+	// eg. duplicate-id findings reported as warnings
+	Severity map[string]Severity
+
+	// AllowBackendPath accepts network backends with a path or a query,
+	// by default the invalid-backend rule reports them
+	AllowBackendPath bool
+
+	// CheckFilterArgs creates the filters of the routes with their
+	// arguments and reports the failures (rule invalid-filter-args). Custom
+	// filters are created too, their CreateFilter must not have side
+	// effects, the mocked filters and the builtin ones reading files or
+	// running scripts (see skippedFilterArgs) are skipped.
+	CheckFilterArgs bool
+
+	// CheckAmbiguity reports the pairs of routes which can both match a
+	// request (rule ambiguous-routes) with a sample of such a request, when
+	// it can be generated for the predicates of both routes
+	CheckAmbiguity bool
+
+	// CheckPathSubtree reports the routes with a literal Path inside the
+	// PathSubtree of another route matching the same requests (rule
+	// path-in-subtree), with a sample request for the exact path, which the
+	// Path route wins, and one for a sub-path, served by the PathSubtree
+	// route. These pairs aren't reported as ambiguous-routes.
+	CheckPathSubtree bool
+
+	// Deprecations filters and predicates reported by the deprecated rule
+	// with their replacement, DefaultDeprecations when nil. An empty list
+	// reports none, use MergeDeprecations to extend or override the defaults.
+	Deprecations []DeprecationRule
+
+	// AllowedHostSuffixes domains the routes must match hosts of, eg.
+	// example.org for example.org and its subdomains. When set, the Host
+	// predicates which can match other hosts are reported (rule
+	// host-not-allowed), with a warning when it can't be verified, and so
+	// are the routes matching any host (rule any-host).
+	AllowedHostSuffixes []string
+}
+
+func (o RuleOptions) ignored(rule string) bool {
+	for _, id := range o.Ignore {
+		if id == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// apply overrides the severity of the finding
+func (o RuleOptions) apply(f Finding) Finding {
+	if severity, ok := o.Severity[f.RuleID]; ok {
+		f.Severity = severity
+	}
+	return f
+}
+
+// lintRoute a route together with where it's defined
+type lintRoute struct {
+	route    *eskip.Route
+	location Location
+	// suppressed rules of the suppress directives of the route
+	suppressed []suppression
+}
+
+// linter holds what the rules need to check the routes
+type linter struct {
+	routes     []lintRoute
+	filters    filters.Registry
+	predicates map[string]routing.PredicateSpec
+	rules      RuleOptions
+	findings   []Finding
+}
+
+// lintRules all the rules run by Lint in order
+var lintRules = []struct {
+	id    string
+	check func(l *linter)
+}{
+	{RuleDuplicateID, (*linter).duplicateIDs},
+	{RuleUnknownFilter, (*linter).unknownFilters},
+	{RuleUnknownPredicate, (*linter).unknownPredicates},
+	{RuleInvalidBackend, (*linter).invalidBackends},
+	{RuleInvalidRegexp, (*linter).invalidRegexps},
+	{RuleUnreachableRoute, (*linter).unreachableRoutes},
+	{RuleShadowedRoute, (*linter).shadowedRoutes},
+	{RulePathInSubtree, (*linter).pathsInSubtrees},
+	{RuleInvalidFilterArgs, (*linter).invalidFilterArgs},
+	{RuleInvalidPredicateArgs, (*linter).invalidPredicateArgs},
+	{RuleAmbiguousRoutes, (*linter).ambiguousRoutes},
+	{RuleDeprecated, (*linter).deprecatedUsages},
+	{RuleHostNotAllowed, (*linter).hostsNotAllowed},
+	{RuleAnyHost, (*linter).anyHosts},
+}
+
+// Lint checks the routes defined by the documents all together, as if
+// they were loaded in the same routing table, and returns the problems found.
+// Findings are sorted by file and line but a document failing to parse is
+// reported first and doesn't contribute routes.
+func Lint(docs []Document, o *LintOptions) []Finding {
+	if o == nil {
+		o = &LintOptions{}
+	}
+	registry := builtinFilters()
+	for _, f := range append(mockFilters(o.MockFilters), o.CustomFilters...) {
+		registry.Register(f)
+	}
+	rules := RuleOptions{
+		Ignore:              o.Ignore,
+		Severity:            o.Severity,
+		AllowBackendPath:    o.AllowBackendPath,
+		CheckFilterArgs:     o.CheckFilterArgs,
+		CheckAmbiguity:      o.CheckAmbiguity,
+		CheckPathSubtree:    o.CheckPathSubtree,
+		Deprecations:        o.Deprecations,
+		AllowedHostSuffixes: o.AllowedHostSuffixes,
+	}
+
+	findings := []Finding{}
+	var routes []lintRoute
+	for _, doc := range docs {
+		parsed, err := eskip.Parse(string(doc.Content))
+		if err != nil {
+			if !rules.ignored(RuleParse) {
+				findings = append(findings, rules.apply(newParseError(doc, err).finding()))
+			}
+			continue
+		}
+		routes = append(routes, locateRoutes(doc, parsed)...)
+	}
+	return append(findings, lintRoutes(routes, registry, mapPredicateSpecs(predicateSpecs(o.CustomPredicates, false)), rules)...)
+}
+
+// lintRoutes runs the rules not ignored on the routes, findings sorted by file and line
+func lintRoutes(routes []lintRoute, registry filters.Registry, predicates map[string]routing.PredicateSpec, rules RuleOptions) []Finding {
+	l := &linter{routes: routes, filters: registry, predicates: predicates, rules: rules}
+	// not in lintRules, it checks their ids
+	if !rules.ignored(RuleUnknownRule) {
+		l.unknownRules()
+	}
+	for _, rule := range lintRules {
+		if !rules.ignored(rule.id) {
+			rule.check(l)
+		}
+	}
+
+	sortFindings(l.findings)
+	return l.findings
+}
+
+// sortFindings sorts the findings by file and line, keeping the order of the ones on the same line
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+}
+
+// locateRoutes pairs the parsed routes with their definitions in the document
+func locateRoutes(doc Document, routes []*eskip.Route) []lintRoute {
+	return pairRoutes(scanRouteDefinitions(doc.File, doc.Content), routes)
+}
+
+// pairRoutes pairs the parsed routes with their definitions, see
+// pairDefinitions
+func pairRoutes(defs []routeDefinition, routes []*eskip.Route) []lintRoute {
+	paired, inOrder := pairDefinitions(defs, routes)
+	lrs := make([]lintRoute, len(routes))
+	for i, r := range routes {
+		lrs[i] = lintRoute{route: r, location: paired[i].location}
+		if inOrder {
+			lrs[i].suppressed = paired[i].suppressed
+		}
+	}
+	return lrs
+}
+
+// pairDefinitions the definition of each route, routes are in the same
+// order of the definitions unless the scan is confused by the syntax, in
+// that case the first definition of each id is used and inOrder is false.
+// The definition of a route missing from the scan is the zero one.
+func pairDefinitions(defs []routeDefinition, routes []*eskip.Route) (paired []routeDefinition, inOrder bool) {
+	inOrder = len(defs) == len(routes)
+	for i := 0; inOrder && i < len(defs); i++ {
+		inOrder = defs[i].id == routes[i].Id
+	}
+	if inOrder {
+		return defs, true
+	}
+
+	first := make(map[string]routeDefinition)
+	for _, d := range defs {
+		if _, ok := first[d.id]; !ok {
+			first[d.id] = d
+		}
+	}
+	paired = make([]routeDefinition, len(routes))
+	for i, r := range routes {
+		paired[i] = first[r.Id]
+	}
+	return paired, false
+}
+
+// report adds a finding about the route and returns it, nil if the route
+// suppresses the rule with a directive
+func (l *linter) report(rule string, severity Severity, r lintRoute, format string, a ...interface{}) *Finding {
+	for _, sup := range r.suppressed {
+		if sup.rule == rule {
+			return nil
+		}
+	}
+	l.findings = append(l.findings, l.rules.apply(Finding{
+		RuleID:   rule,
+		Severity: severity,
+		RouteID:  r.route.Id,
+		File:     r.location.File,
+		Line:     r.location.Line,
+		Message:  fmt.Sprintf(format, a...),
+	}))
+	return &l.findings[len(l.findings)-1]
+}
+
+// knownRule true if the id is the one of a rule
+func knownRule(id string) bool {
+	if id == RuleParse || id == RuleUnknownRule || id == RuleNameCollision || id == RuleAmbiguousWitness {
+		return true
+	}
+	for _, rule := range lintRules {
+		if rule.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownRules reports the rule ids of the options and of the suppress
+// directives which aren't the ones of a rule, eg. misspelled
+func (l *linter) unknownRules() {
+	for _, id := range l.rules.Ignore {
+		if !knownRule(id) {
+			l.findings = append(l.findings, l.rules.apply(Finding{RuleID: RuleUnknownRule, Severity: SeverityWarning, Message: fmt.Sprintf("unknown rule %s in the ignored rules", id)}))
+		}
+	}
+	overridden := make([]string, 0, len(l.rules.Severity))
+	for id := range l.rules.Severity {
+		overridden = append(overridden, id)
+	}
+	sort.Strings(overridden)
+	for _, id := range overridden {
+		if !knownRule(id) {
+			l.findings = append(l.findings, l.rules.apply(Finding{RuleID: RuleUnknownRule, Severity: SeverityWarning, Message: fmt.Sprintf("unknown rule %s in the severity overrides", id)}))
+		}
+	}
+	for _, r := range l.routes {
+		for _, sup := range r.suppressed {
+			if !knownRule(sup.rule) {
+				if f := l.report(RuleUnknownRule, SeverityWarning, r, "unknown rule %s in %s", sup.rule, suppressDirective); f != nil {
+					f.Line = sup.line
+				}
+			}
+		}
+	}
+}
+
+// duplicateIDs reports the definitions of a route id defined more than once
+// which the routing ignores: like skipper does, the last definition wins
+func (l *linter) duplicateIDs() {
+	defs := make(map[string][]lintRoute)
+	for _, r := range l.routes {
+		defs[r.route.Id] = append(defs[r.route.Id], r)
+	}
+	for _, r := range l.routes {
+		all := defs[r.route.Id]
+		winner := all[len(all)-1]
+		if len(all) == 1 || r.route == winner.route {
+			continue
+		}
+		locations := make([]string, len(all))
+		for i, d := range all {
+			locations[i] = d.location.String()
+		}
+		l.report(RuleDuplicateID, SeverityError, r, "route id is defined %d times (%s), the definition at %s wins",
+			len(all), strings.Join(locations, ", "), winner.location)
+	}
+}
+
+// unknownFilters reports the routes using filters not in the registry
+func (l *linter) unknownFilters() {
+	for _, r := range l.routes {
+		for _, f := range r.route.Filters {
+			if _, ok := l.filters[f.Name]; !ok {
+				if f := l.report(RuleUnknownFilter, SeverityError, r, "unknown filter %s, the route is ignored", f.Name); f != nil {
+					f.Suggestion = "register the filter with CustomFilters or mock it with MockFilters"
+				}
+			}
+		}
+	}
+}
+
+// skippedFilterArgs builtin filters whose CreateFilter reads files or runs
+// scripts, CheckFilterArgs doesn't create them
+var skippedFilterArgs = map[string]bool{
+	"lua":       true,
+	"basicAuth": true,
+}
+
+// invalidFilterArgs reports the filters failing to be created with their
+// arguments, see checkFilterArgs
+func (l *linter) invalidFilterArgs() {
+	for _, r := range l.routes {
+		for _, f := range r.route.Filters {
+			spec, ok := l.filters[f.Name]
+			if !ok || !l.checkFilterArgs(spec) {
+				continue
+			}
+			if err := createFilter(spec, f.Args); err != nil {
+				l.report(RuleInvalidFilterArgs, SeverityError, r, "invalid filter %s: %v, the route is ignored", filterString(f), err)
+			}
+		}
+	}
+}
+
+// checkFilterArgs true if the arguments of the filter are checked: always
+// for the mocks of MockFilter, never for the plain ones, only with
+// CheckFilterArgs for the others
+func (l *linter) checkFilterArgs(spec filters.Spec) bool {
+	switch spec.(type) {
+	case *mockFilterSpec:
+		return true
+	case *filtertest.Filter:
+		return false
+	default:
+		return l.rules.CheckFilterArgs && !skippedFilterArgs[spec.Name()]
+	}
+}
+
+// invalidPredicateArgs reports the predicates of PredicateFunc failing to
+// be created with their arguments, the other specs aren't created
+func (l *linter) invalidPredicateArgs() {
+	for _, r := range l.routes {
+		for _, p := range r.route.Predicates {
+			spec, ok := l.predicates[p.Name]
+			if !ok || isBuiltinPredicate(p.Name) {
+				continue
+			}
+			if err := createPredicate(spec, p.Args); err != nil {
+				l.report(RuleInvalidPredicateArgs, SeverityError, r, "invalid predicate %s: %v, the route is ignored", predicateString(p), err)
+			}
+		}
+	}
+}
+
+// createFilter creates a filter, a panic of the spec is returned as an error
+func createFilter(spec filters.Spec, args []interface{}) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+	_, err = spec.CreateFilter(args)
+	return err
+}
+
+// filterString formats a filter with its arguments like in eskip
+func filterString(f *eskip.Filter) string {
+	r := &eskip.Route{Filters: []*eskip.Filter{f}, BackendType: eskip.ShuntBackend}
+	// strip the match all and the backend, the route is only used to format the filter
+	return strings.TrimSuffix(strings.TrimPrefix(r.String(), "* -> "), " -> <shunt>")
+}
+
+// loadFindings reports the loaded routes the routing ignores because they
+// use filters missing in the registry, unknown predicates, or mocked filters
+// and predicates with invalid arguments
+func loadFindings(routes []*eskip.Route, registry filters.Registry, predicates map[string]routing.PredicateSpec, locations map[string]Location) []Finding {
+	l := &linter{filters: registry, predicates: predicates}
+	for _, r := range routes {
+		l.routes = append(l.routes, lintRoute{route: r, location: locations[r.Id]})
+	}
+	l.unknownFilters()
+	l.unknownPredicates()
+	l.invalidFilterArgs()
+	l.invalidPredicateArgs()
+	sortFindings(l.findings)
+	return l.findings
+}
+
+// MissingFiltersError the routes use filters missing in the registry,
+// the routing would ignore them, see Options.AllowMissingFilters
+type MissingFiltersError struct {
+	// Routes ids of the routes using a missing filter by filter name
+	Routes map[string][]string
+}
+
+func (e *MissingFiltersError) Error() string {
+	names := make([]string, 0, len(e.Routes))
+	for name := range e.Routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	missing := make([]string, len(names))
+	for i, name := range names {
+		missing[i] = fmt.Sprintf("%s (used by %s)", name, strings.Join(e.Routes[name], ", "))
+	}
+	return fmt.Sprintf("missing filters %s, register them with CustomFilters or mock them with MockFilters", strings.Join(missing, ", "))
+}
+
+// missingFilters returns the error listing the filters of the routes missing in the registry, nil if there are none
+func missingFilters(routes []*eskip.Route, registry filters.Registry) error {
+	missing := make(map[string][]string)
+	for _, r := range routes {
+		for _, f := range r.Filters {
+			if _, ok := registry[f.Name]; ok {
+				continue
+			}
+			if ids := missing[f.Name]; len(ids) == 0 || ids[len(ids)-1] != r.Id {
+				missing[f.Name] = append(ids, r.Id)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &MissingFiltersError{Routes: missing}
+}
+
+// UnknownPredicatesError the routes use predicates without a spec, the
+// routing would ignore them, see Options.AllowUnknownPredicates
+type UnknownPredicatesError struct {
+	// Routes ids of the routes using an unknown predicate by predicate name
+	Routes map[string][]string
+	// Locations of the route definitions by route id, when known
+	Locations map[string]Location
+}
+
+func (e *UnknownPredicatesError) Error() string {
+	names := make([]string, 0, len(e.Routes))
+	for name := range e.Routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	unknown := make([]string, len(names))
+	for i, name := range names {
+		used := make([]string, len(e.Routes[name]))
+		for j, id := range e.Routes[name] {
+			used[j] = id
+			if loc, ok := e.Locations[id]; ok {
+				used[j] += " at " + loc.String()
+			}
+		}
+		unknown[i] = fmt.Sprintf("%s (used by %s)", name, strings.Join(used, ", "))
+	}
+	return fmt.Sprintf("unknown predicates %s, register them with CustomPredicates or mock them with MockPredicates", strings.Join(unknown, ", "))
+}
+
+// unknownPredicatesError returns the error listing the predicates of the routes without a spec, nil if there are none
+func unknownPredicatesError(routes []*eskip.Route, predicates map[string]routing.PredicateSpec, locations map[string]Location) error {
+	unknown := make(map[string][]string)
+	found := make(map[string]Location)
+	for _, r := range routes {
+		for _, p := range r.Predicates {
+			if _, ok := predicates[p.Name]; ok || isBuiltinPredicate(p.Name) {
+				continue
+			}
+			if ids := unknown[p.Name]; len(ids) == 0 || ids[len(ids)-1] != r.Id {
+				unknown[p.Name] = append(ids, r.Id)
+			}
+			if loc, ok := locations[r.Id]; ok {
+				found[r.Id] = loc
+			}
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	return &UnknownPredicatesError{Routes: unknown, Locations: found}
+}
+
+// unknownPredicates reports the routes using predicates without a spec
+func (l *linter) unknownPredicates() {
+	for _, r := range l.routes {
+		for _, p := range r.route.Predicates {
+			if isBuiltinPredicate(p.Name) {
+				continue
+			}
+			if _, ok := l.predicates[p.Name]; !ok {
+				if f := l.report(RuleUnknownPredicate, SeverityError, r, "unknown predicate %s, the route is ignored", p.Name); f != nil {
+					f.Suggestion = "register the predicate with CustomPredicates or mock it with MockPredicates"
+				}
+			}
+		}
+	}
+}
+
+// isBuiltinPredicate true for the predicates skipper handles without a spec
+func isBuiltinPredicate(name string) bool {
+	switch name {
+	case pathPredicate, pathSubtreePredicate, pathRegexpPredicate, hostPredicate,
+		methodPredicate, headerPredicate, headerRegexpPredicate:
+		return true
+	default:
+		return false
+	}
+}
+
+// invalidBackends reports the network and load balanced backends which aren't
+// valid http(s) urls, the shunt, loopback and dynamic backends are exempt
+func (l *linter) invalidBackends() {
+	for _, r := range l.routes {
+		switch r.route.BackendType {
+		case eskip.NetworkBackend:
+			if err := checkBackendURL(r.route.Backend, l.rules.AllowBackendPath); err != nil {
+				l.report(RuleInvalidBackend, SeverityError, r, "invalid backend %q: %v", r.route.Backend, err)
+			}
+		case eskip.LBBackend:
+			for _, ep := range r.route.LBEndpoints {
+				if err := checkBackendURL(ep, l.rules.AllowBackendPath); err != nil {
+					l.report(RuleInvalidBackend, SeverityError, r, "invalid endpoint %q: %v", ep, err)
+				}
+			}
+		}
+	}
+}
+
+// checkBackendURL checks the scheme and the host of a backend, and that it
+// has no path or query unless allowPath
+func checkBackendURL(backend string, allowPath bool) error {
+	u, err := url.Parse(backend)
+	if err != nil {
+		if uerr, ok := err.(*url.Error); ok {
+			return uerr.Err
+		}
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	if !validHostname(u.Hostname()) {
+		return fmt.Errorf("invalid host %s", u.Host)
+	}
+	if allowPath {
+		return nil
+	}
+	if u.Path != "" && u.Path != "/" {
+		return fmt.Errorf("unexpected path %s", u.Path)
+	}
+	if u.RawQuery != "" || u.ForceQuery {
+		return fmt.Errorf("unexpected query ?%s", u.RawQuery)
+	}
+	return nil
+}
+
+// validHostname true for a host name or an ip address, the port excluded
+func validHostname(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	for _, c := range host {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// unreachableRoutes reports the routes without a path when a catch all
+// route without other predicates, eg. PathSubtree("/") or Path("/*any"),
+// matches every request first: the path tree is searched before them
+func (l *linter) unreachableRoutes() {
+	var catchAll *routeConditions
+	var rest []*routeConditions
+	for _, r := range l.routes {
+		rc := newRouteConditions(r)
+		switch {
+		case catchAll == nil && rc.bareCatchAll():
+			catchAll = rc
+		case rc.leaf == "":
+			rest = append(rest, rc)
+		}
+	}
+	if catchAll == nil {
+		return
+	}
+	ps := Predicates(catchAll.route.route)
+	predicates := make([]string, len(ps))
+	for i, p := range ps {
+		predicates[i] = predicateString(p)
+	}
+	for _, rc := range rest {
+		l.report(RuleUnreachableRoute, SeverityWarning, rc.route, "route is unreachable due to catch-all route %s (%s), %s matches all its requests first",
+			catchAll.route.route.Id, catchAll.route.location, strings.Join(predicates, " && "))
+	}
+}
+
+// shadowedRoutes reports the routes which can never match or only some times
+// because another route matches all their requests with the same or a higher
+// priority, see routeConditions.shadows
+func (l *linter) shadowedRoutes() {
+	byLeaf := make(map[string][]*routeConditions)
+	var catchAll []*routeConditions
+	conditions := make([]*routeConditions, len(l.routes))
+	for i, r := range l.routes {
+		rc := newRouteConditions(r)
+		rc.index = i
+		conditions[i] = rc
+		byLeaf[rc.leaf] = append(byLeaf[rc.leaf], rc)
+		if rc.catchAll() {
+			catchAll = append(catchAll, rc)
+		}
+	}
+
+	for _, b := range conditions {
+		candidates := byLeaf[b.leaf]
+		if b.leaf == "" {
+			candidates = append(append([]*routeConditions{}, candidates...), catchAll...)
+		}
+		var by *routeConditions
+		found := notShadowed
+		for _, a := range candidates {
+			if a == b || a.route.route.Id == b.route.route.Id {
+				// a duplicate id is reported as such
+				continue
+			}
+			if b.leaf == "" && a.bareCatchAll() && !l.rules.ignored(RuleUnreachableRoute) {
+				// reported by unreachableRoutes
+				continue
+			}
+			if s := a.shadows(b); s > found {
+				found, by = s, a
+			}
+		}
+		r := b.route
+		switch {
+		case found == notShadowed:
+		case found == tiedShadowed && predicatesKey(by.route.route) == predicatesKey(r.route):
+			if by.index < b.index {
+				l.report(RuleShadowedRoute, SeverityWarning, r, "route has the same predicates of route %s, only one of them can match", by.route.route.Id)
+			}
+		case found == tiedShadowed:
+			l.report(RuleShadowedRoute, SeverityWarning, r, "route is possibly shadowed by route %s (%s), it matches all its requests with the same priority", by.route.route.Id, by.route.location)
+		case found == possiblyShadowed && by.tied(b):
+			// the other route is possibly shadowed too, reported on the last one
+			if by.index < b.index {
+				l.report(RuleShadowedRoute, SeverityWarning, r, "route is possibly shadowed by route %s (%s), its regexps may match all its requests with the same priority", by.route.route.Id, by.route.location)
+			}
+		case found == possiblyShadowed:
+			l.report(RuleShadowedRoute, SeverityWarning, r, "route is possibly shadowed by route %s (%s), its regexps may match all its requests with a higher priority", by.route.route.Id, by.route.location)
+		default:
+			l.report(RuleShadowedRoute, SeverityWarning, r, "route is shadowed by route %s (%s), it matches all its requests with a higher priority", by.route.route.Id, by.route.location)
+		}
+	}
+}
+
+// predicatesKey a string identifying the requests a route can match
+func predicatesKey(r *eskip.Route) string {
+	ps := Predicates(r)
+	keys := make([]string, 0, len(ps))
+	for _, p := range ps {
+		keys = append(keys, predicateString(p))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, " && ")
+}
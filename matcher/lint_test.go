@@ -0,0 +1,306 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	docs := []Document{
+		{
+			File: "a.eskip",
+			Content: []byte(`healthcheck: Path("/health") -> <shunt>;
+orders: Path("/orders") -> customfilter() -> "http://orders.svc";
+orders_get: Path("/orders") -> "http//orders.svc";
+unknown: Path("/unknown") && TenantIs("acme") -> unknownfilter() -> <shunt>;
+lb: Path("/lb") -> <roundRobin, "http://a.svc", "ftp://b.svc">;
+`),
+		},
+		{
+			File:    "b.eskip",
+			Content: []byte("orders_v2: Path(\"/v2/orders\") -> <shunt>;\n\nhealthcheck: Path(\"/healthz\") -> <shunt>;\n"),
+		},
+		{
+			File:    "broken.eskip",
+			Content: []byte(`broken: Path("/broken") -> `),
+		},
+	}
+
+	findings := Lint(docs, &LintOptions{MockFilters: []string{"customfilter"}})
+
+	if !assert.Len(t, findings, 7) {
+		for _, f := range findings {
+			t.Log(f)
+		}
+		return
+	}
+	assert.Equal(t, RuleParse, findings[0].RuleID)
+	assert.Equal(t, "broken.eskip", findings[0].File)
+	assert.Equal(t, 1, findings[0].Line)
+	assert.Equal(t, 25, findings[0].Column)
+	assert.Equal(t, Finding{
+		RuleID:   RuleDuplicateID,
+		Severity: SeverityError,
+		RouteID:  "healthcheck",
+		File:     "a.eskip",
+		Line:     1,
+		Message:  "route id is defined 2 times (a.eskip:1, b.eskip:3), the definition at b.eskip:3 wins",
+	}, findings[1])
+	assert.Equal(t, Finding{
+		RuleID:   RuleInvalidBackend,
+		Severity: SeverityError,
+		RouteID:  "orders_get",
+		File:     "a.eskip",
+		Line:     3,
+		Message:  `invalid backend "http//orders.svc": scheme must be http or https`,
+	}, findings[2])
+	assert.Equal(t, Finding{
+		RuleID:   RuleShadowedRoute,
+		Severity: SeverityWarning,
+		RouteID:  "orders_get",
+		File:     "a.eskip",
+		Line:     3,
+		Message:  "route has the same predicates of route orders, only one of them can match",
+	}, findings[3])
+
+	strs := []string{}
+	for _, f := range findings[4:] {
+		strs = append(strs, f.String())
+	}
+	assert.Equal(t, []string{
+		`a.eskip:4: error: unknown: unknown filter unknownfilter, the route is ignored [unknown-filter]`,
+		`a.eskip:4: error: unknown: unknown predicate TenantIs, the route is ignored [unknown-predicate]`,
+		`a.eskip:5: error: lb: invalid endpoint "ftp://b.svc": scheme must be http or https [invalid-backend]`,
+	}, strs)
+}
+
+func TestLintDuplicateIDs(t *testing.T) {
+	docs := []Document{
+		{File: "a.eskip", Content: []byte("healthcheck: Path(\"/health\") -> <shunt>;\nother: Path(\"/other\") -> <shunt>;\n")},
+		{File: "b.eskip", Content: []byte("healthcheck: Path(\"/healthz\") -> <shunt>;\n")},
+		{File: "c.eskip", Content: []byte("\nhealthcheck: Path(\"/status\") -> <shunt>;\n")},
+	}
+
+	findings := Lint(docs, &LintOptions{Ignore: []string{RuleShadowedRoute}})
+	want := "route id is defined 3 times (a.eskip:1, b.eskip:1, c.eskip:2), the definition at c.eskip:2 wins"
+	if assert.Len(t, findings, 2) {
+		assert.Equal(t, Finding{RuleID: RuleDuplicateID, Severity: SeverityError, RouteID: "healthcheck", File: "a.eskip", Line: 1, Message: want}, findings[0])
+		assert.Equal(t, Finding{RuleID: RuleDuplicateID, Severity: SeverityError, RouteID: "healthcheck", File: "b.eskip", Line: 1, Message: want}, findings[1])
+	}
+
+	findings = Lint(docs, &LintOptions{Severity: map[string]Severity{RuleDuplicateID: SeverityWarning}})
+	if assert.Len(t, findings, 2) {
+		assert.Equal(t, SeverityWarning, findings[0].Severity)
+		assert.Equal(t, SeverityWarning, findings[1].Severity)
+	}
+}
+
+func TestLintIgnore(t *testing.T) {
+	docs := []Document{{
+		File:    "routes.eskip",
+		Content: []byte(`a: Path("/a") -> unknownfilter() -> <shunt>; b: Path("/a") -> <shunt>;`),
+	}}
+
+	findings := Lint(docs, &LintOptions{Ignore: []string{RuleUnknownFilter}})
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, RuleShadowedRoute, findings[0].RuleID)
+	}
+
+	assert.Empty(t, Lint(docs, &LintOptions{Ignore: []string{RuleUnknownFilter, RuleShadowedRoute}}))
+}
+
+func TestLintValidRoutes(t *testing.T) {
+	docs := []Document{{
+		File: "routes.eskip",
+		Content: []byte(`
+a: Path("/a") && Cookie("c", "v") -> setPath("/b") -> "https://a.svc:8443";
+b: PathSubtree("/a") && Method("POST") -> <loopback>;
+c: Host(/^a[.]org$/) && Path("/a") -> <roundRobin, "http://a.svc", "http://b.svc">;
+d: * -> <shunt>;
+`),
+	}}
+	assert.Empty(t, Lint(docs, nil))
+}
+
+func TestSeverityAtLeast(t *testing.T) {
+	assert.True(t, SeverityError.AtLeast(SeverityWarning))
+	assert.True(t, SeverityError.AtLeast(SeverityError))
+	assert.True(t, SeverityWarning.AtLeast(SeverityWarning))
+	assert.False(t, SeverityWarning.AtLeast(SeverityError))
+}
+
+func TestLintInvalidBackends(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		allow   bool
+		message string
+	}{
+		{name: "valid", backend: `"https://orders.svc:8443"`},
+		{name: "trailing slash", backend: `"http://orders.svc/"`},
+		{name: "missing colon", backend: `"http//orders.svc"`, message: `invalid backend "http//orders.svc": scheme must be http or https`},
+		{name: "stray quote", backend: `"http://orders.svc\""`, message: `invalid backend "http://orders.svc\"": invalid host orders.svc"`},
+		{name: "missing host", backend: `"http:///orders"`, message: `invalid backend "http:///orders": missing host`},
+		{name: "path", backend: `"http://orders.svc/v1"`, message: `invalid backend "http://orders.svc/v1": unexpected path /v1`},
+		{name: "query", backend: `"http://orders.svc?debug=1"`, message: `invalid backend "http://orders.svc?debug=1": unexpected query ?debug=1`},
+		{name: "allowed path and query", backend: `"http://orders.svc/v1?debug=1"`, allow: true},
+		{name: "load balanced", backend: `<roundRobin, "http://a.svc", "http://b.svc/v1">`, message: `invalid endpoint "http://b.svc/v1": unexpected path /v1`},
+		{name: "shunt", backend: `<shunt>`},
+		{name: "loopback", backend: `<loopback>`},
+		{name: "dynamic", backend: `<dynamic>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := []Document{{File: "routes.eskip", Content: []byte(`orders: Path("/orders") -> ` + tt.backend + `;`)}}
+			findings := Lint(docs, &LintOptions{AllowBackendPath: tt.allow})
+			if tt.message == "" {
+				assert.Empty(t, findings)
+				return
+			}
+			if assert.Len(t, findings, 1) {
+				assert.Equal(t, Finding{
+					RuleID:   RuleInvalidBackend,
+					Severity: SeverityError,
+					RouteID:  "orders",
+					File:     "routes.eskip",
+					Line:     1,
+					Message:  tt.message,
+				}, findings[0])
+			}
+		})
+	}
+}
+
+func TestLintInvalidRegexps(t *testing.T) {
+	tests := []struct {
+		name      string
+		predicate string
+		severity  Severity
+		message   string
+	}{
+		{name: "valid", predicate: `PathRegexp("^/orders/[0-9]+$")`},
+		{name: "anchored wildcards", predicate: `Host(/^.*[.]example[.]org$/)`},
+		{name: "unclosed group", predicate: `PathRegexp("^/(orders$")`, severity: SeverityError,
+			message: "invalid regexp PathRegexp(\"^/(orders$\"): missing closing ) `^/(orders$` at position 1, the route is ignored"},
+		{name: "invalid escape", predicate: `HeaderRegexp("X-Id", "^[0-9]+\\q$")`, severity: SeverityError,
+			message: "invalid regexp HeaderRegexp(\"X-Id\", \"^[0-9]+\\\\q$\"): invalid escape sequence `\\q` at position 8, the route is ignored"},
+		{name: "unanchored wildcards", predicate: `PathRegexp(".*orders.*")`, severity: SeverityWarning,
+			message: "pathological regexp PathRegexp(\".*orders.*\"): unanchored .* at both ends, use the regexp without them"},
+		{name: "nested repetition", predicate: `PathRegexp("^/(a+)+$")`, severity: SeverityWarning,
+			message: "pathological regexp PathRegexp(\"^/(a+)+$\"): nested repetition (a+)+ can backtrack catastrophically"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := []Document{{File: "routes.eskip", Content: []byte(`orders: ` + tt.predicate + ` -> <shunt>;`)}}
+			findings := Lint(docs, nil)
+			if tt.message == "" {
+				assert.Empty(t, findings)
+				return
+			}
+			if assert.Len(t, findings, 1) {
+				assert.Equal(t, Finding{
+					RuleID:   RuleInvalidRegexp,
+					Severity: tt.severity,
+					RouteID:  "orders",
+					File:     "routes.eskip",
+					Line:     1,
+					Message:  tt.message,
+				}, findings[0])
+			}
+		})
+	}
+}
+
+func TestLintInvalidFilterArgs(t *testing.T) {
+	docs := []Document{{
+		File: "routes.eskip",
+		Content: []byte(`no_args: Path("/a") -> setPath() -> <shunt>;
+number: Path("/b") -> setRequestHeader("X-A", 1) -> <shunt>;
+valid: Path("/c") -> setPath("/") -> status(204) -> <shunt>;
+mocked: Path("/d") -> customfilter(1, 2, 3) -> <shunt>;
+lua: Path("/e") -> lua("missing.lua") -> <shunt>;
+`),
+	}}
+
+	assert.Empty(t, Lint(docs, &LintOptions{MockFilters: []string{"customfilter"}}), "disabled by default")
+
+	var strs []string
+	for _, f := range Lint(docs, &LintOptions{MockFilters: []string{"customfilter"}, CheckFilterArgs: true}) {
+		strs = append(strs, f.String())
+	}
+	assert.Equal(t, []string{
+		`routes.eskip:1: error: no_args: invalid filter setPath(): invalid filter parameters, the route is ignored [invalid-filter-args]`,
+		`routes.eskip:2: error: number: invalid filter setRequestHeader("X-A", 1): invalid filter parameters, the route is ignored [invalid-filter-args]`,
+	}, strs)
+}
+
+func TestLintDeprecated(t *testing.T) {
+	docs := []Document{{File: "routes.eskip", Content: []byte(`a: Path("/a") -> requestHeader("X-A", "1") -> <shunt>;
+b: Path("/b") && Traffic(0.5) -> redirect(302, "/a") -> <shunt>;
+`)}}
+	finding := func(severity Severity, route string, line int, message string, suggestion string) Finding {
+		return Finding{RuleID: RuleDeprecated, Severity: severity, RouteID: route, File: "routes.eskip", Line: line, Message: message, Suggestion: suggestion}
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		assert.Equal(t, []Finding{
+			finding(SeverityWarning, "a", 1, "filter requestHeader is deprecated, use setRequestHeader or appendRequestHeader", "use setRequestHeader or appendRequestHeader"),
+			finding(SeverityWarning, "b", 2, "filter redirect is deprecated, use redirectTo", "use redirectTo"),
+		}, Lint(docs, nil))
+	})
+
+	t.Run("merged", func(t *testing.T) {
+		deprecations := MergeDeprecations(DefaultDeprecations(),
+			DeprecationRule{Name: "Traffic", Kind: DeprecatedPredicate, Severity: SeverityError},
+			DeprecationRule{Name: "redirect", Kind: DeprecatedFilter, Replacement: "redirectTo(302, ...)"},
+			DeprecationRule{Name: "requestHeader", Kind: DeprecatedPredicate},
+		)
+		assert.Equal(t, []Finding{
+			finding(SeverityWarning, "a", 1, "filter requestHeader is deprecated, use setRequestHeader or appendRequestHeader", "use setRequestHeader or appendRequestHeader"),
+			finding(SeverityError, "b", 2, "predicate Traffic is deprecated", ""),
+			finding(SeverityWarning, "b", 2, "filter redirect is deprecated, use redirectTo(302, ...)", "use redirectTo(302, ...)"),
+		}, Lint(docs, &LintOptions{Deprecations: deprecations}))
+	})
+
+	t.Run("severity override", func(t *testing.T) {
+		findings := Lint(docs, &LintOptions{Severity: map[string]Severity{RuleDeprecated: SeverityError}})
+		if assert.Len(t, findings, 2) {
+			assert.Equal(t, SeverityError, findings[0].Severity)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Empty(t, Lint(docs, &LintOptions{Deprecations: []DeprecationRule{}}))
+		assert.Empty(t, Lint(docs, &LintOptions{Ignore: []string{RuleDeprecated}}))
+	})
+}
+
+func TestLintSuppress(t *testing.T) {
+	docs := []Document{{File: "routes.eskip", Content: []byte(`// eskip-match:ignore deprecated
+a: Path("/a") -> requestHeader("X-A", "1") -> <shunt>;
+b: Path("/b") -> requestHeader("X-B", "1") -> <shunt>;
+c: Path("/c")
+  // eskip-match:ignore unknown-filter, deprecated
+  -> unknownfilter() -> redirect(302, "/a") -> <shunt>;
+d: Path("/d") -> redirect(302, "/a") -> <shunt>; // eskip-match:ignore deprecated
+e: Path("/e") -> <shunt>; // eskip-match:ignore no-such-rule
+`)}}
+
+	findings := Lint(docs, &LintOptions{Ignore: []string{"other-rule"}, Severity: map[string]Severity{"some-rule": SeverityError}})
+	strs := []string{}
+	for _, f := range findings {
+		strs = append(strs, f.String())
+	}
+	assert.Equal(t, []string{
+		`warning: unknown rule other-rule in the ignored rules [unknown-rule]`,
+		`warning: unknown rule some-rule in the severity overrides [unknown-rule]`,
+		`routes.eskip:3: warning: b: filter requestHeader is deprecated, use setRequestHeader or appendRequestHeader [deprecated]`,
+		`routes.eskip:8: warning: e: unknown rule no-such-rule in eskip-match:ignore [unknown-rule]`,
+	}, strs)
+
+	findings = Lint([]Document{{File: "routes.eskip", Content: []byte(`a: Path("/a") -> unknownfilter() -> <shunt>;`)}}, nil)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, "register the filter with CustomFilters or mock it with MockFilters", findings[0].Suggestion)
+	}
+}
@@ -0,0 +1,191 @@
+package matcher
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// names of the decomposed load balancer predicates and filter of the newer
+// skipper versions, missing in the one compiled in
+const (
+	lbGroupPredicate  = "LBGroup"
+	lbMemberPredicate = "LBMember"
+	lbDecideFilter    = "lbDecide"
+)
+
+// lbSpecs the LBGroup and LBMember predicates: LBGroup(group) matches the
+// requests without a decision for the group, LBMember(group, index) the ones
+// whose decision is its index, see matcher.decideLB
+func lbSpecs() []routing.PredicateSpec {
+	return []routing.PredicateSpec{&lbGroupSpec{}, &lbMemberSpec{}}
+}
+
+type lbGroupSpec struct{}
+
+func (s *lbGroupSpec) Name() string { return lbGroupPredicate }
+
+func (s *lbGroupSpec) Create(args []interface{}) (routing.Predicate, error) {
+	if len(args) != 1 {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+	group, ok := args[0].(string)
+	if !ok {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+	return &lbPredicate{group: group, index: -1}, nil
+}
+
+type lbMemberSpec struct{}
+
+func (s *lbMemberSpec) Name() string { return lbMemberPredicate }
+
+func (s *lbMemberSpec) Create(args []interface{}) (routing.Predicate, error) {
+	if len(args) != 2 {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+	group, ok := args[0].(string)
+	if !ok {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+	index, ok := lbIndex(args[1])
+	if !ok {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+	return &lbPredicate{group: group, index: index}, nil
+}
+
+// lbIndex a non negative integral argument
+func lbIndex(arg interface{}) (int, bool) {
+	n, ok := arg.(float64)
+	if !ok || n < 0 || n != float64(int(n)) {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// lbPredicate an LBGroup predicate when index is -1, an LBMember one otherwise
+type lbPredicate struct {
+	group string
+	index int
+}
+
+func (p *lbPredicate) Match(req *http.Request) bool {
+	d, ok := req.Context().Value(lbDecisionKey{}).(lbDecision)
+	if p.index < 0 {
+		return !ok || d.group != p.group
+	}
+	return ok && d.group == p.group && d.index == p.index
+}
+
+// lbDecision the member of a group chosen for a request
+type lbDecision struct {
+	group string
+	index int
+}
+
+// lbDecisionKey context key of the lbDecision of a request
+type lbDecisionKey struct{}
+
+// lbDecideSpec the lbDecide(group, size) filter of the group routes, the
+// matcher decides without it, see matcher.decideLB
+type lbDecideSpec struct{}
+
+func (s *lbDecideSpec) Name() string { return lbDecideFilter }
+
+func (s *lbDecideSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) != 2 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+	if _, ok := args[0].(string); !ok {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+	if size, ok := lbIndex(args[1]); !ok || size == 0 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+	return &filtertest.Filter{FilterName: lbDecideFilter, Args: args}, nil
+}
+
+// roundRobin the default load balancer decisions, like lbDecide the members
+// of a group are chosen in turn
+type roundRobin struct {
+	mu   sync.Mutex
+	next map[string]int
+}
+
+func (r *roundRobin) decide(group string, members []string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next == nil {
+		r.next = make(map[string]int)
+	}
+	i := r.next[group] % len(members)
+	r.next[group] = i + 1
+	return i
+}
+
+// decideLB resolves the member route of a matched LBGroup route: a member is
+// chosen with Options.LBDecision, round robin by default, and the request is
+// routed again with the decision like skipper does on the loopback. Returns
+// the id of the member route matching, empty if none does.
+func (f *matcher) decideLB(req *http.Request, route *eskip.Route) string {
+	group, ok := lbGroup(route)
+	if !ok {
+		return ""
+	}
+	members := lbMembers(f.events.routes(), group)
+	if len(members) == 0 {
+		return ""
+	}
+	decide := f.options.LBDecision
+	if decide == nil {
+		decide = f.lb.decide
+	}
+	d := lbDecision{group: group, index: decide(group, members)}
+	member, _ := f.routing.Route(req.WithContext(context.WithValue(req.Context(), lbDecisionKey{}, d)))
+	if member == nil {
+		return ""
+	}
+	return member.Id
+}
+
+// lbGroup the group of the LBGroup predicate of the route
+func lbGroup(route *eskip.Route) (string, bool) {
+	for _, p := range route.Predicates {
+		if p.Name == lbGroupPredicate && len(p.Args) == 1 {
+			group, ok := p.Args[0].(string)
+			return group, ok
+		}
+	}
+	return "", false
+}
+
+// lbMembers the ids of the member routes of the group by index, the first
+// route of an index when there are several, empty for a missing index
+func lbMembers(routes []*eskip.Route, group string) []string {
+	var members []string
+	for _, r := range routes {
+		for _, p := range r.Predicates {
+			if p.Name != lbMemberPredicate || len(p.Args) != 2 || p.Args[0] != group {
+				continue
+			}
+			i, ok := lbIndex(p.Args[1])
+			if !ok {
+				continue
+			}
+			for len(members) <= i {
+				members = append(members, "")
+			}
+			if members[i] == "" {
+				members[i] = r.Id
+			}
+		}
+	}
+	return members
+}
@@ -0,0 +1,64 @@
+package matcher
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBDecision(t *testing.T) {
+	file := writeRoutes(t, `hello_lb_group: Path("/foo") && LBGroup("hello") -> lbDecide("hello", 2) -> <loopback>;
+hello_1: Path("/foo") && LBMember("hello", 0) -> "http://127.0.0.1:12345";
+hello_2: Path("/foo") && LBMember("hello", 1) -> "http://127.0.0.1:12346";
+other: Path("/bar") -> "http://127.0.0.1:12347";
+`)
+	defer os.Remove(file)
+
+	test := func(m Matcher, path string) TestResult {
+		res := m.Test(&RequestAttributes{Method: "GET", Path: path})
+		require.NoError(t, res.Err())
+		return res
+	}
+
+	for i, want := range []string{"hello_1", "hello_2"} {
+		t.Run(want, func(t *testing.T) {
+			var groups []string
+			var members []string
+			m, err := New(&Options{RoutesFile: file, LBDecision: func(group string, m []string) int {
+				groups, members = append(groups, group), m
+				return i
+			}})
+			require.NoError(t, err)
+			defer m.Close()
+			for j := 0; j < 3; j++ {
+				res := test(m, "/foo")
+				if assert.NotNil(t, res.Route()) {
+					assert.Equal(t, "hello_lb_group", res.Route().Id)
+				}
+				assert.Equal(t, want, res.LBMember())
+				assert.Contains(t, res.PrettyPrint(), "load balancer member: "+want)
+			}
+			assert.Equal(t, []string{"hello", "hello", "hello"}, groups)
+			assert.Equal(t, []string{"hello_1", "hello_2"}, members)
+
+			res := test(m, "/bar")
+			assert.Empty(t, res.LBMember(), "not a group route")
+		})
+	}
+
+	m, err := New(&Options{RoutesFile: file})
+	require.NoError(t, err)
+	defer m.Close()
+	var got []string
+	for j := 0; j < 4; j++ {
+		got = append(got, test(m, "/foo").LBMember())
+	}
+	assert.Equal(t, []string{"hello_1", "hello_2", "hello_1", "hello_2"}, got, "round robin by default")
+
+	m, err = New(&Options{RoutesFile: file, LBDecision: func(string, []string) int { return 2 }})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Empty(t, test(m, "/foo").LBMember(), "no member with the index")
+}
@@ -0,0 +1,165 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/routing"
+)
+
+// RouteLoadError a route the routing dropped, eg. because one of its
+// filters can't be created or it uses an unknown predicate
+type RouteLoadError struct {
+	// RouteID id of the dropped route
+	RouteID string
+	// File and Line where the route is defined, when known
+	File string
+	Line int
+	// Rule the lint rule of the cause, eg. RuleInvalidFilterArgs
+	Rule string
+	// Filter the filter failing with its arguments like in eskip, empty when
+	// the cause isn't a filter with invalid arguments
+	Filter string
+	// Predicate the predicate failing with its arguments like in eskip, empty
+	// when the cause isn't a predicate with invalid arguments
+	Predicate string
+	// Err the cause
+	Err error
+}
+
+func (e RouteLoadError) Error() string {
+	switch {
+	case e.Filter != "":
+		return fmt.Sprintf("route %s: filter %s: %v", e.RouteID, e.Filter, e.Err)
+	case e.Predicate != "":
+		return fmt.Sprintf("route %s: predicate %s: %v", e.RouteID, e.Predicate, e.Err)
+	default:
+		return fmt.Sprintf("route %s: %v", e.RouteID, e.Err)
+	}
+}
+
+// Unwrap returns the cause
+func (e RouteLoadError) Unwrap() error { return e.Err }
+
+// RouteLoadErrors error of New with Options.FailOnLoadErrors when the routing
+// dropped routes, one for each of them
+type RouteLoadErrors struct {
+	Errors []RouteLoadError
+}
+
+func (e *RouteLoadErrors) Error() string {
+	errs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err.Error()
+	}
+	return fmt.Sprintf("routes dropped by the routing: %s", strings.Join(errs, ", "))
+}
+
+// Unwrap returns the error of each dropped route
+func (e *RouteLoadErrors) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// routeLoadErrors tells why the routing rejected the routes, checking them
+// in the order of the routing: the network backend, the filters, created
+// again, and the predicates, the other causes are the reasons the routing
+// gave. Sorted by route id.
+func routeLoadErrors(routes []*eskip.Route, registry filters.Registry, predicates map[string]routing.PredicateSpec, rejected map[string]string, locations map[string]Location) []RouteLoadError {
+	var errs []RouteLoadError
+	for _, r := range routes {
+		reason, ok := rejected[r.Id]
+		if !ok {
+			continue
+		}
+		e := routeLoadError(r, registry, predicates, reason)
+		e.RouteID = r.Id
+		if loc, ok := locations[r.Id]; ok {
+			e.File, e.Line = loc.File, loc.Line
+		}
+		errs = append(errs, e)
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].RouteID < errs[j].RouteID })
+	return errs
+}
+
+// predicateArgsErrors creates the predicates of the routes with their specs
+// before the routing does, reporting the routes it would drop because of the
+// arguments of a predicate, the first failing one of each. Sorted by route
+// id, the unknown predicates are left to unknownPredicatesError.
+func predicateArgsErrors(routes []*eskip.Route, predicates map[string]routing.PredicateSpec, locations map[string]Location) []RouteLoadError {
+	var errs []RouteLoadError
+	for _, r := range routes {
+		for _, p := range r.Predicates {
+			spec, ok := predicates[p.Name]
+			if !ok || isBuiltinPredicate(p.Name) {
+				continue
+			}
+			if err := createPredicate(spec, p.Args); err != nil {
+				e := RouteLoadError{RouteID: r.Id, Rule: RuleInvalidPredicateArgs, Predicate: predicateString(p), Err: err}
+				if loc, ok := locations[r.Id]; ok {
+					e.File, e.Line = loc.File, loc.Line
+				}
+				errs = append(errs, e)
+				break
+			}
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].RouteID < errs[j].RouteID })
+	return errs
+}
+
+// createPredicate creates a predicate, a panic of the spec is returned as an
+// error
+func createPredicate(spec routing.PredicateSpec, args []interface{}) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+	_, err = spec.Create(args)
+	return err
+}
+
+// routeLoadError the first cause of the rejection of the route
+func routeLoadError(r *eskip.Route, registry filters.Registry, predicates map[string]routing.PredicateSpec, reason string) RouteLoadError {
+	if routeBackendType(r) == eskip.NetworkBackend {
+		if _, err := url.ParseRequestURI(r.Backend); err != nil {
+			return RouteLoadError{Rule: RuleInvalidBackend, Err: err}
+		}
+	}
+	for _, f := range r.Filters {
+		spec, ok := registry[f.Name]
+		if !ok {
+			return RouteLoadError{Rule: RuleUnknownFilter, Err: fmt.Errorf("unknown filter %s", f.Name)}
+		}
+		if _, err := spec.CreateFilter(f.Args); err != nil {
+			return RouteLoadError{Rule: RuleInvalidFilterArgs, Filter: filterString(f), Err: err}
+		}
+	}
+	for _, p := range r.Predicates {
+		if isBuiltinPredicate(p.Name) {
+			continue
+		}
+		spec, ok := predicates[p.Name]
+		if !ok {
+			return RouteLoadError{Rule: RuleUnknownPredicate, Err: fmt.Errorf("unknown predicate %s", p.Name)}
+		}
+		if err := createPredicate(spec, p.Args); err != nil {
+			return RouteLoadError{Rule: RuleInvalidPredicateArgs, Predicate: predicateString(p), Err: err}
+		}
+	}
+	if strings.Contains(reason, "regexp") {
+		return RouteLoadError{Rule: RuleInvalidRegexp, Err: errors.New(reason)}
+	}
+	// the arguments of the predicates of the routing, eg. Path
+	return RouteLoadError{Rule: RuleInvalidPredicateArgs, Err: errors.New(reason)}
+}
@@ -0,0 +1,192 @@
+package matcher
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadErrors(t *testing.T) {
+	file := writeRoutes(t, `valid: Path("/valid") -> setPath("/") -> "http://valid.svc";
+badpath: Path("/bad") -> setRequestHeader("X-Ok", "1") -> setPath(42) -> "http://bad.svc";
+badstatus: Path("/status") -> status("teapot") -> <shunt>;
+missing: Path("/missing") -> tenantAuth() -> <shunt>;
+`)
+	defer os.Remove(file)
+
+	var log strings.Builder
+	m, err := New(&Options{RoutesFile: file, AllowMissingFilters: true, Verbose: true, Log: &log})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, 1, m.RouteCount())
+
+	errs := m.LoadErrors()
+	if assert.Len(t, errs, 3) {
+		assert.Equal(t, "badpath", errs[0].RouteID)
+		assert.Equal(t, "setPath(42)", errs[0].Filter)
+		assert.Equal(t, RuleInvalidFilterArgs, errs[0].Rule)
+		assert.Equal(t, file, errs[0].File)
+		assert.Equal(t, 2, errs[0].Line)
+		assert.Error(t, errs[0].Err)
+		assert.Equal(t, "badstatus", errs[1].RouteID)
+		assert.Equal(t, `status("teapot")`, errs[1].Filter)
+		assert.Equal(t, "missing", errs[2].RouteID)
+		assert.Equal(t, RuleUnknownFilter, errs[2].Rule)
+		assert.Equal(t, "route missing: unknown filter tenantAuth", errs[2].Error())
+	}
+	assert.Contains(t, log.String(), "warning: dropped route badpath: filter setPath(42): ")
+
+	_, err = New(&Options{RoutesFile: file, AllowMissingFilters: true, FailOnLoadErrors: true})
+	require.Error(t, err)
+	if assert.IsType(t, &RouteLoadErrors{}, err) {
+		assert.Len(t, err.(*RouteLoadErrors).Errors, 3)
+		assert.Contains(t, err.Error(), "route badstatus: filter status(\"teapot\")")
+	}
+
+	m.Close()
+	assert.Nil(t, m.LoadErrors())
+}
+
+func TestLoadErrorsAllCauses(t *testing.T) {
+	file := writeRoutes(t, `valid: Path("/valid") -> "http://valid.svc";
+badargs: Path("/args") -> setPath(42) -> "http://bad.svc";
+badregexp: PathRegexp("[") -> "http://bad.svc";
+tenant: Path("/tenant") && TenantIs("acme") -> <shunt>;
+`)
+	defer os.Remove(file)
+
+	// not only the unknown predicate, checked first
+	_, err := New(&Options{RoutesFile: file, FailOnLoadErrors: true})
+	require.Error(t, err)
+	var loadErrs *RouteLoadErrors
+	require.True(t, errors.As(err, &loadErrs), "%v", err)
+	want := []struct {
+		id, rule string
+		line     int
+	}{
+		{"badargs", RuleInvalidFilterArgs, 2},
+		{"badregexp", RuleInvalidRegexp, 3},
+		{"tenant", RuleUnknownPredicate, 4},
+	}
+	if assert.Len(t, loadErrs.Errors, len(want)) {
+		for i, w := range want {
+			e := loadErrs.Errors[i]
+			assert.Equal(t, w.id, e.RouteID)
+			assert.Equal(t, w.rule, e.Rule, w.id)
+			assert.Equal(t, file, e.File)
+			assert.Equal(t, w.line, e.Line)
+			assert.Contains(t, err.Error(), "route "+w.id+": ")
+		}
+	}
+	assert.Len(t, loadErrs.Unwrap(), len(want))
+	var routeErr RouteLoadError
+	assert.True(t, errors.As(err, &routeErr), "the errors of the routes are wrapped")
+
+	// lenient: the matcher loads and reports them
+	m, err := New(&Options{RoutesFile: file, AllowUnknownPredicates: true})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, 1, m.RouteCount())
+	assert.Len(t, m.LoadErrors(), len(want))
+
+	_, err = New(&Options{RoutesFile: file})
+	assert.IsType(t, &UnknownPredicatesError{}, err, "without FailOnLoadErrors the unknown predicates fail first")
+}
+
+func TestPredicateArgsErrors(t *testing.T) {
+	file := writeRoutes(t, `valid: Path("/valid") && Cookie("session", "^.+$") -> <shunt>;
+cookiearity: Path("/cookie") && Cookie("session") -> <shunt>;
+cookietype: Path("/cookietype") && Cookie(42, "^.+$") -> <shunt>;
+queryarity: Path("/query") && QueryParam() -> <shunt>;
+querytype: Path("/querytype") && QueryParam(42) -> <shunt>;
+`)
+	defer os.Remove(file)
+
+	want := []struct {
+		id, predicate string
+		line          int
+	}{
+		{"cookiearity", `Cookie("session")`, 2},
+		{"cookietype", `Cookie(42, "^.+$")`, 3},
+		{"queryarity", "QueryParam()", 4},
+		{"querytype", "QueryParam(42)", 5},
+	}
+	check := func(t *testing.T, errs []RouteLoadError) {
+		if assert.Len(t, errs, len(want)) {
+			for i, w := range want {
+				e := errs[i]
+				assert.Equal(t, w.id, e.RouteID)
+				assert.Equal(t, RuleInvalidPredicateArgs, e.Rule, w.id)
+				assert.Equal(t, w.predicate, e.Predicate, w.id)
+				assert.Equal(t, file, e.File)
+				assert.Equal(t, w.line, e.Line)
+				assert.Error(t, e.Err)
+				assert.Equal(t, "route "+w.id+": predicate "+w.predicate+": "+e.Err.Error(), e.Error())
+			}
+		}
+	}
+
+	t.Run("lenient", func(t *testing.T) {
+		var log strings.Builder
+		m, err := New(&Options{RoutesFile: file, Verbose: true, Log: &log})
+		require.NoError(t, err)
+		defer m.Close()
+		assert.Equal(t, 1, m.RouteCount())
+		check(t, m.LoadErrors())
+		assert.Contains(t, log.String(), `warning: dropped route cookiearity: predicate Cookie("session"): `)
+		var rules []string
+		for _, finding := range m.Findings() {
+			if finding.RuleID == RuleInvalidPredicateArgs {
+				rules = append(rules, finding.RouteID)
+			}
+		}
+		assert.Equal(t, []string{"cookiearity", "cookietype", "queryarity", "querytype"}, rules)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		_, err := New(&Options{RoutesFile: file, Strict: true})
+		var loadErrs *RouteLoadErrors
+		require.True(t, errors.As(err, &loadErrs), "%v", err)
+		check(t, loadErrs.Errors)
+	})
+
+	t.Run("aggregated with the other load errors", func(t *testing.T) {
+		_, err := New(&Options{RoutesFile: file, Strict: true, FailOnLoadErrors: true})
+		var loadErrs *RouteLoadErrors
+		require.True(t, errors.As(err, &loadErrs), "%v", err)
+		check(t, loadErrs.Errors)
+	})
+}
+
+func TestOnRouteDropped(t *testing.T) {
+	file := writeRoutes(t, `valid: Path("/valid") -> "http://valid.svc";
+badargs: Path("/bad") -> setPath(42) -> "http://bad.svc";
+badregexp: PathRegexp("[") -> "http://bad.svc";
+`)
+	defer os.Remove(file)
+
+	var mu sync.Mutex
+	dropped := map[string]error{}
+	m, err := New(&Options{RoutesFile: file, OnRouteDropped: func(id string, reason error) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped[id] = reason
+	}})
+	require.NoError(t, err)
+	defer m.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, dropped, 2)
+	if assert.Contains(t, dropped, "badargs") {
+		assert.Contains(t, dropped["badargs"].Error(), "invalid filter parameters")
+	}
+	if assert.Contains(t, dropped, "badregexp") {
+		assert.Contains(t, dropped["badregexp"].Error(), "missing closing ]")
+	}
+}
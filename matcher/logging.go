@@ -0,0 +1,265 @@
+package matcher
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/logging"
+)
+
+// Verbosity levels of the diagnostics written by a matcher, see Options.Verbosity
+const (
+	// VerbositySilent no diagnostics, but the panics of the hooks
+	VerbositySilent = iota
+	// VerbosityWarn the skipper routing errors and warnings, eg. the
+	// rejected routes, the dropped routes and the load findings
+	VerbosityWarn
+	// VerbosityInfo in addition every tested request in its normalized form
+	// and the matching route with the location of its definition, the
+	// skipper routing info lines and the load report
+	VerbosityInfo
+	// VerbosityDebug in addition the near misses of every tested request and
+	// the skipper routing debug lines, the level of Options.Verbose
+	VerbosityDebug
+	// VerbosityTrace in addition the whole route table once loaded and the
+	// effective route table
+	VerbosityTrace
+)
+
+// The former verbosity levels
+const (
+	// Deprecated: use VerbositySilent
+	VerbosityOff = VerbositySilent
+	// Deprecated: use VerbosityInfo
+	VerbosityRequest = VerbosityInfo
+	// Deprecated: use VerbosityDebug
+	VerbosityRouting = VerbosityDebug
+	// Deprecated: use VerbosityTrace
+	VerbosityRoutes = VerbosityTrace
+)
+
+// nearMissesLogged number of near misses written at VerbosityDebug
+const nearMissesLogged = 3
+
+// diagnostics writes the lines enabled by a verbosity level as they come,
+// without retaining them, the disabled ones aren't even formatted.
+// It's safe to use from the routing goroutines.
+type diagnostics struct {
+	mu sync.Mutex
+	w  io.Writer
+	// tb writes the lines instead of w when set, see Options.TB
+	tb       testing.TB
+	level    int
+	redacted []string
+}
+
+func newDiagnostics(o *Options) *diagnostics {
+	level := o.Verbosity
+	if o.Verbose && level < VerbosityDebug {
+		level = VerbosityDebug
+	}
+	w := o.Log
+	if w == nil {
+		w = os.Stderr
+	}
+	redacted := append(append([]string{}, DefaultRedactedHeaders...), o.RedactedHeaders...)
+	return &diagnostics{w: w, tb: o.TB, level: level, redacted: redacted}
+}
+
+func (d *diagnostics) enabled(level int) bool {
+	return d.level >= level
+}
+
+// printf writes a line if level is enabled
+func (d *diagnostics) printf(level int, format string, a ...interface{}) {
+	if !d.enabled(level) {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.tb != nil {
+		d.tb.Helper()
+		d.tb.Logf(format, a...)
+		return
+	}
+	fmt.Fprintf(d.w, format+"\n", a...)
+}
+
+// routeTable writes the loaded routes with their location
+func (d *diagnostics) routeTable(routes []*eskip.Route, locations map[string]Location) {
+	d.printf(VerbosityTrace, "route table: %d route(s)", len(routes))
+	for _, r := range routes {
+		loc := locations[r.Id].String()
+		if loc == "" {
+			loc = "unknown location"
+		}
+		d.printf(VerbosityTrace, "  %s: %s: %s", loc, r.Id, r.String())
+	}
+}
+
+// effectiveRoutes writes the active route table of the matcher
+func (d *diagnostics) effectiveRoutes(m *matcher) {
+	if !d.enabled(VerbosityTrace) {
+		return
+	}
+	var b strings.Builder
+	writeEffectiveRoutes(&b, FormatEskip, m.effectiveRoutes())
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "  " + line
+		}
+	}
+	d.printf(VerbosityTrace, "effective routes:\n%s", strings.Join(lines, "\n"))
+}
+
+// test writes the tested request, the matching route and the near misses
+func (d *diagnostics) test(m *matcher, attributes *RequestAttributes, route *eskip.Route) {
+	if !d.enabled(VerbosityInfo) {
+		return
+	}
+	if d.tb != nil {
+		d.tb.Helper()
+	}
+	raw := strings.TrimSuffix(RawRequest(attributes, d.redacted), "\n")
+	d.printf(VerbosityInfo, "tested request:\n  %s", strings.Replace(raw, "\n", "\n  ", -1))
+	if route != nil {
+		loc := m.locations[route.Id].String()
+		if loc == "" {
+			loc = "unknown location"
+		}
+		d.printf(VerbosityInfo, "matching route: %s (%s)", route.Id, loc)
+	} else {
+		d.printf(VerbosityInfo, "matching route: none")
+	}
+
+	if !d.enabled(VerbosityDebug) {
+		return
+	}
+	for _, miss := range m.Explain(attributes, nearMissesLogged) {
+		var failed []string
+		for _, v := range miss.Verdicts {
+			if !v.Matched {
+				failed = append(failed, v.Predicate)
+			}
+		}
+		d.printf(VerbosityDebug, "near miss: %s, %d of %d predicate(s) failed: %s",
+			miss.RouteID, miss.Failed, len(miss.Verdicts), strings.Join(failed, ", "))
+	}
+}
+
+// logCapture writer keeping each line written as an entry, see Options.CaptureLogs
+type logCapture struct {
+	entries []string
+}
+
+func (c *logCapture) Write(p []byte) (int, error) {
+	c.entries = append(c.entries, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// capture the diagnostics of a test written at VerbosityDebug whatever the
+// verbosity, with the redacted headers of d
+func (d *diagnostics) capture(m *matcher, attributes *RequestAttributes, route *eskip.Route) []string {
+	c := &logCapture{}
+	(&diagnostics{w: c, level: VerbosityDebug, redacted: d.redacted}).test(m, attributes, route)
+	return c.entries
+}
+
+// routingLogger skipper logger writing the routing log lines at the
+// verbosity of their level, or to the logger of Options.Logger or
+// Options.LogWriter when set, the reloads they report are tracked by events
+type routingLogger struct {
+	d      *diagnostics
+	events *routingEvents
+	logger logging.Logger
+}
+
+func (l *routingLogger) log(level string, msg string) {
+	l.events.logged(level, msg)
+	if l.logger == nil {
+		l.d.printf(routingLogVerbosity(level), "routing %s: %s", level, msg)
+		return
+	}
+	switch level {
+	case "error":
+		l.logger.Error(msg)
+	case "warning":
+		l.logger.Warn(msg)
+	case "info":
+		l.logger.Info(msg)
+	default:
+		l.logger.Debug(msg)
+	}
+}
+
+// routingLogVerbosity the verbosity enabling the routing log lines of a level
+func routingLogVerbosity(level string) int {
+	switch level {
+	case "error", "warning":
+		return VerbosityWarn
+	case "info":
+		return VerbosityInfo
+	default:
+		return VerbosityDebug
+	}
+}
+
+func (l *routingLogger) Error(a ...interface{})            { l.log("error", fmt.Sprint(a...)) }
+func (l *routingLogger) Errorf(f string, a ...interface{}) { l.log("error", fmt.Sprintf(f, a...)) }
+func (l *routingLogger) Warn(a ...interface{})             { l.log("warning", fmt.Sprint(a...)) }
+func (l *routingLogger) Warnf(f string, a ...interface{})  { l.log("warning", fmt.Sprintf(f, a...)) }
+func (l *routingLogger) Info(a ...interface{})             { l.log("info", fmt.Sprint(a...)) }
+func (l *routingLogger) Infof(f string, a ...interface{})  { l.log("info", fmt.Sprintf(f, a...)) }
+func (l *routingLogger) Debug(a ...interface{})            { l.log("debug", fmt.Sprint(a...)) }
+func (l *routingLogger) Debugf(f string, a ...interface{}) { l.log("debug", fmt.Sprintf(f, a...)) }
+
+// writerLogger skipper logger writing the log lines to Options.LogWriter
+// prefixed by their level, the debug ones only when verbose
+type writerLogger struct {
+	mu         sync.Mutex
+	w          io.Writer
+	debug      bool
+	timestamps bool
+}
+
+// routingLog the logger of the skipper routing log lines of the options,
+// nil for the diagnostics
+func routingLog(o *Options) logging.Logger {
+	switch {
+	case o.Logger != nil:
+		return o.Logger
+	case o.LogWriter != nil:
+		return &writerLogger{w: o.LogWriter, debug: o.Verbose || o.Verbosity >= VerbosityDebug, timestamps: o.LogTimestamps}
+	default:
+		return nil
+	}
+}
+
+func (l *writerLogger) log(level string, msg string) {
+	if level == "debug" && !l.debug {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.timestamps {
+		fmt.Fprintf(l.w, "%s %s: %s\n", time.Now().Format(time.RFC3339), level, msg)
+		return
+	}
+	fmt.Fprintf(l.w, "%s: %s\n", level, msg)
+}
+
+func (l *writerLogger) Error(a ...interface{})            { l.log("error", fmt.Sprint(a...)) }
+func (l *writerLogger) Errorf(f string, a ...interface{}) { l.log("error", fmt.Sprintf(f, a...)) }
+func (l *writerLogger) Warn(a ...interface{})             { l.log("warning", fmt.Sprint(a...)) }
+func (l *writerLogger) Warnf(f string, a ...interface{})  { l.log("warning", fmt.Sprintf(f, a...)) }
+func (l *writerLogger) Info(a ...interface{})             { l.log("info", fmt.Sprint(a...)) }
+func (l *writerLogger) Infof(f string, a ...interface{})  { l.log("info", fmt.Sprintf(f, a...)) }
+func (l *writerLogger) Debug(a ...interface{})            { l.log("debug", fmt.Sprint(a...)) }
+func (l *writerLogger) Debugf(f string, a ...interface{}) { l.log("debug", fmt.Sprintf(f, a...)) }
@@ -0,0 +1,356 @@
+package matcher
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/filters/builtin"
+	"github.com/zalando/skipper/routing"
+	"github.com/zalando/skipper/routing/testdataclient"
+)
+
+func TestVerbosity(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  Options
+		contains []string
+		missing  []string
+	}{
+		{
+			name:    "silent",
+			missing: []string{"tested request", "routing info", "near miss", "route table"},
+		},
+		{
+			name:    "info",
+			options: Options{Verbosity: VerbosityInfo},
+			contains: []string{
+				"tested request:\n  GET /bar HTTP/1.1\n",
+				"matching route: bar (testdata/routes.eskip:1)\n",
+				"routing info: route settings applied\n",
+				"loaded 8 routes in ",
+			},
+			missing: []string{"near miss", "route table"},
+		},
+		{
+			name:    "debug",
+			options: Options{Verbosity: VerbosityDebug},
+			contains: []string{
+				"routing info: route settings applied\n",
+				`near miss: foo, 1 of 1 predicate(s) failed: PathSubtree("/foo")`,
+				"loaded 8 routes in ",
+			},
+			missing: []string{"route table"},
+		},
+		{
+			name:     "verbose is debug",
+			options:  Options{Verbose: true},
+			contains: []string{"routing info: route settings applied\n", "near miss:"},
+			missing:  []string{"route table"},
+		},
+		{
+			name:    "trace",
+			options: Options{Verbosity: VerbosityTrace},
+			contains: []string{
+				"route table: 8 route(s)\n",
+				"  testdata/routes.eskip:2: foo: PathSubtree(\"/foo\") -> <shunt>\n",
+				"effective routes:\n  bar: Path(\"/bar\") -> <shunt>;\n\n  // mocked filters: customfilter\n  customfilter: ",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &bytes.Buffer{}
+			o := tt.options
+			o.RoutesFile = "testdata/routes.eskip"
+			o.Log = log
+			o.MockFilters = []string{"customfilter"}
+			m, err := New(&o)
+			require.NoError(t, err)
+			m.Test(&RequestAttributes{Path: "/bar"})
+
+			for _, s := range tt.contains {
+				assert.Contains(t, log.String(), s)
+			}
+			for _, s := range tt.missing {
+				assert.NotContains(t, log.String(), s)
+			}
+		})
+	}
+}
+
+func TestVerbosityLevels(t *testing.T) {
+	file := writeRoutes(t, `valid: Path("/valid") -> "http://valid.svc";
+bad: Path("/bad") -> setPath(42) -> "http://bad.svc";
+`)
+	defer os.Remove(file)
+
+	const (
+		rejected = "routing error: failed to process route (bad): invalid filter parameters\n"
+		dropped  = "warning: dropped route bad: filter setPath(42): invalid filter parameters\n"
+		applied  = "routing info: route settings applied\n"
+		loaded   = "loaded 2 routes in "
+		request  = "tested request:\n  GET /valid HTTP/1.1\n"
+		matching = "matching route: valid ("
+		nearMiss = `near miss: bad, 1 of 1 predicate(s) failed: Path("/bad")`
+		table    = "route table: 2 route(s)\n"
+		routing  = "effective routes:\n  valid: Path(\"/valid\") -> \"http://valid.svc\";\n"
+	)
+	all := []string{rejected, dropped, applied, loaded, request, matching, nearMiss, table, routing}
+	tests := []struct {
+		name    string
+		options Options
+		// written the lines written, the others of all aren't
+		written []string
+	}{
+		{name: "silent", options: Options{Verbosity: VerbositySilent}},
+		{name: "warn", options: Options{Verbosity: VerbosityWarn}, written: []string{rejected, dropped}},
+		{name: "info", options: Options{Verbosity: VerbosityInfo}, written: []string{rejected, dropped, applied, loaded, request, matching}},
+		{name: "debug", options: Options{Verbosity: VerbosityDebug}, written: []string{rejected, dropped, applied, loaded, request, matching, nearMiss}},
+		{name: "verbose is debug", options: Options{Verbose: true}, written: []string{rejected, dropped, applied, loaded, request, matching, nearMiss}},
+		{name: "trace", options: Options{Verbosity: VerbosityTrace}, written: all},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log bytes.Buffer
+			o := tt.options
+			o.RoutesFile, o.Log = file, &log
+			m, err := New(&o)
+			require.NoError(t, err)
+			m.Test(&RequestAttributes{Path: "/valid"})
+			m.Close()
+
+			written := make(map[string]bool)
+			for _, s := range tt.written {
+				written[s] = true
+				assert.Contains(t, log.String(), s)
+			}
+			for _, s := range all {
+				if !written[s] {
+					assert.NotContains(t, log.String(), s)
+				}
+			}
+		})
+	}
+}
+
+func TestVerbosityDebugLogLevels(t *testing.T) {
+	tests := []struct {
+		level   int
+		written []string
+	}{
+		{level: VerbositySilent},
+		{level: VerbosityWarn, written: []string{"error", "warning"}},
+		{level: VerbosityInfo, written: []string{"error", "warning", "info"}},
+		{level: VerbosityDebug, written: []string{"error", "warning", "info", "debug"}},
+		{level: VerbosityTrace, written: []string{"error", "warning", "info", "debug"}},
+	}
+	for _, tt := range tests {
+		var log bytes.Buffer
+		d := newDiagnostics(&Options{Verbosity: tt.level, Log: &log})
+		l := &routingLogger{d: d, events: newRoutingEvents(nil, d)}
+		l.Error("e")
+		l.Warn("w")
+		l.Info("i")
+		l.Debug("d")
+		var want strings.Builder
+		for _, level := range tt.written {
+			fmt.Fprintf(&want, "routing %s: %s\n", level, level[:1])
+		}
+		assert.Equal(t, want.String(), log.String(), "verbosity %d", tt.level)
+	}
+}
+
+func TestVerbosityNoMatch(t *testing.T) {
+	log := &bytes.Buffer{}
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, Verbosity: VerbosityInfo, Log: log})
+	require.NoError(t, err)
+	m.Test(&RequestAttributes{Path: "/missing", Headers: map[string]string{"Authorization": "secret"}})
+
+	assert.Contains(t, log.String(), "matching route: none\n")
+	assert.False(t, strings.Contains(log.String(), "secret"), "credentials must be redacted")
+}
+
+// lineCounter writer counting the lines written
+type lineCounter struct {
+	mu    sync.Mutex
+	lines int
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines += bytes.Count(p, []byte("\n"))
+	return len(p), nil
+}
+
+func (c *lineCounter) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lines
+}
+
+func TestRoutingLogNotRetained(t *testing.T) {
+	defer func(d time.Duration) { pollTimeout = d }(pollTimeout)
+	pollTimeout = time.Millisecond
+	const reloads = 200
+
+	for _, level := range []int{VerbositySilent, VerbosityDebug} {
+		log := &lineCounter{}
+		o := &Options{Verbosity: level, Log: log}
+		client, err := testdataclient.NewDoc(`a: Path("/a") -> <shunt>;`)
+		require.NoError(t, err)
+		router, _, err := createRouting([]routing.DataClient{client}, builtin.MakeRegistry(), o, newDiagnostics(o), time.Now().Add(time.Second), time.Second)
+		require.NoError(t, err)
+
+		for i := 0; i < reloads; i++ {
+			require.NoError(t, client.UpdateDoc(fmt.Sprintf(`r%d: Path("/r%d") -> <shunt>;`, i, i), nil))
+		}
+		router.Close()
+
+		if level == VerbositySilent {
+			// nothing is formatted nor kept for a reader
+			assert.Equal(t, 0, log.count())
+			continue
+		}
+		// every reload is written as it happens, the diagnostics keep no entries
+		assert.True(t, log.count() >= reloads, "%d line(s) written for %d reloads", log.count(), reloads)
+	}
+}
+
+// recordingLogger skipper logger recording the lines by level
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines map[string][]string
+}
+
+func (l *recordingLogger) record(level string, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lines == nil {
+		l.lines = make(map[string][]string)
+	}
+	l.lines[level] = append(l.lines[level], msg)
+}
+
+func (l *recordingLogger) level(level string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines[level]...)
+}
+
+func (l *recordingLogger) Error(a ...interface{})            { l.record("error", fmt.Sprint(a...)) }
+func (l *recordingLogger) Errorf(f string, a ...interface{}) { l.record("error", fmt.Sprintf(f, a...)) }
+func (l *recordingLogger) Warn(a ...interface{})             { l.record("warning", fmt.Sprint(a...)) }
+func (l *recordingLogger) Warnf(f string, a ...interface{}) {
+	l.record("warning", fmt.Sprintf(f, a...))
+}
+func (l *recordingLogger) Info(a ...interface{})             { l.record("info", fmt.Sprint(a...)) }
+func (l *recordingLogger) Infof(f string, a ...interface{})  { l.record("info", fmt.Sprintf(f, a...)) }
+func (l *recordingLogger) Debug(a ...interface{})            { l.record("debug", fmt.Sprint(a...)) }
+func (l *recordingLogger) Debugf(f string, a ...interface{}) { l.record("debug", fmt.Sprintf(f, a...)) }
+
+func TestLogger(t *testing.T) {
+	f, err := ioutil.TempFile("", "logger-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`valid: Path("/valid") -> "http://valid.svc";
+bad: Path("/bad") -> setPath(42) -> "http://bad.svc";
+`)
+	f.Close()
+
+	logger := &recordingLogger{}
+	var diag bytes.Buffer
+	m, err := New(&Options{RoutesFile: f.Name(), Verbose: true, Log: &diag, Logger: logger})
+	require.NoError(t, err)
+	defer m.Close()
+
+	if assert.Len(t, logger.level("error"), 1) {
+		assert.Contains(t, logger.level("error")[0], "failed to process route (bad)")
+	}
+	assert.NotEmpty(t, logger.level("info"), "the route updates are logged")
+	assert.Len(t, m.LoadErrors(), 1, "the rejections are still tracked")
+	assert.NotContains(t, diag.String(), "routing ", "the routing lines aren't in the diagnostics")
+}
+
+// lockedBuffer a buffer written by the routing goroutine, which can go on
+// writing after Close
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLogWriter(t *testing.T) {
+	out := &lockedBuffer{}
+	m, err := New(&Options{RoutesFile: "testdata/tenants.eskip", AllowMissingFilters: true, AllowUnknownPredicates: true, LogWriter: out})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Contains(t, out.String(), "info: route settings applied\n")
+
+	m.Close()
+	out = &lockedBuffer{}
+	m, err = New(&Options{RoutesFile: "testdata/tenants.eskip", AllowMissingFilters: true, AllowUnknownPredicates: true, LogWriter: out, LogTimestamps: true})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Regexp(t, `(?m)^\d{4}-\d\d-\d\dT\S+ info: route settings applied$`, out.String())
+
+	_, err = New(&Options{RoutesFile: "testdata/tenants.eskip", LogWriter: out, Logger: &recordingLogger{}})
+	assert.Error(t, err)
+}
+
+func TestCaptureLogs(t *testing.T) {
+	var log bytes.Buffer
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, CaptureLogs: true, Log: &log})
+	require.NoError(t, err)
+	defer m.Close()
+
+	paths := []string{"/bar", "/search"}
+	results := make([]TestResult, 100)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = m.Test(&RequestAttributes{Path: paths[i%2], Headers: map[string]string{"Authorization": "secret"}})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		logs := result.Logs()
+		if !assert.NotEmpty(t, logs) {
+			continue
+		}
+		assert.Contains(t, logs[0], "GET "+paths[i%2]+" HTTP/1.1")
+		assert.NotContains(t, logs[0], paths[(i+1)%2], "the entries of the other calls aren't captured")
+		assert.NotContains(t, strings.Join(logs, "\n"), "secret")
+	}
+	assert.Contains(t, results[0].Logs(), "matching route: bar (testdata/routes.eskip:1)")
+	assert.Contains(t, results[1].Logs(), "matching route: none")
+	assert.Empty(t, log.String(), "the diagnostics are off")
+
+	m, err = New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Nil(t, m.Test(&RequestAttributes{Path: "/bar"}).Logs())
+}
@@ -0,0 +1,257 @@
+package matcher
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// rootLeavesNode the node of the routes without Path or PathSubtree
+const rootLeavesNode = "<no path>"
+
+// LookupStep a step of the lookup of a request in the routing tree, see
+// Options.TraceLookup: a node of the tree visited or a route of the node
+// evaluated
+type LookupStep struct {
+	// Node the visited node, eg. Path("/items/:id") or PathSubtree("/api"),
+	// the routes without a path are in the <no path> node
+	Node string `json:"node"`
+	// RouteID the route evaluated, empty for the visit of the node
+	RouteID string `json:"routeId,omitempty"`
+	// Matched the route matches the request
+	Matched bool `json:"matched,omitempty"`
+	// Rejected the first predicate of the route the request doesn't satisfy
+	Rejected *PredicateVerdict `json:"rejected,omitempty"`
+}
+
+func (s LookupStep) String() string {
+	switch {
+	case s.RouteID == "":
+		return fmt.Sprintf("visit %s", s.Node)
+	case s.Matched:
+		return fmt.Sprintf("  %s: match", s.RouteID)
+	default:
+		return fmt.Sprintf("  %s: rejected by %s", s.RouteID, s.Rejected)
+	}
+}
+
+// cloneLookup copies the steps with their verdicts
+func cloneLookup(steps []LookupStep) []LookupStep {
+	if steps == nil {
+		return nil
+	}
+	c := make([]LookupStep, len(steps))
+	for i, s := range steps {
+		c[i] = s
+		if s.Rejected != nil {
+			c[i].Rejected = &cloneVerdicts([]PredicateVerdict{*s.Rejected})[0]
+		}
+	}
+	return c
+}
+
+// lookupNode a node of the routing tree with its routes, the most specific
+// ones first
+type lookupNode struct {
+	path    string
+	subtree bool
+	routes  []*eskip.Route
+}
+
+func (n *lookupNode) String() string {
+	switch {
+	case n.path == "":
+		return rootLeavesNode
+	case n.subtree:
+		return fmt.Sprintf("%s(%q)", pathSubtreePredicate, n.path)
+	default:
+		return fmt.Sprintf("%s(%q)", pathPredicate, n.path)
+	}
+}
+
+// lookup walks the routing tree like the skipper routing does for the
+// request: the nodes matching the path, the static segments before the
+// :param wildcards before the catch-alls of the subtrees, the routes of a
+// node with more predicates first, and finally the routes without a path,
+// until a route matches. The rejected routes aren't in the tree.
+func (f *matcher) lookup(req *http.Request) []LookupStep {
+	var discarded []string
+	req = withForcedTraffic(req, &discarded)
+	path := cleanPath(req.URL.Path, f.options.IgnoreTrailingSlash)
+
+	var steps []LookupStep
+	for _, n := range lookupNodes(f.routes, f.events.rejections(), path, f.options.IgnoreTrailingSlash) {
+		steps = append(steps, LookupStep{Node: n.String()})
+		for _, r := range n.routes {
+			step := LookupStep{Node: n.String(), RouteID: r.Id, Matched: true}
+			for _, v := range leafVerdicts(r, req, path, f) {
+				if !v.Matched {
+					v := v
+					step.Matched, step.Rejected = false, &v
+					break
+				}
+			}
+			steps = append(steps, step)
+			if step.Matched {
+				return steps
+			}
+		}
+	}
+	return steps
+}
+
+// lookupNodes the nodes of the routing tree matching the path in lookup order
+func lookupNodes(routes []*eskip.Route, rejected map[string]string, path string, ignoreTrailingSlash bool) []*lookupNode {
+	paths := make(map[string]*lookupNode)
+	subtrees := make(map[string]*lookupNode)
+	root := &lookupNode{}
+	for _, r := range routes {
+		if _, ok := rejected[r.Id]; ok {
+			continue
+		}
+		nodes, p, subtree := paths, "", false
+		for _, pr := range Predicates(r) {
+			switch pr.Name {
+			case pathPredicate:
+				arg, _ := stringArg(pr, 0)
+				p = cleanPath(arg, ignoreTrailingSlash)
+			case pathSubtreePredicate:
+				arg, _ := stringArg(pr, 0)
+				nodes, p, subtree = subtrees, cleanPath(arg, true), true
+			}
+		}
+		if p == "" {
+			root.routes = append(root.routes, r)
+			continue
+		}
+		n, ok := nodes[p]
+		if !ok {
+			n = &lookupNode{path: p, subtree: subtree}
+			nodes[p] = n
+		}
+		n.routes = append(n.routes, r)
+	}
+	// like skipper the Path routes of a subtree path are in the subtree node
+	for p, n := range subtrees {
+		for _, alt := range []string{p, p + "/"} {
+			if pn, ok := paths[alt]; ok {
+				n.routes = append(n.routes, pn.routes...)
+				delete(paths, alt)
+			}
+		}
+	}
+
+	type candidate struct {
+		node *lookupNode
+		key  []int
+	}
+	var candidates []candidate
+	for _, n := range paths {
+		if _, ok := matchPath(n.path, path); ok {
+			candidates = append(candidates, candidate{n, segmentKinds(n.path)})
+		}
+	}
+	for _, n := range subtrees {
+		if _, ok := matchPathSubtree(n.path, path); ok {
+			key := segmentKinds(n.path)
+			if len(pathSegments(path)) > len(key) {
+				key = append(key, catchAllSegment)
+			}
+			candidates = append(candidates, candidate{n, key})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i].key, candidates[j].key
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return candidates[i].node.path < candidates[j].node.path
+	})
+
+	nodes := make([]*lookupNode, 0, len(candidates)+1)
+	for _, c := range candidates {
+		nodes = append(nodes, c.node)
+	}
+	if len(root.routes) > 0 {
+		nodes = append(nodes, root)
+	}
+	for _, n := range nodes {
+		sort.SliceStable(n.routes, func(i, j int) bool { return leafWeight(n.routes[i]) > leafWeight(n.routes[j]) })
+	}
+	return nodes
+}
+
+// kinds of the segments of a tree path, in the order the tree tries them
+const (
+	staticSegment = iota
+	paramSegment
+	catchAllSegment
+)
+
+// segmentKinds the kinds of the segments of a path of the tree
+func segmentKinds(path string) []int {
+	segments := pathSegments(path)
+	kinds := make([]int, len(segments))
+	for i, s := range segments {
+		switch {
+		case strings.HasPrefix(s, ":"):
+			kinds[i] = paramSegment
+		case strings.HasPrefix(s, "*"):
+			kinds[i] = catchAllSegment
+		}
+	}
+	return kinds
+}
+
+// leafWeight the number of predicates of the route but its path, the
+// routes of a node with the most are evaluated first
+func leafWeight(r *eskip.Route) int {
+	w := 0
+	for _, p := range Predicates(r) {
+		if p.Name != pathPredicate && p.Name != pathSubtreePredicate {
+			w++
+		}
+	}
+	return w
+}
+
+// leafVerdicts evaluates the predicates of the route but its path in the
+// order of the skipper routing: Method, Host, PathRegexp, the headers and
+// the others
+func leafVerdicts(r *eskip.Route, req *http.Request, path string, f *matcher) []PredicateVerdict {
+	var ps []*eskip.Predicate
+	for _, p := range Predicates(r) {
+		if p.Name != pathPredicate && p.Name != pathSubtreePredicate {
+			ps = append(ps, p)
+		}
+	}
+	sort.SliceStable(ps, func(i, j int) bool { return leafRank(ps[i].Name) < leafRank(ps[j].Name) })
+	verdicts := make([]PredicateVerdict, len(ps))
+	for i, p := range ps {
+		verdicts[i] = evaluatePredicate(p, req, path, f.predicates, f.options.IgnoreTrailingSlash)
+	}
+	return verdicts
+}
+
+func leafRank(name string) int {
+	switch name {
+	case methodPredicate:
+		return 0
+	case hostPredicate:
+		return 1
+	case pathRegexpPredicate:
+		return 2
+	case headerPredicate, headerRegexpPredicate:
+		return 3
+	default:
+		return 4
+	}
+}
@@ -0,0 +1,73 @@
+package matcher
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceLookup(t *testing.T) {
+	file := writeRoutes(t, `item: Path("/api/items/:id") && Method("PUT") -> "http://items.svc";
+itemAdmin: Path("/api/items/:id") && Method("PUT") && Header("X-Admin", "1") -> "http://admin.svc";
+latest: Path("/api/items/latest") && Host(/^internal[.]/) -> "http://latest.svc";
+api: PathSubtree("/api") -> "http://api.svc";
+other: Method("PUT") -> "http://other.svc";
+`)
+	defer os.Remove(file)
+
+	m, err := New(&Options{RoutesFile: file, TraceLookup: true})
+	require.NoError(t, err)
+	defer m.Close()
+
+	result := m.Test(&RequestAttributes{Method: "GET", Path: "/api/items/latest"})
+	require.NoError(t, result.Err())
+	require.Equal(t, "api", result.Route().Id)
+	var steps []string
+	for _, s := range result.Lookup() {
+		steps = append(steps, s.String())
+	}
+	assert.Equal(t, []string{
+		`visit Path("/api/items/latest")`,
+		`  latest: rejected by Host("^internal[.]"): no match`,
+		`visit Path("/api/items/:id")`,
+		`  itemAdmin: rejected by Method("PUT"): no match, observed "GET"`,
+		`  item: rejected by Method("PUT"): no match, observed "GET"`,
+		`visit PathSubtree("/api")`,
+		`  api: match`,
+	}, steps)
+
+	result = m.Test(&RequestAttributes{Method: "PUT", Path: "/orders"})
+	require.Equal(t, "other", result.Route().Id)
+	steps = nil
+	for _, s := range result.Lookup() {
+		steps = append(steps, s.String())
+	}
+	assert.Equal(t, []string{`visit <no path>`, `  other: match`}, steps)
+	assert.Contains(t, result.PrettyPrintLines(), "lookup:   other: match")
+}
+
+func TestTraceLookupAgreesWithRouting(t *testing.T) {
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, TraceLookup: true})
+	require.NoError(t, err)
+	defer m.Close()
+
+	for _, path := range []string{"/", "/bar", "/foo", "/foo/bar", "/missing", "/api/v1/x"} {
+		for _, method := range []string{"GET", "POST"} {
+			result := m.Test(&RequestAttributes{Method: method, Path: path})
+			steps := result.Lookup()
+			if result.Route() == nil {
+				for _, s := range steps {
+					assert.False(t, s.Matched, "%s %s", method, path)
+				}
+				continue
+			}
+			if assert.NotEmpty(t, steps, "%s %s", method, path) {
+				last := steps[len(steps)-1]
+				assert.True(t, last.Matched, "%s %s", method, path)
+				assert.Equal(t, result.Route().Id, last.RouteID, "%s %s", method, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package matcher
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// DefaultMaxLoopbackHops default maximum number of times a request is routed
+// again through <loopback> backends, see Options.FollowLoopback
+const DefaultMaxLoopbackHops = 10
+
+// LoopbackLimitError error of a result whose request still loops back
+// after the maximum number of hops, see Options.MaxLoopbackHops
+type LoopbackLimitError struct {
+	// Max maximum number of hops
+	Max int
+	// Routes ids of the routes of the hops so far, the first one first
+	Routes []string
+}
+
+func (e *LoopbackLimitError) Error() string {
+	return fmt.Sprintf("request still loops back after %d hops: %s", e.Max, strings.Join(e.Routes, " -> "))
+}
+
+// followLoopback routes the request again while the matching route has a
+// <loopback> backend, after the request filters of the route modified it.
+// Returns the result of the last hop carrying all of them, the first result
+// when its route doesn't loop back.
+func (f *matcher) followLoopback(first *testResult, at time.Time) *testResult {
+	max := f.options.MaxLoopbackHops
+	if max <= 0 {
+		max = DefaultMaxLoopbackHops
+	}
+	hops := []*testResult{first}
+	last := first
+	for last.err == nil && last.route != nil && routeBackendType(last.route) == eskip.LoopBackend {
+		if len(hops) > max {
+			last.err = &LoopbackLimitError{Max: max, Routes: hopRoutes(hops)}
+			break
+		}
+		ctx, _, _, err := last.simulateRequest()
+		if err != nil {
+			last.err = fmt.Errorf("failed to follow the loopback of %s: %v", last.route.Id, err)
+			break
+		}
+		if ctx.FServed {
+			// a filter responded, the request doesn't loop back
+			break
+		}
+		last = f.test(requestAttributes(ctx.FRequest), at)
+		hops = append(hops, last)
+	}
+	if len(hops) == 1 {
+		return first
+	}
+	last.hops = make([]TestResult, len(hops))
+	for i, hop := range hops {
+		last.hops[i] = hop
+	}
+	return last
+}
+
+// hopRoutes the ids of the routes of the hops
+func hopRoutes(hops []*testResult) []string {
+	ids := make([]string, 0, len(hops))
+	for _, hop := range hops {
+		if hop.route != nil {
+			ids = append(ids, hop.route.Id)
+		}
+	}
+	return ids
+}
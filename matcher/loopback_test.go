@@ -0,0 +1,68 @@
+package matcher
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowLoopback(t *testing.T) {
+	file := writeRoutes(t, `legacy: Path("/legacy/orders") -> setPath("/v1/orders") -> <loopback>;
+v1: Path("/v1/orders") -> setPath("/v2/orders") -> setRequestHeader("X-Migrated", "true") -> <loopback>;
+v2: Path("/v2/orders") && Header("X-Migrated", "true") -> "http://orders.svc";
+ping: Path("/ping") -> setPath("/pong") -> <loopback>;
+pong: Path("/pong") -> setPath("/ping") -> <loopback>;
+served: Path("/served") -> inlineContent("ok") -> <loopback>;
+`)
+	defer os.Remove(file)
+
+	m, err := New(&Options{RoutesFile: file, FollowLoopback: true})
+	require.NoError(t, err)
+	defer m.Close()
+
+	res := m.Test(&RequestAttributes{Method: "GET", Path: "/legacy/orders"})
+	require.NoError(t, res.Err())
+	if assert.NotNil(t, res.Route()) {
+		assert.Equal(t, "v2", res.Route().Id, "the final route is the result")
+	}
+	hops := res.Hops()
+	if assert.Len(t, hops, 3) {
+		assert.Equal(t, "legacy", hops[0].Route().Id)
+		assert.Equal(t, "/legacy/orders", hops[0].Attributes().Path)
+		assert.Equal(t, "v1", hops[1].Route().Id)
+		assert.Equal(t, "/v1/orders", hops[1].Attributes().Path)
+		assert.Equal(t, res, hops[2])
+		assert.Equal(t, "true", res.Attributes().Headers["X-Migrated"])
+	}
+	assert.Contains(t, res.PrettyPrint(), "loopback hops: legacy -> v1 -> v2")
+
+	res = m.Test(&RequestAttributes{Method: "GET", Path: "/v2/orders"})
+	assert.Nil(t, res.Hops(), "no loopback")
+
+	res = m.Test(&RequestAttributes{Method: "GET", Path: "/served"})
+	require.NoError(t, res.Err())
+	assert.Equal(t, "served", res.Route().Id, "a served request doesn't loop back")
+	assert.Nil(t, res.Hops())
+
+	m, err = New(&Options{RoutesFile: file, FollowLoopback: true, MaxLoopbackHops: 3})
+	require.NoError(t, err)
+	defer m.Close()
+	res = m.Test(&RequestAttributes{Method: "GET", Path: "/ping"})
+	require.Error(t, res.Err())
+	var limit *LoopbackLimitError
+	if assert.True(t, errors.As(res.Err(), &limit)) {
+		assert.Equal(t, []string{"ping", "pong", "ping", "pong"}, limit.Routes)
+		assert.EqualError(t, limit, "request still loops back after 3 hops: ping -> pong -> ping -> pong")
+	}
+	assert.Len(t, res.Hops(), 4)
+
+	m, err = New(&Options{RoutesFile: file})
+	require.NoError(t, err)
+	defer m.Close()
+	res = m.Test(&RequestAttributes{Method: "GET", Path: "/legacy/orders"})
+	assert.Equal(t, "legacy", res.Route().Id, "not followed by default")
+	assert.Nil(t, res.Hops())
+}
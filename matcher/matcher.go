@@ -3,67 +3,287 @@
 package matcher
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
 	"time"
 
 	"github.com/zalando/skipper/eskip"
-	"github.com/zalando/skipper/eskipfile"
 	"github.com/zalando/skipper/filters"
 	"github.com/zalando/skipper/filters/builtin"
 	"github.com/zalando/skipper/filters/filtertest"
-	"github.com/zalando/skipper/logging/loggingtest"
+	"github.com/zalando/skipper/logging"
 	"github.com/zalando/skipper/predicates/cookie"
-	"github.com/zalando/skipper/predicates/interval"
 	"github.com/zalando/skipper/predicates/query"
 	"github.com/zalando/skipper/predicates/source"
 	"github.com/zalando/skipper/predicates/traffic"
 	"github.com/zalando/skipper/routing"
 )
 
-// Matcher helps testing eskip routing logic
+// Matcher helps testing eskip routing logic.
+// A Matcher is safe for concurrent use by multiple goroutines: its methods
+// don't modify the given request attributes, which can be shared between
+// goroutines as long as nobody modifies them during the calls.
 type Matcher interface {
-	// Given request attributes test if a route matches
-	Test(attributes *RequestAttributes) TestResult
+	// Given request attributes test if a route matches, the options
+	// change this test only, eg. WithTrailingSlash, WithHost and At
+	Test(attributes *RequestAttributes, opts ...TestOption) TestResult
+	// TestAt like Test with the interval predicates, Between, Before and
+	// After, evaluated against the instant at instead of the current time,
+	// the current time when at is zero
+	TestAt(attributes *RequestAttributes, at time.Time) TestResult
+	// Explain returns up to n routes not matching the request attributes
+	// (all of them if n <= 0), the closest ones first, with the verdict
+	// of each route predicate
+	Explain(attributes *RequestAttributes, n int) []NearMiss
+	// TestManyStream tests the request attributes received from in until
+	// it's closed, sends the results to out in the same order and then
+	// closes out. With Options.LightResults the results are only valid
+	// until the next receive from out.
+	TestManyStream(in <-chan *RequestAttributes, out chan<- TestResult)
+	// Compare tests the requests against the matcher and the other one, eg.
+	// built from another routes source, and returns the requests they
+	// don't route the same way, in the requests order, classified by
+	// ResultsEquivalent. The pairs of tests run in parallel, a result with
+	// an error is a ChangeError disagreement.
+	Compare(other Matcher, reqs []*RequestAttributes) []Disagreement
+	// Ready true once the routes are applied, the error is why the last
+	// load of the routes failed, eg. a lazy initialization or a reload
+	Ready() (bool, error)
+	// RouteCount number of valid routes in the routing table in use,
+	// 0 once the matcher is closed
+	RouteCount() int
+	// Routes copy of the routes of the routing table in use, after the
+	// routing dropped the invalid ones and kept the last definition of a
+	// duplicate id, sorted by id. Nil once the matcher is closed.
+	Routes() []*eskip.Route
+	// GetRoute copy of the route with the id in the routing table in use,
+	// like the ones returned by Routes, false if there is none or once the
+	// matcher is closed
+	GetRoute(id string) (*eskip.Route, bool)
+	// GenerateRequestFor generates a request for the route with the id, see
+	// GenerateRequest, and tests it: a *GeneratedRequestError is returned
+	// with the request when it doesn't match the route, eg. another route
+	// wins. The predicates the request isn't guaranteed to satisfy are
+	// returned in any case.
+	GenerateRequestFor(id string) (*RequestAttributes, []Unsatisfied, error)
+	// Findings problems found loading the routes, eg. the routes the
+	// routing ignores because they use unknown filters or predicates, nil
+	// once the matcher is closed
+	Findings() []Finding
+	// LoadErrors the routes of the first load the routing dropped with the
+	// cause, eg. a builtin filter with invalid arguments or an invalid
+	// regexp, nil once the matcher is closed
+	LoadErrors() []RouteLoadError
+	// InitStats the timings of the phases of the initialization and of the
+	// reloads, zero once the matcher is closed
+	InitStats() InitStats
+	// DumpEffectiveRoutes writes the active route table sorted by id, in
+	// eskip or json, the routes annotated with what eskip-match changed in
+	// them, eg. the default filters and the mocked filters, followed by the
+	// routes the routing dropped
+	DumpEffectiveRoutes(w io.Writer, format Format) error
+	// Close stops the routing, Test results have ErrClosed afterwards.
+	// It can be called more than once.
+	Close() error
 }
 
+// ErrClosed error of the results of a closed Matcher
+var ErrClosed = errors.New("matcher closed")
+
+// Why New failed, the errors it returns match them with errors.Is
+var (
+	// ErrRoutesFileNotFound the routes file doesn't exist, see RoutesFileError
+	ErrRoutesFileNotFound = errors.New("routes file not found")
+	// ErrRoutesFileUnreadable the routes file can't be read, eg. permission
+	// denied or a directory, see RoutesFileError
+	ErrRoutesFileUnreadable = errors.New("routes file unreadable")
+	// ErrRoutesFileIsDirectory the routes file is a directory, it matches
+	// ErrRoutesFileUnreadable too
+	ErrRoutesFileIsDirectory = errors.New("is a directory, not a routes file")
+	// ErrRoutesFileBrokenSymlink the routes file is a symlink to a missing
+	// target, it matches ErrRoutesFileNotFound too
+	ErrRoutesFileBrokenSymlink = errors.New("is a symlink to a missing target")
+	// ErrRoutesFileEmpty the routes file has 0 bytes, eg. truncated, a file
+	// with only comments loads without routes
+	ErrRoutesFileEmpty = errors.New("is empty (0 bytes)")
+	// ErrParse the routes don't parse, see ParseError
+	ErrParse = errors.New("routes parse failure")
+	// ErrNoRoutes the routing table is empty, see NoRoutesError
+	ErrNoRoutes = errors.New("no routes")
+	// ErrInitTimeout the routing isn't ready within Options.InitTimeout, see
+	// InitTimeoutError
+	ErrInitTimeout = errors.New("routing initialization timeout")
+	// ErrNotReady the results of a lazy matcher whose initialization failed,
+	// see NotReadyError
+	ErrNotReady = errors.New("matcher not ready")
+)
+
 // TestResult result of a Matcher.Test operation
 type TestResult interface {
 	// Matching route if there was match nil if no match
 	Route() *eskip.Route
 	// The http request that was used to perform the test
 	Request() *http.Request
-	// Normalized request attributes after test
+	// Normalized copy of the tested request attributes
 	Attributes() *RequestAttributes
 	// Nice string representation
 	PrettyPrint() string
 	// Nice string representation line by line
 	PrettyPrintLines() []string
+	// Like PrettyPrintLines with the output decorated by style
+	PrettyPrintLinesWith(style Style) []string
+	// Error preventing the test, eg. ErrClosed, nil if the test ran
+	Err() error
+	// ForcedTraffic decisions of the Traffic predicates forced by
+	// Options.TrafficMode, eg. Traffic(0.1): match (always), nil with the
+	// random mode and with LightResults
+	ForcedTraffic() []string
+	// LBMember id of the member route the matching LBGroup route forwards
+	// the request to, see Options.LBDecision, empty for the other routes
+	LBMember() string
+	// SimulateRequestFilters runs the request phase of the filters of the
+	// matching route, created from the registry, on a copy of the request
+	// and returns the modified request with what each filter changed. The
+	// mocked filters change nothing, a filter serving the request stops the
+	// chain. The <loopback> and <dynamic> backends are reported but not
+	// followed: test the modified request to find the route it loops back
+	// to. Fails with ErrNoMatch without a matching route.
+	SimulateRequestFilters() (*http.Request, []FilterEffect, error)
+	// SimulateResponse the response of the matching route when its filters
+	// or its <shunt> backend respond, eg. with status and inlineContent:
+	// the request phase of the filters runs like in SimulateRequestFilters,
+	// then the response phase in reverse order, on the 404 of the shunt
+	// backend when no filter served the request. For the other routes the
+	// result tells where the request would be proxied to.
+	SimulateResponse() (*SimulatedResponse, error)
+	// Trace the verdicts of the predicates of the matching route with the
+	// request values they observed, see Options.Trace, nil without a
+	// matching route, without Options.Trace and with LightResults
+	Trace() []PredicateVerdict
+	// Hops the results of the routing passes of a request following the
+	// <loopback> backends, the first one first and the result itself last,
+	// see Options.FollowLoopback, nil when the request doesn't loop back
+	Hops() []TestResult
+	// Filters the effective filter chain of the matching route, the route
+	// filters with the default ones marked, see
+	// Options.DefaultFiltersPrepend, nil without a matching route
+	Filters() []ChainFilter
+	// Lookup the steps of the lookup of the request in the routing tree up
+	// to the matching route, see Options.TraceLookup, nil without it
+	Lookup() []LookupStep
+	// Logs the diagnostics of the test, see Options.CaptureLogs, nil
+	// without it
+	Logs() []string
+	// PathParams copy of the path parameters of the matching route, eg. id
+	// of Path("/orders/:id"), nil without a matching route
+	PathParams() map[string]string
+	// MustRoute the matching route, it panics with the request line when
+	// no route matches and with the error when the request couldn't be
+	// tested
+	MustRoute() *eskip.Route
 }
 
 // RequestAttributes represents the http request attributes to test
 type RequestAttributes struct {
-	Method  string
-	Path    string
-	Headers map[string]string
+	Method  string            `json:"method" yaml:"method"`
+	Path    string            `json:"path" yaml:"path"`
+	Host    string            `json:"host,omitempty" yaml:"host,omitempty"`
+	Query   map[string]string `json:"query,omitempty" yaml:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
 }
 
 type matcher struct {
 	routing *routing.Routing
+	// events tracks the routes loads of the routing
+	events *routingEvents
+	// routes loaded from the data clients
+	routes []*eskip.Route
+	// predicates available to the routes by name
+	predicates map[string]routing.PredicateSpec
+	// options used to create the matcher
+	options *Options
+	// locations of the route definitions by route id
+	locations map[string]Location
+	// diag writes the diagnostics enabled by the options
+	diag *diagnostics
+	// findings problems found loading the routes
+	findings []Finding
+	// loadErrors routes dropped because their filters can't be created
+	loadErrors []RouteLoadError
+	// registry filters available to the routes
+	registry filters.Registry
+	// defaults filters added to every route, nil without
+	defaults *defaultFilters
+	// decisions writes the decision log, nil without
+	decisions *decisionLog
+	// stats timings of the initialization
+	stats InitStats
+	// lb default decisions of the load balancer groups
+	lb roundRobin
+	// variant the matcher of the other trailing slash mode, created by the
+	// first test needing it, see WithTrailingSlash
+	variant     *matcher
+	variantErr  error
+	variantOnce sync.Once
+	// closed set to 1 by Close
+	closed    int32
+	closeOnce sync.Once
 }
 
 type testResult struct {
 	route      *eskip.Route
 	req        *http.Request
 	attributes *RequestAttributes
+	err        error
+	// forcedTraffic decisions of the Traffic predicates forced by the mode
+	forcedTraffic []string
+	// lbMember member route of a matching LBGroup route
+	lbMember string
+	// params path parameters of the matching route
+	params map[string]string
+	// registry filters the matching route was created with
+	registry filters.Registry
+	// defaults filters added to every route
+	defaults *defaultFilters
+	// trace verdicts of the predicates of the matching route
+	trace []PredicateVerdict
+	// hops results of the loopback hops, the result itself last
+	hops []TestResult
+	// lookup steps of the lookup in the routing tree
+	lookup []LookupStep
+	// logs diagnostics of the test
+	logs []string
 }
 
 func (t *testResult) Route() *eskip.Route {
 	return t.route
 }
 
+func (t *testResult) MustRoute() *eskip.Route {
+	if t.err != nil {
+		panic(fmt.Sprintf("eskip-match: the request couldn't be tested: %v", t.err))
+	}
+	if t.route == nil {
+		line := "request"
+		if t.attributes != nil {
+			line = strings.SplitN(RawRequest(t.attributes, nil), "\n", 2)[0]
+		}
+		panic(fmt.Sprintf("eskip-match: no route matches %s", line))
+	}
+	return t.route
+}
+
 func (t *testResult) Request() *http.Request {
 	return t.req
 }
@@ -72,6 +292,45 @@ func (t *testResult) Attributes() *RequestAttributes {
 	return t.attributes
 }
 
+func (t *testResult) Err() error {
+	return t.err
+}
+
+func (t *testResult) ForcedTraffic() []string {
+	return t.forcedTraffic
+}
+
+func (t *testResult) LBMember() string {
+	return t.lbMember
+}
+
+func (t *testResult) Trace() []PredicateVerdict {
+	return t.trace
+}
+
+func (t *testResult) Hops() []TestResult {
+	return t.hops
+}
+
+func (t *testResult) Logs() []string {
+	return t.logs
+}
+
+func (t *testResult) Lookup() []LookupStep {
+	return t.lookup
+}
+
+func (t *testResult) PathParams() map[string]string {
+	return cloneMap(t.params)
+}
+
+func (t *testResult) Filters() []ChainFilter {
+	if t.route == nil {
+		return nil
+	}
+	return t.defaults.chain(t.route)
+}
+
 // PrettyPrint return a nice string output representing the result
 func (t *testResult) PrettyPrint() string {
 	out := t.PrettyPrintLines()
@@ -81,32 +340,84 @@ func (t *testResult) PrettyPrint() string {
 // PrettyPrintLines like PrettyPrint but return a list of strings
 // representing each line that forms the final output
 func (t *testResult) PrettyPrintLines() []string {
+	return t.PrettyPrintLinesWith(PlainStyle)
+}
+
+// PrettyPrintLinesWith like PrettyPrintLines, the matching route id
+// and the route predicates are decorated by style
+func (t *testResult) PrettyPrintLinesWith(style Style) []string {
 	attrs := t.Attributes()
 	out := []string{}
 	out = append(out, fmt.Sprintf("request: %s %s", attrs.Method, attrs.Path))
+	if attrs.Host != "" {
+		out = append(out, fmt.Sprintf("request host: %s", attrs.Host))
+	}
+	if len(attrs.Query) > 0 {
+		out = append(out, fmt.Sprintf("request query: %s", prettyPrintPairs(attrs.Query)))
+	}
 	if len(attrs.Headers) > 0 {
-		pairs := make([]string, 0, len(attrs.Headers))
-		for key, value := range attrs.Headers {
-			pairs = append(pairs, fmt.Sprintf(`"%s"="%s"`, key, value))
-		}
-		out = append(out, fmt.Sprintf("request headers: %s", strings.Join(pairs, ", ")))
+		out = append(out, fmt.Sprintf("request headers: %s", prettyPrintPairs(attrs.Headers)))
 	}
 
 	route := t.Route()
 	if route != nil {
-		out = append(out, fmt.Sprintf("matching route id: %s", route.Id))
-		out = append(out, fmt.Sprintf("matching route:\n```%s```", t.prettyPrintRoute()))
+		out = append(out, fmt.Sprintf("matching route id: %s", style.DecorateMatch(route.Id)))
+		out = append(out, fmt.Sprintf("matching route:\n```%s: %s\n```", route.Id, prettyPrintRoute(route, style)))
+	}
+	for _, decision := range t.ForcedTraffic() {
+		out = append(out, fmt.Sprintf("forced traffic: %s", decision))
+	}
+	if member := t.LBMember(); member != "" {
+		out = append(out, fmt.Sprintf("load balancer member: %s", style.DecorateMatch(member)))
+	}
+	for _, v := range t.Trace() {
+		out = append(out, fmt.Sprintf("trace: %s", v))
+	}
+	for _, step := range t.Lookup() {
+		out = append(out, fmt.Sprintf("lookup: %s", step))
+	}
+	if hops := t.Hops(); len(hops) > 0 {
+		ids := make([]string, len(hops))
+		for i, hop := range hops {
+			ids[i] = "<no match>"
+			if hop.Route() != nil {
+				ids[i] = hop.Route().Id
+			}
+		}
+		out = append(out, fmt.Sprintf("loopback hops: %s", strings.Join(ids, " -> ")))
+	}
+	if t.defaults != nil && route != nil {
+		chain := t.Filters()
+		names := make([]string, len(chain))
+		for i, f := range chain {
+			names[i] = filterString(f.Filter)
+			if f.Default {
+				names[i] += " [default]"
+			}
+		}
+		out = append(out, fmt.Sprintf("effective filters: %s", strings.Join(names, " -> ")))
 	}
 	return out
 }
 
-// prettyPrintRoute return a nice string representation of the resulting route if any
-func (t *testResult) prettyPrintRoute() string {
-	def := t.route.Print(eskip.PrettyPrintInfo{
-		Pretty:    true,
-		IndentStr: "  ",
-	})
-	return fmt.Sprintf("%s: %s\n", t.route.Id, def)
+// prettyPrintPairs return a "key"="value" list sorted by key
+func prettyPrintPairs(m map[string]string) string {
+	keys := sortedKeys(m)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf(`"%s"="%s"`, key, m[key]))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// sortedKeys returns the keys of m sorted
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // Options when creating a NewMatcher
@@ -114,106 +425,858 @@ type Options struct {
 	// Path to a .eskip file defining routes
 	RoutesFile string
 
-	// CustomPredicates list of of custom Skipper predicate specs
+	// Routes eskip document of the routes, instead of the RoutesFile, eg.
+	// the routes of a test. It can't be set together with RoutesFile or
+	// Kubernetes, Cached doesn't share the matcher.
+	Routes string
+
+	// Kubernetes loads the routes of the Kubernetes ingresses instead of
+	// the RoutesFile, from an API server or a manifests dump, see
+	// KubernetesOptions. Lazy needs a RoutesFile, Cached doesn't share the
+	// matcher.
+	Kubernetes *KubernetesOptions
+
+	// CustomPredicates list of of custom Skipper predicate specs, a custom
+	// predicate replaces the bundled one with the same name, eg. Traffic
 	CustomPredicates []routing.PredicateSpec
 
+	// DisableBundledPredicates registers none of the predicates bundled with
+	// the matcher (Source, SourceFromLast, Cookie, QueryParam, Traffic,
+	// Between, Before, After, True, False, Weight, LBGroup and LBMember),
+	// only the custom ones.
+	// TrafficMode and TestAt have then no effect.
+	DisableBundledPredicates bool
+
 	// CustomFilters lister of custom Skipper filter specs
 	CustomFilters []filters.Spec
 
+	// FilterRegistry filters registered instead of the builtin Skipper
+	// ones, eg. a registry shared by the test helpers, copied and not
+	// modified. DisableBuiltinFilters and BuiltinFilterAllowlist apply to
+	// it, then the mocked and the custom filters are registered on top, the
+	// custom ones win.
+	FilterRegistry filters.Registry
+
 	// MockFilters list of custom Skipper filters to mock by name
 	MockFilters []string
 
+	// PluginDirs directories searched for skipper plugins, the .so files,
+	// like the skipper -plugindir flag: their filters and predicates are
+	// registered in addition to the custom ones. New fails with a
+	// *PluginError naming the plugin file which can't be loaded.
+	PluginDirs []string
+
+	// Plugins multitype plugins of the PluginDirs to load with arguments,
+	// the plugin name, its file name without .so, followed by the arguments,
+	// like the skipper -plugin flag. The other plugins are loaded without
+	// arguments.
+	Plugins [][]string
+
+	// AutoMockFilters mocks every filter of the loaded routes missing in the
+	// registry, the mocked filters are listed in the verbose output. The
+	// routes then never fail with a *MissingFiltersError, AllowMissingFilters
+	// has no effect. The filters of the routes reloaded later aren't mocked.
+	AutoMockFilters bool
+
+	// DisableBuiltinFilters registers none of the builtin Skipper filters,
+	// only the custom and the mocked ones
+	DisableBuiltinFilters bool
+
+	// BuiltinFilterAllowlist names of the builtin Skipper filters to
+	// register, all of them when empty
+	BuiltinFilterAllowlist []string
+
 	// IgnoreTrailingSlash Skipper option
 	IgnoreTrailingSlash bool
 
-	// Verbose verbose debug output
+	// Verbose verbose debug output, same as Verbosity VerbosityDebug
 	Verbose bool
+
+	// Verbosity level of the diagnostics, see VerbositySilent,
+	// VerbosityWarn, VerbosityInfo, VerbosityDebug and VerbosityTrace
+	Verbosity int
+
+	// Log writer of the diagnostics, os.Stderr when nil
+	Log io.Writer
+
+	// TB writes the diagnostics, the skipper routing log lines included
+	// without Logger and LogWriter, with its Logf: they are shown with
+	// the failing tests and go test -v only. New closes the matcher when
+	// the test completes. It can't be set together with Log.
+	TB testing.TB
+
+	// Logger receives the skipper routing log lines at all levels, eg. the
+	// routes the routing rejected, instead of the diagnostics: Verbose and
+	// Verbosity don't apply to them
+	Logger logging.Logger
+
+	// LogWriter receives the skipper routing log lines prefixed by their
+	// level, eg. "info: route settings applied", instead of the
+	// diagnostics, the debug ones only with Verbose or VerbosityDebug. It
+	// can't be set together with Logger. It's written from the routing
+	// goroutine, even shortly after Close, so it must be safe for
+	// concurrent use.
+	LogWriter io.Writer
+
+	// LogTimestamps prefixes the LogWriter lines with their time
+	LogTimestamps bool
+
+	// DecisionLog receives a line for every tested request with the
+	// normalized request, the redacted headers hidden, the matching route
+	// id, the duration and the normalizations applied, see DecisionEntry.
+	// The lines are written from another goroutine, the decisions are
+	// dropped when the writer doesn't keep up and the next line notes how
+	// many. Close writes the remaining ones.
+	DecisionLog io.Writer
+
+	// DecisionLogFormat format of the DecisionLog lines, DecisionLogJSON
+	// (the default) or DecisionLogText
+	DecisionLogFormat string
+
+	// RedactedHeaders headers redacted in the diagnostics
+	// in addition to DefaultRedactedHeaders
+	RedactedHeaders []string
+
+	// InitTimeout maximum time to wait for the routes source to be loaded
+	// and the routes to be applied, DefaultInitTimeout when 0
+	InitTimeout time.Duration
+
+	// AllowMissingFilters creates the matcher even if routes use filters
+	// missing in the registry, by default New fails with a
+	// *MissingFiltersError. The routing ignores those routes, they are
+	// reported by Findings.
+	AllowMissingFilters bool
+
+	// AllowUnknownPredicates creates the matcher even if routes use
+	// predicates without a spec, by default New fails with an
+	// *UnknownPredicatesError. The routing ignores those routes, they are
+	// reported by Findings.
+	AllowUnknownPredicates bool
+
+	// AllowFilterOverride creates the matcher even if custom or mocked
+	// filters have the name of builtin ones, or custom filters the name of
+	// mocked ones, on purpose: the custom filters then win over the mocked
+	// ones, which win over the builtin ones. By default New fails with a
+	// *FilterOverrideError.
+	AllowFilterOverride bool
+
+	// AllowInvalidHeaders tests the requests whose header names aren't
+	// tokens or whose values have control characters, eg. a bare LF, which
+	// can't be sent, to test deliberately malformed requests. By default
+	// their results have an *InvalidHeaderError.
+	AllowInvalidHeaders bool
+
+	// DisablePanicRecovery lets a panic while matching a request, eg. of a
+	// custom predicate, crash the caller. By default its result has a
+	// *PanicError and the next requests are tested.
+	DisablePanicRecovery bool
+
+	// Lazy defers loading the routes and creating the routing to the first
+	// Test or Explain call, the initialization error is the error of the
+	// results. New only checks the routes file exists and isn't a directory
+	// or empty.
+	Lazy bool
+
+	// TrafficMode how the Traffic predicates decide, TrafficRandom by
+	// default: TrafficAlways and TrafficNever pin which side of a traffic
+	// split a request lands on, TrafficSeeded makes the random decisions
+	// repeatable. The forced decisions are noted by TestResult.ForcedTraffic.
+	TrafficMode TrafficMode
+
+	// Trace makes the results carry the verdicts of the predicates of the
+	// matching route with the request values they observed and the
+	// submatches of the regexps, see TestResult.Trace, to check a route
+	// matches for the right reason. The predicates are evaluated twice.
+	Trace bool
+
+	// CaptureLogs makes every Test call keep the diagnostics of its request,
+	// see TestResult.Logs, as written at VerbosityDebug whatever the
+	// verbosity: the tested request, the matching route and the near
+	// misses. The skipper routing logs nothing while matching, the lines of
+	// the reloads belong to no call and aren't captured. It isn't applied
+	// to LightResults.
+	CaptureLogs bool
+
+	// TraceLookup makes the results carry the steps of the lookup of the
+	// request in the routing tree, see TestResult.Lookup: the nodes visited
+	// and the routes evaluated, with the predicate rejecting them, in the
+	// order of the skipper routing. The lookup is reproduced after the
+	// routing matched, it isn't applied to LightResults.
+	TraceLookup bool
+
+	// FollowLoopback routes the request again when the matching route has a
+	// <loopback> backend, after its request filters modified the request
+	// like in TestResult.SimulateRequestFilters, until a route with another
+	// backend or none matches: the result is the one of the last route,
+	// TestResult.Hops returns all of them. Not applied to LightResults.
+	FollowLoopback bool
+
+	// MaxLoopbackHops maximum number of times FollowLoopback routes a
+	// request again, DefaultMaxLoopbackHops when 0, the result of a request
+	// looping back once more has a *LoopbackLimitError
+	MaxLoopbackHops int
+
+	// LBDecision chooses the member of a load balancer group a request
+	// matching its LBGroup route is forwarded to, the index of members, the
+	// ids of the LBMember routes by index. The members are chosen in turn
+	// when nil, like the lbDecide filter. The member route is noted by
+	// TestResult.LBMember.
+	LBDecision func(group string, members []string) int
+
+	// DefaultFiltersPrepend filters added before the filters of every
+	// route, like the skipper -default-filters-prepend flag, eg.
+	// `setRequestHeader("X-Env", "test") -> compress()`. TestResult.Filters
+	// marks them.
+	DefaultFiltersPrepend string
+
+	// DefaultFiltersAppend filters added after the filters of every route,
+	// like the skipper -default-filters-append flag
+	DefaultFiltersAppend string
+
+	// Hooks functions called to report the tests and the reloads
+	Hooks Hooks
+
+	// LightResults makes TestManyStream reuse its results and requests:
+	// a result is only valid until the next receive and its Request is nil
+	LightResults bool
+
+	// Strict makes New fail with a *NoRoutesError when the routing table is
+	// empty, eg. an empty routes file or one whose routes are all invalid,
+	// instead of returning a matcher which never matches, and with a
+	// *RouteLoadErrors when predicates can't be created with their
+	// arguments, eg. Cookie("session") without the value, instead of
+	// dropping their routes
+	Strict bool
+
+	// FailOnLoadErrors makes New fail with a *RouteLoadErrors when the
+	// routing drops routes, listing all of them, see Matcher.LoadErrors,
+	// instead of warning in the verbose output. The routes with missing
+	// filters or unknown predicates are listed too instead of failing
+	// before the others are checked.
+	FailOnLoadErrors bool
+
+	// FailOnNameCollisions makes New fail with a *NameCollisionsError when
+	// custom or mocked filters, with AllowFilterOverride, or predicates have
+	// the name of builtin, bundled or other custom ones, instead of
+	// reporting them in Findings
+	// (rule name-collision) and applying the precedence: the custom filters
+	// win over the mocked ones, which win over the builtin ones, the custom
+	// predicates over the bundled ones, and among the custom ones the last
+	FailOnNameCollisions bool
+
+	// Rules the lint rules Validate checks and the severity of their findings
+	Rules RuleOptions
+
+	// Graph how ExportDOT renders the routing tree
+	Graph GraphOptions
+
+	// Generate how GenerateRequestFor, GenerateAll and the features built
+	// on it generate the requests for the routes
+	Generate GenerateOptions
+
+	// OnRouteDropped called for every route the routing drops instead of
+	// applying it, eg. a filter with invalid arguments or an invalid
+	// regexp, with the reason logged by the routing. Called in the routing
+	// goroutine on every load, also for the routes dropped by a reload.
+	OnRouteDropped func(routeID string, reason error)
+
+	// OnLoadProgress called periodically while the routes file is read and
+	// parsed with the bytes parsed so far and the file size, eg. to render a
+	// progress indicator for very large files
+	OnLoadProgress func(parsed int, total int64)
 }
 
-// New create a new Matcher
+// DefaultInitTimeout default maximum time New waits for the routes to be applied
+const DefaultInitTimeout = 5 * time.Second
+
+// pollTimeout interval between the polls of the data clients for route updates
+var pollTimeout = 3 * time.Second
+
+// newDataClients creates the data clients of a routes source, replaced by tests
+var newDataClients = createDataClients
+
+// New create a new Matcher, it fails if the routes source isn't loaded
+// and the routes applied within Options.InitTimeout. With Options.Lazy
+// it only checks the routes file, see Options.Lazy.
 func New(o *Options) (Matcher, error) {
-	// creates data clients
-	dataClients, err := createDataClients(o.RoutesFile)
+	if err := checkRoutesSource(o); err != nil {
+		return nil, err
+	}
+	var m Matcher
+	if o.Lazy {
+		lazy, err := newLazyMatcher(o)
+		if err != nil {
+			return nil, err
+		}
+		m = lazy
+	} else {
+		f, err := newMatcher(o)
+		if err != nil {
+			return nil, err
+		}
+		m = f
+	}
+	if o.TB != nil {
+		o.TB.Cleanup(func() { m.Close() })
+	}
+	return m, nil
+}
+
+// MustNew like New, it panics with the error when the matcher can't be
+// created, eg. in the setup of the tests:
+//
+//	var m = matcher.MustNew(&matcher.Options{RoutesFile: "routes.eskip"})
+func MustNew(o *Options) Matcher {
+	m, err := New(o)
+	if err != nil {
+		panic(fmt.Sprintf("eskip-match: the matcher can't be created: %v", err))
+	}
+	return m
+}
+
+// newMatcher loads the routes and creates the routing
+func newMatcher(o *Options) (*matcher, error) {
+	o, err := withPlugins(o)
+	if err != nil {
+		return nil, err
+	}
+	timeout := o.InitTimeout
+	if timeout == 0 {
+		timeout = DefaultInitTimeout
+	}
+	start := time.Now()
+	deadline := start.Add(timeout)
+	if o.Logger != nil && o.LogWriter != nil {
+		return nil, errors.New("Logger and LogWriter can't be set together")
+	}
+	if o.Log != nil && o.TB != nil {
+		return nil, errors.New("Log and TB can't be set together")
+	}
+	if err := checkDecisionLogFormat(o.DecisionLogFormat); err != nil {
+		return nil, err
+	}
+	defaults, err := parseDefaultFilters(o)
+	if err != nil {
+		return nil, err
+	}
+	if !o.AllowFilterOverride {
+		if err := filterOverrideError(o); err != nil {
+			return nil, err
+		}
+	}
+
+	var stats InitStats
+	loadStart := time.Now()
+	dataClients, routes, err := loadDataClients(o, timeout)
+	if err != nil {
+		return nil, err
+	}
+	stats.Routes = len(routes)
+	stats.Read, stats.Parse = loadTimings(dataClients, time.Since(loadStart))
+
+	registryStart := time.Now()
+	registry, err := filterRegistry(o)
+	if err != nil {
+		return nil, err
+	}
+	stats.Registry = time.Since(registryStart)
+	locations := map[string]Location{}
+	if client, ok := singleFile(dataClients); ok {
+		locations = client.locations
+	} else if doc, err := ioutil.ReadFile(o.RoutesFile); err == nil {
+		locations = RouteLocations(o.RoutesFile, doc)
+	}
+	dataClients = defaults.wrap(dataClients)
+	routes = defaults.apply(routes)
+	diag := newDiagnostics(o)
+	if !o.DisableBundledPredicates {
+		for _, name := range overriddenPredicates(o.CustomPredicates) {
+			diag.printf(VerbosityInfo, "custom predicate %s replaces the bundled one", name)
+		}
+	}
+	if o.AutoMockFilters {
+		if mocked := autoMockFilters(routes, registry); len(mocked) > 0 {
+			diag.printf(VerbosityInfo, "auto-mocked filters: %s", strings.Join(mocked, ", "))
+		}
+	}
+	// with FailOnLoadErrors all the dropped routes are reported together
+	if err := missingFilters(routes, registry); err != nil && !o.AllowMissingFilters && !o.FailOnLoadErrors {
+		return nil, err
+	}
+	predicates := mapPredicateSpecs(trafficModePredicates(o))
+	if err := unknownPredicatesError(routes, predicates, locations); err != nil && !o.AllowUnknownPredicates && !o.FailOnLoadErrors {
+		return nil, err
+	}
+	// with FailOnLoadErrors they're reported with the other dropped routes
+	if errs := predicateArgsErrors(routes, predicates, locations); len(errs) > 0 && o.Strict && !o.FailOnLoadErrors {
+		return nil, &RouteLoadErrors{Errors: errs}
+	}
+	collisions := nameCollisions(o)
+	if len(collisions) > 0 && o.FailOnNameCollisions {
+		return nil, &NameCollisionsError{Findings: collisions}
+	}
+	findings := append(collisions, loadFindings(routes, registry, predicates, locations)...)
 
+	buildStart := time.Now()
+	routing, events, err := createRouting(dataClients, registry, o, diag, deadline, timeout)
 	if err != nil {
 		return nil, err
 	}
+	stats.Build = time.Since(buildStart)
+	if o.Strict && appliedRoutes(routing) == 0 {
+		routing.Close()
+		return nil, &NoRoutesError{RoutesFile: o.RoutesFile, Loaded: len(routes), Reasons: events.rejections()}
+	}
+	loadErrors := routeLoadErrors(routes, registry, predicates, events.rejections(), locations)
+	if len(loadErrors) > 0 && o.FailOnLoadErrors {
+		routing.Close()
+		return nil, &RouteLoadErrors{Errors: loadErrors}
+	}
+	for _, e := range loadErrors {
+		diag.printf(VerbosityWarn, "warning: dropped %s", e)
+	}
+
+	m := &matcher{
+		routing:    routing,
+		events:     events,
+		routes:     routes,
+		predicates: predicates,
+		options:    o,
+		locations:  locations,
+		diag:       diag,
+		findings:   findings,
+		loadErrors: loadErrors,
+		registry:   registry,
+		defaults:   defaults,
+		decisions:  newDecisionLog(o),
+	}
+	m.stats = stats
+	m.stats.Total = time.Since(start)
+	diag.printf(VerbosityInfo, "%s", m.stats)
+	for _, f := range m.findings {
+		diag.printf(VerbosityWarn, "load finding: %s", f)
+	}
+	diag.routeTable(routes, m.locations)
+	diag.effectiveRoutes(m)
+	return m, nil
+}
+
+// Ready see Matcher.Ready, a closed matcher isn't ready
+func (f *matcher) Ready() (bool, error) {
+	if f.isClosed() {
+		return false, ErrClosed
+	}
+	return true, f.events.err()
+}
+
+// RouteCount see Matcher.RouteCount
+func (f *matcher) RouteCount() int {
+	if f.isClosed() {
+		return 0
+	}
+	return appliedRoutes(f.routing)
+}
+
+// Routes see Matcher.Routes
+func (f *matcher) Routes() []*eskip.Route {
+	if f.isClosed() {
+		return nil
+	}
+	return f.events.routes()
+}
+
+// GetRoute see Matcher.GetRoute
+func (f *matcher) GetRoute(id string) (*eskip.Route, bool) {
+	if f.isClosed() {
+		return nil, false
+	}
+	return f.events.route(id)
+}
 
-	routing := createRouting(dataClients, o)
+// Findings see Matcher.Findings
+func (f *matcher) Findings() []Finding {
+	if f.isClosed() {
+		return nil
+	}
+	return f.findings
+}
 
-	return &matcher{
-		routing,
-	}, nil
+// LoadErrors see Matcher.LoadErrors
+func (f *matcher) LoadErrors() []RouteLoadError {
+	if f.isClosed() {
+		return nil
+	}
+	return f.loadErrors
 }
 
 // Test check if incoming request attributes are matching any eskip route
 // Return is nil if there isn't a match
-func (f *matcher) Test(attributes *RequestAttributes) TestResult {
-	req, _ := createHTTPRequest(attributes)
+func (f *matcher) Test(attributes *RequestAttributes, opts ...TestOption) TestResult {
+	if f.options.TB != nil {
+		f.options.TB.Helper()
+	}
+	if len(opts) > 0 {
+		return f.testWith(attributes, newTestOptions(opts))
+	}
+	return f.TestAt(attributes, time.Time{})
+}
 
-	// find a match
-	route, _ := f.routing.Route(req)
-	var eroute eskip.Route
+// TestAt see Matcher.TestAt
+func (f *matcher) TestAt(attributes *RequestAttributes, at time.Time) TestResult {
+	if f.options.TB != nil {
+		// Options.TB reports the diagnostics at the line of the test
+		f.options.TB.Helper()
+	}
+	return f.testOn(f, attributes, at)
+}
 
-	if route != nil {
-		eroute = route.Route
+// testOn tests the attributes at the instant on the routing of routed, f
+// or its trailing slash variant, with the diagnostics, the hooks and the
+// decision log of f
+func (f *matcher) testOn(routed *matcher, attributes *RequestAttributes, at time.Time) TestResult {
+	if f.options.TB != nil {
+		f.options.TB.Helper()
+	}
+	var start time.Time
+	if f.options.Hooks.OnTest != nil || f.decisions != nil {
+		start = time.Now()
+	}
+	original := attributes
+	attributes = normalizeAttributes(attributes)
+	var result *testResult
+	if f.isClosed() {
+		result = &testResult{attributes: attributes, err: ErrClosed}
+	} else {
+		result = routed.test(attributes, at)
+		if f.options.FollowLoopback {
+			result = routed.followLoopback(result, at)
+		}
+		f.diag.test(f, attributes, result.route)
+		if f.options.CaptureLogs {
+			result.logs = f.diag.capture(f, attributes, result.route)
+		}
+	}
+	if result.err != nil {
+		result.err = newMatchError(attributes, f.RouteCount(), routesSource(f.options), result.err)
 	}
+	if f.options.Hooks.OnTest != nil {
+		f.onTest(result, time.Since(start))
+	}
+	if f.decisions != nil {
+		f.decisions.record(original, result, start)
+	}
+	return result
+}
 
-	if eroute.Id == "" {
-		return &testResult{
-			nil,
-			req,
-			attributes,
+// test matches the attributes at the instant, they are owned by the result
+func (f *matcher) test(attributes *RequestAttributes, at time.Time) *testResult {
+	result := &testResult{attributes: attributes, registry: f.registry, defaults: f.defaults}
+	if !f.options.AllowInvalidHeaders {
+		if err := validateHeaders(attributes.Headers); err != nil {
+			result.err = err
+			return result
 		}
 	}
+	req, err := createHTTPRequest(attributes)
+	if err != nil {
+		// attributes can't be represented as an http request
+		// so nothing can match
+		result.err = err
+		return result
+	}
+	result.req = req
+	if f.options.TrafficMode.kind != trafficRandom {
+		req = withForcedTraffic(req, &result.forcedTraffic)
+	}
+	if !at.IsZero() {
+		req = withTestTime(req, at)
+	}
 
-	result := &testResult{
-		&eroute,
-		req,
-		attributes,
+	// find a match
+	route, params, err := f.matchRoute(req)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	if route != nil && route.Id != "" {
+		eroute := route.Route
+		result.route = &eroute
+		result.params = params
+		result.lbMember = f.decideLB(req, result.route)
+		if f.options.Trace {
+			result.trace = f.trace(req, result.route)
+		}
+	}
+	if f.options.TraceLookup {
+		result.lookup = f.lookup(req)
 	}
 
-	// transform literal to pointer to use eskip.Route methods
 	return result
 }
 
+// trace evaluates again the predicates of the matching route, the random
+// ones, eg. Traffic, can then decide differently. The decisions of the
+// traffic mode aren't recorded twice.
+func (f *matcher) trace(req *http.Request, route *eskip.Route) []PredicateVerdict {
+	var discarded []string
+	req = withForcedTraffic(req, &discarded)
+	return evaluateRoute(route, req, cleanPath(req.URL.Path, f.options.IgnoreTrailingSlash), f.predicates, f.options.IgnoreTrailingSlash)
+}
+
+// Close stops the routing and its data clients polling
+func (f *matcher) Close() error {
+	f.closeOnce.Do(func() {
+		atomic.StoreInt32(&f.closed, 1)
+		f.routing.Close()
+		// waits for a variant being created
+		f.variantOnce.Do(func() {})
+		if f.variant != nil {
+			f.variant.Close()
+		}
+		if f.decisions != nil {
+			f.decisions.close()
+		}
+	})
+	return nil
+}
+
+func (f *matcher) isClosed() bool {
+	return atomic.LoadInt32(&f.closed) == 1
+}
+
+// normalizeAttributes returns a copy of the attributes with the
+// defaults applied, the maps are shared with the original ones
+func normalizeAttributes(attributes *RequestAttributes) *RequestAttributes {
+	a := copyAttributes(attributes)
+	normalize(a)
+	return a
+}
+
+// normalize applies the defaults to the attributes
+func normalize(a *RequestAttributes) {
+	if strings.HasPrefix(a.Path, "/") == false {
+		a.Path = "/" + a.Path
+	}
+	if a.Method == "" {
+		a.Method = "GET"
+	}
+}
+
+// createHTTPRequest creates the request to route from the normalized attributes,
+// the given attributes aren't modified
 func createHTTPRequest(attributes *RequestAttributes) (*http.Request, error) {
-	if strings.HasPrefix(attributes.Path, "/") == false {
-		attributes.Path = "/" + attributes.Path
+	req := &http.Request{}
+	if err := fillHTTPRequest(req, attributes); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// fillHTTPRequest sets req to the request to route from the normalized
+// attributes, the URL and the Header of req are reused when not nil
+func fillHTTPRequest(req *http.Request, attributes *RequestAttributes) error {
+	path := attributes.Path
+	if strings.HasPrefix(path, "/") == false {
+		path = "/" + path
 	}
 
-	u, _ := url.Parse("http://localhost" + attributes.Path)
-	if attributes.Method == "" {
-		attributes.Method = "GET"
+	host := attributes.Host
+	if host == "" {
+		host = "localhost"
+	}
+
+	u := req.URL
+	if isSimpleHost(host) && isSimplePath(path) {
+		// same as parsing, without the round-trip
+		if u == nil {
+			u = &url.URL{}
+		}
+		*u = url.URL{Scheme: "http", Host: host, Path: path}
+	} else {
+		var err error
+		u, err = url.Parse("http://" + host + path)
+		if err != nil {
+			return err
+		}
+	}
+	if len(attributes.Query) > 0 {
+		q := make(url.Values, len(attributes.Query))
+		if u.RawQuery != "" {
+			// query embedded in the path
+			q = u.Query()
+		}
+		for key, value := range attributes.Query {
+			q.Set(key, value)
+		}
+		u.RawQuery = q.Encode()
 	}
 
-	httpReq := &http.Request{
-		Method: strings.ToUpper(attributes.Method),
-		URL:    u,
-		Header: make(http.Header),
+	header := req.Header
+	if header == nil {
+		header = make(http.Header, len(attributes.Headers))
+	}
+	for key := range header {
+		delete(header, key)
 	}
 	for key, value := range attributes.Headers {
-		httpReq.Header.Set(key, value)
+		header.Set(key, value)
 	}
 
-	return httpReq, nil
+	req.Method = canonicalMethod(attributes.Method)
+	req.URL = u
+	req.Host = attributes.Host
+	req.Header = header
+	return nil
 }
 
-func createRouting(dataClients []routing.DataClient, o *Options) *routing.Routing {
-	l := loggingtest.New()
+// methods canonical method strings, reused to avoid the upper case conversions
+var methods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
 
-	if o.Verbose == true {
-		l.Unmute() // unmute skipper logging
+// canonicalMethod returns the method in upper case, GET when empty
+func canonicalMethod(method string) string {
+	if method == "" {
+		return http.MethodGet
 	}
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return m
+		}
+	}
+	return strings.ToUpper(method)
+}
 
-	// create a filter registry with the available filter specs registered,
-	// and register the mock and custom filters
-	registry := builtin.MakeRegistry()
-	customFilters := append(mockFilters(o.MockFilters), o.CustomFilters...)
-	for _, f := range customFilters {
-		registry.Register(f)
+// isSimpleHost true if url.Parse keeps the host as it is: letters,
+// digits, dots and dashes, no port
+func isSimpleHost(host string) bool {
+	for i := 0; i < len(host); i++ {
+		c := host[i]
+		if !isAlphanumeric(c) && c != '.' && c != '-' {
+			return false
+		}
 	}
+	return true
+}
+
+// isSimplePath true if url.Parse keeps the path as it is, without
+// escaping, query or fragment
+func isSimplePath(path string) bool {
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if isAlphanumeric(c) {
+			continue
+		}
+		switch c {
+		case '/', '-', '_', '.', '~', '$', '&', '+', ',', ':', ';', '=', '@':
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func isAlphanumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// NoRoutesError the routing table is empty, see Options.Strict
+type NoRoutesError struct {
+	// RoutesFile the routes were loaded from
+	RoutesFile string
+	// Loaded number of routes loaded from the file, 0 if it's empty
+	Loaded int
+	// Reasons why the routing dropped the loaded routes by route id, when known
+	Reasons map[string]string
+}
+
+func (e *NoRoutesError) Error() string {
+	if e.Loaded == 0 {
+		return fmt.Sprintf("no routes loaded: %s has no routes", e.RoutesFile)
+	}
+	msg := fmt.Sprintf("no routes loaded: all the %d routes of %s are invalid", e.Loaded, e.RoutesFile)
+	ids := make([]string, 0, len(e.Reasons))
+	for id := range e.Reasons {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	reasons := make([]string, 0, len(ids))
+	for i, id := range ids {
+		if i == maxNoRoutesReasons {
+			reasons = append(reasons, fmt.Sprintf("and %d more", len(ids)-i))
+			break
+		}
+		reasons = append(reasons, id+": "+e.Reasons[id])
+	}
+	if len(reasons) > 0 {
+		msg += ": " + strings.Join(reasons, "; ")
+	}
+	return msg
+}
+
+// Is matches ErrNoRoutes
+func (e *NoRoutesError) Is(target error) bool { return target == ErrNoRoutes }
+
+// maxNoRoutesReasons number of reasons a NoRoutesError message lists
+const maxNoRoutesReasons = 5
+
+// InitTimeoutError the routing isn't ready within the timeout
+type InitTimeoutError struct {
+	// Timeout the initialization timeout
+	Timeout time.Duration
+	// Applied number of routes applied at the timeout, 0 when the routes
+	// source wasn't loaded yet
+	Applied int
+}
 
+func (e *InitTimeoutError) Error() string {
+	return fmt.Sprintf("routing not initialized after %s: %d routes applied", e.Timeout, e.Applied)
+}
+
+// Is matches ErrInitTimeout
+func (e *InitTimeoutError) Is(target error) bool { return target == ErrInitTimeout }
+
+// loadDataClients creates the data clients of the routes source and loads
+// their routes, it fails if that takes longer than timeout. On timeout the
+// loading goes on in the background and its result is discarded.
+func loadDataClients(o *Options, timeout time.Duration) ([]routing.DataClient, []*eskip.Route, error) {
+	type result struct {
+		clients []routing.DataClient
+		routes  []*eskip.Route
+		err     error
+	}
+	done := make(chan result, 1)
+	create := newDataClients
+	go func() {
+		var r result
+		r.clients, r.err = create(o)
+		if r.err == nil {
+			r.routes, r.err = loadRoutes(r.clients)
+		}
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		return r.clients, r.routes, r.err
+	case <-time.After(timeout):
+		return nil, nil, &InitTimeoutError{Timeout: timeout}
+	}
+}
+
+// createRouting creates the routing and waits until the routes
+// loaded from the data clients are applied, up to the deadline.
+// The routing keeps receiving the updates of the data clients
+// until it's closed.
+func createRouting(dataClients []routing.DataClient, registry filters.Registry, o *Options, diag *diagnostics, deadline time.Time, timeout time.Duration) (*routing.Routing, *routingEvents, error) {
 	// create routing
 	// create the proxy instance
 	var mo routing.MatchingOptions
@@ -221,46 +1284,333 @@ func createRouting(dataClients []routing.DataClient, o *Options) *routing.Routin
 		mo = routing.IgnoreTrailingSlash
 	}
 
-	// include bundled custom predicates
-	o.CustomPredicates = append(o.CustomPredicates,
-		source.New(),
-		source.NewFromLast(),
-		interval.NewBetween(),
-		interval.NewBefore(),
-		interval.NewAfter(),
-		cookie.New(),
-		query.New(),
-		traffic.New(),
-	)
+	predicates := trafficModePredicates(o)
+
+	events := newRoutingEvents(o.Hooks.OnReload, diag)
+	events.onDropped = o.OnRouteDropped
 
 	routingOptions := routing.Options{
-		DataClients:     dataClients,
-		Log:             l,
+		DataClients:     timeReloads(dataClients, events),
+		Log:             &routingLogger{diag, events, routingLog(o)},
 		FilterRegistry:  registry,
 		MatchingOptions: mo,
-		Predicates:      o.CustomPredicates,
+		Predicates:      predicates,
+		PostProcessors:  []routing.PostProcessor{weightPostProcessor{}, events},
+		SignalFirstLoad: true,
+		PollTimeout:     pollTimeout,
 	}
 
 	router := routing.New(routingOptions)
-	defer router.Close()
 
-	// wait for "route settings applied"
-	time.Sleep(120 * time.Millisecond)
+	select {
+	case <-router.FirstLoad():
+		return router, events, nil
+	case <-time.After(time.Until(deadline)):
+		applied := appliedRoutes(router)
+		router.Close()
+		return nil, nil, &InitTimeoutError{Timeout: timeout, Applied: applied}
+	}
+}
 
-	return router
+// appliedRoutes returns the number of routes in the current routing table
+func appliedRoutes(router *routing.Routing) int {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/", nil))
+	n, _ := strconv.Atoi(rec.Header().Get("X-Count"))
+	return n
 }
 
-func createDataClients(path string) ([]routing.DataClient, error) {
-	client, err := eskipfile.Open(path)
+// filterRegistry creates a filter registry with the builtin filter specs,
+// or the ones of Options.FilterRegistry, enabled by the options registered,
+// and registers the mock and custom filters. It fails if the allowlist names
+// filters that aren't builtin.
+func filterRegistry(o *Options) (filters.Registry, error) {
+	registry, err := enabledBuiltinFilters(o)
 	if err != nil {
 		return nil, err
 	}
+	customFilters := append(mockFilters(o.MockFilters), o.CustomFilters...)
+	for _, f := range customFilters {
+		registry.Register(f)
+	}
+	return registry, nil
+}
+
+// enabledBuiltinFilters the builtin filter specs, or the ones of
+// Options.FilterRegistry, enabled by the options in a new registry
+func enabledBuiltinFilters(o *Options) (filters.Registry, error) {
+	builtins := builtinFilters
+	if o.FilterRegistry != nil {
+		builtins = func() filters.Registry {
+			registry := make(filters.Registry, len(o.FilterRegistry))
+			for name, spec := range o.FilterRegistry {
+				registry[name] = spec
+			}
+			return registry
+		}
+	}
+	registry := make(filters.Registry)
+	switch {
+	case o.DisableBuiltinFilters:
+	case len(o.BuiltinFilterAllowlist) > 0:
+		builtins := builtins()
+		for _, name := range o.BuiltinFilterAllowlist {
+			spec, ok := builtins[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown builtin filter %s in the allowlist", name)
+			}
+			registry.Register(spec)
+		}
+	default:
+		registry = builtins()
+	}
+	return registry, nil
+}
+
+// builtinFilters the builtin skipper filters and the lbDecide filter of the
+// newer skipper versions
+func builtinFilters() filters.Registry {
+	registry := builtin.MakeRegistry()
+	registry.Register(&lbDecideSpec{})
+	return registry
+}
+
+// bundledPredicates skipper predicates available in addition to the builtin ones
+func bundledPredicates() []routing.PredicateSpec {
+	return append([]routing.PredicateSpec{
+		source.New(),
+		source.NewFromLast(),
+		cookie.New(),
+		query.New(),
+		traffic.New(),
+	}, append(append(intervalSpecs(), primitiveSpecs()...), lbSpecs()...)...)
+}
+
+// predicateSpecs returns the bundled predicates, none when disabled,
+// followed by the custom ones, in a new slice to leave the options
+// untouched. A custom predicate replaces the bundled one with the same
+// name, see overriddenPredicates, among the custom ones the last wins.
+func predicateSpecs(custom []routing.PredicateSpec, disableBundled bool) []routing.PredicateSpec {
+	var bundled []routing.PredicateSpec
+	if !disableBundled {
+		bundled = bundledPredicates()
+	}
+	names := mapPredicateSpecs(custom)
+	specs := make([]routing.PredicateSpec, 0, len(bundled)+len(custom))
+	for _, spec := range bundled {
+		if _, ok := names[spec.Name()]; !ok {
+			specs = append(specs, spec)
+		}
+	}
+	return append(specs, custom...)
+}
+
+// overriddenPredicates the sorted names of the bundled predicates replaced
+// by custom ones
+func overriddenPredicates(custom []routing.PredicateSpec) []string {
+	names := mapPredicateSpecs(custom)
+	var overridden []string
+	for _, spec := range bundledPredicates() {
+		if _, ok := names[spec.Name()]; ok {
+			overridden = append(overridden, spec.Name())
+		}
+	}
+	sort.Strings(overridden)
+	return overridden
+}
+
+// mapPredicateSpecs maps predicate specs by name, later specs win
+func mapPredicateSpecs(specs []routing.PredicateSpec) map[string]routing.PredicateSpec {
+	m := make(map[string]routing.PredicateSpec, len(specs))
+	for _, spec := range specs {
+		m[spec.Name()] = spec
+	}
+	return m
+}
+
+// loadRoutes loads all the routes defined by the data clients
+func loadRoutes(dataClients []routing.DataClient) ([]*eskip.Route, error) {
+	var routes []*eskip.Route
+	for _, client := range dataClients {
+		r, err := client.LoadAll()
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, r...)
+	}
+	return routes, nil
+}
+
+// checkRoutesSource fails when the options have more than one routes source
+func checkRoutesSource(o *Options) error {
+	switch {
+	case o.Routes != "" && o.RoutesFile != "":
+		return errors.New("RoutesFile and Routes can't be set together")
+	case o.Routes != "" && o.Kubernetes != nil:
+		return errors.New("Kubernetes and Routes can't be set together")
+	}
+	return nil
+}
+
+func createDataClients(o *Options) ([]routing.DataClient, error) {
+	if o.Kubernetes != nil {
+		return kubernetesDataClients(o.Kubernetes)
+	}
+	if o.Routes != "" {
+		client, err := parseDocument(routesDocument, []byte(o.Routes), 0)
+		if err != nil {
+			return nil, err
+		}
+		return []routing.DataClient{client}, nil
+	}
+	if err := checkRoutesFile(o.RoutesFile); err != nil {
+		return nil, err
+	}
+	client, err := openFile(o.RoutesFile, o.OnLoadProgress)
+	if err != nil {
+		return nil, routesFileError(o.RoutesFile, err)
+	}
 	DataClients := []routing.DataClient{
 		client,
 	}
 	return DataClients, nil
 }
 
+// MockFilter creates a mocked filter spec which checks the arguments of the
+// filters with validate, nil to accept any. A route whose filter arguments
+// are invalid is ignored by the routing and reported by Findings (rule
+// invalid-filter-args). Pass it in Options.CustomFilters.
+func MockFilter(name string, validate func(args []interface{}) error) filters.Spec {
+	return &mockFilterSpec{name: name, validate: validate}
+}
+
+// MockFiltersWithArgs creates the mocked filter specs of the names, sorted by
+// name, which accept only the given number of arguments, -1 for any number
+func MockFiltersWithArgs(specs map[string]int) []filters.Spec {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	mocked := make([]filters.Spec, len(names))
+	for i, name := range names {
+		mocked[i] = MockFilter(name, arity(specs[name]))
+	}
+	return mocked
+}
+
+// arity validates the number of arguments of a filter, -1 accepts any
+func arity(n int) func(args []interface{}) error {
+	return func(args []interface{}) error {
+		if n >= 0 && len(args) != n {
+			return fmt.Errorf("%d arguments, expected %d", len(args), n)
+		}
+		return nil
+	}
+}
+
+// mockFilterSpec see MockFilter
+type mockFilterSpec struct {
+	name     string
+	validate func(args []interface{}) error
+}
+
+func (s *mockFilterSpec) Name() string { return s.name }
+
+func (s *mockFilterSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if s.validate != nil {
+		if err := s.validate(args); err != nil {
+			return nil, err
+		}
+	}
+	return &filtertest.Filter{FilterName: s.name, Args: args}, nil
+}
+
+// MockPredicates creates a spec for each of the names whose predicates
+// ALWAYS MATCH, whatever their arguments: the routes using them load and
+// their other predicates decide if they match, eg. TenantIs("acme") matches
+// every request. Pass them in Options.CustomPredicates, see MockPredicate
+// for a predicate never matching.
+func MockPredicates(names []string) []routing.PredicateSpec {
+	specs := make([]routing.PredicateSpec, len(names))
+	for i, name := range names {
+		specs[i] = MockPredicate(name, true)
+	}
+	return specs
+}
+
+// MockPredicate creates the spec of a mocked predicate which matches every
+// request when matches is true, none when it is false
+func MockPredicate(name string, matches bool) routing.PredicateSpec {
+	return &mockPredicateSpec{name: name, matches: matches}
+}
+
+// mockPredicateSpec see MockPredicate
+type mockPredicateSpec struct {
+	name    string
+	matches bool
+}
+
+func (s *mockPredicateSpec) Name() string { return s.name }
+
+func (s *mockPredicateSpec) Create([]interface{}) (routing.Predicate, error) {
+	return mockPredicate(s.matches), nil
+}
+
+// mockPredicate a predicate with a fixed result
+type mockPredicate bool
+
+func (p mockPredicate) Match(*http.Request) bool { return bool(p) }
+
+// PredicateFunc creates the spec of a predicate matching the requests with
+// match, called with the arguments of the predicate in the route. A route
+// whose predicate hasn't argCount arguments, -1 for any number, is ignored
+// by the routing and reported by Findings (rule invalid-predicate-args).
+// match is called concurrently, its arguments must not be modified.
+func PredicateFunc(name string, argCount int, match func(args []interface{}, req *http.Request) bool) routing.PredicateSpec {
+	return &funcPredicateSpec{name: name, validate: arity(argCount), match: match}
+}
+
+// funcPredicateSpec see PredicateFunc
+type funcPredicateSpec struct {
+	name     string
+	validate func(args []interface{}) error
+	match    func(args []interface{}, req *http.Request) bool
+}
+
+func (s *funcPredicateSpec) Name() string { return s.name }
+
+func (s *funcPredicateSpec) Create(args []interface{}) (routing.Predicate, error) {
+	if err := s.validate(args); err != nil {
+		return nil, err
+	}
+	return &funcPredicate{args: append([]interface{}{}, args...), match: s.match}, nil
+}
+
+// funcPredicate a predicate of PredicateFunc with its own copy of the arguments
+type funcPredicate struct {
+	args  []interface{}
+	match func(args []interface{}, req *http.Request) bool
+}
+
+func (p *funcPredicate) Match(req *http.Request) bool { return p.match(p.args, req) }
+
+// autoMockFilters registers a mock for every filter of the routes missing
+// in the registry and returns their sorted names
+func autoMockFilters(routes []*eskip.Route, registry filters.Registry) []string {
+	var mocked []string
+	for _, r := range routes {
+		for _, f := range r.Filters {
+			if _, ok := registry[f.Name]; !ok {
+				registry.Register(&filtertest.Filter{FilterName: f.Name})
+				mocked = append(mocked, f.Name)
+			}
+		}
+	}
+	sort.Strings(mocked)
+	return mocked
+}
+
 // mockFilters creates a list of mocked filters givane a list of filterNames
 func mockFilters(filterNames []string) []filters.Spec {
 	fs := make([]filters.Spec, len(filterNames))
@@ -1,12 +1,29 @@
 package matcher
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"net/url"
+	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/builtin"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/routing"
+	"github.com/zalando/skipper/routing/testdataclient"
 )
 
 func TestMatcherError(t *testing.T) {
@@ -125,6 +142,28 @@ func TestRoutes(t *testing.T) {
 				},
 			},
 		},
+		{
+			routeID: "host",
+			attrs: []*RequestAttributes{
+				{
+					Path: "/host",
+					Host: "api.example.org",
+				},
+			},
+		},
+		{
+			routeID: "query_value",
+			attrs: []*RequestAttributes{
+				{
+					Path:  "/search",
+					Query: map[string]string{"q": "shoes"},
+				},
+				{
+					Path:  "/search?page=2",
+					Query: map[string]string{"q": "shoes"},
+				},
+			},
+		},
 		{
 			routeID: "no-match",
 			nomatch: true,
@@ -132,6 +171,14 @@ func TestRoutes(t *testing.T) {
 				{
 					Path: "/blobblob",
 				},
+				{
+					Path: "/host",
+					Host: "www.example.org",
+				},
+				{
+					Path:  "/search",
+					Query: map[string]string{"q": "boots"},
+				},
 			},
 		},
 	}
@@ -174,9 +221,292 @@ func TestRoutes(t *testing.T) {
 	}
 }
 
+func TestMatcherLargeRoutesFileReadyAfterNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eskip-match-large")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	const n = 5000
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "route%d: Path(\"/route/%d\") && Method(\"GET\") -> setPath(\"/\") -> \"https://backend%d.example.org\";\n", i, i, i)
+	}
+	file := filepath.Join(dir, "large.eskip")
+	require.NoError(t, ioutil.WriteFile(file, []byte(b.String()), 0644))
+
+	m, err := New(&Options{RoutesFile: file})
+	require.NoError(t, err)
+
+	// no waiting: the routes must be active as soon as New returns
+	for _, i := range []int{0, n / 2, n - 1} {
+		res := m.Test(&RequestAttributes{Method: "GET", Path: fmt.Sprintf("/route/%d", i)})
+		if assert.NotNil(t, res.Route(), "route%d must match", i) {
+			assert.Equal(t, fmt.Sprintf("route%d", i), res.Route().Id)
+		}
+	}
+}
+
+// blockingDataClient never returns the routes
+type blockingDataClient struct {
+	release chan struct{}
+}
+
+func (c *blockingDataClient) LoadAll() ([]*eskip.Route, error) {
+	<-c.release
+	return nil, nil
+}
+
+func (c *blockingDataClient) LoadUpdate() ([]*eskip.Route, []string, error) {
+	<-c.release
+	return nil, nil, nil
+}
+
+func TestCreateRoutingTimeout(t *testing.T) {
+	client := &blockingDataClient{release: make(chan struct{})}
+	defer close(client.release)
+
+	o := &Options{}
+	timeout := 50 * time.Millisecond
+	_, _, err := createRouting([]routing.DataClient{client}, builtin.MakeRegistry(), o, newDiagnostics(o), time.Now().Add(timeout), timeout)
+	require.Error(t, err)
+	assert.Equal(t, "routing not initialized after 50ms: 0 routes applied", err.Error())
+}
+
+func TestNewTimeoutSlowRoutesSource(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	defer func(f func(*Options) ([]routing.DataClient, error)) { newDataClients = f }(newDataClients)
+	newDataClients = func(*Options) ([]routing.DataClient, error) {
+		<-release
+		return nil, errors.New("released")
+	}
+
+	start := time.Now()
+	_, err := New(&Options{RoutesFile: "testdata/routes.eskip", InitTimeout: 50 * time.Millisecond})
+	require.Error(t, err)
+	assert.Equal(t, "routing not initialized after 50ms: 0 routes applied", err.Error())
+	assert.True(t, errors.Is(err, ErrInitTimeout))
+	assert.True(t, time.Since(start) < time.Second, "New must return on timeout")
+}
+
+func TestNewTimeoutSlowDataClient(t *testing.T) {
+	client := &blockingDataClient{release: make(chan struct{})}
+	defer close(client.release)
+	defer func(f func(*Options) ([]routing.DataClient, error)) { newDataClients = f }(newDataClients)
+	newDataClients = func(*Options) ([]routing.DataClient, error) {
+		return []routing.DataClient{client}, nil
+	}
+
+	_, err := New(&Options{RoutesFile: "testdata/routes.eskip", InitTimeout: 50 * time.Millisecond})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routing not initialized after 50ms")
+	var timeout *InitTimeoutError
+	if assert.True(t, errors.As(err, &timeout)) {
+		assert.Equal(t, 50*time.Millisecond, timeout.Timeout)
+	}
+}
+
+func TestNewRoutesFileType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "routes")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	empty := filepath.Join(dir, "empty.eskip")
+	require.NoError(t, ioutil.WriteFile(empty, nil, 0644))
+	broken := filepath.Join(dir, "broken.eskip")
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing.eskip"), broken))
+	link := filepath.Join(dir, "link.eskip")
+	require.NoError(t, os.Symlink(filepath.Join("..", filepath.Base(dir), "routes.eskip"), link))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "routes.eskip"), []byte(`a: Path("/a") -> <shunt>;`), 0644))
+
+	t.Run("directory", func(t *testing.T) {
+		_, err := New(&Options{RoutesFile: dir})
+		assert.True(t, errors.Is(err, ErrRoutesFileIsDirectory))
+		assert.True(t, errors.Is(err, ErrRoutesFileUnreadable))
+		assert.False(t, errors.Is(err, ErrRoutesFileNotFound))
+		assert.Equal(t, dir+" is a directory, not a routes file", err.Error())
+	})
+	t.Run("broken symlink", func(t *testing.T) {
+		_, err := New(&Options{RoutesFile: broken})
+		assert.True(t, errors.Is(err, ErrRoutesFileBrokenSymlink))
+		assert.True(t, errors.Is(err, ErrRoutesFileNotFound))
+		assert.False(t, errors.Is(err, ErrRoutesFileUnreadable))
+		assert.Equal(t, broken+" is a symlink to a missing target: "+filepath.Join(dir, "missing.eskip"), err.Error())
+	})
+	t.Run("empty", func(t *testing.T) {
+		_, err := New(&Options{RoutesFile: empty})
+		assert.True(t, errors.Is(err, ErrRoutesFileEmpty))
+		assert.False(t, errors.Is(err, ErrRoutesFileUnreadable))
+		assert.False(t, errors.Is(err, ErrRoutesFileNotFound))
+		assert.Equal(t, empty+" is empty (0 bytes)", err.Error())
+		_, err = New(&Options{RoutesFile: empty, Lazy: true})
+		assert.True(t, errors.Is(err, ErrRoutesFileEmpty), "Lazy checks it too")
+	})
+	t.Run("symlink", func(t *testing.T) {
+		m, err := New(&Options{RoutesFile: link})
+		require.NoError(t, err)
+		defer m.Close()
+		assert.Equal(t, 1, m.RouteCount())
+	})
+}
+
+func TestNewErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "routes")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	write := func(name, doc string, perm os.FileMode) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(doc), perm))
+		return path
+	}
+	missing := filepath.Join(dir, "missing.eskip")
+	invalid := write("invalid.eskip", `a: Path("/a") -> <shunt>;
+b: Path("/b" -> <shunt>;`, 0644)
+	empty := write("empty.eskip", "// no routes\n", 0644)
+	unreadable := write("unreadable.eskip", `a: Path("/a") -> <shunt>;`, 0)
+
+	sentinels := []error{ErrRoutesFileNotFound, ErrRoutesFileUnreadable, ErrParse, ErrNoRoutes, ErrInitTimeout}
+	tests := []struct {
+		name string
+		o    *Options
+		want error
+	}{
+		{name: "not found", o: &Options{RoutesFile: missing}, want: ErrRoutesFileNotFound},
+		{name: "lazy not found", o: &Options{RoutesFile: missing, Lazy: true}, want: ErrRoutesFileNotFound},
+		{name: "directory", o: &Options{RoutesFile: dir}, want: ErrRoutesFileUnreadable},
+		{name: "unreadable", o: &Options{RoutesFile: unreadable}, want: ErrRoutesFileUnreadable},
+		{name: "parse", o: &Options{RoutesFile: invalid}, want: ErrParse},
+		{name: "no routes", o: &Options{RoutesFile: empty, Strict: true}, want: ErrNoRoutes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.o.RoutesFile == unreadable && os.Geteuid() == 0 {
+				t.Skip("root reads any file")
+			}
+			_, err := New(tt.o)
+			require.Error(t, err)
+			for _, sentinel := range sentinels {
+				assert.Equal(t, sentinel == tt.want, errors.Is(err, sentinel), "%v is %v", err, sentinel)
+			}
+		})
+	}
+
+	_, err = New(&Options{RoutesFile: missing})
+	var fileErr *RoutesFileError
+	if assert.True(t, errors.As(err, &fileErr)) {
+		assert.Equal(t, missing, fileErr.Path)
+	}
+	assert.True(t, os.IsNotExist(fileErr.Err))
+	var pathErr *os.PathError
+	assert.True(t, errors.As(err, &pathErr), "the error of the file system is wrapped")
+
+	_, err = New(&Options{RoutesFile: invalid})
+	var parseErr *ParseError
+	if assert.True(t, errors.As(err, &parseErr)) {
+		assert.Equal(t, 2, parseErr.Line)
+	}
+
+	_, err = New(&Options{RoutesFile: empty, Strict: true})
+	var noRoutes *NoRoutesError
+	if assert.True(t, errors.As(err, &noRoutes)) {
+		assert.Equal(t, 0, noRoutes.Loaded)
+	}
+
+	permission := &RoutesFileError{Path: unreadable, Err: &os.PathError{Op: "open", Path: unreadable, Err: os.ErrPermission}}
+	assert.True(t, errors.Is(permission, ErrRoutesFileUnreadable))
+	assert.False(t, errors.Is(permission, ErrRoutesFileNotFound))
+	assert.True(t, errors.Is(permission, os.ErrPermission))
+
+	wrapped := &RoutesFileError{Path: missing, Err: fmt.Errorf("reading the routes: %w", os.ErrNotExist)}
+	assert.True(t, errors.Is(wrapped, ErrRoutesFileNotFound), "the error of the file system is unwrapped")
+	assert.False(t, errors.Is(wrapped, ErrRoutesFileUnreadable))
+	_, isFileErr := routesFileError(invalid, fmt.Errorf("loading: %w", parseErr)).(*RoutesFileError)
+	assert.False(t, isFileErr, "a wrapped parse error isn't a file error")
+}
+
+func TestRoutingReceivesUpdates(t *testing.T) {
+	defer func(d time.Duration) { pollTimeout = d }(pollTimeout)
+	pollTimeout = 10 * time.Millisecond
+
+	client, err := testdataclient.NewDoc(`a: Path("/a") -> <shunt>;`)
+	require.NoError(t, err)
+	o := &Options{}
+	diag := newDiagnostics(o)
+	router, _, err := createRouting([]routing.DataClient{client}, builtin.MakeRegistry(), o, diag, time.Now().Add(time.Second), time.Second)
+	require.NoError(t, err)
+	m := &matcher{routing: router, options: o, diag: diag}
+
+	require.NotNil(t, m.Test(&RequestAttributes{Path: "/a"}).Route(), "the initial routes must match")
+
+	// the routing must still be receiving updates, the update blocks
+	// until the routing polls the client so it can't be sent inline
+	go client.UpdateDoc(`b: Path("/b") -> <shunt>;`, nil)
+	deadline := time.Now().Add(time.Second)
+	for m.Test(&RequestAttributes{Path: "/b"}).Route() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("the updated routes never matched")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.NotNil(t, m.Test(&RequestAttributes{Path: "/a"}).Route(), "the initial routes must still match")
+}
+
+// routingGoroutines returns the stacks of the goroutines running skipper routing code
+func routingGoroutines() []string {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	var found []string
+	for _, g := range strings.Split(string(buf), "\n\n") {
+		if strings.Contains(g, "github.com/zalando/skipper/routing.") {
+			found = append(found, g)
+		}
+	}
+	return found
+}
+
+// assertRoutingGoroutines waits for the routing goroutines to be at most n
+func assertRoutingGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		running := routingGoroutines()
+		if len(running) <= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d routing goroutine(s) leaked:\n%s", len(running)-n, strings.Join(running, "\n\n"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMatcherClose(t *testing.T) {
+	// the matchers created by other tests may still be running
+	before := len(routingGoroutines())
+
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	require.NoError(t, err)
+	res := m.Test(&RequestAttributes{Path: "/bar"})
+	require.NotNil(t, res.Route())
+	assert.NoError(t, res.Err())
+	assert.NotEmpty(t, routingGoroutines())
+
+	assert.NoError(t, m.Close())
+	assert.NoError(t, m.Close(), "Close can be called twice")
+
+	res = m.Test(&RequestAttributes{Path: "/bar"})
+	assert.Nil(t, res.Route())
+	assert.True(t, errors.Is(res.Err(), ErrClosed))
+	assert.Empty(t, m.Explain(&RequestAttributes{Path: "/bar"}, 0))
+	assert.Equal(t, 0, m.RouteCount())
+	assert.Nil(t, m.Findings())
+
+	assertRoutingGoroutines(t, before)
+}
+
 func Example() {
 	m, err := New(&Options{
-		RoutesFile: "./testdata/routes.eskip",
+		RoutesFile:  "./testdata/routes.eskip",
+		MockFilters: []string{"customfilter"},
 	})
 
 	if err != nil {
@@ -199,3 +529,1070 @@ func Example() {
 		// Output: bar
 	}
 }
+
+func ExamplePredicateFunc() {
+	m, err := New(&Options{
+		RoutesFile: "./testdata/tenants.eskip",
+		CustomPredicates: []routing.PredicateSpec{
+			PredicateFunc("TenantIs", 1, func(args []interface{}, req *http.Request) bool {
+				return req.Header.Get("X-Tenant") == args[0]
+			}),
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer m.Close()
+
+	for _, tenant := range []string{"acme", "globex"} {
+		result := m.Test(&RequestAttributes{Path: "/orders", Headers: map[string]string{"X-Tenant": tenant}})
+		fmt.Println(tenant, result.Route().Id)
+	}
+	// Output:
+	// acme acme
+	// globex orders
+}
+
+func ExampleMustNew() {
+	m := MustNew(&Options{RoutesFile: "./testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	defer m.Close()
+
+	fmt.Println(m.Test(&RequestAttributes{Path: "/bar"}).MustRoute().Id)
+	// Output: bar
+}
+
+// concurrentCases requests tested in parallel with their matching route
+var concurrentCases = []struct {
+	attrs   RequestAttributes
+	routeID string
+}{
+	{RequestAttributes{Path: "bar"}, "bar"},
+	{RequestAttributes{Method: "get", Path: "/foo"}, "foo_get"},
+	{RequestAttributes{Path: "/foo", Headers: map[string]string{"Accept": "application/json"}}, "foo_header"},
+	{RequestAttributes{Method: "POST", Path: "/foo"}, "foo"},
+	{RequestAttributes{Host: "api.example.org", Path: "/host"}, "host"},
+	{RequestAttributes{Path: "/search", Query: map[string]string{"q": "shoes"}}, "query_value"},
+	{RequestAttributes{Path: "/nomatch"}, ""},
+}
+
+func newConcurrentMatcher(t testing.TB) Matcher {
+	m, err := New(&Options{
+		RoutesFile:  "testdata/routes.eskip",
+		MockFilters: []string{"customfilter"},
+		Verbosity:   VerbosityDebug,
+		Log:         ioutil.Discard,
+	})
+	require.NoError(t, err)
+	return m
+}
+
+func routeID(r TestResult) string {
+	if r.Route() == nil {
+		return ""
+	}
+	return r.Route().Id
+}
+
+func TestMatcherConcurrentTest(t *testing.T) {
+	m := newConcurrentMatcher(t)
+	defer m.Close()
+
+	// shared attributes are only read by the matcher
+	shared := make([]*RequestAttributes, len(concurrentCases))
+	for i := range concurrentCases {
+		a := concurrentCases[i].attrs
+		shared[i] = &a
+	}
+
+	const goroutines = 50
+	errs := make(chan error, goroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				c := concurrentCases[(g+i)%len(concurrentCases)]
+				attrs := shared[(g+i)%len(concurrentCases)]
+				if g%2 == 0 {
+					// distinct attributes
+					distinct := c.attrs
+					attrs = &distinct
+				}
+				result := m.Test(attrs)
+				if got := routeID(result); got != c.routeID {
+					errs <- fmt.Errorf("%s %s: got route %q, expected %q", c.attrs.Method, c.attrs.Path, got, c.routeID)
+					return
+				}
+				if !strings.HasPrefix(result.Attributes().Path, "/") || result.Attributes().Method == "" {
+					errs <- fmt.Errorf("attributes not normalized: %+v", result.Attributes())
+					return
+				}
+				m.Explain(attrs, 2)
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for i, c := range concurrentCases {
+		assert.Equal(t, c.attrs, *shared[i], "the given attributes must not be modified")
+	}
+}
+
+func TestMatcherTestKeepsAttributes(t *testing.T) {
+	m := newConcurrentMatcher(t)
+	defer m.Close()
+
+	attrs := &RequestAttributes{Method: "get", Path: "foo"}
+	result := m.Test(attrs)
+	assert.Equal(t, &RequestAttributes{Method: "get", Path: "foo"}, attrs)
+	assert.Equal(t, "/foo", result.Attributes().Path)
+	assert.Equal(t, "GET", result.Request().Method)
+}
+
+func BenchmarkMatcherTestParallel(b *testing.B) {
+	m := newConcurrentMatcher(b)
+	defer m.Close()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.Test(&concurrentCases[i%len(concurrentCases)].attrs)
+			i++
+		}
+	})
+}
+
+func BenchmarkTest(b *testing.B) {
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	require.NoError(b, err)
+	defer m.Close()
+	attrs := &RequestAttributes{
+		Method:  "get",
+		Path:    "/foo",
+		Host:    "api.example.org",
+		Query:   map[string]string{"q": "shoes"},
+		Headers: map[string]string{"Accept": "application/json"},
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Test(attrs)
+	}
+}
+
+func BenchmarkCreateHTTPRequest(b *testing.B) {
+	attrs := &RequestAttributes{
+		Method:  "get",
+		Path:    "/foo",
+		Host:    "api.example.org",
+		Query:   map[string]string{"q": "shoes"},
+		Headers: map[string]string{"Accept": "application/json"},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		createHTTPRequest(attrs)
+	}
+}
+
+// parsedHTTPRequest request built by parsing the whole url,
+// the reference of the createHTTPRequest behavior
+func parsedHTTPRequest(attributes *RequestAttributes) (*http.Request, error) {
+	a := normalizeAttributes(attributes)
+	host := a.Host
+	if host == "" {
+		host = "localhost"
+	}
+	u, err := url.Parse("http://" + host + a.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(a.Query) > 0 {
+		q := u.Query()
+		for key, value := range a.Query {
+			q.Set(key, value)
+		}
+		u.RawQuery = q.Encode()
+	}
+	req := &http.Request{Method: strings.ToUpper(a.Method), URL: u, Host: a.Host, Header: make(http.Header)}
+	for key, value := range a.Headers {
+		req.Header.Set(key, value)
+	}
+	return req, nil
+}
+
+func TestCreateHTTPRequest(t *testing.T) {
+	tests := []RequestAttributes{
+		{Path: "/foo"},
+		{Path: "foo"},
+		{Path: ""},
+		{Path: "/a/b-c_d.e~f/$&+,:;=@"},
+		{Path: "//double//slash/"},
+		{Path: "/a/../b/./c"},
+		{Path: "/a%2Fb"},
+		{Path: "/caf%C3%A9"},
+		{Path: "/café"},
+		{Path: "/a b"},
+		{Path: "/a!b*(c)'"},
+		{Path: "/a%zz"},
+		{Path: "/search?q=shoes&page=2"},
+		{Path: "/search?q=shoes", Query: map[string]string{"q": "boots", "size": "42"}},
+		{Path: "/search?", Query: map[string]string{"q": "a b&c"}},
+		{Path: "/a#fragment"},
+		{Path: "/a", Host: "api.example.org"},
+		{Path: "/a", Host: "api.example.org:8080"},
+		{Path: "/a", Host: "[::1]:8080"},
+		{Path: "/a", Host: "API.Example.ORG"},
+		{Path: "/a", Host: "bad host"},
+		{Path: "/a", Host: "user@example.org"},
+		{Path: "/a", Method: "post"},
+		{Path: "/a", Method: "Delete"},
+		{Path: "/a", Method: "purge"},
+		{Path: "/a", Method: "PROPFIND"},
+		{Path: "/a", Headers: map[string]string{"accept": "text/html", "X-Tenant": "acme"}},
+	}
+	for _, attrs := range tests {
+		a := attrs
+		t.Run(fmt.Sprintf("%s %s %s", a.Method, a.Host, a.Path), func(t *testing.T) {
+			expected, expectedErr := parsedHTTPRequest(&a)
+			got, err := createHTTPRequest(&a)
+			if expectedErr != nil {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, expected.Method, got.Method)
+			assert.Equal(t, expected.Host, got.Host)
+			assert.Equal(t, expected.URL, got.URL)
+			assert.Equal(t, expected.URL.String(), got.URL.String())
+			assert.Equal(t, expected.Header, got.Header)
+			assert.Equal(t, attrs, a, "the attributes must not be modified")
+		})
+	}
+}
+
+func TestCreateHTTPRequestAllocations(t *testing.T) {
+	attrs := &RequestAttributes{
+		Method:  "get",
+		Path:    "/foo",
+		Host:    "api.example.org",
+		Query:   map[string]string{"q": "shoes"},
+		Headers: map[string]string{"Accept": "application/json"},
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		createHTTPRequest(attrs)
+	})
+	assert.True(t, allocs <= 7, "createHTTPRequest allocations: %v, budget 7", allocs)
+
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	require.NoError(t, err)
+	defer m.Close()
+	allocs = testing.AllocsPerRun(100, func() {
+		m.Test(attrs)
+	})
+	assert.True(t, allocs <= 13, "Test allocations: %v, budget 13", allocs)
+}
+
+// tenantSpec custom predicate Tenant("name") matching the X-Tenant header
+type tenantSpec struct{}
+
+type tenantPredicate string
+
+func (tenantSpec) Name() string { return "Tenant" }
+
+func (tenantSpec) Create(args []interface{}) (routing.Predicate, error) {
+	if len(args) != 1 {
+		return nil, errors.New("Tenant expects a tenant name")
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("Tenant expects a tenant name")
+	}
+	return tenantPredicate(name), nil
+}
+
+func (p tenantPredicate) Match(r *http.Request) bool {
+	return r.Header.Get("X-Tenant") == string(p)
+}
+
+func TestNewKeepsCustomPredicates(t *testing.T) {
+	f, err := ioutil.TempFile("", "predicates-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`tenant: Tenant("acme") -> <shunt>; search: Path("/search") && QueryParam("q") -> <shunt>;`)
+	f.Close()
+
+	// the spare capacity of the slice is shared with specs the caller still uses
+	specs := []routing.PredicateSpec{tenantSpec{}, tenantSpec{}}
+	o := &Options{RoutesFile: f.Name(), CustomPredicates: specs[:1]}
+
+	for i := 0; i < 2; i++ {
+		m, err := New(o)
+		require.NoError(t, err)
+		res := m.Test(&RequestAttributes{Path: "/", Headers: map[string]string{"X-Tenant": "acme"}})
+		if assert.NotNil(t, res.Route()) {
+			assert.Equal(t, "tenant", res.Route().Id)
+		}
+		res = m.Test(&RequestAttributes{Path: "/search", Query: map[string]string{"q": "boots"}})
+		if assert.NotNil(t, res.Route(), "bundled predicates must be available") {
+			assert.Equal(t, "search", res.Route().Id)
+		}
+		m.Close()
+
+		assert.Len(t, o.CustomPredicates, 1)
+		assert.Equal(t, tenantSpec{}, o.CustomPredicates[0])
+		assert.Equal(t, tenantSpec{}, specs[1], "the slice spare capacity must not be overwritten")
+	}
+}
+
+// neverSpec a QueryParam predicate never matching, replacing the bundled one
+type neverSpec struct{}
+
+func (neverSpec) Name() string { return "QueryParam" }
+
+func (neverSpec) Create([]interface{}) (routing.Predicate, error) { return neverPredicate{}, nil }
+
+type neverPredicate struct{}
+
+func (neverPredicate) Match(*http.Request) bool { return false }
+
+func TestNewCustomPredicateReplacesBundled(t *testing.T) {
+	f, err := ioutil.TempFile("", "predicates-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`search: Path("/search") && QueryParam("q") -> <shunt>;`)
+	f.Close()
+
+	m, err := New(&Options{RoutesFile: f.Name(), CustomPredicates: []routing.PredicateSpec{neverSpec{}}})
+	require.NoError(t, err)
+	defer m.Close()
+
+	req := &RequestAttributes{Path: "/search", Query: map[string]string{"q": "boots"}}
+	assert.Nil(t, m.Test(req).Route(), "the custom predicate must win in the routing")
+	misses := m.Explain(req, 1)
+	require.Len(t, misses, 1)
+	assert.Equal(t, 1, misses[0].Failed, "the custom predicate must win in Explain too")
+}
+
+func TestNewBuiltinFilters(t *testing.T) {
+	f, err := ioutil.TempFile("", "filters-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`status: Path("/status") -> status(200) -> <shunt>;
+mocked: Path("/mocked") -> customfilter() -> <shunt>;
+path: Path("/path") -> setPath("/other") -> <shunt>;
+`)
+	f.Close()
+
+	tests := []struct {
+		name      string
+		disable   bool
+		allowlist []string
+		matching  []string
+		ignored   []string
+	}{
+		{name: "all builtin filters", matching: []string{"status", "mocked", "path"}},
+		{name: "disabled", disable: true, matching: []string{"mocked"}, ignored: []string{"status", "path"}},
+		{name: "allowlist", allowlist: []string{"status"}, matching: []string{"status", "mocked"}, ignored: []string{"path"}},
+		{name: "disabled wins over the allowlist", disable: true, allowlist: []string{"status"}, matching: []string{"mocked"}, ignored: []string{"status", "path"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(&Options{
+				RoutesFile:             f.Name(),
+				MockFilters:            []string{"customfilter"},
+				DisableBuiltinFilters:  tt.disable,
+				BuiltinFilterAllowlist: tt.allowlist,
+				AllowMissingFilters:    true,
+			})
+			require.NoError(t, err)
+			defer m.Close()
+
+			for _, id := range tt.matching {
+				res := m.Test(&RequestAttributes{Path: "/" + id})
+				if assert.NotNil(t, res.Route(), id) {
+					assert.Equal(t, id, res.Route().Id)
+				}
+			}
+			var ignored []string
+			for _, finding := range m.Findings() {
+				assert.Equal(t, RuleUnknownFilter, finding.RuleID)
+				assert.Equal(t, f.Name(), finding.File)
+				assert.NotZero(t, finding.Line)
+				ignored = append(ignored, finding.RouteID)
+			}
+			assert.Equal(t, tt.ignored, ignored)
+			for _, id := range tt.ignored {
+				assert.Nil(t, m.Test(&RequestAttributes{Path: "/" + id}).Route(), id)
+			}
+		})
+	}
+}
+
+func TestNewBuiltinFilterAllowlistUnknown(t *testing.T) {
+	_, err := New(&Options{RoutesFile: "testdata/routes.eskip", BuiltinFilterAllowlist: []string{"status", "nosuchfilter"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown builtin filter nosuchfilter")
+}
+
+func TestNewFilterRegistry(t *testing.T) {
+	f, err := ioutil.TempFile("", "registry-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`orders: Path("/orders") -> tenantAuth("acme") -> setPath("/") -> "http://orders.svc";
+audited: Path("/audited") -> audit() -> "http://orders.svc";
+`)
+	f.Close()
+
+	shared := builtin.MakeRegistry()
+	shared.Register(&filtertest.Filter{FilterName: "tenantAuth"})
+	shared.Register(&filtertest.Filter{FilterName: "audit"})
+	size := len(shared)
+
+	m, err := New(&Options{RoutesFile: f.Name(), FilterRegistry: shared})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, 2, m.RouteCount())
+
+	custom := MockFilter("audit", arity(0))
+	registry, err := filterRegistry(&Options{FilterRegistry: shared, CustomFilters: []filters.Spec{custom}, MockFilters: []string{"rateLimit"}})
+	require.NoError(t, err)
+	assert.Equal(t, custom, registry["audit"], "the custom filters win")
+	assert.Contains(t, registry, "rateLimit")
+	assert.Len(t, shared, size, "the registry isn't modified")
+	assert.NotContains(t, shared, "rateLimit")
+
+	registry, err = filterRegistry(&Options{FilterRegistry: shared, BuiltinFilterAllowlist: []string{"tenantAuth"}})
+	require.NoError(t, err)
+	assert.Len(t, registry, 1)
+	assert.Contains(t, registry, "tenantAuth")
+
+	_, err = New(&Options{RoutesFile: f.Name(), FilterRegistry: shared, DisableBuiltinFilters: true})
+	assert.IsType(t, &MissingFiltersError{}, err)
+}
+
+func TestNewMissingFilters(t *testing.T) {
+	f, err := ioutil.TempFile("", "missing-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(f, "r%d: Path(\"/r%d\") -> setPath(\"/\") -> <shunt>;\n", i, i)
+	}
+	f.WriteString(`typo: Path("/typo") -> tenantAuht() -> <shunt>;
+typo_v2: Path("/v2/typo") -> tenantAuht() -> <shunt>;
+`)
+	f.Close()
+
+	_, err = New(&Options{RoutesFile: f.Name()})
+	require.Error(t, err)
+	if assert.IsType(t, &MissingFiltersError{}, err) {
+		assert.Equal(t, map[string][]string{"tenantAuht": {"typo", "typo_v2"}}, err.(*MissingFiltersError).Routes)
+	}
+	assert.Equal(t, "missing filters tenantAuht (used by typo, typo_v2), register them with CustomFilters or mock them with MockFilters", err.Error())
+
+	m, err := New(&Options{RoutesFile: f.Name(), AllowMissingFilters: true})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, 100, m.RouteCount())
+	var ignored []string
+	for _, finding := range m.Findings() {
+		assert.Equal(t, RuleUnknownFilter, finding.RuleID)
+		ignored = append(ignored, finding.RouteID)
+	}
+	assert.Equal(t, []string{"typo", "typo_v2"}, ignored)
+}
+
+func TestNewAutoMockFilters(t *testing.T) {
+	f, err := ioutil.TempFile("", "automock-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`orders: Path("/orders") -> tenantAuth("acme") -> setPath("/") -> "http://orders.svc";
+search: Path("/search") -> tenantAuth() -> rateLimitByTenant() -> "http://search.svc";
+`)
+	f.Close()
+
+	var log strings.Builder
+	m, err := New(&Options{RoutesFile: f.Name(), AutoMockFilters: true, Verbose: true, Log: &log})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, 2, m.RouteCount())
+	assert.Empty(t, m.Findings())
+	assert.Contains(t, log.String(), "auto-mocked filters: rateLimitByTenant, tenantAuth\n")
+
+	res := m.Test(&RequestAttributes{Method: "GET", Path: "/search"})
+	require.NoError(t, res.Err())
+	if assert.NotNil(t, res.Route()) {
+		assert.Equal(t, "search", res.Route().Id)
+	}
+}
+
+func TestNewUnknownPredicates(t *testing.T) {
+	f, err := ioutil.TempFile("", "predicates-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`search: Path("/search") && QueryParam("q") -> <shunt>;
+tenant: Path("/tenant") && TenantIs("acme") -> <shunt>;
+`)
+	f.Close()
+
+	_, err = New(&Options{RoutesFile: f.Name()})
+	require.Error(t, err)
+	if assert.IsType(t, &UnknownPredicatesError{}, err) {
+		e := err.(*UnknownPredicatesError)
+		assert.Equal(t, map[string][]string{"TenantIs": {"tenant"}}, e.Routes)
+		assert.Equal(t, map[string]Location{"tenant": {File: f.Name(), Line: 2}}, e.Locations)
+	}
+	assert.Equal(t, "unknown predicates TenantIs (used by tenant at "+f.Name()+":2), register them with CustomPredicates or mock them with MockPredicates", err.Error())
+
+	m, err := New(&Options{RoutesFile: f.Name(), AllowUnknownPredicates: true})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, 1, m.RouteCount())
+	if assert.Len(t, m.Findings(), 1) {
+		assert.Equal(t, RuleUnknownPredicate, m.Findings()[0].RuleID)
+		assert.Equal(t, "tenant", m.Findings()[0].RouteID)
+	}
+}
+
+func TestMockFiltersWithArgs(t *testing.T) {
+	f, err := ioutil.TempFile("", "mockargs-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`orders: Path("/orders") -> tenantAuth("acme") -> audit() -> <shunt>;
+orders_v2: Path("/v2/orders") -> tenantAuth("acme", "beta") -> <shunt>;
+search: Path("/search") -> audit("a", "b", "c") -> quota(0) -> <shunt>;
+`)
+	f.Close()
+
+	positive := func(args []interface{}) error {
+		if len(args) != 1 {
+			return errors.New("expected a limit")
+		}
+		if n, ok := args[0].(float64); !ok || n <= 0 {
+			return fmt.Errorf("invalid limit %v", args[0])
+		}
+		return nil
+	}
+	mocked := append(MockFiltersWithArgs(map[string]int{"tenantAuth": 1, "audit": -1}), MockFilter("quota", positive))
+	m, err := New(&Options{RoutesFile: f.Name(), CustomFilters: mocked})
+	require.NoError(t, err)
+	defer m.Close()
+
+	assert.Equal(t, 1, m.RouteCount())
+	res := m.Test(&RequestAttributes{Method: "GET", Path: "/orders"})
+	if assert.NotNil(t, res.Route()) {
+		assert.Equal(t, "orders", res.Route().Id)
+	}
+	assert.Nil(t, m.Test(&RequestAttributes{Method: "GET", Path: "/v2/orders"}).Route())
+
+	var strs []string
+	for _, finding := range m.Findings() {
+		strs = append(strs, finding.String())
+	}
+	assert.Equal(t, []string{
+		f.Name() + `:2: error: orders_v2: invalid filter tenantAuth("acme", "beta"): 2 arguments, expected 1, the route is ignored [invalid-filter-args]`,
+		f.Name() + `:3: error: search: invalid filter quota(0): invalid limit 0, the route is ignored [invalid-filter-args]`,
+	}, strs)
+}
+
+func TestMockPredicates(t *testing.T) {
+	f, err := ioutil.TempFile("", "mockpredicates-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`tenant: Path("/orders") && TenantIs("acme") -> <shunt>;
+orders: Path("/orders") -> <shunt>;
+feature: Path("/search") && FeatureEnabled("new-search") && Method("POST") -> <shunt>;
+`)
+	f.Close()
+
+	test := func(m Matcher, method, path string) string {
+		res := m.Test(&RequestAttributes{Method: method, Path: path})
+		require.NoError(t, res.Err())
+		if res.Route() == nil {
+			return ""
+		}
+		return res.Route().Id
+	}
+
+	m, err := New(&Options{RoutesFile: f.Name(), CustomPredicates: MockPredicates([]string{"TenantIs", "FeatureEnabled"})})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, 3, m.RouteCount())
+	assert.Equal(t, "tenant", test(m, "GET", "/orders"))
+	assert.Equal(t, "feature", test(m, "POST", "/search"))
+	assert.Equal(t, "", test(m, "GET", "/search"), "the other predicates still decide")
+
+	m, err = New(&Options{RoutesFile: f.Name(), CustomPredicates: []routing.PredicateSpec{MockPredicate("TenantIs", false), MockPredicate("FeatureEnabled", true)}})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, "orders", test(m, "GET", "/orders"))
+}
+
+func TestPredicateFunc(t *testing.T) {
+	f, err := ioutil.TempFile("", "predicatefunc-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`acme: Path("/orders") && TenantIs("acme") -> <shunt>;
+orders: Path("/orders") -> <shunt>;
+broken: Path("/broken") && TenantIs("acme", "globex") -> <shunt>;
+`)
+	f.Close()
+
+	tenantIs := PredicateFunc("TenantIs", 1, func(args []interface{}, req *http.Request) bool {
+		return req.Header.Get("X-Tenant") == args[0]
+	})
+	m, err := New(&Options{RoutesFile: f.Name(), CustomPredicates: []routing.PredicateSpec{tenantIs}})
+	require.NoError(t, err)
+	defer m.Close()
+
+	assert.Equal(t, 2, m.RouteCount())
+	if assert.Len(t, m.Findings(), 1) {
+		assert.Equal(t, Finding{
+			RuleID:   RuleInvalidPredicateArgs,
+			Severity: SeverityError,
+			RouteID:  "broken",
+			File:     f.Name(),
+			Line:     3,
+			Message:  `invalid predicate TenantIs("acme", "globex"): 2 arguments, expected 1, the route is ignored`,
+		}, m.Findings()[0])
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tenant, want := "acme", "acme"
+			if i%2 == 1 {
+				tenant, want = "globex", "orders"
+			}
+			for j := 0; j < 50; j++ {
+				res := m.Test(&RequestAttributes{Path: "/orders", Headers: map[string]string{"X-Tenant": tenant}})
+				if assert.NotNil(t, res.Route()) {
+					assert.Equal(t, want, res.Route().Id)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestCustomPredicatesReplaceBundled(t *testing.T) {
+	f, err := ioutil.TempFile("", "bundled-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`search: Path("/search") && QueryParam("q") -> <shunt>;
+`)
+	f.Close()
+
+	var evaluated []interface{}
+	queryParam := PredicateFunc("QueryParam", -1, func(args []interface{}, req *http.Request) bool {
+		evaluated = append(evaluated, args...)
+		return req.Header.Get("X-Query") != ""
+	})
+	var log strings.Builder
+	m, err := New(&Options{RoutesFile: f.Name(), CustomPredicates: []routing.PredicateSpec{queryParam}, Verbose: true, Log: &log})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Contains(t, log.String(), "custom predicate QueryParam replaces the bundled one\n")
+
+	assert.Nil(t, m.Test(&RequestAttributes{Method: "GET", Path: "/search", Query: map[string]string{"q": "shoes"}}).Route())
+	res := m.Test(&RequestAttributes{Method: "GET", Path: "/search", Headers: map[string]string{"X-Query": "shoes"}})
+	if assert.NotNil(t, res.Route()) {
+		assert.Equal(t, "search", res.Route().Id)
+	}
+	assert.NotEmpty(t, evaluated, "the custom spec is evaluated")
+	assert.Equal(t, "q", evaluated[0])
+
+	_, err = New(&Options{RoutesFile: f.Name(), DisableBundledPredicates: true})
+	if assert.IsType(t, &UnknownPredicatesError{}, err) {
+		assert.Equal(t, map[string][]string{"QueryParam": {"search"}}, err.(*UnknownPredicatesError).Routes)
+	}
+	m, err = New(&Options{RoutesFile: f.Name(), DisableBundledPredicates: true, CustomPredicates: []routing.PredicateSpec{queryParam}})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, 1, m.RouteCount())
+}
+
+func TestNewStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		routes  string
+		loaded  int
+		reasons map[string]string
+	}{
+		// an empty file fails without Strict, see TestNewRoutesFileType
+		{name: "comments only", routes: "// no routes yet\n", reasons: map[string]string{}},
+		{name: "all invalid", routes: `tenant: Path("/tenant") && TenantIs("acme") -> <shunt>;
+bad: PathRegexp("^/(bad$") -> <shunt>;
+`, loaded: 2, reasons: map[string]string{
+			"bad":    "error parsing regexp: missing closing ): `^/(bad$`",
+			"tenant": "predicate not found: 'TenantIs'",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "strict-*.eskip")
+			require.NoError(t, err)
+			defer os.Remove(f.Name())
+			f.WriteString(tt.routes)
+			f.Close()
+
+			m, err := New(&Options{RoutesFile: f.Name(), AllowUnknownPredicates: true})
+			require.NoError(t, err)
+			assert.Equal(t, 0, m.RouteCount())
+			m.Close()
+
+			_, err = New(&Options{RoutesFile: f.Name(), AllowUnknownPredicates: true, Strict: true})
+			require.Error(t, err)
+			if assert.IsType(t, &NoRoutesError{}, err) {
+				e := err.(*NoRoutesError)
+				assert.Equal(t, tt.loaded, e.Loaded)
+				assert.Equal(t, tt.reasons, e.Reasons)
+			}
+		})
+	}
+
+	t.Run("messages", func(t *testing.T) {
+		assert.Equal(t, "no routes loaded: routes.eskip has no routes", (&NoRoutesError{RoutesFile: "routes.eskip"}).Error())
+		assert.Equal(t, "no routes loaded: all the 2 routes of routes.eskip are invalid: a: filter not found: 'foo'; b: invalid",
+			(&NoRoutesError{RoutesFile: "routes.eskip", Loaded: 2, Reasons: map[string]string{"b": "invalid", "a": "filter not found: 'foo'"}}).Error())
+		reasons := map[string]string{}
+		for _, id := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+			reasons[id] = "invalid"
+		}
+		assert.Equal(t, "no routes loaded: all the 7 routes of routes.eskip are invalid: a: invalid; b: invalid; c: invalid; d: invalid; e: invalid; and 2 more",
+			(&NoRoutesError{RoutesFile: "routes.eskip", Loaded: 7, Reasons: reasons}).Error())
+	})
+
+	t.Run("routes", func(t *testing.T) {
+		m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, Strict: true})
+		require.NoError(t, err)
+		m.Close()
+	})
+}
+
+// toggleDataClient data client failing while failing is 1
+type toggleDataClient struct {
+	routes  []*eskip.Route
+	failing int32
+}
+
+func (c *toggleDataClient) LoadAll() ([]*eskip.Route, error) {
+	if atomic.LoadInt32(&c.failing) == 1 {
+		return nil, errors.New("routes source unavailable")
+	}
+	return c.routes, nil
+}
+
+func (c *toggleDataClient) LoadUpdate() ([]*eskip.Route, []string, error) {
+	if atomic.LoadInt32(&c.failing) == 1 {
+		return nil, nil, errors.New("routes source unavailable")
+	}
+	return nil, nil, nil
+}
+
+func TestMatcherReady(t *testing.T) {
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	require.NoError(t, err)
+	ready, err := m.Ready()
+	assert.True(t, ready)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, m.RouteCount())
+
+	m.Close()
+	ready, err = m.Ready()
+	assert.False(t, ready)
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestMatcherReadyLoadFailure(t *testing.T) {
+	defer func(d time.Duration) { pollTimeout = d }(pollTimeout)
+	pollTimeout = 5 * time.Millisecond
+
+	routes, err := eskip.Parse(`a: Path("/a") -> <shunt>; b: Path("/b") -> <shunt>;`)
+	require.NoError(t, err)
+	client := &toggleDataClient{routes: routes}
+	defer func(f func(*Options) ([]routing.DataClient, error)) { newDataClients = f }(newDataClients)
+	newDataClients = func(*Options) ([]routing.DataClient, error) {
+		return []routing.DataClient{client}, nil
+	}
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip"})
+	require.NoError(t, err)
+	defer m.Close()
+
+	waitReady := func(failing bool) {
+		deadline := time.Now().Add(time.Second)
+		for {
+			ready, err := m.Ready()
+			require.True(t, ready, "the loaded routes are still used")
+			if (err != nil) == failing {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("load error %v, expected failing %t", err, failing)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	atomic.StoreInt32(&client.failing, 1)
+	waitReady(true)
+	_, err = m.Ready()
+	assert.Contains(t, err.Error(), "routes source unavailable")
+	assert.Equal(t, 2, m.RouteCount())
+
+	atomic.StoreInt32(&client.failing, 0)
+	waitReady(false)
+	assert.Equal(t, 2, m.RouteCount())
+}
+
+func TestMatcherRouteCountConcurrentReloads(t *testing.T) {
+	defer func(d time.Duration) { pollTimeout = d }(pollTimeout)
+	pollTimeout = time.Millisecond
+
+	client, err := testdataclient.NewDoc(`a: Path("/a") -> <shunt>;`)
+	require.NoError(t, err)
+	o := &Options{}
+	diag := newDiagnostics(o)
+	router, events, err := createRouting([]routing.DataClient{client}, builtin.MakeRegistry(), o, diag, time.Now().Add(time.Second), time.Second)
+	require.NoError(t, err)
+	m := &matcher{routing: router, events: events, options: o, diag: diag}
+	defer m.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			client.UpdateDoc(fmt.Sprintf(`r%d: Path("/r%d") -> <shunt>;`, i, i), nil)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			// the last update may not be applied yet
+			deadline := time.Now().Add(time.Second)
+			for m.RouteCount() != 51 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			assert.Equal(t, 51, m.RouteCount())
+			return
+		default:
+			ready, err := m.Ready()
+			assert.True(t, ready)
+			assert.NoError(t, err)
+			n := m.RouteCount()
+			assert.True(t, n >= 1 && n <= 51, "route count %d", n)
+		}
+	}
+}
+
+func TestMatcherRoutes(t *testing.T) {
+	f, err := ioutil.TempFile("", "routes-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`b: Path("/b") -> <shunt>;
+a: Path("/a") -> setPath("/") -> <shunt>;
+b: Path("/b2") -> <shunt>;
+missing: Path("/missing") -> missingfilter() -> <shunt>;
+`)
+	f.Close()
+
+	m, err := New(&Options{RoutesFile: f.Name(), AllowMissingFilters: true})
+	require.NoError(t, err)
+	routes := m.Routes()
+	var ids []string
+	for _, r := range routes {
+		ids = append(ids, r.Id)
+	}
+	assert.Equal(t, []string{"a", "b"}, ids, "sorted by id, without the invalid routes")
+	assert.Equal(t, "/b2", routes[1].Path, "the last definition of a duplicate id")
+
+	routes[0].Filters[0].Args[0] = "/changed"
+	routes[0].Path = "/changed"
+	again := m.Routes()
+	assert.Equal(t, "/a", again[0].Path, "a copy is returned")
+	assert.Equal(t, "/", again[0].Filters[0].Args[0], "a copy is returned")
+
+	require.NoError(t, m.Close())
+	assert.Nil(t, m.Routes())
+}
+
+func TestMatcherRoutesReload(t *testing.T) {
+	defer func(d time.Duration) { pollTimeout = d }(pollTimeout)
+	pollTimeout = 10 * time.Millisecond
+
+	client, err := testdataclient.NewDoc(`a: Path("/a") -> <shunt>;`)
+	require.NoError(t, err)
+	create := newDataClients
+	defer func() { newDataClients = create }()
+	newDataClients = func(o *Options) ([]routing.DataClient, error) {
+		return []routing.DataClient{client}, nil
+	}
+
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip"})
+	require.NoError(t, err)
+	defer m.Close()
+	require.Len(t, m.Routes(), 1)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				m.Routes()
+				m.Test(&RequestAttributes{Path: "/a"})
+			}
+		}
+	}()
+	require.NoError(t, client.UpdateDoc(`b: Path("/b") -> <shunt>;`, nil))
+	deadline := time.Now().Add(time.Second)
+	for len(m.Routes()) != 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+	if routes := m.Routes(); assert.Len(t, routes, 2) {
+		assert.Equal(t, "a", routes[0].Id)
+		assert.Equal(t, "b", routes[1].Id)
+	}
+}
+
+func TestMatcherGetRoute(t *testing.T) {
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	require.NoError(t, err)
+
+	r, ok := m.GetRoute("bar")
+	require.True(t, ok)
+	assert.Equal(t, "/bar", r.Path)
+	r.Path = "/changed"
+	r, _ = m.GetRoute("bar")
+	assert.Equal(t, "/bar", r.Path, "a copy is returned")
+
+	_, ok = m.GetRoute("nosuchroute")
+	assert.False(t, ok)
+
+	require.NoError(t, m.Close())
+	_, ok = m.GetRoute("bar")
+	assert.False(t, ok)
+}
+
+func TestMatcherGetRouteReloads(t *testing.T) {
+	defer func(d time.Duration) { pollTimeout = d }(pollTimeout)
+	pollTimeout = time.Millisecond
+
+	const n = 50
+	doc := func(version int) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, "r%d: Path(\"/v%d/r%d\") -> <shunt>;\n", i, version, i)
+		}
+		return b.String()
+	}
+	client, err := testdataclient.NewDoc(doc(0))
+	require.NoError(t, err)
+	create := newDataClients
+	defer func() { newDataClients = create }()
+	newDataClients = func(o *Options) ([]routing.DataClient, error) {
+		return []routing.DataClient{client}, nil
+	}
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip"})
+	require.NoError(t, err)
+	defer m.Close()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i = (i + 1) % n {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				id := fmt.Sprintf("r%d", i)
+				r, ok := m.GetRoute(id)
+				if !ok {
+					t.Errorf("route %s not found during a reload", id)
+					return
+				}
+				if !strings.HasSuffix(r.Path, "/"+id) {
+					t.Errorf("unexpected path %s of route %s", r.Path, id)
+					return
+				}
+			}
+		}()
+	}
+	for version := 1; version <= 20; version++ {
+		require.NoError(t, client.UpdateDoc(doc(version), nil))
+		time.Sleep(2 * time.Millisecond)
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if r, _ := m.GetRoute("r0"); r.Path == "/v20/r0" {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+	r, _ := m.GetRoute("r0")
+	assert.Equal(t, "/v20/r0", r.Path)
+}
+
+func TestPathParams(t *testing.T) {
+	m, err := NewWith(WithRoutes(`order: Path("/orders/:id/items/:item") -> <shunt>;
+orders: Path("/orders") -> <shunt>;`))
+	require.NoError(t, err)
+	defer m.Close()
+
+	result := m.Test(&RequestAttributes{Path: "/orders/42/items/7"})
+	params := result.PathParams()
+	assert.Equal(t, map[string]string{"id": "42", "item": "7"}, params)
+	params["id"] = "43"
+	assert.Equal(t, "42", result.PathParams()["id"], "a copy")
+
+	assert.Empty(t, m.Test(&RequestAttributes{Path: "/orders"}).PathParams())
+	assert.Nil(t, m.Test(&RequestAttributes{Path: "/unknown"}).PathParams())
+}
+
+func TestMustNew(t *testing.T) {
+	m := MustNew(&Options{RoutesFile: "./testdata/routes.eskip", MockFilters: []string{"customfilter"}, TB: t})
+	assert.Equal(t, "bar", m.Test(&RequestAttributes{Path: "/bar"}).MustRoute().Id)
+
+	assert.PanicsWithValue(t, "eskip-match: the matcher can't be created: RoutesFile and Routes can't be set together", func() {
+		MustNew(&Options{RoutesFile: "./testdata/routes.eskip", Routes: `bar: Path("/bar") -> <shunt>;`})
+	})
+	_, err := New(&Options{RoutesFile: "./testdata/missing.eskip"})
+	require.Error(t, err)
+	assert.PanicsWithValue(t, "eskip-match: the matcher can't be created: "+err.Error(), func() {
+		MustNew(&Options{RoutesFile: "./testdata/missing.eskip"})
+	}, "with the diagnostic of New")
+}
+
+func TestMustRoute(t *testing.T) {
+	m := MustNew(&Options{RoutesFile: "./testdata/routes.eskip", MockFilters: []string{"customfilter"}, TB: t})
+
+	assert.PanicsWithValue(t, "eskip-match: no route matches GET /baz HTTP/1.1", func() {
+		m.Test(&RequestAttributes{Path: "/baz"}).MustRoute()
+	})
+
+	m.Close()
+	defer func() {
+		assert.Contains(t, recover(), "eskip-match: the request couldn't be tested: failed to test the request: matcher closed\n  request: GET /bar\n")
+	}()
+	m.Test(&RequestAttributes{Path: "/bar"}).MustRoute()
+	t.Error("didn't panic")
+}
@@ -0,0 +1,79 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// MatchError error of a result whose request couldn't be tested, eg. invalid
+// attributes, a closed or not ready matcher or a loopback which can't be
+// followed, with what's needed to debug it from the logs of a CI
+type MatchError struct {
+	// Request the normalized request, eg. GET api.example.org/orders?page=2
+	Request string
+	// Routes number of routes the matcher had applied, 0 when closed or not
+	// ready
+	Routes int
+	// Source where the routes are loaded from, eg. the routes file
+	Source string
+	// Err the cause, eg. ErrClosed or an *InvalidHeaderError
+	Err error
+}
+
+// Error the cause followed by a line for the request, the source and the
+// routes
+func (e *MatchError) Error() string {
+	return fmt.Sprintf("failed to test the request: %v\n  request: %s\n  source:  %s\n  routes:  %d", e.Err, e.Request, e.Source, e.Routes)
+}
+
+// Line the error on one line, eg. for the logs
+func (e *MatchError) Line() string {
+	return fmt.Sprintf("failed to test %s against %d route(s) of %s: %v", e.Request, e.Routes, e.Source, e.Err)
+}
+
+// Unwrap returns the cause
+func (e *MatchError) Unwrap() error { return e.Err }
+
+// newMatchError the error of a result of the normalized attributes
+func newMatchError(attributes *RequestAttributes, routes int, source string, err error) *MatchError {
+	request := requestLine(attributes)
+	if len(attributes.Query) > 0 {
+		q := make(url.Values, len(attributes.Query))
+		for k, v := range attributes.Query {
+			q.Set(k, v)
+		}
+		request += "?" + q.Encode()
+	}
+	return &MatchError{Request: request, Routes: routes, Source: source, Err: err}
+}
+
+// errResult the result of the attributes which can't be tested, eg. by a
+// closed matcher
+func errResult(attributes *RequestAttributes, source string, err error) *testResult {
+	attributes = normalizeAttributes(attributes)
+	return &testResult{attributes: attributes, err: newMatchError(attributes, 0, source, err)}
+}
+
+// matchCause the cause of a *MatchError, the error otherwise, eg. for the
+// messages already telling the request
+func matchCause(err error) error {
+	var e *MatchError
+	if errors.As(err, &e) {
+		return e.Err
+	}
+	return err
+}
+
+// routesSource describes where the routes of the options are loaded from
+func routesSource(o *Options) string {
+	switch {
+	case o.Kubernetes != nil && o.Kubernetes.Manifests != "":
+		return "kubernetes manifests " + o.Kubernetes.Manifests
+	case o.Kubernetes != nil:
+		return "kubernetes " + o.Kubernetes.URL
+	case o.Routes != "":
+		return routesDocument
+	}
+	return o.RoutesFile
+}
@@ -0,0 +1,60 @@
+package matcher
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchError(t *testing.T) {
+	file := writeRoutes(t, `a: Path("/a") -> <shunt>;
+b: Path("/b") -> <shunt>;`)
+	defer os.Remove(file)
+
+	for _, light := range []bool{false, true} {
+		m, err := New(&Options{RoutesFile: file, LightResults: light})
+		require.NoError(t, err)
+		in, out := make(chan *RequestAttributes, 1), make(chan TestResult, 1)
+		in <- &RequestAttributes{Path: "a", Query: map[string]string{"page": "2"}, Headers: map[string]string{"Bad Name": "1"}}
+		close(in)
+		go m.TestManyStream(in, out)
+		res := <-out
+
+		var e *MatchError
+		require.True(t, errors.As(res.Err(), &e))
+		assert.Equal(t, "GET /a?page=2", e.Request)
+		assert.Equal(t, 2, e.Routes)
+		assert.Equal(t, file, e.Source)
+		var invalid *InvalidHeaderError
+		assert.True(t, errors.As(res.Err(), &invalid), "the cause must unwrap")
+		assert.Equal(t, invalid, errors.Unwrap(e))
+		assert.Equal(t, "failed to test the request: invalid header names \"Bad Name\"\n"+
+			"  request: GET /a?page=2\n"+
+			"  source:  "+file+"\n"+
+			"  routes:  2", e.Error())
+		assert.Equal(t, `failed to test GET /a?page=2 against 2 route(s) of `+file+`: invalid header names "Bad Name"`, e.Line())
+		m.Close()
+	}
+}
+
+func TestMatchErrorClosed(t *testing.T) {
+	file := writeRoutes(t, `a: Path("/a") -> <shunt>;`)
+	defer os.Remove(file)
+
+	for _, o := range []*Options{
+		{RoutesFile: file},
+		{RoutesFile: file, Lazy: true},
+	} {
+		m, err := New(o)
+		require.NoError(t, err)
+		m.Close()
+		var e *MatchError
+		require.True(t, errors.As(m.Test(&RequestAttributes{Method: "POST", Path: "/a"}).Err(), &e))
+		assert.Equal(t, ErrClosed, e.Err)
+		assert.Equal(t, 0, e.Routes)
+		assert.Equal(t, "failed to test POST /a against 0 route(s) of "+file+": matcher closed", e.Line())
+	}
+}
@@ -0,0 +1,51 @@
+package matchertest_test
+
+import (
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/matcher/matchertest"
+)
+
+// TestRoutes the typical table test of the routes of a routes file
+func TestRoutes(t *testing.T) {
+	m, err := matcher.New(&matcher.Options{
+		RoutesFile:  "../testdata/routes.eskip",
+		MockFilters: []string{"customfilter"},
+		TB:          t,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		attrs *matcher.RequestAttributes
+		want  string
+	}{
+		{"bar", &matcher.RequestAttributes{Path: "/bar"}, "bar"},
+		{"foo subtree", &matcher.RequestAttributes{Method: "POST", Path: "/foo/baz"}, "foo"},
+		{"foo get", &matcher.RequestAttributes{Method: "GET", Path: "/foo"}, "foo_get"},
+		{"search", &matcher.RequestAttributes{Path: "/search", Query: map[string]string{"q": "shoes"}}, "query_value"},
+		{"unknown", &matcher.RequestAttributes{Path: "/unknown"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.want == "" {
+				matchertest.RequireNoMatch(t, m, tt.attrs)
+				return
+			}
+			matchertest.RequireMatch(t, m, tt.attrs, tt.want)
+		})
+	}
+}
+
+// TestRoutesTerse the same test without the error handling of the setup
+func TestRoutesTerse(t *testing.T) {
+	m := matcher.MustNew(&matcher.Options{RoutesFile: "../testdata/routes.eskip", MockFilters: []string{"customfilter"}, TB: t})
+
+	route := matchertest.MustTest(t, m, &matcher.RequestAttributes{Path: "/bar"}).MustRoute()
+	if route.Id != "bar" {
+		t.Errorf("want route bar, got %s", route.Id)
+	}
+}
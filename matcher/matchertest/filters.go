@@ -0,0 +1,194 @@
+package matchertest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/zalando/skipper/eskip"
+)
+
+// FilterMatcher an expectation on the filter chain of the matching route,
+// see Filter, HasFilter and InOrder
+type FilterMatcher interface {
+	// String describes the expectation like in eskip, eg. setPath("/v2")
+	String() string
+	// find the index of the filter at or after from satisfying the
+	// expectation, the last one for a sequence, -1 if none
+	find(chain []*eskip.Filter, from int) int
+	// mismatch the index of the filter of the chain closest to satisfy the
+	// expectation, eg. the same filter with other arguments, -1 if none,
+	// and why it doesn't
+	mismatch(chain []*eskip.Filter) (int, string)
+}
+
+// Filter expects the filter with exactly the arguments in the chain, the
+// numbers compared like in eskip, eg. Filter("status", 418)
+func Filter(name string, args ...interface{}) FilterMatcher {
+	return exactFilter{&eskip.Filter{Name: name, Args: args}}
+}
+
+// HasFilter expects the filter in the chain whatever its arguments
+func HasFilter(name string) FilterMatcher {
+	return namedFilter(name)
+}
+
+// InOrder expects the filters of the matchers in the chain in their order,
+// not necessarily one after the other, eg.
+// InOrder(HasFilter("oauthTokeninfoAllScope"), HasFilter("forwardToken"))
+func InOrder(matchers ...FilterMatcher) FilterMatcher {
+	return sequence(matchers)
+}
+
+type exactFilter struct{ filter *eskip.Filter }
+
+func (e exactFilter) String() string { return filterString(e.filter) }
+
+func (e exactFilter) find(chain []*eskip.Filter, from int) int {
+	want := e.String()
+	for i := from; i < len(chain); i++ {
+		if filterString(chain[i]) == want {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e exactFilter) mismatch(chain []*eskip.Filter) (int, string) {
+	for i, f := range chain {
+		if f.Name == e.filter.Name {
+			return i, fmt.Sprintf("%s has other arguments", filterString(f))
+		}
+	}
+	return -1, fmt.Sprintf("no %s filter", e.filter.Name)
+}
+
+type namedFilter string
+
+func (n namedFilter) String() string { return string(n) + "(...)" }
+
+func (n namedFilter) find(chain []*eskip.Filter, from int) int {
+	for i := from; i < len(chain); i++ {
+		if chain[i].Name == string(n) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (n namedFilter) mismatch(chain []*eskip.Filter) (int, string) {
+	return -1, fmt.Sprintf("no %s filter", string(n))
+}
+
+type sequence []FilterMatcher
+
+func (s sequence) String() string {
+	names := make([]string, len(s))
+	for i, m := range s {
+		names[i] = m.String()
+	}
+	return "in order " + strings.Join(names, ", ")
+}
+
+func (s sequence) find(chain []*eskip.Filter, from int) int {
+	last := -1
+	for _, m := range s {
+		if last = m.find(chain, from); last < 0 {
+			return -1
+		}
+		from = last + 1
+	}
+	return last
+}
+
+func (s sequence) mismatch(chain []*eskip.Filter) (int, string) {
+	from := 0
+	for i, m := range s {
+		next := m.find(chain, from)
+		if next >= 0 {
+			from = next + 1
+			continue
+		}
+		if i > 0 {
+			if before := m.find(chain, 0); before >= 0 {
+				return before, fmt.Sprintf("%s comes before %s", m, s[i-1])
+			}
+		}
+		return m.mismatch(chain)
+	}
+	return -1, ""
+}
+
+// RequireFilters stops the test when the filter chain of the matching route
+// doesn't satisfy all the matchers or no route matches
+func RequireFilters(t testing.TB, result matcher.TestResult, matchers ...FilterMatcher) {
+	t.Helper()
+	if msg, ok := checkFilters(result, matchers); !ok {
+		t.Fatal(msg)
+	}
+}
+
+// AssertFilters reports a failure when the filter chain of the matching
+// route doesn't satisfy all the matchers or no route matches, true if it
+// does. The message shows the whole chain with the first mismatch marked.
+func AssertFilters(t testing.TB, result matcher.TestResult, matchers ...FilterMatcher) bool {
+	t.Helper()
+	msg, ok := checkFilters(result, matchers)
+	if !ok {
+		t.Error(msg)
+	}
+	return ok
+}
+
+// checkFilters the failure message of the first matcher the filter chain
+// doesn't satisfy, false then
+func checkFilters(result matcher.TestResult, matchers []FilterMatcher) (string, bool) {
+	line := "request"
+	if attrs := result.Attributes(); attrs != nil {
+		line = rawRequestLine(attrs)
+	}
+	if err := result.Err(); err != nil {
+		return fmt.Sprintf("%s: the request couldn't be tested, the filters can't be checked: %v", line, err), false
+	}
+	route := result.Route()
+	if route == nil {
+		return fmt.Sprintf("%s: no matching route, the filters can't be checked", line), false
+	}
+
+	var chain []*eskip.Filter
+	for _, f := range result.Filters() {
+		chain = append(chain, f.Filter)
+	}
+	for _, m := range matchers {
+		if m.find(chain, 0) >= 0 {
+			continue
+		}
+		mark, why := m.mismatch(chain)
+		return fmt.Sprintf("%s: route %s: want %s, %s\n%s", line, route.Id, m, why, chainString(chain, mark)), false
+	}
+	return "", true
+}
+
+// chainString the filters of the chain one by line, the marked one with >
+func chainString(chain []*eskip.Filter, mark int) string {
+	if len(chain) == 0 {
+		return "filter chain: none"
+	}
+	lines := []string{"filter chain:"}
+	for i, f := range chain {
+		prefix := "    "
+		if i == mark {
+			prefix = "  > "
+		}
+		lines = append(lines, prefix+filterString(f))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// filterString formats a filter with its arguments like in eskip
+func filterString(f *eskip.Filter) string {
+	r := &eskip.Route{Filters: []*eskip.Filter{f}, BackendType: eskip.ShuntBackend}
+	// strip the match all and the backend, the route is only used to format the filter
+	return strings.TrimSuffix(strings.TrimPrefix(r.String(), "* -> "), " -> <shunt>")
+}
@@ -0,0 +1,72 @@
+package matchertest
+
+import (
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertFilters(t *testing.T) {
+	m, err := matcher.NewWith(matcher.WithRoutes(`orders: Path("/orders")
+  -> setRequestHeader("X-Team", "orders")
+  -> oauthTokeninfoAllScope("orders.read")
+  -> forwardToken("X-Token")
+  -> status(200)
+  -> <shunt>;`), matcher.WithAutoMockFilters(), matcher.WithTB(t))
+	require.NoError(t, err)
+	result := m.Test(&matcher.RequestAttributes{Path: "/orders"})
+
+	tb := &recordingTB{TB: t}
+	assert.True(t, AssertFilters(tb, result,
+		Filter("setRequestHeader", "X-Team", "orders"),
+		Filter("status", 200),
+		HasFilter("forwardToken"),
+		InOrder(HasFilter("oauthTokeninfoAllScope"), HasFilter("forwardToken")),
+		InOrder(HasFilter("setRequestHeader"), InOrder(HasFilter("forwardToken"), Filter("status", 200))),
+	))
+	assert.True(t, tb.run(func() { RequireFilters(tb, result, HasFilter("status")) }))
+	assert.Empty(t, tb.errors)
+
+	tests := []struct {
+		name    string
+		matcher FilterMatcher
+		want    string
+	}{
+		{"other arguments", Filter("status", 404), `GET /orders HTTP/1.1: route orders: want status(404), status(200) has other arguments
+filter chain:
+    setRequestHeader("X-Team", "orders")
+    oauthTokeninfoAllScope("orders.read")
+    forwardToken("X-Token")
+  > status(200)`},
+		{"missing", HasFilter("setPath"), `GET /orders HTTP/1.1: route orders: want setPath(...), no setPath filter
+filter chain:
+    setRequestHeader("X-Team", "orders")
+    oauthTokeninfoAllScope("orders.read")
+    forwardToken("X-Token")
+    status(200)`},
+		{"order", InOrder(HasFilter("forwardToken"), HasFilter("oauthTokeninfoAllScope")), `GET /orders HTTP/1.1: route orders: want in order forwardToken(...), oauthTokeninfoAllScope(...), oauthTokeninfoAllScope(...) comes before forwardToken(...)
+filter chain:
+    setRequestHeader("X-Team", "orders")
+  > oauthTokeninfoAllScope("orders.read")
+    forwardToken("X-Token")
+    status(200)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &recordingTB{TB: t}
+			assert.False(t, AssertFilters(tb, result, HasFilter("status"), tt.matcher))
+			assert.False(t, tb.fatal, "goes on")
+			assert.Equal(t, []string{tt.want}, tb.errors)
+
+			tb = &recordingTB{TB: t}
+			assert.False(t, tb.run(func() { RequireFilters(tb, result, tt.matcher) }), "stops the test")
+			assert.Equal(t, []string{tt.want}, tb.errors)
+		})
+	}
+
+	tb = &recordingTB{TB: t}
+	assert.False(t, AssertFilters(tb, m.Test(&matcher.RequestAttributes{Path: "/unknown"}), HasFilter("status")))
+	assert.Equal(t, []string{"GET /unknown HTTP/1.1: no matching route, the filters can't be checked"}, tb.errors)
+}
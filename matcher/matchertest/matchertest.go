@@ -0,0 +1,152 @@
+// Package matchertest provides the assertions of the Go tests of routes,
+// eg. in a table test:
+//
+//	m, err := matcher.New(&matcher.Options{RoutesFile: "routes.eskip", TB: t})
+//	require.NoError(t, err)
+//	matchertest.RequireMatch(t, m, &matcher.RequestAttributes{Path: "/orders"}, "orders")
+//	matchertest.RequireNoMatch(t, m, &matcher.RequestAttributes{Path: "/admin"})
+//
+// The Require functions stop the test with Fatal, the Assert ones report the
+// failure with Error and return false so that the test goes on. The failure
+// message tells the tested request line, the expected route and the matching
+// one, pretty printed, or the near misses without a matching route.
+package matchertest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/zalando/skipper/eskip"
+)
+
+// nearMisses number of near misses in the message of an unexpected no match
+const nearMisses = 3
+
+// RequireMatch stops the test when the request attributes don't match the
+// route with the id
+func RequireMatch(t testing.TB, m matcher.Matcher, attrs *matcher.RequestAttributes, wantRouteID string) {
+	t.Helper()
+	if msg, ok := checkMatch(m, attrs, wantRouteID); !ok {
+		t.Fatal(msg)
+	}
+}
+
+// AssertMatch reports a failure when the request attributes don't match the
+// route with the id, true if they do
+func AssertMatch(t testing.TB, m matcher.Matcher, attrs *matcher.RequestAttributes, wantRouteID string) bool {
+	t.Helper()
+	msg, ok := checkMatch(m, attrs, wantRouteID)
+	if !ok {
+		t.Error(msg)
+	}
+	return ok
+}
+
+// RequireNoMatch stops the test when a route matches the request attributes
+func RequireNoMatch(t testing.TB, m matcher.Matcher, attrs *matcher.RequestAttributes) {
+	t.Helper()
+	if msg, ok := checkNoMatch(m, attrs); !ok {
+		t.Fatal(msg)
+	}
+}
+
+// AssertNoMatch reports a failure when a route matches the request
+// attributes, true if none does
+func AssertNoMatch(t testing.TB, m matcher.Matcher, attrs *matcher.RequestAttributes) bool {
+	t.Helper()
+	msg, ok := checkNoMatch(m, attrs)
+	if !ok {
+		t.Error(msg)
+	}
+	return ok
+}
+
+// MustTest tests the request attributes, it stops the test when the request
+// couldn't be tested, eg. with a closed matcher:
+//
+//	route := matchertest.MustTest(t, m, attrs).MustRoute()
+func MustTest(t testing.TB, m matcher.Matcher, attrs *matcher.RequestAttributes) matcher.TestResult {
+	t.Helper()
+	result := m.Test(attrs)
+	if err := result.Err(); err != nil {
+		t.Fatalf("%s: the request couldn't be tested: %v", requestLine(result, attrs), err)
+	}
+	return result
+}
+
+// checkMatch the failure message when the route with the id doesn't match,
+// false then
+func checkMatch(m matcher.Matcher, attrs *matcher.RequestAttributes, wantRouteID string) (string, bool) {
+	result := m.Test(attrs)
+	line := requestLine(result, attrs)
+	if err := result.Err(); err != nil {
+		return fmt.Sprintf("%s: want route %s, the request couldn't be tested: %v", line, wantRouteID, err), false
+	}
+	route := result.Route()
+	if route == nil {
+		msg := fmt.Sprintf("%s: want route %s, got no match", line, wantRouteID)
+		if misses := explain(m, result); misses != "" {
+			msg += "\nnear misses:\n" + misses
+		}
+		return msg, false
+	}
+	if route.Id != wantRouteID {
+		return fmt.Sprintf("%s: want route %s, got route %s:\n%s", line, wantRouteID, route.Id, prettyRoute(route)), false
+	}
+	return "", true
+}
+
+// checkNoMatch the failure message when a route matches, false then
+func checkNoMatch(m matcher.Matcher, attrs *matcher.RequestAttributes) (string, bool) {
+	result := m.Test(attrs)
+	line := requestLine(result, attrs)
+	if err := result.Err(); err != nil {
+		return fmt.Sprintf("%s: want no match, the request couldn't be tested: %v", line, err), false
+	}
+	if route := result.Route(); route != nil {
+		return fmt.Sprintf("%s: want no match, got route %s:\n%s", line, route.Id, prettyRoute(route)), false
+	}
+	return "", true
+}
+
+// requestLine the first line of the tested request, eg. GET /orders HTTP/1.1
+func requestLine(result matcher.TestResult, attrs *matcher.RequestAttributes) string {
+	if normalized := result.Attributes(); normalized != nil {
+		attrs = normalized
+	}
+	return rawRequestLine(attrs)
+}
+
+// rawRequestLine the first line of the raw request of the attributes
+func rawRequestLine(attrs *matcher.RequestAttributes) string {
+	return strings.SplitN(matcher.RawRequest(attrs, nil), "\n", 2)[0]
+}
+
+// explain the closest routes not matching the request, one by line with the
+// failed predicates
+func explain(m matcher.Matcher, result matcher.TestResult) string {
+	attrs := result.Attributes()
+	if attrs == nil {
+		return ""
+	}
+	var lines []string
+	for _, miss := range m.Explain(attrs, nearMisses) {
+		var failed []string
+		for _, v := range miss.Verdicts {
+			if !v.Matched {
+				failed = append(failed, v.Predicate)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("  %s, %d of %d predicate(s) failed: %s",
+			miss.RouteID, miss.Failed, len(miss.Verdicts), strings.Join(failed, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// prettyRoute the route pretty printed in eskip, indented
+func prettyRoute(r *eskip.Route) string {
+	s := eskip.Print(eskip.PrettyPrintInfo{Pretty: true, IndentStr: "  "}, r)
+	return "  " + strings.Replace(strings.TrimSpace(s), "\n", "\n  ", -1)
+}
@@ -0,0 +1,133 @@
+package matchertest
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTB testing.TB keeping the failures, Fatal stops the goroutine
+// of the helper like the testing package does
+type recordingTB struct {
+	testing.TB
+	mu      sync.Mutex
+	errors  []string
+	fatal   bool
+	helpers int
+}
+
+func (r *recordingTB) Helper() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.helpers++
+}
+
+func (r *recordingTB) Error(args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func (r *recordingTB) Fatal(args ...interface{}) {
+	r.Error(args...)
+	r.mu.Lock()
+	r.fatal = true
+	r.mu.Unlock()
+	runtime.Goexit()
+}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.Fatal(fmt.Sprintf(format, args...))
+}
+
+// run calls the assertion in a goroutine of its own, true if it returned
+// without Fatal
+func (r *recordingTB) run(assertion func()) bool {
+	returned := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assertion()
+		returned = true
+	}()
+	<-done
+	return returned
+}
+
+func newTestMatcher(t *testing.T) matcher.Matcher {
+	m, err := matcher.New(&matcher.Options{RoutesFile: "../testdata/routes.eskip", MockFilters: []string{"customfilter"}, TB: t})
+	require.NoError(t, err)
+	return m
+}
+
+func TestMatch(t *testing.T) {
+	m := newTestMatcher(t)
+
+	tb := &recordingTB{TB: t}
+	assert.True(t, tb.run(func() { RequireMatch(tb, m, &matcher.RequestAttributes{Path: "/bar"}, "bar") }))
+	assert.True(t, AssertMatch(tb, m, &matcher.RequestAttributes{Path: "/bar"}, "bar"))
+	assert.Empty(t, tb.errors)
+	assert.Equal(t, 2, tb.helpers)
+
+	tb = &recordingTB{TB: t}
+	assert.False(t, tb.run(func() { RequireMatch(tb, m, &matcher.RequestAttributes{Method: "POST", Path: "/foo/baz"}, "bar") }), "stops the test")
+	assert.True(t, tb.fatal)
+	require.Len(t, tb.errors, 1)
+	assert.Equal(t, "POST /foo/baz HTTP/1.1: want route bar, got route foo:\n  foo: PathSubtree(\"/foo\")\n    -> <shunt>;", tb.errors[0])
+
+	tb = &recordingTB{TB: t}
+	assert.False(t, AssertMatch(tb, m, &matcher.RequestAttributes{Path: "/baz"}, "bar"))
+	assert.False(t, tb.fatal, "goes on")
+	require.Len(t, tb.errors, 1)
+	assert.Contains(t, tb.errors[0], "GET /baz HTTP/1.1: want route bar, got no match\nnear misses:\n")
+	assert.Contains(t, tb.errors[0], "\n  bar, 1 of 1 predicate(s) failed: Path(\"/bar\")")
+
+	m.Close()
+	tb = &recordingTB{TB: t}
+	assert.False(t, AssertMatch(tb, m, &matcher.RequestAttributes{Path: "/bar"}, "bar"))
+	require.Len(t, tb.errors, 1)
+	assert.Contains(t, tb.errors[0], "GET /bar HTTP/1.1: want route bar, the request couldn't be tested: ")
+}
+
+func TestNoMatch(t *testing.T) {
+	m := newTestMatcher(t)
+
+	tb := &recordingTB{TB: t}
+	assert.True(t, tb.run(func() { RequireNoMatch(tb, m, &matcher.RequestAttributes{Path: "/baz"}) }))
+	assert.True(t, AssertNoMatch(tb, m, &matcher.RequestAttributes{Path: "/baz"}))
+	assert.Empty(t, tb.errors)
+
+	tb = &recordingTB{TB: t}
+	assert.False(t, tb.run(func() { RequireNoMatch(tb, m, &matcher.RequestAttributes{Path: "/bar"}) }), "stops the test")
+	require.Len(t, tb.errors, 1)
+	assert.Equal(t, "GET /bar HTTP/1.1: want no match, got route bar:\n  bar: Path(\"/bar\")\n    -> <shunt>;", tb.errors[0])
+
+	tb = &recordingTB{TB: t}
+	assert.False(t, AssertNoMatch(tb, m, &matcher.RequestAttributes{Path: "/bar", Host: "api.example.org"}))
+	assert.False(t, tb.fatal, "goes on")
+	require.Len(t, tb.errors, 1)
+	assert.Contains(t, tb.errors[0], "GET /bar HTTP/1.1: want no match, got route bar:")
+}
+
+func TestMustTest(t *testing.T) {
+	m := newTestMatcher(t)
+
+	tb := &recordingTB{TB: t}
+	var result matcher.TestResult
+	assert.True(t, tb.run(func() { result = MustTest(tb, m, &matcher.RequestAttributes{Path: "/bar"}) }))
+	assert.Equal(t, "bar", result.MustRoute().Id)
+	assert.Empty(t, tb.errors)
+	assert.True(t, tb.run(func() { result = MustTest(tb, m, &matcher.RequestAttributes{Path: "/baz"}) }), "no match isn't an error")
+	assert.Nil(t, result.Route())
+
+	m.Close()
+	tb = &recordingTB{TB: t}
+	assert.False(t, tb.run(func() { MustTest(tb, m, &matcher.RequestAttributes{Path: "/bar"}) }), "stops the test")
+	require.Len(t, tb.errors, 1)
+	assert.Contains(t, tb.errors[0], "GET /bar HTTP/1.1: the request couldn't be tested: ")
+}
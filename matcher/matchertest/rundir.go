@@ -0,0 +1,114 @@
+package matchertest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/suite"
+)
+
+// UpdateSnapshotsEnv environment variable making RunDir write the snapshot
+// files of the cases instead of comparing them when it's not empty, eg.
+// ESKIP_MATCH_UPDATE_SNAPSHOTS=1 go test ./...
+const UpdateSnapshotsEnv = "ESKIP_MATCH_UPDATE_SNAPSHOTS"
+
+// RunDir runs the cases of the fixture files under dir, see package suite,
+// each one as a subtest named after the file, relative to dir, and the case,
+// eg. nested/search.yaml/search, so that go test -run selects them. The
+// cases of a fixture file with parallel: true run in parallel. The test
+// fails without running any case when a fixture file doesn't load or there
+// is none.
+func RunDir(t *testing.T, m matcher.Matcher, dir string) {
+	t.Helper()
+	suites, err := loadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := os.Getenv(UpdateSnapshotsEnv) != ""
+	for _, s := range suites {
+		s := s
+		name, err := filepath.Rel(dir, s.File)
+		if err != nil {
+			name = s.File
+		}
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			if s.Parallel {
+				t.Parallel()
+			}
+			for _, c := range s.Cases {
+				c := c
+				t.Run(c.Name, func(t *testing.T) {
+					if s.Parallel {
+						t.Parallel()
+					}
+					runCase(t, m, s, c, update)
+				})
+			}
+		})
+	}
+}
+
+// runCase runs the case like the suite command, the failure tells the
+// request line
+func runCase(t *testing.T, m matcher.Matcher, s *suite.Suite, c suite.Case, update bool) {
+	t.Helper()
+	single := &suite.Suite{File: s.File, Name: s.Name, Cases: []suite.Case{c}}
+	res := suite.Run(m, []*suite.Suite{single}, &suite.RunOptions{UpdateSnapshots: update})
+	if cr := res.Cases[0]; !cr.Passed {
+		t.Errorf("%s: %s", rawRequestLine(&c.Request), cr.Message)
+	}
+}
+
+// loadDir loads the yaml and json fixture files under dir in lexical order,
+// the json files the fixtures name as snapshots excluded
+func loadDir(dir string) ([]*suite.Suite, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			if !info.IsDir() {
+				files = append(files, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fixture directory %s: %v", dir, err)
+	}
+
+	var suites []*suite.Suite
+	failed := map[string]error{}
+	snapshots := map[string]bool{}
+	for _, file := range files {
+		s, err := suite.Load(file)
+		if err != nil {
+			failed[file] = err
+			continue
+		}
+		suites = append(suites, s)
+		for _, c := range s.Cases {
+			switch {
+			case filepath.IsAbs(c.Expect.Snapshot):
+				snapshots[filepath.Clean(c.Expect.Snapshot)] = true
+			case c.Expect.Snapshot != "":
+				snapshots[filepath.Join(filepath.Dir(file), c.Expect.Snapshot)] = true
+			}
+		}
+	}
+	for _, file := range files {
+		if err, ok := failed[file]; ok && !snapshots[filepath.Clean(file)] {
+			return nil, fmt.Errorf("fixture file doesn't load: %v", err)
+		}
+	}
+	if len(suites) == 0 {
+		return nil, fmt.Errorf("no fixture file under %s", dir)
+	}
+	return suites, nil
+}
@@ -0,0 +1,40 @@
+package matchertest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDir(t *testing.T) {
+	RunDir(t, newTestMatcher(t), "testdata/routing")
+}
+
+func TestLoadDir(t *testing.T) {
+	suites, err := loadDir("testdata/routing")
+	require.NoError(t, err)
+	if assert.Len(t, suites, 2, "the snapshots aren't fixtures") {
+		assert.Equal(t, filepath.Join("testdata", "routing", "bar.yaml"), suites[0].File)
+		assert.True(t, suites[0].Parallel)
+		assert.Equal(t, filepath.Join("testdata", "routing", "nested", "search.json"), suites[1].File)
+		assert.False(t, suites[1].Parallel)
+	}
+
+	_, err = loadDir("testdata/broken")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "fixture file doesn't load: "+filepath.Join("testdata", "broken", "wrong-types.yaml")+": ")
+	}
+
+	empty, err := ioutil.TempDir("", "fixtures-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(empty)
+	_, err = loadDir(empty)
+	assert.EqualError(t, err, "no fixture file under "+empty)
+
+	_, err = loadDir("testdata/missing")
+	assert.Error(t, err)
+}
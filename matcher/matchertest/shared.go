@@ -0,0 +1,96 @@
+package matchertest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+// shared the matchers of Shared by options fingerprint
+var shared = struct {
+	mu      sync.Mutex
+	entries map[string]*sharedEntry
+}{entries: map[string]*sharedEntry{}}
+
+// sharedEntry a matcher of Shared with the number of tests using it
+type sharedEntry struct {
+	once sync.Once
+	m    matcher.Matcher
+	err  error
+	// refs tests holding the entry, guarded by the shared mutex
+	refs int
+}
+
+// Shared returns the matcher of the options shared by the tests running at
+// the same time, created once by the first caller, eg. a test and its
+// parallel subtests:
+//
+//	func TestRoutes(t *testing.T) {
+//		m := matchertest.Shared(t, routesOptions)
+//		t.Run("orders", func(t *testing.T) {
+//			t.Parallel()
+//			matchertest.RequireMatch(t, matchertest.Shared(t, routesOptions), attrs, "orders")
+//		})
+//		...
+//	}
+//
+// The options are the same when their values are, the pointers, eg. the
+// custom filters, and the functions compared by address. The matcher is
+// closed once all the tests which got it completed, the outermost one last,
+// the next test creates it again, so -count and the parallel tests don't
+// share a closed matcher. Closing the returned matcher does nothing. The
+// test fails when the matcher can't be created. Options.TB can't be set,
+// the matcher outlives the test.
+func Shared(tb testing.TB, o *matcher.Options) matcher.Matcher {
+	tb.Helper()
+	if o.TB != nil {
+		tb.Fatal(errors.New("a shared matcher can't have Options.TB, use the t of each test with the matchertest assertions"))
+	}
+	key := fingerprint(o)
+	shared.mu.Lock()
+	e, ok := shared.entries[key]
+	if !ok {
+		e = &sharedEntry{}
+		shared.entries[key] = e
+	}
+	e.refs++
+	shared.mu.Unlock()
+	tb.Cleanup(func() { release(key, e) })
+
+	e.once.Do(func() { e.m, e.err = matcher.New(o) })
+	if e.err != nil {
+		tb.Fatalf("shared matcher: %v", e.err)
+	}
+	return sharedMatcher{e.m}
+}
+
+// release releases the entry of a completed test, closing its matcher
+// with the last one
+func release(key string, e *sharedEntry) {
+	shared.mu.Lock()
+	e.refs--
+	last := e.refs == 0
+	if last && shared.entries[key] == e {
+		delete(shared.entries, key)
+	}
+	shared.mu.Unlock()
+	if last && e.m != nil {
+		e.m.Close()
+	}
+}
+
+// fingerprint the options values, the pointers and the functions by address
+func fingerprint(o *matcher.Options) string {
+	return fmt.Sprintf("%#v", *o)
+}
+
+// sharedMatcher a matcher of Shared, closed once no test uses it
+type sharedMatcher struct {
+	matcher.Matcher
+}
+
+// Close does nothing, the matcher is closed by the cleanup of the tests
+func (sharedMatcher) Close() error { return nil }
@@ -0,0 +1,76 @@
+package matchertest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sharedOptions() *matcher.Options {
+	return &matcher.Options{RoutesFile: "../testdata/routes.eskip", MockFilters: []string{"customfilter"}}
+}
+
+func TestShared(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		matchers = map[matcher.Matcher]bool{}
+	)
+	t.Run("parallel", func(t *testing.T) {
+		parent := Shared(t, sharedOptions())
+		matchers[parent.(sharedMatcher).Matcher] = true
+		for i := 0; i < 50; i++ {
+			i := i
+			t.Run(fmt.Sprintf("case%d", i), func(t *testing.T) {
+				t.Parallel()
+				m := Shared(t, sharedOptions())
+				mu.Lock()
+				matchers[m.(sharedMatcher).Matcher] = true
+				mu.Unlock()
+				if i%2 == 0 {
+					RequireMatch(t, m, &matcher.RequestAttributes{Path: "/bar"}, "bar")
+				} else {
+					RequireNoMatch(t, m, &matcher.RequestAttributes{Path: fmt.Sprintf("/unknown/%d", i)})
+				}
+				assert.NoError(t, m.Close(), "closing does nothing")
+			})
+		}
+	})
+	assert.Len(t, matchers, 1, "one matcher for the same options")
+
+	shared.mu.Lock()
+	assert.Empty(t, shared.entries, "released by the outermost test")
+	shared.mu.Unlock()
+	for m := range matchers {
+		assert.True(t, errors.Is(m.Test(&matcher.RequestAttributes{Path: "/bar"}).Err(), matcher.ErrClosed))
+	}
+
+	t.Run("created again", func(t *testing.T) {
+		m := Shared(t, sharedOptions())
+		assert.False(t, matchers[m.(sharedMatcher).Matcher])
+		RequireMatch(t, m, &matcher.RequestAttributes{Path: "/bar"}, "bar")
+	})
+}
+
+func TestSharedOptions(t *testing.T) {
+	a := Shared(t, sharedOptions())
+	o := sharedOptions()
+	o.IgnoreTrailingSlash = true
+	b := Shared(t, o)
+	assert.NotEqual(t, a.(sharedMatcher).Matcher, b.(sharedMatcher).Matcher, "other options")
+	RequireMatch(t, b, &matcher.RequestAttributes{Path: "/bar/"}, "bar")
+	RequireNoMatch(t, a, &matcher.RequestAttributes{Path: "/bar/"})
+
+	tb := &recordingTB{TB: t}
+	assert.False(t, tb.run(func() { Shared(tb, &matcher.Options{RoutesFile: "missing.eskip"}) }))
+	require.Len(t, tb.errors, 1)
+	assert.Contains(t, tb.errors[0], "shared matcher: ")
+
+	tb = &recordingTB{TB: t}
+	assert.False(t, tb.run(func() { Shared(tb, &matcher.Options{RoutesFile: "../testdata/routes.eskip", TB: t}) }))
+	assert.Equal(t, []string{"a shared matcher can't have Options.TB, use the t of each test with the matchertest assertions"}, tb.errors)
+}
@@ -0,0 +1,204 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// Classes of a MergeFinding
+const (
+	// MergeBothChanged a route of the base changed differently on both
+	// sides, or a route id added on both sides with different definitions
+	MergeBothChanged = "both-changed"
+	// MergeDeleteModify a route of the base removed on one side and changed
+	// or renamed on the other
+	MergeDeleteModify = "delete-modify"
+	// MergeOverlap routes added on both sides matching the same requests
+	MergeOverlap = "overlap"
+)
+
+// MergeReport result of MergeAnalysis
+type MergeReport struct {
+	// Findings the conflicts, sorted by class and route ids
+	Findings []MergeFinding `json:"findings"`
+}
+
+// MergeFinding a conflict between the changes of both sides of a merge
+type MergeFinding struct {
+	// Class MergeBothChanged, MergeDeleteModify or MergeOverlap
+	Class string `json:"class"`
+	// Base id of the route in the base, empty when added on both sides
+	Base string `json:"base,omitempty"`
+	// Ours id of the route on our side, empty when removed
+	Ours string `json:"ours,omitempty"`
+	// Theirs id of the route on their side, empty when removed
+	Theirs string `json:"theirs,omitempty"`
+	// OursChanges the changes of our route from the base one
+	OursChanges []FieldChange `json:"oursChanges,omitempty"`
+	// TheirsChanges the changes of their route from the base one, from our
+	// route for a route id added on both sides
+	TheirsChanges []FieldChange `json:"theirsChanges,omitempty"`
+	// Witness a request both the routes added on both sides match
+	Witness *RequestAttributes `json:"witness,omitempty"`
+	// Message what to review
+	Message string `json:"message"`
+}
+
+// mergeSide the fate of the routes of the base on a side of the merge
+type mergeSide struct {
+	routes map[string]*eskip.Route
+	// renamed the new ids of the renamed routes by base id
+	renamed map[string]string
+	// changes of the changed and renamed routes by base id
+	changes map[string][]FieldChange
+	// added the ids of the added routes, the renamed ones excluded
+	added []string
+}
+
+func newMergeSide(base, side []*eskip.Route) *mergeSide {
+	d := RoutesDiff(base, side)
+	s := &mergeSide{routes: routesByID(side), renamed: make(map[string]string), changes: make(map[string][]FieldChange), added: d.Added}
+	for _, c := range d.Changed {
+		s.changes[c.ID] = c.Changes
+	}
+	for _, r := range d.Renamed {
+		s.renamed[r.Old] = r.New
+		s.changes[r.Old] = r.Changes
+	}
+	return s
+}
+
+// id of the route of the base on the side, empty when removed
+func (s *mergeSide) id(base string) string {
+	if id, ok := s.renamed[base]; ok {
+		return id
+	}
+	if _, ok := s.routes[base]; ok {
+		return base
+	}
+	return ""
+}
+
+// modified true if the route of the base changed or was renamed on the side
+func (s *mergeSide) modified(base string) bool {
+	_, ok := s.renamed[base]
+	return ok || len(s.changes[base]) > 0
+}
+
+// MergeAnalysis checks whether the changes of two branches of a routes file
+// compose, eg. after git merged the text of both: the routes of the base
+// changed on both sides, by id or renamed, see RoutesDiff, to different
+// definitions, the routes removed on a side and changed on the other, and
+// the routes added on both sides matching the same requests, with a witness
+// request generated for both of them. The routes with predicates the
+// generator can't satisfy, eg. Traffic or custom ones, have no witness.
+func MergeAnalysis(base, ours, theirs []*eskip.Route) *MergeReport {
+	o, t := newMergeSide(base, ours), newMergeSide(base, theirs)
+	report := &MergeReport{Findings: []MergeFinding{}}
+
+	for id := range routesByID(base) {
+		oursID, theirsID := o.id(id), t.id(id)
+		f := MergeFinding{Base: id, Ours: oursID, Theirs: theirsID, OursChanges: o.changes[id], TheirsChanges: t.changes[id]}
+		switch {
+		case oursID == "" && theirsID == "":
+		case oursID == "" && t.modified(id):
+			f.Class = MergeDeleteModify
+			f.Message = fmt.Sprintf("route %s is removed by ours and %s by theirs", id, modification(id, theirsID))
+		case theirsID == "" && o.modified(id):
+			f.Class = MergeDeleteModify
+			f.Message = fmt.Sprintf("route %s is removed by theirs and %s by ours", id, modification(id, oursID))
+		case oursID != "" && theirsID != "" && o.modified(id) && t.modified(id):
+			if oursID == theirsID && len(routeFieldChanges(o.routes[oursID], t.routes[theirsID])) == 0 {
+				// the same change on both sides
+				continue
+			}
+			f.Class = MergeBothChanged
+			f.Message = fmt.Sprintf("route %s is %s by ours and %s by theirs", id, modification(id, oursID), modification(id, theirsID))
+		}
+		if f.Class != "" {
+			report.Findings = append(report.Findings, f)
+		}
+	}
+
+	specs := mapPredicateSpecs(predicateSpecs(nil, false))
+	for _, oursID := range o.added {
+		for _, theirsID := range t.added {
+			a, b := o.routes[oursID], t.routes[theirsID]
+			if oursID == theirsID {
+				if changes := routeFieldChanges(a, b); len(changes) > 0 {
+					report.Findings = append(report.Findings, MergeFinding{
+						Class:         MergeBothChanged,
+						Ours:          oursID,
+						Theirs:        theirsID,
+						TheirsChanges: changes,
+						Message:       fmt.Sprintf("route %s is added by both with different definitions", oursID),
+					})
+				}
+				continue
+			}
+			if witness, ok := ambiguityWitness(a, b, specs); ok {
+				report.Findings = append(report.Findings, MergeFinding{
+					Class:   MergeOverlap,
+					Ours:    oursID,
+					Theirs:  theirsID,
+					Witness: witness,
+					Message: fmt.Sprintf("route %s added by ours and route %s added by theirs can match the same requests, eg. %s", oursID, theirsID, witnessString(witness)),
+				})
+			}
+		}
+	}
+
+	sort.Slice(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Class != b.Class {
+			return a.Class < b.Class
+		}
+		if a.Base != b.Base {
+			return a.Base < b.Base
+		}
+		if a.Ours != b.Ours {
+			return a.Ours < b.Ours
+		}
+		return a.Theirs < b.Theirs
+	})
+	return report
+}
+
+// modification describes the change of a route, eg. renamed to orders_v2
+func modification(base, id string) string {
+	if id != base {
+		return "renamed to " + id
+	}
+	return "changed"
+}
+
+// WriteText writes a line for each finding followed by the changes of both
+// sides, eg.
+// both-changed: route orders is changed by ours and changed by theirs
+func (r *MergeReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "%d merge conflict(s)\n", len(r.Findings))
+	for _, f := range r.Findings {
+		fmt.Fprintf(w, "%s: %s\n", f.Class, f.Message)
+		for _, side := range []struct {
+			name    string
+			changes []FieldChange
+		}{{"ours", f.OursChanges}, {"theirs", f.TheirsChanges}} {
+			for _, c := range side.changes {
+				fmt.Fprintf(w, "    %s: %s %s: %s\n", side.name, c.Field, c.Change, fieldChangeText(c))
+			}
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report in indented json
+func (r *MergeReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
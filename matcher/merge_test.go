@@ -0,0 +1,93 @@
+package matcher
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/eskip"
+)
+
+const (
+	mergeBase = `orders: Path("/orders") -> setPath("/v1") -> "https://orders.example.org";
+search: Path("/search") -> "https://search.example.org";
+legacy: Path("/legacy") -> "https://legacy.example.org";
+health: Path("/health") -> <shunt>;
+stock: Path("/stock") -> "https://stock.example.org";`
+	// orders changed, legacy removed, stock renamed, api and cart added
+	mergeOurs = `orders: Path("/orders") -> setPath("/v2") -> "https://orders.example.org";
+search: Path("/search") -> "https://search.example.org";
+health: Path("/health") -> <shunt>;
+inventory: Path("/stock") -> "https://stock.example.org";
+api: PathSubtree("/api") -> "https://api.example.org";
+cart: Path("/cart") -> "https://cart.example.org";`
+	// orders changed too, legacy changed, stock changed, api_items and
+	// cart added, health removed
+	mergeTheirs = `orders: Path("/orders") -> setPath("/v3") -> "https://orders.example.org";
+search: Path("/search") -> "https://search.example.org";
+legacy: Path("/legacy") -> "https://legacy-v2.example.org";
+stock: Path("/stock") -> compress() -> "https://stock.example.org";
+api_items: Path("/api/items") -> "https://items.example.org";
+cart: Path("/cart") -> "https://cart-v2.example.org";`
+)
+
+func parseMergeRoutes(t *testing.T, doc string) []*eskip.Route {
+	routes, err := eskip.Parse(doc)
+	require.NoError(t, err)
+	return routes
+}
+
+func TestMergeAnalysis(t *testing.T) {
+	report := MergeAnalysis(parseMergeRoutes(t, mergeBase), parseMergeRoutes(t, mergeOurs), parseMergeRoutes(t, mergeTheirs))
+
+	type conflict struct{ class, base, ours, theirs string }
+	var conflicts []conflict
+	for _, f := range report.Findings {
+		conflicts = append(conflicts, conflict{f.Class, f.Base, f.Ours, f.Theirs})
+	}
+	assert.Equal(t, []conflict{
+		{MergeBothChanged, "", "cart", "cart"},
+		{MergeBothChanged, "orders", "orders", "orders"},
+		{MergeBothChanged, "stock", "inventory", "stock"},
+		{MergeDeleteModify, "legacy", "", "legacy"},
+		{MergeOverlap, "", "api", "api_items"},
+	}, conflicts, "health is only removed by theirs")
+
+	byClass := make(map[string]MergeFinding)
+	for _, f := range report.Findings {
+		byClass[f.Class+" "+f.Base] = f
+	}
+	orders := byClass[MergeBothChanged+" orders"]
+	assert.Equal(t, "route orders is changed by ours and changed by theirs", orders.Message)
+	assert.Equal(t, []FieldChange{{Field: FieldFilter, Change: RouteChanged, Old: `setPath("/v1")`, New: `setPath("/v2")`}}, orders.OursChanges)
+	assert.Equal(t, []FieldChange{{Field: FieldFilter, Change: RouteChanged, Old: `setPath("/v1")`, New: `setPath("/v3")`}}, orders.TheirsChanges)
+	assert.Equal(t, "route stock is renamed to inventory by ours and changed by theirs", byClass[MergeBothChanged+" stock"].Message)
+	assert.Equal(t, "route legacy is removed by ours and changed by theirs", byClass[MergeDeleteModify+" legacy"].Message)
+	overlap := byClass[MergeOverlap+" "]
+	require.NotNil(t, overlap.Witness)
+	assert.Equal(t, "/api/items", overlap.Witness.Path)
+
+	var b bytes.Buffer
+	require.NoError(t, report.WriteText(&b))
+	assert.Contains(t, b.String(), "5 merge conflict(s)\n")
+	assert.Contains(t, b.String(), "both-changed: route orders is changed by ours and changed by theirs\n    ours: filter changed: setPath(\"/v1\") -> setPath(\"/v2\")\n    theirs: filter changed: setPath(\"/v1\") -> setPath(\"/v3\")\n")
+	assert.Contains(t, b.String(), "overlap: route api added by ours and route api_items added by theirs can match the same requests, eg. GET /api/items\n")
+}
+
+func TestMergeAnalysisNoConflicts(t *testing.T) {
+	base := parseMergeRoutes(t, mergeBase)
+	// the same change on both sides and changes of different routes compose
+	ours := parseMergeRoutes(t, `orders: Path("/orders") -> setPath("/v2") -> "https://orders.example.org";
+search: Path("/search") -> "https://search-v2.example.org";
+health: Path("/health") -> <shunt>;
+stock: Path("/stock") -> "https://stock.example.org";
+api: PathSubtree("/api") -> "https://api.example.org";`)
+	theirs := parseMergeRoutes(t, `orders: Path("/orders") -> setPath("/v2") -> "https://orders.example.org";
+search: Path("/search") -> "https://search.example.org";
+legacy: Path("/legacy") -> "https://legacy.example.org";
+stock: Path("/stock") -> "https://stock.example.org";
+cart: Path("/cart") -> "https://cart.example.org";`)
+	assert.Empty(t, MergeAnalysis(base, ours, theirs).Findings)
+	assert.Empty(t, MergeAnalysis(base, base, base).Findings)
+}
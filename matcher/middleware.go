@@ -0,0 +1,60 @@
+package matcher
+
+import (
+	"net/http"
+)
+
+// Default names of the response headers set by Middleware
+const (
+	DefaultRouteHeader   = "X-Eskip-Match-Route"
+	DefaultBackendHeader = "X-Eskip-Match-Backend"
+)
+
+// MiddlewareOptions options of MiddlewareWith
+type MiddlewareOptions struct {
+	// RouteHeader name of the response header with the id of the matching
+	// route, DefaultRouteHeader when empty
+	RouteHeader string
+	// BackendHeader name of the response header with the backend of the
+	// matching route, the address of a network backend or a placeholder
+	// like <shunt>, DefaultBackendHeader when empty
+	BackendHeader string
+	// OnResult called with each request and its result before the next
+	// handler, eg. to log them, optional. The request must not be changed.
+	OnResult func(r *http.Request, res TestResult)
+}
+
+// Middleware returns a middleware annotating the responses with the route
+// the requests would match, see MiddlewareWith
+func Middleware(m Matcher) func(http.Handler) http.Handler {
+	return MiddlewareWith(m, MiddlewareOptions{})
+}
+
+// MiddlewareWith returns a middleware testing every request against the
+// matcher and setting the response headers of the options to the matching
+// route and its backend before calling the next handler. The headers aren't
+// set when no route matches. Only the method, the host, the path, the query
+// and the headers are tested, the body isn't read.
+func MiddlewareWith(m Matcher, o MiddlewareOptions) func(http.Handler) http.Handler {
+	routeHeader := o.RouteHeader
+	if routeHeader == "" {
+		routeHeader = DefaultRouteHeader
+	}
+	backendHeader := o.BackendHeader
+	if backendHeader == "" {
+		backendHeader = DefaultBackendHeader
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := m.Test(requestAttributes(r))
+			if route := res.Route(); route != nil {
+				w.Header().Set(routeHeader, route.Id)
+				w.Header().Set(backendHeader, backendString(route))
+			}
+			if o.OnResult != nil {
+				o.OnResult(r, res)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
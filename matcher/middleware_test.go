@@ -0,0 +1,132 @@
+package matcher
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMiddlewareMatcher(t testing.TB) Matcher {
+	file := writeRoutes(t, `orders: Path("/orders") && Method("POST") -> "https://orders.example.org";
+search: Path("/search") && QueryParam("q") && Header("Accept", "application/json") -> <shunt>;
+`)
+	defer os.Remove(file)
+	m, err := New(&Options{RoutesFile: file})
+	require.NoError(t, err)
+	return m
+}
+
+func TestMiddleware(t *testing.T) {
+	m := newMiddlewareMatcher(t)
+	defer m.Close()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	h := Middleware(m)(next)
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		headers map[string]string
+		route   string
+		backend string
+	}{
+		{
+			name:    "network backend",
+			method:  "POST",
+			target:  "http://api.example.org/orders",
+			route:   "orders",
+			backend: "https://orders.example.org",
+		},
+		{
+			name:    "query and headers",
+			method:  "GET",
+			target:  "http://api.example.org/search?q=shoes",
+			headers: map[string]string{"Accept": "application/json"},
+			route:   "search",
+			backend: "<shunt>",
+		},
+		{
+			name:   "no match",
+			method: "GET",
+			target: "http://api.example.org/orders",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, "ok", rec.Body.String())
+			assert.Equal(t, tt.route, rec.Header().Get(DefaultRouteHeader))
+			assert.Equal(t, tt.backend, rec.Header().Get(DefaultBackendHeader))
+			if tt.route == "" {
+				assert.NotContains(t, rec.Header(), DefaultRouteHeader)
+				assert.NotContains(t, rec.Header(), DefaultBackendHeader)
+			}
+		})
+	}
+}
+
+func TestMiddlewareWith(t *testing.T) {
+	m := newMiddlewareMatcher(t)
+	defer m.Close()
+
+	var results []string
+	body := bytes.Repeat([]byte("x"), 8<<20)
+	var read int
+	h := MiddlewareWith(m, MiddlewareOptions{
+		RouteHeader:   "X-Route",
+		BackendHeader: "X-Backend",
+		OnResult: func(r *http.Request, res TestResult) {
+			id := "none"
+			if res.Route() != nil {
+				id = res.Route().Id
+			}
+			results = append(results, r.URL.Path+"="+id)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		read = len(b)
+	}))
+
+	// the body is left to the next handler
+	req := httptest.NewRequest("POST", "http://api.example.org/orders", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, len(body), read)
+	assert.Equal(t, "orders", rec.Header().Get("X-Route"))
+	assert.Equal(t, "https://orders.example.org", rec.Header().Get("X-Backend"))
+	assert.Empty(t, rec.Header().Get(DefaultRouteHeader))
+
+	req = httptest.NewRequest("PUT", "http://api.example.org/orders", strings.NewReader("{}"))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, []string{"/orders=orders", "/orders=none"}, results)
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	m := newMiddlewareMatcher(b)
+	defer m.Close()
+	h := Middleware(m)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest("GET", "http://api.example.org/search?q=shoes", nil)
+	req.Header.Set("Accept", "application/json")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
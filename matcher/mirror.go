@@ -0,0 +1,241 @@
+package matcher
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// accessLogRx a line of the skipper access log, the Apache combined format
+// followed by the duration and the requested host, or of the Apache common
+// and combined formats
+var accessLogRx = regexp.MustCompile(`^\S+ \S+ \S+ \[[^\]]*\] "(\S+) (\S+)[^"]*" \d{3} \S+(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)"(?: \S+ (\S+))?)?`)
+
+// AuditOptions options of MirrorAuditorWith
+type AuditOptions struct {
+	// SampleRate fraction of the requests audited, between 0 and 1, all of
+	// them when 0
+	SampleRate float64
+	// Seed seed of the sampling, the same requests in the same order are
+	// sampled with the same seed
+	Seed int64
+	// RedactedHeaders headers redacted in the records in addition to
+	// DefaultRedactedHeaders
+	RedactedHeaders []string
+}
+
+// AuditRecord a JSON line of the audit, a request the matchers route
+// differently
+type AuditRecord struct {
+	// Time when the request was audited
+	Time time.Time `json:"time"`
+	// Request the normalized request, the redacted headers hidden
+	Request *RequestAttributes `json:"request"`
+	// Current the route of the current matcher, nil without a match
+	Current *AuditRoute `json:"current"`
+	// Candidate the route of the candidate matcher, nil without a match
+	Candidate *AuditRoute `json:"candidate"`
+}
+
+// AuditRoute a matching route of an AuditRecord
+type AuditRoute struct {
+	// ID id of the route
+	ID string `json:"id"`
+	// Backend address of a network backend or a placeholder like <shunt>
+	Backend string `json:"backend"`
+	// LBEndpoints endpoints of a load balanced backend
+	LBEndpoints []string `json:"lbEndpoints,omitempty"`
+}
+
+// AuditStats the counts of an Auditor
+type AuditStats struct {
+	// Requests requests received
+	Requests uint64 `json:"requests"`
+	// Audited requests tested against both matchers
+	Audited uint64 `json:"audited"`
+	// Disagreements audited requests the matchers route differently,
+	// the records written
+	Disagreements uint64 `json:"disagreements"`
+	// Errors audited requests at least one of the matchers can't test, eg.
+	// invalid, and lines of the access logs which aren't requests
+	Errors uint64 `json:"errors"`
+}
+
+// Auditor compares the routes of the current and of the candidate matchers
+// for the requests it receives, see MirrorAuditorWith
+type Auditor struct {
+	current, candidate Matcher
+	rate               float64
+	random             *lockedRand
+	redacted           map[string]bool
+
+	mu   sync.Mutex
+	sink io.Writer
+
+	requests, audited, disagreements, errors uint64
+}
+
+// MirrorAuditor returns an Auditor of all the requests, see
+// MirrorAuditorWith
+func MirrorAuditor(current, candidate Matcher, sink io.Writer) *Auditor {
+	return MirrorAuditorWith(current, candidate, sink, AuditOptions{})
+}
+
+// MirrorAuditorWith returns an Auditor testing the sampled requests against
+// both matchers and writing an AuditRecord line to the sink when they
+// resolve to a different route or backend, like DiffBehavior. The agreements aren't written, see Auditor.Stats.
+// The auditor doesn't close the matchers.
+func MirrorAuditorWith(current, candidate Matcher, sink io.Writer, o AuditOptions) *Auditor {
+	rate := o.SampleRate
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	return &Auditor{
+		current:   current,
+		candidate: candidate,
+		rate:      rate,
+		random:    &lockedRand{r: rand.New(rand.NewSource(o.Seed))},
+		redacted:  redactedHeaders(o.RedactedHeaders),
+		sink:      sink,
+	}
+}
+
+// Audit tests the request against both matchers when it's sampled, the
+// error is the one writing the record
+func (a *Auditor) Audit(attributes *RequestAttributes) error {
+	atomic.AddUint64(&a.requests, 1)
+	if a.rate < 1 && a.random.float64() >= a.rate {
+		return nil
+	}
+	atomic.AddUint64(&a.audited, 1)
+
+	current, candidate := a.current.Test(attributes), a.candidate.Test(attributes)
+	if current.Err() != nil || candidate.Err() != nil {
+		atomic.AddUint64(&a.errors, 1)
+		return nil
+	}
+	currentRes, candidateRes := NewResultData(current), NewResultData(candidate)
+	if sameDestination(currentRes, candidateRes) {
+		return nil
+	}
+	atomic.AddUint64(&a.disagreements, 1)
+	record := &AuditRecord{
+		Time:      time.Now(),
+		Request:   redactHeaders(current.Attributes(), a.redacted),
+		Current:   auditRoute(currentRes),
+		Candidate: auditRoute(candidateRes),
+	}
+
+	// the records are always representable in json
+	b, _ := json.Marshal(record)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err := a.sink.Write(append(b, '\n'))
+	return err
+}
+
+// Middleware audits the requests before calling the next handler, the
+// requests and the responses aren't changed. Only the method, the host, the
+// path, the query and the headers are tested, the body isn't read.
+func (a *Auditor) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.Audit(requestAttributes(r))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AuditAccessLog audits the requests of an access log until the end of r,
+// eg. of a tail of the log of the gateway. The lines are the ones of the
+// skipper access log, of the Apache common and combined formats, with the
+// referer and the user agent as headers and the requested host of skipper,
+// or request attributes in JSON. The lines which aren't requests are
+// counted as errors.
+func (a *Auditor) AuditAccessLog(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		attributes, err := ParseAccessLogLine(line)
+		if err != nil {
+			atomic.AddUint64(&a.requests, 1)
+			atomic.AddUint64(&a.errors, 1)
+			continue
+		}
+		if err := a.Audit(attributes); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Stats the counts of the requests audited so far
+func (a *Auditor) Stats() AuditStats {
+	return AuditStats{
+		Requests:      atomic.LoadUint64(&a.requests),
+		Audited:       atomic.LoadUint64(&a.audited),
+		Disagreements: atomic.LoadUint64(&a.disagreements),
+		Errors:        atomic.LoadUint64(&a.errors),
+	}
+}
+
+// ParseAccessLogLine parses the request of an access log line, see
+// Auditor.AuditAccessLog
+func ParseAccessLogLine(line string) (*RequestAttributes, error) {
+	if strings.HasPrefix(line, "{") {
+		var attributes RequestAttributes
+		if err := json.Unmarshal([]byte(line), &attributes); err != nil {
+			return nil, fmt.Errorf("invalid request: %v", err)
+		}
+		return &attributes, nil
+	}
+
+	m := accessLogRx.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("invalid access log line: %s", line)
+	}
+	u, err := url.ParseRequestURI(m[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid request uri %s: %v", m[2], err)
+	}
+	attributes := &RequestAttributes{
+		Method:  m[1],
+		Path:    u.EscapedPath(),
+		Query:   map[string]string{},
+		Headers: map[string]string{},
+	}
+	if u.Host != "" {
+		attributes.Host = u.Host
+	}
+	for key, values := range u.Query() {
+		attributes.Query[key] = values[0]
+	}
+	for name, value := range map[string]string{"Referer": m[3], "User-Agent": m[4]} {
+		if value = strings.Replace(value, `\"`, `"`, -1); value != "" && value != "-" {
+			attributes.Headers[name] = value
+		}
+	}
+	if host := m[5]; host != "" && host != "-" {
+		attributes.Host = host
+	}
+	return attributes, nil
+}
+
+// auditRoute the route of the result, nil without a match
+func auditRoute(res *ResultData) *AuditRoute {
+	if !res.Matched {
+		return nil
+	}
+	return &AuditRoute{ID: res.RouteID, Backend: res.Backend, LBEndpoints: res.LBEndpoints}
+}
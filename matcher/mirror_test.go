@@ -0,0 +1,209 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	mirrorCurrentRoutes = `orders: Path("/orders") -> "https://orders.example.org";
+legacy: PathSubtree("/legacy") -> "https://legacy.example.org";
+search: Path("/search") && QueryParam("q") -> <shunt>;`
+	mirrorCandidateRoutes = `orders: Path("/orders") -> "https://orders-v2.example.org";
+search: Path("/search") && QueryParam("q") -> <shunt>;
+catalog: PathSubtree("/legacy/catalog") -> "https://catalog.example.org";`
+)
+
+// auditRecords the records written to the sink
+func auditRecords(t *testing.T, sink *bytes.Buffer) []AuditRecord {
+	records := []AuditRecord{}
+	for _, line := range strings.Split(strings.TrimSpace(sink.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var r AuditRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestMirrorAuditor(t *testing.T) {
+	current := newTestMatcher(t, mirrorCurrentRoutes)
+	defer current.Close()
+	candidate := newTestMatcher(t, mirrorCandidateRoutes)
+	defer candidate.Close()
+
+	tests := []struct {
+		name      string
+		request   *RequestAttributes
+		current   *AuditRoute
+		candidate *AuditRoute
+	}{
+		{
+			name:      "other backend",
+			request:   &RequestAttributes{Path: "/orders"},
+			current:   &AuditRoute{ID: "orders", Backend: "https://orders.example.org"},
+			candidate: &AuditRoute{ID: "orders", Backend: "https://orders-v2.example.org"},
+		},
+		{
+			name:      "other route",
+			request:   &RequestAttributes{Path: "/legacy/catalog/1"},
+			current:   &AuditRoute{ID: "legacy", Backend: "https://legacy.example.org"},
+			candidate: &AuditRoute{ID: "catalog", Backend: "https://catalog.example.org"},
+		},
+		{
+			name:    "no route",
+			request: &RequestAttributes{Path: "/legacy/users"},
+			current: &AuditRoute{ID: "legacy", Backend: "https://legacy.example.org"},
+		},
+		{
+			name:    "agreement",
+			request: &RequestAttributes{Path: "/search", Query: map[string]string{"q": "shoes"}},
+		},
+		{
+			name:    "agreement without match",
+			request: &RequestAttributes{Path: "/missing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sink bytes.Buffer
+			a := MirrorAuditor(current, candidate, &sink)
+			require.NoError(t, a.Audit(tt.request))
+
+			records := auditRecords(t, &sink)
+			if tt.current == nil && tt.candidate == nil {
+				assert.Empty(t, records)
+				assert.Equal(t, AuditStats{Requests: 1, Audited: 1}, a.Stats())
+				return
+			}
+			require.Len(t, records, 1)
+			assert.Equal(t, tt.current, records[0].Current)
+			assert.Equal(t, tt.candidate, records[0].Candidate)
+			assert.Equal(t, tt.request.Path, records[0].Request.Path)
+			assert.Equal(t, "GET", records[0].Request.Method)
+			assert.False(t, records[0].Time.IsZero())
+			assert.Equal(t, AuditStats{Requests: 1, Audited: 1, Disagreements: 1}, a.Stats())
+		})
+	}
+}
+
+func TestMirrorAuditorRedaction(t *testing.T) {
+	current := newTestMatcher(t, mirrorCurrentRoutes)
+	defer current.Close()
+	candidate := newTestMatcher(t, mirrorCandidateRoutes)
+	defer candidate.Close()
+
+	var sink bytes.Buffer
+	a := MirrorAuditorWith(current, candidate, &sink, AuditOptions{RedactedHeaders: []string{"x-session"}})
+	require.NoError(t, a.Audit(&RequestAttributes{Path: "/orders", Headers: map[string]string{
+		"Authorization": "Bearer secret",
+		"X-Session":     "abc",
+		"Accept":        "application/json",
+	}}))
+
+	records := auditRecords(t, &sink)
+	require.Len(t, records, 1)
+	assert.Equal(t, map[string]string{"Authorization": "<redacted>", "X-Session": "<redacted>", "Accept": "application/json"}, records[0].Request.Headers)
+	assert.NotContains(t, sink.String(), "secret")
+}
+
+func TestMirrorAuditorSampling(t *testing.T) {
+	current := newTestMatcher(t, mirrorCurrentRoutes)
+	defer current.Close()
+	candidate := newTestMatcher(t, mirrorCandidateRoutes)
+	defer candidate.Close()
+
+	audit := func(seed int64) AuditStats {
+		var sink bytes.Buffer
+		a := MirrorAuditorWith(current, candidate, &sink, AuditOptions{SampleRate: 0.25, Seed: seed})
+		for i := 0; i < 400; i++ {
+			require.NoError(t, a.Audit(&RequestAttributes{Path: "/orders", Query: map[string]string{"i": string(rune('a' + i%26))}}))
+		}
+		return a.Stats()
+	}
+	stats := audit(7)
+	assert.Equal(t, uint64(400), stats.Requests)
+	assert.InDelta(t, 100, stats.Audited, 30)
+	assert.Equal(t, stats.Audited, stats.Disagreements)
+
+	assert.Equal(t, stats, audit(7))
+}
+
+func TestMirrorAuditorMiddleware(t *testing.T) {
+	current := newTestMatcher(t, mirrorCurrentRoutes)
+	defer current.Close()
+	candidate := newTestMatcher(t, mirrorCandidateRoutes)
+	defer candidate.Close()
+
+	var sink bytes.Buffer
+	a := MirrorAuditor(current, candidate, &sink)
+	h := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("upstream"))
+	}))
+
+	for _, target := range []string{"/orders", "/search?q=shoes", "/legacy/catalog/1"} {
+		rsp := httptest.NewRecorder()
+		h.ServeHTTP(rsp, httptest.NewRequest("GET", "http://shop.example.org"+target, nil))
+		// the traffic isn't affected
+		assert.Equal(t, http.StatusTeapot, rsp.Code)
+		assert.Equal(t, "upstream", rsp.Body.String())
+		assert.Empty(t, rsp.Header())
+	}
+
+	records := auditRecords(t, &sink)
+	require.Len(t, records, 2)
+	assert.Equal(t, "orders", records[0].Current.ID)
+	assert.Equal(t, "shop.example.org", records[0].Request.Host)
+	assert.Equal(t, "catalog", records[1].Candidate.ID)
+	assert.Equal(t, AuditStats{Requests: 3, Audited: 3, Disagreements: 2}, a.Stats())
+}
+
+func TestMirrorAuditorAccessLog(t *testing.T) {
+	current := newTestMatcher(t, mirrorCurrentRoutes)
+	defer current.Close()
+	candidate := newTestMatcher(t, mirrorCandidateRoutes)
+	defer candidate.Close()
+
+	log := `10.0.0.1 - - [02/Jan/2024:10:00:00 +0000] "GET /orders?page=2 HTTP/1.1" 200 512 "-" "curl/7.68.0" 12 shop.example.org - -
+10.0.0.2 - - [02/Jan/2024:10:00:01 +0000] "GET /search?q=shoes HTTP/1.1" 200 1024 "https://shop.example.org/" "Mozilla/5.0 \"test\""
+not a request
+
+10.0.0.3 - - [02/Jan/2024:10:00:02 +0000] "POST /legacy/catalog/1 HTTP/1.1" 201 0
+{"path": "/legacy/users", "method": "DELETE"}
+`
+	var sink bytes.Buffer
+	a := MirrorAuditor(current, candidate, &sink)
+	require.NoError(t, a.AuditAccessLog(strings.NewReader(log)))
+
+	records := auditRecords(t, &sink)
+	require.Len(t, records, 3)
+	assert.Equal(t, &RequestAttributes{Method: "GET", Path: "/orders", Host: "shop.example.org",
+		Query: map[string]string{"page": "2"}, Headers: map[string]string{"User-Agent": "curl/7.68.0"}}, records[0].Request)
+	assert.Equal(t, "POST", records[1].Request.Method)
+	assert.Equal(t, "catalog", records[1].Candidate.ID)
+	assert.Equal(t, "DELETE", records[2].Request.Method)
+	assert.Nil(t, records[2].Candidate)
+	assert.Equal(t, AuditStats{Requests: 5, Audited: 4, Disagreements: 3, Errors: 1}, a.Stats())
+}
+
+func TestParseAccessLogLine(t *testing.T) {
+	attrs, err := ParseAccessLogLine(`10.0.0.2 - - [02/Jan/2024:10:00:01 +0000] "GET /search?q=shoes HTTP/1.1" 200 1024 "https://shop.example.org/" "Mozilla/5.0 \"test\""`)
+	require.NoError(t, err)
+	assert.Equal(t, &RequestAttributes{Method: "GET", Path: "/search", Query: map[string]string{"q": "shoes"},
+		Headers: map[string]string{"Referer": "https://shop.example.org/", "User-Agent": `Mozilla/5.0 "test"`}}, attrs)
+
+	for _, line := range []string{"not a request", `10.0.0.1 - - [02/Jan/2024:10:00:00 +0000] "GET %zz HTTP/1.1" 400 0`, `{"path": 1}`} {
+		_, err := ParseAccessLogLine(line)
+		assert.Error(t, err, line)
+	}
+}
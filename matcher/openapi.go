@@ -0,0 +1,370 @@
+package matcher
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// openAPIMethods the operations of a path item, in the order they are
+// reported
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// openAPIParamRx a templated parameter of an OpenAPI path or server url,
+// eg. {id}
+var openAPIParamRx = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// openAPIUUID value of the uuid parameters without an example
+const openAPIUUID = "123e4567-e89b-12d3-a456-426614174000"
+
+// OpenAPIReport the operations of OpenAPI documents compared to the routes,
+// see CompareOpenAPI
+type OpenAPIReport struct {
+	// Operations the operations of the documents, in their order
+	Operations []OpenAPIOperation `json:"operations" yaml:"operations"`
+	// Unmatched the operations whose request matches no route
+	Unmatched []OpenAPIOperation `json:"unmatched" yaml:"unmatched"`
+	// Unhit ids of the routes no operation request hits, in the routes
+	// file order
+	Unhit []string `json:"unhit" yaml:"unhit"`
+}
+
+// OpenAPIOperation an operation of an OpenAPI document with the request
+// generated for it
+type OpenAPIOperation struct {
+	// Spec the file of the document
+	Spec string `json:"spec" yaml:"spec"`
+	// Method of the operation, eg. GET
+	Method string `json:"method" yaml:"method"`
+	// Path the templated path of the operation, eg. /users/{id}
+	Path string `json:"path" yaml:"path"`
+	// OperationID the operationId of the operation, if any
+	OperationID string `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	// Request the request generated for the operation
+	Request *RequestAttributes `json:"request" yaml:"request"`
+	// RouteID id of the route matching the request, empty when none does
+	RouteID string `json:"routeId,omitempty" yaml:"routeId,omitempty"`
+}
+
+// String the method and the path of the operation, eg. GET /users/{id}
+func (op OpenAPIOperation) String() string {
+	return op.Method + " " + op.Path
+}
+
+// openAPIDocument the parts of an OpenAPI 3 document CompareOpenAPI uses
+type openAPIDocument struct {
+	OpenAPI    string            `yaml:"openapi"`
+	Servers    []openAPIServer   `yaml:"servers"`
+	Paths      yaml.MapSlice     `yaml:"paths"`
+	Components openAPIComponents `yaml:"components"`
+}
+
+type openAPIComponents struct {
+	Parameters map[string]openAPIParameter `yaml:"parameters"`
+}
+
+type openAPIServer struct {
+	URL       string                           `yaml:"url"`
+	Variables map[string]openAPIServerVariable `yaml:"variables"`
+}
+
+type openAPIServerVariable struct {
+	Default string `yaml:"default"`
+}
+
+type openAPIPathItem struct {
+	Servers    []openAPIServer    `yaml:"servers"`
+	Parameters []openAPIParameter `yaml:"parameters"`
+	Get        *openAPIOperation  `yaml:"get"`
+	Put        *openAPIOperation  `yaml:"put"`
+	Post       *openAPIOperation  `yaml:"post"`
+	Delete     *openAPIOperation  `yaml:"delete"`
+	Options    *openAPIOperation  `yaml:"options"`
+	Head       *openAPIOperation  `yaml:"head"`
+	Patch      *openAPIOperation  `yaml:"patch"`
+	Trace      *openAPIOperation  `yaml:"trace"`
+}
+
+// operation the operation of the method, nil if there is none
+func (p openAPIPathItem) operation(method string) *openAPIOperation {
+	return map[string]*openAPIOperation{
+		"get": p.Get, "put": p.Put, "post": p.Post, "delete": p.Delete,
+		"options": p.Options, "head": p.Head, "patch": p.Patch, "trace": p.Trace,
+	}[method]
+}
+
+type openAPIOperation struct {
+	OperationID string             `yaml:"operationId"`
+	Servers     []openAPIServer    `yaml:"servers"`
+	Parameters  []openAPIParameter `yaml:"parameters"`
+}
+
+type openAPIParameter struct {
+	Ref      string        `yaml:"$ref"`
+	Name     string        `yaml:"name"`
+	In       string        `yaml:"in"`
+	Required bool          `yaml:"required"`
+	Example  interface{}   `yaml:"example"`
+	Schema   openAPISchema `yaml:"schema"`
+}
+
+type openAPISchema struct {
+	Type    string        `yaml:"type"`
+	Format  string        `yaml:"format"`
+	Pattern string        `yaml:"pattern"`
+	Enum    []interface{} `yaml:"enum"`
+	Example interface{}   `yaml:"example"`
+	Default interface{}   `yaml:"default"`
+}
+
+// CompareOpenAPI loads the OpenAPI 3 documents, yaml or json, and the routes
+// file of the options, generates a request for each operation of the
+// documents and tests it: the report has the operations matching no route
+// and the routes no operation hits. The request of an operation has its
+// method, its path with the templated parameters, eg. {id}, replaced by
+// their example or a value of their schema, so a Path("/users/:id") route
+// matches /users/{id}, and the required query, header and cookie parameters.
+// The host and the base path are the ones of the first server url of the
+// operation, of its path or of the document, a relative url has no host.
+// Options.Lazy is ignored, the Traffic predicates always match unless
+// Options.TrafficMode is set.
+func CompareOpenAPI(specPaths []string, o *Options) (*OpenAPIReport, error) {
+	var operations []OpenAPIOperation
+	for _, p := range specPaths {
+		ops, err := loadOpenAPI(p)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, ops...)
+	}
+
+	if o.TrafficMode == TrafficRandom {
+		c := *o
+		c.TrafficMode = TrafficAlways
+		o = &c
+	}
+	f, err := newMatcher(o)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	report := &OpenAPIReport{Operations: []OpenAPIOperation{}, Unmatched: []OpenAPIOperation{}, Unhit: []string{}}
+	hit := make(map[string]bool)
+	for _, op := range operations {
+		res := f.Test(op.Request)
+		if route := res.Route(); route != nil {
+			op.RouteID = route.Id
+		}
+		results := res.Hops()
+		if results == nil {
+			results = []TestResult{res}
+		}
+		for _, hop := range results {
+			if route := hop.Route(); route != nil {
+				hit[route.Id] = true
+			}
+		}
+		report.Operations = append(report.Operations, op)
+		if op.RouteID == "" {
+			report.Unmatched = append(report.Unmatched, op)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, r := range f.routes {
+		if !hit[r.Id] && !seen[r.Id] {
+			report.Unhit = append(report.Unhit, r.Id)
+		}
+		seen[r.Id] = true
+	}
+	return report, nil
+}
+
+// loadOpenAPI the operations of an OpenAPI 3 document with their requests
+func loadOpenAPI(file string) ([]OpenAPIOperation, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	doc := &openAPIDocument{}
+	if err := yaml.Unmarshal(b, doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("%s: not an OpenAPI 3 document, openapi is %q", file, doc.OpenAPI)
+	}
+
+	var operations []OpenAPIOperation
+	for _, entry := range doc.Paths {
+		path, ok := entry.Key.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid path %v", file, entry.Key)
+		}
+		// the path items are decoded again as structs, the map slice keeps
+		// the order of the paths
+		raw, err := yaml.Marshal(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		var item openAPIPathItem
+		if err := yaml.Unmarshal(raw, &item); err != nil {
+			return nil, fmt.Errorf("%s: path %s: %v", file, path, err)
+		}
+		for _, method := range openAPIMethods {
+			op := item.operation(method)
+			if op == nil {
+				continue
+			}
+			attrs, err := doc.request(path, item, op)
+			if err == nil {
+				attrs.Method = strings.ToUpper(method)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s %s: %v", file, strings.ToUpper(method), path, err)
+			}
+			operations = append(operations, OpenAPIOperation{
+				Spec:        file,
+				Method:      strings.ToUpper(method),
+				Path:        path,
+				OperationID: op.OperationID,
+				Request:     attrs,
+			})
+		}
+	}
+	return operations, nil
+}
+
+// request generates the request of the operation of the path item
+func (doc *openAPIDocument) request(path string, item openAPIPathItem, op *openAPIOperation) (*RequestAttributes, error) {
+	servers := op.Servers
+	if len(servers) == 0 {
+		servers = item.Servers
+	}
+	if len(servers) == 0 {
+		servers = doc.Servers
+	}
+	host, basePath, err := serverBase(servers)
+	if err != nil {
+		return nil, err
+	}
+
+	// the parameters of the operation override the ones of the path item
+	// with the same name and location
+	params := make(map[string]openAPIParameter)
+	var order []string
+	for _, list := range [][]openAPIParameter{item.Parameters, op.Parameters} {
+		for _, p := range list {
+			p, err := doc.resolve(p)
+			if err != nil {
+				return nil, err
+			}
+			key := p.In + ":" + p.Name
+			if _, ok := params[key]; !ok {
+				order = append(order, key)
+			}
+			params[key] = p
+		}
+	}
+
+	attrs := &RequestAttributes{
+		Host:    host,
+		Query:   map[string]string{},
+		Headers: map[string]string{},
+	}
+	var cookies []string
+	for _, key := range order {
+		p := params[key]
+		if p.In != "path" && !p.Required {
+			continue
+		}
+		switch p.In {
+		case "query":
+			attrs.Query[p.Name] = p.value()
+		case "header":
+			attrs.Headers[p.Name] = p.value()
+		case "cookie":
+			cookies = append(cookies, p.Name+"="+p.value())
+		}
+	}
+	if len(cookies) > 0 {
+		attrs.Headers["Cookie"] = strings.Join(cookies, "; ")
+	}
+
+	attrs.Path = basePath + openAPIParamRx.ReplaceAllStringFunc(path, func(m string) string {
+		name := m[1 : len(m)-1]
+		v := sampleValue
+		if p, ok := params["path:"+name]; ok {
+			v = p.value()
+		}
+		return url.PathEscape(v)
+	})
+	return attrs, nil
+}
+
+// resolve the parameter a $ref points to, the parameter itself without
+func (doc *openAPIDocument) resolve(p openAPIParameter) (openAPIParameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	const prefix = "#/components/parameters/"
+	if !strings.HasPrefix(p.Ref, prefix) {
+		return p, fmt.Errorf("unsupported parameter reference %s", p.Ref)
+	}
+	resolved, ok := doc.Components.Parameters[strings.TrimPrefix(p.Ref, prefix)]
+	if !ok {
+		return p, fmt.Errorf("parameter reference %s not found", p.Ref)
+	}
+	return resolved, nil
+}
+
+// value an example value of the parameter: its example, the example, the
+// first enum value or the default of its schema, a value of its type or
+// matching its pattern otherwise
+func (p openAPIParameter) value() string {
+	for _, v := range []interface{}{p.Example, p.Schema.Example} {
+		if v != nil {
+			return fmt.Sprint(v)
+		}
+	}
+	if len(p.Schema.Enum) > 0 {
+		return fmt.Sprint(p.Schema.Enum[0])
+	}
+	if p.Schema.Default != nil {
+		return fmt.Sprint(p.Schema.Default)
+	}
+	if p.Schema.Pattern != "" {
+		if v, ok := sampleRegexp(p.Schema.Pattern); ok {
+			return v
+		}
+	}
+	switch {
+	case p.Schema.Type == "integer" || p.Schema.Type == "number":
+		return "1"
+	case p.Schema.Type == "boolean":
+		return "true"
+	case p.Schema.Format == "uuid":
+		return openAPIUUID
+	}
+	return sampleValue
+}
+
+// serverBase the host and the base path of the first server url, the
+// variables replaced by their default
+func serverBase(servers []openAPIServer) (host, basePath string, err error) {
+	if len(servers) == 0 {
+		return "", "", nil
+	}
+	s := servers[0]
+	raw := openAPIParamRx.ReplaceAllStringFunc(s.URL, func(m string) string {
+		return s.Variables[m[1:len(m)-1]].Default
+	})
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid server url %s: %v", s.URL, err)
+	}
+	return u.Hostname(), strings.TrimSuffix(u.EscapedPath(), "/"), nil
+}
@@ -0,0 +1,117 @@
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareOpenAPI(t *testing.T) {
+	file := writeRoutes(t, `users: Host("^users[.]example[.]org$") && Path("/api/v1/users") && Header("X-Tenant", "acme") && Method("GET") -> <shunt>;
+user: Host("^users[.]example[.]org$") && Path("/api/v1/users/:id") && Method("GET") -> <shunt>;
+userDelete: Host("^users[.]example[.]org$") && PathRegexp(/^\/api\/v1\/users\/[0-9]+$/) && Method("DELETE") -> <shunt>;
+avatar: Host("^cdn[.]example[.]org$") && Path("/users/:id/avatar") -> <shunt>;
+order: Path("/orders-api/orders/:orderId") && Cookie("session", /^abc$/) -> <shunt>;
+legacy: Path("/legacy") -> <shunt>;
+`)
+	defer os.Remove(file)
+
+	report, err := CompareOpenAPI([]string{"testdata/openapi/users.yaml", "testdata/openapi/orders.json"}, &Options{RoutesFile: file})
+	require.NoError(t, err)
+
+	var got []string
+	for _, op := range report.Operations {
+		got = append(got, op.String()+" "+op.Request.Host+op.Request.Path+" "+op.RouteID)
+	}
+	assert.Equal(t, []string{
+		"GET /users users.example.org/api/v1/users users",
+		"POST /users users.example.org/api/v1/users ",
+		"GET /users/{id} users.example.org/api/v1/users/000 user",
+		"DELETE /users/{id} users.example.org/api/v1/users/000 userDelete",
+		"GET /users/{id}/avatar cdn.example.org/users/000/avatar avatar",
+		"GET /orders/{orderId} /orders-api/orders/1 order",
+	}, got)
+
+	require.Len(t, report.Unmatched, 1)
+	assert.Equal(t, "createUser", report.Unmatched[0].OperationID)
+	assert.Equal(t, "testdata/openapi/users.yaml", report.Unmatched[0].Spec)
+	assert.Equal(t, []string{"legacy"}, report.Unhit)
+
+	// required parameters only
+	list := report.Operations[0].Request
+	assert.Equal(t, map[string]string{"X-Tenant": "acme"}, list.Headers)
+	assert.Empty(t, list.Query)
+	assert.Equal(t, "session=abc", report.Operations[5].Request.Headers["Cookie"])
+}
+
+// writeSpec writes doc to a temporary OpenAPI file, it returns its path
+func writeSpec(t *testing.T, doc string) string {
+	f, err := ioutil.TempFile("", "openapi-*.yaml")
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(doc)
+	require.NoError(t, err)
+	return f.Name()
+}
+
+func TestCompareOpenAPIPathTemplates(t *testing.T) {
+	spec := writeSpec(t, `openapi: 3.0.0
+paths:
+  /files/{name}:
+    get:
+      parameters:
+      - name: name
+        in: path
+        required: true
+        example: a b.txt
+  /items/{id}/{sub}:
+    get: {}
+  /literal/:id:
+    get: {}
+`)
+	defer os.Remove(spec)
+
+	file := writeRoutes(t, `files: Path("/files/:name") -> <shunt>;
+items: Path("/items/:id/:sub") -> <shunt>;
+literal: Path("/literal/:id") -> <shunt>;
+template: Path("/templated/{id}") -> <shunt>;
+`)
+	defer os.Remove(file)
+
+	report, err := CompareOpenAPI([]string{spec}, &Options{RoutesFile: file})
+	require.NoError(t, err)
+	require.Len(t, report.Operations, 3)
+
+	// no server, no host
+	assert.Equal(t, "", report.Operations[0].Request.Host)
+	assert.Equal(t, "/files/a%20b.txt", report.Operations[0].Request.Path)
+	assert.Equal(t, "files", report.Operations[0].RouteID)
+	// undeclared parameters get a sample value
+	assert.Equal(t, "/items/example/example", report.Operations[1].Request.Path)
+	assert.Equal(t, "items", report.Operations[1].RouteID)
+	// :id isn't an OpenAPI template, it's requested literally and still
+	// matches the route wildcard
+	assert.Equal(t, "/literal/:id", report.Operations[2].Request.Path)
+	assert.Equal(t, "literal", report.Operations[2].RouteID)
+	assert.Empty(t, report.Unmatched)
+	assert.Equal(t, []string{"template"}, report.Unhit)
+}
+
+func TestCompareOpenAPIErrors(t *testing.T) {
+	swagger := writeSpec(t, "swagger: \"2.0\"\npaths: {}\n")
+	defer os.Remove(swagger)
+
+	_, err := CompareOpenAPI([]string{swagger}, &Options{RoutesFile: "testdata/routes.eskip"})
+	assert.EqualError(t, err, swagger+`: not an OpenAPI 3 document, openapi is ""`)
+
+	_, err = CompareOpenAPI([]string{"testdata/openapi/missing.yaml"}, &Options{RoutesFile: "testdata/routes.eskip"})
+	assert.Error(t, err)
+
+	ref := writeSpec(t, "openapi: 3.0.0\npaths:\n  /a/{id}:\n    get:\n      parameters:\n      - $ref: '#/components/parameters/nope'\n")
+	defer os.Remove(ref)
+	_, err = CompareOpenAPI([]string{ref}, &Options{RoutesFile: "testdata/routes.eskip"})
+	assert.EqualError(t, err, ref+": GET /a/{id}: parameter reference #/components/parameters/nope not found")
+}
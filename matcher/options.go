@@ -0,0 +1,125 @@
+package matcher
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/routing"
+)
+
+// Option sets an option of NewWith, each one the counterpart of an Options
+// field, the options applied in order
+type Option func(*Options)
+
+// NewWith creates a matcher like New from the options, eg.
+//
+//	m, err := NewWith(WithRoutes(`bar: Path("/bar") -> <shunt>;`), WithIgnoreTrailingSlash())
+//
+// It fails like New when the options can't be combined, eg. WithRoutesFile
+// and WithRoutes.
+func NewWith(opts ...Option) (Matcher, error) {
+	return New(newOptions(opts...))
+}
+
+// newOptions the Options of the functional options
+func newOptions(opts ...Option) *Options {
+	o := &Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithRoutesFile loads the routes from the eskip file at path, see
+// Options.RoutesFile
+func WithRoutesFile(path string) Option {
+	return func(o *Options) { o.RoutesFile = path }
+}
+
+// WithRoutes loads the routes from the eskip document, see Options.Routes
+func WithRoutes(doc string) Option {
+	return func(o *Options) { o.Routes = doc }
+}
+
+// WithKubernetes loads the routes of the Kubernetes ingresses, see
+// Options.Kubernetes
+func WithKubernetes(k KubernetesOptions) Option {
+	return func(o *Options) { o.Kubernetes = &k }
+}
+
+// WithMockedFilters mocks the filters with the names, added to the ones of
+// the previous options, see Options.MockFilters
+func WithMockedFilters(names ...string) Option {
+	return func(o *Options) { o.MockFilters = append(o.MockFilters, names...) }
+}
+
+// WithAutoMockFilters mocks the filters of the routes missing in the
+// registry, see Options.AutoMockFilters
+func WithAutoMockFilters() Option {
+	return func(o *Options) { o.AutoMockFilters = true }
+}
+
+// WithCustomFilters registers the filter specs, added to the ones of the
+// previous options, see Options.CustomFilters
+func WithCustomFilters(specs ...filters.Spec) Option {
+	return func(o *Options) { o.CustomFilters = append(o.CustomFilters, specs...) }
+}
+
+// WithCustomPredicates registers the predicate specs, added to the ones of
+// the previous options, see Options.CustomPredicates
+func WithCustomPredicates(specs ...routing.PredicateSpec) Option {
+	return func(o *Options) { o.CustomPredicates = append(o.CustomPredicates, specs...) }
+}
+
+// WithIgnoreTrailingSlash matches the paths with or without their trailing
+// slash, see Options.IgnoreTrailingSlash
+func WithIgnoreTrailingSlash() Option {
+	return func(o *Options) { o.IgnoreTrailingSlash = true }
+}
+
+// WithVerbosity sets the level of the diagnostics, eg. VerbosityInfo,
+// see Options.Verbosity
+func WithVerbosity(level int) Option {
+	return func(o *Options) { o.Verbosity = level }
+}
+
+// WithLog writes the diagnostics to w, see Options.Log
+func WithLog(w io.Writer) Option {
+	return func(o *Options) { o.Log = w }
+}
+
+// WithTB writes the diagnostics with the Logf of tb and closes the matcher
+// when the test completes, see Options.TB
+func WithTB(tb testing.TB) Option {
+	return func(o *Options) { o.TB = tb }
+}
+
+// WithInitTimeout sets how long New waits for the routes, see
+// Options.InitTimeout
+func WithInitTimeout(d time.Duration) Option {
+	return func(o *Options) { o.InitTimeout = d }
+}
+
+// WithLazy defers loading the routes to the first test, see Options.Lazy
+func WithLazy() Option {
+	return func(o *Options) { o.Lazy = true }
+}
+
+// WithStrict fails when no route is loaded, see Options.Strict
+func WithStrict() Option {
+	return func(o *Options) { o.Strict = true }
+}
+
+// WithFailOnLoadErrors fails when the routing drops routes, see
+// Options.FailOnLoadErrors
+func WithFailOnLoadErrors() Option {
+	return func(o *Options) { o.FailOnLoadErrors = true }
+}
+
+// WithTrafficMode forces the decisions of the Traffic predicates, see
+// Options.TrafficMode
+func WithTrafficMode(mode TrafficMode) Option {
+	return func(o *Options) { o.TrafficMode = mode }
+}
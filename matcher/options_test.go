@@ -0,0 +1,119 @@
+package matcher
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOptions(t *testing.T) {
+	var log strings.Builder
+	o := newOptions(
+		WithRoutesFile("testdata/routes.eskip"),
+		WithMockedFilters("customfilter"),
+		WithMockedFilters("tenantAuth", "audit"),
+		WithIgnoreTrailingSlash(),
+		WithVerbosity(VerbosityDebug),
+		WithLog(&log),
+		WithInitTimeout(time.Second),
+		WithStrict(),
+		WithFailOnLoadErrors(),
+	)
+	assert.Equal(t, &Options{
+		RoutesFile:          "testdata/routes.eskip",
+		MockFilters:         []string{"customfilter", "tenantAuth", "audit"},
+		IgnoreTrailingSlash: true,
+		Verbosity:           VerbosityDebug,
+		Log:                 &log,
+		InitTimeout:         time.Second,
+		Strict:              true,
+		FailOnLoadErrors:    true,
+	}, o)
+
+	assert.Equal(t, &Options{}, newOptions(), "the zero Options without options")
+	assert.Equal(t, &Options{RoutesFile: "b.eskip"}, newOptions(WithRoutesFile("a.eskip"), WithRoutesFile("b.eskip")), "the last one wins")
+}
+
+func TestNewWith(t *testing.T) {
+	doc, err := ioutil.ReadFile("testdata/routes.eskip")
+	require.NoError(t, err)
+
+	requests := []*RequestAttributes{
+		{Path: "/bar"},
+		{Path: "/bar/"},
+		{Method: "GET", Path: "/foo", Headers: map[string]string{"Accept": "application/json"}},
+		{Path: "/customfilter"},
+		{Path: "/search", Query: map[string]string{"q": "shoes"}},
+		{Path: "/none"},
+	}
+	tests := []struct {
+		name    string
+		opts    []Option
+		options Options
+	}{
+		{"routes file", []Option{WithRoutesFile("testdata/routes.eskip"), WithMockedFilters("customfilter"), WithIgnoreTrailingSlash()},
+			Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, IgnoreTrailingSlash: true}},
+		{"routes", []Option{WithRoutes(string(doc)), WithAutoMockFilters()},
+			Options{Routes: string(doc), AutoMockFilters: true}},
+		{"lazy", []Option{WithRoutes(string(doc)), WithMockedFilters("customfilter"), WithLazy()},
+			Options{Routes: string(doc), MockFilters: []string{"customfilter"}, Lazy: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewWith(tt.opts...)
+			require.NoError(t, err)
+			defer a.Close()
+			b, err := New(&tt.options)
+			require.NoError(t, err)
+			defer b.Close()
+
+			assert.Equal(t, b.Routes(), a.Routes())
+			assert.Equal(t, b.Findings(), a.Findings())
+			for _, req := range requests {
+				want, got := b.Test(req), a.Test(req)
+				require.NoError(t, got.Err())
+				assert.Equal(t, want.Route(), got.Route(), req.Path)
+			}
+		})
+	}
+}
+
+func TestRoutesDocument(t *testing.T) {
+	m, err := NewWith(WithRoutes(`bar: Path("/bar") -> <shunt>;
+foo: Path("/foo") -> setPath(42) -> <shunt>;`))
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, "bar", m.Test(&RequestAttributes{Path: "/bar"}).Route().Id)
+	if errs := m.LoadErrors(); assert.Len(t, errs, 1) {
+		assert.Equal(t, "<routes>", errs[0].File)
+		assert.Equal(t, 2, errs[0].Line)
+	}
+
+	_, err = NewWith(WithRoutes(`bar: Path("/bar") -> `))
+	assert.True(t, errors.Is(err, ErrParse), "%v", err)
+
+	c, err := Cached(&Options{Routes: `bar: Path("/bar") -> <shunt>;`})
+	require.NoError(t, err)
+	defer c.Close()
+	_, shared := c.(*cachedMatcher)
+	assert.False(t, shared)
+}
+
+func TestNewWithIncompatibleOptions(t *testing.T) {
+	_, err := NewWith(WithRoutesFile("testdata/routes.eskip"), WithRoutes(`bar: Path("/bar") -> <shunt>;`))
+	assert.EqualError(t, err, "RoutesFile and Routes can't be set together")
+
+	_, err = NewWith(WithKubernetes(KubernetesOptions{Manifests: "testdata/ingresses.yaml"}), WithRoutes(`bar: Path("/bar") -> <shunt>;`), WithLazy())
+	assert.EqualError(t, err, "Kubernetes and Routes can't be set together", "checked before the lazy initialization")
+
+	_, err = New(&Options{RoutesFile: "testdata/routes.eskip", Routes: `bar: Path("/bar") -> <shunt>;`})
+	assert.EqualError(t, err, "RoutesFile and Routes can't be set together", "New checks them too")
+
+	_, err = NewWith(WithRoutesFile("testdata/routes.eskip"), WithLog(&strings.Builder{}), WithTB(t))
+	assert.EqualError(t, err, "Log and TB can't be set together")
+}
@@ -0,0 +1,64 @@
+package matcher
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/zalando/skipper/routing"
+)
+
+// PanicError a panic while matching a request, eg. of a custom predicate,
+// see Options.DisablePanicRecovery
+type PanicError struct {
+	// Value the value passed to panic
+	Value interface{}
+	// Stack the stack of the panicking goroutine from the panic to the
+	// routing, without the frames of the runtime and of the matcher
+	Stack string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic while matching the request: %v", e.Value)
+}
+
+// routeFunc name of the Route method of the routing in the stacks, the last
+// frame kept
+var routeFunc = runtime.FuncForPC(reflect.ValueOf((*routing.Routing).Route).Pointer()).Name()
+
+// matchRoute finds the route of the request, a panic, eg. of a custom
+// predicate, is a *PanicError unless Options.DisablePanicRecovery is set
+func (f *matcher) matchRoute(req *http.Request) (route *routing.Route, params map[string]string, err error) {
+	if !f.options.DisablePanicRecovery {
+		defer func() {
+			if v := recover(); v != nil {
+				route, params, err = nil, nil, &PanicError{Value: v, Stack: trimStack(debug.Stack())}
+			}
+		}()
+	}
+	route, params = f.routing.Route(req)
+	return route, params, nil
+}
+
+// trimStack keeps the frames of a stack of debug.Stack from the last panic
+// call to the routing
+func trimStack(stack []byte) string {
+	// the goroutine line, then a function line and a file line by frame
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+	var frames []string
+	for i := 1; i+1 < len(lines); i += 2 {
+		fn := lines[i]
+		if strings.HasPrefix(fn, "panic(") {
+			frames = frames[:0]
+			continue
+		}
+		frames = append(frames, fn, lines[i+1])
+		if strings.HasPrefix(fn, routeFunc+"(") {
+			break
+		}
+	}
+	return strings.Join(frames, "\n")
+}
@@ -0,0 +1,66 @@
+package matcher
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/routing"
+)
+
+// panicking a predicate writing to a nil map, on the Panic header only
+func panicking() routing.PredicateSpec {
+	return PredicateFunc("Panicking", 0, func(args []interface{}, req *http.Request) bool {
+		if req.Header.Get("Panic") != "" {
+			var m map[string]string
+			m["a"] = "b"
+		}
+		return false
+	})
+}
+
+func TestPanicRecovery(t *testing.T) {
+	file := writeRoutes(t, `panicking: Panicking() -> <shunt>;
+any: * -> <shunt>;`)
+	defer os.Remove(file)
+
+	for _, light := range []bool{false, true} {
+		m, err := New(&Options{RoutesFile: file, CustomPredicates: []routing.PredicateSpec{panicking()}, LightResults: light})
+		require.NoError(t, err)
+		in, out := make(chan *RequestAttributes, 2), make(chan TestResult, 2)
+		in <- &RequestAttributes{Path: "/", Headers: map[string]string{"Panic": "1"}}
+		in <- &RequestAttributes{Path: "/"}
+		close(in)
+		go m.TestManyStream(in, out)
+
+		res := <-out
+		assert.Nil(t, res.Route())
+		var p *PanicError
+		require.True(t, errors.As(res.Err(), &p))
+		assert.Equal(t, "panic while matching the request: assignment to entry in nil map", p.Error())
+		assert.Contains(t, p.Stack, "matcher.panicking.func1(")
+		assert.Contains(t, p.Stack, "routing.(*Routing).Route(")
+		assert.NotContains(t, p.Stack, "runtime/debug")
+		assert.NotContains(t, p.Stack, "matchRoute", "the stack ends at the routing")
+
+		res = <-out
+		require.NoError(t, res.Err(), "the next requests are tested")
+		assert.Equal(t, "any", res.Route().Id)
+		m.Close()
+	}
+}
+
+func TestDisablePanicRecovery(t *testing.T) {
+	file := writeRoutes(t, `panicking: Panicking() -> <shunt>;`)
+	defer os.Remove(file)
+
+	m, err := New(&Options{RoutesFile: file, CustomPredicates: []routing.PredicateSpec{panicking()}, DisablePanicRecovery: true})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Panics(t, func() {
+		m.Test(&RequestAttributes{Path: "/", Headers: map[string]string{"Panic": "1"}})
+	})
+}
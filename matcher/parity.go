@@ -0,0 +1,172 @@
+package matcher
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// Reasons of a ParityDifference
+const (
+	// ParityMatch a route matches the request in one source only
+	ParityMatch = "match"
+	// ParityBackend the routes have different backends
+	ParityBackend = "backend"
+	// ParityFilters the routes have different filter chains
+	ParityFilters = "filters"
+)
+
+// ParityReport result of CompareSources
+type ParityReport struct {
+	// Requests number of requests tested against both sources
+	Requests int `json:"requests"`
+	// Differences number of requests routed differently
+	Differences int `json:"differences"`
+	// Groups the requests routed differently by host and path prefix,
+	// sorted
+	Groups []ParityGroup `json:"groups"`
+}
+
+// ParityGroup the differences of the requests with the same host and the
+// same first path segment
+type ParityGroup struct {
+	// Host host of the requests
+	Host string `json:"host"`
+	// PathPrefix first segment of the paths, eg. /orders
+	PathPrefix string `json:"pathPrefix"`
+	// Differences the requests routed differently, in the requests order
+	Differences []ParityDifference `json:"differences"`
+}
+
+// ParityDifference a request routed differently by the sources
+type ParityDifference struct {
+	// Request the normalized request
+	Request *RequestAttributes `json:"request"`
+	// A the route of the first source, nil without a match
+	A *ParityRoute `json:"a"`
+	// B the route of the second source, nil without a match
+	B *ParityRoute `json:"b"`
+	// Reasons what differs: ParityMatch, ParityBackend and ParityFilters
+	Reasons []string `json:"reasons"`
+}
+
+// ParityRoute the route of a ParityDifference
+type ParityRoute struct {
+	// ID id of the route, not compared
+	ID string `json:"id"`
+	// Backend address of a network backend or a placeholder like <shunt>
+	Backend string `json:"backend"`
+	// LBEndpoints endpoints of a load balanced backend, sorted
+	LBEndpoints []string `json:"lbEndpoints,omitempty"`
+	// Filters the filter chain
+	Filters []string `json:"filters"`
+}
+
+// CompareSources tests the requests against the routes of both options and
+// reports the requests whose matching routes have another backend or filter
+// chain, or match in one source only, eg. an eskip file and the Kubernetes
+// ingresses replacing it, see Options.Kubernetes. The route ids aren't
+// compared, the generated ones never being the same, and the load balanced
+// endpoints are compared in any order. A <shunt> route without filters
+// answers 404 like no route does: the catch-all routes of the ingress hosts
+// are the same as no match. Without requests a request is generated for
+// each route of both sources, see SeedRequests. The Traffic predicates
+// always match.
+func CompareSources(a, b *Options, requests []*RequestAttributes) (*ParityReport, error) {
+	ma, err := newComparedMatcher(a)
+	if err != nil {
+		return nil, err
+	}
+	defer ma.Close()
+	mb, err := newComparedMatcher(b)
+	if err != nil {
+		return nil, err
+	}
+	defer mb.Close()
+
+	if requests == nil {
+		requests = SeedRequests(append(ma.Routes(), mb.Routes()...))
+	}
+	report := &ParityReport{Requests: len(requests), Groups: []ParityGroup{}}
+	groups := make(map[[2]string]*ParityGroup)
+	for _, req := range requests {
+		ra := ma.Test(req)
+		if err := ra.Err(); err != nil {
+			return nil, fmt.Errorf("invalid request %s %s: %v", req.Method, req.Path, matchCause(err))
+		}
+		d := ParityDifference{
+			Request: ra.Attributes(),
+			A:       parityRoute(ra),
+			B:       parityRoute(mb.Test(req)),
+		}
+		if d.Reasons = parityReasons(d.A, d.B); len(d.Reasons) == 0 {
+			continue
+		}
+		report.Differences++
+		key := [2]string{d.Request.Host, pathPrefix(d.Request.Path, 1)}
+		g, ok := groups[key]
+		if !ok {
+			g = &ParityGroup{Host: key[0], PathPrefix: key[1]}
+			groups[key] = g
+		}
+		g.Differences = append(g.Differences, d)
+	}
+	for _, g := range groups {
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		gi, gj := report.Groups[i], report.Groups[j]
+		if gi.Host != gj.Host {
+			return gi.Host < gj.Host
+		}
+		return gi.PathPrefix < gj.PathPrefix
+	})
+	return report, nil
+}
+
+// newComparedMatcher the matcher of CompareSources
+func newComparedMatcher(o *Options) (*matcher, error) {
+	c := *o
+	if c.TrafficMode == TrafficRandom {
+		c.TrafficMode = TrafficAlways
+	}
+	return newMatcher(&c)
+}
+
+// parityRoute the compared parts of the matching route, nil without a
+// match or with a <shunt> without filters
+func parityRoute(res TestResult) *ParityRoute {
+	route := res.Route()
+	if route == nil || routeBackendType(route) == eskip.ShuntBackend && len(route.Filters) == 0 {
+		return nil
+	}
+	r := &ParityRoute{ID: route.Id, Backend: backendString(route), Filters: []string{}}
+	if len(route.LBEndpoints) > 0 {
+		r.LBEndpoints = append([]string{}, route.LBEndpoints...)
+		sort.Strings(r.LBEndpoints)
+	}
+	for _, f := range route.Filters {
+		r.Filters = append(r.Filters, filterString(f))
+	}
+	return r
+}
+
+// parityReasons what differs between the routes
+func parityReasons(a, b *ParityRoute) []string {
+	if a == nil || b == nil {
+		if a == b {
+			return nil
+		}
+		return []string{ParityMatch}
+	}
+	var reasons []string
+	if a.Backend != b.Backend || strings.Join(a.LBEndpoints, " ") != strings.Join(b.LBEndpoints, " ") {
+		reasons = append(reasons, ParityBackend)
+	}
+	if strings.Join(a.Filters, " -> ") != strings.Join(b.Filters, " -> ") {
+		reasons = append(reasons, ParityFilters)
+	}
+	return reasons
+}
@@ -0,0 +1,69 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareSources(t *testing.T) {
+	ingresses := &Options{Kubernetes: &KubernetesOptions{Manifests: shopManifests}}
+
+	report, err := CompareSources(&Options{RoutesFile: "testdata/kubernetes/shop.eskip"}, ingresses, nil)
+	require.NoError(t, err)
+	assert.NotZero(t, report.Requests)
+	assert.Equal(t, 0, report.Differences)
+	assert.Empty(t, report.Groups)
+
+	report, err = CompareSources(&Options{RoutesFile: "testdata/kubernetes/shop-drift.eskip"}, ingresses, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Differences)
+	require.Len(t, report.Groups, 2)
+
+	legacy := report.Groups[0]
+	assert.Equal(t, "/legacy", legacy.PathPrefix)
+	require.Len(t, legacy.Differences, 1)
+	assert.Equal(t, []string{ParityMatch}, legacy.Differences[0].Reasons)
+	assert.Equal(t, "legacy", legacy.Differences[0].A.ID)
+	// the catch-all route is the same as no match
+	assert.Nil(t, legacy.Differences[0].B)
+
+	orders := report.Groups[1]
+	assert.Equal(t, "/orders", orders.PathPrefix)
+	require.Len(t, orders.Differences, 1)
+	d := orders.Differences[0]
+	assert.Equal(t, []string{ParityBackend, ParityFilters}, d.Reasons)
+	assert.Equal(t, &ParityRoute{ID: "orders", Backend: "http://10.3.0.1:80", Filters: []string{`setRequestHeader("X-Team", "orders")`}}, d.A)
+	assert.Equal(t, []string{"http://10.2.0.1:8080", "http://10.2.0.2:8080"}, d.B.LBEndpoints)
+	assert.Equal(t, []string{`setRequestHeader("X-Team", "shop")`}, d.B.Filters)
+	assert.Equal(t, orders.Host, d.Request.Host)
+}
+
+func TestCompareSourcesRequests(t *testing.T) {
+	report, err := CompareSources(
+		&Options{RoutesFile: "testdata/kubernetes/shop-drift.eskip"},
+		&Options{Kubernetes: &KubernetesOptions{Manifests: shopManifests}},
+		[]*RequestAttributes{
+			{Host: "shop.example.org", Path: "/search/shoes"},
+			{Host: "shop.example.org", Path: "/orders/1"},
+			{Host: "other.example.org", Path: "/orders/1"},
+		})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Requests)
+	assert.Equal(t, 1, report.Differences)
+	require.Len(t, report.Groups, 1)
+	assert.Equal(t, ParityGroup{Host: "shop.example.org", PathPrefix: "/orders"}, ParityGroup{Host: report.Groups[0].Host, PathPrefix: report.Groups[0].PathPrefix})
+
+	_, err = CompareSources(&Options{RoutesFile: "testdata/kubernetes/missing.eskip"}, &Options{RoutesFile: "testdata/kubernetes/shop.eskip"}, nil)
+	assert.Error(t, err)
+}
+
+func TestPathPrefix(t *testing.T) {
+	for path, prefix := range map[string]string{"/": "/", "/orders": "/orders", "/orders/1/items": "/orders"} {
+		assert.Equal(t, prefix, pathPrefix(path, 1), path)
+	}
+	for path, prefix := range map[string]string{"/api": "/api", "/api/orders/42": "/api/orders"} {
+		assert.Equal(t, prefix, pathPrefix(path, 2), path)
+	}
+}
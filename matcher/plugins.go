@@ -0,0 +1,186 @@
+package matcher
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/routing"
+)
+
+// PluginError a skipper plugin can't be found or loaded
+type PluginError struct {
+	// Name of the plugin, the file name without .so
+	Name string
+	// Path of the plugin file, empty if it wasn't found
+	Path string
+	Err  error
+}
+
+func (e *PluginError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("plugin %s: %v", e.Name, e.Err)
+	}
+	return fmt.Sprintf("plugin %s: %v", e.Path, e.Err)
+}
+
+// withPlugins returns the options with the filters and the predicates of
+// the plugins appended to the custom ones, the options when there are none
+func withPlugins(o *Options) (*Options, error) {
+	if len(o.PluginDirs) == 0 && len(o.Plugins) == 0 {
+		return o, nil
+	}
+	fs, ps, err := loadPlugins(o.PluginDirs, o.Plugins)
+	if err != nil {
+		return nil, err
+	}
+	with := *o
+	with.CustomFilters = append(append([]filters.Spec{}, o.CustomFilters...), fs...)
+	with.CustomPredicates = append(append([]routing.PredicateSpec{}, o.CustomPredicates...), ps...)
+	return &with, nil
+}
+
+// loadPlugins loads the .so files of the dirs like skipper: the configured
+// plugins, a name followed by the arguments, with their InitPlugin, all the
+// others with their InitPlugin, InitFilter or InitPredicate. The
+// arguments of a plugin are preceded by the lines of the .conf file next
+// to it. The data clients of the plugins are ignored.
+func loadPlugins(dirs []string, configured [][]string) ([]filters.Spec, []routing.PredicateSpec, error) {
+	found := make(map[string]string)
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(path, ".so") {
+				found[strings.TrimSuffix(filepath.Base(path), ".so")] = path
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to search for plugins: %v", err)
+		}
+	}
+
+	var fs []filters.Spec
+	var ps []routing.PredicateSpec
+	loaded := make(map[string]bool)
+	for _, p := range configured {
+		if len(p) == 0 {
+			continue
+		}
+		name := p[0]
+		path, ok := found[name]
+		if !ok && len(dirs) == 0 {
+			return nil, nil, &PluginError{Name: name, Err: fmt.Errorf("not found, no plugin dirs")}
+		}
+		if !ok {
+			return nil, nil, &PluginError{Name: name, Err: fmt.Errorf("not found in the plugin dirs %s", strings.Join(dirs, ", "))}
+		}
+		pf, pp, err := loadPlugin(path, p[1:], true)
+		if err != nil {
+			return nil, nil, &PluginError{Name: name, Path: path, Err: err}
+		}
+		fs, ps = append(fs, pf...), append(ps, pp...)
+		loaded[name] = true
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		if !loaded[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pf, pp, err := loadPlugin(found[name], nil, false)
+		if err != nil {
+			return nil, nil, &PluginError{Name: name, Path: found[name], Err: err}
+		}
+		fs, ps = append(fs, pf...), append(ps, pp...)
+	}
+	return fs, ps, nil
+}
+
+// loadPlugin opens a plugin and calls its init functions, only InitPlugin
+// when multitype, which is then required
+func loadPlugin(path string, args []string, multitype bool) ([]filters.Spec, []routing.PredicateSpec, error) {
+	mod, err := plugin.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	conf, err := readPluginConfig(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the config: %v", err)
+	}
+	args = append(conf, args...)
+
+	var fs []filters.Spec
+	var ps []routing.PredicateSpec
+	sym, err := mod.Lookup("InitPlugin")
+	switch {
+	case err == nil:
+		fn, ok := sym.(func([]string) ([]filters.Spec, []routing.PredicateSpec, []routing.DataClient, error))
+		if !ok {
+			return nil, nil, fmt.Errorf("InitPlugin function has wrong signature")
+		}
+		pf, pp, _, err := fn(args)
+		if err != nil {
+			return nil, nil, fmt.Errorf("InitPlugin returned: %v", err)
+		}
+		fs, ps = append(fs, pf...), append(ps, pp...)
+	case multitype:
+		return nil, nil, fmt.Errorf("no InitPlugin function: %v", err)
+	}
+	if multitype {
+		return fs, ps, nil
+	}
+
+	if sym, err := mod.Lookup("InitFilter"); err == nil {
+		fn, ok := sym.(func([]string) (filters.Spec, error))
+		if !ok {
+			return nil, nil, fmt.Errorf("InitFilter function has wrong signature")
+		}
+		spec, err := fn(args)
+		if err != nil {
+			return nil, nil, fmt.Errorf("InitFilter returned: %v", err)
+		}
+		fs = append(fs, spec)
+	}
+	if sym, err := mod.Lookup("InitPredicate"); err == nil {
+		fn, ok := sym.(func([]string) (routing.PredicateSpec, error))
+		if !ok {
+			return nil, nil, fmt.Errorf("InitPredicate function has wrong signature")
+		}
+		spec, err := fn(args)
+		if err != nil {
+			return nil, nil, fmt.Errorf("InitPredicate returned: %v", err)
+		}
+		ps = append(ps, spec)
+	}
+	return fs, ps, nil
+}
+
+// readPluginConfig the non empty lines of the .conf file of the plugin,
+// those starting with # are comments
+func readPluginConfig(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(strings.TrimSuffix(path, ".so") + ".conf")
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var conf []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && line[0] != '#' {
+			conf = append(conf, line)
+		}
+	}
+	return conf, nil
+}
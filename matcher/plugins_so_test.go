@@ -0,0 +1,62 @@
+//go:build plugins
+// +build plugins
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildPlugin builds the plugin of testdata/plugins into dir
+func buildPlugin(t *testing.T, dir string, name string) string {
+	path := filepath.Join(dir, name+".so")
+	out, err := exec.Command("go", "build", "-buildmode=plugin", "-o", path, "./testdata/plugins/"+name).CombinedOutput()
+	require.NoError(t, err, string(out))
+	return path
+}
+
+func TestNewPlugins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	buildPlugin(t, dir, "tenantauth")
+
+	f, err := ioutil.TempFile("", "plugins-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`orders: Path("/orders") -> tenantAuth("acme") -> <shunt>;
+invalid: Path("/invalid") -> tenantAuth() -> <shunt>;
+`)
+	f.Close()
+
+	_, err = New(&Options{RoutesFile: f.Name()})
+	assert.IsType(t, &MissingFiltersError{}, err, "without the plugin")
+
+	m, err := New(&Options{RoutesFile: f.Name(), PluginDirs: []string{dir}})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, 1, m.RouteCount())
+	res := m.Test(&RequestAttributes{Method: "GET", Path: "/orders"})
+	if assert.NotNil(t, res.Route()) {
+		assert.Equal(t, "orders", res.Route().Id)
+	}
+
+	findings, err := Validate(&Options{RoutesFile: f.Name(), PluginDirs: []string{dir}, Rules: RuleOptions{CheckFilterArgs: true}})
+	require.NoError(t, err)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, RuleInvalidFilterArgs, findings[0].RuleID)
+		assert.Equal(t, "invalid", findings[0].RouteID)
+	}
+
+	_, err = New(&Options{RoutesFile: f.Name(), PluginDirs: []string{dir}, Plugins: [][]string{{"tenantauth"}}})
+	if assert.IsType(t, &PluginError{}, err) {
+		assert.Contains(t, err.Error(), "plugin "+filepath.Join(dir, "tenantauth.so")+": no InitPlugin function")
+	}
+}
@@ -0,0 +1,48 @@
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	broken := filepath.Join(dir, "broken.so")
+	require.NoError(t, ioutil.WriteFile(broken, []byte("not a plugin"), 0644))
+
+	_, err = New(&Options{RoutesFile: "testdata/routes.eskip", PluginDirs: []string{dir}})
+	if assert.IsType(t, &PluginError{}, err) {
+		assert.Equal(t, broken, err.(*PluginError).Path)
+		assert.Contains(t, err.Error(), "plugin "+broken+": ")
+	}
+
+	_, err = New(&Options{RoutesFile: "testdata/routes.eskip", PluginDirs: []string{dir}, Plugins: [][]string{{"tenantauth", "X-Tenant"}}})
+	assert.EqualError(t, err, "plugin tenantauth: not found in the plugin dirs "+dir)
+	_, err = New(&Options{RoutesFile: "testdata/routes.eskip", Plugins: [][]string{{"tenantauth"}}})
+	assert.EqualError(t, err, "plugin tenantauth: not found, no plugin dirs")
+
+	_, err = Validate(&Options{RoutesFile: "testdata/routes.eskip", PluginDirs: []string{filepath.Join(dir, "missing")}})
+	assert.Error(t, err)
+}
+
+func TestReadPluginConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tenantauth.conf"), []byte("# header\nX-Tenant\n\n  strict  \n"), 0644))
+
+	conf, err := readPluginConfig(filepath.Join(dir, "tenantauth.so"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"X-Tenant", "strict"}, conf)
+
+	conf, err = readPluginConfig(filepath.Join(dir, "other.so"))
+	require.NoError(t, err)
+	assert.Nil(t, conf)
+}
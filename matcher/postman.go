@@ -0,0 +1,378 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// postmanVariableRx a variable of a collection, eg. {{baseUrl}}
+var postmanVariableRx = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// postmanDynamicVariables fixed values of the Postman dynamic variables, so
+// the requests of a collection are the same at every load
+var postmanDynamicVariables = map[string]string{
+	"$guid":         "123e4567-e89b-12d3-a456-426614174000",
+	"$randomUUID":   "123e4567-e89b-12d3-a456-426614174000",
+	"$timestamp":    "1600000000",
+	"$isoTimestamp": "2020-09-13T12:26:40.000Z",
+	"$randomInt":    "42",
+}
+
+// postmanContentTypes content types of the raw body languages, the
+// Content-Type header Postman sends unless the request sets one
+var postmanContentTypes = map[string]string{
+	"json":       "application/json",
+	"xml":        "application/xml",
+	"html":       "text/html",
+	"text":       "text/plain",
+	"javascript": "application/javascript",
+}
+
+// postmanMaxDepth limit of the variables referring to other variables
+const postmanMaxDepth = 10
+
+// PostmanOptions options of LoadPostmanWith
+type PostmanOptions struct {
+	// Variables values of the variables, eg. the ones of a Postman
+	// environment, overriding the collection variables
+	Variables map[string]string
+}
+
+// PostmanRequest a request of a Postman collection
+type PostmanRequest struct {
+	// Name names of the folders and of the request joined by " / "
+	Name string `json:"name" yaml:"name"`
+	// Request the request attributes, nil when Err is set
+	Request *RequestAttributes `json:"request,omitempty" yaml:"request,omitempty"`
+	// Err why the request can't be imported, eg. its variables without
+	// value, see UnresolvedVariablesError
+	Err error `json:"-" yaml:"-"`
+}
+
+// UnresolvedVariablesError the variables of a request without value
+type UnresolvedVariablesError struct {
+	// Variables names of the variables, sorted
+	Variables []string
+}
+
+func (e *UnresolvedVariablesError) Error() string {
+	return fmt.Sprintf("unresolved variables: %s", strings.Join(e.Variables, ", "))
+}
+
+// postmanCollection the parts of a v2.1 collection LoadPostman uses
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanVariable `json:"variable"`
+}
+
+type postmanInfo struct {
+	Schema string `json:"schema"`
+}
+
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request json.RawMessage `json:"request"`
+}
+
+type postmanVariable struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Disabled bool        `json:"disabled"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	URL    json.RawMessage   `json:"url"`
+	Header []postmanVariable `json:"header"`
+	Body   *postmanBody      `json:"body"`
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Protocol string            `json:"protocol"`
+	Host     json.RawMessage   `json:"host"`
+	Port     string            `json:"port"`
+	Path     json.RawMessage   `json:"path"`
+	Query    []postmanVariable `json:"query"`
+	Variable []postmanVariable `json:"variable"`
+}
+
+type postmanBody struct {
+	Mode    string `json:"mode"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+// LoadPostman loads the requests of a Postman collection, see
+// LoadPostmanWith, with the collection variables only. The requests which
+// can't be imported, eg. with variables without value, are left out.
+func LoadPostman(path string) ([]*RequestAttributes, error) {
+	requests, err := LoadPostmanWith(path, PostmanOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var attrs []*RequestAttributes
+	for _, r := range requests {
+		if r.Err == nil {
+			attrs = append(attrs, r.Request)
+		}
+	}
+	return attrs, nil
+}
+
+// LoadPostmanWith loads the requests of a Postman v2.1 collection in order,
+// the requests of the folders where the folders are. The {{name}} variables
+// are replaced by the values of the options or of the collection, the
+// dynamic ones like {{$guid}} by fixed values, and the :name path variables
+// by the url variables. The method, the url, the enabled headers and query
+// parameters are imported, the Host header becomes the request host and a
+// raw body sets the Content-Type of its language, eg. application/json,
+// unless the request has one; the body itself isn't kept. A request which
+// can't be imported has its Err set, only an unreadable file or an invalid
+// collection fail the import.
+func LoadPostmanWith(path string, o PostmanOptions) ([]PostmanRequest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c postmanCollection
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%s: invalid Postman collection: %v", path, err)
+	}
+	if c.Info.Schema != "" && !strings.Contains(c.Info.Schema, "/v2.") {
+		return nil, fmt.Errorf("%s: unsupported Postman collection schema %s, export the collection as v2.1", path, c.Info.Schema)
+	}
+
+	vars := make(map[string]string)
+	for _, v := range c.Variable {
+		if !v.Disabled {
+			vars[v.Key] = fmt.Sprint(v.Value)
+		}
+	}
+	for k, v := range o.Variables {
+		vars[k] = v
+	}
+
+	requests := []PostmanRequest{}
+	var walk func(items []postmanItem, folders []string)
+	walk = func(items []postmanItem, folders []string) {
+		for _, item := range items {
+			name := strings.Join(append(folders, item.Name), " / ")
+			if item.Request == nil {
+				walk(item.Item, append(folders, item.Name))
+				continue
+			}
+			attrs, err := postmanAttributes(item.Request, vars)
+			requests = append(requests, PostmanRequest{Name: name, Request: attrs, Err: err})
+		}
+	}
+	walk(c.Item, nil)
+	return requests, nil
+}
+
+// postmanAttributes the attributes of a request of the collection
+func postmanAttributes(raw json.RawMessage, vars map[string]string) (*RequestAttributes, error) {
+	var req postmanRequest
+	var rawURL string
+	if err := json.Unmarshal(raw, &rawURL); err != nil {
+		if err := json.Unmarshal(raw, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %v", err)
+		}
+	} else {
+		req.URL, _ = json.Marshal(rawURL)
+	}
+
+	r := &postmanResolver{vars: vars, unresolved: map[string]bool{}}
+	u, pathVars, query, err := parsePostmanURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	attrs := &RequestAttributes{
+		Method:  strings.ToUpper(r.resolve(req.Method)),
+		Query:   map[string]string{},
+		Headers: map[string]string{},
+	}
+	if attrs.Method == "" {
+		attrs.Method = "GET"
+	}
+	target := r.resolve(u)
+	for _, h := range req.Header {
+		if h.Disabled {
+			continue
+		}
+		name, value := r.resolve(h.Key), r.resolve(fmt.Sprint(h.Value))
+		if strings.EqualFold(name, "Host") {
+			attrs.Host = value
+			continue
+		}
+		attrs.Headers[name] = value
+	}
+	if req.Body != nil && req.Body.Mode == "raw" {
+		if ct, ok := postmanContentTypes[req.Body.Options.Raw.Language]; ok && !hasHeader(attrs.Headers, "Content-Type") {
+			attrs.Headers["Content-Type"] = ct
+		}
+	}
+	if vars := r.names(); len(vars) > 0 {
+		return nil, &UnresolvedVariablesError{Variables: vars}
+	}
+
+	if !strings.Contains(target, "://") {
+		// like Postman, urls without scheme are http
+		target = "http://" + target
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %v", target, err)
+	}
+	if attrs.Host == "" {
+		attrs.Host = parsed.Host
+	}
+	segments := strings.Split(parsed.EscapedPath(), "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") {
+			if v, ok := pathVars[s[1:]]; ok {
+				segments[i] = url.PathEscape(r.resolve(v))
+			}
+		}
+	}
+	attrs.Path = strings.Join(segments, "/")
+	if attrs.Path == "" {
+		attrs.Path = "/"
+	}
+	if query != nil {
+		for _, q := range query {
+			if !q.Disabled {
+				attrs.Query[r.resolve(q.Key)] = r.resolve(fmt.Sprint(q.Value))
+			}
+		}
+	} else {
+		for key, values := range parsed.Query() {
+			attrs.Query[key] = values[0]
+		}
+	}
+	if vars := r.names(); len(vars) > 0 {
+		return nil, &UnresolvedVariablesError{Variables: vars}
+	}
+	return attrs, nil
+}
+
+// parsePostmanURL the url of a request without its query, its path
+// variables and its query parameters, nil when the url has none or is a
+// string
+func parsePostmanURL(raw json.RawMessage) (string, map[string]string, []postmanVariable, error) {
+	if len(raw) == 0 {
+		return "", nil, nil, fmt.Errorf("missing url")
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil, nil, nil
+	}
+	var u postmanURL
+	if err := json.Unmarshal(raw, &u); err != nil {
+		return "", nil, nil, fmt.Errorf("invalid url: %v", err)
+	}
+	pathVars := make(map[string]string)
+	for _, v := range u.Variable {
+		if !v.Disabled {
+			pathVars[v.Key] = fmt.Sprint(v.Value)
+		}
+	}
+	query := u.Query
+	if u.Query == nil && u.Raw != "" {
+		// the query parameters of the raw url
+		return u.Raw, pathVars, nil, nil
+	}
+	if query == nil {
+		query = []postmanVariable{}
+	}
+
+	target := u.Raw
+	if i := strings.IndexAny(target, "?#"); i >= 0 {
+		target = target[:i]
+	}
+	if target == "" {
+		target = postmanParts(u.Host, ".")
+		if u.Port != "" {
+			target += ":" + u.Port
+		}
+		if path := postmanParts(u.Path, "/"); path != "" {
+			target += "/" + path
+		}
+		if u.Protocol != "" {
+			target = u.Protocol + "://" + target
+		}
+	}
+	if target == "" {
+		return "", nil, nil, fmt.Errorf("missing url")
+	}
+	return target, pathVars, query, nil
+}
+
+// postmanParts joins the host or path parts of a url, a string or an array
+func postmanParts(raw json.RawMessage, sep string) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.TrimPrefix(s, "/")
+	}
+	var parts []string
+	json.Unmarshal(raw, &parts)
+	return strings.Join(parts, sep)
+}
+
+// postmanResolver replaces the variables and records the ones without value
+type postmanResolver struct {
+	vars       map[string]string
+	unresolved map[string]bool
+}
+
+// resolve replaces the variables of s, the ones without value are kept
+func (r *postmanResolver) resolve(s string) string {
+	for depth := 0; depth < postmanMaxDepth && strings.Contains(s, "{{"); depth++ {
+		replaced := postmanVariableRx.ReplaceAllStringFunc(s, func(m string) string {
+			name := strings.TrimSpace(m[2 : len(m)-2])
+			if v, ok := r.vars[name]; ok {
+				return v
+			}
+			if v, ok := postmanDynamicVariables[name]; ok {
+				return v
+			}
+			r.unresolved[name] = true
+			return m
+		})
+		if replaced == s {
+			break
+		}
+		s = replaced
+	}
+	return s
+}
+
+// names the variables without value, sorted
+func (r *postmanResolver) names() []string {
+	names := make([]string, 0, len(r.unresolved))
+	for name := range r.unresolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasHeader true if the headers have name, case insensitive
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
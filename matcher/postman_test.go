@@ -0,0 +1,107 @@
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPostmanWith(t *testing.T) {
+	requests, err := LoadPostmanWith("testdata/postman/collection.json", PostmanOptions{
+		Variables: map[string]string{"token": "abc", "orderId": "42"},
+	})
+	require.NoError(t, err)
+
+	var names []string
+	for _, r := range requests {
+		names = append(names, r.Name)
+	}
+	assert.Equal(t, []string{"orders / get order", "orders / create order", "orders / legacy / export", "health", "admin"}, names)
+
+	assert.Equal(t, &RequestAttributes{
+		Method:  "GET",
+		Path:    "/orders/42",
+		Host:    "api.example.org",
+		Query:   map[string]string{"expand": "items"},
+		Headers: map[string]string{"Authorization": "Bearer abc"},
+	}, requests[0].Request)
+	assert.Equal(t, &RequestAttributes{
+		Method:  "POST",
+		Path:    "/orders",
+		Host:    "api.example.org",
+		Query:   map[string]string{},
+		Headers: map[string]string{"Content-Type": "application/json"},
+	}, requests[1].Request)
+	assert.Equal(t, &RequestAttributes{
+		Method:  "GET",
+		Path:    "/export",
+		Host:    "legacy.example.org",
+		Query:   map[string]string{"format": "csv"},
+		Headers: map[string]string{},
+	}, requests[2].Request)
+	assert.Equal(t, "/health", requests[3].Request.Path)
+
+	// the disabled collection variable has no value
+	assert.Nil(t, requests[4].Request)
+	assert.EqualError(t, requests[4].Err, "unresolved variables: adminUrl")
+}
+
+func TestLoadPostmanUnresolved(t *testing.T) {
+	requests, err := LoadPostmanWith("testdata/postman/collection.json", PostmanOptions{})
+	require.NoError(t, err)
+	require.Len(t, requests, 5)
+	assert.Equal(t, &UnresolvedVariablesError{Variables: []string{"token"}}, requests[0].Err)
+	// the variables of the body aren't resolved, the body isn't kept
+	assert.NoError(t, requests[1].Err)
+	// baseUrl refers to apiHost
+	assert.Equal(t, "api.example.org", requests[3].Request.Host)
+
+	attrs, err := LoadPostman("testdata/postman/collection.json")
+	require.NoError(t, err)
+	assert.Len(t, attrs, 3)
+}
+
+func TestLoadPostmanErrors(t *testing.T) {
+	_, err := LoadPostman("testdata/postman/missing.json")
+	assert.Error(t, err)
+
+	f, err := ioutil.TempFile("", "postman-*.json")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`{"info": {"schema": "https://schema.getpostman.com/json/collection/v1.0.0/collection.json"}}`)
+	f.Close()
+	_, err = LoadPostman(f.Name())
+	assert.EqualError(t, err, f.Name()+": unsupported Postman collection schema https://schema.getpostman.com/json/collection/v1.0.0/collection.json, export the collection as v2.1")
+}
+
+func TestPostmanCollectionRoutes(t *testing.T) {
+	m, err := New(&Options{RoutesFile: "testdata/postman/routes.eskip"})
+	require.NoError(t, err)
+	defer m.Close()
+
+	requests, err := LoadPostmanWith("testdata/postman/collection.json", PostmanOptions{
+		Variables: map[string]string{"token": "abc", "adminUrl": "https://admin.example.org"},
+	})
+	require.NoError(t, err)
+
+	routes := map[string]string{}
+	for _, r := range requests {
+		require.NoError(t, r.Err, r.Name)
+		res := m.Test(r.Request)
+		require.NoError(t, res.Err(), r.Name)
+		routes[r.Name] = ""
+		if res.Route() != nil {
+			routes[r.Name] = res.Route().Id
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"orders / get order":       "order",
+		"orders / create order":    "createOrder",
+		"orders / legacy / export": "export",
+		"health":                   "health",
+		"admin":                    "",
+	}, routes)
+}
@@ -0,0 +1,90 @@
+package matcher
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/routing"
+)
+
+// PredicateImpact tests every request against the routes of the options as
+// they are and with the named predicates always matching, see
+// MockPredicates, and reports the requests routed differently grouped by the
+// mocked predicates of the routes they match on either side, together with
+// the Vestigial predicates none of the requests depends on, eg. before
+// removing a custom predicate. No impact over the requests is no proof a
+// predicate is unused. Without requests one is generated for each route,
+// see SeedRequests. The Traffic predicates always match.
+func PredicateImpact(o *Options, names []string, requests []*RequestAttributes) (*ImpactReport, error) {
+	names = uniqueSorted(names)
+	mocked := *o
+	mocked.CustomPredicates = append(append([]routing.PredicateSpec{}, o.CustomPredicates...), MockPredicates(names)...)
+	m, err := newComparedMatcher(&mocked)
+	if err != nil {
+		return nil, err
+	}
+	// the routes rejected without the mocks load with them
+	routes := m.Routes()
+	m.Close()
+	if requests == nil {
+		requests = SeedRequests(routes)
+	}
+	diff, err := Diff(o, &mocked, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImpactReport{
+		Change:     "mocked predicates " + strings.Join(names, ", "),
+		Requests:   diff.Requests,
+		Unchanged:  diff.Unchanged,
+		Changes:    diff.Changes,
+		Routes:     changedRoutes(diff.Changes),
+		Predicates: []PredicateChanges{},
+		Vestigial:  []string{},
+	}
+	isMocked := make(map[string]bool, len(names))
+	for _, name := range names {
+		isMocked[name] = true
+	}
+	byID := routesByID(routes)
+	byName := make(map[string][]RequestChange)
+	for _, c := range diff.Changes {
+		used := make(map[string]bool)
+		for _, res := range []*ResultData{c.New, c.Old} {
+			if route := byID[res.RouteID]; res.Matched && route != nil {
+				for _, p := range Predicates(route) {
+					if isMocked[p.Name] && !used[p.Name] {
+						used[p.Name] = true
+						byName[p.Name] = append(byName[p.Name], c)
+					}
+				}
+			}
+		}
+	}
+	for _, name := range names {
+		if changes, ok := byName[name]; ok {
+			report.Predicates = append(report.Predicates, PredicateChanges{Name: name, Changes: changes})
+		} else {
+			report.Vestigial = append(report.Vestigial, name)
+		}
+	}
+	if len(report.Vestigial) > 0 {
+		report.Caution = fmt.Sprintf("no impact over the %d tested request(s) is no proof a predicate is unused, other requests can depend on it", report.Requests)
+	}
+	return report, nil
+}
+
+// uniqueSorted a sorted copy of the strings without duplicates
+func uniqueSorted(s []string) []string {
+	sorted := append([]string{}, s...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,72 @@
+package matcher
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/routing"
+)
+
+func tenantIs() routing.PredicateSpec {
+	return PredicateFunc("TenantIs", 1, func(args []interface{}, req *http.Request) bool {
+		return req.Header.Get("X-Tenant") == args[0]
+	})
+}
+
+func TestPredicateImpact(t *testing.T) {
+	file := writeRoutes(t, `acme: TenantIs("acme") && Path("/orders") -> "https://acme.example.org";
+orders: Path("/orders") -> "https://orders.example.org";
+legacy: Beta() && Path("/legacy") -> "https://legacy.example.org";`)
+	defer os.Remove(file)
+
+	o := &Options{RoutesFile: file, CustomPredicates: []routing.PredicateSpec{tenantIs(), MockPredicate("Beta", true)}}
+	report, err := PredicateImpact(o, []string{"TenantIs", "Beta"}, []*RequestAttributes{
+		{Method: "GET", Path: "/orders"},
+		{Method: "GET", Path: "/orders", Headers: map[string]string{"X-Tenant": "acme"}},
+		{Method: "GET", Path: "/legacy"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Requests)
+	assert.Equal(t, 2, report.Unchanged)
+	require.Len(t, report.Changes, 1)
+	assert.Equal(t, "orders", report.Changes[0].Old.RouteID)
+	assert.Equal(t, "acme", report.Changes[0].New.RouteID)
+	assert.Equal(t, []string{"acme", "orders"}, report.Routes)
+	require.Len(t, report.Predicates, 1)
+	assert.Equal(t, "TenantIs", report.Predicates[0].Name)
+	assert.Equal(t, report.Changes, report.Predicates[0].Changes)
+	assert.Equal(t, []string{"Beta"}, report.Vestigial, "Beta matches every request anyway")
+	assert.Contains(t, report.Caution, "no proof")
+
+	var b bytes.Buffer
+	require.NoError(t, report.WriteText(&b))
+	assert.Contains(t, b.String(), "1 of 3 request(s) change with mocked predicates Beta, TenantIs, route(s) acme, orders\n")
+	assert.Contains(t, b.String(), "predicate TenantIs: 1 request(s) change\n")
+	assert.Contains(t, b.String(), "no impact: Beta\n")
+	assert.Contains(t, b.String(), "caution: no impact over the 3 tested request(s) is no proof")
+	assert.NotContains(t, b.String(), "collision")
+}
+
+func TestPredicateImpactSeedRequests(t *testing.T) {
+	file := writeRoutes(t, `acme: TenantIs("acme") && Path("/orders") -> "https://acme.example.org";
+orders: Path("/orders") -> "https://orders.example.org";`)
+	defer os.Remove(file)
+
+	// the request generated for both routes has no tenant
+	o := &Options{RoutesFile: file, CustomPredicates: []routing.PredicateSpec{tenantIs()}}
+	report, err := PredicateImpact(o, []string{"TenantIs"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Requests)
+	assert.Len(t, report.Changes, 1)
+	assert.Empty(t, report.Vestigial)
+	assert.Empty(t, report.Caution)
+
+	var b bytes.Buffer
+	require.NoError(t, report.WriteJSON(&b))
+	assert.Contains(t, b.String(), `"name": "TenantIs"`)
+	assert.NotContains(t, b.String(), `"collisions"`)
+}
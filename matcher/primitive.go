@@ -0,0 +1,100 @@
+package matcher
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+const (
+	truePredicate   = "True"
+	falsePredicate  = "False"
+	weightPredicate = "Weight"
+)
+
+// maxWeight the largest n of a Weight(n), each unit being a predicate of the
+// route, see weightPostProcessor
+const maxWeight = 1000
+
+// primitiveSpecs the True, False and Weight predicates of the newer skipper
+// versions, missing in the one compiled in
+func primitiveSpecs() []routing.PredicateSpec {
+	return []routing.PredicateSpec{
+		&constantSpec{name: truePredicate, matches: true},
+		&constantSpec{name: falsePredicate, matches: false},
+		&weightSpec{},
+	}
+}
+
+// constantSpec True() or False(), without arguments
+type constantSpec struct {
+	name    string
+	matches bool
+}
+
+func (s *constantSpec) Name() string { return s.name }
+
+func (s *constantSpec) Create(args []interface{}) (routing.Predicate, error) {
+	if len(args) != 0 {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+	return mockPredicate(s.matches), nil
+}
+
+// weightSpec Weight(n), it matches every request and adds n to the weight
+// of the route, the number of its predicates the routing prefers the routes
+// with more of, see weightPostProcessor
+type weightSpec struct{}
+
+func (s *weightSpec) Name() string { return weightPredicate }
+
+func (s *weightSpec) Create(args []interface{}) (routing.Predicate, error) {
+	if len(args) != 1 {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+	n, ok := args[0].(float64)
+	if ok && n > maxWeight {
+		return nil, fmt.Errorf("weight %.0f over the maximum %d", n, maxWeight)
+	}
+	if !ok || n != float64(int(n)) {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+	return weight(n), nil
+}
+
+// weight a Weight predicate
+type weight int
+
+func (weight) Match(*http.Request) bool { return true }
+
+// weightPostProcessor gives the routes with a Weight(n) the weight of n
+// predicates more like newer skipper versions: the Weight predicate is
+// replaced with n predicates matching every request, none when n <= 0
+type weightPostProcessor struct{}
+
+func (weightPostProcessor) Do(routes []*routing.Route) []*routing.Route {
+	for _, r := range routes {
+		var padded []routing.Predicate
+		for i, p := range r.Predicates {
+			w, ok := p.(weight)
+			if !ok {
+				if padded != nil {
+					padded = append(padded, p)
+				}
+				continue
+			}
+			if padded == nil {
+				padded = append([]routing.Predicate{}, r.Predicates[:i]...)
+			}
+			for j := 0; j < int(w); j++ {
+				padded = append(padded, mockPredicate(true))
+			}
+		}
+		if padded != nil {
+			r.Predicates = padded
+		}
+	}
+	return routes
+}
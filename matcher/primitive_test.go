@@ -0,0 +1,100 @@
+package matcher
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPrimitiveMatcher(t *testing.T, routes string) Matcher {
+	file := writeRoutes(t, routes)
+	defer os.Remove(file)
+
+	m, err := New(&Options{RoutesFile: file})
+	require.NoError(t, err)
+	return m
+}
+
+func TestPrimitivePredicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		routes  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name: "the route with more predicates wins",
+			routes: `header: Path("/orders") && Header("X-Beta", "1") -> <shunt>;
+plain: Path("/orders") -> <shunt>;`,
+			headers: map[string]string{"X-Beta": "1"},
+			want:    "header",
+		},
+		{
+			name: "weight breaks the tie",
+			routes: `header: Path("/orders") && Header("X-Beta", "1") -> <shunt>;
+weighted: Path("/orders") && Weight(2) -> <shunt>;`,
+			headers: map[string]string{"X-Beta": "1"},
+			want:    "weighted",
+		},
+		{
+			name: "weight 0 adds nothing",
+			routes: `header: Path("/orders") && Header("X-Beta", "1") -> <shunt>;
+weighted: Path("/orders") && Weight(0) -> <shunt>;`,
+			headers: map[string]string{"X-Beta": "1"},
+			want:    "header",
+		},
+		{
+			name: "True() padding counts like a predicate",
+			routes: `method: Path("/orders") && Method("GET") -> <shunt>;
+padded: Path("/orders") && True() && True() -> <shunt>;`,
+			want: "padded",
+		},
+		{
+			name: "False() never matches",
+			routes: `never: Path("/orders") && False() -> <shunt>;
+plain: Path("/orders") -> <shunt>;`,
+			want: "plain",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newPrimitiveMatcher(t, tt.routes)
+			defer m.Close()
+			assert.Equal(t, 2, m.RouteCount())
+			res := m.Test(&RequestAttributes{Method: "GET", Path: "/orders", Headers: tt.headers})
+			if assert.NotNil(t, res.Route()) {
+				assert.Equal(t, tt.want, res.Route().Id)
+			}
+		})
+	}
+}
+
+func TestPrimitivePredicatesInvalidArgs(t *testing.T) {
+	m := newPrimitiveMatcher(t, `a: Path("/a") && True(1) -> <shunt>;
+b: Path("/b") && Weight("high") -> <shunt>;
+c: Path("/c") && Weight(1.5) -> <shunt>;
+d: Path("/d") && Weight(3) -> <shunt>;
+`)
+	defer m.Close()
+	assert.Equal(t, 1, m.RouteCount())
+}
+
+func TestPrimitivePredicatesLargeWeight(t *testing.T) {
+	m := newPrimitiveMatcher(t, `max: Path("/max") && Weight(1000) -> <shunt>;
+huge: Path("/huge") && Weight(100000000) -> <shunt>;
+overflow: Path("/overflow") && Weight(100000000000000000000000000000) -> <shunt>;
+`)
+	defer m.Close()
+	assert.Equal(t, 1, m.RouteCount())
+	assert.Equal(t, "max", routeID(m.Test(&RequestAttributes{Method: "GET", Path: "/max"})))
+
+	errs := m.LoadErrors()
+	require.Len(t, errs, 2)
+	assert.Equal(t, "huge", errs[0].RouteID)
+	assert.Equal(t, RuleInvalidPredicateArgs, errs[0].Rule)
+	assert.EqualError(t, errs[0], "route huge: predicate Weight(100000000): weight 100000000 over the maximum 1000")
+	assert.Equal(t, "overflow", errs[1].RouteID)
+	assert.Contains(t, errs[1].Error(), "over the maximum 1000")
+}
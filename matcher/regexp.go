@@ -0,0 +1,105 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// invalidRegexps reports the regexp predicates failing to compile, which
+// knock the route out of the table, and warns on the pathological ones
+func (l *linter) invalidRegexps() {
+	for _, r := range l.routes {
+		for _, p := range Predicates(r.route) {
+			pattern, ok := regexpArg(p)
+			if !ok {
+				continue
+			}
+			re, err := syntax.Parse(pattern, syntax.Perl)
+			if err != nil {
+				l.report(RuleInvalidRegexp, SeverityError, r, "invalid regexp %s: %s, the route is ignored",
+					predicateString(p), regexpError(pattern, err))
+				continue
+			}
+			if problem := pathologicalRegexp(re); problem != "" {
+				l.report(RuleInvalidRegexp, SeverityWarning, r, "pathological regexp %s: %s", predicateString(p), problem)
+			}
+		}
+	}
+}
+
+// regexpArg the regexp argument of a predicate, see isRegexpPredicate
+func regexpArg(p *eskip.Predicate) (string, bool) {
+	if !isRegexpPredicate(p) {
+		return "", false
+	}
+	if p.Name == hostPredicate || p.Name == pathRegexpPredicate {
+		return stringArg(p, 0)
+	}
+	return stringArg(p, 1)
+}
+
+// regexpError describes a compile error with the position of the failing
+// expression in the pattern, eg. invalid escape sequence `\q` at position 5
+func regexpError(pattern string, err error) string {
+	serr, ok := err.(*syntax.Error)
+	if !ok {
+		return err.Error()
+	}
+	msg := string(serr.Code) + " `" + serr.Expr + "`"
+	if i := strings.Index(pattern, serr.Expr); i >= 0 {
+		msg += fmt.Sprintf(" at position %d", i+1)
+	}
+	return msg
+}
+
+// pathologicalRegexp a heuristic description of why a regexp is pathological,
+// empty if it isn't: unanchored .* at both ends, or nested unbounded repetitions
+func pathologicalRegexp(re *syntax.Regexp) string {
+	if re.Op == syntax.OpConcat && len(re.Sub) > 1 && isAnyStar(re.Sub[0]) && isAnyStar(re.Sub[len(re.Sub)-1]) {
+		return "unanchored .* at both ends, use the regexp without them"
+	}
+	if nested := nestedRepetition(re); nested != nil {
+		return "nested repetition " + nested.String() + " can backtrack catastrophically"
+	}
+	return ""
+}
+
+// isAnyStar true for .*
+func isAnyStar(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpStar && (re.Sub[0].Op == syntax.OpAnyChar || re.Sub[0].Op == syntax.OpAnyCharNotNL)
+}
+
+// nestedRepetition returns the first unbounded repetition containing
+// another one, eg. (a+)+
+func nestedRepetition(re *syntax.Regexp) *syntax.Regexp {
+	for _, sub := range re.Sub {
+		if isUnbounded(re) && hasRepetition(sub) {
+			return re
+		}
+		if nested := nestedRepetition(sub); nested != nil {
+			return nested
+		}
+	}
+	return nil
+}
+
+// hasRepetition true if re is or contains an unbounded repetition
+func hasRepetition(re *syntax.Regexp) bool {
+	if isUnbounded(re) {
+		return true
+	}
+	for _, sub := range re.Sub {
+		if hasRepetition(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnbounded true for *, + and {n,}
+func isUnbounded(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpStar || re.Op == syntax.OpPlus || re.Op == syntax.OpRepeat && re.Max == -1
+}
@@ -0,0 +1,136 @@
+package matcher
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// redactedValue replaces the values of the redacted headers
+const redactedValue = "<redacted>"
+
+// DefaultRedactedHeaders names of the headers carrying credentials,
+// their values are hidden when a request is printed
+var DefaultRedactedHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"X-Api-Key",
+}
+
+// ParseRawRequest parses a raw HTTP/1.x request (request line, headers and
+// an optional body) like the ones captured by proxies or copied from browser
+// developer tools. Lines can end with "\r\n" or "\n". The body is discarded,
+// the Host header becomes the request host and repeated headers and query
+// parameters are joined with ", " (the first query value is kept).
+func ParseRawRequest(r io.Reader) (*RequestAttributes, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// editors and clipboards often add blank lines before the request line
+	b = bytes.TrimLeft(b, " \t\r\n")
+	if !bytes.Contains(b, []byte("\n\n")) && !bytes.Contains(b, []byte("\r\n\r\n")) {
+		// complete the header section of requests without body
+		b = append(b, '\n', '\n')
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw request: %v", err)
+	}
+	defer req.Body.Close()
+	return requestAttributes(req), nil
+}
+
+// requestAttributes the attributes of a request, repeated headers are joined
+// with ", " and the first value of a query parameter is kept
+func requestAttributes(req *http.Request) *RequestAttributes {
+	attrs := &RequestAttributes{
+		Method:  req.Method,
+		Path:    req.URL.EscapedPath(),
+		Host:    req.Host,
+		Query:   map[string]string{},
+		Headers: map[string]string{},
+	}
+	for key, values := range req.URL.Query() {
+		attrs.Query[key] = values[0]
+	}
+	for key, values := range req.Header {
+		attrs.Headers[key] = strings.Join(values, ", ")
+	}
+	return attrs
+}
+
+// RawRequest returns the HTTP/1.1 representation of the request attributes
+// without body, headers sorted by name. The values of the redacted headers
+// (case insensitive) are replaced by a placeholder.
+func RawRequest(attributes *RequestAttributes, redacted []string) string {
+	req, err := createHTTPRequest(copyAttributes(attributes))
+	if err != nil {
+		return fmt.Sprintf("invalid request: %v", err)
+	}
+
+	hidden := make(map[string]bool, len(redacted))
+	for _, name := range redacted {
+		hidden[http.CanonicalHeaderKey(name)] = true
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\n", req.Method, req.URL.RequestURI())
+	if req.Host != "" {
+		fmt.Fprintf(&b, "Host: %s\n", req.Host)
+	}
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := strings.Join(req.Header[name], ", ")
+		if hidden[name] {
+			value = redactedValue
+		}
+		fmt.Fprintf(&b, "%s: %s\n", name, value)
+	}
+	return b.String()
+}
+
+// redactedHeaders the canonical names of DefaultRedactedHeaders and of the
+// other headers
+func redactedHeaders(other []string) map[string]bool {
+	redacted := make(map[string]bool)
+	for _, name := range append(append([]string{}, DefaultRedactedHeaders...), other...) {
+		redacted[http.CanonicalHeaderKey(name)] = true
+	}
+	return redacted
+}
+
+// redactHeaders copies the attributes with the values of the redacted
+// headers hidden
+func redactHeaders(a *RequestAttributes, redacted map[string]bool) *RequestAttributes {
+	c := copyAttributes(a)
+	if len(a.Headers) == 0 {
+		return c
+	}
+	c.Headers = make(map[string]string, len(a.Headers))
+	for name, value := range a.Headers {
+		if redacted[http.CanonicalHeaderKey(name)] {
+			value = redactedValue
+		}
+		c.Headers[name] = value
+	}
+	return c
+}
+
+// copyAttributes returns a copy of the attributes which can be
+// normalized without changing the original ones
+func copyAttributes(a *RequestAttributes) *RequestAttributes {
+	c := *a
+	return &c
+}
@@ -0,0 +1,107 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRawRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want *RequestAttributes
+		err  bool
+	}{
+		{
+			name: "crlf with body",
+			raw: "POST /v1/orders?id=2&id=3&page=1 HTTP/1.1\r\n" +
+				"Host: api.example.org\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Accept: text/html\r\n" +
+				"Accept: application/json\r\n" +
+				"Content-Length: 7\r\n" +
+				"\r\n" +
+				`{"a":1}`,
+			want: &RequestAttributes{
+				Method: "POST",
+				Path:   "/v1/orders",
+				Host:   "api.example.org",
+				Query:  map[string]string{"id": "2", "page": "1"},
+				Headers: map[string]string{
+					"Content-Type":   "application/json",
+					"Accept":         "text/html, application/json",
+					"Content-Length": "7",
+				},
+			},
+		},
+		{
+			name: "lf without trailing blank line",
+			raw:  "\n\nGET /tenant HTTP/1.1\nHost: www.example.org\nX-Tenant: acme",
+			want: &RequestAttributes{
+				Method:  "GET",
+				Path:    "/tenant",
+				Host:    "www.example.org",
+				Query:   map[string]string{},
+				Headers: map[string]string{"X-Tenant": "acme"},
+			},
+		},
+		{
+			name: "absolute uri",
+			raw:  "DELETE http://api.example.org/orders/1 HTTP/1.1\n\n",
+			want: &RequestAttributes{
+				Method:  "DELETE",
+				Path:    "/orders/1",
+				Host:    "api.example.org",
+				Query:   map[string]string{},
+				Headers: map[string]string{},
+			},
+		},
+		{
+			name: "not a request",
+			raw:  "routes.eskip",
+			err:  true,
+		},
+		{
+			name: "empty",
+			raw:  "",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs, err := ParseRawRequest(strings.NewReader(tt.raw))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, attrs)
+			}
+		})
+	}
+}
+
+func TestRawRequest(t *testing.T) {
+	attrs := &RequestAttributes{
+		Path:  "orders",
+		Host:  "api.example.org",
+		Query: map[string]string{"id": "2"},
+		Headers: map[string]string{
+			"authorization": "Bearer secret",
+			"X-Tenant":      "acme",
+			"Cookie":        "session=secret",
+		},
+	}
+	assert.Equal(t, "GET /orders?id=2 HTTP/1.1\n"+
+		"Host: api.example.org\n"+
+		"Authorization: <redacted>\n"+
+		"Cookie: <redacted>\n"+
+		"X-Tenant: acme\n", RawRequest(attrs, DefaultRedactedHeaders))
+
+	// attributes aren't normalized
+	assert.Equal(t, "orders", attrs.Path)
+	assert.Equal(t, "", attrs.Method)
+}
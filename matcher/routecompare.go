@@ -0,0 +1,80 @@
+package matcher
+
+import (
+	"sort"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/zalando/skipper/eskip"
+)
+
+// RouteCompareOption changes what RouteCompareOptions compares
+type RouteCompareOption func(*routeCompare)
+
+// routeCompare what the routes are compared on
+type routeCompare struct {
+	ignoreBackends   bool
+	ignoreFilterArgs bool
+}
+
+// IgnoreBackends compares the routes without their backends
+func IgnoreBackends() RouteCompareOption {
+	return func(c *routeCompare) { c.ignoreBackends = true }
+}
+
+// IgnoreFilterArgs compares the filters of the routes by name, in their
+// order, without their arguments
+func IgnoreFilterArgs() RouteCompareOption {
+	return func(c *routeCompare) { c.ignoreFilterArgs = true }
+}
+
+// comparedRoute the canonical form of a route RouteCompareOptions compares,
+// the definitions like in eskip
+type comparedRoute struct {
+	Id         string
+	Predicates []string
+	Filters    []string
+	Backend    string
+}
+
+// RouteCompareOptions returns the options of cmp.Diff and cmp.Equal
+// comparing the routes by their canonical form, eg.
+// cmp.Diff(wantRoute, result.Route(), matcher.RouteCompareOptions()):
+// the predicates, the legacy fields (Path, Host, Method, ...) included, as
+// sorted eskip definitions so that Path("/orders") parsed and built with
+// eskip.Predicate are equal, the filters in their order and the backend
+// like in eskip, the deprecated Shunt field included. The Name and the
+// Namespace aren't compared, the numbers are equal whether int or float64.
+func RouteCompareOptions(opts ...RouteCompareOption) cmp.Options {
+	c := &routeCompare{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return cmp.Options{cmp.Transformer("matcher.CanonicalRoute", c.canonical)}
+}
+
+// canonical the compared form of the route, nil for a nil route
+func (c *routeCompare) canonical(r *eskip.Route) *comparedRoute {
+	if r == nil {
+		return nil
+	}
+	compared := &comparedRoute{Id: r.Id}
+	for _, p := range Predicates(r) {
+		compared.Predicates = append(compared.Predicates, predicateString(p))
+	}
+	sort.Strings(compared.Predicates)
+	for _, f := range r.Filters {
+		if c.ignoreFilterArgs {
+			compared.Filters = append(compared.Filters, f.Name+"(...)")
+			continue
+		}
+		compared.Filters = append(compared.Filters, filterString(f))
+	}
+	if !c.ignoreBackends {
+		b := *r
+		if b.Shunt && b.BackendType == eskip.NetworkBackend {
+			b.BackendType = eskip.ShuntBackend
+		}
+		compared.Backend = backendDefinition(&b)
+	}
+	return compared
+}
@@ -0,0 +1,72 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/eskip"
+)
+
+func parseRoute(t *testing.T, doc string) *eskip.Route {
+	t.Helper()
+	routes, err := eskip.Parse(doc)
+	require.NoError(t, err)
+	require.Len(t, routes, 1)
+	return routes[0]
+}
+
+func TestRouteCompareOptionsLegacyPredicates(t *testing.T) {
+	parsed := parseRoute(t, `orders: Path("/orders") && Host(/^api[.]example[.]org$/) && Method("GET") && Header("Accept", "application/json") && HeaderRegexp("X-Team", /^o/) && Traffic(.3)
+		-> status(200) -> "https://orders.svc";`)
+	built := &eskip.Route{
+		Id: "orders",
+		Predicates: []*eskip.Predicate{
+			{Name: "Traffic", Args: []interface{}{.3}},
+			{Name: "HeaderRegexp", Args: []interface{}{"X-Team", "^o"}},
+			{Name: "Header", Args: []interface{}{"Accept", "application/json"}},
+			{Name: "Method", Args: []interface{}{"GET"}},
+			{Name: "Host", Args: []interface{}{"^api[.]example[.]org$"}},
+			{Name: "Path", Args: []interface{}{"/orders"}},
+		},
+		Filters: []*eskip.Filter{{Name: "status", Args: []interface{}{200}}},
+		Backend: "https://orders.svc",
+		Name:    "orders",
+	}
+	require.NotEqual(t, "", parsed.Path, "parsed into the legacy field")
+
+	assert.Empty(t, cmp.Diff(built, parsed, RouteCompareOptions()))
+	assert.True(t, cmp.Equal([]*eskip.Route{built, nil}, []*eskip.Route{parsed, nil}, RouteCompareOptions()))
+}
+
+func TestRouteCompareOptionsDiff(t *testing.T) {
+	want := parseRoute(t, `orders: Path("/orders") -> setPath("/v2") -> "https://orders.svc";`)
+	got := parseRoute(t, `orders: Path("/orders") -> setPath("/v3") -> "https://orders-v3.svc";`)
+
+	diff := cmp.Diff(want, got, RouteCompareOptions())
+	// cmp randomizes the spacing of the diff, only the definitions are stable
+	assert.Contains(t, diff, "`setPath(\"/v2\")`")
+	assert.Contains(t, diff, "`setPath(\"/v3\")`")
+	assert.Contains(t, diff, "`\"https://orders-v3.svc\"`")
+	assert.NotContains(t, diff, "HostRegexps", "only the canonical fields")
+
+	assert.NotEmpty(t, cmp.Diff(want, got, RouteCompareOptions(IgnoreFilterArgs())), "the backends differ")
+	assert.NotEmpty(t, cmp.Diff(want, got, RouteCompareOptions(IgnoreBackends())), "the filter args differ")
+	assert.Empty(t, cmp.Diff(want, got, RouteCompareOptions(IgnoreBackends(), IgnoreFilterArgs())))
+
+	reordered := parseRoute(t, `orders: Path("/orders") -> setQuery("v", "2") -> setPath("/v2") -> "https://orders.svc";`)
+	assert.NotEmpty(t, cmp.Diff(parseRoute(t, `orders: Path("/orders") -> setPath("/v2") -> setQuery("v", "2") -> "https://orders.svc";`), reordered, RouteCompareOptions(IgnoreFilterArgs())), "the filters order matters")
+}
+
+func TestRouteCompareOptionsBackends(t *testing.T) {
+	shunt := parseRoute(t, `health: Path("/health") -> <shunt>;`)
+	assert.Empty(t, cmp.Diff(shunt, &eskip.Route{Id: "health", Path: "/health", Shunt: true}, RouteCompareOptions()), "deprecated Shunt field")
+	assert.NotEmpty(t, cmp.Diff(shunt, parseRoute(t, `health: Path("/health") -> <loopback>;`), RouteCompareOptions()))
+	assert.NotEmpty(t, cmp.Diff(shunt, parseRoute(t, `other: Path("/health") -> <shunt>;`), RouteCompareOptions()), "the id is compared")
+	assert.NotEmpty(t, cmp.Diff(shunt, nil, RouteCompareOptions()))
+
+	lb := parseRoute(t, `lb: * -> <roundRobin, "http://10.2.0.1:8080", "http://10.2.0.2:8080">;`)
+	assert.Empty(t, cmp.Diff(lb, parseRoute(t, `lb: * -> <roundRobin, "http://10.2.0.1:8080", "http://10.2.0.2:8080">;`), RouteCompareOptions()))
+	assert.NotEmpty(t, cmp.Diff(lb, parseRoute(t, `lb: * -> <roundRobin, "http://10.2.0.1:8080">;`), RouteCompareOptions()))
+}
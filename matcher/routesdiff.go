@@ -0,0 +1,346 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// Fields of a FieldChange
+const (
+	FieldPredicate = "predicate"
+	FieldFilter    = "filter"
+	FieldBackend   = "backend"
+)
+
+// RoutesDiffReport result of RoutesDiff
+type RoutesDiffReport struct {
+	// Added ids of the routes only in the new document, sorted
+	Added []string `json:"added"`
+	// Removed ids of the routes only in the old document, sorted
+	Removed []string `json:"removed"`
+	// Renamed the removed routes paired with an added one defined the same
+	// way, or almost, sorted by old id. They aren't in Added and Removed.
+	Renamed []RouteRename `json:"renamed"`
+	// Changed the routes of both documents defined differently, sorted by id
+	Changed []RouteFieldChanges `json:"changed"`
+	// Notes the removed routes similar to more than one added route, or the
+	// other way around, left in Added and Removed
+	Notes []string `json:"notes"`
+}
+
+// RoutesDiffOptions options of RoutesDiffWith
+type RoutesDiffOptions struct {
+	// RenameChanges the most field changes between a removed and an added
+	// route reported as a rename, only the identical ones when 0, no renames
+	// when < 0
+	RenameChanges int
+}
+
+// RouteRename a route whose id changed
+type RouteRename struct {
+	// Old id of the removed route
+	Old string `json:"old"`
+	// New id of the added route
+	New string `json:"new"`
+	// Changes the field changes of the route, none when only the id changed
+	Changes []FieldChange `json:"changes"`
+}
+
+// RouteFieldChanges the changed fields of a route in both documents
+type RouteFieldChanges struct {
+	// ID id of the route
+	ID string `json:"id"`
+	// Changes the predicates first, then the filters in the chain order and
+	// the backend
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange a predicate, filter or backend of a route added, removed or
+// changed
+type FieldChange struct {
+	// Field FieldPredicate, FieldFilter or FieldBackend
+	Field string `json:"field"`
+	// Change RouteAdded, RouteRemoved or RouteChanged
+	Change string `json:"change"`
+	// Old eskip definition of the field, empty when added
+	Old string `json:"old,omitempty"`
+	// New eskip definition of the field, empty when removed
+	New string `json:"new,omitempty"`
+}
+
+// RoutesDiff compares two eskip documents by route id, field by field: the
+// predicates are compared in their normalized form, see Predicates, and in
+// any order, so the legacy fields and the predicates compare equal, the
+// filters by their position in the chain. A route defined more than once
+// is compared by its last definition, like in skipper. An added and a
+// removed route defined the same way are reported as renamed, see
+// RoutesDiffWith.
+func RoutesDiff(oldDoc, newDoc []*eskip.Route) *RoutesDiffReport {
+	return RoutesDiffWith(oldDoc, newDoc, RoutesDiffOptions{})
+}
+
+// RoutesDiffWith compares two eskip documents like RoutesDiff, with the
+// options
+func RoutesDiffWith(oldDoc, newDoc []*eskip.Route, o RoutesDiffOptions) *RoutesDiffReport {
+	oldRoutes, newRoutes := routesByID(oldDoc), routesByID(newDoc)
+	report := &RoutesDiffReport{
+		Added:   []string{},
+		Removed: []string{},
+		Renamed: []RouteRename{},
+		Changed: []RouteFieldChanges{},
+		Notes:   []string{},
+	}
+	for id, old := range oldRoutes {
+		new, ok := newRoutes[id]
+		if !ok {
+			report.Removed = append(report.Removed, id)
+			continue
+		}
+		if changes := routeFieldChanges(old, new); len(changes) > 0 {
+			report.Changed = append(report.Changed, RouteFieldChanges{ID: id, Changes: changes})
+		}
+	}
+	for id := range newRoutes {
+		if _, ok := oldRoutes[id]; !ok {
+			report.Added = append(report.Added, id)
+		}
+	}
+	sort.Strings(report.Added)
+	sort.Strings(report.Removed)
+	sort.Slice(report.Changed, func(i, j int) bool { return report.Changed[i].ID < report.Changed[j].ID })
+	if o.RenameChanges >= 0 {
+		detectRenames(report, oldRoutes, newRoutes, o.RenameChanges)
+	}
+	return report
+}
+
+// detectRenames pairs the removed and the added routes differing by at most
+// maxChanges field changes, when neither of them is similar to another
+// route
+func detectRenames(report *RoutesDiffReport, oldRoutes, newRoutes map[string]*eskip.Route, maxChanges int) {
+	candidates := make(map[string][]string)
+	reverse := make(map[string][]string)
+	changes := make(map[[2]string][]FieldChange)
+	for _, old := range report.Removed {
+		for _, new := range report.Added {
+			c := routeFieldChanges(oldRoutes[old], newRoutes[new])
+			if len(c) > maxChanges {
+				continue
+			}
+			candidates[old] = append(candidates[old], new)
+			reverse[new] = append(reverse[new], old)
+			changes[[2]string{old, new}] = c
+		}
+	}
+
+	renamed := make(map[string]bool)
+	for _, old := range report.Removed {
+		news := candidates[old]
+		switch {
+		case len(news) > 1:
+			report.Notes = append(report.Notes, fmt.Sprintf("ambiguous rename of %s: %s", old, strings.Join(news, ", ")))
+		case len(news) == 1 && len(reverse[news[0]]) == 1:
+			report.Renamed = append(report.Renamed, RouteRename{Old: old, New: news[0], Changes: changes[[2]string{old, news[0]}]})
+			renamed[old], renamed[news[0]] = true, true
+		}
+	}
+	for _, new := range report.Added {
+		if olds := reverse[new]; len(olds) > 1 {
+			report.Notes = append(report.Notes, fmt.Sprintf("ambiguous rename to %s: %s", new, strings.Join(olds, ", ")))
+		}
+	}
+	report.Added = unrenamed(report.Added, renamed)
+	report.Removed = unrenamed(report.Removed, renamed)
+}
+
+func unrenamed(ids []string, renamed map[string]bool) []string {
+	kept := []string{}
+	for _, id := range ids {
+		if !renamed[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+func routesByID(routes []*eskip.Route) map[string]*eskip.Route {
+	byID := make(map[string]*eskip.Route, len(routes))
+	for _, r := range routes {
+		byID[r.Id] = r
+	}
+	return byID
+}
+
+// routeFieldChanges the changes of the predicates, filters and backend
+func routeFieldChanges(old, new *eskip.Route) []FieldChange {
+	changes := []FieldChange{}
+	oldPreds, newPreds := predicateStrings(old), predicateStrings(new)
+	for _, p := range unmatched(oldPreds, newPreds) {
+		changes = append(changes, FieldChange{Field: FieldPredicate, Change: RouteRemoved, Old: p})
+	}
+	for _, p := range unmatched(newPreds, oldPreds) {
+		changes = append(changes, FieldChange{Field: FieldPredicate, Change: RouteAdded, New: p})
+	}
+
+	for i := 0; i < len(old.Filters) || i < len(new.Filters); i++ {
+		switch {
+		case i >= len(new.Filters):
+			changes = append(changes, FieldChange{Field: FieldFilter, Change: RouteRemoved, Old: filterString(old.Filters[i])})
+		case i >= len(old.Filters):
+			changes = append(changes, FieldChange{Field: FieldFilter, Change: RouteAdded, New: filterString(new.Filters[i])})
+		default:
+			if o, n := filterString(old.Filters[i]), filterString(new.Filters[i]); o != n {
+				changes = append(changes, FieldChange{Field: FieldFilter, Change: RouteChanged, Old: o, New: n})
+			}
+		}
+	}
+
+	if o, n := backendDefinition(old), backendDefinition(new); o != n {
+		changes = append(changes, FieldChange{Field: FieldBackend, Change: RouteChanged, Old: o, New: n})
+	}
+	return changes
+}
+
+// predicateStrings eskip definitions of the normalized predicates of a
+// route
+func predicateStrings(r *eskip.Route) []string {
+	ps := Predicates(r)
+	defs := make([]string, len(ps))
+	for i, p := range ps {
+		defs[i] = predicateString(p)
+	}
+	return defs
+}
+
+// unmatched the definitions of a without an equal one in b, in their order,
+// each one of b matching a single one of a
+func unmatched(a, b []string) []string {
+	counts := make(map[string]int)
+	for _, def := range b {
+		counts[def]++
+	}
+	var defs []string
+	for _, def := range a {
+		if counts[def] > 0 {
+			counts[def]--
+			continue
+		}
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+// backendDefinition eskip definition of the backend, with the endpoints of
+// the load balanced ones, eg. <roundRobin, "http://10.2.0.1:8080">
+func backendDefinition(r *eskip.Route) string {
+	b := &eskip.Route{BackendType: r.BackendType, Backend: r.Backend, LBAlgorithm: r.LBAlgorithm, LBEndpoints: r.LBEndpoints}
+	// strip the match all, the route is only used to format the backend
+	return strings.TrimPrefix(b.String(), "* -> ")
+}
+
+// Empty true if the documents define the same routes
+func (r *RoutesDiffReport) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Renamed) == 0 && len(r.Changed) == 0
+}
+
+// WriteText writes the counts, the added and removed ids and the changes of
+// each changed route, one per line, eg.
+// filter changed: setQuery("v", "1") -> setQuery("v", "2")
+func (r *RoutesDiffReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "%s\n", r.summary())
+	for _, id := range r.Added {
+		fmt.Fprintf(w, "+ %s\n", id)
+	}
+	for _, id := range r.Removed {
+		fmt.Fprintf(w, "- %s\n", id)
+	}
+	for _, rn := range r.Renamed {
+		fmt.Fprintf(w, "renamed %s -> %s\n", rn.Old, rn.New)
+		for _, f := range rn.Changes {
+			fmt.Fprintf(w, "    %s %s: %s\n", f.Field, f.Change, fieldChangeText(f))
+		}
+	}
+	for _, c := range r.Changed {
+		fmt.Fprintf(w, "~ %s\n", c.ID)
+		for _, f := range c.Changes {
+			fmt.Fprintf(w, "    %s %s: %s\n", f.Field, f.Change, fieldChangeText(f))
+		}
+	}
+	for _, note := range r.Notes {
+		fmt.Fprintf(w, "note: %s\n", note)
+	}
+	return nil
+}
+
+// summary the counts, eg. 1 route(s) added, 0 removed, 1 renamed, 2 changed
+func (r *RoutesDiffReport) summary() string {
+	return fmt.Sprintf("%d route(s) added, %d removed, %d renamed, %d changed", len(r.Added), len(r.Removed), len(r.Renamed), len(r.Changed))
+}
+
+// WriteJSON writes the report in indented json
+func (r *RoutesDiffReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteMarkdown writes the report as a markdown table, eg. for pull request
+// comments, with a row for each added and removed route and for each change
+// of the changed ones
+func (r *RoutesDiffReport) WriteMarkdown(w io.Writer) error {
+	fmt.Fprintf(w, "**%s**\n", r.summary())
+	if r.Empty() {
+		return nil
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Route | Change | Field | Old | New |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- | --- |")
+	for _, id := range r.Added {
+		fmt.Fprintf(w, "| %s | %s | | | |\n", markdownCode(id), RouteAdded)
+	}
+	for _, id := range r.Removed {
+		fmt.Fprintf(w, "| %s | %s | | | |\n", markdownCode(id), RouteRemoved)
+	}
+	for _, rn := range r.Renamed {
+		fmt.Fprintf(w, "| %s | renamed | | %s | %s |\n", markdownCode(rn.New), markdownCode(rn.Old), markdownCode(rn.New))
+		for _, f := range rn.Changes {
+			fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", markdownCode(rn.New), f.Change, f.Field, markdownCode(f.Old), markdownCode(f.New))
+		}
+	}
+	for _, c := range r.Changed {
+		for _, f := range c.Changes {
+			fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", markdownCode(c.ID), f.Change, f.Field, markdownCode(f.Old), markdownCode(f.New))
+		}
+	}
+	for _, note := range r.Notes {
+		fmt.Fprintf(w, "\n> %s\n", note)
+	}
+	return nil
+}
+
+// fieldChangeText the definitions of a change, eg. "old -> new"
+func fieldChangeText(f FieldChange) string {
+	switch f.Change {
+	case RouteAdded:
+		return f.New
+	case RouteRemoved:
+		return f.Old
+	default:
+		return f.Old + " -> " + f.New
+	}
+}
+
+// markdownCode the text as inline code of a table cell, empty when the text
+// is empty
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + strings.NewReplacer("|", `\|`, "\n", " ").Replace(s) + "`"
+}
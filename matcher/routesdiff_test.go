@@ -0,0 +1,165 @@
+package matcher
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/eskip"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// golden compares got with the testdata file, written instead with -update
+func golden(t *testing.T, file string, got []byte) {
+	t.Helper()
+	file = filepath.Join("testdata", file)
+	if *update {
+		require.NoError(t, ioutil.WriteFile(file, got, 0644))
+		return
+	}
+	want, err := ioutil.ReadFile(file)
+	require.NoError(t, err, "run the tests with -update to create it")
+	assert.Equal(t, string(want), string(got))
+}
+
+func parseRoutesFile(t *testing.T, file string) []*eskip.Route {
+	doc, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	routes, err := eskip.Parse(string(doc))
+	require.NoError(t, err)
+	return routes
+}
+
+func TestRoutesDiff(t *testing.T) {
+	old := parseRoutesFile(t, "testdata/routesdiff/old.eskip")
+	new := parseRoutesFile(t, "testdata/routesdiff/new.eskip")
+	report := RoutesDiff(old, new)
+
+	assert.Equal(t, []string{"stores"}, report.Added)
+	assert.Equal(t, []string{"legacy"}, report.Removed)
+	assert.Equal(t, []RouteRename{{Old: "stock", New: "inventory", Changes: []FieldChange{}}}, report.Renamed)
+	assert.Empty(t, report.Notes)
+	ids := make([]string, len(report.Changed))
+	for i, c := range report.Changed {
+		ids[i] = c.ID
+	}
+	assert.Equal(t, []string{"catalog", "search"}, ids, "the reordered predicates of orders are no change")
+
+	for _, format := range []struct {
+		file  string
+		write func(*bytes.Buffer) error
+	}{
+		{"routesdiff/report.txt", func(b *bytes.Buffer) error { return report.WriteText(b) }},
+		{"routesdiff/report.json", func(b *bytes.Buffer) error { return report.WriteJSON(b) }},
+		{"routesdiff/report.md", func(b *bytes.Buffer) error { return report.WriteMarkdown(b) }},
+	} {
+		t.Run(format.file, func(t *testing.T) {
+			var b bytes.Buffer
+			require.NoError(t, format.write(&b))
+			golden(t, format.file, b.Bytes())
+		})
+	}
+}
+
+func TestRoutesDiffLegacyFields(t *testing.T) {
+	legacy := &eskip.Route{
+		Id:          "orders",
+		Path:        "/orders",
+		Method:      "GET",
+		HostRegexps: []string{"^api[.]example[.]org$"},
+		Headers:     map[string]string{"Accept": "application/json"},
+		Backend:     "https://orders.example.org",
+	}
+	predicates := &eskip.Route{
+		Id: "orders",
+		Predicates: []*eskip.Predicate{
+			{Name: "Header", Args: []interface{}{"Accept", "application/json"}},
+			{Name: "Method", Args: []interface{}{"GET"}},
+			{Name: "Host", Args: []interface{}{"^api[.]example[.]org$"}},
+			{Name: "Path", Args: []interface{}{"/orders"}},
+		},
+		Backend: "https://orders.example.org",
+	}
+	report := RoutesDiff([]*eskip.Route{legacy}, []*eskip.Route{predicates})
+	assert.True(t, report.Empty(), "%+v", report.Changed)
+
+	predicates.Predicates[1].Args = []interface{}{"POST"}
+	report = RoutesDiff([]*eskip.Route{legacy}, []*eskip.Route{predicates})
+	require.Len(t, report.Changed, 1)
+	assert.Equal(t, []FieldChange{
+		{Field: FieldPredicate, Change: RouteRemoved, Old: `Method("GET")`},
+		{Field: FieldPredicate, Change: RouteAdded, New: `Method("POST")`},
+	}, report.Changed[0].Changes)
+}
+
+func TestRoutesDiffFilters(t *testing.T) {
+	old, err := eskip.Parse(`r: * -> setPath("/a") -> compress() -> <shunt>;`)
+	require.NoError(t, err)
+	new, err := eskip.Parse(`r: * -> setPath("/b") -> <shunt>;`)
+	require.NoError(t, err)
+	assert.Equal(t, []FieldChange{
+		{Field: FieldFilter, Change: RouteChanged, Old: `setPath("/a")`, New: `setPath("/b")`},
+		{Field: FieldFilter, Change: RouteRemoved, Old: `compress()`},
+	}, RoutesDiff(old, new).Changed[0].Changes)
+
+	var b bytes.Buffer
+	require.NoError(t, RoutesDiff(old, old).WriteMarkdown(&b))
+	assert.Equal(t, "**0 route(s) added, 0 removed, 0 renamed, 0 changed**\n", b.String())
+}
+
+func TestRoutesDiffRenames(t *testing.T) {
+	old, err := eskip.Parse(`orders: Path("/orders") -> setPath("/v1") -> "https://orders.example.org";
+search: Path("/search") -> "https://search.example.org";`)
+	require.NoError(t, err)
+	new, err := eskip.Parse(`orders_v1: Path("/orders") -> setPath("/v2") -> "https://orders.example.org";
+stores: Path("/stores") -> "https://stores.example.org";`)
+	require.NoError(t, err)
+
+	// a filter changed, not a rename by default
+	report := RoutesDiff(old, new)
+	assert.Equal(t, []string{"orders_v1", "stores"}, report.Added)
+	assert.Equal(t, []string{"orders", "search"}, report.Removed)
+	assert.Empty(t, report.Renamed)
+
+	report = RoutesDiffWith(old, new, RoutesDiffOptions{RenameChanges: 1})
+	assert.Equal(t, []string{"stores"}, report.Added)
+	assert.Equal(t, []string{"search"}, report.Removed, "another path and backend is no rename")
+	assert.Equal(t, []RouteRename{{Old: "orders", New: "orders_v1", Changes: []FieldChange{
+		{Field: FieldFilter, Change: RouteChanged, Old: `setPath("/v1")`, New: `setPath("/v2")`},
+	}}}, report.Renamed)
+
+	var b bytes.Buffer
+	require.NoError(t, report.WriteText(&b))
+	assert.Equal(t, `1 route(s) added, 1 removed, 1 renamed, 0 changed
++ stores
+- search
+renamed orders -> orders_v1
+    filter changed: setPath("/v1") -> setPath("/v2")
+`, b.String())
+
+	report = RoutesDiffWith(old, old[:1], RoutesDiffOptions{RenameChanges: -1})
+	assert.Equal(t, []string{"search"}, report.Removed)
+}
+
+func TestRoutesDiffAmbiguousRenames(t *testing.T) {
+	old, err := eskip.Parse(`orders: Path("/orders") -> "https://orders.example.org";`)
+	require.NoError(t, err)
+	new, err := eskip.Parse(`orders_a: Path("/orders") -> "https://orders.example.org";
+orders_b: Path("/orders") -> "https://orders.example.org";`)
+	require.NoError(t, err)
+
+	report := RoutesDiff(old, new)
+	assert.Equal(t, []string{"orders_a", "orders_b"}, report.Added)
+	assert.Equal(t, []string{"orders"}, report.Removed)
+	assert.Empty(t, report.Renamed)
+	assert.Equal(t, []string{"ambiguous rename of orders: orders_a, orders_b"}, report.Notes)
+
+	report = RoutesDiff(new, old)
+	assert.Empty(t, report.Renamed)
+	assert.Equal(t, []string{"ambiguous rename to orders: orders_a, orders_b"}, report.Notes)
+}
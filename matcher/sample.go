@@ -0,0 +1,243 @@
+package matcher
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// maxSampleLength maximum length of a regexp sample, longer ones are
+// rejected as unbounded
+const maxSampleLength = 256
+
+// maxSampleRepeat maximum number of copies a random sample adds to the
+// minimum of a repetition, eg. 0 to 3 for a*
+const maxSampleRepeat = 3
+
+// sampleAttempts number of random samples tried before falling back to the
+// shortest one, a random choice can break an anchor or a boundary
+const sampleAttempts = 8
+
+// sampleAlphabet runes of the random samples of the any char and of the
+// classes including them
+const sampleAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// SampleError a regexp no sample string is found for, see SampleRegexp
+type SampleError struct {
+	// Expr the regexp
+	Expr string
+	// Unbounded true when the samples would be longer than the limit, eg.
+	// for a{1000}
+	Unbounded bool
+	// Err the error parsing the regexp, if any
+	Err error
+}
+
+func (e *SampleError) Error() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("invalid regexp %q: %v", e.Expr, e.Err)
+	case e.Unbounded:
+		return fmt.Sprintf("unbounded regexp %q, its samples are longer than %d characters", e.Expr, maxSampleLength)
+	default:
+		return fmt.Sprintf("no string found matching the regexp %q", e.Expr)
+	}
+}
+
+// SampleRegexp returns a random string the regexp matches, the same for the
+// same seed: literals, character classes, repetitions, bounded to a few
+// copies more than the minimum, and alternations are sampled. The sample is
+// checked against the regexp, a *SampleError is returned when none is found,
+// eg. a{1000} is rejected as unbounded.
+func SampleRegexp(expr string, seed int64) (string, error) {
+	return newRegexpSampler(rand.New(rand.NewSource(seed)), false).sample(expr)
+}
+
+// sampleRegexp returns the shortest simple string matching the regexp, false
+// when none is found
+func sampleRegexp(expr string) (string, bool) {
+	s, err := newRegexpSampler(nil, false).sample(expr)
+	return s, err == nil
+}
+
+// regexpSampler writes strings matched by regexps: random ones, or the
+// shortest ones with the first choices without random
+type regexpSampler struct {
+	rand *rand.Rand
+	// optional a copy of the optional parts in the shortest strings
+	optional bool
+}
+
+func newRegexpSampler(r *rand.Rand, optional bool) *regexpSampler {
+	return &regexpSampler{rand: r, optional: optional}
+}
+
+// sample a string matching the regexp, tried a few times with random
+func (s *regexpSampler) sample(expr string) (string, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return "", &SampleError{Expr: expr, Err: err}
+	}
+	re = re.Simplify()
+	rx := regexp.MustCompile(expr)
+
+	attempts := sampleAttempts
+	if s.rand == nil {
+		attempts = 0
+	}
+	// the shortest sample last
+	for i := 0; i <= attempts; i++ {
+		sampler := s
+		if i == attempts {
+			sampler = newRegexpSampler(nil, s.optional)
+		}
+		var b strings.Builder
+		if !sampler.write(&b, re) {
+			return "", &SampleError{Expr: expr}
+		}
+		switch {
+		case b.Len() > maxSampleLength:
+			if i == attempts {
+				return "", &SampleError{Expr: expr, Unbounded: true}
+			}
+		case rx.MatchString(b.String()):
+			return b.String(), nil
+		}
+	}
+	return "", &SampleError{Expr: expr}
+}
+
+// write writes a string matched by re, false for the regexps matching
+// nothing. It stops writing past maxSampleLength.
+func (s *regexpSampler) write(b *strings.Builder, re *syntax.Regexp) bool {
+	if b.Len() > maxSampleLength {
+		return true
+	}
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return false
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		r, ok := s.class(re.Rune)
+		if !ok {
+			return false
+		}
+		b.WriteRune(r)
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteByte(s.pick(sampleAlphabet, 'a'))
+	case syntax.OpCapture:
+		return s.write(b, re.Sub[0])
+	case syntax.OpStar:
+		return s.repeat(b, re.Sub[0], 0, -1)
+	case syntax.OpPlus:
+		return s.repeat(b, re.Sub[0], 1, -1)
+	case syntax.OpQuest:
+		return s.repeat(b, re.Sub[0], 0, 1)
+	case syntax.OpRepeat:
+		return s.repeat(b, re.Sub[0], re.Min, re.Max)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !s.write(b, sub) {
+				return false
+			}
+		}
+	case syntax.OpAlternate:
+		if s.rand == nil {
+			return s.write(b, re.Sub[0])
+		}
+		return s.write(b, re.Sub[s.rand.Intn(len(re.Sub))])
+	}
+	// empty matches, anchors and boundaries write nothing
+	return true
+}
+
+// repeat writes min to max copies of re, max -1 for no maximum, the minimum
+// without random, a copy of an optional re with optional
+func (s *regexpSampler) repeat(b *strings.Builder, re *syntax.Regexp, min, max int) bool {
+	n := min
+	switch {
+	case s.rand == nil:
+		if s.optional && min == 0 && max != 0 {
+			n = 1
+		}
+	default:
+		top := min + maxSampleRepeat
+		if max >= 0 && max < top {
+			top = max
+		}
+		n += s.rand.Intn(top - min + 1)
+	}
+	for i := 0; i < n; i++ {
+		if !s.write(b, re) {
+			return false
+		}
+		if b.Len() > maxSampleLength {
+			return true
+		}
+	}
+	return true
+}
+
+// class a rune of the character class, a letter or a digit when there is
+// one, the first one without random
+func (s *regexpSampler) class(ranges []rune) (rune, bool) {
+	if s.rand == nil {
+		return classSample(ranges)
+	}
+	var alnum []byte
+	for i := 0; i < len(sampleAlphabet); i++ {
+		if inRanges(ranges, rune(sampleAlphabet[i])) {
+			alnum = append(alnum, sampleAlphabet[i])
+		}
+	}
+	if len(alnum) > 0 {
+		return rune(alnum[s.rand.Intn(len(alnum))]), true
+	}
+	var printable []rune
+	for r := rune('!'); r <= '~'; r++ {
+		if inRanges(ranges, r) {
+			printable = append(printable, r)
+		}
+	}
+	if len(printable) > 0 {
+		return printable[s.rand.Intn(len(printable))], true
+	}
+	return classSample(ranges)
+}
+
+// pick a random byte of the values, def without random
+func (s *regexpSampler) pick(values string, def byte) byte {
+	if s.rand == nil {
+		return def
+	}
+	return values[s.rand.Intn(len(values))]
+}
+
+// inRanges true if r is in the ranges of a character class
+func inRanges(ranges []rune, r rune) bool {
+	for i := 0; i+1 < len(ranges); i += 2 {
+		if r >= ranges[i] && r <= ranges[i+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// classSample picks a rune of a character class, letters and digits first
+func classSample(ranges []rune) (rune, bool) {
+	if len(ranges) == 0 {
+		return 0, false
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		for r := ranges[i]; r <= ranges[i+1] && r < ranges[i]+128; r++ {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return r, true
+			}
+		}
+	}
+	return ranges[0], true
+}
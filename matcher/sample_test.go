@@ -0,0 +1,83 @@
+package matcher
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/eskip"
+)
+
+func TestSampleRegexpSeeded(t *testing.T) {
+	for _, expr := range []string{
+		`^v[0-9]+$`,
+		`^(alpha|beta|gamma)-[a-f0-9]{8}$`,
+		`(?i)^Shop\.example\.(com|org)$`,
+		`^[^/]+\.internal$`,
+		`\d{3}-\w+`,
+		`^Bearer .+$`,
+		`^x?y*z+$`,
+		`^[[:upper:]]{2,4}_[^a-z]?$`,
+		`\bword\b`,
+		``,
+	} {
+		rx := regexp.MustCompile(expr)
+		samples := map[string]bool{}
+		for seed := int64(0); seed < 200; seed++ {
+			s, err := SampleRegexp(expr, seed)
+			require.NoError(t, err, expr)
+			assert.True(t, rx.MatchString(s), "%q doesn't match %s", s, expr)
+			again, _ := SampleRegexp(expr, seed)
+			assert.Equal(t, s, again, "the same seed samples the same string")
+			samples[s] = true
+		}
+		if expr != `` && expr != `\bword\b` {
+			assert.True(t, len(samples) > 1, "random samples of %s", expr)
+		}
+	}
+}
+
+func TestSampleRegexpErrors(t *testing.T) {
+	_, err := SampleRegexp(`a{1000}`, 1)
+	if assert.IsType(t, &SampleError{}, err) {
+		assert.True(t, err.(*SampleError).Unbounded)
+		assert.EqualError(t, err, `unbounded regexp "a{1000}", its samples are longer than 256 characters`)
+	}
+
+	_, err = SampleRegexp(`a^b`, 1)
+	assert.EqualError(t, err, `no string found matching the regexp "a^b"`)
+
+	_, err = SampleRegexp(`(`, 1)
+	if assert.IsType(t, &SampleError{}, err) {
+		assert.Error(t, err.(*SampleError).Err)
+	}
+}
+
+func TestGenerateRequestWithSeed(t *testing.T) {
+	routes, err := eskip.Parse(`r: Path("/") && Header("X-Fixed", "a") && HeaderRegexp("X-Version", /^v[0-9]+$/) && Cookie("session", /^[a-z]{4}-[0-9]{2}$/) && QueryParam("page", /^[1-9][0-9]?$/) -> <shunt>;
+unbounded: QueryParam("q", /^a{300}$/) -> <shunt>;`)
+	require.NoError(t, err)
+
+	shortest, _ := GenerateRequest(routes[0])
+	assert.Equal(t, "v0", shortest.Headers["X-Version"])
+	versions := map[string]bool{}
+	for seed := int64(0); seed < 50; seed++ {
+		attrs, unsatisfied := GenerateRequestWithSeed(routes[0], seed)
+		assert.Empty(t, unsatisfied)
+		assert.Equal(t, "a", attrs.Headers["X-Fixed"])
+		assert.Regexp(t, `^v[0-9]+$`, attrs.Headers["X-Version"])
+		assert.Regexp(t, `^session=[a-z]{4}-[0-9]{2}$`, attrs.Headers["Cookie"])
+		assert.Regexp(t, `^[1-9][0-9]?$`, attrs.Query["page"])
+		again, _ := GenerateRequestWithSeed(routes[0], seed)
+		assert.Equal(t, attrs, again)
+		versions[attrs.Headers["X-Version"]] = true
+	}
+	assert.True(t, len(versions) > 1)
+
+	_, unsatisfied := GenerateRequestWithSeed(routes[1], 1)
+	if assert.Len(t, unsatisfied, 1) {
+		assert.True(t, strings.HasPrefix(unsatisfied[0].Reason, "unbounded"), unsatisfied[0].Reason)
+	}
+}
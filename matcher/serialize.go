@@ -0,0 +1,180 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// ResultData serializable representation of a TestResult,
+// it defines the schema used by TestResult JSON and YAML encoding
+type ResultData struct {
+	// Matched true if a route matched
+	Matched bool `json:"matched" yaml:"matched"`
+	// RouteID id of the matching route
+	RouteID string `json:"routeId,omitempty" yaml:"routeId,omitempty"`
+	// BackendType one of network, shunt, loopback, dynamic, loadbalanced
+	BackendType string `json:"backendType,omitempty" yaml:"backendType,omitempty"`
+	// Backend address of a network backend or the <shunt>, <loopback>
+	// and <dynamic> placeholders
+	Backend string `json:"backend,omitempty" yaml:"backend,omitempty"`
+	// LBEndpoints endpoints of a load balanced backend
+	LBEndpoints []string `json:"lbEndpoints,omitempty" yaml:"lbEndpoints,omitempty"`
+	// Filters filter chain of the matching route
+	Filters []FilterData `json:"filters,omitempty" yaml:"filters,omitempty"`
+	// Request normalized request attributes
+	Request *RequestAttributes `json:"request" yaml:"request"`
+	// ForcedTraffic decisions of the Traffic predicates forced by the
+	// traffic mode, see TestResult.ForcedTraffic
+	ForcedTraffic []string `json:"forcedTraffic,omitempty" yaml:"forcedTraffic,omitempty"`
+	// LBMember member route of a matching load balancer group route, see
+	// TestResult.LBMember
+	LBMember string `json:"lbMember,omitempty" yaml:"lbMember,omitempty"`
+	// Trace verdicts of the predicates of the matching route, see
+	// TestResult.Trace
+	Trace []PredicateVerdict `json:"trace,omitempty" yaml:"trace,omitempty"`
+	// Hops ids of the routes the request went through following the
+	// <loopback> backends, see TestResult.Hops
+	Hops []string `json:"hops,omitempty" yaml:"hops,omitempty"`
+	// Lookup steps of the lookup in the routing tree, see TestResult.Lookup
+	Lookup []LookupStep `json:"lookup,omitempty" yaml:"lookup,omitempty"`
+}
+
+// FilterData serializable representation of a route filter
+type FilterData struct {
+	Name string        `json:"name" yaml:"name"`
+	Args []interface{} `json:"args" yaml:"args"`
+	// Default the filter is a default one, see Options.DefaultFiltersPrepend
+	Default bool `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// NewResultData creates the serializable representation of a test result
+func NewResultData(t TestResult) *ResultData {
+	data := &ResultData{
+		Request:       t.Attributes(),
+		ForcedTraffic: t.ForcedTraffic(),
+		LBMember:      t.LBMember(),
+		Trace:         t.Trace(),
+		Lookup:        t.Lookup(),
+	}
+	for _, hop := range t.Hops() {
+		if hop.Route() != nil {
+			data.Hops = append(data.Hops, hop.Route().Id)
+		}
+	}
+
+	route := t.Route()
+	if route == nil {
+		return data
+	}
+
+	data.Matched = true
+	data.RouteID = route.Id
+	data.BackendType = routeBackendType(route).String()
+	data.Backend = backendString(route)
+	data.LBEndpoints = route.LBEndpoints
+	data.Filters = filterData(route)
+	for i, f := range t.Filters() {
+		if i < len(data.Filters) {
+			data.Filters[i].Default = f.Default
+		}
+	}
+	return data
+}
+
+// RouteData serializable representation of a route,
+// it defines the schema used to list routes
+type RouteData struct {
+	// ID route id
+	ID string `json:"id" yaml:"id"`
+	// Location where the route is defined, when known
+	Location *Location `json:"location,omitempty" yaml:"location,omitempty"`
+	// Predicates normalized predicates of the route, eg. Path("/foo")
+	Predicates []string `json:"predicates" yaml:"predicates"`
+	// Filters filter chain of the route
+	Filters []FilterData `json:"filters,omitempty" yaml:"filters,omitempty"`
+	// BackendType one of network, shunt, loopback, dynamic, loadbalanced
+	BackendType string `json:"backendType" yaml:"backendType"`
+	// Backend address of a network backend or the <shunt>, <loopback>
+	// and <dynamic> placeholders
+	Backend string `json:"backend" yaml:"backend"`
+	// LBEndpoints endpoints of a load balanced backend
+	LBEndpoints []string `json:"lbEndpoints,omitempty" yaml:"lbEndpoints,omitempty"`
+}
+
+// NewRouteData creates the serializable representation of a route
+func NewRouteData(r *eskip.Route) *RouteData {
+	predicates := []string{}
+	for _, p := range Predicates(r) {
+		predicates = append(predicates, predicateString(p))
+	}
+	return &RouteData{
+		ID:          r.Id,
+		Predicates:  predicates,
+		Filters:     filterData(r),
+		BackendType: routeBackendType(r).String(),
+		Backend:     backendString(r),
+		LBEndpoints: r.LBEndpoints,
+	}
+}
+
+// filterData serializable representation of the route filters
+func filterData(r *eskip.Route) []FilterData {
+	var filters []FilterData
+	for _, f := range r.Filters {
+		args := f.Args
+		if args == nil {
+			args = []interface{}{}
+		}
+		filters = append(filters, FilterData{Name: f.Name, Args: args})
+	}
+	return filters
+}
+
+// routeBackendType normalizes the backend type of routes
+// using the deprecated Shunt flag
+func routeBackendType(r *eskip.Route) eskip.BackendType {
+	if r.Shunt {
+		return eskip.ShuntBackend
+	}
+	return r.BackendType
+}
+
+// backendString returns the address of a network backend
+// or the eskip placeholder for the other backend types
+func backendString(r *eskip.Route) string {
+	switch routeBackendType(r) {
+	case eskip.ShuntBackend:
+		return "<shunt>"
+	case eskip.LoopBackend:
+		return "<loopback>"
+	case eskip.DynamicBackend:
+		return "<dynamic>"
+	case eskip.LBBackend:
+		if r.LBAlgorithm == "" {
+			return "<loadbalanced>"
+		}
+		return fmt.Sprintf("<%s>", r.LBAlgorithm)
+	default:
+		return r.Backend
+	}
+}
+
+// MarshalJSON encodes the result using the ResultData schema,
+// backend placeholders like <shunt> are kept as they are
+func (t *testResult) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(NewResultData(t)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// MarshalYAML encodes the result using the ResultData schema
+func (t *testResult) MarshalYAML() (interface{}, error) {
+	return NewResultData(t), nil
+}
@@ -0,0 +1,124 @@
+package matcher
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zalando/skipper/eskip"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestResultMarshalJSON(t *testing.T) {
+	routes, err := eskip.Parse(`
+		network: Path("/network") -> setPath("/v2") -> "https://orders.example.org";
+		lb: Path("/lb") -> <roundRobin, "http://a.example.org", "http://b.example.org">;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		result *testResult
+		want   string
+	}{
+		{
+			name: "no match",
+			result: &testResult{
+				attributes: &RequestAttributes{Method: "GET", Path: "/foo"},
+			},
+			want: `{"matched":false,"request":{"method":"GET","path":"/foo"}}`,
+		},
+		{
+			name: "network backend",
+			result: &testResult{
+				route:      routes[0],
+				attributes: &RequestAttributes{Method: "GET", Path: "/network", Headers: map[string]string{"Accept": "json"}},
+			},
+			want: `{"matched":true,"routeId":"network","backendType":"network","backend":"https://orders.example.org",` +
+				`"filters":[{"name":"setPath","args":["/v2"]}],` +
+				`"request":{"method":"GET","path":"/network","headers":{"Accept":"json"}}}`,
+		},
+		{
+			name: "loadbalanced backend",
+			result: &testResult{
+				route:      routes[1],
+				attributes: &RequestAttributes{Method: "GET", Path: "/lb"},
+			},
+			want: `{"matched":true,"routeId":"lb","backendType":"loadbalanced","backend":"<roundRobin>",` +
+				`"lbEndpoints":["http://a.example.org","http://b.example.org"],` +
+				`"request":{"method":"GET","path":"/lb"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result TestResult = tt.result
+			b, err := json.Marshal(result)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(b))
+		})
+	}
+}
+
+func TestResultMarshalJSONPlaceholders(t *testing.T) {
+	routes, err := eskip.Parse(`shunt: Path("/shunt") -> <shunt>;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := (&testResult{route: routes[0], attributes: &RequestAttributes{Method: "GET", Path: "/shunt"}}).MarshalJSON()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"backend":"<shunt>"`)
+}
+
+func TestResultMarshalYAML(t *testing.T) {
+	routes, err := eskip.Parse(`shunt: Path("/shunt") -> <shunt>;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result TestResult = &testResult{
+		route:      routes[0],
+		attributes: &RequestAttributes{Method: "GET", Path: "/shunt"},
+	}
+	b, err := yaml.Marshal(result)
+	assert.NoError(t, err)
+	assert.Equal(t, `matched: true
+routeId: shunt
+backendType: shunt
+backend: <shunt>
+request:
+  method: GET
+  path: /shunt
+`, string(b))
+}
+
+func TestNewRouteData(t *testing.T) {
+	routes, err := eskip.Parse(`
+		api: Host(/^api[.]example[.]org$/) && Path("/v1") && Method("GET") -> setPath("/") -> "https://v1.example.org";
+		lb: * -> <roundRobin, "http://a.example.org", "http://b.example.org">;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal([]*RouteData{NewRouteData(routes[0]), NewRouteData(routes[1])})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{
+			"id": "api",
+			"predicates": ["Path(\"/v1\")", "Host(\"^api[.]example[.]org$\")", "Method(\"GET\")"],
+			"filters": [{"name": "setPath", "args": ["/"]}],
+			"backendType": "network",
+			"backend": "https://v1.example.org"
+		},
+		{
+			"id": "lb",
+			"predicates": [],
+			"backendType": "loadbalanced",
+			"backend": "<roundRobin>",
+			"lbEndpoints": ["http://a.example.org", "http://b.example.org"]
+		}
+	]`, string(b))
+}
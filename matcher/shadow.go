@@ -0,0 +1,180 @@
+package matcher
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// shadowing how sure it is that a route never matches because of another one
+type shadowing int
+
+const (
+	notShadowed shadowing = iota
+	// possiblyShadowed the other route has regexps that may match all its requests
+	possiblyShadowed
+	// tiedShadowed the other route matches all its requests with the same
+	// priority, which one wins isn't defined
+	tiedShadowed
+	// shadowed the other route matches all its requests and always wins
+	shadowed
+)
+
+// routeConditions the conditions of a route grouped the way the skipper
+// routing evaluates them: first the path tree, then the conditions of the
+// routes sharing a path leaf, the ones with more conditions first
+type routeConditions struct {
+	route lintRoute
+	// index of the route in the linted routes
+	index int
+	// leaf path of the leaf the route is in, exact paths and subtrees share
+	// the leaf of the same path, empty for the routes without a path
+	leaf string
+	// subtree true for a PathSubtree route, it matches the paths below the leaf too
+	subtree bool
+	// conditions evaluated on the leaf, their number is the route priority
+	conditions []*eskip.Predicate
+}
+
+// newRouteConditions groups the predicates of a route
+func newRouteConditions(r lintRoute) *routeConditions {
+	rc := &routeConditions{route: r}
+	for _, p := range Predicates(r.route) {
+		path, _ := stringArg(p, 0)
+		switch p.Name {
+		case pathPredicate:
+			rc.leaf = leafPath(path)
+		case pathSubtreePredicate:
+			rc.leaf, rc.subtree = leafPath(path), true
+		default:
+			rc.conditions = append(rc.conditions, p)
+		}
+	}
+	return rc
+}
+
+// leafPath the path of the leaf of a Path or PathSubtree route
+func leafPath(path string) string {
+	if path == "/" || !strings.HasSuffix(path, "/") {
+		return path
+	}
+	return strings.TrimSuffix(path, "/")
+}
+
+// catchAll true for the routes matching every path before the routes without
+// a path: PathSubtree("/") and Path("/*name")
+func (rc *routeConditions) catchAll() bool {
+	return rc.subtree && rc.leaf == "/" || !rc.subtree && strings.HasPrefix(rc.leaf, "/*")
+}
+
+// bareCatchAll true for a catch all route without other conditions, it
+// matches every request the routes without a path could match
+func (rc *routeConditions) bareCatchAll() bool {
+	return rc.catchAll() && len(rc.conditions) == 0
+}
+
+// tied true if the routing gives both routes the same priority
+func (a *routeConditions) tied(b *routeConditions) bool {
+	return a.leaf == b.leaf && len(a.conditions) == len(b.conditions)
+}
+
+// shadows tells whether the route a matches every request b matches and wins
+// over it. Only the decidable cases are certain: literal paths, methods and
+// headers and identical predicates, a regexp of a not matching a literal of
+// b or another regexp makes b possibly shadowed only.
+func (a *routeConditions) shadows(b *routeConditions) shadowing {
+	var order shadowing
+	switch {
+	case a.leaf == "" && b.leaf == "" || a.leaf == b.leaf && (a.subtree || !b.subtree):
+		// the same leaf, the conditions decide
+		switch {
+		case len(a.conditions) > len(b.conditions):
+			order = shadowed
+		case len(a.conditions) == len(b.conditions):
+			order = tiedShadowed
+		default:
+			return notShadowed
+		}
+	case a.catchAll() && b.leaf == "":
+		// the path tree is searched before the routes without a path
+		order = shadowed
+	default:
+		return notShadowed
+	}
+
+	result := order
+	for _, c := range a.conditions {
+		switch implied(c, b) {
+		case notShadowed:
+			return notShadowed
+		case possiblyShadowed:
+			result = possiblyShadowed
+		}
+	}
+	return result
+}
+
+// implied tells whether every request matching the conditions of b matches the predicate too
+func implied(p *eskip.Predicate, b *routeConditions) shadowing {
+	key := predicateString(p)
+	result := notShadowed
+	for _, c := range b.conditions {
+		if predicateString(c) == key {
+			return shadowed
+		}
+		if r := impliedRegexp(p, c); r > result {
+			result = r
+		}
+	}
+	if p.Name == pathRegexpPredicate && b.leaf != "" && result != shadowed {
+		// an exact path without wildcards is a literal
+		rx, ok := predicateRegexp(p, 0)
+		switch {
+		case !ok:
+		case !b.subtree && !strings.Contains(b.leaf, ":") && !strings.Contains(b.leaf, "*"):
+			if rx.MatchString(b.leaf) {
+				return shadowed
+			}
+		default:
+			result = possiblyShadowed
+		}
+	}
+	return result
+}
+
+// impliedRegexp compares a regexp predicate to a condition of the same kind
+func impliedRegexp(p, c *eskip.Predicate) shadowing {
+	switch {
+	case p.Name == headerRegexpPredicate && (c.Name == headerPredicate || c.Name == headerRegexpPredicate):
+		name, _ := stringArg(p, 0)
+		other, _ := stringArg(c, 0)
+		if !strings.EqualFold(name, other) {
+			return notShadowed
+		}
+		if c.Name == headerPredicate {
+			rx, ok := predicateRegexp(p, 1)
+			value, _ := stringArg(c, 1)
+			if ok && rx.MatchString(value) {
+				return shadowed
+			}
+			return notShadowed
+		}
+		return possiblyShadowed
+	case p.Name == hostPredicate && c.Name == hostPredicate,
+		p.Name == pathRegexpPredicate && c.Name == pathRegexpPredicate:
+		return possiblyShadowed
+	default:
+		return notShadowed
+	}
+}
+
+// predicateRegexp compiles the i-th argument of a predicate
+func predicateRegexp(p *eskip.Predicate, i int) (*regexp.Regexp, bool) {
+	s, ok := stringArg(p, i)
+	if !ok {
+		return nil, false
+	}
+	rx, err := regexp.Compile(s)
+	return rx, err == nil
+}
@@ -0,0 +1,138 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/eskip"
+)
+
+func TestShadows(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want shadowing
+	}{
+		{"identical", `Path("/a")`, `Path("/a")`, tiedShadowed},
+		{"more conditions", `Path("/a") && Method("GET")`, `Path("/a")`, notShadowed},
+		{"subtree of the same path", `PathSubtree("/api")`, `Path("/api")`, tiedShadowed},
+		{"subtree of the same path with trailing slash", `PathSubtree("/api/")`, `Path("/api")`, tiedShadowed},
+		{"exact path doesn't cover a subtree", `Path("/api")`, `PathSubtree("/api")`, notShadowed},
+		{"subtree above a longer path", `PathSubtree("/api")`, `Path("/api/v2/users")`, notShadowed},
+		{"different paths", `Path("/a")`, `Path("/b")`, notShadowed},
+		{"method", `Path("/a") && Method("GET")`, `Path("/a") && Method("GET")`, tiedShadowed},
+		{"different methods", `Path("/a") && Method("GET")`, `Path("/a") && Method("POST")`, notShadowed},
+		{"literal host", `Host("^a[.]org$") && Path("/a")`, `Host("^a[.]org$") && Path("/a")`, tiedShadowed},
+		{"different hosts", `Host("^a[.]org$") && Path("/a")`, `Host("^b[.]org$") && Path("/a")`, possiblyShadowed},
+		{"header equality", `Path("/a") && Header("X-A", "1")`, `Path("/a") && Header("X-A", "1")`, tiedShadowed},
+		{"different header values", `Path("/a") && Header("X-A", "1")`, `Path("/a") && Header("X-A", "2")`, notShadowed},
+		{"header regexp matching the value", `Path("/a") && HeaderRegexp("X-A", "^[0-9]+$")`, `Path("/a") && Header("X-A", "1")`, tiedShadowed},
+		{"header regexp not matching the value", `Path("/a") && HeaderRegexp("X-A", "^[a-z]+$")`, `Path("/a") && Header("X-A", "1")`, notShadowed},
+		{"header regexps", `Path("/a") && HeaderRegexp("X-A", "^[0-9]+$")`, `Path("/a") && HeaderRegexp("X-A", "^1$")`, possiblyShadowed},
+		{"path regexp matching the path", `Path("/a") && PathRegexp("^/a$")`, `Path("/a") && Method("GET")`, tiedShadowed},
+		{"path regexp not matching the path", `Path("/a") && PathRegexp("^/b$")`, `Path("/a") && Method("GET")`, notShadowed},
+		{"heavier with path regexp", `Path("/users") && PathRegexp("^/users") && Method("GET")`, `Path("/users") && Method("GET")`, shadowed},
+		{"path regexp on a subtree", `PathSubtree("/users") && PathRegexp("^/users") && Method("GET")`, `PathSubtree("/users") && Method("GET")`, possiblyShadowed},
+		{"custom predicate", `Path("/a") && QueryParam("q")`, `Path("/a") && QueryParam("q")`, tiedShadowed},
+		{"different custom predicate args", `Path("/a") && QueryParam("q")`, `Path("/a") && QueryParam("p")`, notShadowed},
+		{"catch all before the routes without path", `PathSubtree("/")`, `Method("GET") && Header("X-A", "1")`, shadowed},
+		{"splat catch all before the routes without path", `Path("/*any")`, `Method("GET")`, shadowed},
+		{"splat doesn't cover other paths", `Path("/*any")`, `Path("/a")`, notShadowed},
+		{"catch all with other conditions", `PathSubtree("/") && Method("POST")`, `Method("GET")`, notShadowed},
+		{"catch all with the same conditions", `PathSubtree("/") && Method("GET")`, `Method("GET") && Header("X-A", "1")`, shadowed},
+		{"routes without path", `Method("GET")`, `Method("GET")`, tiedShadowed},
+		{"path and no path", `Method("GET")`, `Path("/a") && Method("GET")`, notShadowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := conditionsOf(t, tt.a), conditionsOf(t, tt.b)
+			assert.Equal(t, tt.want, a.shadows(b))
+		})
+	}
+}
+
+// conditionsOf parses the predicates of a route
+func conditionsOf(t *testing.T, predicates string) *routeConditions {
+	routes, err := eskip.Parse("r: " + predicates + " -> <shunt>;")
+	require.NoError(t, err)
+	return newRouteConditions(lintRoute{route: routes[0]})
+}
+
+func TestLintShadowedRoutes(t *testing.T) {
+	docs := []Document{
+		{
+			File: "a.eskip",
+			Content: []byte(`api: PathSubtree("/api") && Method("GET") -> <shunt>;
+users: Path("/api") && Method("GET") -> <shunt>;
+heavy: Path("/users") && PathRegexp("^/users") && Method("GET") -> <shunt>;
+users_get: Path("/users") && Method("GET") -> <shunt>;
+tenants: Host("^a[.]org$") && Path("/tenants") && Header("X-A", "1") -> <shunt>;
+`),
+		},
+		{
+			File: "b.eskip",
+			Content: []byte(`tenants_b: Host("^b[.]org$") && Path("/tenants") && Header("X-A", "1") -> <shunt>;
+all: PathSubtree("/") && Method("POST") -> <shunt>;
+post: Method("POST") && Header("X-B", "1") -> <shunt>;
+`),
+		},
+	}
+
+	var strs []string
+	for _, f := range Lint(docs, nil) {
+		strs = append(strs, f.String())
+	}
+	assert.Equal(t, []string{
+		`a.eskip:2: warning: users: route is possibly shadowed by route api (a.eskip:1), it matches all its requests with the same priority [shadowed-route]`,
+		`a.eskip:4: warning: users_get: route is shadowed by route heavy (a.eskip:3), it matches all its requests with a higher priority [shadowed-route]`,
+		`b.eskip:1: warning: tenants_b: route is possibly shadowed by route tenants (a.eskip:5), its regexps may match all its requests with the same priority [shadowed-route]`,
+		`b.eskip:3: warning: post: route is shadowed by route all (b.eskip:2), it matches all its requests with a higher priority [shadowed-route]`,
+	}, strs)
+}
+
+func TestLintUnreachableRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		routes   string
+		findings []string
+	}{
+		{
+			name: "subtree catch all",
+			routes: `all: PathSubtree("/") -> <shunt>;
+get: Method("GET") -> <shunt>;
+api: Path("/api") -> <shunt>;
+`,
+			findings: []string{`routes.eskip:2: warning: get: route is unreachable due to catch-all route all (routes.eskip:1), PathSubtree("/") matches all its requests first [unreachable-route]`},
+		},
+		{
+			name: "splat catch all defined later",
+			routes: `tenant: Header("X-Tenant", "acme") && Method("GET") -> <shunt>;
+any: Path("/*any") -> <shunt>;
+`,
+			findings: []string{`routes.eskip:1: warning: tenant: route is unreachable due to catch-all route any (routes.eskip:2), Path("/*any") matches all its requests first [unreachable-route]`},
+		},
+		{
+			name: "catch all with a header",
+			routes: `all: PathSubtree("/") && Header("X-A", "1") -> <shunt>;
+get: Method("GET") -> <shunt>;
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := []Document{{File: "routes.eskip", Content: []byte(tt.routes)}}
+			var strs []string
+			for _, f := range Lint(docs, nil) {
+				strs = append(strs, f.String())
+			}
+			assert.Equal(t, tt.findings, strs)
+		})
+	}
+
+	docs := []Document{{File: "routes.eskip", Content: []byte(`all: PathSubtree("/") -> <shunt>; get: Method("GET") -> <shunt>;`)}}
+	findings := Lint(docs, &LintOptions{Ignore: []string{RuleUnreachableRoute}})
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, RuleShadowedRoute, findings[0].RuleID, "reported as shadowed when the rule is ignored")
+	}
+}
@@ -0,0 +1,287 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+// ErrNoMatch error of the TestResult simulations when no route matched
+var ErrNoMatch = errors.New("no matching route")
+
+// FilterEffect what a filter of the matching route changed in the request,
+// see TestResult.SimulateRequestFilters
+type FilterEffect struct {
+	// Filter the filter with its arguments like in eskip, eg. setPath("/a"),
+	// or the <loopback> and <dynamic> backend placeholders
+	Filter string `json:"filter" yaml:"filter"`
+	// Mocked the filter is mocked and changed nothing
+	Mocked bool `json:"mocked,omitempty" yaml:"mocked,omitempty"`
+	// Changes of the request, eg. path "/a" -> "/b", empty if none
+	Changes []string `json:"changes,omitempty" yaml:"changes,omitempty"`
+}
+
+// String the filter followed by its changes
+func (e FilterEffect) String() string {
+	switch {
+	case e.Mocked:
+		return e.Filter + ": mocked"
+	case len(e.Changes) == 0:
+		return e.Filter + ": no changes"
+	default:
+		return e.Filter + ": " + strings.Join(e.Changes, ", ")
+	}
+}
+
+// SimulateRequestFilters see TestResult.SimulateRequestFilters
+func (t *testResult) SimulateRequestFilters() (*http.Request, []FilterEffect, error) {
+	ctx, _, effects, err := t.simulateRequest()
+	if err != nil {
+		return nil, nil, err
+	}
+	if ctx.FServed {
+		return ctx.FRequest, effects, nil
+	}
+	switch routeBackendType(t.route) {
+	case eskip.LoopBackend:
+		effects = append(effects, FilterEffect{Filter: "<loopback>", Changes: []string{"not followed, test the request to find the route it loops back to"}})
+	case eskip.DynamicBackend:
+		effects = append(effects, FilterEffect{Filter: "<dynamic>", Changes: []string{"not followed, the backend is set by the filters"}})
+	}
+	return ctx.FRequest, effects, nil
+}
+
+// simulateRequest runs the request phase of the filters of the matching
+// route on a copy of the request, returns the filter context, the filters
+// which ran and their effects
+func (t *testResult) simulateRequest() (*filtertest.Context, []filters.Filter, []FilterEffect, error) {
+	if t.err != nil {
+		return nil, nil, nil, t.err
+	}
+	if t.route == nil {
+		return nil, nil, nil, ErrNoMatch
+	}
+	if t.req == nil {
+		return nil, nil, nil, errors.New("no request to simulate, the results are light")
+	}
+
+	ctx := &filtertest.Context{
+		FResponseWriter: httptest.NewRecorder(),
+		FRequest:        cloneRequest(t.req),
+		FParams:         cloneMap(t.params),
+		FStateBag:       make(map[string]interface{}),
+		FBackendUrl:     t.route.Backend,
+	}
+	if u, err := url.Parse(t.route.Backend); err == nil && routeBackendType(t.route) == eskip.NetworkBackend {
+		ctx.FOutgoingHost = u.Host
+	}
+
+	ran := make([]filters.Filter, 0, len(t.route.Filters))
+	effects := make([]FilterEffect, 0, len(t.route.Filters)+1)
+	for _, f := range t.route.Filters {
+		effect := FilterEffect{Filter: filterString(f)}
+		spec, ok := t.registry[f.Name]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("filter %s not found in the registry", f.Name)
+		}
+		filter, err := spec.CreateFilter(f.Args)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create the filter %s: %v", effect.Filter, err)
+		}
+		ran = append(ran, filter)
+		if _, ok := filter.(*filtertest.Filter); ok {
+			effect.Mocked = true
+			effects = append(effects, effect)
+			continue
+		}
+		before := snapshotRequest(ctx)
+		if err := runFilter(filter.Request, ctx); err != nil {
+			return nil, nil, nil, fmt.Errorf("filter %s: %v", effect.Filter, err)
+		}
+		effect.Changes = before.changes(snapshotRequest(ctx))
+		effects = append(effects, effect)
+		if ctx.FServed {
+			// skipper doesn't run the next filters either
+			break
+		}
+	}
+	return ctx, ran, effects, nil
+}
+
+// SimulatedResponse the response a client gets from a matching route, see
+// TestResult.SimulateResponse
+type SimulatedResponse struct {
+	// StatusCode status of the response, 0 when proxied
+	StatusCode int `json:"statusCode,omitempty" yaml:"statusCode,omitempty"`
+	// Header headers of the response
+	Header http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	// Body body of the response
+	Body string `json:"body,omitempty" yaml:"body,omitempty"`
+	// ProxiedTo backend the request is forwarded to instead, eg.
+	// http://api.svc, <loopback> or <dynamic>, empty for a response of the
+	// filters or of the shunt backend
+	ProxiedTo string `json:"proxiedTo,omitempty" yaml:"proxiedTo,omitempty"`
+}
+
+// String the status and the body, or where the request is proxied to
+func (r *SimulatedResponse) String() string {
+	if r.ProxiedTo != "" {
+		return "would be proxied to " + r.ProxiedTo
+	}
+	if r.Body == "" {
+		return fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode))
+	}
+	return fmt.Sprintf("%d %s: %s", r.StatusCode, http.StatusText(r.StatusCode), r.Body)
+}
+
+// SimulateResponse see TestResult.SimulateResponse
+func (t *testResult) SimulateResponse() (*SimulatedResponse, error) {
+	ctx, ran, _, err := t.simulateRequest()
+	if err != nil {
+		return nil, err
+	}
+	if !ctx.FServed {
+		switch routeBackendType(t.route) {
+		case eskip.ShuntBackend:
+			// like skipper, a shunt route not served by its filters
+			ctx.FResponse = &http.Response{StatusCode: http.StatusNotFound, Header: make(http.Header), Body: http.NoBody}
+		case eskip.LBBackend:
+			return &SimulatedResponse{ProxiedTo: strings.Join(t.route.LBEndpoints, ", ")}, nil
+		default:
+			return &SimulatedResponse{ProxiedTo: backendString(t.route)}, nil
+		}
+	}
+	if ctx.FResponse == nil {
+		ctx.FResponse = &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody}
+	}
+	if ctx.FResponse.Header == nil {
+		ctx.FResponse.Header = make(http.Header)
+	}
+	for i := len(ran) - 1; i >= 0; i-- {
+		if err := runFilter(ran[i].Response, ctx); err != nil {
+			return nil, fmt.Errorf("filter %s: %v", filterString(t.route.Filters[i]), err)
+		}
+	}
+
+	res := &SimulatedResponse{StatusCode: ctx.FResponse.StatusCode, Header: ctx.FResponse.Header}
+	if ctx.FResponse.Body != nil {
+		body, err := ioutil.ReadAll(ctx.FResponse.Body)
+		ctx.FResponse.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the response body: %v", err)
+		}
+		res.Body = string(body)
+	}
+	return res, nil
+}
+
+// runFilter runs a phase of a filter, a panic is an error
+func runFilter(phase func(filters.FilterContext), ctx filters.FilterContext) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	phase(ctx)
+	return nil
+}
+
+// cloneRequest copies the request with its URL and headers
+func cloneRequest(r *http.Request) *http.Request {
+	req := *r
+	u := *r.URL
+	req.URL = &u
+	req.Header = make(http.Header, len(r.Header))
+	for key, values := range r.Header {
+		req.Header[key] = append([]string{}, values...)
+	}
+	return &req
+}
+
+// requestSnapshot the parts of the request filters change
+type requestSnapshot struct {
+	method       string
+	path         string
+	query        string
+	host         string
+	outgoingHost string
+	headers      map[string]string
+	served       bool
+	status       int
+}
+
+func snapshotRequest(ctx *filtertest.Context) requestSnapshot {
+	req := ctx.FRequest
+	s := requestSnapshot{
+		method:       req.Method,
+		path:         req.URL.Path,
+		query:        req.URL.RawQuery,
+		host:         req.Host,
+		outgoingHost: ctx.FOutgoingHost,
+		headers:      make(map[string]string, len(req.Header)),
+		served:       ctx.FServed,
+	}
+	for key, values := range req.Header {
+		s.headers[key] = strings.Join(values, ", ")
+	}
+	if ctx.FResponse != nil {
+		s.status = ctx.FResponse.StatusCode
+	}
+	return s
+}
+
+// changes describes the changes from s to after
+func (s requestSnapshot) changes(after requestSnapshot) []string {
+	var changes []string
+	changed := func(what, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s %q -> %q", what, from, to))
+		}
+	}
+	changed("method", s.method, after.method)
+	changed("path", s.path, after.path)
+	changed("query", s.query, after.query)
+	changed("host", s.host, after.host)
+	changed("outgoing host", s.outgoingHost, after.outgoingHost)
+
+	keys := make([]string, 0, len(s.headers)+len(after.headers))
+	for key := range s.headers {
+		keys = append(keys, key)
+	}
+	for key := range after.headers {
+		if _, ok := s.headers[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		from, had := s.headers[key]
+		to, has := after.headers[key]
+		switch {
+		case !had:
+			changes = append(changes, fmt.Sprintf("header %s set to %q", key, to))
+		case !has:
+			changes = append(changes, fmt.Sprintf("header %s removed", key))
+		default:
+			changed("header "+key, from, to)
+		}
+	}
+
+	if !s.served && after.served {
+		if after.status != 0 {
+			changes = append(changes, fmt.Sprintf("served with status %d", after.status))
+		} else {
+			changes = append(changes, "served")
+		}
+	}
+	return changes
+}
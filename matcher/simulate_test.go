@@ -0,0 +1,134 @@
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateRequestFilters(t *testing.T) {
+	f, err := ioutil.TempFile("", "simulate-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`api: Path("/api/:id") && Method("GET") -> setPath("/v2/items/${id}") -> setRequestHeader("X-Version", "2") -> "http://api.svc";
+legacy: Path("/legacy") -> setRequestHeader("X-Version", "1") -> setPath("/old") -> setPath("/older") -> dropRequestHeader("X-Debug") -> "http://legacy.svc";
+loop: Path("/loop") -> setPath("/api/1") -> <loopback>;
+gone: Path("/gone") -> inlineContent("gone") -> setPath("/never") -> <shunt>;
+mocked: Path("/mocked") -> tenantAuth("acme") -> setRequestHeader("X-Tenant", "acme") -> "http://tenant.svc";
+`)
+	f.Close()
+
+	m, err := New(&Options{RoutesFile: f.Name(), MockFilters: []string{"tenantAuth"}})
+	require.NoError(t, err)
+	defer m.Close()
+
+	simulate := func(path string, headers map[string]string) (TestResult, []FilterEffect) {
+		res := m.Test(&RequestAttributes{Method: "GET", Path: path, Headers: headers})
+		req, effects, err := res.SimulateRequestFilters()
+		require.NoError(t, err)
+		require.NotNil(t, req)
+		return res, effects
+	}
+
+	res, effects := simulate("/api/42", nil)
+	req, _, _ := res.SimulateRequestFilters()
+	assert.Equal(t, "/v2/items/42", req.URL.Path)
+	assert.Equal(t, "2", req.Header.Get("X-Version"))
+	assert.Equal(t, []FilterEffect{
+		{Filter: `setPath("/v2/items/${id}")`, Changes: []string{`path "/api/42" -> "/v2/items/42"`}},
+		{Filter: `setRequestHeader("X-Version", "2")`, Changes: []string{`header X-Version set to "2"`}},
+	}, effects)
+	assert.Equal(t, "/api/42", res.Request().URL.Path, "the result request is left untouched")
+	assert.Empty(t, res.Request().Header.Get("X-Version"))
+
+	_, effects = simulate("/legacy", map[string]string{"X-Version": "0", "X-Debug": "true"})
+	assert.Equal(t, []FilterEffect{
+		{Filter: `setRequestHeader("X-Version", "1")`, Changes: []string{`header X-Version "0" -> "1"`}},
+		{Filter: `setPath("/old")`, Changes: []string{`path "/legacy" -> "/old"`}},
+		{Filter: `setPath("/older")`, Changes: []string{`path "/old" -> "/older"`}},
+		{Filter: `dropRequestHeader("X-Debug")`, Changes: []string{`header X-Debug removed`}},
+	}, effects)
+	assert.Equal(t, `setPath("/old"): path "/legacy" -> "/old"`, effects[1].String())
+
+	_, effects = simulate("/loop", nil)
+	if assert.Len(t, effects, 2) {
+		assert.Equal(t, "<loopback>", effects[1].Filter)
+	}
+
+	res, effects = simulate("/gone", nil)
+	req, _, _ = res.SimulateRequestFilters()
+	assert.Equal(t, "/gone", req.URL.Path, "the filters after the serving one don't run")
+	if assert.Len(t, effects, 1) {
+		assert.Equal(t, []string{"served with status 200"}, effects[0].Changes)
+	}
+
+	_, effects = simulate("/mocked", nil)
+	assert.Equal(t, []FilterEffect{
+		{Filter: `tenantAuth("acme")`, Mocked: true},
+		{Filter: `setRequestHeader("X-Tenant", "acme")`, Changes: []string{`header X-Tenant set to "acme"`}},
+	}, effects)
+
+	_, _, err = m.Test(&RequestAttributes{Method: "GET", Path: "/missing"}).SimulateRequestFilters()
+	assert.Equal(t, ErrNoMatch, err)
+}
+
+func TestSimulateResponse(t *testing.T) {
+	f, err := ioutil.TempFile("", "simulate-response-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`notfound: Path("/missing") -> status(404) -> inlineContent("not found") -> <shunt>;
+teapot: Path("/teapot") -> setResponseHeader("X-Teapot", "yes") -> status(418) -> <shunt>;
+old: Path("/old") -> redirectTo(301, "https://example.org/new") -> <shunt>;
+shunt: Path("/shunt") -> <shunt>;
+proxied: Path("/api") -> setResponseHeader("X-Api", "1") -> "http://api.svc";
+redirected: Path("/moved") -> redirectTo(302, "/elsewhere") -> "http://api.svc";
+loop: Path("/loop") -> setPath("/api") -> <loopback>;
+`)
+	f.Close()
+
+	m, err := New(&Options{RoutesFile: f.Name()})
+	require.NoError(t, err)
+	defer m.Close()
+
+	simulate := func(path string) *SimulatedResponse {
+		res, err := m.Test(&RequestAttributes{Method: "GET", Path: path}).SimulateResponse()
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		return res
+	}
+
+	res := simulate("/missing")
+	assert.Equal(t, 404, res.StatusCode)
+	assert.Equal(t, "not found", res.Body)
+	assert.Equal(t, "404 Not Found: not found", res.String())
+
+	res = simulate("/teapot")
+	assert.Equal(t, 418, res.StatusCode)
+	assert.Equal(t, "yes", res.Header.Get("X-Teapot"))
+	assert.Empty(t, res.Body)
+
+	res = simulate("/old")
+	assert.Equal(t, 301, res.StatusCode)
+	assert.Equal(t, "https://example.org/new", res.Header.Get("Location"))
+
+	res = simulate("/shunt")
+	assert.Equal(t, 404, res.StatusCode, "the shunt backend responds 404")
+
+	res = simulate("/moved")
+	assert.Equal(t, 302, res.StatusCode, "served by the filters before reaching the backend")
+	assert.Empty(t, res.ProxiedTo)
+
+	res = simulate("/api")
+	assert.Equal(t, "http://api.svc", res.ProxiedTo)
+	assert.Equal(t, "would be proxied to http://api.svc", res.String())
+	assert.Zero(t, res.StatusCode)
+
+	res = simulate("/loop")
+	assert.Equal(t, "<loopback>", res.ProxiedTo)
+
+	_, err = m.Test(&RequestAttributes{Method: "GET", Path: "/nothing"}).SimulateResponse()
+	assert.Equal(t, ErrNoMatch, err)
+}
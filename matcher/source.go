@@ -0,0 +1,233 @@
+package matcher
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Location position of a route definition in a routes file
+type Location struct {
+	// File path of the routes file
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// Line 1-based line where the route id appears
+	Line int `json:"line,omitempty" yaml:"line,omitempty"`
+}
+
+// String returns file:line
+func (l Location) String() string {
+	if l.Line == 0 {
+		return l.File
+	}
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// RouteLocations scans an eskip document and returns where each route is
+// defined, by route id. Eskip parsing doesn't keep the position of the routes
+// so the document is scanned on its own: comments, strings and regexps are
+// skipped and the identifier followed by ':' at the start of each route
+// definition is taken as the route id. When an id is defined more than once
+// the first definition wins.
+func RouteLocations(file string, doc []byte) map[string]Location {
+	locations := make(map[string]Location)
+	for _, d := range scanRouteDefinitions(file, doc) {
+		if _, ok := locations[d.id]; !ok {
+			locations[d.id] = d.location
+		}
+	}
+	return locations
+}
+
+// suppressDirective prefix of the comments suppressing findings of a route,
+// followed by the rule ids, eg. // eskip-match:ignore shadowed-route
+const suppressDirective = "eskip-match:ignore"
+
+// routeDefinition id and location of a route definition
+type routeDefinition struct {
+	id       string
+	location Location
+	// suppressed rule ids of the suppress directives of the route
+	suppressed []suppression
+	// comments the comments before the route, since the end of the
+	// previous one
+	comments []comment
+}
+
+// suppression a rule id of a suppress directive and where it is
+type suppression struct {
+	rule string
+	line int
+}
+
+// comment a comment of the document, without the leading //
+type comment struct {
+	text string
+	line int
+}
+
+// scanRouteDefinitions returns all the route definitions of an eskip
+// document in order, see RouteLocations. The suppress directives of a route
+// are the ones in the comments before it, in it or after it on the line
+// where it ends.
+func scanRouteDefinitions(file string, doc []byte) []routeDefinition {
+	var defs []routeDefinition
+	s := &scanner{doc: []rune(string(doc)), line: 1}
+	// last the definition of the previous route, -1 if it had no id
+	last, lastLine := -1, 0
+	for {
+		s.skipSpace()
+		comments := s.comments
+		s.comments = nil
+		if last >= 0 {
+			i := 0
+			for i < len(comments) && comments[i].line == lastLine {
+				i++
+			}
+			defs[last].suppressed = append(defs[last].suppressed, suppressions(comments[:i])...)
+			comments = comments[i:]
+		}
+		if s.eof() {
+			return defs
+		}
+
+		last = -1
+		if line, id := s.line, s.ident(); id != "" {
+			s.skipSpace()
+			if s.peek() == ':' {
+				defs = append(defs, routeDefinition{id: id, location: Location{File: file, Line: line}, comments: comments})
+				last = len(defs) - 1
+			}
+		}
+		s.skipRoute()
+		lastLine = s.line
+		if last >= 0 {
+			defs[last].suppressed = suppressions(append(comments, s.comments...))
+		}
+		s.comments = nil
+	}
+}
+
+// leadingSuppressions the suppress directives in the comments on the first
+// line of the document, before its first route
+func leadingSuppressions(doc []byte) []suppression {
+	s := &scanner{doc: []rune(string(doc)), line: 1}
+	s.skipSpace()
+	var first []comment
+	for _, c := range s.comments {
+		if c.line == 1 {
+			first = append(first, c)
+		}
+	}
+	return suppressions(first)
+}
+
+// suppressions the rule ids of the suppress directives in the comments
+func suppressions(comments []comment) []suppression {
+	var rules []suppression
+	for _, c := range comments {
+		text := strings.TrimSpace(c.text)
+		if !strings.HasPrefix(text, suppressDirective) {
+			continue
+		}
+		rest := text[len(suppressDirective):]
+		if rest != "" && !unicode.IsSpace(rune(rest[0])) {
+			continue
+		}
+		for _, rule := range strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+			rules = append(rules, suppression{rule, c.line})
+		}
+	}
+	return rules
+}
+
+// scanner minimal eskip tokenizer used to locate the route definitions
+type scanner struct {
+	doc  []rune
+	pos  int
+	line int
+	// comments skipped since the last reset
+	comments []comment
+}
+
+func (s *scanner) eof() bool {
+	return s.pos >= len(s.doc)
+}
+
+func (s *scanner) peek() rune {
+	if s.eof() {
+		return 0
+	}
+	return s.doc[s.pos]
+}
+
+func (s *scanner) next() rune {
+	r := s.doc[s.pos]
+	s.pos++
+	if r == '\n' {
+		s.line++
+	}
+	return r
+}
+
+// skipSpace skips white spaces and comments
+func (s *scanner) skipSpace() {
+	for !s.eof() {
+		switch {
+		case unicode.IsSpace(s.peek()):
+			s.next()
+		case s.isComment():
+			start, line := s.pos+2, s.line
+			for !s.eof() && s.peek() != '\n' {
+				s.next()
+			}
+			s.comments = append(s.comments, comment{string(s.doc[start:s.pos]), line})
+		default:
+			return
+		}
+	}
+}
+
+func (s *scanner) isComment() bool {
+	return s.peek() == '/' && s.pos+1 < len(s.doc) && s.doc[s.pos+1] == '/'
+}
+
+// ident reads an identifier, empty if the next token isn't one
+func (s *scanner) ident() string {
+	start := s.pos
+	for !s.eof() {
+		r := s.peek()
+		if r != '_' && !unicode.IsLetter(r) && (s.pos == start || !unicode.IsDigit(r)) {
+			break
+		}
+		s.next()
+	}
+	return string(s.doc[start:s.pos])
+}
+
+// skipRoute skips everything up to the end of the current route definition
+func (s *scanner) skipRoute() {
+	for !s.eof() {
+		switch {
+		case s.isComment():
+			s.skipSpace()
+		case s.peek() == '"' || s.peek() == '/' || s.peek() == '`':
+			s.skipQuoted(s.next())
+		case s.next() == ';':
+			return
+		}
+	}
+}
+
+// skipQuoted skips a string or a regexp, the opening quote is already consumed
+func (s *scanner) skipQuoted(quote rune) {
+	for !s.eof() {
+		switch s.next() {
+		case '\\':
+			if !s.eof() {
+				s.next()
+			}
+		case quote:
+			return
+		}
+	}
+}
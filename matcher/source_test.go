@@ -0,0 +1,30 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteLocations(t *testing.T) {
+	doc := `// header comment; with a semicolon
+foo: Path("/foo;bar") -> <shunt>;
+
+bar:
+  PathRegexp(/^\/bar;$/) // trailing; comment
+  -> setPath("/") // baz: not a route
+  -> "https://example.org";
+foo: Path("/dup") -> <shunt>;
+  _baz2 : Path("/baz") -> <shunt>;
+`
+	assert.Equal(t, map[string]Location{
+		"foo":   {File: "routes.eskip", Line: 2},
+		"bar":   {File: "routes.eskip", Line: 4},
+		"_baz2": {File: "routes.eskip", Line: 9},
+	}, RouteLocations("routes.eskip", []byte(doc)))
+}
+
+func TestLocationString(t *testing.T) {
+	assert.Equal(t, "routes.eskip:3", Location{File: "routes.eskip", Line: 3}.String())
+	assert.Equal(t, "routes.eskip", Location{File: "routes.eskip"}.String())
+}
@@ -0,0 +1,99 @@
+package matcher
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// requestPool requests reused by the light results streams
+var requestPool = sync.Pool{
+	New: func() interface{} {
+		return &http.Request{}
+	},
+}
+
+// lightResult storage of a light result
+type lightResult struct {
+	result     testResult
+	attributes RequestAttributes
+	route      eskip.Route
+}
+
+// TestManyStream see Matcher.TestManyStream
+func (f *matcher) TestManyStream(in <-chan *RequestAttributes, out chan<- TestResult) {
+	defer close(out)
+	if !f.options.LightResults {
+		for attributes := range in {
+			out <- f.Test(attributes)
+		}
+		return
+	}
+
+	// once a result is sent the receiver is done with the results sent
+	// before the buffered ones, so cap(out)+2 results are enough
+	results := make([]lightResult, cap(out)+2)
+	i := 0
+	for attributes := range in {
+		out <- f.testLight(attributes, &results[i])
+		i = (i + 1) % len(results)
+	}
+}
+
+// testLight like Test, the result is stored in r and the request is pooled
+func (f *matcher) testLight(attributes *RequestAttributes, r *lightResult) TestResult {
+	if f.options.Hooks.OnTest != nil {
+		defer func(start time.Time) { f.onTest(&r.result, time.Since(start)) }(time.Now())
+	}
+	if f.decisions != nil {
+		defer func(original RequestAttributes, start time.Time) { f.decisions.record(&original, &r.result, start) }(*attributes, time.Now())
+	}
+	r.attributes = *attributes
+	normalize(&r.attributes)
+	r.result = testResult{attributes: &r.attributes, defaults: f.defaults}
+	defer func() {
+		if r.result.err != nil {
+			r.result.err = newMatchError(&r.attributes, f.RouteCount(), routesSource(f.options), r.result.err)
+		}
+	}()
+	if f.isClosed() {
+		r.result.err = ErrClosed
+		return &r.result
+	}
+
+	if !f.options.AllowInvalidHeaders {
+		if err := validateHeaders(r.attributes.Headers); err != nil {
+			r.result.err = err
+			return &r.result
+		}
+	}
+	req := requestPool.Get().(*http.Request)
+	defer requestPool.Put(req)
+	if err := fillHTTPRequest(req, &r.attributes); err != nil {
+		r.result.err = err
+		return &r.result
+	}
+	route, _, err := f.matchRoute(req)
+	if err != nil {
+		r.result.err = err
+		return &r.result
+	}
+	if route != nil && route.Id != "" {
+		r.route = route.Route
+		r.result.route = &r.route
+		r.result.lbMember = f.decideLB(req, r.result.route)
+	}
+	f.diag.test(f, &r.attributes, r.result.route)
+	return &r.result
+}
+
+// failStream sends a result with err for each request attributes received
+// from in, source describes the routes
+func failStream(in <-chan *RequestAttributes, out chan<- TestResult, source string, err error) {
+	defer close(out)
+	for attributes := range in {
+		out <- errResult(attributes, source, err)
+	}
+}
@@ -0,0 +1,132 @@
+package matcher
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stream sends the requests to m.TestManyStream and collects what the
+// results report, before the next receive
+func stream(m Matcher, buffer int, requests []*RequestAttributes) (ids []string, errs []error) {
+	in := make(chan *RequestAttributes)
+	out := make(chan TestResult, buffer)
+	go func() {
+		for _, r := range requests {
+			in <- r
+		}
+		close(in)
+	}()
+	go m.TestManyStream(in, out)
+	for res := range out {
+		ids = append(ids, routeID(res))
+		errs = append(errs, res.Err())
+	}
+	return ids, errs
+}
+
+func streamRequests() (requests []*RequestAttributes, ids []string) {
+	for i := 0; i < 20; i++ {
+		c := concurrentCases[i%len(concurrentCases)]
+		a := c.attrs
+		requests = append(requests, &a)
+		ids = append(ids, c.routeID)
+	}
+	return requests, ids
+}
+
+func TestMatcherTestManyStream(t *testing.T) {
+	requests, expected := streamRequests()
+	for _, light := range []bool{false, true} {
+		for _, buffer := range []int{0, 3} {
+			m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, LightResults: light})
+			require.NoError(t, err)
+			ids, errs := stream(m, buffer, requests)
+			assert.Equal(t, expected, ids, "light %t, buffer %d", light, buffer)
+			for _, err := range errs {
+				assert.NoError(t, err)
+			}
+			m.Close()
+		}
+	}
+}
+
+func TestMatcherTestManyStreamLightResults(t *testing.T) {
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, LightResults: true})
+	require.NoError(t, err)
+	defer m.Close()
+
+	in := make(chan *RequestAttributes, 3)
+	out := make(chan TestResult)
+	attrs := &RequestAttributes{Method: "get", Path: "foo"}
+	in <- attrs
+	in <- &RequestAttributes{Path: "/a", Host: "bad host"}
+	in <- &RequestAttributes{Path: "/bar"}
+	close(in)
+	go m.TestManyStream(in, out)
+
+	res := <-out
+	require.NotNil(t, res.Route())
+	assert.Equal(t, "foo_get", res.Route().Id)
+	assert.Nil(t, res.Request(), "light results don't retain the request")
+	assert.Equal(t, "/foo", res.Attributes().Path)
+	assert.Equal(t, &RequestAttributes{Method: "get", Path: "foo"}, attrs)
+
+	res = <-out
+	assert.Error(t, res.Err())
+	assert.Nil(t, res.Route())
+
+	res = <-out
+	assert.Equal(t, "bar", routeID(res))
+	_, ok := <-out
+	assert.False(t, ok, "out must be closed")
+}
+
+func TestMatcherTestManyStreamClosed(t *testing.T) {
+	requests, _ := streamRequests()
+	for _, o := range []*Options{
+		{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}},
+		{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, LightResults: true},
+		{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, Lazy: true},
+	} {
+		m, err := New(o)
+		require.NoError(t, err)
+		m.Close()
+		_, errs := stream(m, 0, requests[:3])
+		require.Len(t, errs, 3)
+		for _, err := range errs {
+			assert.True(t, errors.Is(err, ErrClosed))
+		}
+	}
+}
+
+func BenchmarkTestManyStream(b *testing.B) {
+	requests, _ := streamRequests()
+	for _, light := range []bool{false, true} {
+		name := "naive"
+		if light {
+			name = "light"
+		}
+		b.Run(name, func(b *testing.B) {
+			m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, LightResults: light})
+			require.NoError(b, err)
+			defer m.Close()
+			in := make(chan *RequestAttributes, 64)
+			out := make(chan TestResult, 64)
+			go m.TestManyStream(in, out)
+			go func() {
+				for i := 0; i < b.N; i++ {
+					in <- requests[i%len(requests)]
+				}
+				close(in)
+			}()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for res := range out {
+				routeID(res)
+			}
+		})
+	}
+}
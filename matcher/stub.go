@@ -0,0 +1,114 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+)
+
+// StubNearMisses number of near misses of the 404 responses of
+// NewStubServer
+const StubNearMisses = 3
+
+// StubResponse canned response of a route of NewStubServer
+type StubResponse struct {
+	// Status status code, 200 when 0
+	Status int
+	// Headers headers of the response
+	Headers map[string]string
+	// Body body of the response
+	Body string
+}
+
+// stubMissData body of the 404 responses of NewStubServer
+type stubMissData struct {
+	Error string `json:"error"`
+	// Request the normalized request
+	Request *RequestAttributes `json:"request"`
+	// NearMisses the closest routes not matching, the closest one first
+	NearMisses []NearMiss `json:"nearMisses"`
+}
+
+// NewStubServer starts a server answering every request with the canned
+// response of the route it matches, a fake edge for the end-to-end tests of
+// the clients: the responses have the DefaultRouteHeader and
+// DefaultBackendHeader headers of the route and its response, the json
+// result of the test for the routes without one. The requests matching no
+// route get a 404 with the StubNearMisses closest routes, the invalid ones
+// a 400. The responses must be of loaded routes, it panics otherwise. The
+// caller closes the server, the server doesn't close the matcher.
+func NewStubServer(m Matcher, responses map[string]StubResponse) *httptest.Server {
+	return httptest.NewServer(newStubHandler(m, responses))
+}
+
+// newStubHandler the handler of NewStubServer
+func newStubHandler(m Matcher, responses map[string]StubResponse) http.Handler {
+	loaded := make(map[string]bool)
+	for _, r := range m.Routes() {
+		loaded[r.Id] = true
+	}
+	var unknown []string
+	for id := range responses {
+		if !loaded[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		panic(fmt.Sprintf("eskip-match: stub responses of routes not loaded: %v", unknown))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attrs := requestAttributes(r)
+		res := m.Test(attrs)
+		if err := res.Err(); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrClosed) {
+				status = http.StatusServiceUnavailable
+			}
+			writeStubJSON(w, status, map[string]string{"error": matchCause(err).Error()})
+			return
+		}
+		route := res.Route()
+		if route == nil {
+			writeStubJSON(w, http.StatusNotFound, stubMissData{
+				Error:      "no route matches the request",
+				Request:    res.Attributes(),
+				NearMisses: m.Explain(attrs, StubNearMisses),
+			})
+			return
+		}
+
+		w.Header().Set(DefaultRouteHeader, route.Id)
+		w.Header().Set(DefaultBackendHeader, backendString(route))
+		response, ok := responses[route.Id]
+		if !ok {
+			writeStubJSON(w, http.StatusOK, NewResultData(res))
+			return
+		}
+		for name, value := range response.Headers {
+			w.Header().Set(name, value)
+		}
+		status := response.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(response.Body))
+	})
+}
+
+func writeStubJSON(w http.ResponseWriter, status int, v interface{}) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	// the bodies are always representable in json
+	enc.Encode(v)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,127 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const stubRoutes = `orders: Path("/orders") && Method("POST") -> "https://orders.example.org";
+order: Path("/orders/:id") -> "https://orders.example.org";
+search: Path("/search") && QueryParam("q") -> <shunt>;`
+
+func ExampleNewStubServer() {
+	m, err := New(&Options{RoutesFile: "./testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer m.Close()
+
+	edge := NewStubServer(m, map[string]StubResponse{
+		"bar": {Headers: map[string]string{"Content-Type": "application/json"}, Body: `{"bar": true}`},
+	})
+	defer edge.Close()
+
+	req, _ := http.NewRequest("GET", edge.URL+"/bar", nil)
+	req.Header.Set("Accept", "application/json")
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	body, _ := ioutil.ReadAll(rsp.Body)
+	fmt.Println(rsp.StatusCode, rsp.Header.Get("X-Eskip-Match-Route"), string(body))
+	// Output: 200 bar {"bar": true}
+}
+
+func TestStubServer(t *testing.T) {
+	m := newTestMatcher(t, stubRoutes)
+	defer m.Close()
+	edge := NewStubServer(m, map[string]StubResponse{
+		"orders": {Status: http.StatusCreated, Headers: map[string]string{"Location": "/orders/1"}, Body: "created"},
+		"order":  {Body: `{"id": 1}`},
+	})
+	defer edge.Close()
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		status  int
+		route   string
+		backend string
+		header  string
+		body    string
+	}{
+		{name: "registered response", method: "POST", target: "/orders", status: http.StatusCreated, route: "orders", backend: "https://orders.example.org", header: "/orders/1", body: "created"},
+		{name: "default status", method: "GET", target: "/orders/1", status: http.StatusOK, route: "order", backend: "https://orders.example.org", body: `{"id": 1}`},
+		{name: "result", method: "GET", target: "/search?q=shoes", status: http.StatusOK, route: "search", backend: "<shunt>", body: `"routeId":"search"`},
+		{name: "no match", method: "GET", target: "/orders", status: http.StatusNotFound, body: `"nearMisses":[{"routeId":"orders"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, edge.URL+tt.target, nil)
+			require.NoError(t, err)
+			rsp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer rsp.Body.Close()
+			body, err := ioutil.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.status, rsp.StatusCode)
+			assert.Equal(t, tt.route, rsp.Header.Get(DefaultRouteHeader))
+			assert.Equal(t, tt.backend, rsp.Header.Get(DefaultBackendHeader))
+			assert.Equal(t, tt.header, rsp.Header.Get("Location"))
+			assert.Contains(t, string(body), tt.body)
+		})
+	}
+}
+
+func TestStubServerNoMatch(t *testing.T) {
+	m := newTestMatcher(t, stubRoutes)
+	defer m.Close()
+	edge := NewStubServer(m, nil)
+	defer edge.Close()
+
+	rsp, err := http.Get(edge.URL + "/search")
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, rsp.StatusCode)
+	assert.Equal(t, "application/json", rsp.Header.Get("Content-Type"))
+
+	var data stubMissData
+	require.NoError(t, json.NewDecoder(rsp.Body).Decode(&data))
+	assert.Equal(t, "no route matches the request", data.Error)
+	assert.Equal(t, "/search", data.Request.Path)
+	require.NotEmpty(t, data.NearMisses)
+	assert.Equal(t, "search", data.NearMisses[0].RouteID)
+	assert.Equal(t, 1, data.NearMisses[0].Failed)
+	assert.True(t, len(data.NearMisses) <= StubNearMisses)
+}
+
+func TestStubServerUnknownRoute(t *testing.T) {
+	m := newTestMatcher(t, stubRoutes)
+	defer m.Close()
+	assert.PanicsWithValue(t, "eskip-match: stub responses of routes not loaded: [missing ordrs]", func() {
+		NewStubServer(m, map[string]StubResponse{"ordrs": {}, "missing": {}, "orders": {}})
+	})
+}
+
+func TestStubServerClosedMatcher(t *testing.T) {
+	m := newTestMatcher(t, stubRoutes)
+	edge := NewStubServer(m, nil)
+	defer edge.Close()
+	m.Close()
+
+	rsp, err := http.Post(edge.URL+"/orders", "text/plain", strings.NewReader("ignored"))
+	require.NoError(t, err)
+	rsp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, rsp.StatusCode)
+}
@@ -0,0 +1,193 @@
+package matcher
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ansi escape sequences used by ColorStyle
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// prettyPredicateSep separates the predicates of a pretty printed route
+const prettyPredicateSep = " && "
+
+// Style decorates parts of the pretty printed output, eg. with colors.
+// The zero value decorates nothing.
+type Style struct {
+	// Match decorates the matching route id
+	Match func(s string) string
+	// NoMatch decorates no match notices
+	NoMatch func(s string) string
+	// Predicate decorates each predicate of the printed routes
+	Predicate func(s string) string
+	// Removed decorates the old lines of the diffs
+	Removed func(s string) string
+	// Added decorates the new lines of the diffs
+	Added func(s string) string
+}
+
+// PlainStyle leaves the output undecorated
+var PlainStyle = Style{}
+
+// ColorStyle decorates the output with ansi colors,
+// matches in green, no matches in red and predicates in yellow, the old
+// lines of the diffs in red and the new ones in green
+var ColorStyle = Style{
+	Match:     ansiColor(ansiGreen),
+	NoMatch:   ansiColor(ansiRed),
+	Predicate: ansiColor(ansiYellow),
+	Removed:   ansiColor(ansiRed),
+	Added:     ansiColor(ansiGreen),
+}
+
+// isTerminal true if w is attached to a terminal, replaced by tests
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && terminal.IsTerminal(int(f.Fd()))
+}
+
+// TerminalStyle ColorStyle when w is attached to a terminal and the
+// NO_COLOR environment variable isn't set, PlainStyle otherwise
+func TerminalStyle(w io.Writer) Style {
+	if os.Getenv("NO_COLOR") != "" || !isTerminal(w) {
+		return PlainStyle
+	}
+	return ColorStyle
+}
+
+func ansiColor(color string) func(s string) string {
+	return func(s string) string {
+		return color + s + ansiReset
+	}
+}
+
+func decorate(f func(s string) string, s string) string {
+	if f == nil {
+		return s
+	}
+	return f(s)
+}
+
+// DecorateMatch decorates str as a match
+func (s Style) DecorateMatch(str string) string {
+	return decorate(s.Match, str)
+}
+
+// DecorateNoMatch decorates str as a no match notice
+func (s Style) DecorateNoMatch(str string) string {
+	return decorate(s.NoMatch, str)
+}
+
+// DecoratePredicate decorates str as a predicate
+func (s Style) DecoratePredicate(str string) string {
+	return decorate(s.Predicate, str)
+}
+
+// DecorateRemoved decorates str as an old line of a diff
+func (s Style) DecorateRemoved(str string) string {
+	return decorate(s.Removed, str)
+}
+
+// DecorateAdded decorates str as a new line of a diff
+func (s Style) DecorateAdded(str string) string {
+	return decorate(s.Added, str)
+}
+
+// prettyPrintRoute pretty prints a route definition decorating its predicates.
+// Eskip prints the predicates in its own order, so each one is looked up at the
+// start of the predicates section; when the lookup fails the definition is
+// returned undecorated.
+func prettyPrintRoute(r *eskip.Route, style Style) string {
+	def := r.Print(eskip.PrettyPrintInfo{
+		Pretty:    true,
+		IndentStr: "  ",
+	})
+	if style.Predicate == nil {
+		return def
+	}
+
+	end := strings.Index(def, "\n  -> ")
+	if end < 0 {
+		end = len(def)
+	}
+	section, rest := def[:end], def[end:]
+
+	candidates := printedPredicates(r)
+	if len(candidates) == 0 {
+		// catch all route, printed as *
+		return style.DecoratePredicate(section) + rest
+	}
+	// the longest first, a predicate can be the prefix of another one
+	sort.Slice(candidates, func(i, j int) bool { return len(candidates[i]) > len(candidates[j]) })
+
+	var b strings.Builder
+	for pos := 0; pos < len(section); {
+		if pos > 0 {
+			if !strings.HasPrefix(section[pos:], prettyPredicateSep) {
+				return def
+			}
+			b.WriteString(prettyPredicateSep)
+			pos += len(prettyPredicateSep)
+		}
+		found := ""
+		for _, c := range candidates {
+			if strings.HasPrefix(section[pos:], c) {
+				found = c
+				break
+			}
+		}
+		if found == "" {
+			return def
+		}
+		b.WriteString(style.DecoratePredicate(found))
+		pos += len(found)
+	}
+	return b.String() + rest
+}
+
+// printedPredicates the predicates of r as eskip prints them, the legacy
+// fields are printed on their own because eskip formats their arguments
+// differently (eg. Host regexps between slashes)
+func printedPredicates(r *eskip.Route) []string {
+	var parts []*eskip.Route
+	if r.Path != "" {
+		parts = append(parts, &eskip.Route{Path: r.Path})
+	}
+	for _, rx := range r.HostRegexps {
+		parts = append(parts, &eskip.Route{HostRegexps: []string{rx}})
+	}
+	for _, rx := range r.PathRegexps {
+		parts = append(parts, &eskip.Route{PathRegexps: []string{rx}})
+	}
+	if r.Method != "" {
+		parts = append(parts, &eskip.Route{Method: r.Method})
+	}
+	for name, value := range r.Headers {
+		parts = append(parts, &eskip.Route{Headers: map[string]string{name: value}})
+	}
+	for name, rxs := range r.HeaderRegexps {
+		for _, rx := range rxs {
+			parts = append(parts, &eskip.Route{HeaderRegexps: map[string][]string{name: {rx}}})
+		}
+	}
+	for _, p := range r.Predicates {
+		parts = append(parts, &eskip.Route{Predicates: []*eskip.Predicate{p}})
+	}
+
+	printed := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part.BackendType = eskip.ShuntBackend
+		printed = append(printed, strings.TrimSuffix(part.String(), " -> <shunt>"))
+	}
+	return printed
+}
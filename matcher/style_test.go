@@ -0,0 +1,72 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zalando/skipper/eskip"
+)
+
+func TestPrettyPrintRoute(t *testing.T) {
+	brackets := Style{Predicate: func(s string) string { return "[" + s + "]" }}
+
+	tests := []struct {
+		name  string
+		route string
+		style Style
+		want  string
+	}{
+		{
+			name:  "plain",
+			route: `r: Path("/a") && Method("GET") -> <shunt>`,
+			style: PlainStyle,
+			want:  "Path(\"/a\") && Method(\"GET\")\n  -> <shunt>",
+		},
+		{
+			name:  "predicates",
+			route: `r: Host(/^a$/) && Path("/a") && Header("X", "y") && QueryParam("q") -> setPath("/") -> "http://a.example.org"`,
+			style: brackets,
+			want:  "[Path(\"/a\")] && [Host(/^a$/)] && [Header(\"X\", \"y\")] && [QueryParam(\"q\")]\n  -> setPath(\"/\")\n  -> \"http://a.example.org\"",
+		},
+		{
+			name:  "separator in the arguments",
+			route: `r: Path("/a && b") && Cookie("c", "d") -> <shunt>`,
+			style: brackets,
+			want:  "[Path(\"/a && b\")] && [Cookie(\"c\", \"d\")]\n  -> <shunt>",
+		},
+		{
+			name:  "catch all",
+			route: `r: * -> <shunt>`,
+			style: brackets,
+			want:  "[*]\n  -> <shunt>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := eskip.Parse(tt.route)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, prettyPrintRoute(routes[0], tt.style))
+		})
+	}
+}
+
+func TestPrettyPrintLinesWith(t *testing.T) {
+	routes, err := eskip.Parse(`bar: Path("/bar") -> <shunt>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &testResult{
+		route:      routes[0],
+		attributes: &RequestAttributes{Method: "GET", Path: "/bar"},
+	}
+
+	assert.Equal(t, res.PrettyPrintLines(), res.PrettyPrintLinesWith(PlainStyle))
+	assert.Equal(t, []string{
+		"request: GET /bar",
+		"matching route id: \x1b[32mbar\x1b[0m",
+		"matching route:\n```bar: \x1b[33mPath(\"/bar\")\x1b[0m\n  -> <shunt>\n```",
+	}, res.PrettyPrintLinesWith(ColorStyle))
+}
@@ -0,0 +1,108 @@
+package matcher
+
+import (
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+// pathsInSubtrees reports the routes with a literal Path inside the
+// PathSubtree of another route when a request can match both: the Path route
+// wins for the exact path, the PathSubtree one serves the sub-paths. The
+// PathSubtree("/") catch-alls are skipped. Only with CheckPathSubtree.
+func (l *linter) pathsInSubtrees() {
+	if !l.rules.CheckPathSubtree {
+		return
+	}
+	var subtrees []lintRoute
+	for _, r := range l.routes {
+		if s, ok := literalPathArg(r.route, pathSubtreePredicate); ok && s != "/" {
+			subtrees = append(subtrees, r)
+		}
+	}
+	if len(subtrees) == 0 {
+		return
+	}
+
+	for _, r := range l.routes {
+		path, ok := literalPathArg(r.route, pathPredicate)
+		if !ok {
+			continue
+		}
+		for _, s := range subtrees {
+			exact, sub, ok := l.pathInSubtree(r.route, s.route)
+			if !ok {
+				continue
+			}
+			subtree, _ := literalPathArg(s.route, pathSubtreePredicate)
+			f := l.report(RulePathInSubtree, SeverityWarning, r, "Path(%q) is inside PathSubtree(%q) of route %s (%s): this route wins for the exact path, eg. %s, route %s serves the sub-paths, eg. %s",
+				path, subtree, s.route.Id, s.location, witnessString(exact), s.route.Id, witnessString(sub))
+			if f != nil {
+				f.Witness = exact
+			}
+		}
+	}
+}
+
+// pathInSubtree returns the witnesses of a Path route inside the subtree of
+// a PathSubtree route, ok is false if they don't match the same requests or
+// the rule isn't checked
+func (l *linter) pathInSubtree(pathRoute, subtreeRoute *eskip.Route) (exact *RequestAttributes, sub *RequestAttributes, ok bool) {
+	if !l.rules.CheckPathSubtree || l.rules.ignored(RulePathInSubtree) {
+		return nil, nil, false
+	}
+	path, ok := literalPathArg(pathRoute, pathPredicate)
+	if !ok {
+		return nil, nil, false
+	}
+	subtree, ok := literalPathArg(subtreeRoute, pathSubtreePredicate)
+	if !ok || subtree == "/" || pathRoute.Id == subtreeRoute.Id || !inSubtree(path, subtree) {
+		return nil, nil, false
+	}
+	return subtreeWitnesses(pathRoute, subtreeRoute, l.predicates)
+}
+
+// literalPathArg the argument of the Path or PathSubtree predicate of the
+// route, if it has one without wildcards
+func literalPathArg(r *eskip.Route, name string) (string, bool) {
+	for _, p := range Predicates(r) {
+		if p.Name != name {
+			continue
+		}
+		path, ok := stringArg(p, 0)
+		if !ok || strings.Contains(path, "/:") || strings.Contains(path, "/*") {
+			return "", false
+		}
+		return path, true
+	}
+	return "", false
+}
+
+// inSubtree true if the path is the subtree or one of its sub-paths
+func inSubtree(path, subtree string) bool {
+	path, subtree = cleanPath(path, true), cleanPath(subtree, true)
+	return path == subtree || strings.HasPrefix(path, strings.TrimSuffix(subtree, "/")+"/")
+}
+
+// subtreeWitnesses generates a request for the exact path of the Path route
+// matched by both the routes, and one for a sub-path matched by the
+// PathSubtree route only
+func subtreeWitnesses(pathRoute, subtreeRoute *eskip.Route, specs map[string]routing.PredicateSpec) (exact *RequestAttributes, sub *RequestAttributes, ok bool) {
+	ps := Predicates(pathRoute)
+	for _, p := range Predicates(subtreeRoute) {
+		if p.Name != pathSubtreePredicate {
+			ps = append(ps, p)
+		}
+	}
+	exact, unsatisfied := GenerateRequest(&eskip.Route{Predicates: ps})
+	if len(unsatisfied) > 0 || !routeMatches(pathRoute, exact, specs) || !routeMatches(subtreeRoute, exact, specs) {
+		return nil, nil, false
+	}
+	sub = copyAttributes(exact)
+	sub.Path = strings.TrimSuffix(exact.Path, "/") + "/x"
+	if !routeMatches(subtreeRoute, sub, specs) || routeMatches(pathRoute, sub, specs) {
+		return nil, nil, false
+	}
+	return exact, sub, true
+}
@@ -0,0 +1,57 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintPathInSubtree(t *testing.T) {
+	docs := []Document{{
+		File: "routes.eskip",
+		Content: []byte(`api: PathSubtree("/api") && Header("X-Tenant", "acme") -> <shunt>;
+users: Path("/api/users") && Method("GET") -> <shunt>;
+api_root: Path("/api") -> <shunt>;
+other_tenant: Path("/api/orders") && Header("X-Tenant", "other") -> <shunt>;
+versioned: Path("/api/:version/users") -> <shunt>;
+outside: Path("/apis") -> <shunt>;
+all: PathSubtree("/") -> <shunt>;
+`),
+	}}
+
+	assert.Empty(t, Lint(docs, &LintOptions{Ignore: []string{RuleShadowedRoute}}), "disabled by default")
+
+	findings := Lint(docs, &LintOptions{CheckPathSubtree: true, Ignore: []string{RuleShadowedRoute}})
+	var strs []string
+	for _, f := range findings {
+		strs = append(strs, f.String())
+	}
+	assert.Equal(t, []string{
+		`routes.eskip:2: warning: users: Path("/api/users") is inside PathSubtree("/api") of route api (routes.eskip:1): this route wins for the exact path, eg. GET /api/users X-Tenant: acme, route api serves the sub-paths, eg. GET /api/users/x X-Tenant: acme [path-in-subtree]`,
+		`routes.eskip:3: warning: api_root: Path("/api") is inside PathSubtree("/api") of route api (routes.eskip:1): this route wins for the exact path, eg. GET /api X-Tenant: acme, route api serves the sub-paths, eg. GET /api/x X-Tenant: acme [path-in-subtree]`,
+	}, strs)
+	if assert.NotEmpty(t, findings) {
+		assert.Equal(t, &RequestAttributes{
+			Method:  "GET",
+			Path:    "/api/users",
+			Query:   map[string]string{},
+			Headers: map[string]string{"X-Tenant": "acme"},
+		}, findings[0].Witness)
+	}
+
+	t.Run("not ambiguous", func(t *testing.T) {
+		for _, f := range Lint(docs, &LintOptions{CheckPathSubtree: true, CheckAmbiguity: true, Ignore: []string{RuleShadowedRoute}}) {
+			if f.RuleID == RuleAmbiguousRoutes && (f.RouteID == "users" || f.RouteID == "api_root") {
+				assert.NotContains(t, f.Message, "route api ")
+			}
+		}
+	})
+}
+
+func TestInSubtree(t *testing.T) {
+	assert.True(t, inSubtree("/api/users", "/api"))
+	assert.True(t, inSubtree("/api/users", "/api/"))
+	assert.True(t, inSubtree("/api", "/api"))
+	assert.False(t, inSubtree("/apis", "/api"))
+	assert.False(t, inSubtree("/", "/api"))
+}
@@ -0,0 +1,101 @@
+package matcher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOptionsTB the pattern: the diagnostics are shown with a failure or
+// go test -v only, and the matcher is closed when the test completes
+func TestOptionsTB(t *testing.T) {
+	m, err := New(&Options{
+		RoutesFile:  "testdata/routes.eskip",
+		MockFilters: []string{"customfilter"},
+		Verbosity:   VerbosityInfo,
+		TB:          t,
+	})
+	require.NoError(t, err)
+
+	result := m.Test(&RequestAttributes{Path: "/bar"})
+	require.NoError(t, result.Err())
+	assert.Equal(t, "bar", result.Route().Id)
+}
+
+// recordingTB testing.TB keeping the Logf lines and the cleanups
+type recordingTB struct {
+	testing.TB
+	mu       sync.Mutex
+	lines    []string
+	helpers  int
+	cleanups []func()
+}
+
+func (r *recordingTB) Helper() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.helpers++
+}
+
+func (r *recordingTB) Logf(format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recordingTB) logged() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.lines...)
+}
+
+func TestTBLogf(t *testing.T) {
+	for _, level := range []int{VerbosityInfo, VerbosityDebug} {
+		tb := &recordingTB{TB: t}
+		m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, Verbosity: level, TB: tb})
+		require.NoError(t, err)
+		m.Test(&RequestAttributes{Path: "/bar"})
+
+		lines := tb.logged()
+		assert.Contains(t, lines, "matching route: bar (testdata/routes.eskip:1)")
+		for _, line := range lines {
+			assert.NotRegexp(t, `\n$`, line)
+		}
+		assert.Contains(t, lines, "routing info: route settings applied")
+		if level == VerbosityDebug {
+			assert.Contains(t, strings.Join(lines, "\n"), "near miss: ")
+		} else {
+			assert.NotContains(t, strings.Join(lines, "\n"), "near miss: ")
+		}
+		assert.True(t, tb.helpers >= len(lines), "every line reported by a helper")
+
+		require.Len(t, tb.cleanups, 1)
+		tb.cleanups[0]()
+		assert.True(t, errors.Is(m.Test(&RequestAttributes{Path: "/bar"}).Err(), ErrClosed))
+	}
+}
+
+func TestTBLazy(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	m, err := New(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}, Lazy: true, Verbosity: VerbosityInfo, TB: tb})
+	require.NoError(t, err)
+	require.Len(t, tb.cleanups, 1)
+	assert.Equal(t, "bar", m.Test(&RequestAttributes{Path: "/bar"}).Route().Id)
+	tb.cleanups[0]()
+	assert.True(t, errors.Is(m.Test(&RequestAttributes{Path: "/bar"}).Err(), ErrClosed))
+}
+
+func TestTBWithLog(t *testing.T) {
+	_, err := New(&Options{RoutesFile: "testdata/routes.eskip", Log: &bytes.Buffer{}, TB: t})
+	assert.EqualError(t, err, "Log and TB can't be set together")
+}
@@ -0,0 +1,44 @@
+// Package main a skipper filter plugin for the plugin tests, build it with
+// go build -buildmode=plugin
+package main
+
+import (
+	"github.com/zalando/skipper/filters"
+)
+
+type spec struct {
+	header string
+}
+
+type filter struct {
+	header string
+	tenant string
+}
+
+// InitFilter the header of the tenant is the first argument of the plugin
+func InitFilter(args []string) (filters.Spec, error) {
+	header := "X-Tenant"
+	if len(args) > 0 {
+		header = args[0]
+	}
+	return &spec{header: header}, nil
+}
+
+func (s *spec) Name() string { return "tenantAuth" }
+
+func (s *spec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) != 1 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+	tenant, ok := args[0].(string)
+	if !ok {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+	return &filter{header: s.header, tenant: tenant}, nil
+}
+
+func (f *filter) Request(ctx filters.FilterContext) {
+	ctx.Request().Header.Set(f.header, f.tenant)
+}
+
+func (f *filter) Response(filters.FilterContext) {}
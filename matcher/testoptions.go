@@ -0,0 +1,106 @@
+package matcher
+
+import (
+	"fmt"
+	"time"
+)
+
+// TrailingSlashMode how a test matches the trailing slash of the paths, see
+// WithTrailingSlash
+type TrailingSlashMode int
+
+const (
+	// Exact matches the paths with or without their trailing slash as they
+	// are, like without Options.IgnoreTrailingSlash
+	Exact TrailingSlashMode = iota + 1
+	// Ignored matches the paths whether they have a trailing slash or not,
+	// like with Options.IgnoreTrailingSlash
+	Ignored
+)
+
+// TestOption changes a single test of Matcher.Test, eg.
+// m.Test(attrs, matcher.WithTrailingSlash(matcher.Exact))
+type TestOption func(*testOptions)
+
+// testOptions the changes of the options of a test
+type testOptions struct {
+	trailingSlash TrailingSlashMode
+	host          string
+	at            time.Time
+}
+
+// WithTrailingSlash tests with the trailing slash mode whatever
+// Options.IgnoreTrailingSlash: the matcher builds the routing of the other
+// mode from the same routes source with the first test needing it
+func WithTrailingSlash(mode TrailingSlashMode) TestOption {
+	return func(o *testOptions) { o.trailingSlash = mode }
+}
+
+// WithHost tests the request with the host instead of the one of the
+// request attributes
+func WithHost(host string) TestOption {
+	return func(o *testOptions) { o.host = host }
+}
+
+// At evaluates the interval predicates against the instant, see
+// Matcher.TestAt
+func At(at time.Time) TestOption {
+	return func(o *testOptions) { o.at = at }
+}
+
+// newTestOptions applies the options in their order
+func newTestOptions(opts []TestOption) *testOptions {
+	o := &testOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// testWith tests the attributes with the options, on the routing of the
+// trailing slash mode
+func (f *matcher) testWith(attributes *RequestAttributes, o *testOptions) TestResult {
+	if f.options.TB != nil {
+		f.options.TB.Helper()
+	}
+	if o.host != "" {
+		with := *attributes
+		with.Host = o.host
+		attributes = &with
+	}
+	routed := f
+	if o.trailingSlash != 0 && (o.trailingSlash == Ignored) != f.options.IgnoreTrailingSlash && !f.isClosed() {
+		v, err := f.slashVariant()
+		if err != nil {
+			return errResult(attributes, routesSource(f.options), err)
+		}
+		if v != nil {
+			routed = v
+		}
+	}
+	return f.testOn(routed, attributes, o.at)
+}
+
+// slashVariant the matcher of the routes with the other trailing slash
+// mode, created once, nil when f was closed before
+func (f *matcher) slashVariant() (*matcher, error) {
+	f.variantOnce.Do(func() {
+		if f.isClosed() {
+			return
+		}
+		o := *f.options
+		o.IgnoreTrailingSlash = !o.IgnoreTrailingSlash
+		// the plugins are already in the custom filters and predicates, the
+		// diagnostics, the hooks and the decision log are the ones of f
+		o.PluginDirs, o.Plugins = nil, nil
+		o.Verbose, o.Verbosity, o.Log, o.TB, o.Logger, o.LogWriter = false, 0, nil, nil, nil, nil
+		o.DecisionLog, o.Hooks, o.OnRouteDropped, o.OnLoadProgress = nil, Hooks{}, nil, nil
+		v, err := newMatcher(&o)
+		if err != nil {
+			f.variantErr = fmt.Errorf("the routing with IgnoreTrailingSlash %t can't be created: %v", o.IgnoreTrailingSlash, err)
+			return
+		}
+		f.variant = v
+	})
+	return f.variant, f.variantErr
+}
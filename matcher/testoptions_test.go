@@ -0,0 +1,75 @@
+package matcher
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOptionsRoutes = `bar: Path("/bar") -> <shunt>;
+legacy: Path("/legacy/") -> <shunt>;
+host: Host(/^api[.]example[.]org$/) && Path("/host") -> <shunt>;
+sale: Path("/shop") && Between("2026-11-27T00:00:00Z", "2026-11-30T00:00:00Z") -> "http://sale.svc";`
+
+func TestTestOptionsTrailingSlash(t *testing.T) {
+	var tests int32
+	m, err := New(&Options{Routes: testOptionsRoutes, TB: t, Hooks: Hooks{
+		OnTest: func(*RequestAttributes, TestResult, error, time.Duration) { atomic.AddInt32(&tests, 1) },
+	}})
+	require.NoError(t, err)
+
+	bar, legacy := &RequestAttributes{Path: "/bar/"}, &RequestAttributes{Path: "/legacy"}
+	assert.Equal(t, "", routeID(m.Test(bar)))
+	assert.Equal(t, "bar", routeID(m.Test(bar, WithTrailingSlash(Ignored))))
+	assert.Equal(t, "legacy", routeID(m.Test(legacy, WithTrailingSlash(Ignored))))
+	assert.Equal(t, "", routeID(m.Test(bar, WithTrailingSlash(Exact))))
+	assert.Equal(t, "", routeID(m.Test(legacy)), "the default mode is unchanged")
+	assert.Equal(t, "legacy", routeID(m.Test(&RequestAttributes{Path: "/legacy/"}, WithTrailingSlash(Ignored))))
+	assert.Equal(t, int32(6), atomic.LoadInt32(&tests), "the hooks of the matcher")
+
+	ignoring, err := New(&Options{Routes: testOptionsRoutes, IgnoreTrailingSlash: true, TB: t})
+	require.NoError(t, err)
+	assert.Equal(t, "bar", routeID(ignoring.Test(bar)))
+	assert.Equal(t, "", routeID(ignoring.Test(bar, WithTrailingSlash(Exact))))
+	assert.Equal(t, "bar", routeID(ignoring.Test(bar, WithTrailingSlash(Ignored))))
+
+	lazy, err := New(&Options{Routes: testOptionsRoutes, Lazy: true, TB: t})
+	require.NoError(t, err)
+	assert.Equal(t, "bar", routeID(lazy.Test(bar, WithTrailingSlash(Ignored))))
+	assert.Equal(t, "", routeID(lazy.Test(bar)))
+}
+
+func TestTestOptions(t *testing.T) {
+	m, err := New(&Options{Routes: testOptionsRoutes, TB: t})
+	require.NoError(t, err)
+
+	attrs := &RequestAttributes{Path: "/host"}
+	assert.Equal(t, "", routeID(m.Test(attrs)))
+	res := m.Test(attrs, WithHost("api.example.org"))
+	assert.Equal(t, "host", routeID(res))
+	assert.Equal(t, "api.example.org", res.Attributes().Host)
+	assert.Equal(t, "", attrs.Host, "the attributes must not be modified")
+
+	shop := &RequestAttributes{Path: "/shop"}
+	assert.Equal(t, "", routeID(m.Test(shop, At(time.Date(2026, 11, 26, 0, 0, 0, 0, time.UTC)))))
+	assert.Equal(t, "sale", routeID(m.Test(shop, At(time.Date(2026, 11, 28, 0, 0, 0, 0, time.UTC)))))
+	assert.Equal(t, "sale", routeID(m.Test(&RequestAttributes{Path: "/shop/"}, At(time.Date(2026, 11, 28, 0, 0, 0, 0, time.UTC)), WithTrailingSlash(Ignored))))
+
+	m.Close()
+	res = m.Test(&RequestAttributes{Path: "/bar/"}, WithTrailingSlash(Ignored))
+	assert.True(t, errors.Is(res.Err(), ErrClosed), "got %v", res.Err())
+}
+
+func TestTestWithoutOptionsAllocations(t *testing.T) {
+	m, err := New(&Options{Routes: testOptionsRoutes})
+	require.NoError(t, err)
+	defer m.Close()
+	attrs := &RequestAttributes{Path: "/bar"}
+	want := testing.AllocsPerRun(100, func() { m.TestAt(attrs, time.Time{}) })
+	got := testing.AllocsPerRun(100, func() { m.Test(attrs) })
+	assert.Equal(t, want, got, "no allocation for the options")
+}
@@ -0,0 +1,53 @@
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	f, err := ioutil.TempFile("", "trace-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`items: PathRegexp("^/items/([0-9]+)$") && Host("^(\\w+)\\.example\\.org$") && HeaderRegexp("Accept", "application/(json|xml)") && Method("GET") -> "http://items.svc";
+search: Path("/search") && QueryParam("q") -> "http://search.svc";
+`)
+	f.Close()
+
+	m, err := New(&Options{RoutesFile: f.Name(), Trace: true})
+	require.NoError(t, err)
+	defer m.Close()
+
+	res := m.Test(&RequestAttributes{
+		Method:  "GET",
+		Path:    "/items/42",
+		Host:    "shop.example.org",
+		Headers: map[string]string{"Accept": "application/json"},
+	})
+	require.NoError(t, res.Err())
+	require.NotNil(t, res.Route())
+	assert.Equal(t, []PredicateVerdict{
+		{Predicate: `Host("^(\\w+)\\.example\\.org$")`, Name: "Host", Matched: true, Observed: "shop.example.org", Captures: []string{"shop"}},
+		{Predicate: `PathRegexp("^/items/([0-9]+)$")`, Name: "PathRegexp", Matched: true, Observed: "/items/42", Captures: []string{"42"}},
+		{Predicate: `Method("GET")`, Name: "Method", Matched: true, Observed: "GET"},
+		{Predicate: `HeaderRegexp("Accept", "application/(json|xml)")`, Name: "HeaderRegexp", Matched: true, Observed: "application/json", Captures: []string{"json"}},
+	}, res.Trace())
+	assert.Contains(t, res.PrettyPrint(), `trace: PathRegexp("^/items/([0-9]+)$"): match, observed "/items/42", captures ["42"]`)
+
+	res = m.Test(&RequestAttributes{Method: "GET", Path: "/search", Query: map[string]string{"q": "shoes"}})
+	if assert.Len(t, res.Trace(), 2) {
+		assert.Equal(t, "QueryParam", res.Trace()[1].Name)
+		assert.True(t, res.Trace()[1].Matched)
+	}
+
+	assert.Nil(t, m.Test(&RequestAttributes{Method: "GET", Path: "/nothing"}).Trace(), "no matching route")
+
+	m, err = New(&Options{RoutesFile: f.Name()})
+	require.NoError(t, err)
+	defer m.Close()
+	assert.Nil(t, m.Test(&RequestAttributes{Method: "GET", Path: "/search", Query: map[string]string{"q": "shoes"}}).Trace(), "off by default")
+}
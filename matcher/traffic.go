@@ -0,0 +1,163 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+// trafficModeKind how the Traffic predicates decide
+type trafficModeKind int
+
+const (
+	trafficRandom trafficModeKind = iota
+	trafficAlways
+	trafficNever
+	trafficSeeded
+)
+
+// TrafficMode how the Traffic predicates of the routes decide, see
+// Options.TrafficMode
+type TrafficMode struct {
+	kind trafficModeKind
+	seed int64
+}
+
+var (
+	// TrafficRandom the Traffic predicates match randomly with their
+	// chance, like in skipper (the default)
+	TrafficRandom = TrafficMode{}
+	// TrafficAlways the Traffic predicates always match, eg. the canary
+	// route wins
+	TrafficAlways = TrafficMode{kind: trafficAlways}
+	// TrafficNever the Traffic predicates never match, eg. the stable route
+	// wins
+	TrafficNever = TrafficMode{kind: trafficNever}
+)
+
+// TrafficSeeded the Traffic predicates match randomly with their chance,
+// the random numbers are generated from the seed so the same tests in the
+// same order get the same decisions
+func TrafficSeeded(seed int64) TrafficMode {
+	return TrafficMode{kind: trafficSeeded, seed: seed}
+}
+
+// String returns random, always, never or seeded(SEED)
+func (m TrafficMode) String() string {
+	switch m.kind {
+	case trafficAlways:
+		return "always"
+	case trafficNever:
+		return "never"
+	case trafficSeeded:
+		return fmt.Sprintf("seeded(%d)", m.seed)
+	default:
+		return "random"
+	}
+}
+
+// trafficModePredicates the predicate specs of the routing, the bundled
+// Traffic spec is replaced by one deciding with the traffic mode
+func trafficModePredicates(o *Options) []routing.PredicateSpec {
+	specs := predicateSpecs(o.CustomPredicates, o.DisableBundledPredicates)
+	if o.TrafficMode.kind == trafficRandom {
+		return specs
+	}
+	var random *lockedRand
+	if o.TrafficMode.kind == trafficSeeded {
+		random = &lockedRand{r: rand.New(rand.NewSource(o.TrafficMode.seed))}
+	}
+	for i, spec := range specs[:len(specs)-len(o.CustomPredicates)] {
+		if spec.Name() == trafficPredicate {
+			specs[i] = &trafficSpec{spec: spec, mode: o.TrafficMode, random: random}
+		}
+	}
+	return specs
+}
+
+// lockedRand random numbers shared by the Traffic predicates of a matcher
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (l *lockedRand) float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Float64()
+}
+
+// trafficSpec the Traffic spec of a traffic mode, the arguments are checked
+// by the skipper spec
+type trafficSpec struct {
+	spec   routing.PredicateSpec
+	mode   TrafficMode
+	random *lockedRand
+}
+
+func (s *trafficSpec) Name() string { return s.spec.Name() }
+
+func (s *trafficSpec) Create(args []interface{}) (routing.Predicate, error) {
+	if _, err := s.spec.Create(args); err != nil {
+		return nil, err
+	}
+	p := &trafficModePredicate{
+		spec:      s,
+		predicate: predicateString(&eskip.Predicate{Name: trafficPredicate, Args: args}),
+	}
+	p.chance, _ = args[0].(float64)
+	if len(args) == 3 {
+		p.cookie, _ = args[1].(string)
+		p.group, _ = args[2].(string)
+	}
+	return p, nil
+}
+
+// trafficModePredicate a Traffic predicate deciding with the traffic mode,
+// the decisions are recorded in the request context, see withForcedTraffic
+type trafficModePredicate struct {
+	spec      *trafficSpec
+	predicate string
+	chance    float64
+	// cookie and group of a sticky Traffic, only checked by TrafficSeeded
+	cookie string
+	group  string
+}
+
+func (p *trafficModePredicate) Match(req *http.Request) bool {
+	var matches bool
+	switch p.spec.mode.kind {
+	case trafficAlways:
+		matches = true
+	case trafficNever:
+		matches = false
+	default:
+		if c, err := req.Cookie(p.cookie); p.cookie != "" && err == nil {
+			matches = c.Value == p.group
+		} else {
+			matches = p.spec.random.float64() < p.chance
+		}
+	}
+	if decisions, ok := req.Context().Value(forcedTrafficKey{}).(*[]string); ok {
+		result := "no match"
+		if matches {
+			result = "match"
+		}
+		*decisions = append(*decisions, fmt.Sprintf("%s: %s (%s)", p.predicate, result, p.spec.mode))
+	}
+	return matches
+}
+
+// forcedTrafficKey context key of the decisions of the Traffic predicates
+type forcedTrafficKey struct{}
+
+// withForcedTraffic returns the request recording the decisions of the
+// Traffic predicates in decisions
+func withForcedTraffic(req *http.Request, decisions *[]string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), forcedTrafficKey{}, decisions))
+}
@@ -0,0 +1,85 @@
+package matcher
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const trafficRoutes = `canary: Path("/orders") && Traffic(0.1) -> "http://canary.svc";
+stable: Path("/orders") -> "http://stable.svc";
+`
+
+func newTrafficMatcher(t *testing.T, mode TrafficMode) Matcher {
+	file := writeRoutes(t, trafficRoutes)
+	defer os.Remove(file)
+
+	m, err := New(&Options{RoutesFile: file, TrafficMode: mode})
+	require.NoError(t, err)
+	return m
+}
+
+func TestTrafficMode(t *testing.T) {
+	attrs := &RequestAttributes{Method: "GET", Path: "/orders"}
+
+	t.Run("canary wins", func(t *testing.T) {
+		m := newTrafficMatcher(t, TrafficAlways)
+		defer m.Close()
+		for i := 0; i < 20; i++ {
+			res := m.Test(attrs)
+			if assert.NotNil(t, res.Route()) {
+				assert.Equal(t, "canary", res.Route().Id)
+			}
+			assert.Equal(t, []string{"Traffic(0.1): match (always)"}, res.ForcedTraffic())
+		}
+	})
+
+	t.Run("stable wins", func(t *testing.T) {
+		m := newTrafficMatcher(t, TrafficNever)
+		defer m.Close()
+		for i := 0; i < 20; i++ {
+			res := m.Test(attrs)
+			if assert.NotNil(t, res.Route()) {
+				assert.Equal(t, "stable", res.Route().Id)
+			}
+			assert.Equal(t, []string{"Traffic(0.1): no match (never)"}, res.ForcedTraffic())
+		}
+		res := m.Test(attrs)
+		assert.Contains(t, res.PrettyPrintLines(), "forced traffic: Traffic(0.1): no match (never)")
+		b, err := json.Marshal(res)
+		require.NoError(t, err)
+		assert.Contains(t, string(b), `"forcedTraffic":["Traffic(0.1): no match (never)"]`)
+	})
+
+	t.Run("seeded", func(t *testing.T) {
+		run := func() []string {
+			m := newTrafficMatcher(t, TrafficSeeded(42))
+			defer m.Close()
+			var ids []string
+			for i := 0; i < 50; i++ {
+				ids = append(ids, m.Test(attrs).Route().Id)
+			}
+			return ids
+		}
+		first := run()
+		assert.Equal(t, first, run(), "the same seed gives the same decisions")
+		assert.Contains(t, first, "canary")
+		assert.Contains(t, first, "stable")
+	})
+
+	t.Run("random", func(t *testing.T) {
+		m := newTrafficMatcher(t, TrafficRandom)
+		defer m.Close()
+		assert.Nil(t, m.Test(attrs).ForcedTraffic())
+	})
+}
+
+func TestTrafficModeString(t *testing.T) {
+	assert.Equal(t, "random", TrafficRandom.String())
+	assert.Equal(t, "always", TrafficAlways.String())
+	assert.Equal(t, "never", TrafficNever.String())
+	assert.Equal(t, "seeded(7)", TrafficSeeded(7).String())
+}
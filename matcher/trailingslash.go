@@ -0,0 +1,115 @@
+package matcher
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// SlashCollision two routes whose predicates differ only by the trailing
+// slash of their paths, eg. Path("/a") and Path("/a/")
+type SlashCollision struct {
+	// Route id of the first route in the document
+	Route string `json:"route"`
+	// Other id of the other route
+	Other string `json:"other"`
+	// Predicates the predicates of both routes without trailing slashes
+	Predicates string `json:"predicates"`
+}
+
+// TrailingSlashImpact tests every request against the routes of the
+// options without and with IgnoreTrailingSlash, whatever the options set,
+// and reports the requests routed differently together with the routes
+// colliding when the trailing slashes are ignored, eg. before enabling the
+// option in production. Without requests one is generated for each route,
+// see SeedRequests, and another one with or without the trailing slash of
+// its path. The Traffic predicates always match.
+func TrailingSlashImpact(o *Options, requests []*RequestAttributes) (*ImpactReport, error) {
+	off, on := *o, *o
+	off.IgnoreTrailingSlash, on.IgnoreTrailingSlash = false, true
+	m, err := newComparedMatcher(&off)
+	if err != nil {
+		return nil, err
+	}
+	routes := m.Routes()
+	m.Close()
+	if requests == nil {
+		requests = slashVariants(SeedRequests(routes))
+	}
+	diff, err := Diff(&off, &on, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImpactReport{
+		Change:     "IgnoreTrailingSlash",
+		Requests:   diff.Requests,
+		Unchanged:  diff.Unchanged,
+		Changes:    diff.Changes,
+		Routes:     changedRoutes(diff.Changes),
+		Collisions: slashCollisions(routes),
+	}, nil
+}
+
+// slashVariants the requests followed by their copies with or without the
+// trailing slash, the root path has none
+func slashVariants(requests []*RequestAttributes) []*RequestAttributes {
+	variants := make([]*RequestAttributes, 0, 2*len(requests))
+	for _, req := range requests {
+		variants = append(variants, req)
+		if req.Path == "" || req.Path == "/" {
+			continue
+		}
+		v := *req
+		if strings.HasSuffix(v.Path, "/") {
+			v.Path = strings.TrimSuffix(v.Path, "/")
+		} else {
+			v.Path += "/"
+		}
+		variants = append(variants, &v)
+	}
+	return variants
+}
+
+// slashCollisions the pairs of routes with the same predicates once the
+// trailing slashes of the Path and PathSubtree predicates are removed, and
+// different predicates otherwise
+func slashCollisions(routes []*eskip.Route) []SlashCollision {
+	byKey := make(map[string][]*eskip.Route)
+	var keys []string
+	for _, r := range routes {
+		key := trimmedPredicatesKey(r)
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], r)
+	}
+
+	collisions := []SlashCollision{}
+	for _, key := range keys {
+		group := byKey[key]
+		for i, r := range group {
+			for _, other := range group[i+1:] {
+				if predicatesKey(r) != predicatesKey(other) {
+					collisions = append(collisions, SlashCollision{Route: r.Id, Other: other.Id, Predicates: key})
+				}
+			}
+		}
+	}
+	sort.SliceStable(collisions, func(i, j int) bool { return collisions[i].Route < collisions[j].Route })
+	return collisions
+}
+
+// trimmedPredicatesKey the predicatesKey of the route without the trailing
+// slashes of its paths
+func trimmedPredicatesKey(r *eskip.Route) string {
+	trimmed := &eskip.Route{}
+	for _, p := range Predicates(r) {
+		if path, ok := stringArg(p, 0); ok && (p.Name == pathPredicate || p.Name == pathSubtreePredicate) && len(path) > 1 {
+			p = &eskip.Predicate{Name: p.Name, Args: []interface{}{strings.TrimSuffix(path, "/")}}
+		}
+		trimmed.Predicates = append(trimmed.Predicates, p)
+	}
+	return predicatesKey(trimmed)
+}
@@ -0,0 +1,64 @@
+package matcher
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrailingSlashImpact(t *testing.T) {
+	file := writeRoutes(t, `orders: Path("/orders") -> "https://orders.example.org";
+orders_slash: Path("/orders/") -> "https://orders-v2.example.org";
+search: Path("/search") -> "https://search.example.org";
+health: Path("/health") -> <shunt>;`)
+	defer os.Remove(file)
+
+	report, err := TrailingSlashImpact(&Options{RoutesFile: file}, []*RequestAttributes{
+		{Method: "GET", Path: "/search"},
+		{Method: "GET", Path: "/search/"},
+		// not /orders, either colliding route matches it with the option
+		{Method: "GET", Path: "/health"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Requests)
+	assert.Equal(t, 2, report.Unchanged)
+	require.Len(t, report.Changes, 1)
+	c := report.Changes[0]
+	assert.Equal(t, "/search/", c.Request.Path)
+	assert.Equal(t, ChangeDestination, c.Class)
+	assert.False(t, c.Old.Matched)
+	assert.Equal(t, "search", c.New.RouteID)
+	assert.Equal(t, []string{"search"}, report.Routes)
+	assert.Equal(t, []SlashCollision{{Route: "orders", Other: "orders_slash", Predicates: `Path("/orders")`}}, report.Collisions)
+
+	var b bytes.Buffer
+	require.NoError(t, report.WriteText(&b))
+	assert.Contains(t, b.String(), "1 of 3 request(s) change with IgnoreTrailingSlash, route(s) search\n")
+	assert.Contains(t, b.String(), "  orders and orders_slash: Path(\"/orders\")\n")
+}
+
+func TestTrailingSlashImpactNoChange(t *testing.T) {
+	file := writeRoutes(t, `root: Path("/") -> <shunt>;
+orders: PathSubtree("/orders") -> "https://orders.example.org";`)
+	defer os.Remove(file)
+
+	// the generated requests, with and without trailing slash, route the same
+	report, err := TrailingSlashImpact(&Options{RoutesFile: file, IgnoreTrailingSlash: true}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, report.Requests, report.Unchanged)
+	assert.Empty(t, report.Changes)
+	assert.Empty(t, report.Routes)
+	assert.Empty(t, report.Collisions)
+
+	var b bytes.Buffer
+	require.NoError(t, report.WriteJSON(&b))
+	assert.Contains(t, b.String(), `"changes": []`)
+}
+
+func TestTrailingSlashImpactLoadError(t *testing.T) {
+	_, err := TrailingSlashImpact(&Options{RoutesFile: "testdata/missing.eskip"}, nil)
+	assert.Error(t, err)
+}
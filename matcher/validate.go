@@ -0,0 +1,249 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// eskipParseError message of the eskip parser errors, with the failing
+// token and the byte offset where it ends
+var eskipParseError = regexp.MustCompile(`^parse failed after token .*, position (\d+): (.*)$`)
+
+// ParseError a syntax error in a routes document, positioned at the token
+// the eskip parser failed on
+type ParseError struct {
+	// File name of the document
+	File string
+	// Line 1-based line of the failing token, 0 if unknown
+	Line int
+	// Column 1-based column of the failing token, in characters
+	Column int
+	// Token source text of the failing token
+	Token string
+	// Reason what's wrong, eg. syntax error
+	Reason string
+	// Snippet the lines around the failing token, numbered, with carets
+	// under it, empty if the position is unknown
+	Snippet string
+	// Err the error of the eskip parser
+	Err error
+}
+
+// Error returns a compiler like representation of the error,
+// eg. routes.eskip:3:14: syntax error near "->"
+func (e *ParseError) Error() string {
+	loc := Location{File: e.File, Line: e.Line}.String()
+	if e.Line > 0 && e.Column > 0 {
+		loc += ":" + strconv.Itoa(e.Column)
+	}
+	if loc == "" {
+		return e.message()
+	}
+	return loc + ": " + e.message()
+}
+
+// Is matches ErrParse
+func (e *ParseError) Is(target error) bool { return target == ErrParse }
+
+// Unwrap returns the error of the eskip parser
+func (e *ParseError) Unwrap() error { return e.Err }
+
+func (e *ParseError) message() string {
+	if e.Token == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s near %q", e.Reason, e.Token)
+}
+
+func (e *ParseError) finding() Finding {
+	return Finding{
+		RuleID:   RuleParse,
+		Severity: SeverityError,
+		File:     e.File,
+		Line:     e.Line,
+		Column:   e.Column,
+		Message:  e.message(),
+	}
+}
+
+// newParseError positions an eskip parse error of the document, the
+// reason is the whole error when it doesn't tell where the parser failed
+func newParseError(doc Document, err error) *ParseError {
+	e := &ParseError{File: doc.File, Reason: err.Error(), Err: err}
+	m := eskipParseError.FindStringSubmatch(err.Error())
+	if m == nil {
+		return e
+	}
+	end, _ := strconv.Atoi(m[1])
+	if end > len(doc.Content) {
+		return e
+	}
+	start := tokenStart(doc.Content, end)
+	e.Reason = m[2]
+	e.Token = string(doc.Content[start:end])
+	e.Line, e.Column = 1, 1
+	for _, r := range string(doc.Content[:start]) {
+		if r == '\n' {
+			e.Line++
+			e.Column = 1
+		} else {
+			e.Column++
+		}
+	}
+	e.Snippet = parseSnippet(doc.Content, e.Line, e.Column, e.Token)
+	return e
+}
+
+// snippetLines lines shown before and after the failing one in a
+// ParseError snippet
+const snippetLines = 2
+
+// parseSnippet the lines around the line of the token, eg.
+//
+//	3 | c: Path("/c") -> <shunt>;
+//	4 | d: Path("/d") -> -> <shunt>;
+//	  |                  ^^
+func parseSnippet(doc []byte, line, column int, token string) string {
+	lines := strings.Split(strings.TrimSuffix(string(doc), "\n"), "\n")
+	if line > len(lines) {
+		return ""
+	}
+	first, last := line-snippetLines, line+snippetLines
+	if first < 1 {
+		first = 1
+	}
+	if last > len(lines) {
+		last = len(lines)
+	}
+	width := len(strconv.Itoa(last))
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		text := strings.TrimSuffix(lines[n-1], "\r")
+		fmt.Fprintf(&b, "%s\n", strings.TrimRight(fmt.Sprintf("%*d | %s", width, n, text), " "))
+		if n != line {
+			continue
+		}
+		// the tabs before the token are kept to align the carets
+		var indent strings.Builder
+		for i, r := range []rune(text) {
+			if i >= column-1 {
+				break
+			}
+			if r == '\t' {
+				indent.WriteRune(r)
+			} else {
+				indent.WriteByte(' ')
+			}
+		}
+		carets := utf8.RuneCountInString(strings.SplitN(token, "\n", 2)[0])
+		if carets == 0 {
+			carets = 1
+		}
+		fmt.Fprintf(&b, "%*s | %s%s\n", width, "", indent.String(), strings.Repeat("^", carets))
+	}
+	return b.String()
+}
+
+// tokenStart returns where the token ending at the end offset starts,
+// strings, regexps, special backends and comments are read as a whole like
+// the eskip lexer does
+func tokenStart(doc []byte, end int) int {
+	start := 0
+	for i := 0; i < end; {
+		r, size := utf8.DecodeRune(doc[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+		case r == '/' && i+1 < len(doc) && doc[i+1] == '/':
+			for i < end && doc[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
+		start = i
+		switch {
+		case r == '"' || r == '/' || r == '`':
+			i++
+			for i < len(doc) && rune(doc[i]) != r {
+				if doc[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+		case r == '<':
+			// shunt, loopback and load balanced backends
+			for i < len(doc) && doc[i] != '>' {
+				i++
+			}
+			i++
+		case i+1 < len(doc) && (string(doc[i:i+2]) == "->" || string(doc[i:i+2]) == "&&"):
+			i += 2
+		case isWordRune(r):
+			for i += size; i < len(doc); {
+				r, size := utf8.DecodeRune(doc[i:])
+				if !isWordRune(r) || r == '-' && i+1 < len(doc) && doc[i+1] == '>' {
+					break
+				}
+				i += size
+			}
+		default:
+			i += size
+		}
+	}
+	return start
+}
+
+// isWordRune true for the runes of identifiers and numbers
+func isWordRune(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Validate reads and parses the routes file of the options like New does,
+// without creating a matcher, and checks the routes with the lint rules
+// using the filters and predicates of the options, see Options.Rules. A
+// syntax error is a RuleParse finding positioned at the failing token. The
+// error is returned only if the file can't be read or the options are invalid.
+func Validate(o *Options) ([]Finding, error) {
+	o, err := withPlugins(o)
+	if err != nil {
+		return nil, err
+	}
+	c, err := openFile(o.RoutesFile, o.OnLoadProgress)
+	if e, ok := err.(*ParseError); ok {
+		if o.Rules.ignored(RuleParse) {
+			return []Finding{}, nil
+		}
+		return []Finding{o.Rules.apply(e.finding())}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	registry, err := filterRegistry(o)
+	if err != nil {
+		return nil, err
+	}
+	routes := pairRoutes(c.definitions, c.routes)
+	findings := append([]Finding{}, nameCollisions(o)...)
+	return append(findings, lintRoutes(routes, registry, mapPredicateSpecs(predicateSpecs(o.CustomPredicates, o.DisableBundledPredicates)), o.Rules)...), nil
+}
+
+// ValidateDocuments parses the documents and returns a RuleParse finding for
+// each one with a syntax error, only the first error of a document is reported
+func ValidateDocuments(docs []Document) []Finding {
+	findings := []Finding{}
+	for _, doc := range docs {
+		if _, err := eskip.Parse(string(doc.Content)); err != nil {
+			findings = append(findings, newParseError(doc, err).finding())
+		}
+	}
+	return findings
+}
@@ -0,0 +1,245 @@
+package matcher
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validRoutes a multi-route document without errors
+const validRoutes = `a: Path("/a") -> <shunt>;
+b: Path("/b") -> <shunt>;
+c: Path("/c") -> <shunt>;
+`
+
+func TestValidateDocuments(t *testing.T) {
+	tests := []struct {
+		name   string
+		docs   []Document
+		file   string
+		line   int
+		column int
+		token  string
+	}{
+		{
+			name:   "start",
+			docs:   []Document{{File: "start.eskip", Content: []byte("a Path(\"/a\") -> <shunt>;\nb: Path(\"/b\") -> <shunt>;\n")}},
+			file:   "start.eskip",
+			line:   1,
+			column: 3,
+			token:  "Path",
+		},
+		{
+			name:   "middle",
+			docs:   []Document{{File: "middle.eskip", Content: []byte("a: Path(\"/a\") -> <shunt>;\nb: Path(\"/b\" -> <shunt>;\nc: Path(\"/c\") -> <shunt>;\n")}},
+			file:   "middle.eskip",
+			line:   2,
+			column: 14,
+			token:  "->",
+		},
+		{
+			name:   "end",
+			docs:   []Document{{File: "end.eskip", Content: []byte("a: Path(\"/a\") -> <shunt>;\n// the last one\nb: Path(\"/b\") -> ")}},
+			file:   "end.eskip",
+			line:   3,
+			column: 15,
+			token:  "->",
+		},
+		{
+			name:   "non ascii",
+			docs:   []Document{{File: "utf8.eskip", Content: []byte("a: Path(\"/ä\") && && Method(\"GET\") -> <shunt>;")}},
+			file:   "utf8.eskip",
+			line:   1,
+			column: 18,
+			token:  "&&",
+		},
+		{
+			name: "second of several documents",
+			docs: []Document{
+				{File: "first.eskip", Content: []byte(validRoutes)},
+				{File: "second.eskip", Content: []byte(validRoutes + "d: Path(\"/d\") -> setPath(\"/e\" <shunt>;\n")},
+				{File: "third.eskip", Content: []byte(validRoutes)},
+			},
+			file:   "second.eskip",
+			line:   4,
+			column: 31,
+			token:  "<shunt>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := ValidateDocuments(tt.docs)
+			require.Len(t, findings, 1)
+			f := findings[0]
+			assert.Equal(t, RuleParse, f.RuleID)
+			assert.Equal(t, SeverityError, f.Severity)
+			assert.Equal(t, tt.file, f.File)
+			assert.Equal(t, tt.line, f.Line)
+			assert.Equal(t, tt.column, f.Column)
+			assert.Contains(t, f.Message, tt.token)
+		})
+	}
+}
+
+func TestValidateDocumentsNoErrors(t *testing.T) {
+	findings := ValidateDocuments([]Document{{File: "a.eskip", Content: []byte(validRoutes)}, {File: "b.eskip", Content: []byte(trickyRoutes)}})
+	assert.NotNil(t, findings)
+	assert.Empty(t, findings)
+}
+
+func TestValidate(t *testing.T) {
+	findings, err := Validate(&Options{RoutesFile: "testdata/routes.eskip", MockFilters: []string{"customfilter"}})
+	require.NoError(t, err)
+	assert.NotNil(t, findings)
+	assert.Empty(t, findings)
+
+	_, err = Validate(&Options{RoutesFile: "testdata/missing.eskip"})
+	assert.True(t, os.IsNotExist(err))
+
+	path := writeRoutes(t, validRoutes+"d: Path(\"/d\") -> -> <shunt>;\n")
+	defer os.Remove(path)
+	findings, err = Validate(&Options{RoutesFile: path})
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, Finding{RuleID: RuleParse, Severity: SeverityError, File: path, Line: 4, Column: 18, Message: `syntax error near "->"`}, findings[0])
+}
+
+func TestNewParseError(t *testing.T) {
+	path := writeRoutes(t, validRoutes+"d: Path(\"/d\") -> -> <shunt>;\n")
+	defer os.Remove(path)
+	_, err := New(&Options{RoutesFile: path})
+	require.IsType(t, &ParseError{}, err)
+	assert.Equal(t, path+`:4:18: syntax error near "->"`, err.Error())
+	assert.Equal(t, "->", err.(*ParseError).Token)
+}
+
+func TestParseErrorSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		line    int
+		snippet string
+	}{
+		{
+			name: "first line",
+			doc:  "a: Path(\"/a\") -> -> <shunt>;\nb: Path(\"/b\") -> <shunt>;\nc: Path(\"/c\") -> <shunt>;\nd: Path(\"/d\") -> <shunt>;\n",
+			line: 1,
+			snippet: `1 | a: Path("/a") -> -> <shunt>;
+  |                  ^^
+2 | b: Path("/b") -> <shunt>;
+3 | c: Path("/c") -> <shunt>;
+`,
+		},
+		{
+			name: "mid-file",
+			doc:  "a: Path(\"/a\") -> <shunt>;\nb: Path(\"/b\") -> <shunt>;\nc: Path(\"/c\")\n\t&& Method(\"GET\" -> <shunt>;\nd: Path(\"/d\") -> <shunt>;\ne: Path(\"/e\") -> <shunt>;\nf: Path(\"/f\") -> <shunt>;\n",
+			line: 4,
+			snippet: "2 | b: Path(\"/b\") -> <shunt>;\n" +
+				"3 | c: Path(\"/c\")\n" +
+				"4 | \t&& Method(\"GET\" -> <shunt>;\n" +
+				"  | \t                ^^\n" +
+				"5 | d: Path(\"/d\") -> <shunt>;\n" +
+				"6 | e: Path(\"/e\") -> <shunt>;\n",
+		},
+		{
+			name: "last line",
+			doc:  "a: Path(\"/a\") -> <shunt>;\n\n\n\n\n\n\n\n\nj: Path(\"/j\") -> ;",
+			line: 10,
+			snippet: ` 8 |
+ 9 |
+10 | j: Path("/j") -> ;
+   |                  ^
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeRoutes(t, tt.doc)
+			defer os.Remove(path)
+			_, err := New(&Options{RoutesFile: path})
+			var e *ParseError
+			require.True(t, errors.As(err, &e), "%v", err)
+			assert.Equal(t, path, e.File)
+			assert.Equal(t, tt.line, e.Line)
+			assert.Equal(t, tt.snippet, e.Snippet)
+			require.Error(t, errors.Unwrap(err))
+			assert.Contains(t, errors.Unwrap(err).Error(), "parse failed after token")
+		})
+	}
+
+	e := newParseError(Document{File: "routes.eskip"}, errors.New("unexpected end of file"))
+	assert.Empty(t, e.Snippet, "no snippet without a position")
+}
+
+func TestValidateDocumentsParseErrorFile(t *testing.T) {
+	findings := ValidateDocuments([]Document{
+		{File: "a.eskip", Content: []byte("a: Path(\"/a\") -> <shunt>;\n")},
+		{File: "b.eskip", Content: []byte("b: Path(\"/b\") -> <shunt>;\nc: Path(\"/c\") -> -> <shunt>;\n")},
+	})
+	require.Len(t, findings, 1)
+	assert.Equal(t, "b.eskip", findings[0].File)
+	assert.Equal(t, 2, findings[0].Line)
+}
+
+func TestValidateRules(t *testing.T) {
+	path := writeRoutes(t, "healthcheck: Path(\"/health\") -> <shunt>;\n\nhealthcheck: Path(\"/healthz\") -> <shunt>;\n")
+	defer os.Remove(path)
+
+	findings, err := Validate(&Options{RoutesFile: path})
+	require.NoError(t, err)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, Finding{
+			RuleID:   RuleDuplicateID,
+			Severity: SeverityError,
+			RouteID:  "healthcheck",
+			File:     path,
+			Line:     1,
+			Message:  "route id is defined 2 times (" + path + ":1, " + path + ":3), the definition at " + path + ":3 wins",
+		}, findings[0])
+	}
+
+	findings, err = Validate(&Options{RoutesFile: path, Rules: RuleOptions{Severity: map[string]Severity{RuleDuplicateID: SeverityWarning}}})
+	require.NoError(t, err)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, SeverityWarning, findings[0].Severity)
+	}
+
+	findings, err = Validate(&Options{RoutesFile: path, Rules: RuleOptions{Ignore: []string{RuleDuplicateID}}})
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestValidateSuppress(t *testing.T) {
+	path := writeRoutes(t, `a: Path("/a") -> requestHeader("X-A", "1") -> <shunt>; // eskip-match:ignore deprecated
+
+// eskip-match:ignore deprecated
+b: Path("/b") -> requestHeader("X-B", "1") -> <shunt>;
+c: Path("/c") -> requestHeader("X-C", "1") -> <shunt>; // eskip-match:ignore no-such-rule
+`)
+	defer os.Remove(path)
+
+	findings, err := Validate(&Options{RoutesFile: path})
+	require.NoError(t, err)
+	if assert.Len(t, findings, 2) {
+		assert.Equal(t, Finding{
+			RuleID:   RuleUnknownRule,
+			Severity: SeverityWarning,
+			RouteID:  "c",
+			File:     path,
+			Line:     5,
+			Message:  "unknown rule no-such-rule in eskip-match:ignore",
+		}, findings[0])
+		assert.Equal(t, Finding{
+			RuleID:     RuleDeprecated,
+			Severity:   SeverityWarning,
+			RouteID:    "c",
+			File:       path,
+			Line:       5,
+			Message:    "filter requestHeader is deprecated, use setRequestHeader or appendRequestHeader",
+			Suggestion: "use setRequestHeader or appendRequestHeader",
+		}, findings[1])
+	}
+}
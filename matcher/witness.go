@@ -0,0 +1,153 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/zalando/skipper/eskip"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// witnessAttempts generated requests tried for each witness requested, the
+// routes without regexps generate the same request with any seed
+const witnessAttempts = 4
+
+// RouteWitnesses the requests demonstrating the change of a route, see
+// DiffReport.Witnesses
+type RouteWitnesses struct {
+	// RouteID id of the route
+	RouteID string `json:"routeId"`
+	// Change RouteAdded, RouteRemoved or RouteChanged
+	Change string `json:"change"`
+	// FilterOnly true when only the filters of the route changed, it matches
+	// the same requests and has no witnesses
+	FilterOnly bool `json:"filterOnly,omitempty"`
+	// Witnesses the requests generated for the route, see GenerateRequest
+	Witnesses []Witness `json:"witnesses"`
+}
+
+// Witness a request generated for a changed route and its results
+type Witness struct {
+	// Request the normalized request
+	Request *RequestAttributes `json:"request"`
+	// Old the result with the old routes
+	Old *ResultData `json:"old"`
+	// New the result with the new routes
+	New *ResultData `json:"new"`
+}
+
+// Witnesses generates up to n requests for each route added, removed or
+// changed between the routes of the report, from both versions of the
+// changed ones, tests them against both routes and stores them in
+// RouteWitnesses, replacing the previous ones. The routes whose filters only
+// changed are labeled FilterOnly instead. The report must be the one of
+// Diff.
+func (r *DiffReport) Witnesses(n int) error {
+	if r.oldOpts == nil || r.newOpts == nil {
+		return errors.New("no routes to witness, the report isn't the one of Diff")
+	}
+	old, err := newComparedMatcher(r.oldOpts)
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+	new, err := newComparedMatcher(r.newOpts)
+	if err != nil {
+		return err
+	}
+	defer new.Close()
+
+	oldRoutes, newRoutes := routesByID(old.Routes()), routesByID(new.Routes())
+	r.RouteWitnesses = []RouteWitnesses{}
+	for _, c := range DiffRoutes(old.Routes(), new.Routes()) {
+		rw := RouteWitnesses{RouteID: c.ID, Change: c.Change, Witnesses: []Witness{}}
+		var versions []*eskip.Route
+		switch c.Change {
+		case RouteAdded:
+			versions = []*eskip.Route{newRoutes[c.ID]}
+		case RouteRemoved:
+			versions = []*eskip.Route{oldRoutes[c.ID]}
+		default:
+			rw.FilterOnly = filterOnly(routeFieldChanges(oldRoutes[c.ID], newRoutes[c.ID]))
+			versions = []*eskip.Route{newRoutes[c.ID], oldRoutes[c.ID]}
+		}
+		if !rw.FilterOnly {
+			for _, req := range witnessRequests(versions, n) {
+				oldTest := old.Test(req)
+				if oldTest.Err() != nil {
+					continue
+				}
+				rw.Witnesses = append(rw.Witnesses, Witness{Request: oldTest.Attributes(), Old: NewResultData(oldTest), New: NewResultData(new.Test(req))})
+			}
+		}
+		r.RouteWitnesses = append(r.RouteWitnesses, rw)
+	}
+	return nil
+}
+
+// filterOnly true if all the changes are changes of the filters
+func filterOnly(changes []FieldChange) bool {
+	for _, c := range changes {
+		if c.Field != FieldFilter {
+			return false
+		}
+	}
+	return len(changes) > 0
+}
+
+// witnessRequests up to n distinct requests generated for the routes, the
+// ones of the first route first
+func witnessRequests(routes []*eskip.Route, n int) []*RequestAttributes {
+	seen := make(map[string]bool)
+	var requests []*RequestAttributes
+	for _, route := range routes {
+		for seed := int64(0); seed < int64(n*witnessAttempts) && len(requests) < n; seed++ {
+			req, _ := GenerateRequestWith(route, GenerateOptions{Seed: seed})
+			key := RawRequest(req, nil)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+// writeWitnessesMarkdown writes the witnesses of each route as the cases of
+// a suite fixture expecting the new results
+func writeWitnessesMarkdown(w io.Writer, witnesses []RouteWitnesses) error {
+	for _, rw := range witnesses {
+		fmt.Fprintf(w, "\n#### `%s` %s\n\n", rw.RouteID, rw.Change)
+		switch {
+		case rw.FilterOnly:
+			fmt.Fprintln(w, "Only the filters changed, the route matches the same requests.")
+			continue
+		case len(rw.Witnesses) == 0:
+			fmt.Fprintln(w, "No request could be generated.")
+			continue
+		}
+		cases := make([]fixtureCase, len(rw.Witnesses))
+		for i, wt := range rw.Witnesses {
+			cases[i] = fixtureCase{Name: fmt.Sprintf("%s witness %d", rw.RouteID, i+1), Request: wt.Request}
+			if wt.New.Matched {
+				cases[i].Expect.Route = wt.New.RouteID
+			} else {
+				cases[i].Expect.NoMatch = true
+			}
+		}
+		y, err := yaml.Marshal(cases)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "| Request | Old | New |")
+		fmt.Fprintln(w, "| --- | --- | --- |")
+		for _, wt := range rw.Witnesses {
+			fmt.Fprintf(w, "| %s | %s | %s |\n", markdownCode(requestLine(wt.Request)),
+				markdownCode(diffDestination(wt.Old)), markdownCode(diffDestination(wt.New)))
+		}
+		fmt.Fprintf(w, "\n```yaml\n%s```\n", string(y))
+	}
+	return nil
+}
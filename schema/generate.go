@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// GenerateOptions options of Generate
+type GenerateOptions struct {
+	// Title title of the schema
+	Title string
+	// Tag the struct tag naming the properties, json when empty, eg. yaml
+	// for the fixture files
+	Tag string
+	// Closed the objects have no other properties, like the strict decoding
+	// of the fixture files, and the values are never null. Otherwise the
+	// slices, maps and pointers without omitempty can be null, like the
+	// encoding/json output.
+	Closed bool
+}
+
+// Generate the schema of the type of v from its fields, the interface{}
+// values allow any value. It panics on the types without a json
+// representation, eg. channels.
+func Generate(v interface{}, o GenerateOptions) *Schema {
+	if o.Tag == "" {
+		o.Tag = "json"
+	}
+	s := generate(reflect.TypeOf(v), &o, false)
+	s.Schema = Draft
+	s.Title = o.Title
+	return s
+}
+
+// Document the indented json document of the schema
+func Document(s *Schema) []byte {
+	// the schemas always marshal
+	b, _ := json.MarshalIndent(s, "", "  ")
+	return append(b, '\n')
+}
+
+// canBeNil the values of the type are written as null when nil
+func canBeNil(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// generate the schema of the type, nullable when its nil values are
+// written as null
+func generate(t reflect.Type, o *GenerateOptions, nullable bool) *Schema {
+	types := func(name string) Types {
+		if nullable && !o.Closed {
+			return Types{name, "null"}
+		}
+		return Types{name}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return generate(t.Elem(), o, nullable)
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: types("array"), Items: generate(t.Elem(), o, false)}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			panic(fmt.Sprintf("schema: map keys of type %s", t.Key()))
+		}
+		return &Schema{Type: types("object"), AdditionalProperties: generate(t.Elem(), o, false)}
+	case reflect.Struct:
+		s := &Schema{Type: types("object"), Properties: make(map[string]*Schema), Closed: o.Closed}
+		addFields(s, t, o)
+		return s
+	default:
+		panic(fmt.Sprintf("schema: no json representation of %s", t))
+	}
+}
+
+// addFields adds the properties of the exported fields of the struct, the
+// ones of the embedded structs too
+func addFields(s *Schema, t reflect.Type, o *GenerateOptions) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get(o.Tag)
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, options = tag[:i], tag[i+1:]
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			addFields(s, ft, o)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = generate(f.Type, o, !strings.Contains(options, "omitempty") && canBeNil(f.Type))
+	}
+}
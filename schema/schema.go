@@ -0,0 +1,216 @@
+// Package schema publishes the JSON Schema documents of the fixture files of
+// the suite package and of the json results, for the tools not written in
+// Go, and validates the documents against them.
+//
+// The schemas are generated from the structs, go generate writes them again
+// after a change:
+//
+//	fixture.schema.json       suite.Suite, a fixture file
+//	test-result.schema.json   matcher.ResultData, the result of a test
+//	suite-result.schema.json  suite.Result, the result of running suites
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:generate go test -run TestSchemas -update
+
+// Names of the schemas, see Get
+const (
+	Fixture     = "fixture"
+	TestResult  = "test-result"
+	SuiteResult = "suite-result"
+)
+
+// Draft the JSON Schema version of the schemas
+const Draft = "http://json-schema.org/draft-07/schema#"
+
+var documents = map[string]string{
+	Fixture:     fixtureSchema,
+	TestResult:  testResultSchema,
+	SuiteResult: suiteResultSchema,
+}
+
+// FixtureSchema the schema of the fixture files
+func FixtureSchema() []byte {
+	return []byte(fixtureSchema)
+}
+
+// TestResultSchema the schema of the json result of a test
+func TestResultSchema() []byte {
+	return []byte(testResultSchema)
+}
+
+// SuiteResultSchema the schema of the json result of running suites
+func SuiteResultSchema() []byte {
+	return []byte(suiteResultSchema)
+}
+
+// Names the names of the schemas, sorted
+func Names() []string {
+	names := make([]string, 0, len(documents))
+	for name := range documents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get the schema with the name, nil when unknown
+func Get(name string) []byte {
+	doc, ok := documents[name]
+	if !ok {
+		return nil
+	}
+	return []byte(doc)
+}
+
+// Schema a node of a schema, the keywords of the generated schemas only
+type Schema struct {
+	// Schema the JSON Schema version, on the root only
+	Schema string `json:"$schema,omitempty"`
+	// Title of the schema, on the root only
+	Title string `json:"title,omitempty"`
+	// Type the types allowed, any when empty
+	Type Types `json:"type,omitempty"`
+	// Properties the schemas of the properties of an object
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// AdditionalProperties the schema of the other properties of an object,
+	// see Closed
+	AdditionalProperties *Schema `json:"additionalProperties,omitempty"`
+	// Items the schema of the items of an array
+	Items *Schema `json:"items,omitempty"`
+	// Closed the object has no other properties, additionalProperties: false
+	Closed bool `json:"-"`
+}
+
+// schemaJSON Schema without its methods
+type schemaJSON Schema
+
+// MarshalJSON writes Closed as additionalProperties: false
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if !s.Closed {
+		return json.Marshal((*schemaJSON)(s))
+	}
+	return json.Marshal(struct {
+		*schemaJSON
+		AdditionalProperties bool `json:"additionalProperties"`
+	}{(*schemaJSON)(s), false})
+}
+
+// UnmarshalJSON reads additionalProperties: false as Closed
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		schemaJSON
+		AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*s = Schema(raw.schemaJSON)
+	switch a := bytes.TrimSpace(raw.AdditionalProperties); {
+	case len(a) == 0, string(a) == "true":
+	case string(a) == "false":
+		s.Closed = true
+	default:
+		return json.Unmarshal(a, &s.AdditionalProperties)
+	}
+	return nil
+}
+
+// Types the type keyword, a single type is written as a string
+type Types []string
+
+// MarshalJSON implements json.Marshaler
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *Types) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(t))
+}
+
+// Parse parses a schema document
+func Parse(doc []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := json.Unmarshal(doc, s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	return s, nil
+}
+
+var (
+	parseOnce sync.Once
+	parsed    map[string]*Schema
+)
+
+// parsedSchema the parsed schema with the name, the published schemas are
+// always valid
+func parsedSchema(name string) *Schema {
+	parseOnce.Do(func() {
+		parsed = make(map[string]*Schema, len(documents))
+		for n, doc := range documents {
+			s, err := Parse([]byte(doc))
+			if err != nil {
+				panic(fmt.Sprintf("schema %s: %v", n, err))
+			}
+			parsed[n] = s
+		}
+	})
+	return parsed[name]
+}
+
+// Validate validates a decoded json or yaml document, eg. of
+// yaml.Unmarshal into an interface{}, against the schema with the name.
+// The error is a *ValidationError listing every violation.
+func Validate(name string, doc interface{}) error {
+	s := parsedSchema(name)
+	if s == nil {
+		return fmt.Errorf("unknown schema %s", name)
+	}
+	return s.Validate(doc)
+}
+
+// ValidationError the violations of a document
+type ValidationError struct {
+	// Violations in the document order
+	Violations []Violation
+}
+
+// Violation a value not valid against its schema
+type Violation struct {
+	// Path of the value, eg. cases[3].request.method, empty for the root
+	Path string
+	// Message what's wrong, eg. expected string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.String()
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,133 @@
+package schema_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/schema"
+	"github.com/rbarilani/eskip-match/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var update = flag.Bool("update", false, "write the schemas generated from the structs")
+
+// published the schemas generated from the structs, by name
+var published = []struct {
+	name   string
+	value  interface{}
+	consts string
+	opts   schema.GenerateOptions
+}{
+	{schema.Fixture, suite.Suite{}, "fixtureSchema", schema.GenerateOptions{Title: "eskip-match fixture file", Tag: "yaml", Closed: true}},
+	{schema.TestResult, matcher.ResultData{}, "testResultSchema", schema.GenerateOptions{Title: "eskip-match test result"}},
+	{schema.SuiteResult, suite.Result{}, "suiteResultSchema", schema.GenerateOptions{Title: "eskip-match suite result"}},
+}
+
+// TestSchemas checks the published schemas are the ones of the structs,
+// with -update it writes them
+func TestSchemas(t *testing.T) {
+	var src bytes.Buffer
+	src.WriteString("// Code generated by go test -run TestSchemas -update; DO NOT EDIT.\n\npackage schema\n")
+	for _, p := range published {
+		doc := schema.Document(schema.Generate(p.value, p.opts))
+		fmt.Fprintf(&src, "\nconst %s = `%s`\n", p.consts, doc)
+		file := p.name + ".schema.json"
+		if *update {
+			require.NoError(t, ioutil.WriteFile(file, doc, 0644))
+			continue
+		}
+		assert.Equal(t, string(doc), string(schema.Get(p.name)), "%s is out of date, go generate ./schema", p.name)
+		b, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, string(doc), string(b), "%s is out of date, go generate ./schema", file)
+	}
+	if *update {
+		require.NoError(t, ioutil.WriteFile("schemas.go", src.Bytes(), 0644))
+	}
+}
+
+// decode the json document of v
+func decode(t *testing.T, v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	var doc interface{}
+	require.NoError(t, json.Unmarshal(b, &doc))
+	return doc
+}
+
+func TestResultsValidate(t *testing.T) {
+	m, err := matcher.New(&matcher.Options{RoutesFile: "../suite/testdata/routes.eskip"})
+	require.NoError(t, err)
+	defer m.Close()
+
+	res := m.Test(&matcher.RequestAttributes{Path: "/orders", Host: "api.example.org"})
+	assert.NoError(t, schema.Validate(schema.TestResult, decode(t, matcher.NewResultData(res))))
+	res = m.Test(&matcher.RequestAttributes{Path: "/missing"})
+	assert.NoError(t, schema.Validate(schema.TestResult, decode(t, matcher.NewResultData(res))))
+
+	suites, err := suite.LoadFiles("../suite/testdata/fixtures/**/*.yaml")
+	require.NoError(t, err)
+	assert.NoError(t, schema.Validate(schema.SuiteResult, decode(t, suite.Run(m, suites, &suite.RunOptions{Coverage: true}))))
+	assert.NoError(t, schema.Validate(schema.SuiteResult, decode(t, &suite.Result{})))
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		err  string
+	}{
+		{name: "valid", doc: "name: orders\ncases:\n  - request: {path: /orders, headers: {Accept: text/html}}\n    expect: {route: orders}\n"},
+		{name: "empty", doc: "{}"},
+		{name: "wrong type", doc: "cases:\n  - {}\n  - request: {method: 1}\n", err: "cases[1].request.method: expected string"},
+		{name: "unknown property", doc: "cases:\n  - expect: {rout: orders}\n", err: `cases[0].expect: unknown property "rout"`},
+		{name: "map values", doc: "cases:\n  - request: {headers: {X-Version: 2}}\n", err: "cases[0].request.headers.X-Version: expected string"},
+		{name: "every violation", doc: "name: [orders]\ncases: {}\n", err: "cases: expected array; name: expected string"},
+		{name: "not an object", doc: "- orders\n", err: "expected object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(tt.doc), &doc))
+			err := schema.Validate(schema.Fixture, doc)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.IsType(t, &schema.ValidationError{}, err)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	assert.EqualError(t, schema.Validate("missing", nil), "unknown schema missing")
+}
+
+func TestParse(t *testing.T) {
+	s, err := schema.Parse([]byte(`{"type": ["object", "null"], "properties": {"id": {"type": "integer"}}, "additionalProperties": {"type": "boolean"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, schema.Types{"object", "null"}, s.Type)
+	assert.False(t, s.Closed)
+	assert.NoError(t, s.Validate(map[string]interface{}{"id": 1.0, "enabled": true}))
+	assert.EqualError(t, s.Validate(map[string]interface{}{"id": 1.5, "enabled": "yes"}), "enabled: expected boolean; id: expected integer")
+	assert.NoError(t, s.Validate(nil))
+
+	_, err = schema.Parse([]byte(`{"type": 1}`))
+	assert.Error(t, err)
+
+	for _, name := range schema.Names() {
+		s, err := schema.Parse(schema.Get(name))
+		require.NoError(t, err, name)
+		assert.Equal(t, string(schema.Get(name)), string(schema.Document(s)), name)
+	}
+	assert.Nil(t, schema.Get("missing"))
+	assert.Equal(t, schema.FixtureSchema(), schema.Get(schema.Fixture))
+	assert.Equal(t, schema.TestResultSchema(), schema.Get(schema.TestResult))
+	assert.Equal(t, schema.SuiteResultSchema(), schema.Get(schema.SuiteResult))
+}
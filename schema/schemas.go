@@ -0,0 +1,347 @@
+// Code generated by go test -run TestSchemas -update; DO NOT EDIT.
+
+package schema
+
+const fixtureSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "eskip-match fixture file",
+  "type": "object",
+  "properties": {
+    "cases": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "expect": {
+            "type": "object",
+            "properties": {
+              "noMatch": {
+                "type": "boolean"
+              },
+              "route": {
+                "type": "string"
+              },
+              "snapshot": {
+                "type": "string"
+              }
+            },
+            "additionalProperties": false
+          },
+          "name": {
+            "type": "string"
+          },
+          "request": {
+            "type": "object",
+            "properties": {
+              "headers": {
+                "type": "object",
+                "additionalProperties": {
+                  "type": "string"
+                }
+              },
+              "host": {
+                "type": "string"
+              },
+              "method": {
+                "type": "string"
+              },
+              "path": {
+                "type": "string"
+              },
+              "query": {
+                "type": "object",
+                "additionalProperties": {
+                  "type": "string"
+                }
+              }
+            },
+            "additionalProperties": false
+          },
+          "tags": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          }
+        },
+        "additionalProperties": false
+      }
+    },
+    "name": {
+      "type": "string"
+    },
+    "parallel": {
+      "type": "boolean"
+    }
+  },
+  "additionalProperties": false
+}
+`
+
+const testResultSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "eskip-match test result",
+  "type": "object",
+  "properties": {
+    "backend": {
+      "type": "string"
+    },
+    "backendType": {
+      "type": "string"
+    },
+    "filters": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "args": {
+            "type": [
+              "array",
+              "null"
+            ],
+            "items": {}
+          },
+          "default": {
+            "type": "boolean"
+          },
+          "name": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "forcedTraffic": {
+      "type": "array",
+      "items": {
+        "type": "string"
+      }
+    },
+    "hops": {
+      "type": "array",
+      "items": {
+        "type": "string"
+      }
+    },
+    "lbEndpoints": {
+      "type": "array",
+      "items": {
+        "type": "string"
+      }
+    },
+    "lbMember": {
+      "type": "string"
+    },
+    "lookup": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "matched": {
+            "type": "boolean"
+          },
+          "node": {
+            "type": "string"
+          },
+          "rejected": {
+            "type": "object",
+            "properties": {
+              "captures": {
+                "type": "array",
+                "items": {
+                  "type": "string"
+                }
+              },
+              "error": {
+                "type": "string"
+              },
+              "matched": {
+                "type": "boolean"
+              },
+              "name": {
+                "type": "string"
+              },
+              "observed": {
+                "type": "string"
+              },
+              "predicate": {
+                "type": "string"
+              }
+            }
+          },
+          "routeId": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "matched": {
+      "type": "boolean"
+    },
+    "request": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "headers": {
+          "type": "object",
+          "additionalProperties": {
+            "type": "string"
+          }
+        },
+        "host": {
+          "type": "string"
+        },
+        "method": {
+          "type": "string"
+        },
+        "path": {
+          "type": "string"
+        },
+        "query": {
+          "type": "object",
+          "additionalProperties": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "routeId": {
+      "type": "string"
+    },
+    "trace": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "captures": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "error": {
+            "type": "string"
+          },
+          "matched": {
+            "type": "boolean"
+          },
+          "name": {
+            "type": "string"
+          },
+          "observed": {
+            "type": "string"
+          },
+          "predicate": {
+            "type": "string"
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+const suiteResultSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "eskip-match suite result",
+  "type": "object",
+  "properties": {
+    "cases": {
+      "type": [
+        "array",
+        "null"
+      ],
+      "items": {
+        "type": "object",
+        "properties": {
+          "case": {
+            "type": "object",
+            "properties": {
+              "expect": {
+                "type": "object",
+                "properties": {
+                  "noMatch": {
+                    "type": "boolean"
+                  },
+                  "route": {
+                    "type": "string"
+                  },
+                  "snapshot": {
+                    "type": "string"
+                  }
+                }
+              },
+              "name": {
+                "type": "string"
+              },
+              "request": {
+                "type": "object",
+                "properties": {
+                  "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                      "type": "string"
+                    }
+                  },
+                  "host": {
+                    "type": "string"
+                  },
+                  "method": {
+                    "type": "string"
+                  },
+                  "path": {
+                    "type": "string"
+                  },
+                  "query": {
+                    "type": "object",
+                    "additionalProperties": {
+                      "type": "string"
+                    }
+                  }
+                }
+              },
+              "tags": {
+                "type": "array",
+                "items": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "file": {
+            "type": "string"
+          },
+          "message": {
+            "type": "string"
+          },
+          "passed": {
+            "type": "boolean"
+          },
+          "routeId": {
+            "type": "string"
+          },
+          "suite": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "failed": {
+      "type": "integer"
+    },
+    "hits": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "integer"
+      }
+    },
+    "passed": {
+      "type": "integer"
+    },
+    "skipped": {
+      "type": "integer"
+    }
+  }
+}
+`
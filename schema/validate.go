@@ -0,0 +1,122 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Validate validates a decoded json or yaml document against the schema,
+// the error is a *ValidationError listing every violation
+func (s *Schema) Validate(doc interface{}) error {
+	var violations []Violation
+	s.validate("", doc, &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func (s *Schema) validate(path string, v interface{}, violations *[]Violation) {
+	t := typeOf(v)
+	if !s.allows(t) {
+		*violations = append(*violations, Violation{Path: path, Message: "expected " + s.expected()})
+		return
+	}
+	switch v := v.(type) {
+	case []interface{}:
+		if s.Items == nil {
+			return
+		}
+		for i, item := range v {
+			s.Items.validate(path+"["+strconv.Itoa(i)+"]", item, violations)
+		}
+	case map[string]interface{}:
+		s.validateObject(path, v, violations)
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			object[fmt.Sprint(key)] = value
+		}
+		s.validateObject(path, object, violations)
+	}
+}
+
+func (s *Schema) validateObject(path string, v map[string]interface{}, violations *[]Violation) {
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		p := key
+		if path != "" {
+			p = path + "." + key
+		}
+		switch property, ok := s.Properties[key]; {
+		case ok:
+			property.validate(p, v[key], violations)
+		case s.AdditionalProperties != nil:
+			s.AdditionalProperties.validate(p, v[key], violations)
+		case s.Closed:
+			*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf("unknown property %q", key)})
+		}
+	}
+}
+
+// allows the schema allows a value of the type
+func (s *Schema) allows(t string) bool {
+	if len(s.Type) == 0 {
+		return true
+	}
+	for _, allowed := range s.Type {
+		if allowed == t || allowed == "number" && t == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// expected the types allowed, eg. string or array or null
+func (s *Schema) expected() string {
+	expected := ""
+	for i, t := range s.Type {
+		if i > 0 {
+			expected += " or "
+		}
+		expected += t
+	}
+	return expected
+}
+
+// typeOf the JSON Schema type of a decoded value
+func typeOf(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32:
+		return numberType(float64(v))
+	case float64:
+		return numberType(v)
+	case []interface{}:
+		return "array"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func numberType(f float64) string {
+	if f == math.Trunc(f) && !math.IsInf(f, 0) {
+		return "integer"
+	}
+	return "number"
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rbarilani/eskip-match/suite"
+)
+
+// Types of the events streamed by /events
+const (
+	// EventSnapshot the first event of a stream, the state when the client
+	// connected
+	EventSnapshot = "snapshot"
+	// EventReloadStart a reload of the routing table started
+	EventReloadStart = "reload-start"
+	// EventReloadSuccess the reloaded routing table is in use
+	EventReloadSuccess = "reload-success"
+	// EventReloadFailure the reload failed, the previous table is still used
+	EventReloadFailure = "reload-failure"
+	// EventCase the outcome of a pinned case changed with a reload
+	EventCase = "case"
+)
+
+// DefaultEventBuffer default number of events buffered for each /events
+// client, see ServerOptions.EventBuffer
+const DefaultEventBuffer = 64
+
+// eventsKeepAlive interval of the comments keeping idle streams open
+// through the proxies
+var eventsKeepAlive = 15 * time.Second
+
+// Event an event streamed by /events, the data of a server-sent event with
+// the same type
+type Event struct {
+	// Type one of EventSnapshot, EventReloadStart, EventReloadSuccess,
+	// EventReloadFailure and EventCase
+	Type string `json:"type"`
+	// Time when the event happened
+	Time time.Time `json:"time"`
+	// Routes number of valid routes in use when the event happened
+	Routes int `json:"routes"`
+	// Status ready or loading, with EventSnapshot
+	Status string `json:"status,omitempty"`
+	// Error why the reload failed with EventReloadFailure, why the last one
+	// failed with EventSnapshot
+	Error string `json:"error,omitempty"`
+	// Cases the last results of the pinned cases, with EventSnapshot
+	Cases []suite.CaseResult `json:"cases,omitempty"`
+	// Case the new result of the pinned case, with EventCase
+	Case *suite.CaseResult `json:"case,omitempty"`
+	// Previous the result of the pinned case before the reload, with
+	// EventCase
+	Previous *suite.CaseResult `json:"previous,omitempty"`
+	// Dropped number of events not sent to the client before this one
+	// because its buffer was full
+	Dropped int `json:"dropped,omitempty"`
+}
+
+// eventBroker sends the events to the /events clients without waiting for
+// them: the events of a client with a full buffer are dropped and counted
+type eventBroker struct {
+	buffer int
+
+	mu      sync.Mutex
+	clients map[*eventClient]bool
+	closed  bool
+	done    chan struct{}
+}
+
+// eventClient an /events stream
+type eventClient struct {
+	events chan Event
+	// dropped events since the last one sent, guarded by the broker
+	dropped int
+}
+
+func newEventBroker(buffer int) *eventBroker {
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+	return &eventBroker{buffer: buffer, clients: make(map[*eventClient]bool), done: make(chan struct{})}
+}
+
+// subscribe adds a client receiving the snapshot first, nil once closed
+func (b *eventBroker) subscribe(snapshot func() Event) *eventClient {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	c := &eventClient{events: make(chan Event, b.buffer)}
+	c.events <- snapshot()
+	b.clients[c] = true
+	return c
+}
+
+func (b *eventBroker) unsubscribe(c *eventClient) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.clients, c)
+}
+
+// publish sends the event to every client with room for it
+func (b *eventBroker) publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		e.Dropped = c.dropped
+		select {
+		case c.events <- e:
+			c.dropped = 0
+		default:
+			c.dropped++
+		}
+	}
+}
+
+// close ends the streams
+func (b *eventBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+	}
+}
+
+// CloseEvents ends the /events streams and refuses the new ones, eg. before
+// shutting down the http server which waits for the open streams
+func (s *Server) CloseEvents() {
+	s.broker.close()
+}
+
+// Close ends the /events streams like CloseEvents and stops the
+// notifications, the one being delivered, retries included, is
+// interrupted and the queued ones are dropped, eg. when shutting down
+func (s *Server) Close() {
+	s.CloseEvents()
+	if s.notifier != nil {
+		s.notifier.close()
+	}
+}
+
+// publish publishes an event of the type with the number of routes in use
+func (s *Server) publish(e Event) {
+	e.Time = time.Now()
+	if t := s.current(); t != nil {
+		e.Routes = t.Matcher.RouteCount()
+	}
+	s.broker.publish(e)
+}
+
+// snapshot the state of the server as an EventSnapshot
+func (s *Server) snapshot() Event {
+	s.mu.RLock()
+	t, reloadErr, cases := s.table, s.reloadErr, s.cases
+	s.mu.RUnlock()
+
+	e := Event{Type: EventSnapshot, Time: time.Now(), Status: "loading", Cases: cases}
+	if reloadErr != nil {
+		e.Error = reloadErr.Error()
+	}
+	if t != nil {
+		if ready, _ := t.Matcher.Ready(); ready {
+			e.Status = "ready"
+		}
+		e.Routes = t.Matcher.RouteCount()
+	}
+	return e
+}
+
+// runPinned runs the pinned cases against the table, publishes the cases
+// whose outcome changed since the previous run and notifies the ones which
+// regressed
+func (s *Server) runPinned(t *Table) {
+	if len(s.pinned) == 0 {
+		return
+	}
+	res := suite.Run(t.Matcher, s.pinned, nil)
+	routes := t.routes()
+
+	s.mu.Lock()
+	previous, previousRoutes := s.cases, s.pinnedRoutes
+	s.cases, s.pinnedRoutes = res.Cases, routes
+	s.mu.Unlock()
+
+	if previous == nil {
+		return
+	}
+	var changes []CaseChange
+	for i := range res.Cases {
+		if i >= len(previous) {
+			break
+		}
+		c, p := res.Cases[i], previous[i]
+		if c.RouteID != p.RouteID || c.Passed != p.Passed {
+			s.publish(Event{Type: EventCase, Case: &c, Previous: &p})
+			changes = append(changes, CaseChange{Case: c, Previous: p})
+		}
+	}
+	if s.notifier != nil {
+		if r := newRegression(changes, previousRoutes, routes); r != nil {
+			s.notifier.notify(r)
+		}
+	}
+}
+
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, errorData{"streaming not supported"})
+		return
+	}
+	c := s.broker.subscribe(s.snapshot)
+	if c == nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorData{"server shutting down"})
+		return
+	}
+	defer s.broker.unsubscribe(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case e := <-c.events:
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.broker.done:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes the event in the server-sent events format, the type as
+// event name and the json as data
+func writeEvent(w http.ResponseWriter, e Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(e); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n", e.Type, buf.Bytes())
+	return err
+}
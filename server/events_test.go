@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvent reads the next server-sent event of the stream, skipping the
+// comments
+func readEvent(t *testing.T, r *bufio.Reader) (string, Event) {
+	t.Helper()
+	var name string
+	var e Event
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, e
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e))
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	f, err := ioutil.TempFile("", "events-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	write := func(routes string) {
+		require.NoError(t, ioutil.WriteFile(f.Name(), []byte(routes), 0644))
+	}
+	write(`orders: Path("/orders") -> <shunt>; search: Path("/search") -> <shunt>;`)
+
+	pinned := []*suite.Suite{{Name: "pinned", Cases: []suite.Case{
+		{Name: "orders", Request: matcher.RequestAttributes{Path: "/orders"}, Expect: suite.Expect{Route: "orders"}},
+		{Name: "search", Request: matcher.RequestAttributes{Path: "/search"}, Expect: suite.Expect{Route: "search"}},
+	}}}
+	s := NewWithOptions(func() (*Table, error) {
+		m, err := matcher.New(&matcher.Options{RoutesFile: f.Name()})
+		if err != nil {
+			return nil, err
+		}
+		return &Table{Matcher: m}, nil
+	}, ServerOptions{Pinned: pinned})
+	require.NoError(t, s.Reload())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	defer s.CloseEvents()
+
+	rsp, err := http.Get(ts.URL + "/events")
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, "text/event-stream", rsp.Header.Get("Content-Type"))
+	stream := bufio.NewReader(rsp.Body)
+
+	// a late client gets the state first
+	name, e := readEvent(t, stream)
+	assert.Equal(t, EventSnapshot, name)
+	assert.Equal(t, EventSnapshot, e.Type)
+	assert.Equal(t, "ready", e.Status)
+	assert.Equal(t, 2, e.Routes)
+	require.Len(t, e.Cases, 2)
+	assert.True(t, e.Cases[0].Passed)
+	assert.Equal(t, "orders", e.Cases[0].RouteID)
+
+	// the orders case changes, the search one doesn't
+	write(`orders_v2: Path("/orders") -> <shunt>; search: Path("/search") -> <shunt>; health: Path("/health") -> <shunt>;`)
+	require.NoError(t, s.Reload())
+	name, e = readEvent(t, stream)
+	assert.Equal(t, EventReloadStart, name)
+	assert.Equal(t, 2, e.Routes)
+	name, e = readEvent(t, stream)
+	assert.Equal(t, EventReloadSuccess, name)
+	assert.Equal(t, 3, e.Routes)
+	name, e = readEvent(t, stream)
+	assert.Equal(t, EventCase, name)
+	require.NotNil(t, e.Case)
+	require.NotNil(t, e.Previous)
+	assert.Equal(t, "orders", e.Case.Case.Name)
+	assert.Equal(t, "orders_v2", e.Case.RouteID)
+	assert.False(t, e.Case.Passed)
+	assert.Equal(t, "orders", e.Previous.RouteID)
+	assert.True(t, e.Previous.Passed)
+
+	write(`broken: Path("/orders") -> ;`)
+	assert.Error(t, s.Reload())
+	name, _ = readEvent(t, stream)
+	assert.Equal(t, EventReloadStart, name)
+	name, e = readEvent(t, stream)
+	assert.Equal(t, EventReloadFailure, name)
+	assert.NotEmpty(t, e.Error)
+	// the previous table is still used
+	assert.Equal(t, 3, e.Routes)
+	assert.Equal(t, 0, e.Dropped)
+
+	status, _ := call(t, "POST", ts.URL+"/events", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+}
+
+func TestEventsSlowClient(t *testing.T) {
+	s := NewWithOptions(loadTable, ServerOptions{EventBuffer: 2})
+	c := s.broker.subscribe(s.snapshot)
+
+	// nobody reads the events, the reloads don't wait
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			s.Reload()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the reloads are blocked by the client")
+	}
+
+	assert.Equal(t, EventSnapshot, (<-c.events).Type)
+	assert.Equal(t, EventReloadStart, (<-c.events).Type)
+	// 2 events of each reload, the first event was sent
+	require.NoError(t, s.Reload())
+	e := <-c.events
+	assert.Equal(t, EventReloadStart, e.Type)
+	assert.Equal(t, 19, e.Dropped)
+	e = <-c.events
+	assert.Equal(t, EventReloadSuccess, e.Type)
+	assert.Equal(t, 0, e.Dropped)
+}
+
+func TestCloseEvents(t *testing.T) {
+	s := New(loadTable)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	rsp, err := http.Get(ts.URL + "/events")
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	stream := bufio.NewReader(rsp.Body)
+	name, e := readEvent(t, stream)
+	assert.Equal(t, EventSnapshot, name)
+	assert.Equal(t, "loading", e.Status)
+
+	s.CloseEvents()
+	_, err = ioutil.ReadAll(stream)
+	assert.NoError(t, err)
+	status, _ := call(t, "GET", ts.URL+"/events", "")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
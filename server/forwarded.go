@@ -0,0 +1,117 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+// ForwardedHeaders names of the headers the gateways forward the original
+// request with, eg. nginx auth_request
+type ForwardedHeaders struct {
+	// URL the original url, a path with the query or an absolute url,
+	// url-encoded or not, X-Original-URL by default
+	URL string
+	// Method the original method, X-Original-Method by default, GET when
+	// not forwarded
+	Method string
+	// Host the original host, X-Forwarded-Host by default, the host of an
+	// absolute url when not forwarded
+	Host string
+}
+
+// DefaultForwardedHeaders the headers forwarded by nginx auth_request and
+// most gateways
+func DefaultForwardedHeaders() ForwardedHeaders {
+	return ForwardedHeaders{URL: "X-Original-URL", Method: "X-Original-Method", Host: "X-Forwarded-Host"}
+}
+
+// withDefaults the headers with the defaults for the empty names
+func (h ForwardedHeaders) withDefaults() ForwardedHeaders {
+	d := DefaultForwardedHeaders()
+	if h.URL == "" {
+		h.URL = d.URL
+	}
+	if h.Method == "" {
+		h.Method = d.Method
+	}
+	if h.Host == "" {
+		h.Host = d.Host
+	}
+	return h
+}
+
+// attributes reconstructs the original request from the forwarded headers,
+// the other headers are the ones of the original request
+func (h ForwardedHeaders) attributes(r *http.Request) (*matcher.RequestAttributes, error) {
+	original := r.Header.Get(h.URL)
+	if original == "" {
+		return nil, errors.New("missing " + h.URL + " header")
+	}
+	if !strings.HasPrefix(original, "/") && !strings.Contains(original, "://") {
+		// url-encoded, eg. %2Fsearch%3Fq%3Dshoes
+		unescaped, err := url.QueryUnescape(original)
+		if err != nil {
+			return nil, err
+		}
+		original = unescaped
+	}
+	u, err := url.Parse(original)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := &matcher.RequestAttributes{
+		Method:  r.Header.Get(h.Method),
+		Path:    u.EscapedPath(),
+		Host:    r.Header.Get(h.Host),
+		Query:   map[string]string{},
+		Headers: map[string]string{},
+	}
+	if attrs.Host == "" {
+		attrs.Host = u.Host
+	}
+	for key, values := range u.Query() {
+		attrs.Query[key] = values[0]
+	}
+	for key, values := range r.Header {
+		switch key {
+		case http.CanonicalHeaderKey(h.URL), http.CanonicalHeaderKey(h.Method), http.CanonicalHeaderKey(h.Host):
+			continue
+		}
+		attrs.Headers[key] = strings.Join(values, ", ")
+	}
+	return attrs, nil
+}
+
+// matchForwarded answers 200 with the route headers of matcher.Middleware
+// when the forwarded request matches a route, 404 when it doesn't, with the
+// match result as body. Any method is accepted, the gateways may forward the
+// one of the original request.
+func (s *Server) matchForwarded(w http.ResponseWriter, r *http.Request) {
+	t := s.current()
+	if t == nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorData{"routes not loaded"})
+		return
+	}
+	attrs, err := s.forwarded.attributes(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorData{"invalid forwarded request: " + err.Error()})
+		return
+	}
+	res, _, ok := s.test(w, t, attrs)
+	if !ok {
+		return
+	}
+	if res.Route() == nil {
+		writeJSON(w, http.StatusNotFound, res)
+		return
+	}
+	data := matcher.NewResultData(res)
+	w.Header().Set(matcher.DefaultRouteHeader, data.RouteID)
+	w.Header().Set(matcher.DefaultBackendHeader, data.Backend)
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// forward sends a request with the headers to /match/forwarded, returns the
+// response and its body
+func forward(t *testing.T, url string, headers map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest("GET", url+"/match/forwarded", nil)
+	require.NoError(t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	b, err := ioutil.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	return rsp, string(b)
+}
+
+func TestMatchForwarded(t *testing.T) {
+	s := New(loadTable)
+	require.NoError(t, s.Reload())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		route   string
+		want    string
+	}{
+		{
+			name:    "path with the query",
+			headers: map[string]string{"X-Original-URL": "/search?q=shoes"},
+			status:  http.StatusOK,
+			route:   "search",
+		},
+		{
+			name:    "absolute url",
+			headers: map[string]string{"X-Original-URL": "https://api.example.org/orders"},
+			status:  http.StatusOK,
+			route:   "orders",
+		},
+		{
+			name:    "forwarded host",
+			headers: map[string]string{"X-Original-URL": "/orders", "X-Forwarded-Host": "api.example.org", "X-Original-Method": "POST"},
+			status:  http.StatusOK,
+			route:   "orders",
+		},
+		{
+			name:    "url-encoded",
+			headers: map[string]string{"X-Original-URL": "%2Fsearch%3Fq%3Dshoes"},
+			status:  http.StatusOK,
+			route:   "search",
+		},
+		{
+			name:    "url-encoded absolute url",
+			headers: map[string]string{"X-Original-URL": "https%3A%2F%2Fapi.example.org%2Forders"},
+			status:  http.StatusOK,
+			route:   "orders",
+		},
+		{
+			name:    "no match",
+			headers: map[string]string{"X-Original-URL": "/orders"},
+			status:  http.StatusNotFound,
+			want:    `{"matched": false, "request": {"method": "GET", "path": "/orders", "headers": {"Accept-Encoding": "gzip", "User-Agent": "Go-http-client/1.1"}}}`,
+		},
+		{
+			name:   "missing original url",
+			status: http.StatusBadRequest,
+			want:   `{"error": "invalid forwarded request: missing X-Original-URL header"}`,
+		},
+		{
+			name:    "invalid url-encoding",
+			headers: map[string]string{"X-Original-URL": "%zz"},
+			status:  http.StatusBadRequest,
+			want:    `{"error": "invalid forwarded request: invalid URL escape \"%zz\""}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rsp, body := forward(t, ts.URL, tt.headers)
+			assert.Equal(t, tt.status, rsp.StatusCode)
+			assert.Equal(t, tt.route, rsp.Header.Get(matcher.DefaultRouteHeader))
+			if tt.want != "" {
+				assert.JSONEq(t, tt.want, body)
+			}
+		})
+	}
+}
+
+func TestMatchForwardedHeaderNames(t *testing.T) {
+	m, err := matcher.New(&matcher.Options{RoutesFile: routesFile})
+	require.NoError(t, err)
+	defer m.Close()
+	ts := httptest.NewServer(NewDebugServer(m, ServerOptions{Forwarded: ForwardedHeaders{URL: "X-Original-URI"}}))
+	defer ts.Close()
+
+	rsp, _ := forward(t, ts.URL, map[string]string{"X-Original-URI": "/orders", "X-Forwarded-Host": "api.example.org"})
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, "orders", rsp.Header.Get(matcher.DefaultRouteHeader))
+	assert.Equal(t, "https://orders.example.org", rsp.Header.Get(matcher.DefaultBackendHeader))
+
+	// the default url header isn't read anymore
+	rsp, _ = forward(t, ts.URL, map[string]string{"X-Original-URL": "/orders"})
+	assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+}
+
+func TestMatchForwardedNotLoaded(t *testing.T) {
+	ts := httptest.NewServer(New(loadTable))
+	defer ts.Close()
+
+	rsp, _ := forward(t, ts.URL, map[string]string{"X-Original-URL": "/orders"})
+	assert.Equal(t, http.StatusServiceUnavailable, rsp.StatusCode)
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+// Outcomes of the matches recorded by Metrics
+const (
+	OutcomeMatched   = "matched"
+	OutcomeUnmatched = "unmatched"
+	OutcomeError     = "error"
+)
+
+// Metrics records the metrics of a long-lived server, served at /metrics,
+// see the prommetrics package for the Prometheus ones
+type Metrics interface {
+	// ObserveMatch records a match with its outcome, one of OutcomeMatched,
+	// OutcomeUnmatched and OutcomeError, and its duration
+	ObserveMatch(outcome string, d time.Duration)
+	// SetRoutes records the number of valid routes in use
+	SetRoutes(n int)
+	// ObserveReload records a reload of the routing table, err nil when it
+	// succeeded
+	ObserveReload(err error)
+	// Handler serves the metrics
+	Handler() http.Handler
+}
+
+// MetricsHooks the matcher hooks feeding the matches and the number of
+// routes of the routing reloads to the metrics, to set in the options of the
+// matchers of the routing tables
+func MetricsHooks(m Metrics) matcher.Hooks {
+	return matcher.Hooks{
+		OnTest: func(attributes *matcher.RequestAttributes, result matcher.TestResult, err error, d time.Duration) {
+			switch {
+			case err != nil:
+				m.ObserveMatch(OutcomeError, d)
+			case result.Route() != nil:
+				m.ObserveMatch(OutcomeMatched, d)
+			default:
+				m.ObserveMatch(OutcomeUnmatched, d)
+			}
+		},
+		OnReload: func(routes int, err error) {
+			if err == nil {
+				m.SetRoutes(routes)
+			}
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMetrics records the calls of the Metrics methods
+type fakeMetrics struct {
+	mu       sync.Mutex
+	outcomes []string
+	routes   int
+	reloads  []error
+}
+
+func (m *fakeMetrics) ObserveMatch(outcome string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outcomes = append(m.outcomes, outcome)
+}
+
+func (m *fakeMetrics) SetRoutes(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes = n
+}
+
+func (m *fakeMetrics) ObserveReload(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reloads = append(m.reloads, err)
+}
+
+// recorded the outcomes and the number of routes recorded so far
+func (m *fakeMetrics) recorded() ([]string, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.outcomes...), m.routes
+}
+
+func (m *fakeMetrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("metrics\n"))
+	})
+}
+
+func TestMetricsHooks(t *testing.T) {
+	metrics := &fakeMetrics{}
+	m, err := matcher.New(&matcher.Options{RoutesFile: routesFile, Hooks: MetricsHooks(metrics)})
+	require.NoError(t, err)
+	defer m.Close()
+
+	m.Test(&matcher.RequestAttributes{Path: "/search", Query: map[string]string{"q": "shoes"}})
+	m.Test(&matcher.RequestAttributes{Path: "/missing"})
+	m.Test(&matcher.RequestAttributes{Path: "/%zz"})
+	outcomes, routes := metrics.recorded()
+	assert.Equal(t, []string{OutcomeMatched, OutcomeUnmatched, OutcomeError}, outcomes)
+	assert.Equal(t, 2, routes)
+}
+
+func TestServerMetrics(t *testing.T) {
+	metrics := &fakeMetrics{}
+	fail := errors.New("parse error")
+	s := NewWithOptions(func() (*Table, error) {
+		return nil, fail
+	}, ServerOptions{Metrics: metrics})
+	assert.Equal(t, fail, s.Reload())
+	assert.Equal(t, []error{fail}, metrics.reloads)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	status, body := call(t, "GET", ts.URL+"/metrics", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "metrics\n", body)
+
+	// no metrics endpoint without metrics
+	ts2 := httptest.NewServer(New(loadTable))
+	defer ts2.Close()
+	status, _ = call(t, "GET", ts2.URL+"/metrics", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/suite"
+	"github.com/zalando/skipper/eskip"
+)
+
+// Defaults of NotifyOptions
+const (
+	DefaultNotifyTimeout = 10 * time.Second
+	DefaultNotifyRetries = 3
+	DefaultNotifyBackoff = time.Second
+)
+
+// notifyQueue regressions waiting for the delivery of the previous ones,
+// the next ones are dropped
+const notifyQueue = 16
+
+// errNotifierClosed the server was closed before the delivery
+var errNotifierClosed = errors.New("the server was closed")
+
+// NotifyOptions the webhook notified when a reload makes pinned cases fail,
+// see ServerOptions.Notify
+type NotifyOptions struct {
+	// URL the webhook the Regression is posted to, eg. a Slack incoming
+	// webhook, no notifications when empty
+	URL string
+	// Template the json payload, executed with the Regression, the
+	// Regression itself when nil. See NotifyTemplate.
+	Template *template.Template
+	// Timeout of each attempt, DefaultNotifyTimeout when 0
+	Timeout time.Duration
+	// Retries attempts after a failed one, DefaultNotifyRetries when 0,
+	// none when < 0
+	Retries int
+	// Backoff wait before the first retry, doubled for each next one,
+	// DefaultNotifyBackoff when 0
+	Backoff time.Duration
+	// Log where the notifications failing every attempt are logged, they are
+	// dropped when nil
+	Log io.Writer
+}
+
+// Regression payload of the notifications, the pinned cases a reload made
+// fail
+type Regression struct {
+	// Time of the reload
+	Time time.Time `json:"time"`
+	// Summary one line description, eg. for a chat message
+	Summary string `json:"summary"`
+	// Cases the pinned cases passing before the reload and failing after
+	Cases []CaseChange `json:"cases"`
+	// Routes the routes added, removed and changed by the reload
+	Routes []matcher.RouteChange `json:"routes"`
+}
+
+// CaseChange a pinned case whose outcome changed with a reload
+type CaseChange struct {
+	// Case the result after the reload
+	Case suite.CaseResult `json:"case"`
+	// Previous the result before the reload
+	Previous suite.CaseResult `json:"previous"`
+}
+
+// NotifyTemplate parses the template of a payload, with the json function
+// writing a value as json, eg. {"text": {{json .Summary}}} for Slack
+func NotifyTemplate(text string) (*template.Template, error) {
+	return template.New("payload").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+}
+
+// notifier posts the regressions to the webhook in the background, one
+// after the other in their order
+type notifier struct {
+	o      NotifyOptions
+	client *http.Client
+	queue  chan *Regression
+	// ctx canceled by close, it stops the delivery and the worker
+	ctx    context.Context
+	cancel context.CancelFunc
+	start  sync.Once
+	// done closed when the worker stopped
+	done chan struct{}
+}
+
+// newNotifier the notifier of the options, nil without URL
+func newNotifier(o NotifyOptions) *notifier {
+	if o.URL == "" {
+		return nil
+	}
+	if o.Timeout == 0 {
+		o.Timeout = DefaultNotifyTimeout
+	}
+	switch {
+	case o.Retries == 0:
+		o.Retries = DefaultNotifyRetries
+	case o.Retries < 0:
+		o.Retries = 0
+	}
+	if o.Backoff == 0 {
+		o.Backoff = DefaultNotifyBackoff
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &notifier{o: o, client: &http.Client{Timeout: o.Timeout}, queue: make(chan *Regression, notifyQueue),
+		ctx: ctx, cancel: cancel, done: make(chan struct{})}
+}
+
+// notify queues the regression without waiting for the delivery, the
+// failures are logged. The worker delivering them is started by the first
+// one.
+func (n *notifier) notify(r *Regression) {
+	if n.ctx.Err() != nil {
+		return
+	}
+	n.start.Do(func() { go n.run() })
+	select {
+	case n.queue <- r:
+	default:
+		n.logf("eskip-match: notification of %s dropped, %d are waiting\n", n.o.URL, notifyQueue)
+	}
+}
+
+// run delivers the queued regressions until close
+func (n *notifier) run() {
+	defer close(n.done)
+	for {
+		select {
+		case r := <-n.queue:
+			if err := n.send(r); err != nil {
+				n.logf("eskip-match: notification of %s failed: %v\n", n.o.URL, err)
+			}
+		case <-n.ctx.Done():
+			return
+		}
+	}
+}
+
+// close stops the worker, interrupting the delivery in progress, the
+// queued regressions are dropped
+func (n *notifier) close() {
+	n.cancel()
+}
+
+func (n *notifier) logf(format string, a ...interface{}) {
+	if n.o.Log != nil {
+		fmt.Fprintf(n.o.Log, format, a...)
+	}
+}
+
+// send posts the payload of the regression, retrying the failed attempts
+func (n *notifier) send(r *Regression) error {
+	var payload bytes.Buffer
+	if n.o.Template == nil {
+		if err := json.NewEncoder(&payload).Encode(r); err != nil {
+			return err
+		}
+	} else if err := n.o.Template.Execute(&payload, r); err != nil {
+		return err
+	}
+
+	backoff := n.o.Backoff
+	var err error
+	for attempt := 0; attempt <= n.o.Retries; attempt++ {
+		if attempt > 0 {
+			wait := time.NewTimer(backoff)
+			select {
+			case <-wait.C:
+			case <-n.ctx.Done():
+				wait.Stop()
+				return fmt.Errorf("%v, after %d attempt(s)", errNotifierClosed, attempt)
+			}
+			backoff *= 2
+		}
+		if err = n.post(payload.Bytes()); err == nil {
+			return nil
+		}
+		if n.ctx.Err() != nil {
+			return fmt.Errorf("%v, after %d attempt(s)", errNotifierClosed, attempt+1)
+		}
+	}
+	return fmt.Errorf("%v, after %d attempt(s)", err, n.o.Retries+1)
+}
+
+func (n *notifier) post(payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, n.o.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rsp, err := n.client.Do(req.WithContext(n.ctx))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	// the connection is reused
+	io.Copy(ioutil.Discard, rsp.Body)
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return fmt.Errorf("status %d", rsp.StatusCode)
+	}
+	return nil
+}
+
+// newRegression the regression of the changed cases, nil when none of them
+// regressed
+func newRegression(changes []CaseChange, old, new []*eskip.Route) *Regression {
+	r := &Regression{Time: time.Now(), Cases: []CaseChange{}}
+	for _, c := range changes {
+		if c.Previous.Passed && !c.Case.Passed {
+			r.Cases = append(r.Cases, c)
+		}
+	}
+	if len(r.Cases) == 0 {
+		return nil
+	}
+	r.Routes = matcher.DiffRoutes(old, new)
+
+	counts := make(map[string]int)
+	for _, c := range r.Routes {
+		counts[c.Change]++
+	}
+	var routes []string
+	for _, change := range []string{matcher.RouteAdded, matcher.RouteRemoved, matcher.RouteChanged} {
+		if counts[change] > 0 {
+			routes = append(routes, fmt.Sprintf("%d %s", counts[change], change))
+		}
+	}
+	names := make([]string, len(r.Cases))
+	for i, c := range r.Cases {
+		names[i] = c.Case.Suite + ": " + c.Case.Case.Name
+	}
+	r.Summary = fmt.Sprintf("%d pinned case(s) regressed (%s)", len(r.Cases), strings.Join(names, ", "))
+	if len(routes) > 0 {
+		r.Summary += ", route(s) " + strings.Join(routes, ", ")
+	}
+	return r
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhook records the payloads posted to it, failing the first attempts
+type webhook struct {
+	failures int
+
+	mu       sync.Mutex
+	attempts int
+	payloads chan string
+}
+
+func newWebhook(failures int) (*webhook, *httptest.Server) {
+	h := &webhook{failures: failures, payloads: make(chan string, 10)}
+	return h, httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.Lock()
+		h.attempts++
+		fail := h.attempts <= h.failures
+		h.mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		h.payloads <- r.Header.Get("Content-Type") + " " + string(b)
+	}))
+}
+
+// payload the next payload posted
+func (h *webhook) payload(t *testing.T) string {
+	select {
+	case p := <-h.payloads:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification")
+		return ""
+	}
+}
+
+// pinnedServer a server with pinned cases reloading the routes written
+func pinnedServer(t *testing.T, notify NotifyOptions) (*Server, func(routes string), func()) {
+	f, err := ioutil.TempFile("", "notify-*.eskip")
+	require.NoError(t, err)
+	f.Close()
+	write := func(routes string) {
+		require.NoError(t, ioutil.WriteFile(f.Name(), []byte(routes), 0644))
+	}
+	pinned := []*suite.Suite{{Name: "pinned", Cases: []suite.Case{
+		{Name: "orders", Request: matcher.RequestAttributes{Path: "/orders"}, Expect: suite.Expect{Route: "orders"}},
+		{Name: "search", Request: matcher.RequestAttributes{Path: "/search"}, Expect: suite.Expect{Route: "search"}},
+	}}}
+	s := NewWithOptions(func() (*Table, error) {
+		m, err := matcher.New(&matcher.Options{RoutesFile: f.Name()})
+		if err != nil {
+			return nil, err
+		}
+		return &Table{Matcher: m}, nil
+	}, ServerOptions{Pinned: pinned, Notify: notify})
+	return s, write, func() { os.Remove(f.Name()) }
+}
+
+func TestNotifyRegressions(t *testing.T) {
+	h, ts := newWebhook(1)
+	defer ts.Close()
+	s, write, remove := pinnedServer(t, NotifyOptions{URL: ts.URL, Backoff: time.Millisecond})
+	defer remove()
+
+	write(`orders: Path("/orders") -> <shunt>;`)
+	require.NoError(t, s.Reload())
+	// the search case passing doesn't notify
+	write(`orders: Path("/orders") -> <shunt>; search: Path("/search") -> <shunt>;`)
+	require.NoError(t, s.Reload())
+	// nor a change without new failures
+	write(`orders: Path("/orders") -> setPath("/v1/orders") -> <shunt>; search: Path("/search") -> <shunt>;`)
+	require.NoError(t, s.Reload())
+
+	write(`orders_v2: Path("/orders") -> <shunt>; search: Path("/search") -> <shunt>;`)
+	require.NoError(t, s.Reload())
+
+	contentType := "application/json "
+	p := h.payload(t)
+	require.True(t, strings.HasPrefix(p, contentType), p)
+	var r Regression
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(p, contentType)), &r))
+	assert.Equal(t, `1 pinned case(s) regressed (pinned: orders), route(s) 1 added, 1 removed`, r.Summary)
+	require.Len(t, r.Cases, 1)
+	assert.Equal(t, "orders", r.Cases[0].Case.Case.Name)
+	assert.Equal(t, "orders_v2", r.Cases[0].Case.RouteID)
+	assert.False(t, r.Cases[0].Case.Passed)
+	assert.Equal(t, "orders", r.Cases[0].Previous.RouteID)
+	assert.True(t, r.Cases[0].Previous.Passed)
+	assert.Equal(t, []matcher.RouteChange{
+		{ID: "orders", Change: matcher.RouteRemoved, Old: `Path("/orders") -> setPath("/v1/orders") -> <shunt>`},
+		{ID: "orders_v2", Change: matcher.RouteAdded, New: `Path("/orders") -> <shunt>`},
+	}, r.Routes)
+	assert.False(t, r.Time.IsZero())
+
+	// the first attempt failed, the first reloads weren't notified
+	h.mu.Lock()
+	assert.Equal(t, 2, h.attempts)
+	h.mu.Unlock()
+}
+
+func TestNotifyTemplate(t *testing.T) {
+	h, ts := newWebhook(0)
+	defer ts.Close()
+	tmpl, err := NotifyTemplate(`{"text": {{json .Summary}}, "cases": {{len .Cases}}}`)
+	require.NoError(t, err)
+	s, write, remove := pinnedServer(t, NotifyOptions{URL: ts.URL, Template: tmpl})
+	defer remove()
+
+	write(`orders: Path("/orders") -> <shunt>; search: Path("/search") -> <shunt>;`)
+	require.NoError(t, s.Reload())
+	write(`search: Path("/search") -> <shunt>;`)
+	require.NoError(t, s.Reload())
+
+	assert.Equal(t, `application/json {"text": "1 pinned case(s) regressed (pinned: orders), route(s) 1 removed", "cases": 1}`, h.payload(t))
+
+	_, err = NotifyTemplate(`{{json .Summary`)
+	assert.Error(t, err)
+}
+
+// syncBuffer a buffer written by the notifications
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestNotifyFailures(t *testing.T) {
+	h, ts := newWebhook(100)
+	defer ts.Close()
+	var log syncBuffer
+	s, write, remove := pinnedServer(t, NotifyOptions{URL: ts.URL, Retries: 2, Backoff: time.Millisecond, Log: &log})
+	defer remove()
+
+	write(`orders: Path("/orders") -> <shunt>; search: Path("/search") -> <shunt>;`)
+	require.NoError(t, s.Reload())
+	write(`search: Path("/search") -> <shunt>;`)
+	// the reload doesn't wait for the delivery
+	require.NoError(t, s.Reload())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for log.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "eskip-match: notification of "+ts.URL+" failed: status 502, after 3 attempt(s)\n", log.String())
+	h.mu.Lock()
+	assert.Equal(t, 3, h.attempts)
+	h.mu.Unlock()
+
+	// an unreachable webhook
+	n := newNotifier(NotifyOptions{URL: "http://127.0.0.1:1", Retries: -1, Timeout: time.Second})
+	assert.Error(t, n.send(&Regression{}))
+	assert.Nil(t, newNotifier(NotifyOptions{}))
+}
+
+func TestNotifyOrder(t *testing.T) {
+	h, ts := newWebhook(0)
+	defer ts.Close()
+	n := newNotifier(NotifyOptions{URL: ts.URL})
+	defer n.close()
+
+	for _, summary := range []string{"first", "second", "third"} {
+		n.notify(&Regression{Summary: summary})
+	}
+	for _, summary := range []string{"first", "second", "third"} {
+		var r Regression
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(h.payload(t), "application/json ")), &r))
+		assert.Equal(t, summary, r.Summary, "delivered in their order")
+	}
+}
+
+func TestNotifyClose(t *testing.T) {
+	h, ts := newWebhook(100)
+	defer ts.Close()
+	var log syncBuffer
+	s, write, remove := pinnedServer(t, NotifyOptions{URL: ts.URL, Backoff: time.Hour, Log: &log})
+	defer remove()
+
+	write(`orders: Path("/orders") -> <shunt>; search: Path("/search") -> <shunt>;`)
+	require.NoError(t, s.Reload())
+	write(`search: Path("/search") -> <shunt>;`)
+	require.NoError(t, s.Reload())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		h.mu.Lock()
+		attempts := h.attempts
+		h.mu.Unlock()
+		if attempts == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the worker waits an hour before the retry
+	s.Close()
+	select {
+	case <-s.notifier.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the retry wasn't interrupted")
+	}
+	assert.Equal(t, "eskip-match: notification of "+ts.URL+" failed: the server was closed, after 1 attempt(s)\n", log.String())
+
+	// nothing is delivered after close
+	s.notifier.notify(&Regression{})
+	h.mu.Lock()
+	assert.Equal(t, 1, h.attempts)
+	h.mu.Unlock()
+}
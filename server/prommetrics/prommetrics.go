@@ -0,0 +1,99 @@
+// Package prommetrics implements server.Metrics with the Prometheus client,
+// keeping the dependency out of the server package. The metrics are:
+//
+//	eskip_match_matches_total{outcome}       counter of the matches, outcome matched, unmatched or error
+//	eskip_match_match_duration_seconds       histogram of the match latency
+//	eskip_match_routes                       gauge of the valid routes in use
+//	eskip_match_reloads_total{result}        counter of the reloads, result success or failure
+//
+// The names are stable, new metrics get new names.
+package prommetrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rbarilani/eskip-match/server"
+)
+
+// namespace prefix of the metric names
+const namespace = "eskip_match"
+
+// Results of the reloads
+const (
+	reloadSuccess = "success"
+	reloadFailure = "failure"
+)
+
+// Metrics server.Metrics registered in a Prometheus registry of their own
+type Metrics struct {
+	registry *prometheus.Registry
+	matches  *prometheus.CounterVec
+	latency  prometheus.Histogram
+	routes   prometheus.Gauge
+	reloads  *prometheus.CounterVec
+}
+
+var _ server.Metrics = (*Metrics)(nil)
+
+// New creates the metrics, the outcome and result labels start at 0
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		matches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "matches_total",
+			Help:      "Number of matched requests by outcome: matched, unmatched or error.",
+		}, []string{"outcome"}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "match_duration_seconds",
+			Help:      "Duration of the matches.",
+			Buckets:   []float64{.00001, .000025, .00005, .0001, .00025, .0005, .001, .0025, .005, .01, .1},
+		}),
+		routes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "routes",
+			Help:      "Number of valid routes in use.",
+		}),
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reloads_total",
+			Help:      "Number of reloads of the routing table by result: success or failure.",
+		}, []string{"result"}),
+	}
+	m.registry.MustRegister(m.matches, m.latency, m.routes, m.reloads)
+	for _, outcome := range []string{server.OutcomeMatched, server.OutcomeUnmatched, server.OutcomeError} {
+		m.matches.WithLabelValues(outcome)
+	}
+	m.reloads.WithLabelValues(reloadSuccess)
+	m.reloads.WithLabelValues(reloadFailure)
+	return m
+}
+
+// ObserveMatch see server.Metrics
+func (m *Metrics) ObserveMatch(outcome string, d time.Duration) {
+	m.matches.WithLabelValues(outcome).Inc()
+	m.latency.Observe(d.Seconds())
+}
+
+// SetRoutes see server.Metrics
+func (m *Metrics) SetRoutes(n int) {
+	m.routes.Set(float64(n))
+}
+
+// ObserveReload see server.Metrics
+func (m *Metrics) ObserveReload(err error) {
+	if err != nil {
+		m.reloads.WithLabelValues(reloadFailure).Inc()
+		return
+	}
+	m.reloads.WithLabelValues(reloadSuccess).Inc()
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
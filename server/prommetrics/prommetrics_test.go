@@ -0,0 +1,65 @@
+package prommetrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := New()
+	var fail error
+	s := server.NewWithOptions(func() (*server.Table, error) {
+		if fail != nil {
+			return nil, fail
+		}
+		m, err := matcher.New(&matcher.Options{RoutesFile: "../testdata/routes.eskip", Hooks: server.MetricsHooks(metrics)})
+		if err != nil {
+			return nil, err
+		}
+		return &server.Table{Matcher: m}, nil
+	}, server.ServerOptions{Metrics: metrics})
+	require.NoError(t, s.Reload())
+	fail = errors.New("parse error")
+	assert.Error(t, s.Reload())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, body := range []string{
+		`{"path": "/search", "query": {"q": "shoes"}}`,
+		`{"path": "/orders", "host": "api.example.org"}`,
+		`{"path": "/missing"}`,
+		`{"path": "/%zz"}`,
+	} {
+		rsp, err := http.Post(ts.URL+"/match", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		rsp.Body.Close()
+	}
+
+	rsp, err := http.Get(ts.URL + "/metrics")
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	b, err := ioutil.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	scraped := string(b)
+	for _, line := range []string{
+		`eskip_match_matches_total{outcome="matched"} 2`,
+		`eskip_match_matches_total{outcome="unmatched"} 1`,
+		`eskip_match_matches_total{outcome="error"} 1`,
+		`eskip_match_match_duration_seconds_count 4`,
+		`eskip_match_routes 2`,
+		`eskip_match_reloads_total{result="success"} 1`,
+		`eskip_match_reloads_total{result="failure"} 1`,
+	} {
+		assert.Contains(t, scraped, line+"\n")
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+// EskipContentType content type of the eskip documents pushed to /-/reload
+const EskipContentType = "text/eskip"
+
+// PushedDocument file name of the locations and of the errors of the routes
+// pushed to /-/reload
+const PushedDocument = "body"
+
+// maxPushedDocument size limit of the documents pushed to /-/reload
+const maxPushedDocument = 64 << 20
+
+// reloadData body of the /-/reload response
+type reloadData struct {
+	// Routes number of valid routes of the new routing table
+	Routes int `json:"routes"`
+}
+
+// reloadErrorData body of the /-/reload response when the routes can't be
+// loaded
+type reloadErrorData struct {
+	Error string `json:"error"`
+	// Findings the positioned errors, the ones of a pushed document are in
+	// the file "body"
+	Findings []matcher.Finding `json:"findings,omitempty"`
+}
+
+// reload answers POST /-/reload: without body the routing table is loaded
+// again with the Loader, with an eskip document it's created from the
+// document with ServerOptions.LoadDocument
+func (s *Server) reload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	if s.fixed {
+		writeJSON(w, http.StatusNotImplemented, errorData{"the routes of the matcher can't be reloaded"})
+		return
+	}
+	if s.reloadToken != "" && !validBearer(r, s.reloadToken) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="eskip-match"`)
+		writeJSON(w, http.StatusUnauthorized, errorData{"missing or invalid bearer token"})
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPushedDocument))
+	if err != nil {
+		writeJSON(w, http.StatusRequestEntityTooLarge, errorData{"invalid body: " + err.Error()})
+		return
+	}
+	load := s.load
+	if len(body) > 0 {
+		contentType := r.Header.Get("Content-Type")
+		if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != EskipContentType {
+			writeJSON(w, http.StatusUnsupportedMediaType, errorData{fmt.Sprintf("unsupported content type %q, push the routes as %s", contentType, EskipContentType)})
+			return
+		}
+		if s.loadDocument == nil {
+			writeJSON(w, http.StatusNotImplemented, errorData{"pushing routes isn't enabled"})
+			return
+		}
+		load = func() (*Table, error) {
+			return s.loadDocument(body)
+		}
+	}
+
+	if err := s.reloadWith(load); err != nil {
+		if e, ok := err.(*matcher.ParseError); ok && len(body) > 0 {
+			// positioned in the body, not in the file LoadDocument created
+			c := *e
+			c.File = PushedDocument
+			err = &c
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, reloadErrorData{Error: err.Error(), Findings: loadErrorFindings(err, body)})
+		return
+	}
+	writeJSON(w, http.StatusOK, reloadData{Routes: s.current().Matcher.RouteCount()})
+}
+
+// validBearer true if the request has the bearer token
+func validBearer(r *http.Request, token string) bool {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), []byte(token)) == 1
+}
+
+// loadErrorFindings the positioned errors of a failed load, the routes of
+// a pushed document are located in it
+func loadErrorFindings(err error, doc []byte) []matcher.Finding {
+	var locations map[string]matcher.Location
+	if len(doc) > 0 {
+		locations = matcher.RouteLocations(PushedDocument, doc)
+	}
+	route := func(rule, id, message string) matcher.Finding {
+		f := matcher.Finding{RuleID: rule, Severity: matcher.SeverityError, RouteID: id, Message: message}
+		if loc, ok := locations[id]; ok {
+			f.File, f.Line = loc.File, loc.Line
+		}
+		return f
+	}
+
+	var findings []matcher.Finding
+	switch e := err.(type) {
+	case *matcher.ParseError:
+		message := e.Reason
+		if e.Token != "" {
+			message = fmt.Sprintf("%s near %q", e.Reason, e.Token)
+		}
+		findings = append(findings, matcher.Finding{RuleID: matcher.RuleParse, Severity: matcher.SeverityError, File: e.File, Line: e.Line, Column: e.Column, Message: message})
+	case *matcher.UnknownPredicatesError:
+		for _, name := range sortedKeys(e.Routes) {
+			for _, id := range e.Routes[name] {
+				findings = append(findings, route(matcher.RuleUnknownPredicate, id, "unknown predicate "+name))
+			}
+		}
+	case *matcher.MissingFiltersError:
+		for _, name := range sortedKeys(e.Routes) {
+			for _, id := range e.Routes[name] {
+				findings = append(findings, route(matcher.RuleUnknownFilter, id, "unknown filter "+name))
+			}
+		}
+	case *matcher.RouteLoadErrors:
+		for _, le := range e.Errors {
+			message := le.Err.Error()
+			if le.Filter != "" {
+				message = fmt.Sprintf("filter %s: %v", le.Filter, le.Err)
+			}
+			findings = append(findings, route(le.Rule, le.RouteID, message))
+		}
+	}
+	return findings
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadDocument creates a table from a pushed document like the serve command
+func loadDocument(doc []byte) (*Table, error) {
+	f, err := ioutil.TempFile("", "pushed-*.eskip")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	f.Write(doc)
+	f.Close()
+	m, err := matcher.New(&matcher.Options{RoutesFile: f.Name(), FailOnLoadErrors: true})
+	if err != nil {
+		return nil, err
+	}
+	return &Table{Matcher: m, Locations: matcher.RouteLocations(PushedDocument, doc)}, nil
+}
+
+// push posts a document to /-/reload
+func push(t *testing.T, url, contentType, doc string, headers map[string]string) (int, string) {
+	req, err := http.NewRequest("POST", url+"/-/reload", strings.NewReader(doc))
+	require.NoError(t, err)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	b, err := ioutil.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	return rsp.StatusCode, string(b)
+}
+
+// matchedRoute the id of the route matching the path, empty without match
+func matchedRoute(t *testing.T, url, path string) string {
+	status, body := call(t, "POST", url+"/match", `{"path": "`+path+`"}`)
+	require.Equal(t, http.StatusOK, status, body)
+	var res matcher.ResultData
+	require.NoError(t, json.Unmarshal([]byte(body), &res))
+	return res.RouteID
+}
+
+func TestReloadPush(t *testing.T) {
+	s := NewWithOptions(loadTable, ServerOptions{LoadDocument: loadDocument})
+	require.NoError(t, s.Reload())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	status, body := push(t, ts.URL, "text/eskip; charset=utf-8", `pushed: Path("/pushed") -> <shunt>;`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"routes": 1}`, body)
+	assert.Equal(t, "pushed", matchedRoute(t, ts.URL, "/pushed"))
+	assert.Equal(t, "", matchedRoute(t, ts.URL, "/search"))
+	status, body = call(t, "GET", ts.URL+"/routes/pushed", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"location":{"file":"body","line":1}`)
+
+	// the table in use is kept
+	status, body = push(t, ts.URL, "text/eskip", "ok: Path(\"/ok\") -> <shunt>;\nbroken: Path(\"/broken\") -> ;", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.JSONEq(t, `{"error": "body:2:28: syntax error near \";\"", "findings": [
+		{"rule": "parse", "severity": "error", "file": "body", "line": 2, "column": 28, "message": "syntax error near \";\""}]}`, body)
+	assert.Equal(t, "pushed", matchedRoute(t, ts.URL, "/pushed"))
+
+	status, body = push(t, ts.URL, "text/eskip", "ok: Path(\"/ok\") -> <shunt>;\n\ncustom: Path(\"/custom\") -> customFilter() -> <shunt>;", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	var res reloadErrorData
+	require.NoError(t, json.Unmarshal([]byte(body), &res))
+	assert.Equal(t, []matcher.Finding{{RuleID: matcher.RuleUnknownFilter, Severity: matcher.SeverityError, RouteID: "custom", File: "body", Line: 3, Message: "unknown filter customFilter"}}, res.Findings)
+
+	status, body = push(t, ts.URL, "text/eskip", `status: Path("/status") -> status("teapot") -> <shunt>;`, nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	require.NoError(t, json.Unmarshal([]byte(body), &res))
+	require.Len(t, res.Findings, 1)
+	assert.Equal(t, matcher.RuleInvalidFilterArgs, res.Findings[0].RuleID)
+	assert.Equal(t, 1, res.Findings[0].Line)
+
+	assert.Equal(t, "pushed", matchedRoute(t, ts.URL, "/pushed"))
+	status, body = call(t, "GET", ts.URL+"/healthz", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"reloadError"`)
+}
+
+func TestReloadSources(t *testing.T) {
+	loads := 0
+	s := New(func() (*Table, error) {
+		loads++
+		return loadTable()
+	})
+	require.NoError(t, s.Reload())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	status, body := push(t, ts.URL, "", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"routes": 2}`, body)
+	assert.Equal(t, 2, loads)
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"method", "GET", "", "", http.StatusMethodNotAllowed},
+		{"content type", "POST", "application/json", `{}`, http.StatusUnsupportedMediaType},
+		{"pushes not enabled", "POST", "text/eskip", `a: * -> <shunt>;`, http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, ts.URL+"/-/reload", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tt.contentType)
+			rsp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			rsp.Body.Close()
+			assert.Equal(t, tt.status, rsp.StatusCode)
+		})
+	}
+	assert.Equal(t, 2, loads)
+}
+
+func TestReloadDebugServer(t *testing.T) {
+	table, err := loadTable()
+	require.NoError(t, err)
+	defer table.Matcher.Close()
+	ts := httptest.NewServer(NewDebugServer(table.Matcher, ServerOptions{LoadDocument: loadDocument}))
+	defer ts.Close()
+
+	status, _ := push(t, ts.URL, "", "", nil)
+	assert.Equal(t, http.StatusNotImplemented, status)
+	status, _ = push(t, ts.URL, "text/eskip", `a: * -> <shunt>;`, nil)
+	assert.Equal(t, http.StatusNotImplemented, status)
+	status, _ = call(t, "GET", ts.URL+"/routes/search", "")
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestReloadToken(t *testing.T) {
+	s := NewWithOptions(loadTable, ServerOptions{ReloadToken: "s3cret", LoadDocument: loadDocument})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, auth := range []string{"", "Bearer wrong", "Basic s3cret", "Bearer s3cret2"} {
+		status, _ := push(t, ts.URL, "", "", map[string]string{"Authorization": auth})
+		assert.Equal(t, http.StatusUnauthorized, status, auth)
+	}
+	status, _ := push(t, ts.URL, "", "", map[string]string{"Authorization": "bearer s3cret"})
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = push(t, ts.URL, "text/eskip", `a: * -> <shunt>;`, map[string]string{"Authorization": "Bearer s3cret"})
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestReloadConcurrentMatches(t *testing.T) {
+	s := NewWithOptions(loadTable, ServerOptions{LoadDocument: loadDocument})
+	require.NoError(t, s.Reload())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	docs := []string{
+		`first: Path("/route") -> <shunt>;`,
+		`second: Path("/route") -> <shunt>;`,
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// every match is answered by a whole table
+				id := matchedRoute(t, ts.URL, "/route")
+				mu.Lock()
+				seen[id]++
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		status, body := push(t, ts.URL, "text/eskip", docs[i%2], nil)
+		require.Equal(t, http.StatusOK, status, body)
+	}
+	close(stop)
+	wg.Wait()
+
+	for id := range seen {
+		assert.Contains(t, []string{"", "first", "second"}, id)
+	}
+	assert.Equal(t, "second", matchedRoute(t, ts.URL, "/route"))
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rbarilani/eskip-match/schema"
+)
+
+// SchemaContentType content type of the /schemas responses
+const SchemaContentType = "application/schema+json"
+
+// schemas answers GET /schemas with the names of the schemas and
+// GET /schemas/{name}.json with the schema, eg. /schemas/fixture.json
+func (s *Server) schemas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	if r.URL.Path == "/schemas" || r.URL.Path == "/schemas/" {
+		paths := []string{}
+		for _, name := range schema.Names() {
+			paths = append(paths, "/schemas/"+name+".json")
+		}
+		writeJSON(w, http.StatusOK, paths)
+		return
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/schemas/"), ".json")
+	doc := schema.Get(name)
+	if doc == nil {
+		writeJSON(w, http.StatusNotFound, errorData{"schema " + name + " not found"})
+		return
+	}
+	w.Header().Set("Content-Type", SchemaContentType)
+	w.Write(doc)
+}
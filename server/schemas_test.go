@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemas(t *testing.T) {
+	m, err := matcher.New(&matcher.Options{RoutesFile: routesFile})
+	require.NoError(t, err)
+	defer m.Close()
+	ts := httptest.NewServer(NewDebugServer(m, ServerOptions{}))
+	defer ts.Close()
+
+	rsp, err := http.Get(ts.URL + "/schemas/fixture.json")
+	require.NoError(t, err)
+	rsp.Body.Close()
+	assert.Equal(t, SchemaContentType, rsp.Header.Get("Content-Type"))
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		want   string
+	}{
+		{method: "GET", path: "/schemas", status: http.StatusOK, want: `["/schemas/fixture.json", "/schemas/suite-result.json", "/schemas/test-result.json"]`},
+		{method: "GET", path: "/schemas/fixture.json", status: http.StatusOK, want: string(schema.FixtureSchema())},
+		{method: "GET", path: "/schemas/test-result.json", status: http.StatusOK, want: string(schema.TestResultSchema())},
+		{method: "GET", path: "/schemas/suite-result", status: http.StatusOK, want: string(schema.SuiteResultSchema())},
+		{method: "GET", path: "/schemas/missing.json", status: http.StatusNotFound, want: `{"error": "schema missing not found"}`},
+		{method: "POST", path: "/schemas/fixture.json", status: http.StatusMethodNotAllowed, want: `{"error": "method not allowed, use GET"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			status, body := call(t, tt.method, ts.URL+tt.path, "")
+			assert.Equal(t, tt.status, status)
+			assert.JSONEq(t, tt.want, body)
+		})
+	}
+}
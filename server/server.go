@@ -0,0 +1,407 @@
+// Package server exposes a matcher over a small HTTP API, so that tools not
+// written in Go can query the routing behavior of a routes file:
+//
+//	POST /match             request attributes as json body, returns the match result
+//	GET  /match/forwarded   matches the original request forwarded as headers
+//	GET  /routes            lists the loaded routes
+//	GET  /routes/{id}       returns the route with the id
+//	GET  /healthz           reports if the routes are loaded
+//	GET  /events            streams the reloads as server-sent events
+//	GET  /metrics           the metrics, with ServerOptions.Metrics
+//	POST /-/reload          reloads the routes, or loads the eskip document of the body
+//	GET  /schemas/{name}    the JSON Schema of the fixture files or of the results, see package schema
+//
+// The body of /match uses the matcher.RequestAttributes schema, eg.:
+//
+//	{"method": "GET", "path": "/v1/orders", "host": "api.example.org", "headers": {"Accept": "application/json"}}
+//
+// POST /match?explain=true adds the closest routes not matching the request
+// to the result, as nearMisses. /match/forwarded answers the auth requests of
+// the gateways, eg. nginx auth_request, see ForwardedHeaders. /events sends
+// a snapshot of the state first, then an Event for every reload and for
+// every change of the outcome of the ServerOptions.Pinned cases. /-/reload
+// answers 422 with the positioned errors when the routes can't be loaded,
+// the routes in use are kept.
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/suite"
+	"github.com/zalando/skipper/eskip"
+)
+
+// Table a matcher together with the routes it was created from
+type Table struct {
+	// Matcher tests the requests
+	Matcher matcher.Matcher
+	// Routes the routes loaded by the matcher, the ones of Matcher.Routes
+	// when nil
+	Routes []*eskip.Route
+	// Locations where the routes are defined by id, optional
+	Locations map[string]matcher.Location
+}
+
+// Loader creates a routing table, called on every reload
+type Loader func() (*Table, error)
+
+// DefaultNearMisses default number of near misses returned by /match?explain=true
+const DefaultNearMisses = 3
+
+// ServerOptions options of NewWithOptions and NewDebugServer
+type ServerOptions struct {
+	// Locations where the routes of NewDebugServer are defined by id,
+	// optional, the loaders set the ones of their tables
+	Locations map[string]matcher.Location
+	// NearMisses number of near misses returned by /match?explain=true,
+	// DefaultNearMisses when 0, all of them when < 0
+	NearMisses int
+	// Forwarded headers of the original request read by /match/forwarded,
+	// the defaults for the empty names
+	Forwarded ForwardedHeaders
+	// Metrics records the reloads and serves /metrics, none when nil. The
+	// matches are recorded by the MetricsHooks of the matchers.
+	Metrics Metrics
+	// Pinned suites run after every successful reload, /events streams the
+	// cases whose matching route or outcome changed
+	Pinned []*suite.Suite
+	// EventBuffer number of events buffered for each /events client,
+	// DefaultEventBuffer when 0. The events of a client with a full buffer
+	// are dropped, the reloads never wait for the clients.
+	EventBuffer int
+	// LoadDocument creates a routing table from the eskip document pushed
+	// to /-/reload, the pushes are refused when nil
+	LoadDocument func(doc []byte) (*Table, error)
+	// ReloadToken bearer token /-/reload requires, none when empty
+	ReloadToken string
+	// Notify the webhook posted the Pinned cases a reload made fail, eg.
+	// when a save of a watched routes file breaks them
+	Notify NotifyOptions
+}
+
+// Server http handler answering the match debugging API
+type Server struct {
+	load       Loader
+	mux        *http.ServeMux
+	nearMisses int
+	forwarded  ForwardedHeaders
+	metrics    Metrics
+	pinned     []*suite.Suite
+	broker     *eventBroker
+	notifier   *notifier
+
+	loadDocument func(doc []byte) (*Table, error)
+	reloadToken  string
+	// fixed the matcher of NewDebugServer, never reloaded by /-/reload
+	fixed bool
+	// reloadMu serializes the reloads
+	reloadMu sync.Mutex
+
+	mu        sync.RWMutex
+	table     *Table
+	reloadErr error
+	// cases the results of the pinned cases with the table
+	cases []suite.CaseResult
+	// pinnedRoutes the routes the pinned cases ran against
+	pinnedRoutes []*eskip.Route
+}
+
+// New creates a server loading its routing table with load,
+// nothing is loaded until Reload is called
+func New(load Loader) *Server {
+	return NewWithOptions(load, ServerOptions{})
+}
+
+// NewWithOptions like New with the options
+func NewWithOptions(load Loader, opts ServerOptions) *Server {
+	s := &Server{load: load, mux: http.NewServeMux(), nearMisses: DefaultNearMisses, forwarded: opts.Forwarded.withDefaults(), metrics: opts.Metrics,
+		pinned: opts.Pinned, broker: newEventBroker(opts.EventBuffer),
+		loadDocument: opts.LoadDocument, reloadToken: opts.ReloadToken, notifier: newNotifier(opts.Notify)}
+	switch {
+	case opts.NearMisses > 0:
+		s.nearMisses = opts.NearMisses
+	case opts.NearMisses < 0:
+		s.nearMisses = 0
+	}
+	s.mux.HandleFunc("/match", s.match)
+	s.mux.HandleFunc("/match/forwarded", s.matchForwarded)
+	s.mux.HandleFunc("/routes", s.routes)
+	s.mux.HandleFunc("/routes/", s.route)
+	s.mux.HandleFunc("/healthz", s.healthz)
+	s.mux.HandleFunc("/events", s.events)
+	s.mux.HandleFunc("/-/reload", s.reload)
+	s.mux.HandleFunc("/schemas", s.schemas)
+	s.mux.HandleFunc("/schemas/", s.schemas)
+	if s.metrics != nil {
+		s.mux.Handle("/metrics", s.metrics.Handler())
+	}
+	return s
+}
+
+// NewDebugServer creates a handler answering the match debugging API for
+// the matcher, to embed it in another server or a test. The matcher isn't
+// closed by the handler, a lazy matcher is loaded by the first match.
+func NewDebugServer(m matcher.Matcher, opts ServerOptions) http.Handler {
+	s := NewWithOptions(func() (*Table, error) {
+		return &Table{Matcher: m, Locations: opts.Locations}, nil
+	}, opts)
+	s.fixed = true
+	s.Reload()
+	return s
+}
+
+// Reload loads the routing table again and swaps it with the current one,
+// closing its matcher. On failure the current one keeps being used and
+// the error is reported by /healthz
+func (s *Server) Reload() error {
+	return s.reloadWith(s.load)
+}
+
+// reloadWith swaps the routing table with the one of load, see Reload
+func (s *Server) reloadWith(load Loader) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	s.publish(Event{Type: EventReloadStart})
+	t, err := load()
+	if s.metrics != nil {
+		s.metrics.ObserveReload(err)
+		if err == nil {
+			s.metrics.SetRoutes(t.Matcher.RouteCount())
+		}
+	}
+
+	s.mu.Lock()
+	s.reloadErr = err
+	if err == nil {
+		if s.table != nil {
+			s.table.Matcher.Close()
+		}
+		s.table = t
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		s.publish(Event{Type: EventReloadFailure, Error: err.Error()})
+		return err
+	}
+	s.publish(Event{Type: EventReloadSuccess})
+	s.runPinned(t)
+	return nil
+}
+
+// current returns the routing table in use, nil if never loaded
+func (s *Server) current() *Table {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.table
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// errorData body of the error responses
+type errorData struct {
+	Error string `json:"error"`
+}
+
+// matchData body of the /match response with explain
+type matchData struct {
+	*matcher.ResultData
+	// NearMisses the closest routes not matching, the closest one first
+	NearMisses []matcher.NearMiss `json:"nearMisses"`
+}
+
+// healthData body of the /healthz response
+type healthData struct {
+	// Status ready or loading
+	Status string `json:"status"`
+	// Routes number of valid routes in use
+	Routes int `json:"routes"`
+	// ReloadError why the last reload failed, the previous routes are still used
+	ReloadError string `json:"reloadError,omitempty"`
+}
+
+func (s *Server) match(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	t := s.current()
+	if t == nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorData{"routes not loaded"})
+		return
+	}
+
+	explain := false
+	if v := r.URL.Query().Get("explain"); v != "" {
+		var err error
+		if explain, err = strconv.ParseBool(v); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorData{"invalid explain parameter " + strconv.Quote(v)})
+			return
+		}
+	}
+	var attrs matcher.RequestAttributes
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&attrs); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorData{"invalid request attributes: " + err.Error()})
+		return
+	}
+	res, t, ok := s.test(w, t, &attrs)
+	if !ok {
+		return
+	}
+	if !explain {
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+	writeJSON(w, http.StatusOK, matchData{
+		ResultData: matcher.NewResultData(res),
+		NearMisses: t.Matcher.Explain(&attrs, s.nearMisses),
+	})
+}
+
+// test tests the attributes against the table, the table in use when it
+// was swapped meanwhile is returned too. When the attributes can't be tested
+// the error is written and false returned.
+func (s *Server) test(w http.ResponseWriter, t *Table, attrs *matcher.RequestAttributes) (matcher.TestResult, *Table, bool) {
+	res := t.Matcher.Test(attrs)
+	if errors.Is(res.Err(), matcher.ErrClosed) {
+		// the table was swapped and closed by a reload meanwhile
+		t = s.current()
+		res = t.Matcher.Test(attrs)
+	}
+	if err := res.Err(); err != nil && !errors.Is(err, matcher.ErrClosed) {
+		// the client knows the request, the cause is enough
+		var me *matcher.MatchError
+		if errors.As(err, &me) {
+			err = me.Err
+		}
+		// a lazy matcher failing to load
+		if ready, _ := t.Matcher.Ready(); !ready {
+			writeJSON(w, http.StatusServiceUnavailable, errorData{"routes not loaded: " + err.Error()})
+			return nil, t, false
+		}
+		writeJSON(w, http.StatusBadRequest, errorData{"invalid request attributes: " + err.Error()})
+		return nil, t, false
+	}
+	return res, t, true
+}
+
+func (s *Server) routes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	t := s.current()
+	if t == nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorData{"routes not loaded"})
+		return
+	}
+
+	routes := t.routes()
+	data := make([]*matcher.RouteData, 0, len(routes))
+	for _, route := range routes {
+		data = append(data, t.routeData(route))
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	t := s.current()
+	if t == nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorData{"routes not loaded"})
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/routes/")
+	for _, route := range t.routes() {
+		if route.Id == id {
+			writeJSON(w, http.StatusOK, t.routeData(route))
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, errorData{"route " + id + " not found"})
+}
+
+// routes the routes of the table
+func (t *Table) routes() []*eskip.Route {
+	if t.Routes == nil {
+		return t.Matcher.Routes()
+	}
+	return t.Routes
+}
+
+// routeData the serializable representation of a route with its location
+func (t *Table) routeData(route *eskip.Route) *matcher.RouteData {
+	data := matcher.NewRouteData(route)
+	if loc, ok := t.Locations[route.Id]; ok {
+		data.Location = &loc
+	}
+	return data
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	s.mu.RLock()
+	t, reloadErr := s.table, s.reloadErr
+	s.mu.RUnlock()
+
+	health := healthData{Status: "ready"}
+	if reloadErr != nil {
+		health.ReloadError = reloadErr.Error()
+	}
+	if t == nil {
+		health.Status = "loading"
+		writeJSON(w, http.StatusServiceUnavailable, health)
+		return
+	}
+	// a lazy matcher may still be loading, a routing may fail loading updates
+	ready, loadErr := t.Matcher.Ready()
+	if loadErr != nil && health.ReloadError == "" {
+		health.ReloadError = loadErr.Error()
+	}
+	health.Routes = t.Matcher.RouteCount()
+	if !ready {
+		health.Status = "loading"
+		writeJSON(w, http.StatusServiceUnavailable, health)
+		return
+	}
+	writeJSON(w, http.StatusOK, health)
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeJSON(w, http.StatusMethodNotAllowed, errorData{"method not allowed, use " + allowed})
+}
+
+// writeJSON writes v as json, backend placeholders like <shunt> are kept as they are
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
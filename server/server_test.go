@@ -0,0 +1,320 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/skipper/eskip"
+)
+
+const routesFile = "testdata/routes.eskip"
+
+func loadTable() (*Table, error) {
+	b, err := ioutil.ReadFile(routesFile)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := eskip.Parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+	m, err := matcher.New(&matcher.Options{RoutesFile: routesFile})
+	if err != nil {
+		return nil, err
+	}
+	return &Table{Matcher: m, Routes: routes, Locations: matcher.RouteLocations(routesFile, b)}, nil
+}
+
+// call sends a request to the test server and returns status code and body
+func call(t *testing.T, method, url, body string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	rsp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	b, err := ioutil.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	return rsp.StatusCode, string(b)
+}
+
+func TestServer(t *testing.T) {
+	s := New(loadTable)
+	require.NoError(t, s.Reload())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{
+			name:   "match",
+			method: "POST",
+			path:   "/match",
+			body:   `{"method": "GET", "path": "/orders", "host": "api.example.org"}`,
+			status: http.StatusOK,
+			want: `{"matched": true, "routeId": "orders", "backendType": "network", "backend": "https://orders.example.org",
+				"request": {"method": "GET", "path": "/orders", "host": "api.example.org"}}`,
+		},
+		{
+			name:   "no match",
+			method: "POST",
+			path:   "/match",
+			body:   `{"path": "/search"}`,
+			status: http.StatusOK,
+			want:   `{"matched": false, "request": {"method": "GET", "path": "/search"}}`,
+		},
+		{
+			name:   "invalid body",
+			method: "POST",
+			path:   "/match",
+			body:   `{"route": "orders"}`,
+			status: http.StatusBadRequest,
+			want:   `{"error": "invalid request attributes: json: unknown field \"route\""}`,
+		},
+		{
+			name:   "match method not allowed",
+			method: "GET",
+			path:   "/match",
+			status: http.StatusMethodNotAllowed,
+			want:   `{"error": "method not allowed, use POST"}`,
+		},
+		{
+			name:   "routes",
+			method: "GET",
+			path:   "/routes",
+			status: http.StatusOK,
+			want: `[
+				{"id": "orders", "location": {"file": "testdata/routes.eskip", "line": 1},
+				 "predicates": ["Path(\"/orders\")", "Host(\"^api[.]example[.]org$\")"],
+				 "backendType": "network", "backend": "https://orders.example.org"},
+				{"id": "search", "location": {"file": "testdata/routes.eskip", "line": 2},
+				 "predicates": ["Path(\"/search\")", "QueryParam(\"q\")"],
+				 "backendType": "shunt", "backend": "<shunt>"}
+			]`,
+		},
+		{
+			name:   "healthz",
+			method: "GET",
+			path:   "/healthz",
+			status: http.StatusOK,
+			want:   `{"status": "ready", "routes": 2}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := call(t, tt.method, ts.URL+tt.path, tt.body)
+			assert.Equal(t, tt.status, status)
+			assert.JSONEq(t, tt.want, body)
+		})
+	}
+}
+
+func TestServerReload(t *testing.T) {
+	var fail error
+	loads := 0
+	s := New(func() (*Table, error) {
+		loads++
+		if fail != nil {
+			return nil, fail
+		}
+		return loadTable()
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	status, body := call(t, "GET", ts.URL+"/healthz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.JSONEq(t, `{"status": "loading", "routes": 0}`, body)
+
+	status, _ = call(t, "POST", ts.URL+"/match", `{"path": "/orders"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	require.NoError(t, s.Reload())
+	fail = errors.New("parse error")
+	assert.Equal(t, fail, s.Reload())
+
+	// the previous routes are still used
+	status, body = call(t, "GET", ts.URL+"/healthz", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"status": "ready", "routes": 2, "reloadError": "parse error"}`, body)
+
+	status, body = call(t, "POST", ts.URL+"/match", `{"path": "/search", "query": {"q": "shoes"}}`)
+	assert.Equal(t, http.StatusOK, status)
+	var res matcher.ResultData
+	require.NoError(t, json.Unmarshal([]byte(body), &res))
+	assert.Equal(t, "search", res.RouteID)
+	assert.Equal(t, 2, loads)
+
+	// a successful reload closes the previous matcher
+	fail = nil
+	previous := s.current().Matcher
+	require.NoError(t, s.Reload())
+	assert.True(t, errors.Is(previous.Test(&matcher.RequestAttributes{Path: "/orders"}).Err(), matcher.ErrClosed))
+	status, body = call(t, "POST", ts.URL+"/match", `{"path": "/search", "query": {"q": "shoes"}}`)
+	assert.Equal(t, http.StatusOK, status)
+	require.NoError(t, json.Unmarshal([]byte(body), &res))
+	assert.Equal(t, "search", res.RouteID)
+}
+
+func TestServerHealthzLazyMatcher(t *testing.T) {
+	s := New(func() (*Table, error) {
+		m, err := matcher.New(&matcher.Options{RoutesFile: routesFile, Lazy: true})
+		if err != nil {
+			return nil, err
+		}
+		return &Table{Matcher: m}, nil
+	})
+	require.NoError(t, s.Reload())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// the routes are loaded by the first match
+	status, body := call(t, "GET", ts.URL+"/healthz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.JSONEq(t, `{"status": "loading", "routes": 0}`, body)
+
+	status, _ = call(t, "POST", ts.URL+"/match", `{"path": "/orders"}`)
+	assert.Equal(t, http.StatusOK, status)
+	status, body = call(t, "GET", ts.URL+"/healthz", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"status": "ready", "routes": 2}`, body)
+}
+
+func TestDebugServer(t *testing.T) {
+	m, err := matcher.New(&matcher.Options{RoutesFile: routesFile})
+	require.NoError(t, err)
+	defer m.Close()
+	b, err := ioutil.ReadFile(routesFile)
+	require.NoError(t, err)
+	ts := httptest.NewServer(NewDebugServer(m, ServerOptions{Locations: matcher.RouteLocations(routesFile, b), NearMisses: 1}))
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{
+			name:   "match",
+			method: "POST",
+			path:   "/match",
+			body:   `{"path": "/search", "query": {"q": "shoes"}}`,
+			status: http.StatusOK,
+			want:   `{"matched": true, "routeId": "search", "backendType": "shunt", "backend": "<shunt>", "request": {"method": "GET", "path": "/search", "query": {"q": "shoes"}}}`,
+		},
+		{
+			name:   "explain",
+			method: "POST",
+			path:   "/match?explain=true",
+			body:   `{"path": "/orders"}`,
+			status: http.StatusOK,
+			want: `{"matched": false, "request": {"method": "GET", "path": "/orders"}, "nearMisses": [
+				{"routeId": "orders", "failed": 1, "verdicts": [
+					{"name": "Path", "predicate": "Path(\"/orders\")", "matched": true, "observed": "/orders"},
+					{"name": "Host", "predicate": "Host(\"^api[.]example[.]org$\")", "matched": false}
+				]}
+			]}`,
+		},
+		{
+			name:   "invalid explain",
+			method: "POST",
+			path:   "/match?explain=maybe",
+			body:   `{"path": "/orders"}`,
+			status: http.StatusBadRequest,
+			want:   `{"error": "invalid explain parameter \"maybe\""}`,
+		},
+		{
+			name:   "invalid attributes",
+			method: "POST",
+			path:   "/match",
+			body:   `{"path": 42}`,
+			status: http.StatusBadRequest,
+			want:   `{"error": "invalid request attributes: json: cannot unmarshal number into Go struct field RequestAttributes.path of type string"}`,
+		},
+		{
+			name:   "route",
+			method: "GET",
+			path:   "/routes/search",
+			status: http.StatusOK,
+			want: `{"id": "search", "location": {"file": "testdata/routes.eskip", "line": 2},
+				"predicates": ["Path(\"/search\")", "QueryParam(\"q\")"],
+				"backendType": "shunt", "backend": "<shunt>"}`,
+		},
+		{
+			name:   "unknown route",
+			method: "GET",
+			path:   "/routes/missing",
+			status: http.StatusNotFound,
+			want:   `{"error": "route missing not found"}`,
+		},
+		{
+			name:   "route method not allowed",
+			method: "DELETE",
+			path:   "/routes/search",
+			status: http.StatusMethodNotAllowed,
+			want:   `{"error": "method not allowed, use GET"}`,
+		},
+		{
+			name:   "healthz",
+			method: "GET",
+			path:   "/healthz",
+			status: http.StatusOK,
+			want:   `{"status": "ready", "routes": 2}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := call(t, tt.method, ts.URL+tt.path, tt.body)
+			assert.Equal(t, tt.status, status)
+			assert.JSONEq(t, tt.want, body)
+		})
+	}
+
+	// the routes are listed without a Table.Routes
+	status, body := call(t, "GET", ts.URL+"/routes", "")
+	assert.Equal(t, http.StatusOK, status)
+	var routes []matcher.RouteData
+	require.NoError(t, json.Unmarshal([]byte(body), &routes))
+	assert.Len(t, routes, 2)
+}
+
+func TestDebugServerLazyMatcher(t *testing.T) {
+	f, err := ioutil.TempFile("", "invalid-*.eskip")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`broken: Path("/") -> `)
+	f.Close()
+	m, err := matcher.New(&matcher.Options{RoutesFile: f.Name(), Lazy: true})
+	require.NoError(t, err)
+	defer m.Close()
+	ts := httptest.NewServer(NewDebugServer(m, ServerOptions{}))
+	defer ts.Close()
+
+	status, body := call(t, "GET", ts.URL+"/healthz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.JSONEq(t, `{"status": "loading", "routes": 0}`, body)
+
+	// the routes file fails loading
+	status, body = call(t, "POST", ts.URL+"/match", `{"path": "/orders"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Contains(t, body, "routes not loaded: ")
+}
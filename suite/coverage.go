@@ -0,0 +1,60 @@
+package suite
+
+import (
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/zalando/skipper/eskip"
+)
+
+// Coverage which routes are hit by the cases of a run
+type Coverage struct {
+	// Total number of routes
+	Total int `json:"total"`
+	// Percent of routes hit by at least one case, 100 when there aren't routes
+	Percent float64 `json:"percent"`
+	// Covered routes hit by at least one case
+	Covered []RouteCoverage `json:"covered"`
+	// Uncovered routes not hit by any case
+	Uncovered []RouteCoverage `json:"uncovered"`
+}
+
+// RouteCoverage coverage of a single route
+type RouteCoverage struct {
+	// ID of the route
+	ID string `json:"id"`
+	// Location where the route is defined
+	Location matcher.Location `json:"location"`
+	// Hits number of cases matching the route
+	Hits int `json:"hits"`
+}
+
+// NewCoverage computes the coverage of routes given the hits of a run
+// (see RunOptions.Coverage), routes are kept in the given order and
+// locations are used to tell where they are defined
+func NewCoverage(routes []*eskip.Route, locations map[string]matcher.Location, hits map[string]int) *Coverage {
+	c := &Coverage{
+		Covered:   []RouteCoverage{},
+		Uncovered: []RouteCoverage{},
+	}
+
+	seen := make(map[string]bool)
+	for _, r := range routes {
+		if seen[r.Id] {
+			continue
+		}
+		seen[r.Id] = true
+
+		rc := RouteCoverage{ID: r.Id, Location: locations[r.Id], Hits: hits[r.Id]}
+		if rc.Hits > 0 {
+			c.Covered = append(c.Covered, rc)
+		} else {
+			c.Uncovered = append(c.Uncovered, rc)
+		}
+	}
+
+	c.Total = len(c.Covered) + len(c.Uncovered)
+	c.Percent = 100
+	if c.Total > 0 {
+		c.Percent = float64(len(c.Covered)) * 100 / float64(c.Total)
+	}
+	return c
+}
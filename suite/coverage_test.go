@@ -0,0 +1,37 @@
+package suite
+
+import (
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/zalando/skipper/eskip"
+)
+
+func TestNewCoverage(t *testing.T) {
+	routes, err := eskip.Parse(`a: Path("/a") -> <shunt>; b: Path("/b") -> <shunt>; c: Path("/c") -> <shunt>; a: Path("/a2") -> <shunt>;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locations := map[string]matcher.Location{
+		"a": {File: "routes.eskip", Line: 1},
+		"b": {File: "routes.eskip", Line: 2},
+	}
+
+	c := NewCoverage(routes, locations, map[string]int{"a": 2, "c": 1, "unknown": 1})
+	assert.Equal(t, &Coverage{
+		Total:   3,
+		Percent: float64(2) * 100 / 3,
+		Covered: []RouteCoverage{
+			{ID: "a", Location: matcher.Location{File: "routes.eskip", Line: 1}, Hits: 2},
+			{ID: "c", Hits: 1},
+		},
+		Uncovered: []RouteCoverage{
+			{ID: "b", Location: matcher.Location{File: "routes.eskip", Line: 2}},
+		},
+	}, c)
+
+	empty := NewCoverage(nil, nil, nil)
+	assert.Equal(t, float64(100), empty.Percent)
+	assert.Equal(t, 0, empty.Total)
+}
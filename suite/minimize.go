@@ -0,0 +1,142 @@
+package suite
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+// MinimizeReport a minimal subset of cases hitting all the routes the
+// given cases hit, see Minimize
+type MinimizeReport struct {
+	// Kept the cases of the minimal subset, in the given order
+	Kept []MinimizedCase `json:"kept"`
+	// Dropped the cases hitting only routes the kept ones hit, in the
+	// given order
+	Dropped []MinimizedCase `json:"dropped"`
+	// Covered ids of the routes hit by the cases, sorted
+	Covered []string `json:"covered"`
+	// Dedicated ids of the routes whose cases hit no other route, they
+	// need a case of their own, sorted
+	Dedicated []string `json:"dedicated"`
+}
+
+// MinimizedCase a case with the routes it hits
+type MinimizedCase struct {
+	Case Case `json:"case"`
+	// Routes ids of the routes the request hits, the matching route and
+	// the ones of the loopback hops, see matcher.Options.FollowLoopback,
+	// in the order of the hops
+	Routes []string `json:"routes"`
+}
+
+// Minimize computes a minimal subset of the cases still hitting every route
+// they hit, picking the case hitting the most routes not hit yet first, the
+// first one on a tie (greedy set cover). A request hits the matching route
+// and, following the <loopback> backends, the routes of the hops. The cases
+// matching no route are dropped, the expectations are ignored.
+func Minimize(m matcher.Matcher, cases []Case) MinimizeReport {
+	hits := make([]MinimizedCase, len(cases))
+	covering := make(map[string][]int)
+	for i, c := range cases {
+		hits[i] = MinimizedCase{Case: c, Routes: caseRoutes(m, c)}
+		for _, id := range hits[i].Routes {
+			covering[id] = append(covering[id], i)
+		}
+	}
+
+	uncovered := make(map[string]bool, len(covering))
+	for id := range covering {
+		uncovered[id] = true
+	}
+	kept := make([]bool, len(cases))
+	for len(uncovered) > 0 {
+		best, bestCount := -1, 0
+		for i := range hits {
+			count := 0
+			for _, id := range hits[i].Routes {
+				if uncovered[id] {
+					count++
+				}
+			}
+			if count > bestCount {
+				best, bestCount = i, count
+			}
+		}
+		kept[best] = true
+		for _, id := range hits[best].Routes {
+			delete(uncovered, id)
+		}
+	}
+
+	r := MinimizeReport{Kept: []MinimizedCase{}, Dropped: []MinimizedCase{}, Covered: []string{}, Dedicated: []string{}}
+	for i, h := range hits {
+		if kept[i] {
+			r.Kept = append(r.Kept, h)
+		} else {
+			r.Dropped = append(r.Dropped, h)
+		}
+	}
+	for id, indexes := range covering {
+		r.Covered = append(r.Covered, id)
+		dedicated := true
+		for _, i := range indexes {
+			if len(hits[i].Routes) > 1 {
+				dedicated = false
+				break
+			}
+		}
+		if dedicated {
+			r.Dedicated = append(r.Dedicated, id)
+		}
+	}
+	sort.Strings(r.Covered)
+	sort.Strings(r.Dedicated)
+	return r
+}
+
+// caseRoutes ids of the routes the case request hits, without duplicates
+func caseRoutes(m matcher.Matcher, c Case) []string {
+	res := m.Test(&c.Request)
+	results := res.Hops()
+	if results == nil {
+		results = []matcher.TestResult{res}
+	}
+	routes := []string{}
+	seen := make(map[string]bool)
+	for _, hop := range results {
+		if route := hop.Route(); route != nil && !seen[route.Id] {
+			seen[route.Id] = true
+			routes = append(routes, route.Id)
+		}
+	}
+	return routes
+}
+
+// WriteText writes the kept and the dropped cases with the routes they hit,
+// the dedicated routes and a summary
+func (r *MinimizeReport) WriteText(w io.Writer) error {
+	var b strings.Builder
+	line := func(verb string, c MinimizedCase) {
+		routes := strings.Join(c.Routes, ", ")
+		if routes == "" {
+			routes = "no match"
+		}
+		fmt.Fprintf(&b, "%s %s: %s\n", verb, c.Case.Name, routes)
+	}
+	for _, c := range r.Kept {
+		line("keep", c)
+	}
+	for _, c := range r.Dropped {
+		line("drop", c)
+	}
+	if len(r.Dedicated) > 0 {
+		fmt.Fprintf(&b, "routes needing a dedicated case: %s\n", strings.Join(r.Dedicated, ", "))
+	}
+	fmt.Fprintf(&b, "%d of %d cases hit the %d routes hit by all of them\n", len(r.Kept), len(r.Kept)+len(r.Dropped), len(r.Covered))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,68 @@
+package suite
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinimize(t *testing.T) {
+	m, err := matcher.New(&matcher.Options{RoutesFile: "testdata/loopback.eskip", FollowLoopback: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	request := func(path string) Case {
+		return Case{Name: path, Request: matcher.RequestAttributes{Path: path}}
+	}
+	cases := []Case{request("/b"), request("/c"), request("/d"), request("/a"), request("/e"), request("/x"), request("/d"), request("/f")}
+	r := Minimize(m, cases)
+
+	var kept, dropped []string
+	for _, c := range r.Kept {
+		kept = append(kept, c.Case.Name)
+	}
+	for _, c := range r.Dropped {
+		dropped = append(dropped, c.Case.Name)
+	}
+	// /a hits a, b and c, /e hits e and d, only /f hits f
+	assert.Equal(t, []string{"/a", "/e", "/f"}, kept)
+	assert.Equal(t, []string{"/b", "/c", "/d", "/x", "/d"}, dropped)
+	assert.Equal(t, []string{"a", "b", "c"}, r.Kept[0].Routes)
+	assert.Equal(t, []string{}, r.Dropped[3].Routes)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, r.Covered)
+	assert.Equal(t, []string{"f"}, r.Dedicated)
+
+	var b bytes.Buffer
+	assert.NoError(t, r.WriteText(&b))
+	assert.Equal(t, `keep /a: a, b, c
+keep /e: e, d
+keep /f: f
+drop /b: b, c
+drop /c: c
+drop /d: d
+drop /x: no match
+drop /d: d
+routes needing a dedicated case: f
+3 of 8 cases hit the 6 routes hit by all of them
+`, b.String())
+}
+
+func TestMinimizeWithoutLoopback(t *testing.T) {
+	m := newTestMatcher(t)
+	defer m.Close()
+
+	cases := []Case{
+		{Name: "order 1", Request: matcher.RequestAttributes{Path: "/orders/1"}},
+		{Name: "order 2", Request: matcher.RequestAttributes{Path: "/orders/2"}},
+		{Name: "search", Request: matcher.RequestAttributes{Path: "/search", Query: map[string]string{"q": "shoes"}}},
+	}
+	r := Minimize(m, cases)
+	assert.Len(t, r.Kept, 2)
+	assert.Equal(t, "order 2", r.Dropped[0].Case.Name)
+	// a request hits a single route, each one needs its own case
+	assert.Equal(t, []string{"order", "search"}, r.Dedicated)
+}
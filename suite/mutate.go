@@ -0,0 +1,355 @@
+package suite
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/zalando/skipper/eskip"
+)
+
+// DefaultMaxMutants default maximum number of mutants tested by Mutate
+const DefaultMaxMutants = 100
+
+// mutatedBackend backend of the retargeted routes
+const mutatedBackend = "http://mutated.invalid"
+
+// Mutator a perturbation of the routes for the mutation testing, see Mutate
+type Mutator struct {
+	// Name of the perturbation, eg. remove-header
+	Name string
+	// Mutate returns the mutated copies of the route, none when the
+	// perturbation doesn't apply to it. The route must not be changed.
+	Mutate func(r *eskip.Route) []*eskip.Route
+}
+
+// DefaultMutators the perturbations used by Mutate by default: a literal
+// segment of the Path and PathSubtree patterns changed, a Header or
+// HeaderRegexp predicate removed, the Method changed and a network backend
+// retargeted
+func DefaultMutators() []Mutator {
+	return []Mutator{
+		{Name: "swap-path-segment", Mutate: swapPathSegments},
+		{Name: "remove-header", Mutate: removeHeaders},
+		{Name: "change-method", Mutate: changeMethod},
+		{Name: "retarget-backend", Mutate: retargetBackend},
+	}
+}
+
+// MutateOptions options of Mutate
+type MutateOptions struct {
+	// Mutators the perturbations, DefaultMutators when nil
+	Mutators []Mutator
+	// MaxMutants maximum number of mutants tested, DefaultMaxMutants when <= 0
+	MaxMutants int
+	// Parallel number of mutants tested at the same time, 1 when <= 0
+	Parallel int
+}
+
+// MutationReport how many mutants of the routes the suites kill
+type MutationReport struct {
+	// Mutants number of mutants tested
+	Mutants int `json:"mutants"`
+	// Killed number of mutants making a case fail that passes with the
+	// routes file
+	Killed int `json:"killed"`
+	// Invalid number of mutants the matcher can't load
+	Invalid int `json:"invalid"`
+	// KillRate percent of the valid mutants killed, 100 when there aren't any
+	KillRate float64 `json:"killRate"`
+	// Capped true if there were more mutants than MutateOptions.MaxMutants
+	Capped bool `json:"capped"`
+	// Survivors the mutants no case killed, in the order they were tested
+	Survivors []Mutant `json:"survivors"`
+}
+
+// Mutant a mutated route
+type Mutant struct {
+	// RouteID id of the mutated route
+	RouteID string `json:"routeId"`
+	// Mutation name of the Mutator
+	Mutation string `json:"mutation"`
+	// Diff the route before and after the mutation
+	Diff string `json:"diff"`
+	// ShouldHaveFailed the cases matching the route with the routes file,
+	// as "suite / case"
+	ShouldHaveFailed []string `json:"shouldHaveFailed"`
+}
+
+// mutantResult the outcome of testing a mutant
+type mutantResult struct {
+	killed  bool
+	invalid bool
+}
+
+// Mutate tests the suites against mutants of the routes file of the options,
+// copies where a single route is perturbed by one of the mutators, and
+// reports how many of them make a case fail that passes with the routes
+// file. The routes are mutated in file order up to MutateOptions.MaxMutants.
+// The diagnostics of the mutants are discarded.
+func Mutate(o *matcher.Options, suites []*Suite, mo *MutateOptions) (*MutationReport, error) {
+	if mo == nil {
+		mo = &MutateOptions{}
+	}
+	mutators := mo.Mutators
+	if mutators == nil {
+		mutators = DefaultMutators()
+	}
+	max := mo.MaxMutants
+	if max <= 0 {
+		max = DefaultMaxMutants
+	}
+	parallel := mo.Parallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	doc, err := ioutil.ReadFile(o.RoutesFile)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := eskip.Parse(string(doc))
+	if err != nil {
+		return nil, err
+	}
+	m, err := matcher.New(o)
+	if err != nil {
+		return nil, err
+	}
+	baseline := Run(m, suites, nil)
+	m.Close()
+
+	report := &MutationReport{Survivors: []Mutant{}}
+	var mutants []Mutant
+	var mutated [][]*eskip.Route
+	for i, r := range routes {
+		for _, mutator := range mutators {
+			for _, mr := range mutator.Mutate(r) {
+				if len(mutants) == max {
+					report.Capped = true
+					break
+				}
+				rs := append([]*eskip.Route{}, routes...)
+				rs[i] = mr
+				mutated = append(mutated, rs)
+				mutants = append(mutants, Mutant{
+					RouteID:          r.Id,
+					Mutation:         mutator.Name,
+					Diff:             fmt.Sprintf("- %s: %s\n+ %s: %s", r.Id, r, mr.Id, mr),
+					ShouldHaveFailed: hittingCases(baseline, r.Id),
+				})
+			}
+		}
+	}
+
+	// results are stored by mutant index to keep the routes order
+	results := make([]mutantResult, len(mutants))
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = testMutant(o, mutated[i], suites, baseline)
+			}
+		}()
+	}
+	for i := range mutants {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	report.Mutants = len(mutants)
+	for i, res := range results {
+		switch {
+		case res.invalid:
+			report.Invalid++
+		case res.killed:
+			report.Killed++
+		default:
+			report.Survivors = append(report.Survivors, mutants[i])
+		}
+	}
+	report.KillRate = 100
+	if valid := report.Mutants - report.Invalid; valid > 0 {
+		report.KillRate = float64(report.Killed) * 100 / float64(valid)
+	}
+	return report, nil
+}
+
+// testMutant runs the suites against the mutated routes, the mutant is
+// killed when a case passing in the baseline fails
+func testMutant(o *matcher.Options, routes []*eskip.Route, suites []*Suite, baseline *Result) mutantResult {
+	f, err := ioutil.TempFile("", "mutant-*.eskip")
+	if err != nil {
+		return mutantResult{invalid: true}
+	}
+	defer os.Remove(f.Name())
+	eskip.Fprint(f, eskip.PrettyPrintInfo{}, routes...)
+	f.Close()
+
+	mo := *o
+	mo.RoutesFile = f.Name()
+	mo.Lazy = false
+	mo.Verbose = false
+	mo.Verbosity = matcher.VerbositySilent
+	mo.Log = ioutil.Discard
+	mo.TB = nil
+	mo.OnLoadProgress = nil
+	mo.DecisionLog = nil
+	m, err := matcher.New(&mo)
+	if err != nil {
+		return mutantResult{invalid: true}
+	}
+	defer m.Close()
+
+	res := Run(m, suites, nil)
+	for i, cr := range res.Cases {
+		if baseline.Cases[i].Passed && !cr.Passed {
+			return mutantResult{killed: true}
+		}
+	}
+	return mutantResult{}
+}
+
+// hittingCases the cases of the result matching the route, as "suite / case"
+func hittingCases(res *Result, id string) []string {
+	cases := []string{}
+	for _, cr := range res.Cases {
+		if cr.RouteID == id {
+			cases = append(cases, fmt.Sprintf("%s / %s", cr.Suite, cr.Case.Name))
+		}
+	}
+	return cases
+}
+
+// swapPathSegments a mutant for each literal segment of the Path and
+// PathSubtree patterns, the segment suffixed with -mutated
+func swapPathSegments(r *eskip.Route) []*eskip.Route {
+	var mutants []*eskip.Route
+	swapped := func(pattern string) []string {
+		var patterns []string
+		segments := strings.Split(pattern, "/")
+		for i, s := range segments {
+			if s == "" || strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+				continue
+			}
+			c := append([]string{}, segments...)
+			c[i] = s + "-mutated"
+			patterns = append(patterns, strings.Join(c, "/"))
+		}
+		return patterns
+	}
+	for _, p := range swapped(r.Path) {
+		c := r.Copy()
+		c.Path = p
+		mutants = append(mutants, c)
+	}
+	for i, pr := range r.Predicates {
+		if pr.Name != "PathSubtree" || len(pr.Args) != 1 {
+			continue
+		}
+		pattern, ok := pr.Args[0].(string)
+		if !ok {
+			continue
+		}
+		for _, p := range swapped(pattern) {
+			c := r.Copy()
+			c.Predicates[i].Args[0] = p
+			mutants = append(mutants, c)
+		}
+	}
+	return mutants
+}
+
+// removeHeaders a mutant for each Header and HeaderRegexp predicate, without it
+func removeHeaders(r *eskip.Route) []*eskip.Route {
+	var mutants []*eskip.Route
+	for name := range r.Headers {
+		c := r.Copy()
+		delete(c.Headers, name)
+		mutants = append(mutants, c)
+	}
+	for name := range r.HeaderRegexps {
+		c := r.Copy()
+		delete(c.HeaderRegexps, name)
+		mutants = append(mutants, c)
+	}
+	for i, pr := range r.Predicates {
+		if pr.Name == "Header" || pr.Name == "HeaderRegexp" {
+			c := r.Copy()
+			c.Predicates = append(c.Predicates[:i], c.Predicates[i+1:]...)
+			mutants = append(mutants, c)
+		}
+	}
+	sortMutants(mutants)
+	return mutants
+}
+
+// changeMethod a mutant with the method changed, GET to POST and the other
+// ones to GET
+func changeMethod(r *eskip.Route) []*eskip.Route {
+	if r.Method == "" {
+		return nil
+	}
+	c := r.Copy()
+	c.Method = "GET"
+	if strings.ToUpper(r.Method) == "GET" {
+		c.Method = "POST"
+	}
+	return []*eskip.Route{c}
+}
+
+// retargetBackend a mutant of a route with a network backend forwarding to
+// another backend
+func retargetBackend(r *eskip.Route) []*eskip.Route {
+	if r.BackendType != eskip.NetworkBackend {
+		return nil
+	}
+	c := r.Copy()
+	c.Backend = mutatedBackend
+	return []*eskip.Route{c}
+}
+
+// sortMutants sorts the mutants by their eskip representation, the headers
+// are removed in map order
+func sortMutants(mutants []*eskip.Route) {
+	sort.Slice(mutants, func(i, j int) bool {
+		return mutants[i].String() < mutants[j].String()
+	})
+}
+
+// WriteText writes the surviving mutants, with the diff of the route and the
+// cases that should have failed, and the kill rate
+func (r *MutationReport) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, m := range r.Survivors {
+		fmt.Fprintf(&b, "SURVIVED %s %s\n", m.RouteID, m.Mutation)
+		for _, line := range strings.Split(m.Diff, "\n") {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+		if len(m.ShouldHaveFailed) == 0 {
+			b.WriteString("  no case matches the route\n")
+		} else {
+			fmt.Fprintf(&b, "  should have failed: %s\n", strings.Join(m.ShouldHaveFailed, ", "))
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%d of %d mutants killed (%.1f%%)", r.Killed, r.Mutants-r.Invalid, r.KillRate)
+	if r.Invalid > 0 {
+		fmt.Fprintf(&b, ", %d invalid", r.Invalid)
+	}
+	if r.Capped {
+		fmt.Fprintf(&b, ", capped at %d mutants", r.Mutants)
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
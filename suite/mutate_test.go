@@ -0,0 +1,98 @@
+package suite
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/zalando/skipper/eskip"
+)
+
+func mutationSuites(cases ...Case) []*Suite {
+	return []*Suite{{Name: "routes", Cases: append([]Case{
+		{Name: "get orders", Request: matcher.RequestAttributes{Method: "GET", Path: "/orders"}, Expect: Expect{Route: "orders"}},
+		{Name: "tenant", Request: matcher.RequestAttributes{Path: "/tenant", Headers: map[string]string{"X-Tenant": "acme"}}, Expect: Expect{Route: "tenant"}},
+	}, cases...)}}
+}
+
+func TestMutate(t *testing.T) {
+	o := &matcher.Options{RoutesFile: "testdata/mutate.eskip"}
+	report, err := Mutate(o, mutationSuites(), &MutateOptions{Parallel: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, report.Mutants)
+	assert.Equal(t, 3, report.Killed)
+	assert.InDelta(t, 60, report.KillRate, 0.001)
+	assert.False(t, report.Capped)
+	assert.Equal(t, []Mutant{
+		{
+			RouteID:          "orders",
+			Mutation:         "retarget-backend",
+			Diff:             "- orders: Path(\"/orders\") && Method(\"GET\") -> \"https://orders.example.org\"\n+ orders: Path(\"/orders\") && Method(\"GET\") -> \"http://mutated.invalid\"",
+			ShouldHaveFailed: []string{"routes / get orders"},
+		},
+		{
+			RouteID:          "tenant",
+			Mutation:         "remove-header",
+			Diff:             "- tenant: Path(\"/tenant\") && Header(\"X-Tenant\", \"acme\") -> <shunt>\n+ tenant: Path(\"/tenant\") -> <shunt>",
+			ShouldHaveFailed: []string{"routes / tenant"},
+		},
+	}, report.Survivors)
+
+	var b bytes.Buffer
+	assert.NoError(t, report.WriteText(&b))
+	assert.Equal(t, `SURVIVED orders retarget-backend
+  - orders: Path("/orders") && Method("GET") -> "https://orders.example.org"
+  + orders: Path("/orders") && Method("GET") -> "http://mutated.invalid"
+  should have failed: routes / get orders
+
+SURVIVED tenant remove-header
+  - tenant: Path("/tenant") && Header("X-Tenant", "acme") -> <shunt>
+  + tenant: Path("/tenant") -> <shunt>
+  should have failed: routes / tenant
+
+3 of 5 mutants killed (60.0%)
+`, b.String())
+
+	// a case without the header kills the mutant
+	report, err = Mutate(o, mutationSuites(Case{Name: "no tenant", Request: matcher.RequestAttributes{Path: "/tenant"}, Expect: Expect{Route: "fallback"}}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, report.Killed)
+	assert.Len(t, report.Survivors, 1)
+}
+
+func TestMutateOptions(t *testing.T) {
+	o := &matcher.Options{RoutesFile: "testdata/mutate.eskip"}
+	report, err := Mutate(o, mutationSuites(), &MutateOptions{MaxMutants: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, report.Mutants)
+	assert.True(t, report.Capped)
+
+	// a custom perturbation moving the tenant route to another path
+	moved := Mutator{Name: "move", Mutate: func(r *eskip.Route) []*eskip.Route {
+		if r.Id != "tenant" {
+			return nil
+		}
+		c := r.Copy()
+		c.Path = "/elsewhere"
+		return []*eskip.Route{c}
+	}}
+	report, err = Mutate(o, mutationSuites(), &MutateOptions{Mutators: []Mutator{moved}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, report.Mutants)
+	assert.Equal(t, 1, report.Killed)
+	assert.Empty(t, report.Survivors)
+}
+
+func TestMutateWithoutRoutesFile(t *testing.T) {
+	_, err := Mutate(&matcher.Options{RoutesFile: "testdata/missing.eskip"}, nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,170 @@
+package suite
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteText writes the coverage as a human readable table
+func (c *Coverage) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROUTE\tHITS\tLOCATION")
+	for _, rc := range c.Covered {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", rc.ID, rc.Hits, rc.Location)
+	}
+	for _, rc := range c.Uncovered {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", rc.ID, rc.Hits, rc.Location)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\ncoverage: %.1f%% (%d of %d routes)\n", c.Percent, len(c.Covered), c.Total)
+	return err
+}
+
+// WriteMarkdown writes the coverage as a markdown report
+func (c *Coverage) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Route coverage\n\n")
+	fmt.Fprintf(&b, "**%.1f%%** of the routes are covered (%d of %d).\n", c.Percent, len(c.Covered), c.Total)
+
+	if len(c.Uncovered) > 0 {
+		fmt.Fprintf(&b, "\n### Uncovered routes\n\n")
+		fmt.Fprintf(&b, "| Route | Location |\n|---|---|\n")
+		for _, rc := range c.Uncovered {
+			fmt.Fprintf(&b, "| `%s` | %s |\n", rc.ID, rc.Location)
+		}
+	}
+
+	if len(c.Covered) > 0 {
+		fmt.Fprintf(&b, "\n### Covered routes\n\n")
+		fmt.Fprintf(&b, "| Route | Hits | Location |\n|---|---|---|\n")
+		for _, rc := range c.Covered {
+			fmt.Fprintf(&b, "| `%s` | %d | %s |\n", rc.ID, rc.Hits, rc.Location)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteText writes a summary of the result followed by the failures
+func (r *Result) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, f := range r.Failures() {
+		fmt.Fprintf(&b, "FAIL %s / %s: %s\n", f.Suite, f.Case.Name, f.Message)
+	}
+	if r.Failed > 0 {
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%d passed, %d failed, %d skipped\n", r.Passed, r.Failed, r.Skipped)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMarkdown writes the result as a markdown report
+func (r *Result) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	status := "passed"
+	if !r.OK() {
+		status = "failed"
+	}
+	fmt.Fprintf(&b, "## Routing tests %s\n\n", status)
+	fmt.Fprintf(&b, "| Passed | Failed | Skipped |\n|---|---|---|\n| %d | %d | %d |\n", r.Passed, r.Failed, r.Skipped)
+
+	if failures := r.Failures(); len(failures) > 0 {
+		fmt.Fprintf(&b, "\n### Failures\n\n")
+		fmt.Fprintf(&b, "| Suite | Case | Problem |\n|---|---|---|\n")
+		for _, f := range failures {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(f.Suite), markdownCell(f.Case.Name), markdownCell(f.Message))
+		}
+	}
+	if r.Hits != nil {
+		fmt.Fprintf(&b, "\n### Route hits\n\n")
+		fmt.Fprintf(&b, "| Route | Hits |\n|---|---|\n")
+		ids := make([]string, 0, len(r.Hits))
+		for id := range r.Hits {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			fmt.Fprintf(&b, "| `%s` | %d |\n", id, r.Hits[id])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell escapes a value to be used in a markdown table cell
+func markdownCell(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
+
+// junit xml schema, as understood by the common CI servers
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Tests   int              `xml:"tests,attr"`
+	Failed  int              `xml:"failures,attr"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name   string          `xml:"name,attr"`
+	File   string          `xml:"file,attr,omitempty"`
+	Tests  int             `xml:"tests,attr"`
+	Failed int             `xml:"failures,attr"`
+	Cases  []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the result as a junit xml report, a testsuite for each
+// suite (in the order their cases were run) and a testcase for each case
+func (r *Result) WriteJUnit(w io.Writer) error {
+	report := junitTestSuites{Tests: len(r.Cases), Failed: r.Failed}
+	index := make(map[string]int)
+	for _, c := range r.Cases {
+		key := c.File + "\x00" + c.Suite
+		i, ok := index[key]
+		if !ok {
+			i = len(report.Suites)
+			index[key] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: c.Suite, File: c.File})
+		}
+
+		s := &report.Suites[i]
+		tc := junitTestCase{Name: c.Case.Name, ClassName: c.Suite}
+		if !c.Passed {
+			tc.Failure = &junitFailure{Message: c.Message, Text: c.Message}
+			s.Failed++
+		}
+		s.Tests++
+		s.Cases = append(s.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
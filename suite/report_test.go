@@ -0,0 +1,95 @@
+package suite
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCoverage = &Coverage{
+	Total:   2,
+	Percent: 50,
+	Covered: []RouteCoverage{
+		{ID: "orders", Location: matcher.Location{File: "routes.eskip", Line: 1}, Hits: 3},
+	},
+	Uncovered: []RouteCoverage{
+		{ID: "search_v2", Location: matcher.Location{File: "routes.eskip", Line: 7}},
+	},
+}
+
+func TestCoverageWriteText(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, testCoverage.WriteText(&b))
+	assert.Equal(t, `ROUTE      HITS  LOCATION
+orders     3     routes.eskip:1
+search_v2  0     routes.eskip:7
+
+coverage: 50.0% (1 of 2 routes)
+`, b.String())
+}
+
+func TestCoverageWriteMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, testCoverage.WriteMarkdown(&b))
+	assert.Equal(t, "## Route coverage\n\n"+
+		"**50.0%** of the routes are covered (1 of 2).\n\n"+
+		"### Uncovered routes\n\n"+
+		"| Route | Location |\n|---|---|\n"+
+		"| `search_v2` | routes.eskip:7 |\n\n"+
+		"### Covered routes\n\n"+
+		"| Route | Hits | Location |\n|---|---|---|\n"+
+		"| `orders` | 3 | routes.eskip:1 |\n", b.String())
+}
+
+var testResult = &Result{
+	Cases: []CaseResult{
+		{Suite: "orders", File: "orders.yaml", Case: Case{Name: "list"}, RouteID: "orders", Passed: true},
+		{Suite: "orders", File: "orders.yaml", Case: Case{Name: "get | one"}, Message: "expected route order to match but there is no match"},
+		{Suite: "search", File: "search.yaml", Case: Case{Name: "search"}, RouteID: "search", Passed: true},
+	},
+	Passed:  2,
+	Failed:  1,
+	Skipped: 1,
+	Hits:    map[string]int{"search": 1, "orders": 1},
+}
+
+func TestResultWriteText(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, testResult.WriteText(&b))
+	assert.Equal(t, "FAIL orders / get | one: expected route order to match but there is no match\n\n"+
+		"2 passed, 1 failed, 1 skipped\n", b.String())
+}
+
+func TestResultWriteMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, testResult.WriteMarkdown(&b))
+	assert.Equal(t, "## Routing tests failed\n\n"+
+		"| Passed | Failed | Skipped |\n|---|---|---|\n| 2 | 1 | 1 |\n\n"+
+		"### Failures\n\n"+
+		"| Suite | Case | Problem |\n|---|---|---|\n"+
+		"| orders | get \\| one | expected route order to match but there is no match |\n\n"+
+		"### Route hits\n\n"+
+		"| Route | Hits |\n|---|---|\n"+
+		"| `orders` | 1 |\n"+
+		"| `search` | 1 |\n", b.String())
+}
+
+func TestResultWriteJUnit(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, testResult.WriteJUnit(&b))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="3" failures="1">
+  <testsuite name="orders" file="orders.yaml" tests="2" failures="1">
+    <testcase name="list" classname="orders"></testcase>
+    <testcase name="get | one" classname="orders">
+      <failure message="expected route order to match but there is no match">expected route order to match but there is no match</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="search" file="search.yaml" tests="1" failures="0">
+    <testcase name="search" classname="search"></testcase>
+  </testsuite>
+</testsuites>
+`, b.String())
+}
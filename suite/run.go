@@ -0,0 +1,258 @@
+package suite
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rbarilani/eskip-match/matcher"
+)
+
+// RunOptions options when running suites
+type RunOptions struct {
+	// Coverage records how many cases hit each route
+	Coverage bool
+
+	// Parallel number of cases run at the same time, 1 when <= 0
+	Parallel int
+
+	// Tags when not empty only the cases having at least one of them are run
+	Tags []string
+
+	// FailFast stops running cases after the first failure
+	FailFast bool
+
+	// UpdateSnapshots writes the snapshot files of the cases expecting
+	// one instead of comparing them with the results
+	UpdateSnapshots bool
+}
+
+// Result result of running suites
+type Result struct {
+	// Cases one result for each case run, in the suites order
+	Cases []CaseResult `json:"cases"`
+	// Passed number of passed cases
+	Passed int `json:"passed"`
+	// Failed number of failed cases
+	Failed int `json:"failed"`
+	// Skipped number of cases not run because of tags or fail fast
+	Skipped int `json:"skipped"`
+	// Hits number of cases matching each route id, only when running with coverage
+	Hits map[string]int `json:"hits,omitempty"`
+}
+
+// CaseResult result of running a single case
+type CaseResult struct {
+	// Suite name of the suite the case belongs to
+	Suite string `json:"suite"`
+	// File the suite was loaded from
+	File string `json:"file,omitempty"`
+	// Case the case run
+	Case Case `json:"case"`
+	// RouteID id of the matching route, empty on no match
+	RouteID string `json:"routeId,omitempty"`
+	// Passed true if the outcome is the expected one
+	Passed bool `json:"passed"`
+	// Message why the case failed
+	Message string `json:"message,omitempty"`
+}
+
+// OK true if all the cases passed
+func (r *Result) OK() bool {
+	return r.Failed == 0
+}
+
+// Failures results of the failed cases
+func (r *Result) Failures() []CaseResult {
+	failures := []CaseResult{}
+	for _, c := range r.Cases {
+		if !c.Passed {
+			failures = append(failures, c)
+		}
+	}
+	return failures
+}
+
+// job a case to run together with the suite it belongs to
+type job struct {
+	suite *Suite
+	c     Case
+}
+
+// Run runs all the cases of the suites against the matcher
+func Run(m matcher.Matcher, suites []*Suite, o *RunOptions) *Result {
+	if o == nil {
+		o = &RunOptions{}
+	}
+
+	res := &Result{Cases: []CaseResult{}}
+	var jobs []job
+	for _, s := range suites {
+		for _, c := range s.Cases {
+			if hasAnyTag(c, o.Tags) {
+				jobs = append(jobs, job{s, c})
+			} else {
+				res.Skipped++
+			}
+		}
+	}
+
+	parallel := o.Parallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	// results are stored by job index to keep the suites order
+	results := make([]*CaseResult, len(jobs))
+	var (
+		mu     sync.Mutex
+		failed bool
+		wg     sync.WaitGroup
+	)
+	next := make(chan int)
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				cr := runCase(m, jobs[i].suite, jobs[i].c, o)
+				mu.Lock()
+				results[i] = &cr
+				failed = failed || !cr.Passed
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range jobs {
+		mu.Lock()
+		stop := o.FailFast && failed
+		mu.Unlock()
+		if stop {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	if o.Coverage {
+		res.Hits = make(map[string]int)
+	}
+	for _, cr := range results {
+		if cr == nil {
+			res.Skipped++
+			continue
+		}
+		res.Cases = append(res.Cases, *cr)
+		if cr.Passed {
+			res.Passed++
+		} else {
+			res.Failed++
+		}
+		if o.Coverage && cr.RouteID != "" {
+			res.Hits[cr.RouteID]++
+		}
+	}
+	return res
+}
+
+// hasAnyTag true if the case has one of the tags or tags are empty
+func hasAnyTag(c Case, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		for _, t := range c.Tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runCase tests the case request and checks the expectation
+func runCase(m matcher.Matcher, s *Suite, c Case, o *RunOptions) CaseResult {
+	res := m.Test(&c.Request)
+	route := res.Route()
+
+	cr := CaseResult{Suite: s.Name, File: s.File, Case: c}
+	if route != nil {
+		cr.RouteID = route.Id
+	}
+
+	var panicked *matcher.PanicError
+	switch {
+	case errors.As(res.Err(), &panicked):
+		// the other cases still run
+		cr.Message = fmt.Sprintf("%v\n%s", panicked, panicked.Stack)
+	case res.Err() != nil:
+		// an error isn't a no match, whatever the expectation
+		cr.Message = fmt.Sprintf("the request couldn't be tested: %v", res.Err())
+	case c.Expect.NoMatch:
+		if route != nil {
+			cr.Message = fmt.Sprintf("expected no match but route %s matches", route.Id)
+		}
+	case c.Expect.Route != "":
+		if route == nil {
+			cr.Message = fmt.Sprintf("expected route %s to match but there is no match", c.Expect.Route)
+		} else if route.Id != c.Expect.Route {
+			cr.Message = fmt.Sprintf("expected route %s to match but route %s matches", c.Expect.Route, route.Id)
+		}
+	case c.Expect.Snapshot == "" && route == nil:
+		cr.Message = "no match"
+	}
+
+	if cr.Message == "" && c.Expect.Snapshot != "" {
+		if err := checkSnapshot(snapshotPath(s, c), res, o.UpdateSnapshots); err != nil {
+			cr.Message = err.Error()
+		}
+	}
+	cr.Passed = cr.Message == ""
+	return cr
+}
+
+// snapshotPath path of the case snapshot, relative to the suite file
+func snapshotPath(s *Suite, c Case) string {
+	if filepath.IsAbs(c.Expect.Snapshot) || s.File == "" {
+		return c.Expect.Snapshot
+	}
+	return filepath.Join(filepath.Dir(s.File), c.Expect.Snapshot)
+}
+
+// checkSnapshot compares the json representation of the result with the
+// golden snapshot file, or writes it when updating the snapshots
+func checkSnapshot(file string, res matcher.TestResult, update bool) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(res); err != nil {
+		return err
+	}
+	got := buf.Bytes()
+
+	if update {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(file, got, 0644)
+	}
+
+	want, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("snapshot %s doesn't exist, run with snapshots update to create it", file)
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(want, got) {
+		return fmt.Errorf("result doesn't match snapshot %s:\n%s", file, got)
+	}
+	return nil
+}
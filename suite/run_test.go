@@ -0,0 +1,210 @@
+package suite
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/zalando/skipper/routing"
+)
+
+func newTestMatcher(t *testing.T) matcher.Matcher {
+	m, err := matcher.New(&matcher.Options{RoutesFile: "testdata/routes.eskip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestRun(t *testing.T) {
+	m := newTestMatcher(t)
+	suites := []*Suite{
+		{
+			Name: "all",
+			Cases: []Case{
+				{Name: "expected route", Request: matcher.RequestAttributes{Path: "/orders/1"}, Expect: Expect{Route: "order"}},
+				{Name: "other route", Request: matcher.RequestAttributes{Path: "/orders/1"}, Expect: Expect{Route: "orders"}},
+				{Name: "route without match", Request: matcher.RequestAttributes{Path: "/"}, Expect: Expect{Route: "orders"}},
+				{Name: "no match", Request: matcher.RequestAttributes{Path: "/"}, Expect: Expect{NoMatch: true}},
+				{Name: "unexpected match", Request: matcher.RequestAttributes{Path: "/orders/1"}, Expect: Expect{NoMatch: true}},
+				{Name: "any match", Request: matcher.RequestAttributes{Path: "/orders/2"}},
+				{Name: "any match fails", Request: matcher.RequestAttributes{Path: "/"}},
+			},
+		},
+	}
+
+	res := Run(m, suites, nil)
+	assert.Equal(t, 3, res.Passed)
+	assert.Equal(t, 4, res.Failed)
+	assert.False(t, res.OK())
+	assert.Nil(t, res.Hits)
+
+	messages := []string{}
+	for _, f := range res.Failures() {
+		messages = append(messages, f.Case.Name+": "+f.Message)
+	}
+	assert.Equal(t, []string{
+		"other route: expected route orders to match but route order matches",
+		"route without match: expected route orders to match but there is no match",
+		"unexpected match: expected no match but route order matches",
+		"any match fails: no match",
+	}, messages)
+
+	// cases are left untouched by the matcher normalization
+	assert.Equal(t, "", suites[0].Cases[0].Request.Method)
+}
+
+func TestRunCoverage(t *testing.T) {
+	m := newTestMatcher(t)
+	suites, err := LoadFiles("testdata/fixtures/**/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := Run(m, suites, &RunOptions{Coverage: true})
+	assert.True(t, res.OK(), "%+v", res.Failures())
+	assert.Equal(t, map[string]int{"orders": 1, "order": 1, "search": 1}, res.Hits)
+}
+
+func TestRunOptions(t *testing.T) {
+	m := newTestMatcher(t)
+	suites, err := LoadFiles("testdata/fixtures/**/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := &Suite{Name: "broken", Cases: []Case{
+		{Name: "fails", Request: matcher.RequestAttributes{Path: "/"}, Expect: Expect{Route: "orders"}},
+		{Name: "passes", Request: matcher.RequestAttributes{Path: "/orders/1"}},
+	}}
+
+	names := func(res *Result) []string {
+		names := []string{}
+		for _, c := range res.Cases {
+			names = append(names, c.Case.Name)
+		}
+		return names
+	}
+
+	t.Run("tags", func(t *testing.T) {
+		res := Run(m, suites, &RunOptions{Tags: []string{"smoke"}})
+		assert.Equal(t, []string{"search", "get an order"}, names(res))
+		assert.Equal(t, 3, res.Skipped)
+	})
+
+	t.Run("parallel keeps the order", func(t *testing.T) {
+		sequential := Run(m, append(suites, broken), nil)
+		parallel := Run(m, append(suites, broken), &RunOptions{Parallel: 4})
+		assert.Equal(t, sequential, parallel)
+		assert.Equal(t, 6, parallel.Passed)
+		assert.Equal(t, 1, parallel.Failed)
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		res := Run(m, append([]*Suite{broken}, suites...), &RunOptions{FailFast: true})
+		assert.Equal(t, []string{"fails"}, names(res))
+		assert.Equal(t, 1, res.Failed)
+		assert.Equal(t, 6, res.Skipped)
+	})
+}
+
+func TestRunSnapshots(t *testing.T) {
+	m := newTestMatcher(t)
+	dir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &Suite{
+		Name: "snapshots",
+		File: filepath.Join(dir, "fixture.yaml"),
+		Cases: []Case{
+			{Name: "order", Request: matcher.RequestAttributes{Path: "/orders/1"}, Expect: Expect{Snapshot: "snapshots/order.json"}},
+		},
+	}
+
+	res := Run(m, []*Suite{s}, nil)
+	if assert.Equal(t, 1, res.Failed) {
+		assert.Contains(t, res.Cases[0].Message, "doesn't exist")
+	}
+
+	res = Run(m, []*Suite{s}, &RunOptions{UpdateSnapshots: true})
+	assert.True(t, res.OK(), "%+v", res.Failures())
+	b, err := ioutil.ReadFile(filepath.Join(dir, "snapshots", "order.json"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{
+  "matched": true,
+  "routeId": "order",
+  "backendType": "shunt",
+  "backend": "<shunt>",
+  "request": {
+    "method": "GET",
+    "path": "/orders/1"
+  }
+}
+`, string(b))
+	}
+
+	res = Run(m, []*Suite{s}, nil)
+	assert.True(t, res.OK(), "%+v", res.Failures())
+
+	s.Cases[0].Request.Path = "/orders/2"
+	res = Run(m, []*Suite{s}, nil)
+	if assert.Equal(t, 1, res.Failed) {
+		assert.Contains(t, res.Cases[0].Message, "result doesn't match snapshot")
+	}
+}
+
+func TestRunPanic(t *testing.T) {
+	f, err := ioutil.TempFile("", "routes-*.eskip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if err := ioutil.WriteFile(f.Name(), []byte(`panicking: Panicking() -> <shunt>;
+any: * -> <shunt>;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	panicking := matcher.PredicateFunc("Panicking", 0, func(args []interface{}, req *http.Request) bool {
+		if req.Header.Get("Panic") != "" {
+			panic("buggy predicate")
+		}
+		return false
+	})
+	m, err := matcher.New(&matcher.Options{RoutesFile: f.Name(), CustomPredicates: []routing.PredicateSpec{panicking}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	res := Run(m, []*Suite{{Name: "all", Cases: []Case{
+		{Name: "panic", Request: matcher.RequestAttributes{Path: "/", Headers: map[string]string{"Panic": "1"}}, Expect: Expect{NoMatch: true}},
+		{Name: "next", Request: matcher.RequestAttributes{Path: "/"}, Expect: Expect{Route: "any"}},
+	}}}, nil)
+	assert.Equal(t, 1, res.Passed)
+	if assert.Len(t, res.Failures(), 1) {
+		assert.Equal(t, "panic", res.Failures()[0].Case.Name)
+		assert.Contains(t, res.Failures()[0].Message, "panic while matching the request: buggy predicate\n")
+		assert.Contains(t, res.Failures()[0].Message, "routing.(*Routing).Route(")
+	}
+}
+
+func TestRunTestError(t *testing.T) {
+	m := newTestMatcher(t)
+	defer m.Close()
+
+	res := Run(m, []*Suite{{Name: "all", Cases: []Case{
+		{Name: "invalid header", Request: matcher.RequestAttributes{Path: "/", Headers: map[string]string{"Bad Name": "x"}}, Expect: Expect{NoMatch: true}},
+		{Name: "no match", Request: matcher.RequestAttributes{Path: "/"}, Expect: Expect{NoMatch: true}},
+	}}}, nil)
+	assert.Equal(t, 1, res.Passed)
+	if assert.Len(t, res.Failures(), 1) {
+		assert.Equal(t, "invalid header", res.Failures()[0].Case.Name)
+		assert.Contains(t, res.Failures()[0].Message, "the request couldn't be tested: ")
+		assert.Contains(t, res.Failures()[0].Message, "Bad Name")
+	}
+}
@@ -0,0 +1,190 @@
+// Package suite loads fixture files describing requests together with
+// the route expected to match them, and runs them against a matcher.
+//
+// A fixture file is a yaml (or json) document like:
+//
+//	name: orders
+//	parallel: true
+//	cases:
+//	  - name: get an order
+//	    request:
+//	      method: GET
+//	      path: /v1/orders/1
+//	      host: api.example.org
+//	    expect:
+//	      route: orders
+//	  - name: unknown path
+//	    request:
+//	      path: /v1/unknown
+//	    expect:
+//	      noMatch: true
+//	  - name: order filters
+//	    request:
+//	      path: /v1/orders/2
+//	    expect:
+//	      snapshot: snapshots/order.json
+//
+// The fixture files are validated against schema.FixtureSchema, the JSON
+// Schema the tools authoring them can use too.
+package suite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/rbarilani/eskip-match/schema"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Suite a list of cases loaded from a fixture file
+type Suite struct {
+	// File the suite was loaded from
+	File string `json:"file,omitempty" yaml:"-"`
+	// Name of the suite, the file name when omitted
+	Name string `json:"name" yaml:"name"`
+	// Cases of the suite
+	Cases []Case `json:"cases" yaml:"cases"`
+	// Parallel the cases can run in parallel with the other ones, eg. the
+	// subtests of matchertest.RunDir call t.Parallel
+	Parallel bool `json:"parallel,omitempty" yaml:"parallel,omitempty"`
+}
+
+// Case a request and the routing behavior expected for it
+type Case struct {
+	// Name of the case
+	Name string `json:"name" yaml:"name"`
+	// Tags allow to select the cases to run
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// Request attributes to test
+	Request matcher.RequestAttributes `json:"request" yaml:"request"`
+	// Expect expected outcome, any match when empty
+	Expect Expect `json:"expect" yaml:"expect"`
+}
+
+// Expect expected outcome of a case
+type Expect struct {
+	// Route id of the route expected to match
+	Route string `json:"route,omitempty" yaml:"route,omitempty"`
+	// NoMatch true if no route is expected to match
+	NoMatch bool `json:"noMatch,omitempty" yaml:"noMatch,omitempty"`
+	// Snapshot golden file, relative to the fixture file, holding the
+	// expected json result (matching route, backend, filters, request)
+	Snapshot string `json:"snapshot,omitempty" yaml:"snapshot,omitempty"`
+}
+
+// Load loads a suite from a fixture file
+func Load(file string) (*Suite, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// the schema reports every mistake with its path, eg.
+	// cases[3].request.method: expected string, an empty file is an empty
+	// suite
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if doc != nil {
+		if err := schema.Validate(schema.Fixture, doc); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+
+	s := &Suite{}
+	if err := yaml.UnmarshalStrict(b, s); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	s.File = file
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+
+	for i, c := range s.Cases {
+		if c.Name == "" {
+			s.Cases[i].Name = fmt.Sprintf("case %d", i+1)
+		}
+		if c.Expect.Route != "" && c.Expect.NoMatch {
+			return nil, fmt.Errorf("%s: %s: route and noMatch can't be expected together", file, s.Cases[i].Name)
+		}
+	}
+	return s, nil
+}
+
+// LoadFiles loads the suites from all the files matching the glob patterns,
+// in addition to filepath.Match syntax a "**" path segment matches any number
+// of directories (eg. "tests/**/*.yaml"). Files are loaded in lexical order
+// and a pattern not matching any file is an error.
+func LoadFiles(patterns ...string) ([]*Suite, error) {
+	var suites []*Suite
+	for _, pattern := range patterns {
+		files, err := glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no fixture file matches %s", pattern)
+		}
+		for _, file := range files {
+			s, err := Load(file)
+			if err != nil {
+				return nil, err
+			}
+			suites = append(suites, s)
+		}
+	}
+	return suites, nil
+}
+
+// glob like filepath.Glob supporting "**" segments
+func glob(pattern string) ([]string, error) {
+	i := strings.Index(pattern, "**")
+	if i < 0 {
+		return filepath.Glob(pattern)
+	}
+
+	root := filepath.Clean(pattern[:i])
+	if pattern[:i] == "" {
+		root = "."
+	}
+	rest := strings.TrimPrefix(pattern[i+2:], string(filepath.Separator))
+
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		matches, err := glob(filepath.Join(path, rest))
+		if err != nil {
+			return err
+		}
+		files = append(files, matches...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return unique(files), nil
+}
+
+// unique removes duplicates from a sorted list
+func unique(items []string) []string {
+	out := items[:0]
+	for i, item := range items {
+		if i == 0 || item != items[i-1] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
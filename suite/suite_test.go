@@ -0,0 +1,140 @@
+package suite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbarilani/eskip-match/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	s, err := Load("testdata/fixtures/nested/search.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &Suite{
+		File: "testdata/fixtures/nested/search.yaml",
+		Name: "search",
+		Cases: []Case{
+			{
+				Name:    "search",
+				Tags:    []string{"smoke"},
+				Request: matcher.RequestAttributes{Path: "/search", Query: map[string]string{"q": "shoes"}},
+				Expect:  Expect{Route: "search"},
+			},
+			{
+				Name:    "case 2",
+				Request: matcher.RequestAttributes{Path: "/search"},
+				Expect:  Expect{NoMatch: true},
+			},
+		},
+	}, s)
+}
+
+func TestLoadErrors(t *testing.T) {
+	_, err := Load("testdata/missing.yaml")
+	assert.Error(t, err)
+
+	_, err = Load("testdata/invalid.yaml")
+	assert.EqualError(t, err, "testdata/invalid.yaml: both: route and noMatch can't be expected together")
+
+	_, err = Load("testdata/routes.eskip")
+	assert.Error(t, err)
+
+	_, err = Load("testdata/wrong-types.yaml")
+	assert.EqualError(t, err, `testdata/wrong-types.yaml: cases[1].request: unknown property "header"; cases[1].request.method: expected string`)
+}
+
+func TestLoadFiles(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		files    []string
+		err      bool
+	}{
+		{
+			patterns: []string{"testdata/fixtures/*.yaml"},
+			files:    []string{"testdata/fixtures/orders.yaml"},
+		},
+		{
+			patterns: []string{"testdata/fixtures/**/*.yaml"},
+			files:    []string{"testdata/fixtures/nested/search.yaml", "testdata/fixtures/orders.yaml"},
+		},
+		{
+			patterns: []string{"testdata/fixtures/orders.yaml", "testdata/fixtures/nested/search.yaml"},
+			files:    []string{"testdata/fixtures/orders.yaml", "testdata/fixtures/nested/search.yaml"},
+		},
+		{
+			patterns: []string{"testdata/fixtures/*.json"},
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Join(tt.patterns...), func(t *testing.T) {
+			suites, err := LoadFiles(tt.patterns...)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			files := []string{}
+			for _, s := range suites {
+				files = append(files, s.File)
+			}
+			assert.Equal(t, tt.files, files)
+		})
+	}
+}
+
+func TestGeneratedFixture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generated-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	routes, err := ioutil.ReadFile("testdata/routes.eskip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	routesFile := filepath.Join(dir, "routes.eskip")
+	routes = append(routes, `canary: Path("/canary") && Traffic(0.1) -> <shunt>;
+shadowed: PathRegexp(/^\/orders\/[0-9]+$/) -> <shunt>;
+`...)
+	if err := ioutil.WriteFile(routesFile, routes, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture, err := os.Create(filepath.Join(dir, "generated.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = matcher.GenerateFixture(&matcher.Options{RoutesFile: routesFile}, fixture)
+	fixture.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(fixture.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range s.Cases {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"orders", "order", "search", "tenant"}, names, "the TODO cases are commented out")
+
+	m, err := matcher.New(&matcher.Options{RoutesFile: routesFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	res := Run(m, []*Suite{s}, nil)
+	assert.True(t, res.OK(), "%+v", res.Failures())
+	assert.Equal(t, 4, res.Passed)
+}